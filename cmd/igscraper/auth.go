@@ -2,8 +2,11 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"runtime"
 	"strings"
 	"syscall"
 	"time"
@@ -11,6 +14,8 @@ import (
 	"github.com/spf13/cobra"
 	"golang.org/x/term"
 	"igscraper/pkg/auth"
+	"igscraper/pkg/config"
+	"igscraper/pkg/doctor"
 	"igscraper/pkg/ui"
 )
 
@@ -31,16 +36,19 @@ SECURITY:
   • No passwords stored - Only session cookies are saved
 
 SUBCOMMANDS:
-  login    - Add or update Instagram credentials
-  logout   - Remove stored credentials
-  list     - Show all saved accounts
-  switch   - Select default account
+  login          - Add or update Instagram credentials
+  import-browser - Import cookies from Chrome, Firefox or Safari
+  logout         - Remove stored credentials
+  list           - Show all saved accounts
+  switch         - Select default account
+  test           - Check that stored cookies still work
+  device         - Show or regenerate an account's browser fingerprint
 
 QUICK START:
   1. Login to Instagram in your browser
-  2. Extract session cookies (see 'auth login --help')
-  3. Store credentials: igscraper auth login
-  4. Start downloading: igscraper username
+  2. Import its cookies: igscraper auth import-browser
+     (or enter them by hand: igscraper auth login)
+  3. Start downloading: igscraper username
 
 For detailed instructions, see: https://github.com/marcusziade/igscraper/blob/master/docs/MANUAL.md#authentication`,
 }
@@ -166,15 +174,142 @@ NOTE:
 	Run:  runSwitch,
 }
 
+// testCmd represents the auth test command
+var testCmd = &cobra.Command{
+	Use:   "test [username]",
+	Short: "Check that stored credentials still work",
+	Long: `Check whether Instagram still accepts an account's session cookies.
+
+A single logged-in profile request is sent, so the check is cheap and safe to
+run often. The result tells apart:
+  • Valid      - sessionid and csrftoken are accepted
+  • Expired    - Instagram asks to log in again
+  • Challenged - the account must pass a security check in a browser
+
+The session's age is also compared with Instagram's usual session lifetime,
+and a warning is shown when the cookies are close to expiring.
+
+NOTE:
+  Scrapes run the same check automatically before downloading anything.
+  Use --skip-session-check on scrape, liked, saved or daemon to disable it.
+
+Without a username, the default account is tested. Exits with status 1 when
+the session is expired or challenged.`,
+	Example: `  # Test the default account
+  igscraper auth test
+
+  # Test a specific stored account
+  igscraper auth test work_account`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runTest,
+}
+
+var (
+	// Auth import-browser flags
+	importBrowserName string
+)
+
+// importBrowserCmd represents the auth import-browser command
+var importBrowserCmd = &cobra.Command{
+	Use:   "import-browser [username]",
+	Short: "Import session cookies from a browser",
+	Long: `Import Instagram session cookies straight from a browser's cookie store
+instead of copying them from the developer tools.
+
+SUPPORTED BROWSERS:
+  • Chrome  - macOS and Linux
+  • Firefox - macOS, Linux and Windows
+  • Safari  - macOS (the terminal needs Full Disk Access)
+
+HOW IT WORKS:
+  1. Log in to instagram.com in the browser
+  2. Run this command and pick the browser
+  3. Confirm before its cookie store is read
+  4. Review the cookies found and confirm before they are stored
+
+Only the instagram.com sessionid, csrftoken and ds_user_id cookies are used.
+Chrome encrypts cookies with a key from the system keychain, so macOS may ask
+to allow access to "Chrome Safe Storage". The imported session is tested with
+a single request afterwards.`,
+	Example: `  # Pick a browser interactively
+  igscraper auth import-browser
+
+  # Import from Firefox as account myusername
+  igscraper auth import-browser myusername --browser firefox`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runImportBrowser,
+}
+
+// deviceCmd represents the auth device command
+var deviceCmd = &cobra.Command{
+	Use:   "device",
+	Short: "Show or replace the browser fingerprint of an account",
+	Long: `Each stored account presents its own browser to Instagram: a User-Agent,
+app ID, Accept-Language and device cookies (ig_did and mid). The device is
+generated when the account is first stored, or first used if it was stored
+before, and kept with its credentials so every request of the account looks
+like it comes from the same browser.
+
+An account's own User-Agent, set with 'auth login', is sent instead of the
+generated one.`,
+}
+
+// deviceShowCmd represents the auth device show command
+var deviceShowCmd = &cobra.Command{
+	Use:   "show [username]",
+	Short: "Show the device an account presents",
+	Example: `  # Show the device of the default account
+  igscraper auth device show
+
+  # Of a specific stored account
+  igscraper auth device show work_account`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runDeviceShow,
+}
+
+// deviceRegenerateCmd represents the auth device regenerate command
+var deviceRegenerateCmd = &cobra.Command{
+	Use:   "regenerate [username]",
+	Short: "Give an account a new device",
+	Long: `Replace the device an account presents with a newly generated one, such as
+after logging in to the account from a new browser. The session cookies are
+kept.`,
+	Example: `  # Regenerate the device of the default account
+  igscraper auth device regenerate
+
+  # Of a specific stored account
+  igscraper auth device regenerate work_account`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runDeviceRegenerate,
+}
+
 func init() {
 	rootCmd.AddCommand(authCmd)
 	authCmd.AddCommand(loginCmd)
 	authCmd.AddCommand(logoutCmd)
 	authCmd.AddCommand(listCmd)
 	authCmd.AddCommand(switchCmd)
+	authCmd.AddCommand(testCmd)
+	authCmd.AddCommand(importBrowserCmd)
+	authCmd.AddCommand(deviceCmd)
+	deviceCmd.AddCommand(deviceShowCmd)
+	deviceCmd.AddCommand(deviceRegenerateCmd)
+
+	authCmd.PersistentFlags().StringVar(&outputFormat, "output-format", ui.OutputText, "output format: text, or json for one JSON event per line on stdout")
+	importBrowserCmd.Flags().StringVar(&importBrowserName, "browser", "", "browser to import from: chrome, firefox or safari (default: ask)")
+}
+
+// requireTextOutput exits when JSON output is requested for a command that
+// prompts for input, since scripts cannot answer the prompts
+func requireTextOutput(what string) {
+	if ui.IsJSONOutput() {
+		ui.PrintError("Not available with --output-format json", what+" prompts for input")
+		os.Exit(1)
+	}
 }
 
 func runLogin(cmd *cobra.Command, args []string) {
+	requireTextOutput("auth login")
 	manager, err := auth.NewManager()
 	if err != nil {
 		ui.PrintError("Failed to initialize credential manager", err.Error())
@@ -189,6 +324,20 @@ func runLogin(cmd *cobra.Command, args []string) {
 	// Interactive prompts
 	reader := bufio.NewReader(os.Stdin)
 	
+	// Offer to skip the manual steps when a browser cookie store is available
+	if browsers := auth.DetectBrowsers(); len(browsers) > 0 {
+		names := make([]string, len(browsers))
+		for i, browser := range browsers {
+			names[i] = browser.String()
+		}
+		fmt.Printf("🌐 Found browser cookies in: %s\n", strings.Join(names, ", "))
+		if askYesNo(reader, "Import your Instagram session from a browser instead of copying cookies by hand? (Y/n): ", true) {
+			importFromBrowser(manager, reader, username)
+			return
+		}
+		fmt.Println()
+	}
+	
 	// Show extraction guide first
 	auth.ShowCookieExtractionGuide()
 	
@@ -348,6 +497,8 @@ func runLogout(cmd *cobra.Command, args []string) {
 	}
 
 	if len(args) == 0 {
+		requireTextOutput("auth logout without a username")
+
 		// List accounts and ask which to remove
 		accounts, err := manager.List()
 		if err != nil || len(accounts) == 0 {
@@ -445,6 +596,23 @@ func runList(cmd *cobra.Command, args []string) {
 		return
 	}
 	
+	if ui.IsJSONOutput() {
+		for _, account := range accounts {
+			sanitized := auth.SanitizeAccount(account)
+			fields := map[string]interface{}{
+				"username":      sanitized.Username,
+				"session_id":    sanitized.SessionID,
+				"csrf_token":    sanitized.CSRFToken,
+				"last_modified": sanitized.LastModified.Format(time.RFC3339),
+			}
+			if sanitized.UserAgent != "" {
+				fields["user_agent"] = sanitized.UserAgent
+			}
+			ui.EmitEvent("account", fields)
+		}
+		return
+	}
+	
 	ui.PrintHighlight("Stored Accounts")
 	fmt.Println()
 	
@@ -483,6 +651,8 @@ func runSwitch(cmd *cobra.Command, args []string) {
 	if len(args) > 0 {
 		username = args[0]
 	} else {
+		requireTextOutput("auth switch without a username")
+
 		// Interactive selection
 		fmt.Println("Select account:")
 		for i, account := range accounts {
@@ -514,6 +684,9 @@ func runSwitch(cmd *cobra.Command, args []string) {
 	// Note: In a real implementation, we might store the default account preference
 	// For now, just show confirmation
 	ui.PrintSuccess("Account selected: " + username)
+	if ui.IsJSONOutput() {
+		return
+	}
 	fmt.Println("\nUse the --account flag to use this account:")
 	fmt.Printf("  igscraper scrape <username> --account %s\n", username)
 }
@@ -538,9 +711,363 @@ func readPassword() (string, error) {
 	return strings.TrimSpace(input), nil
 }
 
-// testCredentials tests if the provided credentials work with Instagram
-func testCredentials(account *auth.Account) error {
-	// For now, we'll skip the test to avoid complexity
-	// In a real implementation, we'd make a test API call
-	return nil
-}
\ No newline at end of file
+// sessionCheckTarget is the public profile requested by session checks
+const sessionCheckTarget = "instagram"
+
+// testCredentials sends one logged-in request with the credentials in cfg and
+// reports whether Instagram still accepts them
+func testCredentials(cfg *config.Config) doctor.SessionCheck {
+	return doctor.New(cfg, sessionCheckTarget).CheckSession()
+}
+
+// accountConfig loads the configuration with account's credentials applied.
+// Session checks only take the user agent, timeout and audit log from it.
+func accountConfig(account *auth.Account) *config.Config {
+	cfg := config.DefaultConfig()
+	if err := cfg.LoadFromFile(configFile); err != nil {
+		ui.PrintError("Failed to load configuration", err.Error())
+		os.Exit(1)
+	}
+	if err := cfg.LoadFromEnv(); err != nil {
+		ui.PrintError("Failed to load configuration", err.Error())
+		os.Exit(1)
+	}
+	if auditLog != "" {
+		cfg.Logging.AuditFile = auditLog
+	}
+	initAuditLog(cfg)
+
+	cfg.Instagram.SessionID = account.SessionID
+	cfg.Instagram.CSRFToken = account.CSRFToken
+	applyDevice(cfg, account)
+	return cfg
+}
+
+func runTest(cmd *cobra.Command, args []string) {
+	manager, err := auth.NewManager()
+	if err != nil {
+		ui.PrintError("Failed to initialize credential manager", err.Error())
+		os.Exit(1)
+	}
+
+	var account *auth.Account
+	if len(args) > 0 {
+		account, err = manager.Retrieve(args[0])
+		if err != nil {
+			ui.PrintError("Account not found", args[0])
+			ui.PrintInfo("Available accounts", "Use 'igscraper auth list' to see stored accounts")
+			os.Exit(1)
+		}
+	} else if account, err = manager.RetrieveDefault(); err != nil {
+		ui.PrintError("No stored accounts found", "Use 'igscraper auth login' to add an account")
+		os.Exit(1)
+	}
+
+	cfg := accountConfig(account)
+
+	if ui.IsJSONOutput() {
+		emitSessionCheck(account, testCredentials(cfg))
+		return
+	}
+
+	fmt.Printf("%s %s\n", ui.Magenta("Session check for"), account.Username)
+	check := testCredentials(cfg)
+	r := check.Result
+	latency := ui.Dim(r.Latency.Round(time.Millisecond).String())
+
+	switch check.State {
+	case doctor.SessionValid:
+		fmt.Printf("  %s %s %s\n", ui.Green("✓"), "sessionid and csrftoken are valid", latency)
+	case doctor.SessionExpired:
+		fmt.Printf("  %s %s\n", ui.Red("✗"), ui.Red("Session expired: Instagram asks to log in again"))
+	case doctor.SessionChallenged:
+		fmt.Printf("  %s %s\n", ui.Red("✗"), ui.Red("Account challenged: "+r.Challenge))
+	default:
+		if r.Err != nil {
+			fmt.Printf("  %s %s\n", ui.Yellow("!"), ui.Yellow("Could not reach Instagram: "+r.Err.Error()))
+		} else {
+			fmt.Printf("  %s %s %s\n", ui.Yellow("!"), ui.Yellow(fmt.Sprintf("Inconclusive answer: %d %s", r.StatusCode, http.StatusText(r.StatusCode))), latency)
+		}
+	}
+
+	if expires := account.ExpiresAt(); !expires.IsZero() {
+		fmt.Printf("  %s %s\n", ui.Cyan("Saved:"), account.LastModified.Format("2006-01-02"))
+		fmt.Printf("  %s %s\n", ui.Cyan("Estimated expiry:"), expires.Format("2006-01-02"))
+	}
+	fmt.Println()
+
+	switch check.State {
+	case doctor.SessionExpired:
+		fmt.Println("  → Log in at instagram.com in a browser and copy fresh cookies")
+		fmt.Printf("  → Store them with: igscraper auth login %s\n", account.Username)
+		os.Exit(1)
+	case doctor.SessionChallenged:
+		fmt.Println("  → Log in at instagram.com in a browser and complete the security check")
+		fmt.Println("  → Pause scraping with this account for 24-48 hours")
+		os.Exit(1)
+	case doctor.SessionUnknown:
+		fmt.Println("  → Run 'igscraper doctor' for a full connection check")
+	}
+	if account.ExpiresSoon(time.Now()) {
+		fmt.Println(ui.Yellow("! Session is close to its expected expiry"))
+		fmt.Printf("  → Refresh the cookies soon with: igscraper auth import-browser %s\n", account.Username)
+	}
+}
+
+// emitSessionCheck writes the result of an auth test as a session_check
+// event, exiting with status 1 when the session is expired or challenged
+func emitSessionCheck(account *auth.Account, check doctor.SessionCheck) {
+	r := check.Result
+	fields := map[string]interface{}{
+		"username":     account.Username,
+		"state":        string(check.State),
+		"latency_ms":   r.Latency.Milliseconds(),
+		"expires_soon": account.ExpiresSoon(time.Now()),
+	}
+	if r.StatusCode != 0 {
+		fields["status"] = r.StatusCode
+	}
+	if r.Challenge != "" {
+		fields["challenge"] = r.Challenge
+	}
+	if r.Err != nil {
+		fields["error"] = r.Err.Error()
+	}
+	if expires := account.ExpiresAt(); !expires.IsZero() {
+		fields["saved"] = account.LastModified.Format(time.RFC3339)
+		fields["expires"] = expires.Format(time.RFC3339)
+	}
+	ui.EmitEvent("session_check", fields)
+
+	if check.State == doctor.SessionExpired || check.State == doctor.SessionChallenged {
+		os.Exit(1)
+	}
+}
+
+func runImportBrowser(cmd *cobra.Command, args []string) {
+	requireTextOutput("auth import-browser")
+	manager, err := auth.NewManager()
+	if err != nil {
+		ui.PrintError("Failed to initialize credential manager", err.Error())
+		os.Exit(1)
+	}
+
+	var username string
+	if len(args) > 0 {
+		username = args[0]
+	}
+	importFromBrowser(manager, bufio.NewReader(os.Stdin), username)
+}
+
+// importFromBrowser reads the Instagram session from a browser and stores it
+// for username, prompting for anything missing. The user confirms both
+// before the cookie store is read and before the credentials are saved.
+func importFromBrowser(manager *auth.Manager, reader *bufio.Reader, username string) {
+	browser := chooseBrowser(reader)
+
+	fmt.Printf("\n🔍 igscraper will read your instagram.com cookies from %s.\n", browser)
+	if browser == auth.BrowserChrome && runtime.GOOS == "darwin" {
+		fmt.Println("   macOS may ask to allow access to \"Chrome Safe Storage\"; choose Allow.")
+	}
+	if !askYesNo(reader, "Continue? (y/N): ", false) {
+		fmt.Println("\nNothing was read.")
+		return
+	}
+
+	cookies, err := auth.ReadBrowserCookies(browser)
+	if err != nil {
+		if errors.Is(err, auth.ErrNoInstagramSession) {
+			ui.PrintError("No Instagram session in "+browser.String(), "log in at instagram.com in this browser and try again")
+		} else {
+			ui.PrintError("Failed to read "+browser.String()+" cookies", err.Error())
+		}
+		fmt.Println("\nYou can still enter cookies by hand with: igscraper auth login")
+		os.Exit(1)
+	}
+
+	sanitized := auth.SanitizeAccount(cookies.Account(username))
+	fmt.Println("\n📋 Found Instagram session:")
+	fmt.Printf("   Cookie store: %s\n", cookies.Store)
+	if cookies.UserID != "" {
+		fmt.Printf("   User ID: %s\n", cookies.UserID)
+	}
+	fmt.Printf("   SessionID: %s\n", sanitized.SessionID)
+	fmt.Printf("   CSRF Token: %s\n", sanitized.CSRFToken)
+	if !cookies.Expires.IsZero() {
+		fmt.Printf("   Expires: %s\n", cookies.Expires.Format("2006-01-02"))
+	}
+
+	if username == "" {
+		fmt.Print("\n📱 Instagram username for this session: ")
+		input, err := reader.ReadString('\n')
+		if err != nil {
+			ui.PrintError("Failed to read username", err.Error())
+			os.Exit(1)
+		}
+		username = strings.TrimSpace(input)
+	}
+	if username == "" {
+		ui.PrintError("Username is required", "")
+		os.Exit(1)
+	}
+
+	account := cookies.Account(username)
+	if existing, _ := manager.Retrieve(username); existing != nil {
+		if !askYesNo(reader, fmt.Sprintf("\n⚠️  Account '%s' already exists. Replace its cookies? (y/N): ", username), false) {
+			return
+		}
+		account.UserAgent = existing.UserAgent
+	} else if !askYesNo(reader, fmt.Sprintf("\nStore this session as '%s'? (Y/n): ", username), true) {
+		return
+	}
+
+	if err := manager.Store(account); err != nil {
+		ui.PrintError("Failed to store credentials", err.Error())
+		os.Exit(1)
+	}
+	fmt.Printf("\n%s Account saved: %s\n", ui.Green("✓"), username)
+
+	fmt.Print("🔌 Testing the session... ")
+	switch check := testCredentials(accountConfig(account)); check.State {
+	case doctor.SessionValid:
+		fmt.Println(ui.Green("valid"))
+	case doctor.SessionExpired:
+		fmt.Println(ui.Yellow("rejected: log in again in the browser, then re-run the import"))
+	case doctor.SessionChallenged:
+		fmt.Println(ui.Yellow("account needs a security check: complete it at instagram.com in the browser"))
+	default:
+		fmt.Println(ui.Yellow("inconclusive: check later with 'igscraper auth test " + username + "'"))
+	}
+	fmt.Println("\n📖 Start downloading with:")
+	fmt.Printf("   $ igscraper scrape <instagram_username> --account %s\n", username)
+}
+
+// chooseBrowser returns the browser named by --browser, the only browser
+// found, or the user's pick from a menu. It exits when none is available.
+func chooseBrowser(reader *bufio.Reader) auth.Browser {
+	if importBrowserName != "" {
+		for _, browser := range auth.Browsers {
+			if strings.EqualFold(importBrowserName, string(browser)) {
+				return browser
+			}
+		}
+		ui.PrintError("Unknown browser", importBrowserName+" (use chrome, firefox or safari)")
+		os.Exit(1)
+	}
+
+	browsers := auth.DetectBrowsers()
+	switch len(browsers) {
+	case 0:
+		ui.PrintError("No supported browser found", "Chrome, Firefox and Safari are supported; use 'igscraper auth login' to enter cookies by hand")
+		os.Exit(1)
+	case 1:
+		return browsers[0]
+	}
+
+	fmt.Println("\nSelect browser:")
+	for i, browser := range browsers {
+		fmt.Printf("  %d. %s\n", i+1, browser)
+	}
+	fmt.Print("\nChoice: ")
+	input, _ := reader.ReadString('\n')
+
+	var choice int
+	fmt.Sscanf(strings.TrimSpace(input), "%d", &choice)
+	if choice < 1 || choice > len(browsers) {
+		ui.PrintError("Invalid choice", "")
+		os.Exit(1)
+	}
+	return browsers[choice-1]
+}
+
+// askYesNo prints prompt and reads a yes/no answer; an empty answer means
+// defaultYes
+func askYesNo(reader *bufio.Reader, prompt string, defaultYes bool) bool {
+	fmt.Print(prompt)
+	input, _ := reader.ReadString('\n')
+	answer := strings.ToLower(strings.TrimSpace(input))
+	if answer == "" {
+		return defaultYes
+	}
+	return strings.HasPrefix(answer, "y")
+}
+
+// deviceAccountName returns the username in args, or that of the default
+// account
+func deviceAccountName(manager *auth.Manager, args []string) string {
+	if len(args) > 0 {
+		return args[0]
+	}
+	account, err := manager.RetrieveDefault()
+	if err != nil {
+		ui.PrintError("No stored accounts found", "Use 'igscraper auth login' to add an account")
+		os.Exit(1)
+	}
+	return account.Username
+}
+
+func runDeviceShow(cmd *cobra.Command, args []string) {
+	manager, err := auth.NewManager()
+	if err != nil {
+		ui.PrintError("Failed to initialize credential manager", err.Error())
+		os.Exit(1)
+	}
+
+	username := deviceAccountName(manager, args)
+	account, err := manager.Retrieve(username)
+	if err != nil {
+		ui.PrintError("Account not found", username)
+		ui.PrintInfo("Available accounts", "Use 'igscraper auth list' to see stored accounts")
+		os.Exit(1)
+	}
+	if err := manager.EnsureDevice(account); err != nil {
+		ui.PrintError("Account has no device", "credentials from environment variables present the default browser")
+		os.Exit(1)
+	}
+	printDevice(account)
+}
+
+func runDeviceRegenerate(cmd *cobra.Command, args []string) {
+	manager, err := auth.NewManager()
+	if err != nil {
+		ui.PrintError("Failed to initialize credential manager", err.Error())
+		os.Exit(1)
+	}
+
+	username := deviceAccountName(manager, args)
+	account, err := manager.RegenerateDevice(username)
+	if err != nil {
+		ui.PrintError("Failed to regenerate device", err.Error())
+		os.Exit(1)
+	}
+	if !ui.IsJSONOutput() {
+		ui.PrintSuccess("New device for " + account.Username)
+	}
+	printDevice(account)
+}
+
+// printDevice prints the device an account presents, as a device event with
+// --output-format json
+func printDevice(account *auth.Account) {
+	device := account.Fingerprint()
+	if ui.IsJSONOutput() {
+		ui.EmitEvent("device", map[string]interface{}{
+			"username":        account.Username,
+			"user_agent":      device.UserAgent,
+			"app_id":          device.AppID,
+			"accept_language": device.AcceptLanguage,
+			"device_id":       device.DeviceID,
+			"machine_id":      device.MachineID,
+			"generated_at":    device.GeneratedAt.Format(time.RFC3339),
+		})
+		return
+	}
+
+	fmt.Printf("%s %s\n", ui.Magenta("Device of"), account.Username)
+	fmt.Printf("  %s %s\n", ui.Cyan("User Agent:"), device.UserAgent)
+	fmt.Printf("  %s %s\n", ui.Cyan("App ID:"), device.AppID)
+	fmt.Printf("  %s %s\n", ui.Cyan("Accept-Language:"), device.AcceptLanguage)
+	fmt.Printf("  %s %s\n", ui.Cyan("Device ID (ig_did):"), device.DeviceID)
+	fmt.Printf("  %s %s\n", ui.Cyan("Machine ID (mid):"), device.MachineID)
+	fmt.Printf("  %s %s\n", ui.Cyan("Generated:"), device.GeneratedAt.Format("2006-01-02 15:04:05"))
+}
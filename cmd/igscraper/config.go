@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
@@ -20,8 +22,25 @@ var configCmd = &cobra.Command{
 Configuration can be loaded from:
   - Command line flags (highest priority)
   - Environment variables
+  - A profile from the configuration file, selected with --profile
   - Configuration file
-  - Default values (lowest priority)`,
+  - Default values (lowest priority)
+
+PROFILES:
+  The profiles section of the configuration file holds named presets. Each
+  has the same settings as the rest of the file, and --profile (or
+  IGSCRAPER_PROFILE) merges one over them:
+
+    rate_limit:
+      requests_per_minute: 60
+    profiles:
+      slow:
+        rate_limit:
+          requests_per_minute: 20
+        download:
+          concurrent_downloads: 1
+
+    igscraper scrape natgeo --profile slow`,
 }
 
 // initCmd represents the config init command
@@ -49,6 +68,29 @@ Sensitive values like credentials will be masked for security.`,
 	Run: runConfigShow,
 }
 
+// migrateCmd represents the config migrate command
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Upgrade a configuration file to the current layout",
+	Long: `Rewrite a configuration file written for an older version of igscraper
+in the current layout.
+
+Settings that were renamed or moved, such as download.output, now
+output.base_directory, are moved to their new place, and settings that are
+no longer used are dropped. Comments are kept. The original file is saved
+next to it with a .bak extension.
+
+Other commands read older files as if they were migrated, and log a warning
+naming the deprecated settings.`,
+	Example: `  # Upgrade the default configuration file
+  igscraper config migrate
+
+  # Upgrade a specific file
+  igscraper config migrate --config old.yaml`,
+	Args: cobra.NoArgs,
+	Run:  runConfigMigrate,
+}
+
 // validateCmd represents the config validate command
 var validateCmd = &cobra.Command{
 	Use:   "validate",
@@ -57,9 +99,12 @@ var validateCmd = &cobra.Command{
 
 This command checks:
   - YAML syntax
+  - Unknown settings, such as misspelt ones, which other commands ignore
+  - Value types and ranges, in the profiles too
   - Required fields
-  - Value types and ranges
-  - Path accessibility`,
+  - Path accessibility
+
+Problems found in the file are reported with their line numbers.`,
 	Run: runConfigValidate,
 }
 
@@ -68,6 +113,7 @@ func init() {
 	configCmd.AddCommand(initCmd)
 	configCmd.AddCommand(showCmd)
 	configCmd.AddCommand(validateCmd)
+	configCmd.AddCommand(migrateCmd)
 }
 
 func runConfigInit(cmd *cobra.Command, args []string) {
@@ -88,10 +134,13 @@ func runConfigInit(cmd *cobra.Command, args []string) {
 	// Create example configuration
 	exampleConfig := `# Instagram Scraper Configuration File
 # 
-# This file contains all available configuration options.
+# This file contains the most used configuration options.
 # You can also use environment variables prefixed with IGSCRAPER_
 # For example: IGSCRAPER_SESSION_ID, IGSCRAPER_CSRF_TOKEN
 
+# Layout of this file, upgraded by 'igscraper config migrate'
+version: ` + strconv.Itoa(config.CurrentVersion) + `
+
 # Instagram credentials
 instagram:
   # Session ID from Instagram cookies (required)
@@ -105,22 +154,51 @@ instagram:
   # Leave empty to use default
   user_agent: ""
 
-# Download configuration
-download:
+# Output configuration
+output:
   # Output directory for downloads
-  # Default: current directory
-  output: "./downloads"
+  base_directory: "./downloads"
+  
+  # Create a folder per user
+  create_user_folders: true
+  
+  # Download saved posts again, replacing their files
+  overwrite_existing: false
   
+  # Download saved posts taken within this long again when their caption,
+  # alt text, location or tags were edited since they were saved; 0 disables
+  refresh_newer_than: 0
+  
+  # Save a copy of each photo this many pixels on its longer side in
+  # .thumbnails/ for browsing large archives; 0 disables
+  gallery_thumbnails: 0
+  
+  # When a profile is already archived in another folder: warn, or use
+  # that folder instead of downloading everything again
+  existing_archive: "warn"
+
+# Download configuration
+download:
   # Number of concurrent downloads
   # Range: 1-10
   concurrent_downloads: 3
   
-  # Download timeout in seconds
-  # Range: 10-300
-  timeout: 30
+  # Download timeout
+  download_timeout: 30s
   
-  # Overwrite existing files
-  overwrite_existing: false
+  # Longest a single download may take, retries included, before it is
+  # cancelled and queued again behind the others; 0 for no limit
+  job_timeout: 5m
+  
+  # Wait for downloads in progress when stopped with Ctrl+C
+  shutdown_timeout: 30s
+  
+  # Times a failed download is queued again
+  retry_attempts: 3
+  
+  # Skip files smaller or larger than this many bytes; 0 for no limit
+  min_file_size: 0
+  max_file_size: 0
 
 # Rate limiting configuration
 rate_limit:
@@ -128,9 +206,6 @@ rate_limit:
   # Range: 1-120
   requests_per_minute: 60
   
-  # Enable burst mode for faster initial downloads
-  burst_enabled: true
-  
   # Burst size (number of requests allowed in burst)
   burst_size: 10
 
@@ -138,24 +213,47 @@ rate_limit:
 retry:
   # Maximum number of retry attempts
   # Range: 0-10
-  max_retries: 3
+  max_attempts: 3
   
-  # Initial backoff duration in seconds
-  initial_backoff: 1
+  # Initial backoff duration
+  base_delay: 1s
   
-  # Maximum backoff duration in seconds
-  max_backoff: 60
+  # Maximum backoff duration
+  max_delay: 60s
   
   # Backoff multiplier
   multiplier: 2.0
 
+# Notification configuration
+notifications:
+  # Enable desktop notifications
+  enabled: true
+  
+  # Where notifications are shown: terminal, desktop or none
+  notification_type: "terminal"
+
+# Commands run at points of a scrape, through the shell, with the hook's
+# context as JSON on stdin. Empty commands run nothing.
+hooks:
+  # After every file is saved, e.g. "rclone copy \"$(jq -r .path)\" remote:ig"
+  post_download: ""
+  
+  # When a profile or feed has been scraped
+  on_complete: ""
+  
+  # When a download or a scrape fails
+  on_error: ""
+  
+  # Each command is stopped after this long
+  timeout: 1m
+
 # Logging configuration
 logging:
   # Log level: debug, info, warn, error
   level: "info"
   
-  # Log format: text, json
-  format: "text"
+  # Console format: console, json
+  format: "console"
   
   # Log file path (optional)
   # Leave empty to log to stdout only
@@ -170,39 +268,20 @@ logging:
   # Maximum age of log files in days
   max_age: 30
 
-# UI configuration
-ui:
-  # Enable colored output
-  color_enabled: true
-  
-  # Enable progress bars
-  progress_enabled: true
-  
-  # Enable desktop notifications
-  notifications_enabled: true
-  
-  # Show download speed
-  show_speed: true
-  
-  # Update interval in milliseconds
-  update_interval: 100
-
-# Storage configuration
-storage:
-  # Create user directories
-  create_user_dir: true
-  
-  # Directory permissions (octal)
-  dir_permissions: "0755"
-  
-  # File permissions (octal)
-  file_permissions: "0644"
+# Named presets, selected with --profile NAME
+# Each one merges its settings over the ones above
+profiles:
+  slow:
+    rate_limit:
+      requests_per_minute: 20
+    download:
+      concurrent_downloads: 1
   
-  # Save metadata alongside downloads
-  save_metadata: true
-  
-  # Metadata format: json, yaml
-  metadata_format: "json"
+  aggressive:
+    rate_limit:
+      requests_per_minute: 120
+    download:
+      concurrent_downloads: 8
 `
 
 	// Write configuration file
@@ -220,7 +299,7 @@ storage:
 
 func runConfigShow(cmd *cobra.Command, args []string) {
 	// Load configuration
-	cfg, err := config.Load(configFile, nil)
+	cfg, err := config.Load(configFile, profileFlags())
 	if err != nil {
 		ui.PrintError("Failed to load configuration", err.Error())
 		os.Exit(1)
@@ -245,6 +324,10 @@ func runConfigShow(cmd *cobra.Command, args []string) {
 			displayCfg.Instagram.CSRFToken = "***"
 		}
 	}
+	
+	if displayCfg.Notifications.Email.Password != "" {
+		displayCfg.Notifications.Email.Password = "***"
+	}
 
 	// Convert to YAML for display
 	data, err := yaml.Marshal(&displayCfg)
@@ -261,26 +344,29 @@ func runConfigShow(cmd *cobra.Command, args []string) {
 	fmt.Println("\nConfiguration sources (in order of priority):")
 	fmt.Println("1. Command line flags")
 	fmt.Println("2. Environment variables (IGSCRAPER_*)")
+	if profile := selectedProfile(); profile != "" {
+		fmt.Printf("3. Profile: %s\n", profile)
+	} else if names := cfg.ProfileNames(); len(names) > 0 {
+		fmt.Printf("3. Profile: (none selected, available: %s)\n", strings.Join(names, ", "))
+	} else {
+		fmt.Println("3. Profile: (none)")
+	}
 	if configFile != "" {
-		fmt.Printf("3. Configuration file: %s\n", configFile)
+		fmt.Printf("4. Configuration file: %s\n", configFile)
 	} else {
-		fmt.Println("3. Configuration file: (not specified)")
+		fmt.Println("4. Configuration file: (not specified)")
 	}
-	fmt.Println("4. Default values")
+	fmt.Println("5. Default values")
 }
 
 func runConfigValidate(cmd *cobra.Command, args []string) {
 	// Check if config file is specified
 	if configFile == "" {
 		// Try to find config file in common locations
-		possiblePaths := []string{
+		possiblePaths := append([]string{
 			"igscraper.yaml",
 			"igscraper.yml",
-			".igscraper.yaml",
-			".igscraper.yml",
-			filepath.Join(os.Getenv("HOME"), ".igscraper.yaml"),
-			filepath.Join(os.Getenv("HOME"), ".config", "igscraper", "config.yaml"),
-		}
+		}, config.SearchPaths()...)
 		
 		for _, path := range possiblePaths {
 			if _, err := os.Stat(path); err == nil {
@@ -297,8 +383,23 @@ func runConfigValidate(cmd *cobra.Command, args []string) {
 
 	ui.PrintInfo("Validating configuration", configFile)
 
+	// Check every setting in the file, which loading it would not: unknown
+	// settings are ignored and values are only checked once merged
+	issues, err := config.CheckFile(configFile)
+	if err != nil {
+		ui.PrintError("Configuration validation failed", err.Error())
+		os.Exit(1)
+	}
+	if len(issues) > 0 {
+		ui.PrintError("Configuration has errors:", "")
+		for _, issue := range issues {
+			fmt.Printf("  - %s\n", issue)
+		}
+		os.Exit(1)
+	}
+
 	// Try to load and validate configuration
-	cfg, err := config.Load(configFile, nil)
+	cfg, err := config.Load(configFile, profileFlags())
 	if err != nil {
 		ui.PrintError("Configuration validation failed", err.Error())
 		os.Exit(1)
@@ -308,6 +409,11 @@ func runConfigValidate(cmd *cobra.Command, args []string) {
 	warnings := []string{}
 	errors := []string{}
 
+	// Settings of an older layout still work, but should be migrated
+	for _, d := range cfg.Deprecated() {
+		warnings = append(warnings, d.String()+" (run 'igscraper config migrate')")
+	}
+
 	// Check credentials
 	if cfg.Instagram.SessionID == "" || cfg.Instagram.SessionID == "YOUR_SESSION_ID" {
 		warnings = append(warnings, "Instagram session ID not configured")
@@ -368,4 +474,37 @@ func runConfigValidate(cmd *cobra.Command, args []string) {
 	fmt.Printf("  Rate limit: %d requests/minute\n", cfg.RateLimit.RequestsPerMinute)
 	fmt.Printf("  Max retries: %d\n", cfg.Retry.MaxAttempts)
 	fmt.Printf("  Log level: %s\n", cfg.Logging.Level)
-}
\ No newline at end of file
+}
+
+func runConfigMigrate(cmd *cobra.Command, args []string) {
+	path := config.FindFile(configFile)
+	if path == "" {
+		ui.PrintError("No configuration file found", "Specify a file with --config flag")
+		os.Exit(1)
+	}
+
+	deprecated, err := config.MigrateFile(path)
+	if err != nil {
+		ui.PrintError("Failed to migrate configuration", err.Error())
+		os.Exit(1)
+	}
+	if len(deprecated) == 0 {
+		ui.PrintSuccess(fmt.Sprintf("Configuration file is up to date (version %d): %s", config.CurrentVersion, path))
+		return
+	}
+
+	for _, d := range deprecated {
+		fmt.Printf("  - %s\n", d)
+	}
+	ui.PrintSuccess(fmt.Sprintf("Configuration file migrated to version %d: %s", config.CurrentVersion, path))
+	ui.PrintInfo("Original kept as", path+".bak")
+}
+
+// selectedProfile returns the profile merged over the config file, from
+// --profile or IGSCRAPER_PROFILE
+func selectedProfile() string {
+	if configProfile != "" {
+		return configProfile
+	}
+	return os.Getenv("IGSCRAPER_PROFILE")
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"igscraper/pkg/config"
+	"igscraper/pkg/daemon"
+	"igscraper/pkg/logger"
+	"igscraper/pkg/ratelimit"
+	"igscraper/pkg/scraper"
+	"igscraper/pkg/ui"
+)
+
+var (
+	// Daemon command flags
+	metricsAddr   string
+	daemonWebAddr string
+)
+
+// daemonCmd represents the daemon command
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Scrape a list of profiles on a schedule",
+	Long: `Run igscraper as a long-running service that scrapes the profiles listed
+in the daemon section of the configuration file on a schedule.
+
+Each profile is scraped when the daemon starts and again whenever its interval
+elapses. Runs are incremental: photos already on disk are skipped and an
+interrupted run resumes from its checkpoint. Scrapes run one at a time and
+share a single rate limiter, so the rate_limit settings are a global budget.
+Set download.skip_synced_within to skip profiles with no new posts since
+their last complete sync without walking their timeline.
+
+CONFIGURATION:
+  daemon:
+    default_interval: 6h
+    status_interval: 5m
+    metrics_addr: "127.0.0.1:9090"
+    profiles:
+      - username: natgeo
+        interval: 1h
+      - username: nasa
+
+STATUS:
+  Every run is logged with its duration and outcome, and a summary is logged
+  every status_interval. When metrics_addr is set, /status serves the schedule
+  as JSON and /metrics serves counters in the Prometheus text format. With
+  --web, a page on that address shows the running scrape's downloads and logs.
+
+RELOADING:
+  The configuration file is watched while the daemon runs. Changes to
+  rate_limit.requests_per_minute, download.max_bandwidth, logging.level,
+  logging.levels and the notifications section are applied without a restart:
+  the rate and bandwidth limits and log levels at once, notifications from the
+  next scrape. Other changes are logged as needing a restart and ignored.`,
+	Example: `  # Run with the profiles from the default config file
+  igscraper daemon
+
+  # Use a dedicated config file and expose metrics
+  igscraper daemon --config daemon.yaml --metrics-addr 127.0.0.1:9090
+
+  # Watch the scrapes from a browser
+  igscraper daemon --web 127.0.0.1:8080`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		runDaemon(cmd, args)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(daemonCmd)
+
+	// Local flags for daemon command
+	daemonCmd.Flags().StringVar(&metricsAddr, "metrics-addr", "", "address for the /status and /metrics endpoints (overrides config)")
+	daemonCmd.Flags().StringVar(&daemonWebAddr, "web", "", "serve a progress page of the running scrapes on this address (e.g. 127.0.0.1:8080)")
+	daemonCmd.Flags().StringVarP(&accountName, "account", "a", "", "use specific stored account")
+	daemonCmd.Flags().BoolVar(&skipSessionCheck, "skip-session-check", false, "do not check that the session cookies are valid at startup")
+	daemonCmd.Flags().BoolVar(&rotateAccounts, "rotate-accounts", false, "switch to another stored account when the current one is repeatedly rate limited or logged out")
+	daemonCmd.Flags().DurationVar(&keepAlive, "keep-alive", 0, "check every so often (e.g. 15m) that the session is still logged in while a profile is scraped")
+}
+
+func runDaemon(cmd *cobra.Command, args []string) {
+	// Logs are the daemon's output, so keep them unless a level was requested
+	// or --quiet leaves only errors
+	flags := profileFlags()
+	if cmd.Flags().Changed("log-level") || quiet {
+		flags["log-level"] = logLevel
+	}
+	if auditLog != "" {
+		flags["audit-log"] = auditLog
+	}
+	if !notifications {
+		flags["notifications-enabled"] = false
+	}
+	if rotateAccounts {
+		flags["rotate-accounts"] = true
+	}
+	if keepAlive > 0 {
+		flags["keep-alive"] = keepAlive
+	}
+
+	loadConfig := func() (*config.Config, error) {
+		cfg, err := config.Load(configFile, flags)
+		if err != nil {
+			return nil, err
+		}
+		if metricsAddr != "" {
+			cfg.Daemon.MetricsAddr = metricsAddr
+		}
+		return cfg, nil
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		ui.PrintError("Failed to load configuration", err.Error())
+		os.Exit(1)
+	}
+	loaded := *cfg
+
+	logger.Initialize(&cfg.Logging)
+	initAuditLog(cfg)
+	warnDeprecatedConfig(cfg)
+	logger.WithField("version", version).Info("Instagram Scraper daemon starting")
+
+	applyCredentials(cfg)
+
+	// The scraper's progress output is meant for a terminal, not a service
+	ui.SetQuietMode(true)
+	ui.SetProgressOnlyMode(false)
+
+	// One limiter shared by every run keeps the whole daemon within budget
+	// (and with other processes when rate_limit.shared_file is set)
+	limiter, err := scraper.NewRateLimiter(cfg)
+	if err != nil {
+		ui.PrintError("Invalid rate limit settings", err.Error())
+		os.Exit(1)
+	}
+
+	// Likewise one worker pool downloads for every profile
+	pool := scraper.NewWorkerPool(cfg, logger.Named(logger.ComponentDownloader))
+	pool.Start()
+	defer pool.Stop()
+
+	// and one bandwidth limit covers them all
+	maxBandwidth, _ := ratelimit.ParseBandwidth(cfg.Download.MaxBandwidth)
+	bandwidth := ratelimit.NewBandwidth(maxBandwidth)
+
+	if daemonWebAddr != "" {
+		server, err := startWebServer(daemonWebAddr)
+		if err != nil {
+			ui.PrintError("Failed to start progress page", err.Error())
+			os.Exit(1)
+		}
+		defer server.Close()
+		for _, profile := range cfg.Daemon.Profiles {
+			webMonitor.AddProfiles(profile.Username)
+		}
+	}
+
+	live := &liveConfig{
+		cfg:       cfg,
+		loaded:    &loaded,
+		limiter:   limiter,
+		bandwidth: bandwidth,
+		log:       logger.Named(logger.ComponentDaemon),
+	}
+
+	d, err := daemon.New(cfg.Daemon, func(ctx context.Context, username string) error {
+		cfg := live.current()
+		s, err := scraper.New(cfg)
+		if err != nil {
+			return err
+		}
+		s.SetContext(ctx)
+		s.SetRateLimiter(limiter)
+		s.SetWorkerPool(pool)
+		s.SetBandwidth(bandwidth)
+		if credentialManager != nil {
+			s.SetSessionStore(credentialManager, storedAccount)
+		}
+		if accountRotator != nil {
+			s.SetAccountRotator(accountRotator)
+		}
+		if headlessBrowser != nil {
+			s.SetBrowserFallback(headlessBrowser)
+		}
+		s.SetSkipSynced(cfg.Download.SkipSyncedWithin)
+		if webMonitor != nil {
+			s.SetTUI(webMonitor)
+		}
+		return s.DownloadUserPhotosWithResume(username, true, false)
+	}, logger.Named(logger.ComponentDaemon))
+	if err != nil {
+		ui.PrintError("Failed to start daemon", err.Error())
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		// Restore default signal handling so a second interrupt exits at once
+		<-ctx.Done()
+		logger.Info("Shutdown requested, waiting for the current scrape to finish")
+		stop()
+	}()
+
+	if path := config.FindFile(configFile); path != "" {
+		live.log.WithField("file", path).Debug("Watching the config file for changes")
+		go config.Watch(ctx, path, config.DefaultWatchInterval, func() {
+			live.reload(loadConfig)
+		})
+	}
+
+	if err := d.Run(ctx); err != nil {
+		logger.WithError(err).Error("Daemon failed")
+		ui.PrintError("Daemon failed", err.Error())
+		os.Exit(1)
+	}
+}
+
+// liveConfig is the configuration of a running daemon, which follows the
+// config file as it is edited. Each scrape works on a copy taken as it
+// starts.
+type liveConfig struct {
+	mu        sync.Mutex
+	cfg       *config.Config // in use, with the credentials applied
+	loaded    *config.Config // as read from the file, less the changes ignored
+	limiter   ratelimit.Limiter
+	bandwidth *ratelimit.Bandwidth
+	log       logger.Logger
+}
+
+// current returns a copy of the configuration for a scrape
+func (l *liveConfig) current() *config.Config {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	cfg := *l.cfg
+	return &cfg
+}
+
+// reload reads the config file again and applies the settings that can
+// change while scrapes run. A file that fails to load or validate changes
+// nothing, and other settings keep their values until the daemon restarts.
+func (l *liveConfig) reload(load func() (*config.Config, error)) {
+	updated, err := load()
+	if err != nil {
+		l.log.WithError(err).Warn("Ignoring the edited config file")
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var applied, ignored []string
+	for _, setting := range config.Changes(l.loaded, updated) {
+		if config.Reloadable(setting) {
+			applied = append(applied, setting)
+		} else {
+			ignored = append(ignored, setting)
+		}
+	}
+	if len(ignored) > 0 {
+		l.log.WithField("settings", strings.Join(ignored, ", ")).Warn("Config changes need a restart and were ignored")
+	}
+	if len(applied) == 0 {
+		return
+	}
+
+	for _, cfg := range []*config.Config{l.cfg, l.loaded} {
+		cfg.RateLimit.RequestsPerMinute = updated.RateLimit.RequestsPerMinute
+		cfg.Download.MaxBandwidth = updated.Download.MaxBandwidth
+		cfg.Logging.Level = updated.Logging.Level
+		cfg.Logging.Levels = updated.Logging.Levels
+		cfg.Notifications = updated.Notifications
+	}
+	if limiter, ok := l.limiter.(ratelimit.Adjustable); ok && updated.RateLimit.RequestsPerMinute > 0 {
+		limiter.SetCapacity(updated.RateLimit.RequestsPerMinute)
+	}
+	maxBandwidth, _ := ratelimit.ParseBandwidth(updated.Download.MaxBandwidth)
+	l.bandwidth.SetLimit(maxBandwidth)
+	if err := logger.SetLevels(&updated.Logging); err != nil {
+		l.log.WithError(err).Warn("Failed to change the log level")
+	}
+	l.log.WithField("settings", strings.Join(applied, ", ")).Info("Reloaded the config file")
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"igscraper/pkg/config"
+	"igscraper/pkg/demo"
+	"igscraper/pkg/instagram"
+	"igscraper/pkg/logger"
+	"igscraper/pkg/scraper"
+	"igscraper/pkg/ui"
+)
+
+var (
+	// Demo command flags
+	demoOutputDir string
+	demoLatency   time.Duration
+)
+
+// demoCmd represents the demo command
+var demoCmd = &cobra.Command{
+	Use:   "demo",
+	Short: "Run a full download against built-in sample data",
+	Long: `Run the complete download pipeline against a fictional profile bundled
+with igscraper. No Instagram account, credentials or network access are needed.
+
+The demo starts a local server that answers the same requests Instagram would,
+then downloads the sample profile with the regular scraper: pagination,
+concurrent workers, rate limiting, checkpoints, metadata and the TUI all behave
+exactly as they do for a real profile.
+
+OUTPUT:
+  By default, photos are saved to a new temporary directory which is printed
+  when the demo finishes. Use --output to choose a location instead.`,
+	Example: `  # Run the demo with progress output
+  igscraper demo
+
+  # Watch the demo in the terminal UI
+  igscraper demo --tui
+
+  # Slow the sample server down and keep the photos
+  igscraper demo --latency 300ms --output ./demo_photos`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		runDemo(cmd, args)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(demoCmd)
+
+	// Local flags for demo command
+	demoCmd.Flags().StringVarP(&demoOutputDir, "output", "o", "", "output directory for downloads (default: temporary directory)")
+	demoCmd.Flags().DurationVar(&demoLatency, "latency", 100*time.Millisecond, "artificial delay added to every sample server response")
+	demoCmd.Flags().IntVar(&concurrent, "concurrent", 3, "number of concurrent downloads")
+	demoCmd.Flags().BoolVar(&useTUI, "tui", false, "use interactive terminal UI with real-time progress")
+}
+
+func runDemo(cmd *cobra.Command, args []string) {
+	server, err := demo.NewServer()
+	if err != nil {
+		ui.PrintError("Failed to start demo server", err.Error())
+		os.Exit(1)
+	}
+	defer server.Close()
+	server.SetLatency(demoLatency)
+
+	outputBase := demoOutputDir
+	if outputBase == "" {
+		outputBase, err = os.MkdirTemp("", "igscraper-demo-")
+		if err != nil {
+			ui.PrintError("Failed to create output directory", err.Error())
+			os.Exit(1)
+		}
+	}
+
+	// The demo never reads the user's config file or stored credentials
+	cfg := config.DefaultConfig()
+	cfg.Instagram.SessionID = "demo"
+	cfg.Instagram.CSRFToken = "demo"
+	cfg.Output.BaseDirectory = outputBase
+	cfg.Download.ConcurrentDownloads = concurrent
+	cfg.RateLimit.RequestsPerMinute = 6000
+	cfg.Notifications.Enabled = false
+	if logLevel != "info" {
+		cfg.Logging.Level = logLevel
+	}
+	cfg.Logging.AuditFile = auditLog
+
+	logger.Initialize(&cfg.Logging)
+	initAuditLog(cfg)
+	logger.WithField("version", version).Info("Instagram Scraper demo starting")
+
+	if !useTUI {
+		ui.PrintInfo("Demo Profile", demo.Username)
+		ui.PrintInfo("Output Directory", outputBase)
+	}
+
+	client := instagram.NewClientWithConfig(cfg.Download.DownloadTimeout, &cfg.Retry, logger.Named(logger.ComponentInstagram))
+	client.SetTransport(server.Transport())
+
+	// Always start from scratch so repeated demos never prompt about checkpoints
+	err = runScraper(cfg, demo.Username, false, true, func(s *scraper.Scraper) {
+		s.SetClient(client)
+	})
+	if err != nil {
+		os.Exit(1)
+	}
+
+	ui.PrintInfo("Photos saved to", outputBase)
+}
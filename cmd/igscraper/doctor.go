@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"igscraper/pkg/auth"
+	"igscraper/pkg/config"
+	"igscraper/pkg/doctor"
+	"igscraper/pkg/ui"
+)
+
+var (
+	// Doctor command flags
+	doctorTarget string
+)
+
+// doctorCmd represents the doctor command
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose rate limiting and session problems",
+	Long: `Send a few test requests to Instagram and explain why scraping is failing.
+
+The doctor checks, one request each:
+  • The profile API without cookies
+  • The profile API with your session
+  • The media API with your session
+  • A photo download from the CDN
+
+Comparing the answers tells apart an expired session, a rate-limited account,
+a throttled IP address and a flagged account, and each finding comes with
+suggested next steps. Requests are not retried, so running the doctor does not
+make rate limiting worse.`,
+	Example: `  # Check the default account
+  igscraper doctor
+
+  # Check a specific stored account against a different public profile
+  igscraper doctor --account myaccount --target natgeo`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		runDoctor(cmd, args)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+
+	// Local flags for doctor command
+	doctorCmd.Flags().StringVar(&doctorTarget, "target", "instagram", "public profile used for the test requests")
+	doctorCmd.Flags().StringVarP(&accountName, "account", "a", "", "use specific stored account")
+}
+
+func runDoctor(cmd *cobra.Command, args []string) {
+	// Missing credentials are a finding, not an error, so skip validation
+	cfg := config.DefaultConfig()
+	if err := cfg.LoadFromFile(configFile); err != nil {
+		ui.PrintError("Failed to load configuration", err.Error())
+		os.Exit(1)
+	}
+	if err := cfg.LoadFromEnv(); err != nil {
+		ui.PrintError("Failed to load configuration", err.Error())
+		os.Exit(1)
+	}
+	if auditLog != "" {
+		cfg.Logging.AuditFile = auditLog
+	}
+	initAuditLog(cfg)
+
+	account := loadDoctorAccount(cfg)
+
+	d := doctor.New(cfg, doctorTarget)
+
+	fmt.Println(ui.Magenta("Instagram connection check"))
+	switch {
+	case account != "":
+		fmt.Printf("%s %s\n", ui.Cyan("Account:"), account)
+	case d.HasCredentials():
+		fmt.Printf("%s %s\n", ui.Cyan("Account:"), "credentials from configuration")
+	default:
+		fmt.Printf("%s %s\n", ui.Cyan("Account:"), ui.Dim("none"))
+	}
+	fmt.Printf("%s %s\n\n", ui.Cyan("Target:"), doctorTarget)
+
+	results := d.Run()
+	for _, r := range results {
+		printProbeResult(r)
+	}
+
+	fmt.Println()
+	findings := doctor.Diagnose(results)
+	for _, f := range findings {
+		printFinding(f)
+	}
+
+	for _, f := range findings {
+		if f.Severity == doctor.SeverityProblem {
+			os.Exit(1)
+		}
+	}
+}
+
+// loadDoctorAccount applies stored credentials when the configuration has
+// none, and returns the account name that was used
+func loadDoctorAccount(cfg *config.Config) string {
+	hasConfigCredentials := cfg.Instagram.SessionID != "" && cfg.Instagram.CSRFToken != "" &&
+		cfg.Instagram.SessionID != "YOUR_SESSION_ID" && cfg.Instagram.CSRFToken != "YOUR_CSRF_TOKEN"
+	if accountName == "" && hasConfigCredentials {
+		return ""
+	}
+
+	credManager, err := auth.NewManager()
+	if err != nil {
+		ui.PrintError("Failed to initialize credential manager", err.Error())
+		os.Exit(1)
+	}
+
+	var account *auth.Account
+	if accountName != "" {
+		account, err = credManager.Retrieve(accountName)
+		if err != nil {
+			ui.PrintError("Account not found", accountName)
+			os.Exit(1)
+		}
+	} else if account, err = credManager.RetrieveDefault(); err != nil {
+		return ""
+	}
+
+	cfg.Instagram.SessionID = account.SessionID
+	cfg.Instagram.CSRFToken = account.CSRFToken
+	applyDevice(cfg, account)
+	return account.Username
+}
+
+// printProbeResult prints one line per probe
+func printProbeResult(r doctor.Result) {
+	label := fmt.Sprintf("%-20s", r.Name)
+
+	switch {
+	case r.Skipped != "":
+		fmt.Printf("  %s %s %s\n", ui.Dim("-"), label, ui.Dim("skipped: "+r.Skipped))
+		return
+	case r.Err != nil:
+		fmt.Printf("  %s %s %s\n", ui.Red("✗"), label, ui.Red(r.Err.Error()))
+		return
+	}
+
+	status := fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode))
+	latency := ui.Dim(r.Latency.Round(time.Millisecond).String())
+	switch {
+	case r.OK():
+		fmt.Printf("  %s %s %s %s\n", ui.Green("✓"), label, status, latency)
+	case r.Challenge != "":
+		fmt.Printf("  %s %s %s %s\n", ui.Red("✗"), label, ui.Red(status+", "+r.Challenge), latency)
+	case r.RequiresLogin:
+		fmt.Printf("  %s %s %s %s\n", ui.Red("✗"), label, ui.Red(status+", login required"), latency)
+	default:
+		fmt.Printf("  %s %s %s %s\n", ui.Red("✗"), label, ui.Red(status), latency)
+	}
+}
+
+// printFinding prints a finding with its next steps
+func printFinding(f doctor.Finding) {
+	switch f.Severity {
+	case doctor.SeverityOK:
+		fmt.Println(ui.Green("✓ " + f.Title))
+	case doctor.SeverityWarning:
+		fmt.Println(ui.Yellow("! " + f.Title))
+	default:
+		fmt.Println(ui.Red("✗ " + f.Title))
+	}
+	fmt.Printf("  %s\n", f.Detail)
+	for _, step := range f.NextSteps {
+		fmt.Printf("  → %s\n", step)
+	}
+	fmt.Println()
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"igscraper/pkg/config"
+	"igscraper/pkg/logger"
+	"igscraper/pkg/scraper"
+	"igscraper/pkg/ui"
+)
+
+// runEstimate prints what scraping usernames one after another would cost
+// under the configured rate limit, without downloading anything. Profiles
+// that cannot be estimated are reported and left out of the total.
+func runEstimate(cfg *config.Config, usernames []string) error {
+	s, err := scraper.New(cfg)
+	if err != nil {
+		ui.PrintError("Failed to initialize scraper", err.Error())
+		return err
+	}
+
+	var estimates []*scraper.Estimate
+	var failed int
+	for _, username := range usernames {
+		estimate, err := s.EstimateProfile(username)
+		if err != nil {
+			logger.WithError(err).WithField("username", username).Error("Estimate failed")
+			ui.PrintError("Failed to estimate "+username, err.Error())
+			failed++
+			continue
+		}
+		estimates = append(estimates, estimate)
+	}
+	if len(estimates) > 0 {
+		printEstimates(cfg, estimates, time.Now())
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d profiles could not be estimated", failed, len(usernames))
+	}
+	return nil
+}
+
+// printEstimates prints each profile's estimate followed by the total for
+// the whole run, assuming it starts at start
+func printEstimates(cfg *config.Config, estimates []*scraper.Estimate, start time.Time) {
+	var apiCalls, downloads int
+	var duration time.Duration
+	fmt.Println()
+	for _, e := range estimates {
+		fmt.Printf("%s @%s\n", ui.Magenta("Profile"), e.Username)
+		fmt.Printf("  %s %d posts, %d already downloaded\n", ui.Cyan("Posts:"), e.Posts, e.Archived)
+		fmt.Printf("  %s %d\n", ui.Cyan("API calls:"), e.APICalls)
+		fmt.Printf("  %s at least %d\n", ui.Cyan("Downloads:"), e.Downloads)
+		fmt.Printf("  %s %s %s\n", ui.Cyan("Time:"), formatEstimate(e.Duration),
+			ui.Dim(fmt.Sprintf("(%s waiting for the rate limit)", formatEstimate(e.RateLimitWait))))
+		apiCalls += e.APICalls
+		downloads += e.Downloads
+		duration += e.Duration
+	}
+
+	finish := start.Add(duration)
+	fmt.Println()
+	fmt.Printf("%s %d API calls, at least %d downloads at %d requests per minute with %d workers\n",
+		ui.Magenta("Total"), apiCalls, downloads, cfg.RateLimit.RequestsPerMinute, cfg.Download.ConcurrentDownloads)
+	fmt.Printf("  %s %s\n", ui.Cyan("Time:"), formatEstimate(duration))
+	fmt.Printf("  %s %s\n", ui.Cyan("Finishes:"), formatFinish(start, finish))
+	fmt.Println(ui.Dim("  Carousels, --since/--until and --filter change the actual number of downloads."))
+}
+
+// formatEstimate rounds an estimated duration to the nearest minute, or to
+// seconds below one minute
+func formatEstimate(d time.Duration) string {
+	if d < time.Minute {
+		return d.Round(time.Second).String()
+	}
+	return d.Round(time.Minute).String()
+}
+
+// formatFinish shows the time of day a run would finish, with the date when
+// that is not the day it starts
+func formatFinish(start, finish time.Time) string {
+	if finish.YearDay() == start.YearDay() && finish.Year() == start.Year() {
+		return "around " + finish.Format("15:04")
+	}
+	return "around " + finish.Format("Mon 2006-01-02 15:04")
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"igscraper/pkg/export"
+	"igscraper/pkg/ui"
+)
+
+var (
+	// Export command flags
+	exportFormat      string
+	exportOutput      string
+	exportImagePrefix string
+)
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export <archive-dir>",
+	Short: "Export a downloaded archive for static site generators",
+	Long: `Convert a folder downloaded by igscraper into files a static site generator
+can publish.
+
+The markdown format writes one file per post, named <date>-<shortcode>.md:
+  • Front matter: title, date, likes, comments, location, hashtags as tags,
+    the Instagram URL and the image path
+  • Body: the caption, followed by a link to the photo
+
+The html format writes index.html, the static gallery of the gallery command.
+
+The files work as Jekyll's _posts and as a Hugo content section. Image links
+are relative to the output directory unless --image-prefix is set, for sites
+that copy the photos into their static folder. Posts whose photo is not on
+disk are skipped, and files from an earlier export are overwritten.`,
+	Example: `  # Write Jekyll posts that link to the archive
+  igscraper export ./username_photos --output ./blog/_posts
+
+  # Hugo, serving the photos from static/images/instagram
+  igscraper export ./username_photos -o ./site/content/posts --image-prefix /images/instagram`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		runExport(cmd, args)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+
+	// Local flags for export command
+	flags := exportCmd.Flags()
+	flags.StringVarP(&exportFormat, "format", "f", "markdown", "export format ("+strings.Join(export.Formats(), ", ")+")")
+	flags.StringVarP(&exportOutput, "output", "o", "export", "directory to write the exported files to")
+	flags.StringVar(&exportImagePrefix, "image-prefix", "", "URL path the site serves the photos from (default: paths relative to the output directory)")
+}
+
+func runExport(cmd *cobra.Command, args []string) {
+	exporter, err := export.New(exportFormat, export.Options{ImagePrefix: exportImagePrefix})
+	if err != nil {
+		ui.PrintError("Invalid export format", err.Error())
+		os.Exit(1)
+	}
+
+	archive, err := export.LoadArchive(args[0])
+	if err != nil {
+		ui.PrintError("Failed to load archive", err.Error())
+		os.Exit(1)
+	}
+
+	written, err := exporter.Export(archive, exportOutput)
+	if err != nil {
+		ui.PrintError("Export failed", err.Error())
+		os.Exit(1)
+	}
+
+	if !quiet {
+		fmt.Println(ui.Green(fmt.Sprintf("✓ Exported %d posts from @%s to %s", written, archive.Metadata.Username, exportOutput)))
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"igscraper/pkg/config"
+	"igscraper/pkg/export"
+	"igscraper/pkg/logger"
+	"igscraper/pkg/scraper"
+	"igscraper/pkg/ui"
+)
+
+// galleryDest is where the gallery command writes index.html
+var galleryDest string
+
+// galleryCmd represents the gallery command
+var galleryCmd = &cobra.Command{
+	Use:   "gallery <username>",
+	Short: "Render a downloaded profile as a static HTML gallery",
+	Long: `Write index.html into a profile's folder: a grid of its downloaded posts
+with their captions, dates, likes and locations, each linking to the original
+file and to the post on Instagram. It is made from metadata.json without any
+request to Instagram.
+
+The page links to the files with relative paths, so the folder can be browsed
+offline or copied elsewhere whole. Photos saved with --gallery-thumbnails are
+shown from their small copies in .thumbnails/, which keeps large archives
+quick to open. Running it again replaces the page.`,
+	Example: `  # Write ./username_photos/index.html
+  igscraper gallery username
+
+  # Of a profile archived elsewhere, with the page next to the archive
+  igscraper gallery username --output ./archive --dest ./site`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		runGallery(cmd, args)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(galleryCmd)
+
+	// Local flags for gallery command
+	flags := galleryCmd.Flags()
+	flags.StringVarP(&outputDir, "output", "o", "", "output directory the profile was scraped into (default: current directory)")
+	flags.StringVar(&galleryDest, "dest", "", "directory to write index.html to (default: the profile's folder)")
+}
+
+func runGallery(cmd *cobra.Command, args []string) {
+	username := strings.TrimSpace(args[0])
+
+	cfg, err := config.Load(configFile, scrapeFlags())
+	if err != nil {
+		ui.PrintError("Failed to load configuration", err.Error())
+		os.Exit(1)
+	}
+	logger.Initialize(&cfg.Logging)
+
+	s, err := scraper.New(cfg)
+	if err != nil {
+		ui.PrintError("Failed to create scraper", err.Error())
+		os.Exit(1)
+	}
+
+	archive, err := export.LoadArchive(s.OutputDir(username))
+	if err != nil {
+		ui.PrintError("Failed to load archive", err.Error())
+		os.Exit(1)
+	}
+	dest := galleryDest
+	if dest == "" {
+		dest = archive.Dir
+	}
+
+	exporter, err := export.New("html", export.Options{})
+	if err != nil {
+		ui.PrintError("Gallery failed", err.Error())
+		os.Exit(1)
+	}
+	written, err := exporter.Export(archive, dest)
+	if err != nil {
+		ui.PrintError("Gallery failed", err.Error())
+		os.Exit(1)
+	}
+
+	if !quiet {
+		fmt.Println(ui.Green(fmt.Sprintf("✓ Wrote a gallery of %d posts from @%s to %s", written, archive.Metadata.Username, filepath.Join(dest, export.GalleryFile))))
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"igscraper/pkg/config"
+	"igscraper/pkg/instagram"
+	"igscraper/pkg/logger"
+	"igscraper/pkg/scraper"
+	"igscraper/pkg/ui"
+)
+
+var (
+	// Hashtag command flags
+	hashtagLimit int
+	hashtagTop   bool
+)
+
+// hashtagCmd represents the hashtag command
+var hashtagCmd = &cobra.Command{
+	Use:   "hashtag <tag>",
+	Short: "Download posts tagged with a hashtag",
+	Long: `Download the posts tagged with a hashtag into a #<tag>/ folder inside the
+output directory.
+
+By default the most recent posts are fetched, newest first; --top fetches the
+hashtag's top posts instead. Popular hashtags have millions of posts, so use
+--limit to stop after a number of posts.
+
+Each entry in #<tag>/metadata.json records the post's owner next to the usual
+caption and engagement details. Like the scrape command, runs are resumable,
+share the worker pool and rate limiter, and --since, --until and --filter
+select which posts are kept.`,
+	Example: `  # The 100 most recent posts tagged #sunset, into ./#sunset
+  igscraper hashtag sunset --limit 100
+
+  # The hashtag's top posts
+  igscraper hashtag '#sunset' --top
+
+  # Recent photos from this year only
+  igscraper hashtag sunset --since 2024-01-01 --filter 'type:photo'`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		runHashtag(cmd, args)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(hashtagCmd)
+
+	// Local flags for hashtag command
+	flags := hashtagCmd.Flags()
+	flags.IntVar(&hashtagLimit, "limit", 0, "stop after this many posts (0 = no limit)")
+	flags.BoolVar(&hashtagTop, "top", false, "download the hashtag's top posts instead of the most recent")
+	addFeedFlags(flags, "output directory; posts are saved to its #<tag>/ folder (default: current directory)")
+}
+
+func runHashtag(cmd *cobra.Command, args []string) {
+	tag, err := instagram.ParseHashtag(args[0])
+	if err != nil {
+		ui.PrintError("Invalid hashtag", err.Error())
+		os.Exit(1)
+	}
+	if hashtagLimit < 0 {
+		ui.PrintError("Invalid --limit value", "the limit cannot be negative")
+		os.Exit(1)
+	}
+
+	cfg, err := config.Load(configFile, scrapeFlags())
+	if err != nil {
+		ui.PrintError("Failed to load configuration", err.Error())
+		os.Exit(1)
+	}
+
+	logger.Initialize(&cfg.Logging)
+	initAuditLog(cfg)
+	warnDeprecatedConfig(cfg)
+	logger.WithField("version", version).Info("Instagram Scraper starting")
+
+	// Hashtag feeds are only available to logged in sessions
+	applyCredentials(cfg)
+
+	if !useTUI {
+		ui.PrintInfo("Target", "#"+tag+" → "+filepath.Join(cfg.Output.BaseDirectory, "#"+tag))
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"hashtag": tag,
+		"top":     hashtagTop,
+		"limit":   hashtagLimit,
+	}).Info("Starting hashtag download")
+	err = runDownload(cfg, "#"+tag, nil, func(s *scraper.Scraper) error {
+		return s.DownloadHashtag(tag, hashtagTop, hashtagLimit, resumeDownload, forceRestart)
+	})
+	if err != nil {
+		os.Exit(1)
+	}
+}
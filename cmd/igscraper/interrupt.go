@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// interruptContext returns the context scrapes run under. It is cancelled
+// by the first Ctrl+C or SIGTERM, which stops them once their downloads in
+// progress are done; default handling is restored then, so a second one
+// quits at once.
+var interruptContext = sync.OnceValue(func() context.Context {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx
+})
+
+// resumeCommand returns the command line args ran with, changed to resume
+// from the checkpoint, or "" if the command cannot resume
+func resumeCommand(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	cmd, _, err := rootCmd.Find(args[1:])
+	if err != nil || cmd.Flags().Lookup("resume") == nil {
+		return ""
+	}
+
+	words := []string{args[0]}
+	resume := false
+	for _, arg := range args[1:] {
+		switch arg {
+		case "--force-restart", "--force-restart=true":
+			continue
+		case "--resume", "--resume=true":
+			resume = true
+		case "--resume=false":
+			continue
+		}
+		words = append(words, shellQuote(arg))
+	}
+	if !resume {
+		words = append(words, "--resume")
+	}
+	return strings.Join(words, " ")
+}
+
+// shellQuote quotes arg for a POSIX shell if it needs it
+func shellQuote(arg string) string {
+	if arg != "" && strings.Trim(arg, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:=@,+#") == "" {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+	"igscraper/pkg/ui"
+)
+
+// The original igscraper was a single binary invoked as "igscraper <username>"
+// with its own download loop. That form is kept as a thin shim over the scrape
+// command: it shares the scrape flag set and the same runScrape code path, so
+// fixes and features only ever need to land in pkg/scraper and scrape.go.
+
+// legacyInvocation is set when the scrape was started through the root shortcut
+var legacyInvocation bool
+
+func init() {
+	// Register the scrape flags on the root command for backward compatibility
+	addScrapeFlags(rootCmd.Flags())
+
+	// Add a hidden alias to make scraping work without the "scrape" subcommand
+	origRunE := rootCmd.RunE
+	rootCmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if origRunE != nil {
+			return origRunE(cmd, args)
+		}
+		if len(args) > 0 && !isKnownCommand(args[0]) {
+			// If the first argument is not a known command, treat it as a username
+			// No need to transfer flags since we're using the same variables
+			legacyInvocation = true
+			return scrapeCmd.RunE(scrapeCmd, args)
+		}
+		// Otherwise show help
+		return cmd.Help()
+	}
+
+	// Set Args to allow arbitrary arguments
+	rootCmd.Args = cobra.ArbitraryArgs
+}
+
+// printLegacyNotice points users of the root shortcut at the scrape command.
+// It is only shown in verbose mode so existing scripts keep their output.
+func printLegacyNotice() {
+	if !legacyInvocation || !verbose {
+		return
+	}
+	ui.PrintWarning("Deprecated invocation", "'igscraper <username>' is kept for compatibility, prefer 'igscraper scrape <username>'")
+}
+
+// isKnownCommand reports whether arg names a registered subcommand or alias
+func isKnownCommand(arg string) bool {
+	for _, cmd := range rootCmd.Commands() {
+		if cmd.Name() == arg || cmd.HasAlias(arg) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"igscraper/pkg/config"
+	"igscraper/pkg/logger"
+	"igscraper/pkg/scraper"
+	"igscraper/pkg/ui"
+)
+
+// likedCmd represents the liked command
+var likedCmd = &cobra.Command{
+	Use:   "liked",
+	Short: "Archive the posts you have liked",
+	Long: `Download every post liked by the authenticated account into a liked/
+folder inside the output directory.
+
+Posts are fetched from the account's liked feed, most recently liked first.
+Each entry in liked/metadata.json records the post's owner (ID, username and
+full name) next to the usual caption and engagement details, so the archive
+stays attributable after the original is gone.
+
+Like the scrape command, runs are incremental and resumable, and --since,
+--until and --filter select which posts are kept. The liked feed is ordered
+by when you liked a post, not when it was posted, so --since does not stop
+pagination early.`,
+	Example: `  # Archive liked posts into ./liked
+  igscraper liked
+
+  # Use a specific account and output directory
+  igscraper liked --account work_account --output ./archive
+
+  # Resume an interrupted run
+  igscraper liked --resume`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		runLiked(cmd, args)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(likedCmd)
+
+	// Local flags for liked command
+	addFeedFlags(likedCmd.Flags(), "output directory; posts are saved to its liked/ folder (default: current directory)")
+}
+
+func runLiked(cmd *cobra.Command, args []string) {
+	cfg, err := config.Load(configFile, scrapeFlags())
+	if err != nil {
+		ui.PrintError("Failed to load configuration", err.Error())
+		os.Exit(1)
+	}
+
+	logger.Initialize(&cfg.Logging)
+	initAuditLog(cfg)
+	warnDeprecatedConfig(cfg)
+	logger.WithField("version", version).Info("Instagram Scraper starting")
+
+	// The liked feed belongs to the account, so credentials are always needed
+	applyCredentials(cfg)
+
+	if !useTUI {
+		ui.PrintInfo("Target", "Liked posts → "+filepath.Join(cfg.Output.BaseDirectory, scraper.LikedFolder))
+	}
+
+	logger.Info("Starting liked posts archive")
+	err = runDownload(cfg, scraper.LikedFolder, nil, func(s *scraper.Scraper) error {
+		return s.DownloadLikedPosts(resumeDownload, forceRestart)
+	})
+	if err != nil {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"igscraper/pkg/config"
+	"igscraper/pkg/instagram"
+	"igscraper/pkg/logger"
+	"igscraper/pkg/scraper"
+	"igscraper/pkg/ui"
+)
+
+// locationCmd represents the location command
+var locationCmd = &cobra.Command{
+	Use:   "location <location-id>",
+	Short: "Download posts geotagged at a location",
+	Long: `Download the posts geotagged at a location into a locations/<id>/ folder
+inside the output directory. The location is given by its numeric ID or by
+its page URL, such as https://www.instagram.com/explore/locations/212988663/.
+
+Posts are fetched most recent first. locations/<id>/metadata.json records the
+location's name, address and coordinates, and each entry names the post's
+owner next to the usual caption and engagement details.
+
+Like the scrape command, runs are incremental and resumable, and --since,
+--until and --filter select which posts are kept.`,
+	Example: `  # Archive the posts tagged at a location
+  igscraper location 212988663
+
+  # Use the location's page URL, keeping only this year's posts
+  igscraper location https://www.instagram.com/explore/locations/212988663/ --since 2024-01-01
+
+  # Resume an interrupted run
+  igscraper location 212988663 --resume`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		runLocation(cmd, args)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(locationCmd)
+
+	// Local flags for location command
+	addFeedFlags(locationCmd.Flags(), "output directory; posts are saved to its locations/<id>/ folder (default: current directory)")
+}
+
+func runLocation(cmd *cobra.Command, args []string) {
+	locationID, err := instagram.ParseLocationID(args[0])
+	if err != nil {
+		ui.PrintError("Invalid location", err.Error())
+		os.Exit(1)
+	}
+
+	cfg, err := config.Load(configFile, scrapeFlags())
+	if err != nil {
+		ui.PrintError("Failed to load configuration", err.Error())
+		os.Exit(1)
+	}
+
+	logger.Initialize(&cfg.Logging)
+	initAuditLog(cfg)
+	warnDeprecatedConfig(cfg)
+	logger.WithField("version", version).Info("Instagram Scraper starting")
+
+	// Location feeds are only available to logged in sessions
+	applyCredentials(cfg)
+
+	if !useTUI {
+		ui.PrintInfo("Target", "Location "+locationID+" → "+filepath.Join(cfg.Output.BaseDirectory, scraper.LocationsFolder, locationID))
+	}
+
+	logger.WithField("location_id", locationID).Info("Starting location download")
+	err = runDownload(cfg, "location "+locationID, nil, func(s *scraper.Scraper) error {
+		return s.DownloadLocation(locationID, resumeDownload, forceRestart)
+	})
+	if err != nil {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"igscraper/pkg/checkpoint"
+	"igscraper/pkg/config"
+	"igscraper/pkg/storage"
+	"igscraper/pkg/ui"
+)
+
+var (
+	// Migrate-layout command flags
+	migrateTo     string
+	migrateDryRun bool
+)
+
+// migrateLayoutCmd represents the migrate-layout command
+var migrateLayoutCmd = &cobra.Command{
+	Use:   "migrate-layout <archive-dir>",
+	Short: "Rename downloaded photos to a new file name pattern",
+	Long: `Rename the photos of a downloaded folder after a new file name pattern,
+using the post details stored in its metadata.json.
+
+Patterns must contain {shortcode}, may put photos into folders with /,
+and may use:
+  • {shortcode}   the post's shortcode
+  • {id}          the post's numeric ID
+  • {username}    the account that posted it
+  • {date_taken}  the day it was posted, as YYYY-MM-DD
+  • {taken_at}    when it was posted, as YYYY-MM-DD_HH-MM-SS
+  • {year}        the year it was posted
+  • {month}       the month it was posted, as 01-12
+  • {timestamp}   when it was posted, in Unix seconds
+  • {likes}       its like count when it was downloaded
+  • {index}       its position in a carousel, 1 for single posts
+  • {media_type}  photo or video
+  • {ext}         the file extension (jpg, or mp4 for videos)
+
+metadata.json, the deduplication index and any interrupted download's
+checkpoint are updated with the new names, so later runs keep recognising
+the photos. Set output.file_name_pattern to the same pattern so new
+downloads follow it. Photos without metadata are left as they are.`,
+	Example: `  # Prefix every photo with the day it was posted
+  igscraper migrate-layout ./username_photos --to "{date_taken}_{shortcode}.{ext}"
+
+  # Sort the photos into a folder per month
+  igscraper migrate-layout ./username_photos --to "{year}/{month}/{shortcode}.{ext}"
+
+  # Show what would be renamed
+  igscraper migrate-layout ./username_photos --to "{date_taken}_{shortcode}.{ext}" --dry-run`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		runMigrateLayout(cmd, args)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(migrateLayoutCmd)
+
+	// Local flags for migrate-layout command
+	flags := migrateLayoutCmd.Flags()
+	flags.StringVar(&migrateTo, "to", "", "file name pattern to rename the photos to")
+	flags.BoolVar(&migrateDryRun, "dry-run", false, "show what would be renamed without changing anything")
+	migrateLayoutCmd.MarkFlagRequired("to")
+}
+
+func runMigrateLayout(cmd *cobra.Command, args []string) {
+	dir := args[0]
+
+	layout, err := storage.ParseLayout(migrateTo)
+	if err != nil {
+		ui.PrintError("Invalid file name pattern", err.Error())
+		os.Exit(1)
+	}
+
+	// Only the output directory is needed, so credentials are not required
+	cfg := config.DefaultConfig()
+	if err := cfg.LoadFromFile(configFile); err != nil {
+		ui.PrintError("Failed to load configuration", err.Error())
+		os.Exit(1)
+	}
+	if err := cfg.LoadFromEnv(); err != nil {
+		ui.PrintError("Failed to load configuration", err.Error())
+		os.Exit(1)
+	}
+	index, err := storage.LoadHashIndex(filepath.Join(cfg.Output.BaseDirectory, storage.HashIndexFile))
+	if err != nil {
+		ui.PrintError("Failed to load hash index", err.Error())
+		os.Exit(1)
+	}
+
+	result, err := storage.MigrateLayout(dir, layout, index, migrateDryRun)
+	if err != nil {
+		ui.PrintError("Migration failed", err.Error())
+		os.Exit(1)
+	}
+
+	if migrateDryRun {
+		printMigrationPlan(result)
+		return
+	}
+
+	if err := index.Save(); err != nil {
+		ui.PrintError("Failed to save hash index", err.Error())
+		os.Exit(1)
+	}
+	if err := updateCheckpoint(result); err != nil {
+		ui.PrintError("Failed to update checkpoint", err.Error())
+		os.Exit(1)
+	}
+
+	if !quiet {
+		fmt.Println(ui.Green(fmt.Sprintf("✓ Renamed %d photos in %s to %s", len(result.Renamed), dir, layout.Pattern())))
+		printMigrationSkipped(result)
+		if cfg.Output.FileNamePattern != layout.Pattern() {
+			fmt.Printf("  → Set %s to %q so new downloads use the same names\n", ui.Green("output.file_name_pattern"), layout.Pattern())
+		}
+	}
+}
+
+// updateCheckpoint records the new names in the checkpoint of an
+// interrupted download of the archive, if there is one
+func updateCheckpoint(result *storage.MigrationResult) error {
+	if len(result.Renamed) == 0 || result.Username == "" {
+		return nil
+	}
+	mgr, err := checkpoint.NewManager(result.Username)
+	if err != nil {
+		return err
+	}
+	cp, err := mgr.Load()
+	if err != nil || cp == nil {
+		return err
+	}
+	for shortcode, name := range result.Renamed {
+		if _, ok := cp.DownloadedPhotos[shortcode]; ok {
+			cp.DownloadedPhotos[shortcode] = name
+		}
+	}
+	return mgr.Save(cp)
+}
+
+// printMigrationPlan lists the renames a dry run found
+func printMigrationPlan(result *storage.MigrationResult) {
+	shortcodes := make([]string, 0, len(result.Renamed))
+	for shortcode := range result.Renamed {
+		shortcodes = append(shortcodes, shortcode)
+	}
+	sort.Strings(shortcodes)
+
+	for _, shortcode := range shortcodes {
+		fmt.Printf("  %s → %s\n", shortcode, result.Renamed[shortcode])
+	}
+	fmt.Printf("%d photos would be renamed, %d already match\n", len(result.Renamed), result.Unchanged)
+	printMigrationSkipped(result)
+}
+
+// printMigrationSkipped lists photos in metadata.json that are not on disk
+func printMigrationSkipped(result *storage.MigrationResult) {
+	if len(result.Missing) == 0 {
+		return
+	}
+	fmt.Println(ui.Yellow(fmt.Sprintf("! %d photos in metadata.json were not found and were skipped", len(result.Missing))))
+	for _, shortcode := range result.Missing {
+		fmt.Printf("  %s\n", shortcode)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"igscraper/pkg/config"
+	"igscraper/pkg/instagram"
+	"igscraper/pkg/logger"
+	"igscraper/pkg/scraper"
+	"igscraper/pkg/ui"
+)
+
+// postCmd represents the post command
+var postCmd = &cobra.Command{
+	Use:   "post <url-or-shortcode>",
+	Short: "Download a single post",
+	Long: `Download every photo and video of one post, given by its URL or shortcode,
+and print its details.
+
+Posts are saved to a posts/ folder inside the output directory, named after
+output.file_name_pattern. The photos and videos of a carousel are saved as
+<shortcode>_1, <shortcode>_2 and so on. Media already in the folder are not
+downloaded again.
+
+Post, reel and IGTV links are accepted.`,
+	Example: `  # Download a post by URL
+  igscraper post https://www.instagram.com/p/C1a2B3c4D5e/
+
+  # Or by shortcode, into ./archive/posts
+  igscraper post C1a2B3c4D5e --output ./archive`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		runPost(cmd, args)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(postCmd)
+
+	// Local flags for post command
+	flags := postCmd.Flags()
+	flags.StringVarP(&outputDir, "output", "o", "", "output directory; posts are saved to its posts/ folder (default: current directory)")
+	flags.StringVarP(&accountName, "account", "a", "", "use specific stored account")
+	flags.BoolVar(&skipSessionCheck, "skip-session-check", false, "do not check that the session cookies are valid before downloading")
+	flags.BoolVar(&embedMetadata, "embed-metadata", false, "write caption, author, post URL and date into each photo's EXIF/XMP metadata")
+	flags.BoolVar(&withComments, "comments", false, "save each post's comments to comments/<shortcode>.json")
+	flags.IntVar(&maxComments, "max-comments", 0, "most comments saved per post with --comments (default 100)")
+}
+
+func runPost(cmd *cobra.Command, args []string) {
+	shortcode, err := instagram.ParseShortcode(args[0])
+	if err != nil {
+		ui.PrintError("Invalid post", err.Error())
+		os.Exit(1)
+	}
+
+	cfg, err := config.Load(configFile, scrapeFlags())
+	if err != nil {
+		ui.PrintError("Failed to load configuration", err.Error())
+		os.Exit(1)
+	}
+
+	logger.Initialize(&cfg.Logging)
+	initAuditLog(cfg)
+	warnDeprecatedConfig(cfg)
+	applyCredentials(cfg)
+
+	s, err := scraper.New(cfg)
+	if err != nil {
+		ui.PrintError("Failed to create scraper", err.Error())
+		os.Exit(1)
+	}
+
+	post, err := s.DownloadPost(shortcode)
+	if post != nil {
+		printPost(post)
+	}
+	if err != nil {
+		ui.PrintError("Failed to download post", err.Error())
+		os.Exit(1)
+	}
+}
+
+// printPost prints a downloaded post's details and the files it was saved to
+func printPost(post *scraper.Post) {
+	fmt.Println()
+	fmt.Printf("%s %s\n", ui.Magenta("Post"), instagram.GetPostURL(post.Shortcode))
+	if len(post.Media) > 0 {
+		first := post.Media[0]
+		fmt.Printf("  %s @%s\n", ui.Cyan("Owner:"), first.Owner.Username)
+		if !first.TakenAt.IsZero() && first.TakenAt.Unix() > 0 {
+			fmt.Printf("  %s %s\n", ui.Cyan("Posted:"), first.TakenAt.Format("2006-01-02 15:04"))
+		}
+		fmt.Printf("  %s %d likes, %d comments\n", ui.Cyan("Engagement:"), first.LikesCount, first.CommentsCount)
+		if first.Location != nil && first.Location.Name != "" {
+			fmt.Printf("  %s %s\n", ui.Cyan("Location:"), first.Location.Name)
+		}
+		if first.Caption != "" {
+			fmt.Printf("  %s %s\n", ui.Cyan("Caption:"), strings.ReplaceAll(first.Caption, "\n", "\n           "))
+		}
+	}
+
+	fmt.Println()
+	for _, media := range post.Media {
+		kind := "photo"
+		if media.IsVideo {
+			kind = "video"
+		}
+		fmt.Printf("  %s %s %s\n", ui.Green("✓"), filepath.Join(post.OutputDir, media.File), ui.Dim(kind))
+	}
+	for _, shortcode := range post.Skipped {
+		fmt.Printf("  %s %s %s\n", ui.Yellow("-"), shortcode, ui.Dim("video skipped"))
+	}
+	for shortcode, err := range post.Failed {
+		fmt.Printf("  %s %s %s\n", ui.Red("✗"), shortcode, ui.Dim(err.Error()))
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"igscraper/pkg/config"
+	"igscraper/pkg/logger"
+	"igscraper/pkg/metadata"
+	"igscraper/pkg/scraper"
+	"igscraper/pkg/ui"
+)
+
+// runProfileSnapshots saves the profile snapshot of each username without
+// downloading posts. Profiles that fail are reported and the rest go on.
+func runProfileSnapshots(cfg *config.Config, usernames []string) error {
+	s, err := scraper.New(cfg)
+	if err != nil {
+		ui.PrintError("Failed to initialize scraper", err.Error())
+		return err
+	}
+
+	var failed []string
+	for _, username := range usernames {
+		logger.WithField("username", username).Info("Saving profile snapshot")
+		profile, err := s.SaveProfile(username)
+		if err != nil {
+			logger.WithError(err).WithField("username", username).Error("Profile snapshot failed")
+			ui.PrintError("Failed to save profile of "+username, err.Error())
+			failed = append(failed, username)
+			continue
+		}
+		printProfile(profile, s.OutputDir(username))
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to save %d profiles: %s", len(failed), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// printProfile shows a saved profile snapshot
+func printProfile(profile *metadata.Profile, outputDir string) {
+	fmt.Println()
+	name := "@" + profile.Username
+	if profile.FullName != "" {
+		name = profile.FullName + " (" + name + ")"
+	}
+	fmt.Printf("%s %s\n", ui.Magenta("Profile"), name)
+	fmt.Printf("  %s %d posts, %d followers, %d following\n", ui.Cyan("Counts:"), profile.Posts, profile.Followers, profile.Following)
+	if profile.Biography != "" {
+		fmt.Printf("  %s %s\n", ui.Cyan("Bio:"), strings.ReplaceAll(profile.Biography, "\n", "\n       "))
+	}
+	if profile.ExternalURL != "" {
+		fmt.Printf("  %s %s\n", ui.Cyan("Link:"), profile.ExternalURL)
+	}
+	fmt.Printf("  %s %s\n", ui.Green("✓"), filepath.Join(outputDir, metadata.ProfileFile))
+	if profile.ProfilePic != "" {
+		fmt.Printf("  %s %s\n", ui.Green("✓"), filepath.Join(outputDir, filepath.FromSlash(profile.ProfilePic)))
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"igscraper/pkg/config"
+	"igscraper/pkg/logger"
+	"igscraper/pkg/scraper"
+	"igscraper/pkg/ui"
+)
+
+// reelsCmd represents the reels command
+var reelsCmd = &cobra.Command{
+	Use:   "reels <username>",
+	Short: "Archive a profile's reels",
+	Long: `Download the reels of an Instagram profile into a reels/ folder inside the
+profile's output directory.
+
+Each reel is saved as a video, and its cover image is saved under the same
+name in reels/thumbnails/. Every entry in reels/metadata.json records the
+reel's play count and audio title next to the usual caption and engagement
+details.
+
+Like the scrape command, runs are incremental and resumable, and --since,
+--until and --filter select which reels are kept.`,
+	Example: `  # Archive reels into ./username_photos/reels
+  igscraper reels username
+
+  # Only reels posted this year
+  igscraper reels username --since 2024-01-01
+
+  # Resume an interrupted run
+  igscraper reels username --resume`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		runReels(cmd, args)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(reelsCmd)
+
+	// Local flags for reels command
+	addFeedFlags(reelsCmd.Flags(), "output directory (default: current directory)")
+}
+
+func runReels(cmd *cobra.Command, args []string) {
+	username := strings.TrimSpace(args[0])
+
+	cfg, err := config.Load(configFile, scrapeFlags())
+	if err != nil {
+		ui.PrintError("Failed to load configuration", err.Error())
+		os.Exit(1)
+	}
+
+	logger.Initialize(&cfg.Logging)
+	initAuditLog(cfg)
+	warnDeprecatedConfig(cfg)
+	logger.WithField("version", version).Info("Instagram Scraper starting")
+
+	// The reels endpoint is only available to logged in sessions
+	applyCredentials(cfg)
+
+	if !useTUI {
+		ui.PrintInfo("Target", "Reels of "+username)
+	}
+
+	logger.WithField("username", username).Info("Starting reels archive")
+	err = runDownload(cfg, username, nil, func(s *scraper.Scraper) error {
+		return s.DownloadReels(username, resumeDownload, forceRestart)
+	})
+	if err != nil {
+		os.Exit(1)
+	}
+}
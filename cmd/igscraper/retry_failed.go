@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"igscraper/pkg/config"
+	"igscraper/pkg/logger"
+	"igscraper/pkg/scraper"
+	"igscraper/pkg/ui"
+)
+
+// retryFailedCmd represents the retry-failed command
+var retryFailedCmd = &cobra.Command{
+	Use:   "retry-failed <username>",
+	Short: "Download again the media a scrape could not save",
+	Long: `Download again the media listed in a profile's failed.json.
+
+A scrape queues a failed download again up to download.retry_attempts times.
+Downloads that still fail, for a reason that may pass such as a network error
+or a 5xx, are listed in failed.json in the profile's folder. This command
+tries them once more, adds those it saves to metadata.json and keeps the
+rest listed. Posts found deleted or blocked are recorded in metadata.json
+instead.
+
+Instagram's media links expire after a few days, so old entries may only be
+fetched by scraping the profile again. Exits with status 1 when a download
+still fails.`,
+	Example: `  # Retry the failed downloads in ./username_photos
+  igscraper retry-failed username
+
+  # Of a profile archived elsewhere
+  igscraper retry-failed username --output ./archive`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		runRetryFailed(cmd, args)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(retryFailedCmd)
+
+	// Local flags for retry-failed command
+	flags := retryFailedCmd.Flags()
+	flags.StringVarP(&outputDir, "output", "o", "", "output directory the profile was scraped into (default: current directory)")
+	flags.IntVar(&concurrent, "concurrent", 3, "number of concurrent downloads")
+	flags.StringVarP(&accountName, "account", "a", "", "use specific stored account")
+	flags.BoolVar(&skipSessionCheck, "skip-session-check", false, "do not check that the session cookies are valid before downloading")
+}
+
+func runRetryFailed(cmd *cobra.Command, args []string) {
+	username := strings.TrimSpace(args[0])
+
+	cfg, err := config.Load(configFile, scrapeFlags())
+	if err != nil {
+		ui.PrintError("Failed to load configuration", err.Error())
+		os.Exit(1)
+	}
+
+	logger.Initialize(&cfg.Logging)
+	initAuditLog(cfg)
+	warnDeprecatedConfig(cfg)
+	applyCredentials(cfg)
+
+	s, err := scraper.New(cfg)
+	if err != nil {
+		ui.PrintError("Failed to create scraper", err.Error())
+		os.Exit(1)
+	}
+
+	report, err := s.RetryFailed(username)
+	if err != nil {
+		ui.PrintError("Failed to retry downloads", err.Error())
+		os.Exit(1)
+	}
+
+	printRetryReport(report)
+	if len(report.Failed) > 0 {
+		os.Exit(1)
+	}
+}
+
+// printRetryReport prints the downloads that were saved and those that
+// failed again
+func printRetryReport(r *scraper.RetryReport) {
+	fmt.Println()
+	if len(r.Saved) == 0 && len(r.Failed) == 0 {
+		fmt.Printf("%s @%s has no failed downloads in %s\n", ui.Green("✓"), r.Username, r.OutputDir)
+		return
+	}
+
+	fmt.Printf("%s @%s\n", ui.Magenta("Retried downloads of"), r.Username)
+	for _, shortcode := range r.Saved {
+		fmt.Printf("  %s %s\n", ui.Green("✓"), shortcode)
+	}
+	for shortcode, err := range r.Failed {
+		fmt.Printf("  %s %s %s\n", ui.Red("✗"), shortcode, ui.Dim(err.Error()))
+	}
+	fmt.Printf("\n%d saved, %d failed\n", len(r.Saved), len(r.Failed))
+}
@@ -4,8 +4,12 @@ import (
 	"fmt"
 	"os"
 	"runtime"
+	"strings"
 
 	"github.com/spf13/cobra"
+	"igscraper/pkg/audit"
+	"igscraper/pkg/config"
+	"igscraper/pkg/logger"
 	"igscraper/pkg/ui"
 )
 
@@ -17,12 +21,14 @@ var (
 
 	// Global flags
 	configFile    string
+	configProfile string
 	logLevel      string
 	noColor       bool
 	notifications bool
 	quiet         bool
 	progressOnly  bool
 	verbose       bool
+	auditLog      string
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -68,28 +74,20 @@ DOCUMENTATION:
 For more information and examples, visit: https://github.com/marcusziade/igscraper`,
 	Version: fmt.Sprintf("%s (commit: %s, built: %s)", version, gitCommit, buildDate),
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
-		// Progress mode is default unless verbose is specified
-		if !verbose && !quiet {
-			progressOnly = true
-		}
-		
-		// Set quiet mode if requested or log level is error
-		if quiet || logLevel == "error" {
-			ui.SetQuietMode(true)
-		}
-		
-		// Set progress-only mode
-		if progressOnly {
-			ui.SetProgressOnlyMode(true)
-			// Also set log level to error to suppress logs
-			logLevel = "error"
-		}
+		applyOutputFormat()
+		applyVerbosity(cmd)
 		
 		// Don't show logo for certain commands
 		if cmd.Name() != "version" && cmd.Name() != "help" && cmd.Name() != "completion" {
 			ui.PrintLogo()
 		}
 	},
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		// Shut down Chrome if the browser fallback started it
+		if headlessBrowser != nil {
+			headlessBrowser.Close()
+		}
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -102,13 +100,16 @@ func Execute() {
 
 func init() {
 	// Global flags
-	rootCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "", "config file (default is $HOME/.igscraper.yaml)")
+	rootCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "", "config file (default is ./.igscraper.yaml or config.yaml in the config directory)")
+	rootCmd.PersistentFlags().StringVar(&configProfile, "profile", "", "merge this profile from the config file's profiles section over its settings")
 	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "log level (debug, info, warn, error)")
 	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "disable colored output")
-	rootCmd.PersistentFlags().BoolVar(&notifications, "notifications", true, "enable desktop notifications")
+	rootCmd.PersistentFlags().BoolVar(&notifications, "notifications", true, "enable desktop and email notifications")
 	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "suppress all output except errors")
 	rootCmd.PersistentFlags().BoolVarP(&progressOnly, "progress", "p", false, "show only progress bar and essential info")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "show all output (logo, logs, progress)")
+	rootCmd.PersistentFlags().StringVar(&auditLog, "audit-log", "", "append a JSON-lines record of every outbound request to this file")
+	rootCmd.MarkFlagsMutuallyExclusive("quiet", "progress", "verbose")
 
 	// Version template
 	rootCmd.SetVersionTemplate(`Instagram Scraper {{.Version}}
@@ -120,8 +121,97 @@ OS/Arch: ` + runtime.GOOS + `/` + runtime.GOARCH + `
 	rootCmd.CompletionOptions.DisableDefaultCmd = true
 }
 
+// applyOutputFormat switches the UI to JSON lines for --output-format json,
+// moving the console logs to stderr so stdout only carries events. It exits
+// on an unknown format.
+func applyOutputFormat() {
+	switch outputFormat {
+	case ui.OutputText:
+	case ui.OutputJSON:
+		ui.SetJSONOutput(true)
+		logger.SetConsoleOutput(os.Stderr)
+	default:
+		ui.PrintError("Invalid --output-format value", fmt.Sprintf("%q: use text or json", outputFormat))
+		os.Exit(1)
+	}
+}
+
+// applyVerbosity sets how much every command prints for --quiet, --progress
+// or --verbose, and the log level that goes with it:
+//
+//   - --quiet prints errors only and logs errors
+//   - --progress, the default, prints the progress bar and results and logs
+//     errors
+//   - --verbose prints the logo, messages and progress, and logs from the
+//     configured level on
+//
+// An explicit --log-level sets the level in every mode. With --output-format
+// json every event is written whatever the mode, which then only sets the
+// level of the logs on stderr. It exits on an unknown log level.
+func applyVerbosity(cmd *cobra.Command) {
+	switch {
+	case quiet:
+		ui.SetQuietMode(true)
+		ui.SetProgressOnlyMode(false)
+	case verbose:
+		ui.SetQuietMode(false)
+		ui.SetProgressOnlyMode(false)
+	default:
+		progressOnly = true
+		ui.SetQuietMode(true)
+		ui.SetProgressOnlyMode(true)
+	}
+	
+	if !verbose && !cmd.Flags().Changed("log-level") {
+		logLevel = "error"
+	}
+	// Commands set up the logger again with the config file's settings;
+	// until then, and for those that never do, it follows the mode
+	if err := logger.Initialize(&config.LoggingConfig{Level: logLevel}); err != nil {
+		ui.PrintError("Invalid --log-level value", err.Error())
+		os.Exit(1)
+	}
+}
+
+// profileFlags returns the flags for config.Load that select the --profile
+// preset, which the commands add their own flags to
+func profileFlags() map[string]interface{} {
+	flags := make(map[string]interface{})
+	if configProfile != "" {
+		flags["profile"] = configProfile
+	}
+	return flags
+}
+
 // initConfig reads in config file and ENV variables if set.
 func initConfig() {
 	// This will be called before any command execution
 	// Config loading logic will be handled in individual commands
-}
\ No newline at end of file
+}
+
+// warnDeprecatedConfig logs the settings of an older layout the config file
+// was read with, which config migrate rewrites
+func warnDeprecatedConfig(cfg *config.Config) {
+	deprecated := cfg.Deprecated()
+	if len(deprecated) == 0 {
+		return
+	}
+	settings := make([]string, len(deprecated))
+	for i, d := range deprecated {
+		settings[i] = d.String()
+	}
+	logger.WithField("settings", strings.Join(settings, "; ")).Warn("The config file uses deprecated settings, run 'igscraper config migrate' to update it")
+}
+
+// initAuditLog starts recording outbound requests if an audit log is
+// configured. It exits when the log cannot be opened, since running without
+// the requested record would defeat its purpose.
+func initAuditLog(cfg *config.Config) {
+	if cfg.Logging.AuditFile == "" {
+		return
+	}
+	if err := audit.Initialize(cfg.Logging.AuditFile); err != nil {
+		ui.PrintError("Failed to open audit log", err.Error())
+		os.Exit(1)
+	}
+}
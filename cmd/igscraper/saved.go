@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"igscraper/pkg/config"
+	"igscraper/pkg/logger"
+	"igscraper/pkg/scraper"
+	"igscraper/pkg/ui"
+)
+
+// savedCmd represents the saved command
+var savedCmd = &cobra.Command{
+	Use:   "saved",
+	Short: "Archive your saved posts, organized by collection",
+	Long: `Download the posts saved by the authenticated account into a saved/
+folder inside the output directory.
+
+COLLECTIONS:
+  Each collection gets its own subfolder, named after the collection. Posts
+  that are not in any collection are stored directly in saved/. A post saved
+  to several collections is stored in each of their folders, and its entry
+  in every folder's metadata.json lists all of its collections:
+
+    saved/
+      Travel/         posts in the "Travel" collection
+      Recipes/        posts in the "Recipes" collection
+      metadata.json   posts saved outside any collection
+
+Like the scrape command, runs are incremental and resumable, and --since,
+--until and --filter select which posts are kept.`,
+	Example: `  # Archive saved posts into ./saved
+  igscraper saved
+
+  # Use a specific account and output directory
+  igscraper saved --account work_account --output ./archive
+
+  # Resume an interrupted run
+  igscraper saved --resume`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		runSaved(cmd, args)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(savedCmd)
+
+	// Local flags for saved command
+	addFeedFlags(savedCmd.Flags(), "output directory; posts are saved to its saved/ folder (default: current directory)")
+}
+
+func runSaved(cmd *cobra.Command, args []string) {
+	cfg, err := config.Load(configFile, scrapeFlags())
+	if err != nil {
+		ui.PrintError("Failed to load configuration", err.Error())
+		os.Exit(1)
+	}
+
+	logger.Initialize(&cfg.Logging)
+	initAuditLog(cfg)
+	warnDeprecatedConfig(cfg)
+	logger.WithField("version", version).Info("Instagram Scraper starting")
+
+	// Saved posts belong to the account, so credentials are always needed
+	applyCredentials(cfg)
+
+	if !useTUI {
+		ui.PrintInfo("Target", "Saved posts → "+filepath.Join(cfg.Output.BaseDirectory, scraper.SavedFolder))
+	}
+
+	logger.Info("Starting saved posts archive")
+	err = runDownload(cfg, scraper.SavedFolder, nil, func(s *scraper.Scraper) error {
+		return s.DownloadSavedPosts(resumeDownload, forceRestart)
+	})
+	if err != nil {
+		os.Exit(1)
+	}
+}
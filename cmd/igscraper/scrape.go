@@ -1,15 +1,25 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"igscraper/pkg/auth"
+	"igscraper/pkg/browser"
 	"igscraper/pkg/config"
+	"igscraper/pkg/doctor"
+	igerrors "igscraper/pkg/errors"
+	"igscraper/pkg/filter"
 	"igscraper/pkg/logger"
+	"igscraper/pkg/ratelimit"
 	"igscraper/pkg/scraper"
+	"igscraper/pkg/storage"
 	"igscraper/pkg/ui"
 	"igscraper/pkg/ui/tui"
 )
@@ -19,18 +29,66 @@ var (
 	outputDir   string
 	concurrent  int
 	rateLimit   int
+	rateLimitFile string
 	accountName string
 	maxRetries  int
 	downloadTimeout int
 	resumeDownload bool
 	forceRestart bool
 	useTUI bool
+	skipSyncedWithin time.Duration
+	sinceDate string
+	untilDate string
+	filterExpr string
+	minLikes int
+	skipVideos bool
+	skipImages bool
+	overwrite bool
+	refreshNewerThan time.Duration
+	galleryThumbnails int
+	existingArchive string
+	maxPosts int
+	maxPages int
+	embedMetadata bool
+	dedup bool
+	withComments bool
+	maxComments int
+	maxBandwidth string
+	maxTotalSize string
+	prefetchPages int
+	skipSessionCheck bool
+	rotateAccounts bool
+	browserFallback bool
+	keepAlive time.Duration
+	dryRun bool
+	useCache bool
+	cacheTTL time.Duration
+	profileOnly bool
+	scrapeUserID string
+	webAddr string
+	outputFormat = ui.OutputText
+)
+
+// headlessBrowser is the browser fallback shared by every scraper of the run
+// when it is enabled. Chrome is only started if an API call is blocked.
+var headlessBrowser *browser.Browser
+
+// accountRotator is shared by every scraper of the run when account rotation
+// is enabled, so a batch keeps using the account it last switched to
+var accountRotator *auth.AccountRotator
+
+// credentialManager saves the cookies Instagram renews during the run for
+// storedAccount, the stored account in use. storedAccount is empty when the
+// credentials come from the config file or environment.
+var (
+	credentialManager *auth.Manager
+	storedAccount     string
 )
 
 // scrapeCmd represents the scrape command
 var scrapeCmd = &cobra.Command{
-	Use:   "scrape <username>",
-	Short: "Download photos from an Instagram user profile",
+	Use:   "scrape <username> [username...] | --user-id <id>",
+	Short: "Download photos from one or more Instagram user profiles",
 	Long: `Download photos from an Instagram user's profile with advanced options.
 
 AUTHENTICATION:
@@ -46,11 +104,35 @@ FEATURES:
   • Resume interrupted downloads with --resume
   • Rate limiting to avoid API restrictions
   • Concurrent downloads for speed
+  • Batch mode: pass several usernames to scrape them one after another
+  • Date filtering with --since and --until (YYYY-MM-DD or RFC 3339)
+  • Post filtering with --filter on hashtags, captions, likes and type
+  • Embed caption, author, post URL and date into photos with --embed-metadata
+  • Store re-posted identical images only once with --dedup
+  • Save each post's comments with --comments, up to --max-comments per post
+  • Estimate API calls, downloads and run time with --dry-run
+  • Snapshot of the profile's bio, follower counts and picture in profile.json
+  • Download by numeric user ID with --user-id, without looking up the username
+
+BATCH MODE:
+  With more than one username, a failed profile does not stop the batch; a
+  summary is printed at the end. --skip-synced-within skips profiles whose
+  last complete sync is newer than the threshold and that have no more posts
+  than they had then, at the cost of a single profile request.
+
+STOPPING:
+  Ctrl+C or SIGTERM stops queuing posts and gives the downloads in progress
+  download.shutdown_timeout (30s) to finish. The checkpoint and metadata are
+  saved, and a summary ends with the command that resumes the scrape. A
+  second Ctrl+C quits at once.
 
 OUTPUT:
   By default, photos are saved to ./<username>_photos/
   Each photo is saved as: <shortcode>_<index>.jpg
-  Metadata is saved as: <username>_metadata.json`,
+  Metadata is saved as: <username>_metadata.json
+  The profile snapshot is saved as profile.json, its picture in avatar/
+  A report of the run is saved as report.json and report.txt
+  Use --profile-only to save just the snapshot`,
 	Example: `  # Basic download
   igscraper scrape johndoe
 
@@ -73,8 +155,40 @@ OUTPUT:
   igscraper scrape johndoe --resume
 
   # Force restart, ignoring existing checkpoint
-  igscraper scrape johndoe --force-restart`,
-	Args: cobra.ExactArgs(1),
+  igscraper scrape johndoe --force-restart
+
+  # Only photos posted in the first half of 2024
+  igscraper scrape johndoe --since 2024-01-01 --until 2024-06-30
+
+  # Only popular sunset photos, skipping sponsored posts
+  igscraper scrape johndoe --filter 'hashtag:sunset AND likes>100 AND NOT #ad'
+
+  # Just the 20 most recent posts with at least 1000 likes
+  igscraper scrape johndoe --min-likes 1000 --max-posts 20
+
+  # Pick up captions edited on posts of the last week
+  igscraper scrape johndoe --refresh-newer-than 168h
+
+  # Batch download, skipping profiles synced within the last day
+  igscraper scrape johndoe janedoe natgeo --skip-synced-within 24h
+
+  # See how long a batch would take at 30 requests per minute
+  igscraper scrape johndoe janedoe --dry-run --rate-limit 30
+
+  # Refresh the bio, follower counts and profile picture only
+  igscraper scrape johndoe --profile-only
+
+  # Download a profile by the user_id recorded in its metadata.json
+  igscraper scrape --user-id 123456789`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if scrapeUserID != "" {
+			if len(args) > 0 {
+				return fmt.Errorf("--user-id cannot be combined with usernames")
+			}
+			return nil
+		}
+		return cobra.MinimumNArgs(1)(cmd, args)
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		runScrape(cmd, args)
 		return nil
@@ -85,43 +199,175 @@ func init() {
 	rootCmd.AddCommand(scrapeCmd)
 
 	// Local flags for scrape command
-	scrapeCmd.Flags().StringVarP(&outputDir, "output", "o", "", "output directory for downloads (default: current directory)")
-	scrapeCmd.Flags().IntVar(&concurrent, "concurrent", 3, "number of concurrent downloads")
-	scrapeCmd.Flags().IntVar(&rateLimit, "rate-limit", 60, "requests per minute")
-	scrapeCmd.Flags().StringVarP(&accountName, "account", "a", "", "use specific stored account")
-	scrapeCmd.Flags().IntVar(&maxRetries, "max-retries", 3, "maximum number of retry attempts")
-	scrapeCmd.Flags().IntVar(&downloadTimeout, "download-timeout", 30, "download timeout in seconds")
-	scrapeCmd.Flags().BoolVar(&resumeDownload, "resume", false, "resume from last checkpoint")
-	scrapeCmd.Flags().BoolVar(&forceRestart, "force-restart", false, "force restart, ignoring existing checkpoint")
-	scrapeCmd.Flags().BoolVar(&useTUI, "tui", false, "use interactive terminal UI with real-time progress")
-	
-	// Also add these flags to root command for backward compatibility
-	rootCmd.Flags().StringVarP(&outputDir, "output", "o", "", "output directory for downloads (default: current directory)")
-	rootCmd.Flags().IntVar(&concurrent, "concurrent", 3, "number of concurrent downloads")
-	rootCmd.Flags().IntVar(&rateLimit, "rate-limit", 60, "requests per minute")
-	rootCmd.Flags().StringVarP(&accountName, "account", "a", "", "use specific stored account")
-	rootCmd.Flags().BoolVar(&resumeDownload, "resume", false, "resume from last checkpoint")
-	rootCmd.Flags().BoolVar(&forceRestart, "force-restart", false, "force restart, ignoring existing checkpoint")
-	rootCmd.Flags().BoolVar(&useTUI, "tui", false, "use interactive terminal UI with real-time progress")
+	addScrapeFlags(scrapeCmd.Flags())
+	scrapeCmd.Flags().StringVar(&scrapeUserID, "user-id", "", "download the profile with this numeric user ID, skipping the username lookup")
+}
+
+// addScrapeFlags registers the scrape flags on the given flag set. The root
+// command shares these so the legacy "igscraper <username>" form stays in sync
+func addScrapeFlags(flags *pflag.FlagSet) {
+	addFeedFlags(flags, "output directory for downloads (default: current directory)")
+	flags.DurationVar(&skipSyncedWithin, "skip-synced-within", 0, "in batch mode, skip profiles fully synced within this duration (e.g. 24h)")
+	flags.StringVar(&existingArchive, "existing-archive", "", "when the profile is archived in another folder too: warn, or use that folder (default warn)")
+	flags.BoolVar(&dryRun, "dry-run", false, "estimate API calls, downloads and run time under the rate limit without downloading")
+	flags.BoolVar(&profileOnly, "profile-only", false, "only save the profile snapshot (bio, follower counts and picture), not the posts")
+	flags.StringVar(&webAddr, "web", "", "serve a progress page and JSON status on this address (e.g. 127.0.0.1:8080)")
+}
+
+// addFeedFlags registers the flags of every command downloading posts, with
+// output as the help of --output. Flags that only apply to profiles are left
+// to addScrapeFlags.
+func addFeedFlags(flags *pflag.FlagSet, output string) {
+	flags.StringVarP(&outputDir, "output", "o", "", output)
+	flags.IntVar(&concurrent, "concurrent", 3, "number of concurrent downloads")
+	flags.IntVar(&rateLimit, "rate-limit", 60, "requests per minute")
+	flags.StringVar(&rateLimitFile, "rate-limit-file", "", "share the request budget with every igscraper process using this file")
+	flags.StringVarP(&accountName, "account", "a", "", "use specific stored account")
+	flags.IntVar(&maxRetries, "max-retries", 3, "maximum number of retry attempts")
+	flags.IntVar(&downloadTimeout, "download-timeout", 30, "download timeout in seconds")
+	flags.BoolVar(&resumeDownload, "resume", false, "resume from last checkpoint")
+	flags.BoolVar(&forceRestart, "force-restart", false, "force restart, ignoring existing checkpoint")
+	flags.BoolVar(&useTUI, "tui", false, "use interactive terminal UI with real-time progress")
+	flags.StringVar(&sinceDate, "since", "", "only download media posted on or after this date (YYYY-MM-DD or RFC 3339)")
+	flags.StringVar(&untilDate, "until", "", "only download media posted on or before this date (YYYY-MM-DD or RFC 3339)")
+	flags.StringVar(&filterExpr, "filter", "", "only download posts matching this expression (e.g. 'hashtag:sunset AND likes>100')")
+	flags.IntVar(&minLikes, "min-likes", 0, "only download posts with at least this many likes")
+	flags.BoolVar(&skipVideos, "skip-videos", false, "do not download videos, only photos")
+	flags.BoolVar(&skipImages, "skip-images", false, "do not download photos, only videos")
+	flags.BoolVar(&overwrite, "overwrite", false, "download posts already saved again, replacing their files")
+	flags.IntVar(&galleryThumbnails, "gallery-thumbnails", 0, "save a copy of each photo at most this many pixels wide or tall in .thumbnails/ (e.g. 320)")
+	flags.DurationVar(&refreshNewerThan, "refresh-newer-than", 0, "download saved posts taken within this duration again if they were edited since (e.g. 168h)")
+	flags.IntVar(&maxPosts, "max-posts", 0, "stop once this many posts are queued for download (0 = no limit)")
+	flags.IntVar(&maxPages, "max-pages", 0, "stop after this many pages of posts (0 = no limit)")
+	flags.BoolVar(&embedMetadata, "embed-metadata", false, "write caption, author, post URL and date into each photo's EXIF/XMP metadata")
+	flags.BoolVar(&dedup, "dedup", false, "hardlink photos whose content is identical to an earlier download instead of storing them again")
+	flags.BoolVar(&withComments, "comments", false, "save each post's comments to comments/<shortcode>.json")
+	flags.IntVar(&maxComments, "max-comments", 0, "most comments saved per post with --comments (default 100)")
+	flags.StringVar(&maxBandwidth, "max-bandwidth", "", "limit media downloads to this bandwidth (e.g. 5MB/s)")
+	flags.StringVar(&maxTotalSize, "max-total-size", "", "pause with a checkpoint once the output directory holds this much (e.g. 50GB)")
+	flags.IntVar(&prefetchPages, "prefetch-pages", 0, "fetch up to this many media pages ahead while earlier pages download (0-5)")
+	flags.BoolVar(&skipSessionCheck, "skip-session-check", false, "do not check that the session cookies are valid before downloading")
+	flags.BoolVar(&rotateAccounts, "rotate-accounts", false, "switch to another stored account when the current one is repeatedly rate limited or logged out")
+	flags.BoolVar(&browserFallback, "browser-fallback", false, "experimental: repeat blocked API requests from a headless browser (needs a build with -tags chromedp)")
+	flags.DurationVar(&keepAlive, "keep-alive", 0, "check every so often (e.g. 15m) that the session is still logged in, pausing the scrape if it is not")
+	flags.BoolVar(&useCache, "cache", false, "reuse profile and media page responses fetched within the cache TTL instead of requesting them again")
+	flags.DurationVar(&cacheTTL, "cache-ttl", 0, "how long cached responses are reused, implies --cache (default 1h)")
+	flags.StringVar(&outputFormat, "output-format", ui.OutputText, "output format: text, or json for one JSON event per line on stdout")
+}
+
+// parseDateFlag parses a --since/--until value. A bare date means the start of
+// that day, or its end when endOfDay is set, so --until includes the whole day.
+func parseDateFlag(value string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q: use YYYY-MM-DD or RFC 3339", value)
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	return t, nil
 }
 
 func runScrape(cmd *cobra.Command, args []string) {
-	username := strings.TrimSpace(args[0])
-	
-	// Set quiet mode if log level is error
-	if logLevel == "error" {
-		ui.SetQuietMode(true)
+	usernames := make([]string, 0, len(args))
+	for _, arg := range args {
+		if username := strings.TrimSpace(arg); username != "" {
+			usernames = append(usernames, username)
+		}
 	}
-	
+	printLegacyNotice()
+
+	if useTUI && ui.IsJSONOutput() {
+		ui.PrintError("Invalid flags", "--tui cannot be combined with --output-format json")
+		os.Exit(1)
+	}
+
 	// If TUI is enabled, we'll handle output differently
 	if !useTUI {
-		ui.PrintInfo("Target Profile", username)
+		if scrapeUserID != "" {
+			ui.PrintInfo("Target Profile", "user ID "+scrapeUserID)
+		} else {
+			ui.PrintInfo("Target Profile", strings.Join(usernames, ", "))
+		}
 	}
 
-	// Build flags map from command line
-	flags := make(map[string]interface{})
+	flags := scrapeFlags()
+
+	// Load configuration
+	cfg, err := config.Load(configFile, flags)
+	if err != nil {
+		ui.PrintError("Failed to load configuration", err.Error())
+		os.Exit(1)
+	}
+
+	// Initialize logger
+	logger.Initialize(&cfg.Logging)
+	initAuditLog(cfg)
+	warnDeprecatedConfig(cfg)
+	logger.WithField("version", version).Info("Instagram Scraper starting")
+
+	// Handle credentials
+	applyCredentials(cfg)
+
+	if webAddr != "" && !dryRun && !profileOnly {
+		server, err := startWebServer(webAddr)
+		if err != nil {
+			ui.PrintError("Failed to start progress page", err.Error())
+			os.Exit(1)
+		}
+		defer server.Close()
+		ui.PrintInfo("Progress Page", server.URL())
+		webMonitor.AddProfiles(usernames...)
+	}
+
+	if scrapeUserID != "" {
+		if dryRun || profileOnly {
+			ui.PrintError("Invalid flags", "--dry-run and --profile-only need the profile's username, not --user-id")
+			os.Exit(1)
+		}
+		logger.WithField("user_id", scrapeUserID).Info("Starting scrape operation")
+		err := runDownload(cfg, "user ID "+scrapeUserID, nil, func(s *scraper.Scraper) error {
+			return s.DownloadUserID(scrapeUserID, resumeDownload, forceRestart)
+		})
+		if err != nil {
+			os.Exit(1)
+		}
+		return
+	}
+	if dryRun {
+		if err := runEstimate(cfg, usernames); err != nil {
+			os.Exit(1)
+		}
+		return
+	}
+	if profileOnly {
+		if err := runProfileSnapshots(cfg, usernames); err != nil {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Create and run scraper
+	if len(usernames) == 1 {
+		logger.WithField("username", usernames[0]).Info("Starting scrape operation")
+		if err := runScraper(cfg, usernames[0], resumeDownload, forceRestart, nil); err != nil {
+			os.Exit(1)
+		}
+		return
+	}
+
+	runBatch(cfg, usernames)
+}
+
+// scrapeFlags collects the download flags that differ from their defaults
+// into the map merged by config.Load. It exits on invalid flag values.
+func scrapeFlags() map[string]interface{} {
+	flags := profileFlags()
 	if outputDir != "" {
-		flags["base-directory"] = outputDir
+		flags["output"] = outputDir
 	}
 	if concurrent != 3 {
 		flags["concurrent-downloads"] = concurrent
@@ -129,6 +375,9 @@ func runScrape(cmd *cobra.Command, args []string) {
 	if rateLimit != 60 {
 		flags["requests-per-minute"] = rateLimit
 	}
+	if rateLimitFile != "" {
+		flags["rate-limit-file"] = rateLimitFile
+	}
 	if !notifications {
 		flags["enabled"] = false
 	}
@@ -142,100 +391,309 @@ func runScrape(cmd *cobra.Command, args []string) {
 	if logLevel != "info" {
 		flags["log-level"] = logLevel
 	}
+	if auditLog != "" {
+		flags["audit-log"] = auditLog
+	}
+	if skipSyncedWithin > 0 {
+		flags["skip-synced-within"] = skipSyncedWithin
+	}
+	if sinceDate != "" {
+		since, err := parseDateFlag(sinceDate, false)
+		if err != nil {
+			ui.PrintError("Invalid --since value", err.Error())
+			os.Exit(1)
+		}
+		flags["since"] = since
+	}
+	if untilDate != "" {
+		until, err := parseDateFlag(untilDate, true)
+		if err != nil {
+			ui.PrintError("Invalid --until value", err.Error())
+			os.Exit(1)
+		}
+		flags["until"] = until
+	}
+	if filterExpr != "" {
+		if _, err := filter.Parse(filterExpr); err != nil {
+			ui.PrintError("Invalid --filter value", err.Error())
+			os.Exit(1)
+		}
+		flags["filter"] = filterExpr
+	}
+	if minLikes > 0 {
+		flags["min-likes"] = minLikes
+	}
+	if skipVideos {
+		flags["skip-videos"] = true
+	}
+	if skipImages {
+		flags["skip-images"] = true
+	}
+	if overwrite {
+		flags["overwrite"] = true
+	}
+	if refreshNewerThan > 0 {
+		flags["refresh-newer-than"] = refreshNewerThan
+	}
+	if existingArchive != "" {
+		flags["existing-archive"] = existingArchive
+	}
+	if galleryThumbnails > 0 {
+		flags["gallery-thumbnails"] = galleryThumbnails
+	}
+	if maxPosts > 0 {
+		flags["max-posts"] = maxPosts
+	}
+	if maxPages > 0 {
+		flags["max-pages"] = maxPages
+	}
+	if embedMetadata {
+		flags["embed-metadata"] = true
+	}
+	if dedup {
+		flags["dedup"] = true
+	}
+	if withComments {
+		flags["comments"] = true
+	}
+	if maxComments > 0 {
+		flags["max-comments"] = maxComments
+	}
+	if maxBandwidth != "" {
+		if _, err := ratelimit.ParseBandwidth(maxBandwidth); err != nil {
+			ui.PrintError("Invalid --max-bandwidth value", err.Error())
+			os.Exit(1)
+		}
+		flags["max-bandwidth"] = maxBandwidth
+	}
+	if maxTotalSize != "" {
+		quota, err := storage.ParseSize(maxTotalSize)
+		if err != nil {
+			ui.PrintError("Invalid --max-total-size value", err.Error())
+			os.Exit(1)
+		}
+		flags["max-total-size"] = quota
+	}
+	if prefetchPages > 0 {
+		flags["prefetch-pages"] = prefetchPages
+	}
+	if rotateAccounts {
+		flags["rotate-accounts"] = true
+	}
+	if browserFallback {
+		flags["browser-fallback"] = true
+	}
+	if keepAlive > 0 {
+		flags["keep-alive"] = keepAlive
+	}
+	if useCache {
+		flags["cache"] = true
+	}
+	if cacheTTL > 0 {
+		flags["cache-ttl"] = cacheTTL
+	}
+	return flags
+}
 
-	// Load configuration
-	cfg, err := config.Load(configFile, flags)
+// runBatch scrapes several profiles in turn. A failing profile is reported
+// and the batch moves on; the process exits non-zero if any profile failed.
+func runBatch(cfg *config.Config, usernames []string) {
+	logger.WithField("profiles", len(usernames)).Info("Starting batch scrape")
+
+	// One worker pool downloads for every profile instead of each starting its own
+	pool := scraper.NewWorkerPool(cfg, logger.Named(logger.ComponentDownloader))
+	pool.Start()
+
+	// and one bandwidth limit covers them all, keeping changes made in the TUI
+	maxBandwidth, _ := ratelimit.ParseBandwidth(cfg.Download.MaxBandwidth)
+	bandwidth := ratelimit.NewBandwidth(maxBandwidth)
+
+	// and one quota covers every profile's downloads
+	guard, err := storage.NewGuard(cfg.Output.BaseDirectory, cfg.Download.MinFreeSpace, cfg.Download.MaxTotalSize)
 	if err != nil {
-		ui.PrintError("Failed to load configuration", err.Error())
+		ui.PrintError("Failed to check disk space", err.Error())
 		os.Exit(1)
 	}
 
-	// Initialize logger
-	logger.Initialize(&cfg.Logging)
-	logger.WithField("version", version).Info("Instagram Scraper starting")
+	var failed []string
+	var stopErr error
+	scrapeAll := func() {
+		for i, username := range usernames {
+			if ui.IsJSONOutput() {
+				ui.EmitEvent("profile_started", map[string]interface{}{
+					"username": username,
+					"index":    i + 1,
+					"profiles": len(usernames),
+				})
+			} else if !useTUI {
+				ui.PrintHighlight(fmt.Sprintf("[PROFILE %d/%d] %s", i+1, len(usernames), username))
+			}
+			logger.WithField("username", username).Info("Starting scrape operation")
 
-	// Handle credentials
-	credManager, err := auth.NewManager()
-	if err != nil {
-		ui.PrintError("Failed to initialize credential manager", err.Error())
-		os.Exit(1)
+			err := runScraper(cfg, username, resumeDownload, forceRestart, func(s *scraper.Scraper) {
+				s.SetSkipSynced(cfg.Download.SkipSyncedWithin)
+				s.SetWorkerPool(pool)
+				s.SetBandwidth(bandwidth)
+				s.SetStorageGuard(guard)
+			})
+			if err != nil {
+				failed = append(failed, username)
+			}
+			// The remaining profiles would pause straight away, or be refused
+			// until the account passes its security check or logs in again.
+			// An abort from the TUI or an interrupt stops the whole batch.
+			var spaceErr *storage.SpaceError
+			if errors.As(err, &spaceErr) || igerrors.IsChallenge(err) || errors.Is(err, scraper.ErrSessionLost) || errors.Is(err, scraper.ErrAborted) || interruptContext().Err() != nil {
+				failed = append(failed, usernames[i+1:]...)
+				stopErr = err
+				break
+			}
+		}
 	}
 
-	var account *auth.Account
+	if useTUI {
+		// Every profile is shown on one dashboard rather than a TUI each
+		batchTerminal = tui.NewTUI(cfg.Download.ConcurrentDownloads)
+		batchTerminal.AddProfiles(usernames...)
+		batchTerminal.SetBandwidth(bandwidth)
 
-	// Try to get credentials from various sources
-	if accountName != "" {
-		// Use specific account
-		account, err = credManager.Retrieve(accountName)
-		if err != nil {
-			ui.PrintError("Account not found", accountName)
-			ui.PrintInfo("Available accounts", "Use 'igscraper auth list' to see stored accounts")
+		scraped := make(chan struct{})
+		go func() {
+			scrapeAll()
+			close(scraped)
+		}()
+		tuiDone := make(chan error, 1)
+		go func() {
+			tuiDone <- batchTerminal.Start()
+		}()
+
+		select {
+		case <-scraped:
+			batchTerminal.Stop()
+			<-tuiDone
+		case err := <-tuiDone:
+			if err != nil {
+				logger.WithError(err).Error("TUI failed")
+			}
+			ui.PrintWarning("Batch stopped", "run again with --resume to continue")
 			os.Exit(1)
 		}
-	} else if cfg.Instagram.SessionID != "" && cfg.Instagram.CSRFToken != "" && 
-			  cfg.Instagram.SessionID != "YOUR_SESSION_ID" && cfg.Instagram.CSRFToken != "YOUR_CSRF_TOKEN" {
-		// Use credentials from config/env (backward compatibility)
-		logger.Info("Using credentials from configuration")
-	} else {
-		// Try to get default account from credential manager
-		account, err = credManager.RetrieveDefault()
-		if err != nil {
-			// No credentials found anywhere
-			logger.Error("No credentials found")
-			ui.PrintError("No Instagram credentials found", "")
-			fmt.Println("\nTo store credentials securely, run:")
-			fmt.Println("  igscraper auth login")
-			fmt.Println("\nFor backward compatibility, you can also set environment variables:")
-			fmt.Println("  export IGSCRAPER_SESSION_ID=your_session_id")
-			fmt.Println("  export IGSCRAPER_CSRF_TOKEN=your_csrf_token")
-			os.Exit(1)
+		batchTerminal = nil
+		if errors.Is(stopErr, scraper.ErrAborted) || errors.Is(stopErr, scraper.ErrInterrupted) {
+			ui.PrintWarning("Batch stopped", "continue with "+resumeCommand(os.Args))
 		}
+		printChallengeHelp(stopErr)
+	} else {
+		scrapeAll()
 	}
+	pool.Stop()
 
-	// If we got an account from credential manager, update config
-	if account != nil {
-		cfg.Instagram.SessionID = account.SessionID
-		cfg.Instagram.CSRFToken = account.CSRFToken
-		if account.UserAgent != "" {
-			cfg.Instagram.UserAgent = account.UserAgent
-		}
-		logger.WithField("account", account.Username).Info("Using stored credentials")
-		ui.PrintInfo("Using account", account.Username)
-	}
+	logger.WithFields(map[string]interface{}{
+		"profiles": len(usernames),
+		"failed":   len(failed),
+	}).Info("Batch scrape finished")
 
-	// Final credential validation
-	if cfg.Instagram.SessionID == "" || cfg.Instagram.SessionID == "YOUR_SESSION_ID" {
-		logger.Error("Missing Instagram session ID")
-		ui.PrintError("Missing Instagram session ID", "Run 'igscraper auth login' to store credentials")
-		os.Exit(1)
+	if ui.IsJSONOutput() {
+		ui.EmitEvent("batch_summary", map[string]interface{}{
+			"profiles":        len(usernames),
+			"failed":          len(failed),
+			"failed_profiles": append([]string{}, failed...),
+		})
 	}
-
-	if cfg.Instagram.CSRFToken == "" || cfg.Instagram.CSRFToken == "YOUR_CSRF_TOKEN" {
-		logger.Error("Missing Instagram CSRF token")
-		ui.PrintError("Missing Instagram CSRF token", "Run 'igscraper auth login' to store credentials")
+	if len(failed) > 0 {
+		ui.PrintError("Batch finished with failures", strings.Join(failed, ", "))
 		os.Exit(1)
 	}
+	ui.PrintSuccess(fmt.Sprintf("[BATCH COMPLETED: %d PROFILES]", len(usernames)))
+}
 
-	logger.WithField("username", username).Info("Starting scrape operation")
+// runScraper creates a scraper for cfg, lets setup customise it and downloads
+// the profile, either under the TUI or with the plain progress output.
+// Failures are reported to the user before being returned.
+func runScraper(cfg *config.Config, username string, resume, restart bool, setup func(*scraper.Scraper)) error {
+	return runDownload(cfg, username, setup, func(s *scraper.Scraper) error {
+		return s.DownloadUserPhotosWithResume(username, resume, restart)
+	})
+}
+
+// batchTerminal is the dashboard a batch shows its profiles on, set while a
+// batch runs with --tui
+var batchTerminal *tui.TUI
+
+// runDownload runs download on a new scraper under the TUI or with the plain
+// progress output. target names what is being downloaded in the logs.
+func runDownload(cfg *config.Config, target string, setup func(*scraper.Scraper), download func(*scraper.Scraper) error) error {
+	if batchTerminal != nil {
+		// The batch runs the TUI and reports a challenge once it has stopped
+		s, err := scraper.New(cfg)
+		if err != nil {
+			batchTerminal.LogError("Failed to initialize scraper: %v", err)
+			batchTerminal.FinishProfile(target, err)
+			return err
+		}
+		if credentialManager != nil {
+			s.SetSessionStore(credentialManager, storedAccount)
+		}
+		if accountRotator != nil {
+			s.SetAccountRotator(accountRotator)
+		}
+		if headlessBrowser != nil {
+			s.SetBrowserFallback(headlessBrowser)
+		}
+		if setup != nil {
+			setup(s)
+		}
+		s.SetContext(interruptContext())
+		s.SetTUI(withWebMonitor(batchTerminal))
+		batchTerminal.SetRateLimiter(s.RateLimiter())
+
+		if err := download(s); err != nil {
+			logger.WithError(err).WithField("target", target).Error("Extraction failed")
+			return err
+		}
+		logger.WithField("target", target).Info("Extraction completed successfully")
+		return nil
+	}
 
-	// Create and run scraper
 	if useTUI {
+		if ui.IsJSONOutput() {
+			ui.PrintError("Invalid flags", "--tui cannot be combined with --output-format json")
+			return errors.New("--tui cannot be combined with --output-format json")
+		}
+		
 		// Create TUI
 		terminal := tui.NewTUI(cfg.Download.ConcurrentDownloads)
 		
 		// Run scraper in a goroutine
 		scraperDone := make(chan error)
+		var scraped *scraper.Scraper
 		go func() {
 			s, err := scraper.New(cfg)
 			if err != nil {
 				scraperDone <- err
 				return
 			}
+			if credentialManager != nil {
+				s.SetSessionStore(credentialManager, storedAccount)
+			}
+			if accountRotator != nil {
+				s.SetAccountRotator(accountRotator)
+			}
+			if headlessBrowser != nil {
+				s.SetBrowserFallback(headlessBrowser)
+			}
+			if setup != nil {
+				setup(s)
+			}
+			s.SetContext(interruptContext())
+			scraped = s
 			
 			// Set the TUI on the scraper
-			s.SetTUI(terminal)
+			s.SetTUI(withWebMonitor(terminal))
+			terminal.SetBandwidth(s.Bandwidth())
+			terminal.SetRateLimiter(s.RateLimiter())
 			
-			err = s.DownloadUserPhotosWithResume(username, resumeDownload, forceRestart)
+			err = download(s)
 			scraperDone <- err
 		}()
 		
@@ -250,18 +708,29 @@ func runScrape(cmd *cobra.Command, args []string) {
 		case err := <-scraperDone:
 			terminal.Stop()
 			<-tuiDone // Wait for TUI to finish
+			if errors.Is(err, scraper.ErrAborted) {
+				ui.PrintWarning("Scrape stopped", "run again with --resume to continue")
+				return err
+			}
+			if scraped != nil {
+				printReport(scraped)
+			}
+			if errors.Is(err, scraper.ErrInterrupted) {
+				return err
+			}
 			if err != nil {
-				logger.WithError(err).WithField("username", username).Error("Extraction failed")
-				os.Exit(1)
+				logger.WithError(err).WithField("target", target).Error("Extraction failed")
+				printChallengeHelp(err)
+				return err
 			}
 		case err := <-tuiDone:
 			if err != nil {
 				logger.WithError(err).Error("TUI failed")
-				os.Exit(1)
+				return err
 			}
 		}
 		
-		logger.WithField("username", username).Info("Extraction completed successfully")
+		logger.WithField("target", target).Info("Extraction completed successfully")
 	} else {
 		// Original non-TUI flow
 		ui.PrintHighlight("[INITIATING EXTRACTION SEQUENCE]")
@@ -269,47 +738,323 @@ func runScrape(cmd *cobra.Command, args []string) {
 		s, err := scraper.New(cfg)
 		if err != nil {
 			ui.PrintError("Failed to initialize scraper", err.Error())
-			os.Exit(1)
+			return err
+		}
+		if credentialManager != nil {
+			s.SetSessionStore(credentialManager, storedAccount)
+		}
+		if accountRotator != nil {
+			s.SetAccountRotator(accountRotator)
+		}
+		if headlessBrowser != nil {
+			s.SetBrowserFallback(headlessBrowser)
+		}
+		if setup != nil {
+			setup(s)
+		}
+		s.SetContext(interruptContext())
+		if webMonitor != nil {
+			s.SetTUI(ui.Multi(consoleTUI{}, webMonitor))
 		}
 
-		err = s.DownloadUserPhotosWithResume(username, resumeDownload, forceRestart)
+		err = download(s)
+		printReport(s)
+		if errors.Is(err, scraper.ErrInterrupted) {
+			logger.WithField("target", target).Info("Extraction interrupted")
+			return err
+		}
 		if err != nil {
-			logger.WithError(err).WithField("username", username).Error("Extraction failed")
+			logger.WithError(err).WithField("target", target).Error("Extraction failed")
 			ui.PrintError("EXTRACTION FAILED", err.Error())
-			os.Exit(1)
+			printChallengeHelp(err)
+			return err
 		}
 
-		logger.WithField("username", username).Info("Extraction completed successfully")
+		logger.WithField("target", target).Info("Extraction completed successfully")
 		ui.PrintSuccess("[EXTRACTION COMPLETED SUCCESSFULLY]")
 	}
+	return nil
 }
 
-// Make scrape the default command when no subcommand is specified
-func init() {
-	// Add a hidden alias to make scraping work without the "scrape" subcommand
-	origRunE := rootCmd.RunE
-	rootCmd.RunE = func(cmd *cobra.Command, args []string) error {
-		if origRunE != nil {
-			return origRunE(cmd, args)
+// printReport prints the report of the scrape that just ended, with the
+// command that continues it when it was interrupted. Scrapes that stopped
+// before downloading have none.
+func printReport(s *scraper.Scraper) {
+	summary := s.Summary()
+	if summary.Status == "" {
+		return
+	}
+	resume := ""
+	if summary.Interrupted() {
+		resume = resumeCommand(os.Args)
+	}
+	if ui.IsJSONOutput() {
+		report := summary.Report(s.ScrapeID())
+		ui.EmitEvent("report", map[string]interface{}{
+			"target":            report.Target,
+			"status":            report.Status,
+			"duration_seconds":  report.DurationSeconds,
+			"pages":             report.Pages,
+			"downloaded":        report.Downloaded,
+			"bytes":             report.Bytes,
+			"bytes_per_second":  report.BytesPerSecond,
+			"skipped":           report.Skipped,
+			"failed":            report.Failed,
+			"failures":          report.Failures,
+			"cancelled":         report.Cancelled,
+			"rate_limit_pauses": report.RateLimitPauses,
+			"resume_command":    resume,
+		})
+		return
+	}
+	if ui.IsQuietMode() && !ui.IsProgressOnlyMode() {
+		return
+	}
+
+	fmt.Printf("\n%s %s", ui.Cyan("[SCRAPE REPORT]"), summary.Table())
+	if summary.Interrupted() {
+		if resume != "" {
+			fmt.Printf("  %-12s %s\n", "Continue", ui.Green(resume))
+		} else {
+			fmt.Printf("  %-12s %s\n", "Continue", "run the command again to start over")
 		}
-		if len(args) > 0 && !isKnownCommand(args[0]) {
-			// If the first argument is not a known command, treat it as a username
-			// No need to transfer flags since we're using the same variables
-			return scrapeCmd.RunE(scrapeCmd, args)
+	}
+}
+
+// printChallengeHelp tells the user how to get past the security check
+// Instagram put the account behind, when err is such a challenge. The scrape
+// has already stopped: every further request would be refused.
+func printChallengeHelp(err error) {
+	var apiErr *igerrors.Error
+	if !errors.As(err, &apiErr) || apiErr.Type != igerrors.ErrorTypeChallenge {
+		return
+	}
+	where := "instagram.com"
+	if apiErr.ChallengeURL != "" {
+		where = apiErr.ChallengeURL
+	}
+	if ui.IsJSONOutput() {
+		ui.PrintError("Instagram account needs a security check", "complete it at "+where)
+		return
+	}
+
+	ui.PrintError("Instagram account needs a security check", "")
+	fmt.Printf("  → Open %s in a browser logged in as this account and complete the check\n", where)
+	fmt.Println("  → Pause scraping with this account for 24-48 hours")
+	fmt.Println("  → Use a lower --rate-limit once the account works again")
+	fmt.Println("  → Confirm the session works with: igscraper auth test")
+}
+
+// applyCredentials fills in the Instagram credentials from --account, the
+// config/environment or the default stored account, in that order, and checks
+// them unless --skip-session-check is set. It exits the process when no
+// usable credentials are found.
+func applyCredentials(cfg *config.Config) {
+	credManager, err := auth.NewManager()
+	if err != nil {
+		ui.PrintError("Failed to initialize credential manager", err.Error())
+		os.Exit(1)
+	}
+
+	var account *auth.Account
+
+	// Try to get credentials from various sources
+	if accountName != "" {
+		// Use specific account
+		account, err = credManager.Retrieve(accountName)
+		if err != nil {
+			ui.PrintError("Account not found", accountName)
+			ui.PrintInfo("Available accounts", "Use 'igscraper auth list' to see stored accounts")
+			os.Exit(1)
+		}
+	} else if cfg.Instagram.SessionID != "" && cfg.Instagram.CSRFToken != "" && 
+			  cfg.Instagram.SessionID != "YOUR_SESSION_ID" && cfg.Instagram.CSRFToken != "YOUR_CSRF_TOKEN" {
+		// Use credentials from config/env (backward compatibility)
+		logger.Info("Using credentials from configuration")
+	} else {
+		// Try to get default account from credential manager
+		account, err = credManager.RetrieveDefault()
+		if err != nil {
+			// No credentials found anywhere
+			logger.Error("No credentials found")
+			if ui.IsJSONOutput() {
+				ui.PrintError("No Instagram credentials found", "run 'igscraper auth login' or set IGSCRAPER_SESSION_ID and IGSCRAPER_CSRF_TOKEN")
+				os.Exit(1)
+			}
+			ui.PrintError("No Instagram credentials found", "")
+			fmt.Println("\nTo store credentials securely, run:")
+			fmt.Println("  igscraper auth login")
+			fmt.Println("\nFor backward compatibility, you can also set environment variables:")
+			fmt.Println("  export IGSCRAPER_SESSION_ID=your_session_id")
+			fmt.Println("  export IGSCRAPER_CSRF_TOKEN=your_csrf_token")
+			os.Exit(1)
+		}
+	}
+
+	// If we got an account from credential manager, update config
+	if account != nil {
+		cfg.Instagram.SessionID = account.SessionID
+		cfg.Instagram.CSRFToken = account.CSRFToken
+		if err := credManager.EnsureDevice(account); err != nil {
+			logger.WithError(err).WithField("account", account.Username).Debug("No device profile saved for the account")
+		}
+		applyDevice(cfg, account)
+		storedAccount = account.Username
+		logger.WithField("account", account.Username).Info("Using stored credentials")
+		ui.PrintInfo("Using account", account.Username)
+	}
+
+	// Final credential validation
+	if cfg.Instagram.SessionID == "" || cfg.Instagram.SessionID == "YOUR_SESSION_ID" {
+		logger.Error("Missing Instagram session ID")
+		ui.PrintError("Missing Instagram session ID", "Run 'igscraper auth login' to store credentials")
+		os.Exit(1)
+	}
+
+	if cfg.Instagram.CSRFToken == "" || cfg.Instagram.CSRFToken == "YOUR_CSRF_TOKEN" {
+		logger.Error("Missing Instagram CSRF token")
+		ui.PrintError("Missing Instagram CSRF token", "Run 'igscraper auth login' to store credentials")
+		os.Exit(1)
+	}
+
+	if !skipSessionCheck {
+		verifySession(cfg, account)
+	}
+	
+	credentialManager = credManager
+	if cfg.Instagram.RotateAccounts {
+		accountRotator = newAccountRotator(cfg, credManager, account)
+	}
+	if cfg.Instagram.BrowserFallback {
+		headlessBrowser = newHeadlessBrowser(cfg)
+	}
+}
+
+// applyDevice makes requests present the device fingerprint of account
+func applyDevice(cfg *config.Config, account *auth.Account) {
+	device := account.Fingerprint()
+	if device.UserAgent != "" {
+		cfg.Instagram.UserAgent = device.UserAgent
+	}
+	cfg.Instagram.AppID = device.AppID
+	cfg.Instagram.AcceptLanguage = device.AcceptLanguage
+	cfg.Instagram.DeviceID = device.DeviceID
+	cfg.Instagram.MachineID = device.MachineID
+}
+
+// newHeadlessBrowser sets up the browser fallback with the credentials in
+// use. It exits the process when this build cannot drive a browser.
+func newHeadlessBrowser(cfg *config.Config) *browser.Browser {
+	b, err := browser.New(browser.Options{
+		SessionID: cfg.Instagram.SessionID,
+		CSRFToken: cfg.Instagram.CSRFToken,
+		UserAgent: cfg.Instagram.UserAgent,
+		ExecPath:  cfg.Instagram.BrowserPath,
+		Timeout:   cfg.Download.DownloadTimeout,
+	})
+	if err != nil {
+		ui.PrintError("Browser fallback unavailable", err.Error())
+		os.Exit(1)
+	}
+	logger.Warn("Experimental headless browser fallback enabled")
+	return b
+}
+
+// newAccountRotator builds the rotation from the account in use followed by
+// the other stored accounts. Accounts without their own User-Agent send the
+// configured one. It returns nil when there is nothing to rotate to.
+func newAccountRotator(cfg *config.Config, credManager *auth.Manager, current *auth.Account) *auth.AccountRotator {
+	if current == nil {
+		// Credentials from the config file or environment lead the rotation
+		current = &auth.Account{
+			Username:  "configured credentials",
+			SessionID: cfg.Instagram.SessionID,
+			CSRFToken: cfg.Instagram.CSRFToken,
+		}
+	}
+	accounts := []*auth.Account{current}
+	
+	stored, err := credManager.List()
+	if err != nil {
+		logger.WithError(err).Warn("Failed to list stored accounts for rotation")
+	}
+	sort.Slice(stored, func(i, j int) bool {
+		return stored[i].Username < stored[j].Username
+	})
+	for _, account := range stored {
+		if account.Username != current.Username && account.SessionID != current.SessionID {
+			if err := credManager.EnsureDevice(account); err != nil {
+				logger.WithError(err).WithField("account", account.Username).Debug("No device profile saved for the account")
+			}
+			accounts = append(accounts, account)
 		}
-		// Otherwise show help
-		return cmd.Help()
 	}
 	
-	// Set Args to allow arbitrary arguments
-	rootCmd.Args = cobra.ArbitraryArgs
+	if len(accounts) < 2 {
+		logger.Warn("Account rotation needs at least two stored accounts")
+		printSessionWarning("Account rotation needs at least two stored accounts", "add another with 'igscraper auth login'")
+		return nil
+	}
+	
+	names := make([]string, len(accounts))
+	for i, account := range accounts {
+		if account.UserAgent == "" && account.Device == nil {
+			account.UserAgent = cfg.Instagram.UserAgent
+		}
+		names[i] = account.Username
+	}
+	rotator, err := auth.NewAccountRotator(accounts, cfg.Instagram.RotateAfter)
+	if err != nil {
+		ui.PrintError("Failed to set up account rotation", err.Error())
+		os.Exit(1)
+	}
+	logger.WithFields(map[string]interface{}{
+		"accounts":     strings.Join(names, ", "),
+		"rotate_after": cfg.Instagram.RotateAfter,
+	}).Info("Account rotation enabled")
+	return rotator
 }
 
-func isKnownCommand(arg string) bool {
-	for _, cmd := range rootCmd.Commands() {
-		if cmd.Name() == arg || cmd.HasAlias(arg) {
-			return true
+// verifySession checks the credentials with one request before anything is
+// downloaded, so an expired session fails fast with a clear message instead
+// of partway through the first profile. Inconclusive answers such as rate
+// limiting only warn; the scrape's own retries deal with those.
+func verifySession(cfg *config.Config, account *auth.Account) {
+	check := testCredentials(cfg)
+	logger.WithField("state", string(check.State)).Debug("Checked Instagram session")
+
+	switch check.State {
+	case doctor.SessionExpired:
+		logger.Error("Instagram session expired")
+		ui.PrintError("Instagram session expired", "log in at instagram.com, then refresh your cookies with 'igscraper auth login'")
+		os.Exit(1)
+	case doctor.SessionChallenged:
+		logger.WithField("challenge", check.Result.Challenge).Error("Instagram account challenged")
+		ui.PrintError("Instagram account needs a security check", "log in at instagram.com in a browser and complete it, then try again")
+		os.Exit(1)
+	case doctor.SessionUnknown:
+		fields := map[string]interface{}{"status": check.Result.StatusCode}
+		if check.Result.Err != nil {
+			fields["error"] = check.Result.Err.Error()
 		}
+		logger.WithFields(fields).Warn("Could not verify Instagram session")
+		printSessionWarning("Could not verify the Instagram session", "continuing anyway; run 'igscraper doctor' if downloads fail")
+	}
+
+	if account != nil && account.ExpiresSoon(time.Now()) {
+		logger.WithField("expires", account.ExpiresAt().Format("2006-01-02")).Warn("Instagram session close to expiry")
+		printSessionWarning("Session cookies are close to expiring", "refresh them soon with 'igscraper auth import-browser "+account.Username+"'")
 	}
-	return false
-}
\ No newline at end of file
+}
+
+// printSessionWarning prints a session warning. Unlike ui.PrintWarning it is
+// shown in the default progress-only mode, and only --quiet hides it.
+func printSessionWarning(msg, detail string) {
+	if ui.IsJSONOutput() {
+		ui.PrintWarning(msg, detail)
+		return
+	}
+	if !quiet {
+		fmt.Println(ui.Yellow(msg + ": " + detail))
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"igscraper/pkg/config"
+	"igscraper/pkg/export"
+	"igscraper/pkg/logger"
+	"igscraper/pkg/scraper"
+	"igscraper/pkg/search"
+	"igscraper/pkg/ui"
+)
+
+// searchLimit caps the posts the search command lists
+var searchLimit int
+
+// searchCmd represents the search command
+var searchCmd = &cobra.Command{
+	Use:   "search <username> <query>",
+	Short: "Find posts in a downloaded profile by caption, hashtag or date",
+	Long: `Search the posts of a profile's folder by the words of their captions, alt
+text and locations, their hashtags and the dates they were taken. The index is
+built from metadata.json without any request to Instagram.
+
+A post is listed when it matches every term of the query:
+  beach                 a word, in any case
+  beach*                a word starting with beach
+  "golden hour"         the words next to each other
+  #sunset               a hashtag (also hashtag:sunset)
+  2024-03, 2024-03-15   taken in that month or on that day
+  date:2024             taken in that year, month or day
+  since:2024-01-01      taken on or after it
+  until:2024-06         taken on or before it
+
+Matches are listed newest first with the files they were saved as.`,
+	Example: `  # Posts tagged #sunset
+  igscraper search username "#sunset"
+
+  # Posts mentioning golden hour taken in 2024
+  igscraper search username '"golden hour" date:2024'
+
+  # The 10 latest posts at the beach, in an archive elsewhere
+  igscraper search username beach --output ./archive --limit 10`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		runSearch(cmd, args)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(searchCmd)
+
+	// Local flags for search command
+	flags := searchCmd.Flags()
+	flags.StringVarP(&outputDir, "output", "o", "", "output directory the profile was scraped into (default: current directory)")
+	flags.IntVar(&searchLimit, "limit", 0, "list at most this many posts (0 = all)")
+}
+
+func runSearch(cmd *cobra.Command, args []string) {
+	username := strings.TrimSpace(args[0])
+
+	// Unquoted words after the username are one query
+	query, err := search.ParseQuery(strings.Join(args[1:], " "))
+	if err != nil {
+		ui.PrintError("Invalid query", err.Error())
+		os.Exit(1)
+	}
+
+	cfg, err := config.Load(configFile, scrapeFlags())
+	if err != nil {
+		ui.PrintError("Failed to load configuration", err.Error())
+		os.Exit(1)
+	}
+	logger.Initialize(&cfg.Logging)
+
+	s, err := scraper.New(cfg)
+	if err != nil {
+		ui.PrintError("Failed to create scraper", err.Error())
+		os.Exit(1)
+	}
+
+	archive, err := export.LoadArchive(s.OutputDir(username))
+	if err != nil {
+		ui.PrintError("Failed to load archive", err.Error())
+		os.Exit(1)
+	}
+
+	index := search.Build(archive.Metadata)
+	results := index.Search(query)
+	printSearchResults(archive, index, query, results)
+}
+
+// printSearchResults lists the posts found, each with its date, files and
+// the start of its caption
+func printSearchResults(archive *export.Archive, index *search.Index, query *search.Query, results []search.Result) {
+	fmt.Println()
+	fmt.Printf("%s %s in @%s\n", ui.Magenta("Search for"), query, archive.Metadata.Username)
+	fmt.Printf("  %s %d of %d posts\n\n", ui.Cyan("Matched:"), len(results), index.Len())
+
+	if len(results) == 0 {
+		fmt.Println(ui.Yellow("No posts match"))
+		fmt.Println()
+		return
+	}
+
+	shown := results
+	if searchLimit > 0 && len(shown) > searchLimit {
+		shown = shown[:searchLimit]
+	}
+	for _, result := range shown {
+		photo := result.Photo
+		date := "          "
+		if !photo.TakenAt.IsZero() && photo.TakenAt.Unix() != 0 {
+			date = photo.TakenAt.Local().Format("2006-01-02")
+		}
+		fmt.Printf("  %s  %s  %s\n", ui.Dim(date), ui.Green(photo.Shortcode), filepath.Join(archive.Dir, result.Files[0]))
+		for _, name := range result.Files[1:] {
+			fmt.Printf("  %s  %s\n", strings.Repeat(" ", len(date)+2+len(photo.Shortcode)), filepath.Join(archive.Dir, name))
+		}
+		if caption := captionLine(photo.Caption, 72); caption != "" {
+			fmt.Printf("  %s  %s\n", strings.Repeat(" ", len(date)), ui.Dim(caption))
+		}
+	}
+	if len(shown) < len(results) {
+		fmt.Printf("\n  … %d more, list them with --limit 0\n", len(results)-len(shown))
+	}
+	fmt.Println()
+}
+
+// captionLine returns a caption on one line, cut to at most limit characters
+func captionLine(caption string, limit int) string {
+	line := []rune(strings.Join(strings.Fields(caption), " "))
+	if len(line) <= limit {
+		return string(line)
+	}
+	return strings.TrimSpace(string(line[:limit-1])) + "…"
+}
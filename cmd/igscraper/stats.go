@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"igscraper/pkg/config"
+	"igscraper/pkg/logger"
+	"igscraper/pkg/scraper"
+	"igscraper/pkg/ui"
+	"igscraper/pkg/ui/tui"
+)
+
+// statsCmd represents the stats command
+var statsCmd = &cobra.Command{
+	Use:   "stats <username>",
+	Short: "Count a profile's posts by kind, date and likes without downloading",
+	Long: `Walk a profile's timeline without downloading anything and report what a
+scrape would be up against:
+
+  • Photos, videos and carousels, and how many posts are pinned
+  • The dates of the oldest and newest post
+  • A rough size of the download, one file per post
+  • How the posts' likes are distributed
+
+Only timeline pages are requested, so a scan costs one request per 50 posts.
+--max-pages scans just the newest posts of a large profile.`,
+	Example: `  # Statistics of the whole profile
+  igscraper stats johndoe
+
+  # Sample the 250 newest posts
+  igscraper stats johndoe --max-pages 5
+
+  # As a JSON stats event
+  igscraper stats johndoe --output-format json`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		runStats(cmd, args)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+
+	// Local flags for stats command
+	flags := statsCmd.Flags()
+	flags.IntVar(&rateLimit, "rate-limit", 60, "requests per minute")
+	flags.StringVarP(&accountName, "account", "a", "", "use specific stored account")
+	flags.BoolVar(&skipSessionCheck, "skip-session-check", false, "do not check that the session cookies are valid before scanning")
+	flags.BoolVar(&rotateAccounts, "rotate-accounts", false, "switch to another stored account when the current one is repeatedly rate limited or logged out")
+	flags.IntVar(&maxPages, "max-pages", 0, "only scan this many pages of posts, the newest (0 = all)")
+	flags.StringVar(&outputFormat, "output-format", ui.OutputText, "output format: text, or json for a stats event on stdout")
+}
+
+func runStats(cmd *cobra.Command, args []string) {
+	username := strings.TrimSpace(args[0])
+
+	cfg, err := config.Load(configFile, scrapeFlags())
+	if err != nil {
+		ui.PrintError("Failed to load configuration", err.Error())
+		os.Exit(1)
+	}
+
+	logger.Initialize(&cfg.Logging)
+	initAuditLog(cfg)
+	warnDeprecatedConfig(cfg)
+	applyCredentials(cfg)
+
+	s, err := scraper.New(cfg)
+	if err != nil {
+		ui.PrintError("Failed to create scraper", err.Error())
+		os.Exit(1)
+	}
+	if credentialManager != nil {
+		s.SetSessionStore(credentialManager, storedAccount)
+	}
+	if accountRotator != nil {
+		s.SetAccountRotator(accountRotator)
+	}
+	if headlessBrowser != nil {
+		s.SetBrowserFallback(headlessBrowser)
+	}
+
+	quietOutput := ui.IsQuietMode() || ui.IsJSONOutput()
+	stats, err := s.ScanProfile(username, func(scanned int) {
+		if !quietOutput {
+			fmt.Printf("\r%s %d posts", ui.Cyan("Scanning timeline:"), scanned)
+		}
+	})
+	if !quietOutput {
+		fmt.Println()
+	}
+	if err != nil {
+		ui.PrintError("Scan failed", err.Error())
+		os.Exit(1)
+	}
+
+	printProfileStats(stats)
+}
+
+// printProfileStats prints the statistics of a profile, as a stats event
+// with --output-format json
+func printProfileStats(p *scraper.ProfileStats) {
+	if ui.IsJSONOutput() {
+		fields := map[string]interface{}{
+			"username":        p.Username,
+			"posts":           p.Posts,
+			"scanned":         p.Scanned,
+			"pages":           p.Pages,
+			"photos":          p.Photos,
+			"videos":          p.Videos,
+			"carousels":       p.Carousels,
+			"pinned":          p.Pinned,
+			"estimated_bytes": p.EstimatedBytes,
+			"likes":           p.Likes,
+		}
+		if !p.Oldest.IsZero() {
+			fields["oldest"] = p.Oldest
+			fields["newest"] = p.Newest
+		}
+		ui.EmitEvent("stats", fields)
+		return
+	}
+
+	fmt.Println()
+	fmt.Printf("%s @%s\n", ui.Magenta("Post statistics for"), p.Username)
+	posts := "unknown"
+	if p.Posts >= 0 {
+		posts = fmt.Sprint(p.Posts)
+	}
+	fmt.Printf("  %s %s, %d scanned on %d pages\n", ui.Cyan("Posts:"), posts, p.Scanned, p.Pages)
+	fmt.Printf("  %s %d photos, %d videos, %d carousels\n", ui.Cyan("Kinds:"), p.Photos, p.Videos, p.Carousels)
+	if p.Pinned > 0 {
+		fmt.Printf("  %s %d\n", ui.Cyan("Pinned:"), p.Pinned)
+	}
+	if !p.Oldest.IsZero() {
+		fmt.Printf("  %s %s to %s\n", ui.Cyan("Dates:"), p.Oldest.Format("2006-01-02"), p.Newest.Format("2006-01-02"))
+	}
+	fmt.Printf("  %s about %s\n", ui.Cyan("Download size:"), tui.FormatBytes(p.EstimatedBytes))
+
+	if p.Scanned == 0 {
+		return
+	}
+	likes := p.Likes
+	fmt.Printf("  %s min %d, median %d, mean %.0f, 90th percentile %d, max %d\n",
+		ui.Cyan("Likes:"), likes.Min, likes.Median, likes.Mean, likes.P90, likes.Max)
+	for _, bucket := range likes.Buckets {
+		label := fmt.Sprintf("%d+", bucket.Min)
+		if bucket.Max > 0 {
+			label = fmt.Sprintf("%d-%d", bucket.Min, bucket.Max-1)
+		}
+		fmt.Printf("    %-12s %d\n", label, bucket.Posts)
+	}
+	fmt.Println(ui.Dim("  A scrape saves one file per post, the first of a carousel; igscraper post saves them all."))
+}
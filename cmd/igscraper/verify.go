@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"igscraper/pkg/config"
+	"igscraper/pkg/logger"
+	"igscraper/pkg/scraper"
+	"igscraper/pkg/ui"
+)
+
+// repairArchive re-downloads the files verify finds missing or damaged
+var repairArchive bool
+
+// verifyCmd represents the verify command
+var verifyCmd = &cobra.Command{
+	Use:   "verify <username>",
+	Short: "Check a local archive for missing and damaged files",
+	Long: `Check a profile's folder against its metadata.json and, if a scrape of it
+is unfinished, its checkpoint, without any request to Instagram.
+
+The report lists:
+  • Missing:        recorded downloads whose file is gone
+  • Empty:          files of 0 bytes
+  • Corrupt:        .jpg files that do not start like a JPEG image
+  • Size mismatch:  files whose size differs from the one recorded
+  • Checksum:       files whose SHA-256 differs from the one recorded when
+                    they were saved, in metadata.json or the checkpoint
+
+With --repair the posts of the bad files are fetched again and their media
+downloaded anew, replacing the damaged files and their metadata.json entries.
+Exits with status 1 when problems remain.`,
+	Example: `  # Check the archive in ./username_photos
+  igscraper verify username
+
+  # Check an archive elsewhere and download the bad files again
+  igscraper verify username --output ./archive --repair`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		runVerify(cmd, args)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+
+	// Local flags for verify command
+	flags := verifyCmd.Flags()
+	flags.StringVarP(&outputDir, "output", "o", "", "output directory the profile was scraped into (default: current directory)")
+	flags.BoolVar(&repairArchive, "repair", false, "download the missing and damaged files again")
+	flags.IntVar(&concurrent, "concurrent", 3, "number of concurrent downloads with --repair")
+	flags.StringVarP(&accountName, "account", "a", "", "use specific stored account with --repair")
+	flags.BoolVar(&skipSessionCheck, "skip-session-check", false, "do not check that the session cookies are valid before repairing")
+}
+
+func runVerify(cmd *cobra.Command, args []string) {
+	username := strings.TrimSpace(args[0])
+
+	cfg, err := config.Load(configFile, scrapeFlags())
+	if err != nil {
+		ui.PrintError("Failed to load configuration", err.Error())
+		os.Exit(1)
+	}
+
+	logger.Initialize(&cfg.Logging)
+	initAuditLog(cfg)
+	warnDeprecatedConfig(cfg)
+	// Only a repair makes requests
+	if repairArchive {
+		applyCredentials(cfg)
+	}
+
+	s, err := scraper.New(cfg)
+	if err != nil {
+		ui.PrintError("Failed to create scraper", err.Error())
+		os.Exit(1)
+	}
+
+	report, err := s.AuditArchive(username)
+	if err != nil {
+		ui.PrintError("Verification failed", err.Error())
+		os.Exit(1)
+	}
+	if repairArchive {
+		if err := s.RepairArchive(report); err != nil {
+			ui.PrintError("Repair failed", err.Error())
+			os.Exit(1)
+		}
+	}
+
+	printAuditReport(report)
+	if !report.OK() {
+		os.Exit(1)
+	}
+}
+
+// printAuditReport prints the files checked, the problems found and, after
+// a repair, which of them were fixed
+func printAuditReport(r *scraper.AuditReport) {
+	fmt.Println()
+	fmt.Printf("%s @%s\n", ui.Magenta("Archive audit for"), r.Username)
+	fmt.Printf("  %s %s\n", ui.Cyan("Directory:"), r.OutputDir)
+	fmt.Printf("  %s %d files\n\n", ui.Cyan("Checked:"), r.Checked)
+
+	if len(r.Problems) == 0 {
+		fmt.Println(ui.Green("✓ No missing or damaged files"))
+		return
+	}
+
+	repaired := make(map[string]bool)
+	for _, shortcode := range r.Repaired {
+		repaired[shortcode] = true
+	}
+	fmt.Println(ui.Yellow(fmt.Sprintf("! %d problems", len(r.Problems))))
+	for _, problem := range r.Problems {
+		name := problem.File
+		if name == "" {
+			name = problem.Shortcode
+		}
+		status := ""
+		switch {
+		case repaired[problem.Shortcode]:
+			status = ui.Green(" repaired")
+		case r.Unrepaired[problem.Shortcode] != nil:
+			status = ui.Red(" not repaired: " + r.Unrepaired[problem.Shortcode].Error())
+		}
+		fmt.Printf("  %-17s %s %s%s\n", problem.Kind, name, ui.Dim(problem.Detail), status)
+	}
+	if len(r.Repaired) == 0 && len(r.Unrepaired) == 0 {
+		fmt.Printf("  → Run %s to download them again\n", ui.Green("igscraper verify "+r.Username+" --repair"))
+	}
+	fmt.Println()
+}
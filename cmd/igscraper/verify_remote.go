@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"igscraper/pkg/config"
+	"igscraper/pkg/instagram"
+	"igscraper/pkg/logger"
+	"igscraper/pkg/scraper"
+	"igscraper/pkg/ui"
+)
+
+// verifyRemoteCmd represents the verify-remote command
+var verifyRemoteCmd = &cobra.Command{
+	Use:   "verify-remote <username>",
+	Short: "Compare a local archive with the profile's remote timeline",
+	Long: `Walk a profile's remote timeline without downloading anything and compare
+it with the local archive.
+
+The report lists:
+  • Missing:  remote photos that have not been downloaded yet
+  • Orphaned: local photos whose post is no longer on the profile
+
+Only timeline pages are requested, so a check costs one request per 50 posts.
+Posts the scraper would skip, such as those excluded by --since, --until or
+--filter and videos or photos when download.skip_videos or skip_images is
+set, are never reported as missing. Exits with status 1 when the archive is out of sync.`,
+	Example: `  # Check the archive in ./username_photos
+  igscraper verify-remote username
+
+  # Check an archive elsewhere, only for posts a filtered scrape would keep
+  igscraper verify-remote username --output ./archive --since 2024-01-01`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		runVerifyRemote(cmd, args)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(verifyRemoteCmd)
+
+	// Local flags for verify-remote command
+	flags := verifyRemoteCmd.Flags()
+	flags.StringVarP(&outputDir, "output", "o", "", "output directory the profile was scraped into (default: current directory)")
+	flags.IntVar(&rateLimit, "rate-limit", 60, "requests per minute")
+	flags.StringVarP(&accountName, "account", "a", "", "use specific stored account")
+	flags.BoolVar(&skipSessionCheck, "skip-session-check", false, "do not check that the session cookies are valid before scanning")
+	flags.BoolVar(&rotateAccounts, "rotate-accounts", false, "switch to another stored account when the current one is repeatedly rate limited or logged out")
+	flags.StringVar(&sinceDate, "since", "", "only expect media posted on or after this date (YYYY-MM-DD or RFC 3339)")
+	flags.StringVar(&untilDate, "until", "", "only expect media posted on or before this date (YYYY-MM-DD or RFC 3339)")
+	flags.StringVar(&filterExpr, "filter", "", "only expect posts matching this expression (e.g. 'hashtag:sunset AND likes>100')")
+}
+
+func runVerifyRemote(cmd *cobra.Command, args []string) {
+	username := strings.TrimSpace(args[0])
+
+	cfg, err := config.Load(configFile, scrapeFlags())
+	if err != nil {
+		ui.PrintError("Failed to load configuration", err.Error())
+		os.Exit(1)
+	}
+
+	logger.Initialize(&cfg.Logging)
+	initAuditLog(cfg)
+	warnDeprecatedConfig(cfg)
+	applyCredentials(cfg)
+
+	s, err := scraper.New(cfg)
+	if err != nil {
+		ui.PrintError("Failed to create scraper", err.Error())
+		os.Exit(1)
+	}
+	if credentialManager != nil {
+		s.SetSessionStore(credentialManager, storedAccount)
+	}
+	if accountRotator != nil {
+		s.SetAccountRotator(accountRotator)
+	}
+	if headlessBrowser != nil {
+		s.SetBrowserFallback(headlessBrowser)
+	}
+
+	quietOutput := ui.IsQuietMode()
+	report, err := s.VerifyRemote(username, func(scanned int) {
+		if !quietOutput {
+			fmt.Printf("\r%s %d posts", ui.Cyan("Scanning timeline:"), scanned)
+		}
+	})
+	if !quietOutput {
+		fmt.Println()
+	}
+	if err != nil {
+		ui.PrintError("Verification failed", err.Error())
+		os.Exit(1)
+	}
+
+	printVerifyReport(report)
+	if !report.InSync() {
+		os.Exit(1)
+	}
+}
+
+// printVerifyReport prints the comparison summary and the differing posts
+func printVerifyReport(r *scraper.VerifyReport) {
+	fmt.Println()
+	fmt.Printf("%s @%s\n", ui.Magenta("Archive check for"), r.Username)
+	fmt.Printf("  %s %s\n", ui.Cyan("Directory:"), r.OutputDir)
+	fmt.Printf("  %s %d posts, %d expected photos\n", ui.Cyan("Remote:"), r.RemotePosts, r.RemotePhotos)
+	fmt.Printf("  %s %d photos\n\n", ui.Cyan("Local:"), r.LocalPhotos)
+
+	if r.InSync() {
+		fmt.Println(ui.Green("✓ Archive is in sync with the remote timeline"))
+		return
+	}
+
+	if len(r.Missing) > 0 {
+		fmt.Println(ui.Yellow(fmt.Sprintf("! %d missing locally", len(r.Missing))))
+		for _, shortcode := range r.Missing {
+			fmt.Printf("  %s %s\n", shortcode, ui.Dim(instagram.GetPostURL(shortcode)))
+		}
+		fmt.Printf("  → Run %s to download them\n\n", ui.Green("igscraper scrape "+r.Username))
+	}
+
+	if len(r.Orphaned) > 0 {
+		fmt.Println(ui.Yellow(fmt.Sprintf("! %d no longer on the profile", len(r.Orphaned))))
+		for _, shortcode := range r.Orphaned {
+			fmt.Printf("  %s\n", filepath.Join(r.OutputDir, r.Files[shortcode]))
+		}
+		fmt.Println("  → These posts were deleted, archived or made private; the local copies are kept")
+		fmt.Println()
+	}
+}
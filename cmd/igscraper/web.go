@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"igscraper/pkg/logger"
+	"igscraper/pkg/ui"
+	"igscraper/pkg/ui/web"
+)
+
+// webMonitor collects the progress served by --web, set while the server runs
+var webMonitor *web.Monitor
+
+// startWebServer serves the progress page on addr and sets webMonitor. The
+// caller closes the returned server.
+func startWebServer(addr string) (*web.Server, error) {
+	monitor := web.NewMonitor()
+	server, err := web.NewServer(addr, monitor)
+	if err != nil {
+		return nil, err
+	}
+	webMonitor = monitor
+	logger.WithField("url", server.URL()).Info("Progress page listening")
+	return server, nil
+}
+
+// withWebMonitor returns terminal, also reporting to the web monitor when
+// --web is set
+func withWebMonitor(terminal ui.TUI) ui.TUI {
+	if webMonitor == nil {
+		return terminal
+	}
+	return ui.Multi(terminal, webMonitor)
+}
+
+// consoleTUI prints the scraper's messages as plain console output. It stands
+// in for the progress bar when progress goes to the web monitor without
+// --tui, as the scraper reports either to a TUI or to the progress bar.
+type consoleTUI struct{}
+
+func (consoleTUI) StartDownload(id, username, filename string, size int64)           {}
+func (consoleTUI) UpdateDownloadProgress(id string, downloaded int64, speed float64) {}
+func (consoleTUI) CompleteDownload(id string)                                        {}
+func (consoleTUI) FailDownload(id string, err error)                                 {}
+func (consoleTUI) UpdateRateLimit(used, max int, resetAt time.Time)                  {}
+func (consoleTUI) UpdateWorkers(workers, max int)                                    {}
+func (consoleTUI) UpdateProfile(username string, page, total int)                    {}
+func (consoleTUI) FinishProfile(username string, err error)                          {}
+func (consoleTUI) IsPaused() bool                                                    { return false }
+func (consoleTUI) Controls() <-chan ui.Control                                       { return nil }
+
+func (consoleTUI) LogInfo(format string, args ...interface{}) {
+	ui.PrintInfo("INFO", fmt.Sprintf(format, args...))
+}
+
+func (consoleTUI) LogSuccess(format string, args ...interface{}) {
+	ui.PrintSuccess(fmt.Sprintf(format, args...))
+}
+
+func (consoleTUI) LogWarning(format string, args ...interface{}) {
+	ui.PrintWarning(fmt.Sprintf(format, args...))
+}
+
+func (consoleTUI) LogError(format string, args ...interface{}) {
+	ui.PrintError(fmt.Sprintf(format, args...))
+}
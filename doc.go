@@ -0,0 +1,40 @@
+// Package igscraper is the Go API of igscraper: it downloads the photos and
+// videos of Instagram profiles from another program, the way the igscraper
+// command does.
+//
+// A Scraper is made with New and functional options, and scrapes one profile
+// at a time with ScrapeUser:
+//
+//	s, err := igscraper.New(
+//	    igscraper.WithSession(sessionID, csrfToken),
+//	    igscraper.WithOutputDir("/photos"),
+//	    igscraper.WithLogger(slog.Default()),
+//	    igscraper.WithProgress(igscraper.ProgressFunc(func(e igscraper.Event) {
+//	        if e.Kind == igscraper.EventDownloadCompleted {
+//	            fmt.Println("saved", e.File)
+//	        }
+//	    })),
+//	)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//
+//	result, err := s.ScrapeUser(ctx, "johndoe")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	fmt.Println(result.Downloaded, "files in", result.OutputDir)
+//
+// Unlike the command, the package prints nothing, sends no desktop
+// notifications unless WithConfig enables them, and never writes to the
+// global logger of igscraper/pkg/logger: its log lines go to the slog.Logger
+// given to WithLogger, and are dropped without one.
+//
+// Compatibility:
+//
+// The identifiers of this package follow semantic versioning: they are not
+// removed or changed in an incompatible way within a major version. New
+// options, event kinds and fields of Event and Result may be added. The
+// packages under pkg and internal, including the config.Config taken by
+// WithConfig, have no such promise.
+package igscraper
@@ -0,0 +1,122 @@
+package igscraper
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"igscraper/pkg/config"
+	"igscraper/pkg/logger"
+	"igscraper/pkg/scraper"
+)
+
+// Statuses a scrape ends with, as Result.Status
+const (
+	StatusComplete    = scraper.StatusComplete
+	StatusInterrupted = scraper.StatusInterrupted // by cancelling the context
+	StatusPaused      = scraper.StatusPaused      // to protect disk space or the download quota, or as the session was lost
+	StatusFailed      = scraper.StatusFailed
+)
+
+// ErrInterrupted is returned by ScrapeUser when its context was cancelled.
+// It wraps context.Canceled.
+var ErrInterrupted = scraper.ErrInterrupted
+
+// Scraper downloads Instagram profiles. It runs one scrape at a time: a call
+// of ScrapeUser while another is running waits for it to end.
+type Scraper struct {
+	mu      sync.Mutex
+	scraper *scraper.Scraper
+	restart bool
+}
+
+// Result is what a scrape did
+type Result struct {
+	Username  string
+	OutputDir string // the profile's folder
+	Status    string // one of the Status constants
+
+	// Downloaded counts the files saved, or found already on disk, and Bytes
+	// the bytes downloaded
+	Downloaded int
+	Bytes      int64
+
+	// Skipped counts the posts not downloaded, such as those left out by a
+	// filter, and Failed the files that could not be saved
+	Skipped int
+	Failed  int
+
+	Duration time.Duration
+}
+
+// New returns a Scraper with the default settings as changed by opts. It
+// fails if the settings are invalid, such as without WithSession.
+func New(opts ...Option) (*Scraper, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	cfg := config.DefaultConfig()
+	// Desktop notifications are for the command's user
+	cfg.Notifications.Enabled = false
+	if o.config != nil {
+		copied := *o.config
+		cfg = &copied
+	}
+	for _, edit := range o.edits {
+		edit(cfg)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid settings: %w", err)
+	}
+
+	log := newLogger(o.logger)
+	s, err := scraper.NewWithLogger(cfg, log)
+	if err != nil {
+		return nil, err
+	}
+	if o.client != nil {
+		s.SetClient(o.client)
+	}
+	s.SetTUI(newProgressTUI(o.progress, log.WithField("component", logger.ComponentScraper)))
+	return &Scraper{scraper: s, restart: o.restart}, nil
+}
+
+// ScrapeUser downloads the posts of the profile username into its folder
+// under the output directory, skipping the files already there. Cancelling
+// ctx stops the scrape once the downloads in progress are done, returning
+// an error wrapping ErrInterrupted; its checkpoint is kept, so the next
+// ScrapeUser of the profile carries on from there.
+//
+// The Result is returned whenever the scrape started, with its error if it
+// did not complete.
+func (s *Scraper) ScrapeUser(ctx context.Context, username string) (*Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.scraper.SetContext(ctx)
+	defer s.scraper.SetContext(nil)
+	started := time.Now()
+	err := s.scraper.DownloadUserPhotosWithResume(username, !s.restart, s.restart)
+
+	// The summary is of an earlier scrape if this one stopped before starting
+	summary := s.scraper.Summary()
+	if summary.StartedAt.Before(started) {
+		return nil, err
+	}
+	return &Result{
+		Username:   username,
+		OutputDir:  s.scraper.OutputDir(username),
+		Status:     summary.Status,
+		Downloaded: summary.Downloaded,
+		Bytes:      summary.Bytes,
+		Skipped:    summary.SkippedCount(),
+		Failed:     summary.FailedCount(),
+		Duration:   summary.Elapsed,
+	}, err
+}
@@ -0,0 +1,142 @@
+package igscraper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"igscraper/pkg/config"
+	"igscraper/pkg/instagram"
+)
+
+// mockClient serves a profile of posts with a photo each
+type mockClient struct {
+	shortcodes []string
+}
+
+func (m *mockClient) GetJSON(url string, target interface{}) error {
+	resp := target.(*instagram.InstagramResponse)
+	resp.Status = "ok"
+	resp.Data.User.ID = "42"
+	media := &resp.Data.User.EdgeOwnerToTimelineMedia
+	media.Count = len(m.shortcodes)
+	if strings.Contains(url, "graphql") {
+		for _, shortcode := range m.shortcodes {
+			media.Edges = append(media.Edges, instagram.Edge{Node: instagram.Node{
+				Shortcode:  shortcode,
+				DisplayURL: "http://example.com/" + shortcode + ".jpg",
+			}})
+		}
+	}
+	return nil
+}
+
+func (m *mockClient) DownloadPhoto(photoURL string) ([]byte, error) {
+	return []byte("photo " + photoURL), nil
+}
+
+func (m *mockClient) FetchUserProfile(username string) (*instagram.InstagramResponse, error) {
+	var response instagram.InstagramResponse
+	err := m.GetJSON(instagram.GetProfileURL(username), &response)
+	return &response, err
+}
+
+func (m *mockClient) FetchUserMedia(userID string, after string) (*instagram.InstagramResponse, error) {
+	var response instagram.InstagramResponse
+	err := m.GetJSON(instagram.GetMediaURL(userID, after), &response)
+	return &response, err
+}
+
+func TestNew(t *testing.T) {
+	_, err := New()
+	assert.ErrorContains(t, err, "session ID is required")
+
+	base := config.DefaultConfig()
+	s, err := New(
+		WithSession("session", "csrf"),
+		WithOutputDir("/photos"),
+		WithConcurrency(5),
+		WithRateLimit(30),
+		// Applied before the options above whatever its place
+		WithConfig(base),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("/photos", "johndoe_photos"), s.scraper.OutputDir("johndoe"))
+	assert.Equal(t, "./downloads", base.Output.BaseDirectory, "the given settings are not changed")
+
+	_, err = New(WithSession("session", "csrf"), WithConcurrency(0))
+	assert.ErrorContains(t, err, "invalid settings")
+}
+
+func TestScrapeUser(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	dir := t.TempDir()
+
+	var mu sync.Mutex
+	var events []Event
+	var logs bytes.Buffer
+	s, err := New(
+		WithSession("session", "csrf"),
+		WithOutputDir(dir),
+		WithClient(&mockClient{shortcodes: []string{"LIB1", "LIB2"}}),
+		WithLogger(slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))),
+		WithProgress(ProgressFunc(func(e Event) {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, e)
+		})),
+	)
+	require.NoError(t, err)
+
+	result, err := s.ScrapeUser(context.Background(), "library")
+	require.NoError(t, err)
+	assert.Equal(t, "library", result.Username)
+	assert.Equal(t, StatusComplete, result.Status)
+	assert.Equal(t, 2, result.Downloaded)
+	assert.Zero(t, result.Failed)
+	assert.Equal(t, filepath.Join(dir, "library_photos"), result.OutputDir)
+	assert.FileExists(t, filepath.Join(result.OutputDir, "LIB1.jpg"))
+
+	kinds := make(map[EventKind][]string)
+	for _, e := range events {
+		kinds[e.Kind] = append(kinds[e.Kind], e.Shortcode)
+		if e.Kind == EventDownloadCompleted {
+			assert.Equal(t, "library", e.Username)
+			assert.Equal(t, e.Shortcode+".jpg", e.File)
+		}
+	}
+	assert.Len(t, kinds[EventPage], 1)
+	assert.ElementsMatch(t, []string{"LIB1", "LIB2"}, kinds[EventDownloadStarted])
+	assert.ElementsMatch(t, []string{"LIB1", "LIB2"}, kinds[EventDownloadCompleted])
+
+	// Every line goes to the given logger, naming its component
+	components := make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry), line)
+		component, _ := entry["component"].(string)
+		components[component] = true
+	}
+	assert.True(t, components["scraper"])
+	assert.True(t, components["downloader"])
+	assert.False(t, components[""], "every line names its component")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result, err = s.ScrapeUser(ctx, "library")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, result)
+}
+
+func TestEventKindString(t *testing.T) {
+	assert.Equal(t, "download_completed", EventDownloadCompleted.String())
+	assert.Equal(t, "EventKind(0)", EventKind(0).String())
+}
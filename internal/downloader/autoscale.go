@@ -0,0 +1,86 @@
+package downloader
+
+import (
+	"errors"
+
+	igerrors "igscraper/pkg/errors"
+)
+
+const (
+	// scaleWindow is the number of results the autoscaler judges the pool
+	// by before it adds a worker
+	scaleWindow = 20
+
+	// scaleUpFailures is the number of failed results a window may have for
+	// a worker to be added
+	scaleUpFailures = 1
+
+	// throttleSpike is the number of 429s and 5xxs within a window that
+	// drop the pool to its fewest workers
+	throttleSpike = 3
+)
+
+// autoscaler adapts the number of jobs a pool runs at once to how Instagram
+// answers. A window of results with hardly any failures adds a worker, up to
+// max; a spike of 429s or 5xxs drops the pool to min at once, from where it
+// grows again as the errors stop.
+type autoscaler struct {
+	min, max int
+	workers  int
+
+	// Counted since the workers last changed or the window was full
+	results   int
+	failures  int
+	throttled int
+}
+
+// newAutoscaler returns an autoscaler running start workers, kept between
+// min and max
+func newAutoscaler(start, min, max int) *autoscaler {
+	return &autoscaler{
+		min:     min,
+		max:     max,
+		workers: clamp(start, min, max),
+	}
+}
+
+// record counts the result of a job that failed with err, or succeeded if
+// err is nil, and returns the number of workers to run and whether it
+// changed. Cancelled jobs and files outside the size limits say nothing
+// about Instagram, so they are not counted.
+func (a *autoscaler) record(err error) (int, bool) {
+	if errors.Is(err, ErrCancelled) || errors.Is(err, ErrSizeLimit) {
+		return a.workers, false
+	}
+
+	a.results++
+	if err != nil {
+		a.failures++
+		if igerrors.IsThrottled(err) {
+			a.throttled++
+		}
+	}
+
+	switch {
+	case a.throttled >= throttleSpike:
+		return a.reset(a.min)
+	case a.results >= scaleWindow && a.failures <= scaleUpFailures:
+		return a.reset(min(a.workers+1, a.max))
+	case a.results >= scaleWindow:
+		return a.reset(a.workers)
+	}
+	return a.workers, false
+}
+
+// reset starts a new window with workers
+func (a *autoscaler) reset(workers int) (int, bool) {
+	changed := workers != a.workers
+	a.workers = workers
+	a.results, a.failures, a.throttled = 0, 0, 0
+	return workers, changed
+}
+
+// clamp returns n kept between lo and hi
+func clamp(n, lo, hi int) int {
+	return max(lo, min(n, hi))
+}
@@ -0,0 +1,119 @@
+package downloader
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	igerrors "igscraper/pkg/errors"
+	"igscraper/pkg/ratelimit"
+)
+
+var errThrottled = &igerrors.Error{Type: igerrors.ErrorTypeRateLimit, Message: "rate limited", Code: 429}
+
+func TestAutoscaler(t *testing.T) {
+	a := newAutoscaler(2, 1, 3)
+
+	// A window without failures adds a worker
+	for i := 0; i < scaleWindow-1; i++ {
+		if _, changed := a.record(nil); changed {
+			t.Fatalf("Scaled after %d results, before the window was full", i+1)
+		}
+	}
+	if workers, changed := a.record(nil); !changed || workers != 3 {
+		t.Errorf("Expected 3 workers after a clean window, got %d (changed %v)", workers, changed)
+	}
+
+	// No more than max
+	for i := 0; i < scaleWindow; i++ {
+		a.record(nil)
+	}
+	if a.workers != 3 {
+		t.Errorf("Expected workers to stay at max 3, got %d", a.workers)
+	}
+
+	// Failures that are not throttling keep the pool as it is
+	for i := 0; i < scaleWindow; i++ {
+		var err error
+		if i%5 == 0 {
+			err = fmt.Errorf("not found")
+		}
+		if _, changed := a.record(err); changed {
+			t.Errorf("Expected a window with 4 failures not to scale")
+		}
+	}
+
+	// Cancelled jobs are not counted
+	for i := 0; i < throttleSpike; i++ {
+		a.record(ErrCancelled)
+	}
+	if a.results != 0 {
+		t.Errorf("Expected cancelled jobs not to be counted, got %d results", a.results)
+	}
+
+	// A spike of 429s drops to min
+	a.record(errThrottled)
+	a.record(&igerrors.Error{Type: igerrors.ErrorTypeServerError, Message: "bad gateway", Code: 502})
+	if workers, changed := a.record(errThrottled); !changed || workers != 1 {
+		t.Errorf("Expected 1 worker after a spike of throttling, got %d (changed %v)", workers, changed)
+	}
+}
+
+// throttlingClient fails downloads of URLs starting with "throttled" as
+// Instagram does when it rate limits
+type throttlingClient struct{}
+
+func (throttlingClient) DownloadPhoto(url string) ([]byte, error) {
+	time.Sleep(time.Millisecond)
+	if strings.HasPrefix(url, "throttled") {
+		return nil, errThrottled
+	}
+	return []byte("mock photo data"), nil
+}
+
+func TestWorkerPoolAutoscale(t *testing.T) {
+	pool := NewWorkerPool(4, throttlingClient{}, NewMockStorageManager(), ratelimit.NewTokenBucket(1000, time.Second), nil)
+	pool.SetAutoscale(1, 6)
+
+	if workers, maxWorkers := pool.Workers(); workers != 4 || maxWorkers != 6 {
+		t.Errorf("Expected 4 of 6 workers before any results, got %d of %d", workers, maxWorkers)
+	}
+
+	var mu sync.Mutex
+	var scaled []int
+	pool.OnScale(func(workers, maxWorkers int) {
+		mu.Lock()
+		defer mu.Unlock()
+		scaled = append(scaled, workers)
+	})
+	pool.Start()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for range pool.Results() {
+		}
+	}()
+
+	for i := 0; i < throttleSpike; i++ {
+		pool.Submit(DownloadJob{URL: fmt.Sprintf("throttled%d", i), Shortcode: fmt.Sprintf("t%d", i)})
+	}
+	for i := 0; i < scaleWindow+5; i++ {
+		pool.Submit(DownloadJob{URL: fmt.Sprintf("ok%d", i), Shortcode: fmt.Sprintf("s%d", i)})
+	}
+	pool.Stop()
+	wg.Wait()
+
+	// Dropped to 1 on the throttling, then grew by one on the clean window
+	if workers, _ := pool.Workers(); workers != 2 {
+		t.Errorf("Expected 2 workers at the end, got %d", workers)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(scaled) != 2 || scaled[0] != 1 || scaled[1] != 2 {
+		t.Errorf("Expected the pool to scale to 1 then 2, got %v", scaled)
+	}
+}
@@ -3,9 +3,12 @@ package downloader
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"igscraper/pkg/instagram"
@@ -13,12 +16,47 @@ import (
 	"igscraper/pkg/ratelimit"
 )
 
+// ErrCancelled is the error of jobs dropped by CancelPending before a worker
+// started them
+var ErrCancelled = errors.New("download cancelled")
+
+// ErrTimedOut is the error of jobs cancelled for taking longer than the
+// pool's job timeout
+var ErrTimedOut = errors.New("download timed out")
+
+// ErrSizeLimit is the error of jobs whose file is smaller or larger than the
+// pool's size limits allow. Such jobs are skipped rather than failed.
+var ErrSizeLimit = errors.New("file size outside the limits")
+
 // DownloadJob represents a single download task
 type DownloadJob struct {
 	URL       string
 	Shortcode string
 	Username  string
 	Node      *instagram.Node // Full node data for metadata
+
+	// ThumbnailURL, if set, is a cover image downloaded after the video and
+	// saved next to it when the storage keeps thumbnails
+	ThumbnailURL string
+
+	// Overwrite downloads the media even if the storage already holds it,
+	// replacing the file once the new one is complete
+	Overwrite bool
+
+	// Progress, if set, is called with the number of bytes written so far
+	// as media streamed to disk arrives. Chunked videos call it from several
+	// goroutines at once.
+	Progress func(written int64)
+
+	// Retries is the number of times the job failed and was queued again
+	Retries int
+
+	// skipped is set by Target.Skip while a worker downloads the job
+	skipped *atomic.Bool
+
+	// ctx is the job's own context while a worker downloads it, done once
+	// the pool's job timeout passes
+	ctx context.Context
 }
 
 // DownloadResult represents the result of a download job
@@ -35,6 +73,25 @@ type PhotoDownloader interface {
 	DownloadPhoto(url string) ([]byte, error)
 }
 
+// VideoDownloader is implemented by clients that write videos straight to a
+// file, fetching large ones in parallel ranged chunks
+type VideoDownloader interface {
+	DownloadVideo(url string, w io.WriterAt) (int64, error)
+}
+
+// FileDownloader is implemented by clients that stream photos straight to a
+// file instead of returning them in memory
+type FileDownloader interface {
+	DownloadFile(url string, w io.WriterAt) (int64, error)
+}
+
+// SizeProber is implemented by clients that can tell the size of a file
+// without downloading it, such as with a HEAD request. The size is -1 when
+// the server does not say.
+type SizeProber interface {
+	ContentLength(url string) (int64, error)
+}
+
 // PhotoStorage interface for storing photos
 type PhotoStorage interface {
 	IsDownloaded(shortcode string) bool
@@ -42,21 +99,118 @@ type PhotoStorage interface {
 	SavePhotoWithMetadata(r io.Reader, shortcode string, node *instagram.Node) error
 }
 
-// WorkerPool manages concurrent download workers
+// FileStorage is implemented by storage that takes over a file the media was
+// downloaded into, in its output directory, instead of copying it
+type FileStorage interface {
+	GetOutputDir() string
+	SaveFile(path, shortcode string, node *instagram.Node) error
+}
+
+// ThumbnailStorage is implemented by storage that keeps the cover images of
+// videos
+type ThumbnailStorage interface {
+	SaveThumbnail(r io.Reader, shortcode string, node *instagram.Node) error
+}
+
+// WorkerPool manages concurrent download workers. A pool serves one or more
+// targets, such as the profiles of a batch, each with its own queue, client,
+// storage and results. Workers take jobs from the targets in turn, so a
+// large profile cannot starve the others.
 type WorkerPool struct {
-	numWorkers     int
-	jobQueue       chan DownloadJob
-	resultQueue    chan DownloadResult
-	wg             sync.WaitGroup
-	ctx            context.Context
-	cancel         context.CancelFunc
-	client         PhotoDownloader
-	storageManager PhotoStorage
-	rateLimiter    ratelimit.Limiter
-	logger         logger.Logger
-}
-
-// NewWorkerPool creates a new download worker pool
+	numWorkers int
+	queueSize  int
+	wg         sync.WaitGroup
+	logger     logger.Logger
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	targets []*Target
+	next    int
+	started bool
+	stopped bool
+	paused  bool
+	
+	// Jobs being downloaded, and how many may be at once. The limit is
+	// numWorkers unless the pool autoscales.
+	active    int
+	limit     int
+	autoscale *autoscaler
+	onScale   func(workers, maxWorkers int)
+
+	// The order queued jobs are taken in, and how often a failed job is
+	// queued again
+	order    Order
+	requeues int
+
+	// How long a job may take to download before it is cancelled, 0 for no
+	// limit
+	jobTimeout time.Duration
+
+	// The smallest and largest file downloaded, 0 for no bound
+	minSize int64
+	maxSize int64
+
+	// single is the target of pools created with NewWorkerPool, used by
+	// Submit and Results
+	single *Target
+}
+
+// Target is a source of download jobs served by a WorkerPool
+type Target struct {
+	name    string
+	pool    *WorkerPool
+	client  PhotoDownloader
+	storage PhotoStorage
+	limiter ratelimit.Limiter
+	results chan DownloadResult
+	logger  logger.Logger
+
+	// Guarded by pool.mu
+	queue      []DownloadJob
+	running    map[string]*atomic.Bool // skip flags of jobs being downloaded
+	closed     bool
+	finished   bool
+	cancelling int // cancelled jobs whose results are still being sent
+	stats      TargetStats
+}
+
+// TargetStats counts the jobs of a target
+type TargetStats struct {
+	Name      string
+	Queued    int   // waiting for a worker
+	Active    int   // being downloaded
+	Completed int   // saved, or found already downloaded
+	Failed    int
+	Cancelled int   // dropped by CancelPending before they started
+	Requeued  int   // failed and queued again, counted once per retry
+	TimedOut  int   // cancelled for running past the job timeout, counted once per attempt
+	Skipped   int   // files outside the size limits
+	Bytes     int64 // downloaded
+}
+
+// NewSharedPool creates a worker pool without targets. Targets are added
+// with AddTarget and may come and go while the pool runs.
+func NewSharedPool(numWorkers int, log logger.Logger) *WorkerPool {
+	if log == nil {
+		log = logger.Named(logger.ComponentDownloader)
+	}
+	if numWorkers < 1 {
+		numWorkers = 1
+	}
+	
+	wp := &WorkerPool{
+		numWorkers: numWorkers,
+		queueSize:  numWorkers * 2, // Buffer size = 2x workers
+		logger:     log,
+		limit:      numWorkers,
+		order:      OrderNewest,
+	}
+	wp.cond = sync.NewCond(&wp.mu)
+	return wp
+}
+
+// NewWorkerPool creates a new download worker pool serving a single target,
+// whose jobs are passed to Submit and whose results are read from Results
 func NewWorkerPool(
 	numWorkers int,
 	client PhotoDownloader,
@@ -64,29 +218,146 @@ func NewWorkerPool(
 	rateLimiter ratelimit.Limiter,
 	log logger.Logger,
 ) *WorkerPool {
-	ctx, cancel := context.WithCancel(context.Background())
+	wp := NewSharedPool(numWorkers, log)
+	wp.single, _ = wp.AddTarget("", client, storageManager, rateLimiter)
+	return wp
+}
+
+// AddTarget registers a source of jobs. Its photos are downloaded with
+// client, saved to storage and paced by rateLimiter. The target must be
+// closed once all its jobs are submitted.
+func (wp *WorkerPool) AddTarget(name string, client PhotoDownloader, storage PhotoStorage, rateLimiter ratelimit.Limiter) (*Target, error) {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
 	
-	if log == nil {
-		log = logger.GetLogger()
+	if wp.stopped {
+		return nil, fmt.Errorf("worker pool is shutting down")
 	}
 	
-	return &WorkerPool{
-		numWorkers:     numWorkers,
-		jobQueue:       make(chan DownloadJob, numWorkers*2), // Buffer size = 2x workers
-		resultQueue:    make(chan DownloadResult, numWorkers),
-		ctx:            ctx,
-		cancel:         cancel,
-		client:         client,
-		storageManager: storageManager,
-		rateLimiter:    rateLimiter,
-		logger:         log,
+	t := &Target{
+		name:    name,
+		pool:    wp,
+		client:  client,
+		storage: storage,
+		limiter: rateLimiter,
+		results: make(chan DownloadResult, wp.numWorkers),
+		logger:  wp.logger,
+		running: make(map[string]*atomic.Bool),
 	}
+	t.stats.Name = name
+	wp.targets = append(wp.targets, t)
+	
+	wp.logger.DebugWithFields("Target added to worker pool", map[string]interface{}{
+		"target":  name,
+		"targets": len(wp.targets),
+	})
+	return t, nil
+}
+
+// SetAutoscale makes the pool adapt the number of downloads it runs at once
+// to how Instagram answers, between minWorkers and maxWorkers. It starts
+// with the pool's size, adds a worker while downloads rarely fail and drops
+// to minWorkers when 429s or 5xxs spike. It is called before Start.
+func (wp *WorkerPool) SetAutoscale(minWorkers, maxWorkers int) {
+	minWorkers = max(minWorkers, 1)
+	maxWorkers = max(maxWorkers, minWorkers)
+	
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	wp.autoscale = newAutoscaler(wp.numWorkers, minWorkers, maxWorkers)
+	wp.limit = wp.autoscale.workers
+	wp.numWorkers = maxWorkers
+	wp.queueSize = maxWorkers * 2
+}
+
+// SetOrder sets the order in which each target's queued jobs are
+// downloaded, by the time their posts were taken. Only jobs waiting in the
+// queue are reordered, so it decides between the posts submitted so far.
+// It is called before Start.
+func (wp *WorkerPool) SetOrder(order Order) {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	wp.order = order
+}
+
+// SetRequeue makes the pool queue a failed job again, behind the jobs already
+// waiting, up to attempts times before its failure is sent as the result.
+// Jobs cancelled or failing with an error repeating cannot fix, such as a
+// deleted post, are not retried. It is called before Start.
+func (wp *WorkerPool) SetRequeue(attempts int) {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	wp.requeues = max(attempts, 0)
 }
 
-// Start initializes and starts all workers
+// SetJobTimeout gives each job's download a deadline of timeout, counted
+// from when a worker starts it after the rate limit lets it through. A job
+// still downloading then is cancelled with ErrTimedOut and, like other
+// failures, queued again behind the jobs waiting. A timeout of 0 disables
+// the deadline. It is called before Start.
+func (wp *WorkerPool) SetJobTimeout(timeout time.Duration) {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	wp.jobTimeout = max(timeout, 0)
+}
+
+// SetSizeLimits skips the files of jobs smaller than minSize or larger than
+// maxSize bytes, a bound of 0 leaving that side open. When the client is a
+// SizeProber the size is asked for before the download, otherwise the file
+// is checked once downloaded and thrown away. Skipped jobs have a result
+// with ErrSizeLimit. It is called before Start.
+func (wp *WorkerPool) SetSizeLimits(minSize, maxSize int64) {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	wp.minSize = max(minSize, 0)
+	wp.maxSize = max(maxSize, 0)
+}
+
+// checkSize returns an error wrapping ErrSizeLimit if size is known and
+// outside the pool's size limits
+func (wp *WorkerPool) checkSize(size int64) error {
+	switch {
+	case size < 0:
+		return nil
+	case wp.minSize > 0 && size < wp.minSize:
+		return fmt.Errorf("%w: %d bytes is below the minimum of %d", ErrSizeLimit, size, wp.minSize)
+	case wp.maxSize > 0 && size > wp.maxSize:
+		return fmt.Errorf("%w: %d bytes is above the maximum of %d", ErrSizeLimit, size, wp.maxSize)
+	}
+	return nil
+}
+
+// Workers returns the number of downloads the pool runs at once and the
+// most it may run, which differ while it autoscales
+func (wp *WorkerPool) Workers() (int, int) {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	return wp.limit, wp.numWorkers
+}
+
+// OnScale calls fn with the new number of workers and the most there may be
+// whenever the autoscaler changes it, such as to show it in the TUI. It is
+// called from the worker that recorded the result, without the pool's lock.
+func (wp *WorkerPool) OnScale(fn func(workers, maxWorkers int)) {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	wp.onScale = fn
+}
+
+// Start initializes and starts all workers. Starting a running pool does
+// nothing.
 func (wp *WorkerPool) Start() {
+	wp.mu.Lock()
+	if wp.started {
+		wp.mu.Unlock()
+		return
+	}
+	wp.started = true
+	wp.mu.Unlock()
+	
 	wp.logger.InfoWithFields("Starting worker pool", map[string]interface{}{
 		"num_workers": wp.numWorkers,
+		"autoscale":   wp.autoscale != nil,
 	})
 	
 	for i := 0; i < wp.numWorkers; i++ {
@@ -95,42 +366,276 @@ func (wp *WorkerPool) Start() {
 	}
 }
 
-// Stop gracefully shuts down the worker pool
+// Stop gracefully shuts down the worker pool. Jobs already queued are
+// downloaded first, then the results of every target are closed.
 func (wp *WorkerPool) Stop() {
-	wp.logger.Info("Stopping worker pool...")
+	wp.mu.Lock()
+	if wp.stopped {
+		wp.mu.Unlock()
+		return
+	}
+	wp.stopped = true
+	wp.cond.Broadcast()
+	wp.mu.Unlock()
 	
-	// Close job queue to signal no more jobs will be added
-	close(wp.jobQueue)
+	wp.logger.Info("Stopping worker pool...")
 	
 	// Wait for all workers to finish processing remaining jobs
 	wp.wg.Wait()
 	
-	// Close result queue
-	close(wp.resultQueue)
-	
-	// Cancel context
-	wp.cancel()
+	// Close the results of targets that were never closed
+	wp.mu.Lock()
+	for _, t := range append([]*Target(nil), wp.targets...) {
+		t.closed = true
+		t.finishIfDone()
+	}
+	wp.mu.Unlock()
 	
 	wp.logger.Info("Worker pool stopped")
 }
 
-// Submit adds a new download job to the queue
+// Pause keeps workers from starting jobs until Resume is called. Downloads
+// already in progress finish, and jobs can still be submitted until the
+// queues are full. A pool being stopped downloads its queued jobs even when
+// paused.
+func (wp *WorkerPool) Pause() {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	wp.paused = true
+}
+
+// Resume lets a paused pool's workers take jobs again
+func (wp *WorkerPool) Resume() {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	wp.paused = false
+	wp.cond.Broadcast()
+}
+
+// Paused returns true if the pool is paused
+func (wp *WorkerPool) Paused() bool {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	return wp.paused
+}
+
+// Submit adds a new download job to the queue of a pool created with
+// NewWorkerPool
 func (wp *WorkerPool) Submit(job DownloadJob) error {
-	select {
-	case wp.jobQueue <- job:
-		wp.logger.DebugWithFields("Job submitted to queue", map[string]interface{}{
-			"shortcode": job.Shortcode,
-			"username":  job.Username,
-		})
+	if wp.single == nil {
+		return fmt.Errorf("worker pool has no default target")
+	}
+	return wp.single.Submit(job)
+}
+
+// Results returns the result channel of a pool created with NewWorkerPool.
+// It is closed when the pool stops.
+func (wp *WorkerPool) Results() <-chan DownloadResult {
+	if wp.single == nil {
 		return nil
-	case <-wp.ctx.Done():
+	}
+	return wp.single.Results()
+}
+
+// Stats returns the job counts of the targets the pool is serving
+func (wp *WorkerPool) Stats() []TargetStats {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	
+	stats := make([]TargetStats, 0, len(wp.targets))
+	for _, t := range wp.targets {
+		stats = append(stats, t.stats)
+	}
+	return stats
+}
+
+// Submit adds a job to the target's queue, waiting while the queue is full
+func (t *Target) Submit(job DownloadJob) error {
+	wp := t.pool
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	
+	for len(t.queue) >= wp.queueSize && !t.closed && !wp.stopped {
+		wp.cond.Wait()
+	}
+	if t.closed || wp.stopped {
 		return fmt.Errorf("worker pool is shutting down")
 	}
+	
+	t.enqueue(job)
+	wp.cond.Broadcast()
+	
+	t.jobLogger(job).DebugWithFields("Job submitted to queue", map[string]interface{}{
+		"shortcode": job.Shortcode,
+		"username":  job.Username,
+		"target":    t.name,
+	})
+	return nil
 }
 
-// Results returns the result channel for consuming download results
-func (wp *WorkerPool) Results() <-chan DownloadResult {
-	return wp.resultQueue
+// SetLogger logs the target's jobs to log instead of the pool's logger, such
+// as one carrying the scrape ID of the profile. It is called before jobs are
+// submitted.
+func (t *Target) SetLogger(log logger.Logger) {
+	if log != nil {
+		t.logger = log
+	}
+}
+
+// jobLogger returns the target's logger with the ID of the request that
+// listed the job's post, when known
+func (t *Target) jobLogger(job DownloadJob) logger.Logger {
+	if job.Node == nil || job.Node.RequestID == "" {
+		return t.logger
+	}
+	return t.logger.WithField(logger.FieldRequestID, job.Node.RequestID)
+}
+
+// Results returns the channel the target's download results are sent to.
+// It is closed once the target is closed and its jobs are done.
+func (t *Target) Results() <-chan DownloadResult {
+	return t.results
+}
+
+// Close tells the pool no more jobs will be submitted. Queued jobs are
+// still downloaded. Closing a target twice does nothing.
+func (t *Target) Close() {
+	wp := t.pool
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	
+	t.closed = true
+	t.finishIfDone()
+	wp.cond.Broadcast()
+}
+
+// QueueSize returns the number of the target's jobs waiting for a worker
+func (t *Target) QueueSize() int {
+	t.pool.mu.Lock()
+	defer t.pool.mu.Unlock()
+	return len(t.queue)
+}
+
+// Stats returns the target's job counts
+func (t *Target) Stats() TargetStats {
+	t.pool.mu.Lock()
+	defer t.pool.mu.Unlock()
+	return t.stats
+}
+
+// CancelPending drops the target's queued jobs for which cancel returns
+// true. Jobs being downloaded are not affected. Each dropped job gets a
+// result with ErrCancelled, and the number dropped is returned.
+func (t *Target) CancelPending(cancel func(job DownloadJob) bool) int {
+	wp := t.pool
+	wp.mu.Lock()
+	var cancelled []DownloadJob
+	kept := t.queue[:0]
+	for _, job := range t.queue {
+		if cancel(job) {
+			cancelled = append(cancelled, job)
+		} else {
+			kept = append(kept, job)
+		}
+	}
+	t.queue = kept
+	t.stats.Queued -= len(cancelled)
+	t.stats.Cancelled += len(cancelled)
+	t.cancelling += len(cancelled)
+	wp.cond.Broadcast() // The queue has room again
+	wp.mu.Unlock()
+	
+	if len(cancelled) > 0 {
+		t.logger.InfoWithFields("Cancelled queued jobs", map[string]interface{}{
+			"target":    t.name,
+			"cancelled": len(cancelled),
+		})
+	}
+	
+	// Results are sent without the lock, as the reader may be slow
+	for _, job := range cancelled {
+		t.results <- DownloadResult{Job: job, Error: ErrCancelled}
+		
+		wp.mu.Lock()
+		t.cancelling--
+		t.finishIfDone()
+		wp.mu.Unlock()
+	}
+	return len(cancelled)
+}
+
+// Skip drops the job for shortcode from the target's queue, or stops its
+// download if a worker has already started it. Either way the job's result
+// has ErrCancelled. It returns false if the job is neither queued nor being
+// downloaded.
+func (t *Target) Skip(shortcode string) bool {
+	if t.CancelPending(func(job DownloadJob) bool { return job.Shortcode == shortcode }) > 0 {
+		return true
+	}
+	
+	t.pool.mu.Lock()
+	defer t.pool.mu.Unlock()
+	skipped, ok := t.running[shortcode]
+	if ok {
+		skipped.Store(true)
+	}
+	return ok
+}
+
+// SkipRunning stops the target's downloads in progress, as Skip does for a
+// single one, and returns how many it stopped. Streamed downloads end at
+// their next write; others are thrown away once they arrive.
+func (t *Target) SkipRunning() int {
+	t.pool.mu.Lock()
+	defer t.pool.mu.Unlock()
+	for _, skipped := range t.running {
+		skipped.Store(true)
+	}
+	return len(t.running)
+}
+
+// CancelPending drops the queued jobs of every target for which cancel
+// returns true, for example all videos when the user decides to archive only
+// photos. Downloads already in progress finish normally. It returns the
+// number of jobs dropped.
+func (wp *WorkerPool) CancelPending(cancel func(job DownloadJob) bool) int {
+	wp.mu.Lock()
+	targets := append([]*Target(nil), wp.targets...)
+	wp.mu.Unlock()
+	
+	total := 0
+	for _, t := range targets {
+		total += t.CancelPending(cancel)
+	}
+	return total
+}
+
+// finishIfDone closes the results of a closed target without remaining
+// jobs and removes it from the pool. The pool's lock must be held.
+func (t *Target) finishIfDone() {
+	if t.finished || !t.closed || len(t.queue) > 0 || t.stats.Active > 0 || t.cancelling > 0 {
+		return
+	}
+	t.finished = true
+	close(t.results)
+	
+	wp := t.pool
+	for i, other := range wp.targets {
+		if other == t {
+			wp.targets = append(wp.targets[:i], wp.targets[i+1:]...)
+			if wp.next > i {
+				wp.next--
+			}
+			break
+		}
+	}
+	
+	t.logger.DebugWithFields("Target finished", map[string]interface{}{
+		"target":    t.name,
+		"completed": t.stats.Completed,
+		"failed":    t.stats.Failed,
+		"bytes":     t.stats.Bytes,
+	})
 }
 
 // worker is the main worker routine
@@ -141,53 +646,137 @@ func (wp *WorkerPool) worker(id int) {
 		"worker_id": id,
 	})
 	
-	for job := range wp.jobQueue {
-		// Check if context is cancelled
-		select {
-		case <-wp.ctx.Done():
-			wp.logger.DebugWithFields("Worker stopping - context cancelled", map[string]interface{}{
-				"worker_id": id,
-			})
-			return
-		default:
+	for {
+		t, job, ok := wp.nextJob()
+		if !ok {
+			break
 		}
 		
-		// Process the job
-		result := wp.processJob(job, id)
+		// Process the job and, unless it is retried, send its result before
+		// the target can finish
+		result := t.processJob(job, id)
+		wp.mu.Lock()
+		requeued := t.requeue(job, result)
+		if !requeued {
+			wp.mu.Unlock()
+			t.results <- result
+			wp.mu.Lock()
+		}
+		
+		t.stats.Active--
+		wp.active--
+		if t.running[job.Shortcode] == job.skipped {
+			delete(t.running, job.Shortcode)
+		}
+		switch {
+		case requeued:
+			// Counted once its last attempt is done
+		case result.Success:
+			t.stats.Completed++
+			t.stats.Bytes += int64(result.Size)
+		case errors.Is(result.Error, ErrCancelled):
+			t.stats.Cancelled++
+		case errors.Is(result.Error, ErrSizeLimit):
+			t.stats.Skipped++
+		default:
+			t.stats.Failed++
+		}
+		if errors.Is(result.Error, ErrTimedOut) {
+			t.stats.TimedOut++
+		}
+		t.finishIfDone()
+		workers, scaled, onScale := wp.scale(result)
+		wp.cond.Broadcast() // A worker is free again
+		wp.mu.Unlock()
 		
-		// Send result
-		select {
-		case wp.resultQueue <- result:
-		case <-wp.ctx.Done():
-			wp.logger.DebugWithFields("Worker stopping - context cancelled while sending result", map[string]interface{}{
-				"worker_id": id,
+		if scaled {
+			wp.logger.InfoWithFields("Worker pool scaled", map[string]interface{}{
+				"workers":     workers,
+				"max_workers": wp.numWorkers,
 			})
-			return
+			if onScale != nil {
+				onScale(workers, wp.numWorkers)
+			}
 		}
 	}
 	
-	wp.logger.DebugWithFields("Worker stopping - job queue closed", map[string]interface{}{
+	wp.logger.DebugWithFields("Worker stopping - pool stopped", map[string]interface{}{
 		"worker_id": id,
 	})
 }
 
+// scale records a job's result with the autoscaler, if the pool has one,
+// returning the number of workers, whether it changed and the hook to tell.
+// The caller holds wp.mu.
+func (wp *WorkerPool) scale(result DownloadResult) (int, bool, func(workers, maxWorkers int)) {
+	if wp.autoscale == nil {
+		return wp.limit, false, nil
+	}
+	var err error
+	if !result.Success {
+		err = result.Error
+	}
+	limit, changed := wp.autoscale.record(err)
+	wp.limit = limit
+	return limit, changed, wp.onScale
+}
+
+// nextJob waits for a job, taking one from each target with queued jobs in
+// turn. It returns false once the pool is stopped and every queue is empty.
+// While the pool autoscales, jobs wait for one of the workers it runs.
+func (wp *WorkerPool) nextJob() (*Target, DownloadJob, bool) {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	
+	for {
+		if (wp.paused && !wp.stopped) || wp.active >= wp.limit {
+			wp.cond.Wait()
+			continue
+		}
+		for i := range wp.targets {
+			idx := (wp.next + i) % len(wp.targets)
+			t := wp.targets[idx]
+			if len(t.queue) == 0 {
+				continue
+			}
+			
+			job := t.queue[0]
+			t.queue = t.queue[1:]
+			t.stats.Queued--
+			t.stats.Active++
+			wp.active++
+			job.skipped = &atomic.Bool{}
+			t.running[job.Shortcode] = job.skipped
+			wp.next = (idx + 1) % len(wp.targets)
+			wp.cond.Broadcast() // A queue has room again
+			return t, job, true
+		}
+		
+		if wp.stopped {
+			return nil, DownloadJob{}, false
+		}
+		wp.cond.Wait()
+	}
+}
+
 // processJob handles a single download job
-func (wp *WorkerPool) processJob(job DownloadJob, workerID int) DownloadResult {
+func (t *Target) processJob(job DownloadJob, workerID int) DownloadResult {
+	log := t.jobLogger(job)
 	start := time.Now()
 	result := DownloadResult{
 		Job:     job,
 		Success: false,
 	}
 	
-	wp.logger.DebugWithFields("Worker processing job", map[string]interface{}{
+	log.DebugWithFields("Worker processing job", map[string]interface{}{
 		"worker_id": workerID,
 		"shortcode": job.Shortcode,
 		"username":  job.Username,
 	})
 	
 	// Check if already downloaded
-	if wp.storageManager.IsDownloaded(job.Shortcode) {
-		wp.logger.DebugWithFields("Photo already downloaded", map[string]interface{}{
+	if !job.Overwrite && t.storage.IsDownloaded(job.Shortcode) {
+		log.DebugWithFields("Photo already downloaded", map[string]interface{}{
 			"worker_id": workerID,
 			"shortcode": job.Shortcode,
 		})
@@ -197,21 +786,59 @@ func (wp *WorkerPool) processJob(job DownloadJob, workerID int) DownloadResult {
 	}
 	
 	// Wait for rate limit
-	if !wp.rateLimiter.Allow() {
-		wp.logger.DebugWithFields("Worker waiting for rate limit", map[string]interface{}{
+	if !t.limiter.Allow() {
+		log.DebugWithFields("Worker waiting for rate limit", map[string]interface{}{
+			"worker_id": workerID,
+			"shortcode": job.Shortcode,
+		})
+		t.limiter.Wait()
+	}
+	
+	// Skip a file outside the size limits before downloading it, when the
+	// client can tell its size
+	if err := t.probeSize(job); err != nil {
+		result.Error = err
+		result.Duration = time.Since(start)
+		log.DebugWithFields("Worker skipped file outside size limits", map[string]interface{}{
 			"worker_id": workerID,
 			"shortcode": job.Shortcode,
+			"reason":    err.Error(),
 		})
-		wp.rateLimiter.Wait()
+		return result
 	}
 	
-	// Download the photo
-	data, err := wp.client.DownloadPhoto(job.URL)
+	// Download the photo within the job's deadline
+	ctx, cancel := t.pool.jobContext()
+	defer cancel()
+	job.ctx = ctx
+	media, err := t.fetch(job)
+	if job.skipped != nil && job.skipped.Load() {
+		if media != nil {
+			media.cleanup()
+		}
+		result.Error = ErrCancelled
+		result.Duration = time.Since(start)
+		log.InfoWithFields("Worker skipped job", map[string]interface{}{
+			"worker_id": workerID,
+			"shortcode": job.Shortcode,
+		})
+		return result
+	}
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		result.Error = fmt.Errorf("%w after %s", ErrTimedOut, t.pool.jobTimeout)
+		result.Duration = time.Since(start)
+		log.WarnWithFields("Worker timed out job", map[string]interface{}{
+			"worker_id": workerID,
+			"shortcode": job.Shortcode,
+			"timeout":   t.pool.jobTimeout,
+		})
+		return result
+	}
 	if err != nil {
 		result.Error = fmt.Errorf("download failed: %w", err)
 		result.Duration = time.Since(start)
 		
-		wp.logger.ErrorWithFields("Worker failed to download photo", map[string]interface{}{
+		log.ErrorWithFields("Worker failed to download photo", map[string]interface{}{
 			"worker_id": workerID,
 			"shortcode": job.Shortcode,
 			"error":     err.Error(),
@@ -221,20 +848,29 @@ func (wp *WorkerPool) processJob(job DownloadJob, workerID int) DownloadResult {
 		return result
 	}
 	
-	result.Size = len(data)
+	defer media.cleanup()
+	result.Size = int(media.size)
 	
-	// Save the photo with metadata if available
-	if job.Node != nil {
-		err = wp.storageManager.SavePhotoWithMetadata(bytes.NewReader(data), job.Shortcode, job.Node)
-	} else {
-		err = wp.storageManager.SavePhoto(bytes.NewReader(data), job.Shortcode)
+	// The size could not be told in advance, or the server got it wrong
+	if err := t.pool.checkSize(media.size); err != nil {
+		result.Error = err
+		result.Duration = time.Since(start)
+		log.DebugWithFields("Worker dropped file outside size limits", map[string]interface{}{
+			"worker_id": workerID,
+			"shortcode": job.Shortcode,
+			"reason":    err.Error(),
+		})
+		return result
 	}
 	
+	// Save the photo with metadata if available
+	err = t.save(job, media)
+	
 	if err != nil {
 		result.Error = fmt.Errorf("save failed: %w", err)
 		result.Duration = time.Since(start)
 		
-		wp.logger.ErrorWithFields("Worker failed to save photo", map[string]interface{}{
+		log.ErrorWithFields("Worker failed to save photo", map[string]interface{}{
 			"worker_id": workerID,
 			"shortcode": job.Shortcode,
 			"error":     err.Error(),
@@ -244,10 +880,14 @@ func (wp *WorkerPool) processJob(job DownloadJob, workerID int) DownloadResult {
 		return result
 	}
 	
+	if job.ThumbnailURL != "" {
+		t.saveThumbnail(job, workerID)
+	}
+	
 	result.Success = true
 	result.Duration = time.Since(start)
 	
-	wp.logger.DebugWithFields("Worker completed job successfully", map[string]interface{}{
+	log.DebugWithFields("Worker completed job successfully", map[string]interface{}{
 		"worker_id": workerID,
 		"shortcode": job.Shortcode,
 		"size":      result.Size,
@@ -257,12 +897,221 @@ func (wp *WorkerPool) processJob(job DownloadJob, workerID int) DownloadResult {
 	return result
 }
 
+// media is a downloaded photo or video, held either in memory or in a
+// temporary file
+type media struct {
+	data []byte
+	file *os.File
+	size int64
+}
+
+// cleanup removes the temporary file, unless the storage took it over
+func (m *media) cleanup() {
+	if m.file != nil {
+		m.file.Close()
+		os.Remove(m.file.Name())
+	}
+}
+
+// probeSize returns an error wrapping ErrSizeLimit if the pool has size
+// limits, the client can tell the size of the job's file and it is outside
+// them. A failed probe is left to the download to report.
+func (t *Target) probeSize(job DownloadJob) error {
+	wp := t.pool
+	prober, ok := t.client.(SizeProber)
+	if !ok || (wp.minSize <= 0 && wp.maxSize <= 0) {
+		return nil
+	}
+	size, err := prober.ContentLength(job.URL)
+	if err != nil {
+		t.jobLogger(job).DebugWithFields("Failed to probe file size", map[string]interface{}{
+			"shortcode": job.Shortcode,
+			"error":     err.Error(),
+		})
+		return nil
+	}
+	return wp.checkSize(size)
+}
+
+// jobContext returns the context of a job being downloaded, which ends once
+// the pool's job timeout passes
+func (wp *WorkerPool) jobContext() (context.Context, context.CancelFunc) {
+	if wp.jobTimeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), wp.jobTimeout)
+}
+
+// fetch downloads the media of a job, giving up once the job's context is
+// done. The clients take no context, so a download still running then is
+// left to end on its own: streamed ones fail at their next write, and the
+// media of others is thrown away when it arrives.
+func (t *Target) fetch(job DownloadJob) (*media, error) {
+	type fetched struct {
+		media *media
+		err   error
+	}
+	done := make(chan fetched, 1)
+	go func() {
+		m, err := t.download(job)
+		done <- fetched{m, err}
+	}()
+	
+	select {
+	case f := <-done:
+		return f.media, f.err
+	case <-job.ctx.Done():
+		go func() {
+			if f := <-done; f.media != nil {
+				f.media.cleanup()
+			}
+		}()
+		return nil, job.ctx.Err()
+	}
+}
+
+// download fetches the media of a job. When the client and storage support
+// it, the media is streamed to a temporary file in the output directory, so
+// it is never held in memory; videos are, even without file storage.
+// Otherwise photos are downloaded into memory.
+func (t *Target) download(job DownloadJob) (*media, error) {
+	dir := ""
+	if d, ok := t.storage.(interface{ GetOutputDir() string }); ok {
+		dir = d.GetOutputDir()
+	}
+	
+	var fetch func(url string, w io.WriterAt) (int64, error)
+	_, toFile := t.storage.(FileStorage)
+	if videos, ok := t.client.(VideoDownloader); ok && job.Node != nil && job.Node.IsVideo {
+		fetch = videos.DownloadVideo
+	} else if files, ok := t.client.(FileDownloader); ok && toFile {
+		fetch = files.DownloadFile
+	} else {
+		photo, err := t.client.DownloadPhoto(job.URL)
+		if err != nil {
+			return nil, err
+		}
+		if job.Progress != nil {
+			job.Progress(int64(len(photo)))
+		}
+		return &media{data: photo, size: int64(len(photo))}, nil
+	}
+	
+	file, err := os.CreateTemp(dir, ".download-*.part")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	m := &media{file: file}
+	
+	var w io.WriterAt = file
+	if job.skipped != nil || job.ctx != nil {
+		w = &skipWriter{w: w, skipped: job.skipped, ctx: job.ctx}
+	}
+	if job.Progress != nil {
+		w = &progressWriter{w: w, progress: job.Progress}
+	}
+	m.size, err = fetch(job.URL, w)
+	if err != nil {
+		m.cleanup()
+		return nil, err
+	}
+	return m, nil
+}
+
+// save stores downloaded media. A temporary file is handed to file storage
+// as it is, or read back by storage that only takes readers.
+func (t *Target) save(job DownloadJob, m *media) error {
+	if files, ok := t.storage.(FileStorage); ok && m.file != nil {
+		if err := m.file.Close(); err != nil {
+			return fmt.Errorf("failed to close temporary file: %w", err)
+		}
+		return files.SaveFile(m.file.Name(), job.Shortcode, job.Node)
+	}
+	
+	var r io.Reader = bytes.NewReader(m.data)
+	if m.file != nil {
+		if _, err := m.file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		r = m.file
+	}
+	if job.Node != nil {
+		return t.storage.SavePhotoWithMetadata(r, job.Shortcode, job.Node)
+	}
+	return t.storage.SavePhoto(r, job.Shortcode)
+}
+
+// progressWriter reports the bytes written through it
+type progressWriter struct {
+	w        io.WriterAt
+	written  atomic.Int64
+	progress func(written int64)
+}
+
+// WriteAt writes b at off and reports the new total
+func (p *progressWriter) WriteAt(b []byte, off int64) (int, error) {
+	n, err := p.w.WriteAt(b, off)
+	if n > 0 {
+		p.progress(p.written.Add(int64(n)))
+	}
+	return n, err
+}
+
+// skipWriter fails writes once its job is skipped or its context is done,
+// which ends a streamed download early
+type skipWriter struct {
+	w       io.WriterAt
+	skipped *atomic.Bool
+	ctx     context.Context
+}
+
+// WriteAt writes b at off unless the job is skipped or cancelled
+func (s *skipWriter) WriteAt(b []byte, off int64) (int, error) {
+	if s.skipped != nil && s.skipped.Load() {
+		return 0, ErrCancelled
+	}
+	if s.ctx != nil {
+		if err := s.ctx.Err(); err != nil {
+			return 0, err
+		}
+	}
+	return s.w.WriteAt(b, off)
+}
+
+// saveThumbnail downloads and stores the cover image of a job's video. The
+// video is what matters, so a failure is only logged.
+func (t *Target) saveThumbnail(job DownloadJob, workerID int) {
+	thumbnails, ok := t.storage.(ThumbnailStorage)
+	if !ok {
+		return
+	}
+	
+	data, err := t.client.DownloadPhoto(job.ThumbnailURL)
+	if err == nil {
+		err = thumbnails.SaveThumbnail(bytes.NewReader(data), job.Shortcode, job.Node)
+	}
+	if err != nil {
+		t.jobLogger(job).WarnWithFields("Worker failed to save thumbnail", map[string]interface{}{
+			"worker_id": workerID,
+			"shortcode": job.Shortcode,
+			"error":     err.Error(),
+		})
+	}
+}
+
 // GetQueueSize returns the current number of jobs in the queue
 func (wp *WorkerPool) GetQueueSize() int {
-	return len(wp.jobQueue)
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	
+	size := 0
+	for _, t := range wp.targets {
+		size += len(t.queue)
+	}
+	return size
 }
 
 // GetActiveWorkers returns the number of active workers
 func (wp *WorkerPool) GetActiveWorkers() int {
 	return wp.numWorkers
-}
\ No newline at end of file
+}
@@ -1,14 +1,19 @@
 package downloader
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"os"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"igscraper/pkg/instagram"
+	"igscraper/pkg/logger"
 	"igscraper/pkg/ratelimit"
 )
 
@@ -309,4 +314,702 @@ func TestWorkerPoolDuplicateDetection(t *testing.T) {
 	if mockStorage.GetSavedCount() != 4 {
 		t.Errorf("Expected 4 saved photos, got %d", mockStorage.GetSavedCount())
 	}
-}
\ No newline at end of file
+}
+
+func TestWorkerPoolOverwrite(t *testing.T) {
+	client := &MockClient{}
+	storage := NewMockStorageManager()
+	storage.savedPhotos["existing"] = true
+	pool := NewWorkerPool(1, client, storage, ratelimit.NewTokenBucket(100, time.Second), nil)
+	pool.Start()
+	
+	pool.Submit(DownloadJob{URL: "https://example.com/existing.jpg", Shortcode: "existing", Overwrite: true})
+	go pool.Stop()
+	
+	for result := range pool.Results() {
+		if !result.Success {
+			t.Errorf("Expected the overwrite to succeed, got %v", result.Error)
+		}
+	}
+	if client.GetDownloadCount() != 1 {
+		t.Errorf("Expected the saved photo to be downloaded again, got %d downloads", client.GetDownloadCount())
+	}
+}
+// recordingClient records the order photos are downloaded in
+type recordingClient struct {
+	mu   sync.Mutex
+	urls []string
+}
+
+func (c *recordingClient) DownloadPhoto(url string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.urls = append(c.urls, url)
+	return []byte("photo"), nil
+}
+
+func TestSharedPoolFairScheduling(t *testing.T) {
+	client := &recordingClient{}
+	rateLimiter := ratelimit.NewTokenBucket(100, time.Second)
+	pool := NewSharedPool(1, nil)
+	
+	a, err := pool.AddTarget("a", client, NewMockStorageManager(), rateLimiter)
+	if err != nil {
+		t.Fatalf("Failed to add target: %v", err)
+	}
+	b, err := pool.AddTarget("b", client, NewMockStorageManager(), rateLimiter)
+	if err != nil {
+		t.Fatalf("Failed to add target: %v", err)
+	}
+	
+	// Queue both targets before any worker runs
+	for i := 0; i < 2; i++ {
+		a.Submit(DownloadJob{URL: fmt.Sprintf("a%d", i), Shortcode: fmt.Sprintf("a%d", i)})
+		b.Submit(DownloadJob{URL: fmt.Sprintf("b%d", i), Shortcode: fmt.Sprintf("b%d", i)})
+	}
+	
+	var wg sync.WaitGroup
+	counts := make(map[string]int)
+	var mu sync.Mutex
+	for _, target := range []*Target{a, b} {
+		wg.Add(1)
+		go func(target *Target) {
+			defer wg.Done()
+			for range target.Results() {
+				mu.Lock()
+				counts[target.name]++
+				mu.Unlock()
+			}
+		}(target)
+	}
+	
+	pool.Start()
+	a.Close()
+	b.Close()
+	wg.Wait()
+	pool.Stop()
+	
+	want := []string{"a0", "b0", "a1", "b1"}
+	if fmt.Sprint(client.urls) != fmt.Sprint(want) {
+		t.Errorf("Expected targets to take turns %v, got %v", want, client.urls)
+	}
+	if counts["a"] != 2 || counts["b"] != 2 {
+		t.Errorf("Expected 2 results per target, got %v", counts)
+	}
+	if stats := a.Stats(); stats.Completed != 2 || stats.Bytes != int64(2*len("photo")) || stats.Queued != 0 {
+		t.Errorf("Unexpected stats for a: %+v", stats)
+	}
+	if len(pool.Stats()) != 0 {
+		t.Errorf("Expected finished targets to leave the pool, got %v", pool.Stats())
+	}
+}
+
+func TestSharedPoolTargetsComeAndGo(t *testing.T) {
+	mockClient := &MockClient{downloadDelay: 5 * time.Millisecond}
+	rateLimiter := ratelimit.NewTokenBucket(100, time.Second)
+	pool := NewSharedPool(3, nil)
+	pool.Start()
+	
+	// Targets run concurrently, each with its own storage and results
+	var wg sync.WaitGroup
+	storages := make([]*MockStorageManager, 4)
+	for i := range storages {
+		storages[i] = NewMockStorageManager()
+		target, err := pool.AddTarget(fmt.Sprintf("user%d", i), mockClient, storages[i], rateLimiter)
+		if err != nil {
+			t.Fatalf("Failed to add target: %v", err)
+		}
+		
+		wg.Add(1)
+		go func(target *Target) {
+			defer wg.Done()
+			done := make(chan int)
+			go func() {
+				n := 0
+				for range target.Results() {
+					n++
+				}
+				done <- n
+			}()
+			for j := 0; j < 5; j++ {
+				if err := target.Submit(DownloadJob{URL: "url", Shortcode: fmt.Sprintf("s%d", j)}); err != nil {
+					t.Errorf("Failed to submit job: %v", err)
+				}
+			}
+			target.Close()
+			if n := <-done; n != 5 {
+				t.Errorf("Expected 5 results for %s, got %d", target.name, n)
+			}
+		}(target)
+	}
+	wg.Wait()
+	pool.Stop()
+	
+	for i, storage := range storages {
+		if storage.GetSavedCount() != 5 {
+			t.Errorf("Expected 5 photos saved for user%d, got %d", i, storage.GetSavedCount())
+		}
+	}
+	if _, err := pool.AddTarget("late", mockClient, NewMockStorageManager(), rateLimiter); err == nil {
+		t.Error("Expected adding a target to a stopped pool to fail")
+	}
+}
+
+func TestTargetLogger(t *testing.T) {
+	pool := NewSharedPool(1, nil)
+	pool.Start()
+	defer pool.Stop()
+	
+	target, err := pool.AddTarget("user", &MockClient{}, NewMockStorageManager(), ratelimit.NewTokenBucket(100, time.Second))
+	if err != nil {
+		t.Fatalf("Failed to add target: %v", err)
+	}
+	log := logger.NewTestLogger()
+	target.SetLogger(log.WithField(logger.FieldScrapeID, "3f9a1c0e7b24d5a8"))
+	
+	node := &instagram.Node{Shortcode: "ABC123", RequestID: "9c1e04b7a2f3"}
+	if err := target.Submit(DownloadJob{URL: "url", Shortcode: "ABC123", Node: node}); err != nil {
+		t.Fatalf("Failed to submit job: %v", err)
+	}
+	target.Close()
+	for range target.Results() {
+	}
+	
+	// The job's lines carry the scrape ID and the ID of the request that
+	// listed the post
+	found := false
+	for _, msg := range log.GetMessages() {
+		if msg.Message != "Worker completed job successfully" {
+			continue
+		}
+		found = true
+		if msg.Fields[logger.FieldScrapeID] != "3f9a1c0e7b24d5a8" || msg.Fields[logger.FieldRequestID] != "9c1e04b7a2f3" {
+			t.Errorf("Job logged with fields %v", msg.Fields)
+		}
+	}
+	if !found {
+		t.Errorf("Job was not logged to the target's logger: %v", log.GetMessages())
+	}
+}
+
+// videoClient also downloads videos to a file, as the Instagram client does
+type videoClient struct {
+	MockClient
+	videos int32
+}
+
+func (c *videoClient) DownloadVideo(url string, w io.WriterAt) (int64, error) {
+	atomic.AddInt32(&c.videos, 1)
+	n, err := w.WriteAt([]byte("mock video data"), 0)
+	return int64(n), err
+}
+
+// contentStorage records what was saved for each shortcode
+type contentStorage struct {
+	MockStorageManager
+	content map[string]string
+	dir     string
+}
+
+func (s *contentStorage) SavePhotoWithMetadata(r io.Reader, shortcode string, node *instagram.Node) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.content[shortcode] = string(data)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *contentStorage) GetOutputDir() string {
+	return s.dir
+}
+
+func TestWorkerPoolVideos(t *testing.T) {
+	client := &videoClient{}
+	storage := &contentStorage{content: make(map[string]string), dir: t.TempDir()}
+	pool := NewWorkerPool(2, client, storage, ratelimit.NewTokenBucket(100, time.Second), nil)
+	pool.Start()
+	
+	pool.Submit(DownloadJob{URL: "http://example.com/photo.jpg", Shortcode: "photo", Node: &instagram.Node{}})
+	pool.Submit(DownloadJob{URL: "http://example.com/video.mp4", Shortcode: "video", Node: &instagram.Node{IsVideo: true}})
+	go pool.Stop()
+	
+	for result := range pool.Results() {
+		if !result.Success {
+			t.Errorf("Job %s failed: %v", result.Job.Shortcode, result.Error)
+		}
+		if result.Job.Shortcode == "video" && result.Size != len("mock video data") {
+			t.Errorf("Expected video size %d, got %d", len("mock video data"), result.Size)
+		}
+	}
+	
+	if client.GetDownloadCount() != 1 || atomic.LoadInt32(&client.videos) != 1 {
+		t.Errorf("Expected 1 photo and 1 video download, got %d and %d", client.GetDownloadCount(), client.videos)
+	}
+	if storage.content["video"] != "mock video data" || storage.content["photo"] != "mock photo data" {
+		t.Errorf("Unexpected saved content: %v", storage.content)
+	}
+	
+	// Temporary video files are removed once saved
+	if leftover, _ := os.ReadDir(storage.dir); len(leftover) > 0 {
+		t.Errorf("Temporary video files left behind: %v", leftover)
+	}
+}
+
+func TestCancelPending(t *testing.T) {
+	release := make(chan struct{})
+	client := &blockingClient{release: release}
+	storage := NewMockStorageManager()
+	
+	pool := NewSharedPool(1, nil)
+	target, err := pool.AddTarget("profile", client, storage, ratelimit.NewTokenBucket(1000, time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.Start()
+	defer pool.Stop()
+	
+	// The only worker blocks on the first job, so the next two stay queued
+	target.Submit(DownloadJob{Shortcode: "first", Node: &instagram.Node{}})
+	for target.QueueSize() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	target.Submit(DownloadJob{Shortcode: "video", Node: &instagram.Node{IsVideo: true}})
+	target.Submit(DownloadJob{Shortcode: "photo", Node: &instagram.Node{}})
+	
+	results := make(map[string]error)
+	done := make(chan struct{})
+	go func() {
+		for result := range target.Results() {
+			results[result.Job.Shortcode] = result.Error
+		}
+		close(done)
+	}()
+	
+	cancelled := pool.CancelPending(func(job DownloadJob) bool {
+		return job.Node != nil && job.Node.IsVideo
+	})
+	if cancelled != 1 {
+		t.Errorf("Expected 1 cancelled job, got %d", cancelled)
+	}
+	if stats := target.Stats(); stats.Cancelled != 1 || stats.Queued != 1 {
+		t.Errorf("Unexpected stats after cancelling: %+v", stats)
+	}
+	
+	close(release)
+	target.Close()
+	<-done
+	
+	if results["first"] != nil || results["photo"] != nil {
+		t.Errorf("Expected photos to download, got %v", results)
+	}
+	if !errors.Is(results["video"], ErrCancelled) {
+		t.Errorf("Expected video to be cancelled, got %v", results["video"])
+	}
+	if storage.GetSavedCount() != 2 {
+		t.Errorf("Expected 2 saved photos, got %d", storage.GetSavedCount())
+	}
+}
+
+func TestPauseAndSkip(t *testing.T) {
+	release := make(chan struct{})
+	client := &blockingClient{release: release}
+	storage := NewMockStorageManager()
+	
+	pool := NewSharedPool(2, nil)
+	target, err := pool.AddTarget("profile", client, storage, ratelimit.NewTokenBucket(1000, time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.Pause()
+	pool.Start()
+	defer pool.Stop()
+	
+	target.Submit(DownloadJob{Shortcode: "first", Node: &instagram.Node{}})
+	target.Submit(DownloadJob{Shortcode: "second", Node: &instagram.Node{}})
+	target.Submit(DownloadJob{Shortcode: "third", Node: &instagram.Node{}})
+	
+	time.Sleep(20 * time.Millisecond)
+	if stats := target.Stats(); stats.Active != 0 || stats.Queued != 3 {
+		t.Errorf("Expected a paused pool to start no jobs, got %+v", stats)
+	}
+	
+	// A queued job is dropped
+	if !target.Skip("second") {
+		t.Error("Expected the queued job to be skipped")
+	}
+	if target.Skip("missing") {
+		t.Error("Expected an unknown job not to be skipped")
+	}
+	
+	results := make(map[string]error)
+	done := make(chan struct{})
+	go func() {
+		for result := range target.Results() {
+			results[result.Job.Shortcode] = result.Error
+		}
+		close(done)
+	}()
+	
+	pool.Resume()
+	if pool.Paused() {
+		t.Error("Expected the pool to be resumed")
+	}
+	for target.Stats().Active < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	
+	// A job being downloaded is stopped
+	if !target.Skip("first") {
+		t.Error("Expected the running job to be skipped")
+	}
+	close(release)
+	target.Close()
+	<-done
+	
+	if !errors.Is(results["first"], ErrCancelled) || !errors.Is(results["second"], ErrCancelled) {
+		t.Errorf("Expected skipped jobs to be cancelled, got %v", results)
+	}
+	if results["third"] != nil {
+		t.Errorf("Expected the third job to download, got %v", results["third"])
+	}
+	if storage.GetSavedCount() != 1 {
+		t.Errorf("Expected 1 saved photo, got %d", storage.GetSavedCount())
+	}
+	if stats := target.Stats(); stats.Cancelled != 2 || stats.Completed != 1 || stats.Failed != 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestSkipRunning(t *testing.T) {
+	release := make(chan struct{})
+	client := &blockingClient{release: release}
+	storage := NewMockStorageManager()
+	
+	pool := NewSharedPool(2, nil)
+	target, err := pool.AddTarget("profile", client, storage, ratelimit.NewTokenBucket(1000, time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.Start()
+	defer pool.Stop()
+	
+	target.Submit(DownloadJob{Shortcode: "first", Node: &instagram.Node{}})
+	target.Submit(DownloadJob{Shortcode: "second", Node: &instagram.Node{}})
+	for target.Stats().Active < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	
+	if n := target.SkipRunning(); n != 2 {
+		t.Errorf("Expected 2 downloads to be stopped, got %d", n)
+	}
+	close(release)
+	target.Close()
+	for result := range target.Results() {
+		if !errors.Is(result.Error, ErrCancelled) {
+			t.Errorf("Expected %s to be cancelled, got %v", result.Job.Shortcode, result.Error)
+		}
+	}
+	if storage.GetSavedCount() != 0 {
+		t.Errorf("Expected nothing saved, got %d", storage.GetSavedCount())
+	}
+}
+
+// blockingClient holds every download until release is closed
+type blockingClient struct {
+	release chan struct{}
+}
+
+func (c *blockingClient) DownloadPhoto(url string) ([]byte, error) {
+	<-c.release
+	return []byte("photo"), nil
+}
+
+// fileClient also streams photos to a file, as the Instagram client does
+type fileClient struct {
+	MockClient
+	files int32
+}
+
+func (c *fileClient) DownloadFile(url string, w io.WriterAt) (int64, error) {
+	atomic.AddInt32(&c.files, 1)
+	n, err := w.WriteAt([]byte("streamed "), 0)
+	if err != nil {
+		return int64(n), err
+	}
+	m, err := w.WriteAt([]byte("photo"), int64(n))
+	return int64(n + m), err
+}
+
+// fileStorage takes over downloaded files, as the storage manager does
+type fileStorage struct {
+	MockStorageManager
+	dir   string
+	saved map[string]string
+}
+
+func (s *fileStorage) GetOutputDir() string {
+	return s.dir
+}
+
+func (s *fileStorage) SaveFile(path, shortcode string, node *instagram.Node) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.saved[shortcode] = path + ".saved"
+	return os.Rename(path, path+".saved")
+}
+
+func TestWorkerPoolStreaming(t *testing.T) {
+	client := &fileClient{}
+	storage := &fileStorage{dir: t.TempDir(), saved: make(map[string]string)}
+	pool := NewWorkerPool(2, client, storage, ratelimit.NewTokenBucket(100, time.Second), nil)
+	pool.Start()
+	
+	var progress []int64
+	var mu sync.Mutex
+	pool.Submit(DownloadJob{
+		URL:       "http://example.com/photo.jpg",
+		Shortcode: "photo",
+		Node:      &instagram.Node{},
+		Progress: func(written int64) {
+			mu.Lock()
+			progress = append(progress, written)
+			mu.Unlock()
+		},
+	})
+	go pool.Stop()
+	
+	for result := range pool.Results() {
+		if !result.Success {
+			t.Fatalf("Job %s failed: %v", result.Job.Shortcode, result.Error)
+		}
+		if result.Size != len("streamed photo") {
+			t.Errorf("Expected size %d, got %d", len("streamed photo"), result.Size)
+		}
+	}
+	
+	if client.GetDownloadCount() != 0 || atomic.LoadInt32(&client.files) != 1 {
+		t.Errorf("Expected the photo to be streamed, got %d in memory and %d streamed", client.GetDownloadCount(), client.files)
+	}
+	data, err := os.ReadFile(storage.saved["photo"])
+	if err != nil || string(data) != "streamed photo" {
+		t.Errorf("Unexpected saved file: %q, %v", data, err)
+	}
+	if len(progress) != 2 || progress[0] != int64(len("streamed ")) || progress[1] != int64(len("streamed photo")) {
+		t.Errorf("Unexpected progress: %v", progress)
+	}
+	
+	// The storage took the file over; nothing else is left behind
+	if entries, _ := os.ReadDir(storage.dir); len(entries) != 1 {
+		t.Errorf("Expected only the saved file, got %v", entries)
+	}
+}
+
+// stallingClient holds the first download of each URL in stall until release
+// is closed, and records the order of the downloads
+type stallingClient struct {
+	mu      sync.Mutex
+	stall   map[string]bool
+	calls   []string
+	release chan struct{}
+}
+
+func (c *stallingClient) DownloadPhoto(url string) ([]byte, error) {
+	c.mu.Lock()
+	c.calls = append(c.calls, url)
+	stall := c.stall[url]
+	c.stall[url] = false
+	c.mu.Unlock()
+	if stall {
+		<-c.release
+	}
+	return []byte("photo"), nil
+}
+
+func TestJobTimeout(t *testing.T) {
+	for _, attempts := range []int{0, 1} {
+		client := &stallingClient{stall: map[string]bool{"slow": true}, release: make(chan struct{})}
+		storage := NewMockStorageManager()
+		pool := NewWorkerPool(1, client, storage, ratelimit.NewTokenBucket(1000, time.Second), nil)
+		pool.SetRequeue(attempts)
+		pool.SetJobTimeout(50 * time.Millisecond)
+		pool.Start()
+		
+		pool.Submit(DownloadJob{URL: "slow", Shortcode: "slow"})
+		pool.Submit(DownloadJob{URL: "fast", Shortcode: "fast"})
+		go pool.Stop()
+		
+		results := make(map[string]DownloadResult)
+		for result := range pool.Results() {
+			results[result.Job.Shortcode] = result
+		}
+		close(client.release)
+		
+		if !results["fast"].Success {
+			t.Errorf("Requeue %d: expected fast to download, got %v", attempts, results["fast"].Error)
+		}
+		stats := pool.single.Stats()
+		if stats.TimedOut != 1 {
+			t.Errorf("Requeue %d: expected 1 timed out attempt, got %+v", attempts, stats)
+		}
+		
+		slow := results["slow"]
+		if attempts == 0 {
+			if !errors.Is(slow.Error, ErrTimedOut) {
+				t.Errorf("Expected slow to time out, got %v", slow.Error)
+			}
+			if stats.Failed != 1 {
+				t.Errorf("Expected the timeout to count as a failure, got %+v", stats)
+			}
+			continue
+		}
+		
+		// The timed out job waits behind the others before its retry
+		if !slow.Success || slow.Job.Retries != 1 {
+			t.Errorf("Expected slow to download on its retry, got %+v", slow)
+		}
+		if got := strings.Join(client.calls, " "); got != "slow fast slow" {
+			t.Errorf("Expected downloads slow fast slow, got %s", got)
+		}
+		if storage.GetSavedCount() != 2 {
+			t.Errorf("Expected 2 saved photos, got %d", storage.GetSavedCount())
+		}
+	}
+}
+
+// slowFileClient streams a file a byte at a time, one every interval
+type slowFileClient struct {
+	MockClient
+	interval time.Duration
+}
+
+func (c *slowFileClient) DownloadFile(url string, w io.WriterAt) (int64, error) {
+	var written int64
+	for i := 0; i < 100; i++ {
+		time.Sleep(c.interval)
+		n, err := w.WriteAt([]byte{'x'}, written)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+func TestJobTimeoutStreaming(t *testing.T) {
+	client := &slowFileClient{interval: 5 * time.Millisecond}
+	storage := &fileStorage{dir: t.TempDir(), saved: make(map[string]string)}
+	pool := NewWorkerPool(1, client, storage, ratelimit.NewTokenBucket(100, time.Second), nil)
+	pool.SetJobTimeout(20 * time.Millisecond)
+	pool.Start()
+	
+	pool.Submit(DownloadJob{URL: "http://example.com/photo.jpg", Shortcode: "photo", Node: &instagram.Node{}})
+	go pool.Stop()
+	
+	for result := range pool.Results() {
+		if !errors.Is(result.Error, ErrTimedOut) {
+			t.Errorf("Expected the download to time out, got %v", result.Error)
+		}
+	}
+	
+	// The abandoned download stops at its next write and its file is removed
+	deadline := time.Now().Add(time.Second)
+	for {
+		entries, _ := os.ReadDir(storage.dir)
+		if len(entries) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the temporary file to be removed, got %v", entries)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// sizingClient tells the size of each file from a map, -1 if it is unknown
+type sizingClient struct {
+	MockClient
+	sizes map[string]int64
+}
+
+func (c *sizingClient) ContentLength(url string) (int64, error) {
+	if size, ok := c.sizes[url]; ok {
+		return size, nil
+	}
+	return -1, nil
+}
+
+func TestSizeLimits(t *testing.T) {
+	// "mock photo data" is 15 bytes once downloaded
+	tests := []struct {
+		name      string
+		client    PhotoDownloader
+		minSize   int64
+		downloads int
+		skipped   []string
+	}{
+		{
+			name:      "probed before the download",
+			client:    &sizingClient{sizes: map[string]int64{"small": 10, "large": 2000}},
+			minSize:   12,
+			downloads: 1,
+			skipped:   []string{"large", "small"},
+		},
+		{
+			name:      "checked after the download",
+			client:    &MockClient{},
+			minSize:   20,
+			downloads: 3,
+			skipped:   []string{"fits", "large", "small"},
+		},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := NewMockStorageManager()
+			pool := NewWorkerPool(1, tt.client, storage, ratelimit.NewTokenBucket(1000, time.Second), nil)
+			pool.SetRequeue(2)
+			pool.SetSizeLimits(tt.minSize, 1000)
+			pool.Start()
+			
+			for _, shortcode := range []string{"small", "large", "fits"} {
+				pool.Submit(DownloadJob{URL: shortcode, Shortcode: shortcode})
+			}
+			go pool.Stop()
+			
+			var skipped []string
+			for result := range pool.Results() {
+				if errors.Is(result.Error, ErrSizeLimit) {
+					skipped = append(skipped, result.Job.Shortcode)
+				}
+				if result.Job.Retries != 0 {
+					t.Errorf("Expected %s not to be requeued", result.Job.Shortcode)
+				}
+			}
+			sort.Strings(skipped)
+			
+			if strings.Join(skipped, " ") != strings.Join(tt.skipped, " ") {
+				t.Errorf("Expected %v skipped, got %v", tt.skipped, skipped)
+			}
+			var counter *MockClient
+			switch c := tt.client.(type) {
+			case *sizingClient:
+				counter = &c.MockClient
+			case *MockClient:
+				counter = c
+			}
+			if counter.GetDownloadCount() != tt.downloads {
+				t.Errorf("Expected %d downloads, got %d", tt.downloads, counter.GetDownloadCount())
+			}
+			stats := pool.single.Stats()
+			if stats.Skipped != len(tt.skipped) || stats.Failed != 0 {
+				t.Errorf("Expected %d skipped and no failures, got %+v", len(tt.skipped), stats)
+			}
+			if storage.GetSavedCount() != 3-len(tt.skipped) {
+				t.Errorf("Expected %d saved photos, got %d", 3-len(tt.skipped), storage.GetSavedCount())
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package downloader
+
+import (
+	"errors"
+	"time"
+
+	igerrors "igscraper/pkg/errors"
+)
+
+// Order is the order in which the queued jobs of a target are downloaded
+type Order string
+
+const (
+	// OrderNewest downloads the most recent posts first
+	OrderNewest Order = "newest"
+
+	// OrderOldest downloads the earliest posts first
+	OrderOldest Order = "oldest"
+)
+
+// before reports whether a job for a post taken at a comes before one taken
+// at b. Posts without a timestamp come after those with one.
+func (o Order) before(a, b time.Time) bool {
+	switch {
+	case a.IsZero():
+		return false
+	case b.IsZero():
+		return true
+	case o == OrderOldest:
+		return a.Before(b)
+	default:
+		return a.After(b)
+	}
+}
+
+// takenAt returns the time the job's post was taken, or the zero time when
+// it is not known
+func (job DownloadJob) takenAt() time.Time {
+	if job.Node == nil {
+		return time.Time{}
+	}
+	return job.Node.TakenAt()
+}
+
+// enqueue adds a job to the target's queue in the pool's order. Jobs of
+// posts taken at the same time, and re-queued jobs, keep the order they were
+// queued in. The pool's lock must be held.
+func (t *Target) enqueue(job DownloadJob) {
+	taken := job.takenAt()
+	i := len(t.queue)
+	for j, queued := range t.queue {
+		if queued.Retries > 0 || t.pool.order.before(taken, queued.takenAt()) {
+			i = j
+			break
+		}
+	}
+	t.queue = append(t.queue, DownloadJob{})
+	copy(t.queue[i+1:], t.queue[i:])
+	t.queue[i] = job
+	t.stats.Queued++
+}
+
+// requeue puts a failed job back at the end of the target's queue, unless
+// it has been retried as often as the pool allows or retrying cannot help.
+// It reports whether the job was queued again. The pool's lock must be held.
+func (t *Target) requeue(job DownloadJob, result DownloadResult) bool {
+	if result.Success || job.Retries >= t.pool.requeues || !retryable(result.Error) {
+		return false
+	}
+	job.Retries++
+	job.skipped = nil
+	t.queue = append(t.queue, job)
+	t.stats.Queued++
+	t.stats.Requeued++
+
+	t.jobLogger(job).WarnWithFields("Re-queued failed job", map[string]interface{}{
+		"shortcode": job.Shortcode,
+		"target":    t.name,
+		"retry":     job.Retries,
+		"error":     result.Error.Error(),
+	})
+	return true
+}
+
+// retryable reports whether a download that failed with err may succeed when
+// tried again. Cancelled jobs, files outside the size limits and API errors
+// that repeating cannot fix, such as a deleted post, are not retried.
+func retryable(err error) bool {
+	if errors.Is(err, ErrCancelled) || errors.Is(err, ErrSizeLimit) {
+		return false
+	}
+	var apiErr *igerrors.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Retryable()
+	}
+	return true
+}
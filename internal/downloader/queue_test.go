@@ -0,0 +1,148 @@
+package downloader
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	igerrors "igscraper/pkg/errors"
+	"igscraper/pkg/instagram"
+	"igscraper/pkg/ratelimit"
+)
+
+func TestQueueOrder(t *testing.T) {
+	job := func(shortcode string, takenAt int64) DownloadJob {
+		return DownloadJob{Shortcode: shortcode, Node: &instagram.Node{Shortcode: shortcode, TakenAtTimestamp: takenAt}}
+	}
+
+	tests := []struct {
+		order Order
+		want  string
+	}{
+		{OrderNewest, "c b a none retry"},
+		{OrderOldest, "a b c none retry"},
+	}
+	for _, tt := range tests {
+		pool := NewSharedPool(1, nil)
+		pool.SetOrder(tt.order)
+		target, _ := pool.AddTarget("test", &MockClient{}, NewMockStorageManager(), ratelimit.NewTokenBucket(100, time.Second))
+
+		// Re-queued jobs wait behind the others
+		retry := job("retry", 100)
+		retry.Retries = 1
+		pool.mu.Lock()
+		target.enqueue(job("b", 200))
+		target.queue = append(target.queue, retry)
+		target.enqueue(DownloadJob{Shortcode: "none"})
+		target.enqueue(job("a", 100))
+		target.enqueue(job("c", 300))
+		var got []string
+		for _, queued := range target.queue {
+			got = append(got, queued.Shortcode)
+		}
+		pool.mu.Unlock()
+
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("Order %s: expected queue %q, got %q", tt.order, tt.want, strings.Join(got, " "))
+		}
+		if target.Stats().Queued != 4 {
+			t.Errorf("Order %s: expected 4 submitted jobs, got %d", tt.order, target.Stats().Queued)
+		}
+	}
+}
+
+// flakyClient fails the first downloads of each URL: "flaky" URLs fail
+// twice with a network error and "gone" URLs always fail with a 404
+type flakyClient struct {
+	mu       sync.Mutex
+	attempts map[string]int
+}
+
+func (c *flakyClient) DownloadPhoto(url string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.attempts[url]++
+	switch {
+	case strings.HasPrefix(url, "gone"):
+		return nil, &igerrors.Error{Type: igerrors.ErrorTypeNotFound, Message: "resource not found", Code: 404}
+	case strings.HasPrefix(url, "flaky") && c.attempts[url] <= 2:
+		return nil, &igerrors.Error{Type: igerrors.ErrorTypeNetwork, Message: "connection reset"}
+	}
+	return []byte("mock photo data"), nil
+}
+
+func TestWorkerPoolRequeue(t *testing.T) {
+	for _, attempts := range []int{0, 1, 2} {
+		client := &flakyClient{attempts: make(map[string]int)}
+		pool := NewWorkerPool(2, client, NewMockStorageManager(), ratelimit.NewTokenBucket(1000, time.Second), nil)
+		pool.SetRequeue(attempts)
+		pool.Start()
+
+		results := make(map[string]DownloadResult)
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for result := range pool.Results() {
+				if _, ok := results[result.Job.Shortcode]; ok {
+					t.Errorf("Got a second result for %s", result.Job.Shortcode)
+				}
+				results[result.Job.Shortcode] = result
+			}
+		}()
+
+		for _, url := range []string{"ok", "flaky", "gone"} {
+			if err := pool.Submit(DownloadJob{URL: url, Shortcode: url}); err != nil {
+				t.Fatalf("Failed to submit %s: %v", url, err)
+			}
+		}
+		pool.Stop()
+		wg.Wait()
+
+		if len(results) != 3 {
+			t.Fatalf("Requeue %d: expected 3 results, got %d", attempts, len(results))
+		}
+		if !results["ok"].Success {
+			t.Errorf("Requeue %d: expected ok to succeed", attempts)
+		}
+
+		// The flaky download succeeds on its third attempt
+		flaky := results["flaky"]
+		if flaky.Success != (attempts == 2) || flaky.Job.Retries != attempts {
+			t.Errorf("Requeue %d: expected flaky to succeed only with 2 retries, got success %v after %d retries", attempts, flaky.Success, flaky.Job.Retries)
+		}
+		if client.attempts["flaky"] != attempts+1 {
+			t.Errorf("Requeue %d: expected %d downloads of flaky, got %d", attempts, attempts+1, client.attempts["flaky"])
+		}
+
+		// A deleted post is not retried
+		if results["gone"].Success || client.attempts["gone"] != 1 {
+			t.Errorf("Requeue %d: expected gone to fail once, got %d downloads", attempts, client.attempts["gone"])
+		}
+		if requeued := pool.single.Stats().Requeued; requeued != attempts {
+			t.Errorf("Requeue %d: expected %d re-queued jobs, got %d", attempts, attempts, requeued)
+		}
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{fmt.Errorf("save failed: disk full"), true},
+		{fmt.Errorf("download failed: %w", &igerrors.Error{Type: igerrors.ErrorTypeRateLimit, Code: 429}), true},
+		{fmt.Errorf("download failed: %w", &igerrors.Error{Type: igerrors.ErrorTypeServerError, Code: 503}), true},
+		{fmt.Errorf("download failed: %w", &igerrors.Error{Type: igerrors.ErrorTypeNotFound, Code: 404}), false},
+		{fmt.Errorf("download failed: %w", &igerrors.Error{Type: igerrors.ErrorTypeUnknown, Code: 403}), false},
+		{ErrCancelled, false},
+		{fmt.Errorf("%w after 5m0s", ErrTimedOut), true},
+	}
+	for _, tt := range tests {
+		if got := retryable(tt.err); got != tt.want {
+			t.Errorf("retryable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
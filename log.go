@@ -0,0 +1,88 @@
+package igscraper
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+
+	"github.com/rs/zerolog"
+
+	"igscraper/pkg/logger"
+)
+
+// slogLogger is a logger.Logger writing to a slog.Logger, so the scraper's
+// components log to the program using the package
+type slogLogger struct {
+	log *slog.Logger
+	ctx context.Context
+}
+
+// newLogger returns the logger.Logger writing to log, or one dropping every
+// line if log is nil
+func newLogger(log *slog.Logger) logger.Logger {
+	if log == nil {
+		return logger.NewNopLogger()
+	}
+	return &slogLogger{log: log, ctx: context.Background()}
+}
+
+func (l *slogLogger) with(args ...any) logger.Logger {
+	return &slogLogger{log: l.log.With(args...), ctx: l.ctx}
+}
+
+// attrs returns fields as slog arguments, in the order of their keys
+func attrs(fields map[string]interface{}) []any {
+	args := make([]any, 0, len(fields))
+	for _, key := range slices.Sorted(maps.Keys(fields)) {
+		args = append(args, slog.Any(key, fields[key]))
+	}
+	return args
+}
+
+func (l *slogLogger) Debug(msg string) { l.log.DebugContext(l.ctx, msg) }
+func (l *slogLogger) Info(msg string)  { l.log.InfoContext(l.ctx, msg) }
+func (l *slogLogger) Warn(msg string)  { l.log.WarnContext(l.ctx, msg) }
+func (l *slogLogger) Error(msg string) { l.log.ErrorContext(l.ctx, msg) }
+
+// Fatal logs msg as an error: a library does not end the program
+func (l *slogLogger) Fatal(msg string) { l.log.ErrorContext(l.ctx, msg) }
+
+func (l *slogLogger) WithField(key string, value interface{}) logger.Logger {
+	return l.with(slog.Any(key, value))
+}
+
+func (l *slogLogger) WithFields(fields map[string]interface{}) logger.Logger {
+	return l.with(attrs(fields)...)
+}
+
+func (l *slogLogger) WithError(err error) logger.Logger {
+	return l.with(slog.Any("error", err))
+}
+
+func (l *slogLogger) WithContext(ctx context.Context) logger.Logger {
+	return &slogLogger{log: l.log, ctx: ctx}
+}
+
+func (l *slogLogger) DebugWithFields(msg string, fields map[string]interface{}) {
+	l.log.DebugContext(l.ctx, msg, attrs(fields)...)
+}
+
+func (l *slogLogger) InfoWithFields(msg string, fields map[string]interface{}) {
+	l.log.InfoContext(l.ctx, msg, attrs(fields)...)
+}
+
+func (l *slogLogger) WarnWithFields(msg string, fields map[string]interface{}) {
+	l.log.WarnContext(l.ctx, msg, attrs(fields)...)
+}
+
+func (l *slogLogger) ErrorWithFields(msg string, fields map[string]interface{}) {
+	l.log.ErrorContext(l.ctx, msg, attrs(fields)...)
+}
+
+func (l *slogLogger) FatalWithFields(msg string, fields map[string]interface{}) {
+	l.log.ErrorContext(l.ctx, msg, attrs(fields)...)
+}
+
+// GetZerolog returns nil: there is no zerolog logger underneath
+func (l *slogLogger) GetZerolog() *zerolog.Logger { return nil }
@@ -0,0 +1,104 @@
+package igscraper
+
+import (
+	"log/slog"
+
+	"igscraper/pkg/config"
+	"igscraper/pkg/igclient"
+)
+
+// Option configures a Scraper made by New
+type Option func(*options)
+
+// options collects the options given to New
+type options struct {
+	config   *config.Config
+	edits    []func(*config.Config)
+	client   igclient.InstagramClient
+	logger   *slog.Logger
+	progress Progress
+	restart  bool
+}
+
+// WithConfig starts from cfg rather than the default settings, for settings
+// no option covers. The other options change the settings of cfg whatever
+// their order; cfg itself is not modified.
+func WithConfig(cfg *config.Config) Option {
+	return func(o *options) {
+		o.config = cfg
+	}
+}
+
+// WithSession sets the Instagram session cookies to scrape with. A session
+// is required.
+func WithSession(sessionID, csrfToken string) Option {
+	return setting(func(cfg *config.Config) {
+		cfg.Instagram.SessionID = sessionID
+		cfg.Instagram.CSRFToken = csrfToken
+	})
+}
+
+// WithOutputDir sets the directory profiles are saved in, each in a folder
+// of its own. The default is ./downloads.
+func WithOutputDir(dir string) Option {
+	return setting(func(cfg *config.Config) {
+		cfg.Output.BaseDirectory = dir
+	})
+}
+
+// WithConcurrency sets how many files are downloaded at once. The default
+// is 3.
+func WithConcurrency(downloads int) Option {
+	return setting(func(cfg *config.Config) {
+		cfg.Download.ConcurrentDownloads = downloads
+	})
+}
+
+// WithRateLimit sets how many requests a minute are made to Instagram's API.
+// The default is 60.
+func WithRateLimit(requestsPerMinute int) Option {
+	return setting(func(cfg *config.Config) {
+		cfg.RateLimit.RequestsPerMinute = requestsPerMinute
+	})
+}
+
+// WithClient makes the Scraper talk to Instagram through client instead of
+// the HTTP client made from the session, such as to go through a proxy of
+// your own or to serve canned responses in tests.
+func WithClient(client igclient.InstagramClient) Option {
+	return func(o *options) {
+		o.client = client
+	}
+}
+
+// WithLogger sends the Scraper's log lines to log, each with a component
+// attribute naming the part of igscraper it comes from. Without it log lines
+// are dropped.
+func WithLogger(log *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = log
+	}
+}
+
+// WithProgress reports the progress of scrapes to progress
+func WithProgress(progress Progress) Option {
+	return func(o *options) {
+		o.progress = progress
+	}
+}
+
+// WithRestart makes ScrapeUser start every profile from the first page. By
+// default a profile whose last scrape stopped early carries on from where it
+// stopped.
+func WithRestart() Option {
+	return func(o *options) {
+		o.restart = true
+	}
+}
+
+// setting returns an Option changing the settings with edit
+func setting(edit func(*config.Config)) Option {
+	return func(o *options) {
+		o.edits = append(o.edits, edit)
+	}
+}
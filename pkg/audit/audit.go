@@ -0,0 +1,175 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Entry is one outbound request in the audit log. It deliberately holds no
+// URL path, query, headers, cookies or bodies.
+type Entry struct {
+	Time          time.Time `json:"time"`
+	Method        string    `json:"method"`
+	Host          string    `json:"host"`
+	Category      string    `json:"category"`
+	Status        int       `json:"status,omitempty"`
+	BytesSent     int64     `json:"bytes_sent"`
+	BytesReceived int64     `json:"bytes_received"`
+	DurationMs    int64     `json:"duration_ms"`
+	Error         string    `json:"error,omitempty"`
+}
+
+// Log appends entries to a JSON-lines file
+type Log struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// Open opens the audit log at path for appending, creating it if needed. The
+// file is only readable by the current user.
+func Open(path string) (*Log, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return &Log{file: file}, nil
+}
+
+// Record appends an entry. Each entry is written with a single write, so the
+// file stays valid JSON lines even if the process is killed.
+func (l *Log) Record(entry Entry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %w", err)
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.file.Write(line); err != nil {
+		return fmt.Errorf("failed to write audit entry: %w", err)
+	}
+	return nil
+}
+
+// Close closes the audit log file
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// globalLog is the audit log used by every transport from Transport
+var globalLog atomic.Pointer[Log]
+
+// Initialize opens the global audit log at path. An empty path disables
+// auditing. Any previously opened global log is closed.
+func Initialize(path string) error {
+	var log *Log
+	if path != "" {
+		var err error
+		log, err = Open(path)
+		if err != nil {
+			return err
+		}
+	}
+	if old := globalLog.Swap(log); old != nil {
+		old.Close()
+	}
+	return nil
+}
+
+// Enabled reports whether a global audit log is open
+func Enabled() bool {
+	return globalLog.Load() != nil
+}
+
+// Transport wraps next so every request it sends is recorded in the global
+// audit log, labelled by category. A nil next uses http.DefaultTransport.
+// Requests pass through untouched while auditing is disabled.
+func Transport(next http.RoundTripper, category func(*http.Request) string) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &transport{next: next, category: category}
+}
+
+type transport struct {
+	next     http.RoundTripper
+	category func(*http.Request) string
+}
+
+// RoundTrip sends the request and records it once the response body has been
+// read or closed
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	log := globalLog.Load()
+	if log == nil {
+		return t.next.RoundTrip(req)
+	}
+
+	entry := Entry{
+		Time:      time.Now().UTC(),
+		Method:    req.Method,
+		Host:      req.URL.Hostname(),
+		Category:  "other",
+		BytesSent: max(req.ContentLength, 0),
+	}
+	if t.category != nil {
+		entry.Category = t.category(req)
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		entry.DurationMs = time.Since(start).Milliseconds()
+		entry.Error = err.Error()
+		log.Record(entry)
+		return nil, err
+	}
+
+	entry.Status = resp.StatusCode
+	resp.Body = &countingBody{body: resp.Body, log: log, entry: entry, start: start}
+	return resp, nil
+}
+
+// countingBody counts the bytes read from a response body and records the
+// entry at EOF or close, whichever comes first
+type countingBody struct {
+	body  io.ReadCloser
+	log   *Log
+	entry Entry
+	start time.Time
+	once  sync.Once
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	b.entry.BytesReceived += int64(n)
+	if err == io.EOF {
+		b.record()
+	}
+	return n, err
+}
+
+func (b *countingBody) Close() error {
+	err := b.body.Close()
+	b.record()
+	return err
+}
+
+func (b *countingBody) record() {
+	b.once.Do(func() {
+		b.entry.DurationMs = time.Since(b.start).Milliseconds()
+		b.log.Record(b.entry)
+	})
+}
@@ -0,0 +1,142 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// roundTripFunc adapts a function to http.RoundTripper
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func readEntries(t *testing.T, path string) []Entry {
+	t.Helper()
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry Entry
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry), "line %q", scanner.Text())
+		entries = append(entries, entry)
+	}
+	require.NoError(t, scanner.Err())
+	return entries
+}
+
+func TestTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, "hello world")
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "logs", "audit.jsonl")
+	require.NoError(t, Initialize(path))
+	t.Cleanup(func() { Initialize("") })
+	assert.True(t, Enabled())
+
+	category := func(req *http.Request) string {
+		return "test:" + strings.TrimPrefix(req.URL.Path, "/")
+	}
+	client := &http.Client{Transport: Transport(nil, category)}
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/found?secret=token", nil)
+	require.NoError(t, err)
+	req.Header.Set("Cookie", "sessionid=very-secret")
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "hello world", string(body))
+
+	// Closed without reading
+	resp, err = client.Get(server.URL + "/missing")
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	entries := readEntries(t, path)
+	require.Len(t, entries, 2)
+
+	assert.Equal(t, http.MethodGet, entries[0].Method)
+	assert.Equal(t, "127.0.0.1", entries[0].Host)
+	assert.Equal(t, "test:found", entries[0].Category)
+	assert.Equal(t, http.StatusOK, entries[0].Status)
+	assert.Equal(t, int64(len("hello world")), entries[0].BytesReceived)
+	assert.Zero(t, entries[0].BytesSent)
+	assert.False(t, entries[0].Time.IsZero())
+
+	assert.Equal(t, "test:missing", entries[1].Category)
+	assert.Equal(t, http.StatusNotFound, entries[1].Status)
+
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+	for _, secret := range []string{"very-secret", "token", "hello world"} {
+		assert.NotContains(t, string(raw), secret)
+	}
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestTransportError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	require.NoError(t, Initialize(path))
+	t.Cleanup(func() { Initialize("") })
+
+	failing := roundTripFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	})
+	client := &http.Client{Transport: Transport(failing, nil)}
+
+	_, err := client.Post("https://www.example.com/upload", "text/plain", strings.NewReader("12345"))
+	require.Error(t, err)
+
+	entries := readEntries(t, path)
+	require.Len(t, entries, 1)
+	assert.Equal(t, http.MethodPost, entries[0].Method)
+	assert.Equal(t, "www.example.com", entries[0].Host)
+	assert.Equal(t, "other", entries[0].Category)
+	assert.Equal(t, int64(5), entries[0].BytesSent)
+	assert.Zero(t, entries[0].Status)
+	assert.Equal(t, "connection refused", entries[0].Error)
+}
+
+func TestTransportDisabled(t *testing.T) {
+	require.NoError(t, Initialize(""))
+	assert.False(t, Enabled())
+
+	called := false
+	next := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		called = true
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("ok"))}, nil
+	})
+
+	req, err := http.NewRequest(http.MethodGet, "https://www.example.com/", nil)
+	require.NoError(t, err)
+	resp, err := Transport(next, nil).RoundTrip(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.True(t, called)
+}
@@ -0,0 +1,21 @@
+// Package audit records every outbound HTTP request the scraper makes, so
+// users can verify exactly what the tool sends and where.
+//
+// The audit log is a separate file of JSON lines, one per request:
+//
+//	{"time":"2024-03-15T18:30:00Z","method":"GET","host":"www.instagram.com","category":"profile","status":200,"bytes_sent":0,"bytes_received":5120,"duration_ms":412}
+//
+// Entries name the host and an endpoint category, never the full URL, and
+// contain no headers, cookies or bodies. Retries and redirects are recorded
+// as separate requests.
+//
+// Usage:
+//
+//	if err := audit.Initialize("/var/log/igscraper-audit.jsonl"); err != nil {
+//	    log.Fatal(err)
+//	}
+//
+//	client := &http.Client{
+//	    Transport: audit.Transport(nil, instagram.EndpointCategory),
+//	}
+package audit
@@ -0,0 +1,276 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Browser identifies a browser whose cookie store can be imported
+type Browser string
+
+const (
+	BrowserChrome  Browser = "chrome"
+	BrowserFirefox Browser = "firefox"
+	BrowserSafari  Browser = "safari"
+)
+
+// Browsers lists the supported browsers in the order they are offered
+var Browsers = []Browser{BrowserChrome, BrowserFirefox, BrowserSafari}
+
+// String returns the browser's display name
+func (b Browser) String() string {
+	switch b {
+	case BrowserChrome:
+		return "Chrome"
+	case BrowserFirefox:
+		return "Firefox"
+	case BrowserSafari:
+		return "Safari"
+	}
+	return string(b)
+}
+
+// Browser import errors
+var (
+	ErrBrowserNotFound    = errors.New("browser cookie store not found")
+	ErrBrowserUnsupported = errors.New("browser not supported on this platform")
+	ErrNoInstagramSession = errors.New("no Instagram session found; log in at instagram.com in this browser first")
+)
+
+// BrowserCookies are the Instagram session cookies read from a browser
+type BrowserCookies struct {
+	Browser   Browser
+	Store     string // path of the cookie store they were read from
+	SessionID string
+	CSRFToken string
+	UserID    string    // ds_user_id, the numeric ID of the logged-in account
+	Expires   time.Time // expiry of the sessionid cookie, zero if unknown
+}
+
+// Account returns the cookies as credentials for username
+func (c *BrowserCookies) Account(username string) *Account {
+	return &Account{
+		Username:       username,
+		SessionID:      c.SessionID,
+		CSRFToken:      c.CSRFToken,
+		SessionExpires: c.Expires,
+		LastModified:   time.Now(),
+	}
+}
+
+// browserCookie is one cookie read from a browser's store
+type browserCookie struct {
+	Host    string
+	Name    string
+	Value   string
+	Expires time.Time
+	Jar     string // separates cookie jars within one store, e.g. Firefox containers
+}
+
+// browserEnv describes where browsers keep their profiles
+type browserEnv struct {
+	goos      string
+	home      string
+	configDir string // XDG config directory on Linux
+	appData   string // %APPDATA% on Windows
+	localData string // %LOCALAPPDATA% on Windows
+}
+
+// currentBrowserEnv returns the environment of the running process
+func currentBrowserEnv() browserEnv {
+	home, _ := os.UserHomeDir()
+	configDir := os.Getenv("XDG_CONFIG_HOME")
+	if configDir == "" && home != "" {
+		configDir = filepath.Join(home, ".config")
+	}
+	return browserEnv{
+		goos:      runtime.GOOS,
+		home:      home,
+		configDir: configDir,
+		appData:   os.Getenv("APPDATA"),
+		localData: os.Getenv("LOCALAPPDATA"),
+	}
+}
+
+// DetectBrowsers returns the supported browsers that have a cookie store on
+// this machine. Nothing is read from the stores.
+func DetectBrowsers() []Browser {
+	env := currentBrowserEnv()
+	var found []Browser
+	for _, browser := range Browsers {
+		if len(env.cookieStores(browser)) > 0 {
+			found = append(found, browser)
+		}
+	}
+	return found
+}
+
+// ReadBrowserCookies reads the Instagram session cookies from a browser's
+// cookie store. Every profile of the browser is searched, most recently used
+// first. Reading Chrome's cookies may make the operating system ask for
+// permission to use the key Chrome encrypts them with.
+func ReadBrowserCookies(browser Browser) (*BrowserCookies, error) {
+	return currentBrowserEnv().readCookies(browser)
+}
+
+// readCookies reads the Instagram session from the first store of browser
+// that has one
+func (env browserEnv) readCookies(browser Browser) (*BrowserCookies, error) {
+	stores := env.cookieStores(browser)
+	if len(stores) == 0 {
+		if browser == BrowserSafari && env.goos != "darwin" {
+			return nil, ErrBrowserUnsupported
+		}
+		return nil, ErrBrowserNotFound
+	}
+
+	var firstErr error
+	for _, store := range stores {
+		var cookies []browserCookie
+		var err error
+		switch browser {
+		case BrowserChrome:
+			cookies, err = readChromeCookies(store, env.goos)
+		case BrowserFirefox:
+			cookies, err = readFirefoxCookies(store)
+		case BrowserSafari:
+			cookies, err = readSafariCookies(store)
+		default:
+			return nil, fmt.Errorf("unknown browser %q", browser)
+		}
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to read %s: %w", store, err)
+			}
+			continue
+		}
+
+		if session := instagramSession(cookies); session != nil {
+			session.Browser = browser
+			session.Store = store
+			return session, nil
+		}
+	}
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return nil, ErrNoInstagramSession
+}
+
+// cookieStores returns the cookie store files of browser, most recently
+// modified first
+func (env browserEnv) cookieStores(browser Browser) []string {
+	var patterns []string
+	switch browser {
+	case BrowserChrome:
+		var userData string
+		switch env.goos {
+		case "darwin":
+			userData = filepath.Join(env.home, "Library", "Application Support", "Google", "Chrome")
+		case "windows":
+			userData = filepath.Join(env.localData, "Google", "Chrome", "User Data")
+		default:
+			userData = filepath.Join(env.configDir, "google-chrome")
+		}
+		patterns = []string{
+			filepath.Join(userData, "*", "Network", "Cookies"),
+			filepath.Join(userData, "*", "Cookies"),
+		}
+	case BrowserFirefox:
+		switch env.goos {
+		case "darwin":
+			patterns = []string{filepath.Join(env.home, "Library", "Application Support", "Firefox", "Profiles", "*", "cookies.sqlite")}
+		case "windows":
+			patterns = []string{filepath.Join(env.appData, "Mozilla", "Firefox", "Profiles", "*", "cookies.sqlite")}
+		default:
+			patterns = []string{
+				filepath.Join(env.home, ".mozilla", "firefox", "*", "cookies.sqlite"),
+				filepath.Join(env.home, "snap", "firefox", "common", ".mozilla", "firefox", "*", "cookies.sqlite"),
+			}
+		}
+	case BrowserSafari:
+		if env.goos == "darwin" {
+			patterns = []string{
+				filepath.Join(env.home, "Library", "Containers", "com.apple.Safari", "Data", "Library", "Cookies", "Cookies.binarycookies"),
+				filepath.Join(env.home, "Library", "Cookies", "Cookies.binarycookies"),
+			}
+		}
+	}
+
+	type store struct {
+		path     string
+		modified time.Time
+	}
+	var stores []store
+	for _, pattern := range patterns {
+		matches := []string{pattern}
+		if strings.ContainsAny(pattern, "*?[") {
+			matches, _ = filepath.Glob(pattern)
+		}
+		for _, path := range matches {
+			// Keep stores the process may not open, such as Safari's
+			// protected container, so reading them fails with a clear error
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				stores = append(stores, store{path: path, modified: info.ModTime()})
+			} else if os.IsPermission(err) {
+				stores = append(stores, store{path: path})
+			}
+		}
+	}
+	sort.SliceStable(stores, func(i, j int) bool {
+		return stores[i].modified.After(stores[j].modified)
+	})
+
+	paths := make([]string, len(stores))
+	for i, s := range stores {
+		paths[i] = s.path
+	}
+	return paths
+}
+
+// instagramSession picks the Instagram session cookies. When a store holds
+// several sessions (for example in Firefox containers), the one whose
+// sessionid expires last wins, together with the cookies from its jar.
+func instagramSession(cookies []browserCookie) *BrowserCookies {
+	var session *browserCookie
+	for i, c := range cookies {
+		if c.Name == "sessionid" && c.Value != "" && isInstagramHost(c.Host) {
+			if session == nil || c.Expires.After(session.Expires) {
+				session = &cookies[i]
+			}
+		}
+	}
+	if session == nil {
+		return nil
+	}
+
+	result := &BrowserCookies{SessionID: session.Value, Expires: session.Expires}
+	for _, c := range cookies {
+		if c.Jar != session.Jar || !isInstagramHost(c.Host) || c.Value == "" {
+			continue
+		}
+		switch c.Name {
+		case "csrftoken":
+			result.CSRFToken = c.Value
+		case "ds_user_id":
+			result.UserID = c.Value
+		}
+	}
+	if result.CSRFToken == "" {
+		return nil
+	}
+	return result
+}
+
+// isInstagramHost reports whether a cookie host belongs to instagram.com
+func isInstagramHost(host string) bool {
+	host = strings.TrimPrefix(host, ".")
+	return host == "instagram.com" || strings.HasSuffix(host, ".instagram.com")
+}
@@ -0,0 +1,188 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha1"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/zalando/go-keyring"
+	ss "github.com/zalando/go-keyring/secret_service"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Chrome's os_crypt parameters on macOS and Linux
+const (
+	chromeSalt          = "saltysalt"
+	chromeKeychainName  = "Chrome Safe Storage"
+	chromeLinuxPassword = "peanuts" // used for "v10" values on Linux
+)
+
+// chromeEpochOffset is the number of seconds between 1601-01-01, the start of
+// Chrome's timestamps, and the Unix epoch
+const chromeEpochOffset = 11644473600
+
+// readChromeCookies reads the instagram.com cookies from a Chrome cookie
+// database, decrypting their values with the platform's os_crypt key
+func readChromeCookies(path, goos string) ([]browserCookie, error) {
+	db, err := openSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+	columns, rows, err := db.table("cookies")
+	if err != nil {
+		return nil, err
+	}
+	col := columnIndex(columns)
+	for _, name := range []string{"host_key", "name", "value", "encrypted_value", "expires_utc"} {
+		if _, ok := col[name]; !ok {
+			return nil, fmt.Errorf("unexpected Chrome cookie database: no %s column", name)
+		}
+	}
+
+	decrypter := &chromeDecrypter{goos: goos, keys: make(map[string][]byte)}
+	var cookies []browserCookie
+	for _, row := range rows {
+		host, _ := row[col["host_key"]].(string)
+		if !isInstagramHost(host) {
+			continue
+		}
+		name, _ := row[col["name"]].(string)
+		value, _ := row[col["value"]].(string)
+		if encrypted, _ := row[col["encrypted_value"]].([]byte); value == "" && len(encrypted) > 0 {
+			if value, err = decrypter.decrypt(encrypted, host); err != nil {
+				return nil, fmt.Errorf("failed to decrypt cookie %s: %w", name, err)
+			}
+		}
+
+		cookie := browserCookie{Host: host, Name: name, Value: value}
+		if expires, _ := row[col["expires_utc"]].(int64); expires > 0 {
+			cookie.Expires = time.Unix(expires/1e6-chromeEpochOffset, expires%1e6*1e3)
+		}
+		cookies = append(cookies, cookie)
+	}
+	return cookies, nil
+}
+
+// chromeDecrypter decrypts Chrome cookie values, deriving each os_crypt key
+// at most once
+type chromeDecrypter struct {
+	goos string
+	keys map[string][]byte
+}
+
+// decrypt decrypts a value prefixed with its os_crypt version ("v10", "v11")
+func (d *chromeDecrypter) decrypt(encrypted []byte, host string) (string, error) {
+	if len(encrypted) < 3 {
+		return "", errors.New("value too short")
+	}
+	version := string(encrypted[:3])
+	key, ok := d.keys[version]
+	if !ok {
+		var err error
+		if key, err = chromeKey(version, d.goos); err != nil {
+			return "", err
+		}
+		d.keys[version] = key
+	}
+	return decryptChromeValue(encrypted[3:], key, host)
+}
+
+// chromeKey derives the AES key Chrome uses for values of the given version
+func chromeKey(version, goos string) ([]byte, error) {
+	var password string
+	iterations := 1
+	switch {
+	case goos == "darwin" && version == "v10":
+		secret, err := keyring.Get(chromeKeychainName, "Chrome")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %q from the keychain: %w", chromeKeychainName, err)
+		}
+		password, iterations = secret, 1003
+	case goos == "linux" && version == "v10":
+		password = chromeLinuxPassword
+	case goos == "linux" && version == "v11":
+		secret, err := chromeSecretServicePassword()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the Chrome key from the Secret Service: %w", err)
+		}
+		password = secret
+	case goos == "windows":
+		return nil, fmt.Errorf("%w: Chrome cookies cannot be decrypted on Windows", ErrBrowserUnsupported)
+	default:
+		return nil, fmt.Errorf("%w: unknown Chrome encryption %q", ErrBrowserUnsupported, version)
+	}
+	return pbkdf2.Key([]byte(password), []byte(chromeSalt), iterations, 16, sha1.New), nil
+}
+
+// chromeSecretServicePassword reads the password Chrome stores in the
+// Secret Service (GNOME Keyring, KWallet's Secret Service bridge) on Linux
+func chromeSecretServicePassword() (string, error) {
+	svc, err := ss.NewSecretService()
+	if err != nil {
+		return "", err
+	}
+	session, err := svc.OpenSession()
+	if err != nil {
+		return "", err
+	}
+	defer svc.Close(session)
+
+	collection := svc.GetLoginCollection()
+	if err := svc.Unlock(collection.Path()); err != nil {
+		return "", err
+	}
+	items, err := svc.SearchItems(collection, map[string]string{"application": "chrome"})
+	if err != nil {
+		return "", err
+	}
+	if len(items) == 0 {
+		return "", errors.New("Chrome Safe Storage not found")
+	}
+	secret, err := svc.GetSecret(items[0], session.Path())
+	if err != nil {
+		return "", err
+	}
+	return string(secret.Value), nil
+}
+
+// decryptChromeValue decrypts an AES-128-CBC cookie value. Recent Chrome
+// versions prefix the plaintext with the SHA-256 of the cookie's host, which
+// is removed.
+func decryptChromeValue(ciphertext, key []byte, host string) (string, error) {
+	if len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 {
+		return "", errors.New("invalid ciphertext length")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	plaintext := make([]byte, len(ciphertext))
+	iv := bytes.Repeat([]byte{' '}, aes.BlockSize)
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext)
+
+	padding := int(plaintext[len(plaintext)-1])
+	if padding == 0 || padding > aes.BlockSize || padding > len(plaintext) {
+		return "", errors.New("wrong key or corrupt value")
+	}
+	plaintext = plaintext[:len(plaintext)-padding]
+
+	hostHash := sha256.Sum256([]byte(host))
+	if bytes.HasPrefix(plaintext, hostHash[:]) {
+		plaintext = plaintext[len(hostHash):]
+	}
+	return string(plaintext), nil
+}
+
+// columnIndex maps column names to their position
+func columnIndex(columns []string) map[string]int {
+	index := make(map[string]int, len(columns))
+	for i, name := range columns {
+		index[name] = i
+	}
+	return index
+}
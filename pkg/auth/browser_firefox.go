@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"fmt"
+	"time"
+)
+
+// readFirefoxCookies reads the instagram.com cookies from a Firefox profile's
+// cookies.sqlite. Firefox stores values unencrypted.
+func readFirefoxCookies(path string) ([]browserCookie, error) {
+	db, err := openSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+	columns, rows, err := db.table("moz_cookies")
+	if err != nil {
+		return nil, err
+	}
+	col := columnIndex(columns)
+	for _, name := range []string{"host", "name", "value", "expiry"} {
+		if _, ok := col[name]; !ok {
+			return nil, fmt.Errorf("unexpected Firefox cookie database: no %s column", name)
+		}
+	}
+	jarColumn, hasJars := col["originAttributes"]
+
+	var cookies []browserCookie
+	for _, row := range rows {
+		host, _ := row[col["host"]].(string)
+		if !isInstagramHost(host) {
+			continue
+		}
+		cookie := browserCookie{Host: host}
+		cookie.Name, _ = row[col["name"]].(string)
+		cookie.Value, _ = row[col["value"]].(string)
+		if hasJars {
+			cookie.Jar, _ = row[jarColumn].(string)
+		}
+
+		// Older versions store the expiry in seconds, newer ones in milliseconds
+		if expiry, _ := row[col["expiry"]].(int64); expiry > 1e11 {
+			cookie.Expires = time.UnixMilli(expiry)
+		} else if expiry > 0 {
+			cookie.Expires = time.Unix(expiry, 0)
+		}
+		cookies = append(cookies, cookie)
+	}
+	return cookies, nil
+}
@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"time"
+)
+
+// safariEpoch is the start of Safari's timestamps, 2001-01-01 UTC
+var safariEpoch = time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// errBinaryCookiesCorrupt is returned for malformed Cookies.binarycookies files
+var errBinaryCookiesCorrupt = errors.New("malformed binarycookies file")
+
+// readSafariCookies reads the instagram.com cookies from Safari's
+// Cookies.binarycookies. The file lives in Safari's sandbox container, so
+// the terminal needs Full Disk Access to read it.
+func readSafariCookies(path string) ([]browserCookie, error) {
+	data, err := os.ReadFile(path)
+	if os.IsPermission(err) {
+		return nil, fmt.Errorf("%w (grant your terminal Full Disk Access in System Settings → Privacy & Security)", err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	all, err := parseBinaryCookies(data)
+	if err != nil {
+		return nil, err
+	}
+	var cookies []browserCookie
+	for _, c := range all {
+		if isInstagramHost(c.Host) {
+			cookies = append(cookies, c)
+		}
+	}
+	return cookies, nil
+}
+
+// parseBinaryCookies decodes Safari's binary cookie format: a big-endian
+// header listing page sizes, followed by little-endian pages of cookie records.
+func parseBinaryCookies(data []byte) ([]browserCookie, error) {
+	if len(data) < 8 || !bytes.Equal(data[:4], []byte("cook")) {
+		return nil, errors.New("not a Safari binarycookies file")
+	}
+	pageCount := int(binary.BigEndian.Uint32(data[4:8]))
+	offset := 8 + pageCount*4
+	if pageCount < 0 || offset > len(data) {
+		return nil, errBinaryCookiesCorrupt
+	}
+
+	var cookies []browserCookie
+	for i := 0; i < pageCount; i++ {
+		size := int(binary.BigEndian.Uint32(data[8+i*4:]))
+		if size < 8 || offset+size > len(data) {
+			return nil, errBinaryCookiesCorrupt
+		}
+		page := data[offset : offset+size]
+		offset += size
+
+		count := int(binary.LittleEndian.Uint32(page[4:8]))
+		if 8+count*4 > len(page) {
+			return nil, errBinaryCookiesCorrupt
+		}
+		for j := 0; j < count; j++ {
+			start := int(binary.LittleEndian.Uint32(page[8+j*4:]))
+			cookie, err := parseBinaryCookie(page, start)
+			if err != nil {
+				return nil, err
+			}
+			cookies = append(cookies, cookie)
+		}
+	}
+	return cookies, nil
+}
+
+// parseBinaryCookie decodes the cookie record starting at start in page
+func parseBinaryCookie(page []byte, start int) (browserCookie, error) {
+	if start < 0 || start+56 > len(page) {
+		return browserCookie{}, errBinaryCookiesCorrupt
+	}
+	size := int(binary.LittleEndian.Uint32(page[start:]))
+	if size < 56 || start+size > len(page) {
+		return browserCookie{}, errBinaryCookiesCorrupt
+	}
+	record := page[start : start+size]
+
+	field := func(at int) string {
+		offset := int(binary.LittleEndian.Uint32(record[at:]))
+		if offset <= 0 || offset >= len(record) {
+			return ""
+		}
+		value := record[offset:]
+		if end := bytes.IndexByte(value, 0); end >= 0 {
+			value = value[:end]
+		}
+		return string(value)
+	}
+
+	cookie := browserCookie{
+		Host:  field(16),
+		Name:  field(20),
+		Value: field(28),
+	}
+	expiry := math.Float64frombits(binary.LittleEndian.Uint64(record[40:48]))
+	if expiry > 0 {
+		cookie.Expires = safariEpoch.Add(time.Duration(expiry * float64(time.Second)))
+	}
+	return cookie, nil
+}
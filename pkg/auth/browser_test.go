@@ -0,0 +1,235 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// copyFixture copies testdata files into dir, creating it
+func copyFixture(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for src, dst := range files {
+		data, err := os.ReadFile(filepath.Join("testdata", src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, dst), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSQLiteReader(t *testing.T) {
+	db, err := openSQLite(filepath.Join("testdata", "chrome_Cookies"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	columns, rows, err := db.table("cookies")
+	if err != nil {
+		t.Fatalf("Failed to read table: %v", err)
+	}
+
+	// 300 filler rows span several pages, so the b-tree has interior pages
+	if len(rows) != 304 {
+		t.Errorf("Expected 304 rows, got %d", len(rows))
+	}
+	col := columnIndex(columns)
+	if col["host_key"] != 1 || col["has_cross_site_ancestor"] != 19 {
+		t.Errorf("Unexpected columns: %v", columns)
+	}
+
+	// A 10 KB value does not fit on one page and is read from overflow pages
+	var big string
+	for _, row := range rows {
+		if row[col["host_key"]] == "big.example.com" {
+			big, _ = row[col["value"]].(string)
+		}
+	}
+	if big != strings.Repeat("y", 10000) {
+		t.Errorf("Overflow value has length %d, want 10000", len(big))
+	}
+
+	if _, _, err := db.table("missing"); err == nil {
+		t.Error("Expected error for a missing table")
+	}
+}
+
+func TestReadChromeCookies(t *testing.T) {
+	configDir := t.TempDir()
+	copyFixture(t, filepath.Join(configDir, "google-chrome", "Default"), map[string]string{"chrome_Cookies": "Cookies"})
+	env := browserEnv{goos: "linux", home: t.TempDir(), configDir: configDir}
+
+	stores := env.cookieStores(BrowserChrome)
+	if len(stores) != 1 {
+		t.Fatalf("Expected 1 Chrome cookie store, got %v", stores)
+	}
+
+	// sessionid and ds_user_id are encrypted with Linux's "v10" key and
+	// prefixed with the host hash; csrftoken is stored in plain text
+	cookies, err := env.readCookies(BrowserChrome)
+	if err != nil {
+		t.Fatalf("Failed to read Chrome cookies: %v", err)
+	}
+	if cookies.SessionID != "12345678%3AabcDEF%3A26%3AAYc" {
+		t.Errorf("SessionID = %q", cookies.SessionID)
+	}
+	if cookies.CSRFToken != "YTQHujAgMhyveLvvuwCfw9CPI8ROAHoy" {
+		t.Errorf("CSRFToken = %q", cookies.CSRFToken)
+	}
+	if cookies.UserID != "12345678" {
+		t.Errorf("UserID = %q", cookies.UserID)
+	}
+	if want := time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC); !cookies.Expires.Equal(want) {
+		t.Errorf("Expires = %v, want %v", cookies.Expires, want)
+	}
+	if cookies.Browser != BrowserChrome || cookies.Store != stores[0] {
+		t.Errorf("Unexpected source %s %s", cookies.Browser, cookies.Store)
+	}
+
+	account := cookies.Account("me")
+	if account.Username != "me" || account.SessionID != cookies.SessionID || !account.SessionExpires.Equal(cookies.Expires) {
+		t.Errorf("Unexpected account %+v", account)
+	}
+}
+
+func TestReadFirefoxCookies(t *testing.T) {
+	home := t.TempDir()
+	profile := filepath.Join(home, ".mozilla", "firefox", "abcd1234.default-release")
+	copyFixture(t, profile, map[string]string{
+		"firefox_cookies.sqlite":     "cookies.sqlite",
+		"firefox_cookies.sqlite-wal": "cookies.sqlite-wal",
+	})
+	env := browserEnv{goos: "linux", home: home}
+
+	// The Instagram cookies were written after the last checkpoint, so they
+	// are only in the WAL. The container session expires last and wins.
+	cookies, err := env.readCookies(BrowserFirefox)
+	if err != nil {
+		t.Fatalf("Failed to read Firefox cookies: %v", err)
+	}
+	if cookies.SessionID != "222%3Acontainer%3A1" || cookies.CSRFToken != "csrf-container" || cookies.UserID != "222" {
+		t.Errorf("Unexpected cookies %+v", cookies)
+	}
+	if want := time.Date(2027, 3, 1, 0, 0, 0, 0, time.UTC); !cookies.Expires.Equal(want) {
+		t.Errorf("Expires = %v, want %v", cookies.Expires, want)
+	}
+
+	// Without the WAL only the checkpointed rows remain
+	if err := os.Remove(filepath.Join(profile, "cookies.sqlite-wal")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.readCookies(BrowserFirefox); !errors.Is(err, ErrNoInstagramSession) {
+		t.Errorf("Expected ErrNoInstagramSession, got %v", err)
+	}
+}
+
+func TestReadBrowserCookiesNotFound(t *testing.T) {
+	env := browserEnv{goos: "linux", home: t.TempDir(), configDir: t.TempDir()}
+	for _, browser := range []Browser{BrowserChrome, BrowserFirefox} {
+		if _, err := env.readCookies(browser); !errors.Is(err, ErrBrowserNotFound) {
+			t.Errorf("%s: expected ErrBrowserNotFound, got %v", browser, err)
+		}
+	}
+	if _, err := env.readCookies(BrowserSafari); !errors.Is(err, ErrBrowserUnsupported) {
+		t.Errorf("Expected ErrBrowserUnsupported for Safari on Linux, got %v", err)
+	}
+}
+
+// binaryCookie encodes one Safari cookie record
+func binaryCookie(host, name, value string, expires time.Time) []byte {
+	strs := []string{host, name, "/", value}
+	header := make([]byte, 56)
+	offset := 56
+	var body []byte
+	for i, s := range strs {
+		binary.LittleEndian.PutUint32(header[16+i*4:], uint32(offset))
+		body = append(body, append([]byte(s), 0)...)
+		offset += len(s) + 1
+	}
+	binary.LittleEndian.PutUint32(header[0:], uint32(offset))
+	expiry := expires.Sub(safariEpoch).Seconds()
+	binary.LittleEndian.PutUint64(header[40:], math.Float64bits(expiry))
+	return append(header, body...)
+}
+
+func TestParseBinaryCookies(t *testing.T) {
+	expires := time.Date(2027, 6, 1, 0, 0, 0, 0, time.UTC)
+	records := [][]byte{
+		binaryCookie(".example.com", "id", "ignored", expires),
+		binaryCookie(".instagram.com", "sessionid", "333%3Asafari%3A1", expires),
+		binaryCookie(".instagram.com", "csrftoken", "csrf-safari", expires),
+	}
+
+	var page bytes.Buffer
+	page.Write([]byte{0, 0, 1, 0})
+	binary.Write(&page, binary.LittleEndian, uint32(len(records)))
+	offset := 8 + 4*len(records) + 4
+	for _, r := range records {
+		binary.Write(&page, binary.LittleEndian, uint32(offset))
+		offset += len(r)
+	}
+	page.Write([]byte{0, 0, 0, 0})
+	for _, r := range records {
+		page.Write(r)
+	}
+
+	var file bytes.Buffer
+	file.WriteString("cook")
+	binary.Write(&file, binary.BigEndian, uint32(1))
+	binary.Write(&file, binary.BigEndian, uint32(page.Len()))
+	file.Write(page.Bytes())
+
+	path := filepath.Join(t.TempDir(), "Cookies.binarycookies")
+	if err := os.WriteFile(path, file.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cookies, err := readSafariCookies(path)
+	if err != nil {
+		t.Fatalf("Failed to read Safari cookies: %v", err)
+	}
+	if len(cookies) != 2 {
+		t.Fatalf("Expected 2 Instagram cookies, got %d", len(cookies))
+	}
+
+	session := instagramSession(cookies)
+	if session == nil || session.SessionID != "333%3Asafari%3A1" || session.CSRFToken != "csrf-safari" {
+		t.Fatalf("Unexpected session %+v", session)
+	}
+	if !session.Expires.Equal(expires) {
+		t.Errorf("Expires = %v, want %v", session.Expires, expires)
+	}
+
+	if _, err := parseBinaryCookies([]byte("nope")); err == nil {
+		t.Error("Expected error for a file without the cookie magic")
+	}
+}
+
+func TestDecryptChromeValueWrongKey(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 16)
+	if _, err := decryptChromeValue(bytes.Repeat([]byte{2}, 32), key, ".instagram.com"); err == nil {
+		t.Error("Expected error for a value encrypted with another key")
+	}
+	if _, err := decryptChromeValue([]byte{1, 2, 3}, key, ".instagram.com"); err == nil {
+		t.Error("Expected error for a truncated value")
+	}
+}
+
+func TestInstagramSessionRequiresCSRFToken(t *testing.T) {
+	cookies := []browserCookie{
+		{Host: ".instagram.com", Name: "sessionid", Value: "abc"},
+		{Host: ".notinstagram.com", Name: "csrftoken", Value: "def"},
+	}
+	if session := instagramSession(cookies); session != nil {
+		t.Errorf("Expected no session without an Instagram csrftoken, got %+v", session)
+	}
+}
@@ -5,8 +5,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"runtime"
 	"time"
+
+	"igscraper/pkg/platformdirs"
 )
 
 // Account represents an Instagram account's credentials
@@ -16,6 +17,43 @@ type Account struct {
 	CSRFToken    string    `json:"csrf_token"`
 	UserAgent    string    `json:"user_agent,omitempty"`
 	LastModified time.Time `json:"last_modified"`
+
+	// SessionExpires is the sessionid cookie's own expiry, when known
+	SessionExpires time.Time `json:"session_expires,omitempty"`
+
+	// Device is the browser fingerprint the account presents, generated
+	// when it is first stored
+	Device *Device `json:"device,omitempty"`
+}
+
+// SessionLifetime is roughly how long Instagram keeps a web session valid
+// after its cookies were issued. Stored sessions are assumed to expire this
+// long after they were saved, or after the scraper last saved a sessionid
+// Instagram renewed.
+const SessionLifetime = 90 * 24 * time.Hour
+
+// SessionExpiryWarning is how long before the estimated expiry users are
+// warned to refresh their cookies
+const SessionExpiryWarning = 14 * 24 * time.Hour
+
+// ExpiresAt estimates when the account's session cookies stop working: the
+// cookie's own expiry if it is known and earlier than the estimate from the
+// save time. It returns the zero time when neither is known.
+func (a *Account) ExpiresAt() time.Time {
+	if !a.SessionExpires.IsZero() && (a.LastModified.IsZero() || a.SessionExpires.Before(a.LastModified.Add(SessionLifetime))) {
+		return a.SessionExpires
+	}
+	if a.LastModified.IsZero() {
+		return time.Time{}
+	}
+	return a.LastModified.Add(SessionLifetime)
+}
+
+// ExpiresSoon reports whether the session is past or within
+// SessionExpiryWarning of its estimated expiry at now
+func (a *Account) ExpiresSoon(now time.Time) bool {
+	expires := a.ExpiresAt()
+	return !expires.IsZero() && now.After(expires.Add(-SessionExpiryWarning))
 }
 
 // CredentialStore is the interface for storing and retrieving credentials
@@ -69,7 +107,8 @@ func NewManager() (*Manager, error) {
 	return &Manager{stores: stores}, nil
 }
 
-// Store saves credentials using the first available store
+// Store saves credentials using the first available store. An account stored
+// without a device keeps the one stored for it before, or gets a new one.
 func (m *Manager) Store(account *Account) error {
 	if account.Username == "" {
 		return errors.New("username is required")
@@ -81,8 +120,103 @@ func (m *Manager) Store(account *Account) error {
 		return errors.New("CSRF token is required")
 	}
 
+	if account.Device == nil {
+		if existing, err := m.Retrieve(account.Username); err == nil && existing.Device != nil {
+			account.Device = existing.Device
+		} else if account.Device, err = NewDevice(account.UserAgent); err != nil {
+			return err
+		}
+	}
 	account.LastModified = time.Now()
+	return m.store(account)
+}
+
+// EnsureDevice gives an account stored before device profiles existed a
+// device and saves it. Accounts that have one are left alone. The account's
+// LastModified is kept, as it dates its session cookies.
+func (m *Manager) EnsureDevice(account *Account) error {
+	if account.Device != nil {
+		return nil
+	}
+	if !m.persisted(account.Username) {
+		// Such as credentials from environment variables
+		return ErrStoreUnavailable
+	}
+	device, err := NewDevice(account.UserAgent)
+	if err != nil {
+		return err
+	}
+	account.Device = device
+	if err := m.store(account); err != nil {
+		account.Device = nil
+		return err
+	}
+	return nil
+}
 
+// UpdateSession saves the cookies Instagram renewed for a stored account.
+// When the sessionid changed the session is dated from now and its cookie
+// expiry is forgotten; a new csrftoken alone leaves both alone. Credentials
+// from environment variables are not saved and return ErrStoreUnavailable.
+func (m *Manager) UpdateSession(username, sessionID, csrfToken string) error {
+	if sessionID == "" || csrfToken == "" {
+		return errors.New("session ID and CSRF token are required")
+	}
+	if !m.persisted(username) {
+		return ErrStoreUnavailable
+	}
+	account, err := m.Retrieve(username)
+	if err != nil {
+		return err
+	}
+	if account.SessionID == sessionID && account.CSRFToken == csrfToken {
+		return nil
+	}
+
+	if account.SessionID != sessionID {
+		account.SessionID = sessionID
+		account.SessionExpires = time.Time{}
+		account.LastModified = time.Now()
+	}
+	account.CSRFToken = csrfToken
+	return m.store(account)
+}
+
+// RegenerateDevice replaces the device of a stored account with a new one,
+// keeping the account's own User-Agent if it has one, and returns the
+// account
+func (m *Manager) RegenerateDevice(username string) (*Account, error) {
+	if !m.persisted(username) {
+		return nil, fmt.Errorf("credentials not found for user: %s", username)
+	}
+	account, err := m.Retrieve(username)
+	if err != nil {
+		return nil, err
+	}
+	device, err := NewDevice(account.UserAgent)
+	if err != nil {
+		return nil, err
+	}
+	account.Device = device
+	if err := m.store(account); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// persisted reports whether a store other than the environment holds
+// credentials for username
+func (m *Manager) persisted(username string) bool {
+	for _, store := range m.stores {
+		if _, env := store.(*EnvironmentStore); !env && store.Exists(username) {
+			return true
+		}
+	}
+	return false
+}
+
+// store saves account in the first store that accepts it
+func (m *Manager) store(account *Account) error {
 	// Try each store in order
 	var lastErr error
 	for _, store := range m.stores {
@@ -191,27 +325,9 @@ func (m *Manager) DeleteAll() error {
 
 // getConfigDir returns the configuration directory path
 func getConfigDir() (string, error) {
-	var configDir string
-
-	switch runtime.GOOS {
-	case "darwin":
-		home, err := os.UserHomeDir()
-		if err != nil {
-			return "", err
-		}
-		configDir = filepath.Join(home, "Library", "Application Support", "igscraper")
-	case "windows":
-		configDir = filepath.Join(os.Getenv("APPDATA"), "igscraper")
-	default: // Linux and others
-		if xdgConfig := os.Getenv("XDG_CONFIG_HOME"); xdgConfig != "" {
-			configDir = filepath.Join(xdgConfig, "igscraper")
-		} else {
-			home, err := os.UserHomeDir()
-			if err != nil {
-				return "", err
-			}
-			configDir = filepath.Join(home, ".config", "igscraper")
-		}
+	configDir, err := platformdirs.ConfigDir()
+	if err != nil {
+		return "", err
 	}
 
 	// Create directory if it doesn't exist
@@ -234,6 +350,9 @@ func SanitizeAccount(account *Account) *Account {
 		CSRFToken:    maskString(account.CSRFToken),
 		UserAgent:    account.UserAgent,
 		LastModified: account.LastModified,
+
+		SessionExpires: account.SessionExpires,
+		Device:         account.Device,
 	}
 }
 
@@ -262,6 +262,75 @@ func TestMockStore(t *testing.T) {
 	}
 }
 
+func TestAccountExpiry(t *testing.T) {
+	saved := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	account := &Account{Username: "user", LastModified: saved}
+
+	if got, want := account.ExpiresAt(), saved.Add(SessionLifetime); !got.Equal(want) {
+		t.Errorf("ExpiresAt = %v, want %v", got, want)
+	}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want bool
+	}{
+		{"fresh", saved.Add(24 * time.Hour), false},
+		{"just before warning", saved.Add(SessionLifetime - SessionExpiryWarning - time.Hour), false},
+		{"within warning", saved.Add(SessionLifetime - time.Hour), true},
+		{"expired", saved.Add(SessionLifetime + time.Hour), true},
+	}
+	for _, tt := range tests {
+		if got := account.ExpiresSoon(tt.now); got != tt.want {
+			t.Errorf("%s: ExpiresSoon = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	unknown := &Account{Username: "user"}
+	if !unknown.ExpiresAt().IsZero() {
+		t.Error("ExpiresAt should be zero without a save time")
+	}
+	if unknown.ExpiresSoon(time.Now()) {
+		t.Error("ExpiresSoon should be false without a save time")
+	}
+}
+
+func TestUpdateSession(t *testing.T) {
+	manager, mockStore := NewMockManager()
+	saved := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	expires := saved.Add(30 * 24 * time.Hour)
+	device := &Device{DeviceID: "device"}
+	mockStore.Store(&Account{Username: "user", SessionID: "session", CSRFToken: "csrf", LastModified: saved, SessionExpires: expires, Device: device})
+
+	// A new csrftoken keeps the session's dates
+	if err := manager.UpdateSession("user", "session", "csrf-2"); err != nil {
+		t.Fatalf("Failed to update session: %v", err)
+	}
+	stored, _ := mockStore.GetAccount("user")
+	if stored.CSRFToken != "csrf-2" || !stored.LastModified.Equal(saved) || !stored.SessionExpires.Equal(expires) {
+		t.Errorf("Expected only the CSRF token to change, got %+v", stored)
+	}
+
+	// A new sessionid dates the session from now
+	if err := manager.UpdateSession("user", "session-2", "csrf-3"); err != nil {
+		t.Fatalf("Failed to update session: %v", err)
+	}
+	stored, _ = mockStore.GetAccount("user")
+	if stored.SessionID != "session-2" || stored.CSRFToken != "csrf-3" || !stored.LastModified.After(saved) || !stored.SessionExpires.IsZero() {
+		t.Errorf("Expected a renewed session, got %+v", stored)
+	}
+	if stored.Device == nil || stored.Device.DeviceID != "device" {
+		t.Errorf("Expected the device to be kept, got %+v", stored.Device)
+	}
+
+	if err := manager.UpdateSession("nobody", "session", "csrf"); err != ErrStoreUnavailable {
+		t.Errorf("Expected ErrStoreUnavailable for an unknown account, got %v", err)
+	}
+	if err := manager.UpdateSession("user", "", "csrf"); err == nil {
+		t.Error("Expected an error without a session ID")
+	}
+}
+
 func contains(data []byte, substr []byte) bool {
 	for i := 0; i <= len(data)-len(substr); i++ {
 		if string(data[i:i+len(substr)]) == string(substr) {
@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// DefaultAppID is the X-IG-App-ID of Instagram's web app
+const DefaultAppID = "936619743392459"
+
+// DefaultAcceptLanguage is the Accept-Language sent by accounts without a
+// device profile
+const DefaultAcceptLanguage = "en-US,en;q=0.9"
+
+// Device is the browser an account presents to Instagram. It is generated
+// once per stored account and kept with its credentials, so every request of
+// the account looks like it comes from the same browser, and no two accounts
+// share one.
+type Device struct {
+	UserAgent      string    `json:"user_agent"`
+	AppID          string    `json:"app_id"`
+	DeviceID       string    `json:"device_id"`  // ig_did cookie, a UUID
+	MachineID      string    `json:"machine_id"` // mid cookie
+	AcceptLanguage string    `json:"accept_language"`
+	GeneratedAt    time.Time `json:"generated_at"`
+}
+
+// deviceUserAgents are the desktop browsers devices are picked from. The
+// Chrome version is filled in from chromeVersions.
+var deviceUserAgents = []string{
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/%s Safari/537.36",
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/%s Safari/537.36",
+	"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/%s Safari/537.36",
+}
+
+// chromeVersions are recent stable Chrome releases
+var chromeVersions = []string{
+	"120.0.0.0",
+	"121.0.0.0",
+	"122.0.0.0",
+	"123.0.0.0",
+	"124.0.0.0",
+	"125.0.0.0",
+}
+
+// deviceLanguages are the Accept-Language headers devices are picked from
+var deviceLanguages = []string{
+	"en-US,en;q=0.9",
+	"en-GB,en;q=0.9",
+	"en-US,en;q=0.9,es;q=0.8",
+	"en-US,en;q=0.9,de;q=0.8",
+	"en-US,en;q=0.9,fr;q=0.8",
+	"en-CA,en;q=0.9,fr-CA;q=0.8",
+}
+
+// NewDevice generates a device with random identifiers and a browser picked
+// at random. A non-empty userAgent is kept instead of a generated one.
+func NewDevice(userAgent string) (*Device, error) {
+	if userAgent == "" {
+		agent, err := pick(deviceUserAgents)
+		if err != nil {
+			return nil, err
+		}
+		version, err := pick(chromeVersions)
+		if err != nil {
+			return nil, err
+		}
+		userAgent = fmt.Sprintf(agent, version)
+	}
+	language, err := pick(deviceLanguages)
+	if err != nil {
+		return nil, err
+	}
+	deviceID, err := newUUID()
+	if err != nil {
+		return nil, err
+	}
+	machineID, err := randomToken(21)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Device{
+		UserAgent:      userAgent,
+		AppID:          DefaultAppID,
+		DeviceID:       deviceID,
+		MachineID:      machineID,
+		AcceptLanguage: language,
+		GeneratedAt:    time.Now(),
+	}, nil
+}
+
+// Headers returns the request headers that identify the device. Empty
+// fields are left out.
+func (d *Device) Headers() map[string]string {
+	headers := make(map[string]string)
+	if d.UserAgent != "" {
+		headers["User-Agent"] = d.UserAgent
+	}
+	if d.AppID != "" {
+		headers["X-IG-App-ID"] = d.AppID
+	}
+	if d.AcceptLanguage != "" {
+		headers["Accept-Language"] = d.AcceptLanguage
+	}
+	return headers
+}
+
+// Cookies returns the ig_did and mid cookies of the device, as name=value
+func (d *Device) Cookies() []string {
+	var cookies []string
+	if d.DeviceID != "" {
+		cookies = append(cookies, "ig_did="+d.DeviceID)
+	}
+	if d.MachineID != "" {
+		cookies = append(cookies, "mid="+d.MachineID)
+	}
+	return cookies
+}
+
+// Fingerprint returns the device the account presents: its device profile
+// with the account's own User-Agent, if one was set. Accounts without a
+// profile only have that User-Agent.
+func (a *Account) Fingerprint() *Device {
+	device := &Device{}
+	if a.Device != nil {
+		*device = *a.Device
+	}
+	if a.UserAgent != "" {
+		device.UserAgent = a.UserAgent
+	}
+	return device
+}
+
+// pick returns a random element of choices
+func pick(choices []string) (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(choices))))
+	if err != nil {
+		return "", fmt.Errorf("failed to generate device: %w", err)
+	}
+	return choices[n.Int64()], nil
+}
+
+// newUUID returns a random version 4 UUID in upper case, as Instagram writes
+// ig_did
+func newUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate device: %w", err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%X-%X-%X-%X-%X", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// randomToken returns n random bytes encoded as unpadded URL-safe base64
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate device: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
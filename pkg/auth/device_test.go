@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestNewDevice(t *testing.T) {
+	first, err := NewDevice("")
+	if err != nil {
+		t.Fatalf("Failed to generate device: %v", err)
+	}
+	second, err := NewDevice("")
+	if err != nil {
+		t.Fatalf("Failed to generate device: %v", err)
+	}
+
+	uuid := regexp.MustCompile(`^[0-9A-F]{8}-[0-9A-F]{4}-4[0-9A-F]{3}-[89AB][0-9A-F]{3}-[0-9A-F]{12}$`)
+	if !uuid.MatchString(first.DeviceID) {
+		t.Errorf("Device ID %q is not an upper case UUID", first.DeviceID)
+	}
+	if len(first.MachineID) != 28 {
+		t.Errorf("Expected a 28 character machine ID, got %q", first.MachineID)
+	}
+	if first.UserAgent == "" || first.AppID != DefaultAppID || first.AcceptLanguage == "" {
+		t.Errorf("Device is missing headers: %+v", first)
+	}
+	if first.DeviceID == second.DeviceID || first.MachineID == second.MachineID {
+		t.Error("Expected devices to have unique IDs")
+	}
+
+	// An account's own User-Agent is kept
+	device, err := NewDevice("Custom/1.0")
+	if err != nil {
+		t.Fatalf("Failed to generate device: %v", err)
+	}
+	if device.UserAgent != "Custom/1.0" || device.Headers()["User-Agent"] != "Custom/1.0" {
+		t.Errorf("Expected the given User-Agent, got %+v", device)
+	}
+	if cookies := device.Cookies(); len(cookies) != 2 || cookies[0] != "ig_did="+device.DeviceID {
+		t.Errorf("Unexpected cookies: %v", cookies)
+	}
+}
+
+func TestManagerDevices(t *testing.T) {
+	manager, mockStore := NewMockManager()
+
+	// Storing an account generates its device, and storing it again keeps it
+	account := &Account{Username: "testuser", SessionID: "session", CSRFToken: "csrf"}
+	if err := manager.Store(account); err != nil {
+		t.Fatalf("Failed to store account: %v", err)
+	}
+	if account.Device == nil {
+		t.Fatal("Expected a device to be generated")
+	}
+	device := *account.Device
+	if err := manager.Store(&Account{Username: "testuser", SessionID: "new-session", CSRFToken: "csrf"}); err != nil {
+		t.Fatalf("Failed to store account: %v", err)
+	}
+	stored, err := mockStore.GetAccount("testuser")
+	if err != nil || stored.Device == nil || *stored.Device != device {
+		t.Errorf("Expected the device to be kept, got %+v", stored)
+	}
+
+	// Regenerating replaces the device and keeps the cookies
+	regenerated, err := manager.RegenerateDevice("testuser")
+	if err != nil {
+		t.Fatalf("Failed to regenerate device: %v", err)
+	}
+	if regenerated.Device.DeviceID == device.DeviceID || regenerated.SessionID != "new-session" {
+		t.Errorf("Unexpected regenerated account: %+v", regenerated)
+	}
+
+	// Accounts stored before devices existed get one, with their save time
+	// unchanged
+	saved := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	mockStore.Store(&Account{Username: "olduser", SessionID: "session", CSRFToken: "csrf", LastModified: saved})
+	old, _ := manager.Retrieve("olduser")
+	if err := manager.EnsureDevice(old); err != nil {
+		t.Fatalf("Failed to ensure device: %v", err)
+	}
+	if stored, _ := mockStore.GetAccount("olduser"); stored.Device == nil || !stored.LastModified.Equal(saved) {
+		t.Errorf("Expected a device saved without touching LastModified, got %+v", stored)
+	}
+
+	// Credentials that cannot be saved get none
+	if err := manager.EnsureDevice(&Account{Username: "default", SessionID: "session", CSRFToken: "csrf"}); err != ErrStoreUnavailable {
+		t.Errorf("Expected ErrStoreUnavailable, got %v", err)
+	}
+}
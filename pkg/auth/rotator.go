@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// DefaultRotateAfter is how many consecutive rate-limit or authentication
+// errors an account may receive before the rotator switches away from it
+const DefaultRotateAfter = 3
+
+// RotationCooldown is how long an account that was rotated out is rested
+// before it may be used again
+const RotationCooldown = 15 * time.Minute
+
+// AccountRotator spreads a long scrape over several stored accounts. When
+// the current account keeps getting rate limited or logged out, the next
+// account that is not resting takes over. It is safe for concurrent use.
+type AccountRotator struct {
+	mu        sync.Mutex
+	accounts  []*Account
+	current   int
+	failures  int
+	threshold int
+	resting   map[string]time.Time // username -> end of its cooldown
+	now       func() time.Time
+}
+
+// NewAccountRotator returns a rotator that starts with the first account and
+// switches after threshold consecutive failures. A threshold below 1 uses
+// DefaultRotateAfter.
+func NewAccountRotator(accounts []*Account, threshold int) (*AccountRotator, error) {
+	if len(accounts) == 0 {
+		return nil, errors.New("account rotation needs at least one account")
+	}
+	if threshold < 1 {
+		threshold = DefaultRotateAfter
+	}
+	return &AccountRotator{
+		accounts:  accounts,
+		threshold: threshold,
+		resting:   make(map[string]time.Time),
+		now:       time.Now,
+	}, nil
+}
+
+// Current returns the account requests should use
+func (r *AccountRotator) Current() *Account {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.accounts[r.current]
+}
+
+// Len returns the number of accounts in the rotation
+func (r *AccountRotator) Len() int {
+	return len(r.accounts)
+}
+
+// Success records a request that the current account completed, resetting
+// its failure count
+func (r *AccountRotator) Success() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failures = 0
+}
+
+// Failure records a rate-limit or authentication error for the current
+// account. Once the account has failed threshold times in a row, it is rested
+// for RotationCooldown and the next available account becomes current;
+// Failure then returns that account and true. While every other account is
+// resting the current one is kept.
+func (r *AccountRotator) Failure() (*Account, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.failures++
+	if r.failures < r.threshold {
+		return nil, false
+	}
+
+	now := r.now()
+	for step := 1; step < len(r.accounts); step++ {
+		next := (r.current + step) % len(r.accounts)
+		if until, ok := r.resting[r.accounts[next].Username]; ok && now.Before(until) {
+			continue
+		}
+		r.resting[r.accounts[r.current].Username] = now.Add(RotationCooldown)
+		delete(r.resting, r.accounts[next].Username)
+		r.current = next
+		r.failures = 0
+		return r.accounts[next], true
+	}
+	return nil, false
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAccountRotator(t *testing.T) {
+	accounts := []*Account{{Username: "a"}, {Username: "b"}, {Username: "c"}}
+	r, err := NewAccountRotator(accounts, 2)
+	if err != nil {
+		t.Fatalf("Failed to create rotator: %v", err)
+	}
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+
+	if r.Current().Username != "a" {
+		t.Fatalf("Expected to start with a, got %s", r.Current().Username)
+	}
+
+	// A success in between resets the count
+	r.Failure()
+	r.Success()
+	if _, rotated := r.Failure(); rotated {
+		t.Error("Rotated before reaching the threshold")
+	}
+	next, rotated := r.Failure()
+	if !rotated || next.Username != "b" || r.Current().Username != "b" {
+		t.Fatalf("Expected rotation to b, got %v %v", next, rotated)
+	}
+
+	r.Failure()
+	if next, _ := r.Failure(); next == nil || next.Username != "c" {
+		t.Fatalf("Expected rotation to c, got %v", next)
+	}
+
+	// a and b are resting, so c is kept
+	r.Failure()
+	if _, rotated := r.Failure(); rotated {
+		t.Error("Rotated to a resting account")
+	}
+	if r.Current().Username != "c" {
+		t.Errorf("Current = %s, want c", r.Current().Username)
+	}
+
+	// After the cooldown a is available again
+	now = now.Add(RotationCooldown)
+	if next, rotated := r.Failure(); !rotated || next.Username != "a" {
+		t.Errorf("Expected rotation back to a, got %v %v", next, rotated)
+	}
+}
+
+func TestAccountRotatorSingleAccount(t *testing.T) {
+	r, err := NewAccountRotator([]*Account{{Username: "only"}}, 0)
+	if err != nil {
+		t.Fatalf("Failed to create rotator: %v", err)
+	}
+	for i := 0; i < DefaultRotateAfter*2; i++ {
+		if _, rotated := r.Failure(); rotated {
+			t.Fatal("Rotated with a single account")
+		}
+	}
+
+	if _, err := NewAccountRotator(nil, 1); err == nil {
+		t.Error("Expected error without accounts")
+	}
+}
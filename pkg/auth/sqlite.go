@@ -0,0 +1,437 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+)
+
+// sqliteDB is a minimal read-only reader for SQLite database files. It
+// supports what browser cookie stores need and nothing more: reading every
+// row of a rowid table, with committed pages from a write-ahead log that has
+// not been checkpointed yet applied on top. The files are read directly, so
+// a browser holding the database open does not block the import.
+type sqliteDB struct {
+	data     []byte
+	pageSize int
+	usable   int
+	wal      map[uint32][]byte
+}
+
+// errSQLiteCorrupt is returned for structures the reader cannot make sense of
+var errSQLiteCorrupt = errors.New("malformed SQLite database")
+
+// openSQLite reads the database at path and its -wal file, if present
+func openSQLite(path string) (*sqliteDB, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 100 || !bytes.HasPrefix(data, []byte("SQLite format 3\x00")) {
+		return nil, fmt.Errorf("%s is not a SQLite database", path)
+	}
+
+	pageSize := int(binary.BigEndian.Uint16(data[16:18]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	if pageSize < 512 || pageSize&(pageSize-1) != 0 {
+		return nil, errSQLiteCorrupt
+	}
+
+	db := &sqliteDB{
+		data:     data,
+		pageSize: pageSize,
+		usable:   pageSize - int(data[20]),
+	}
+
+	wal, err := os.ReadFile(path + "-wal")
+	if err == nil {
+		db.wal = parseWAL(wal, pageSize)
+	}
+	return db, nil
+}
+
+// parseWAL returns the newest committed version of every page in a WAL file.
+// Frames after the last commit, from an older WAL generation, or failing the
+// checksum chain are ignored, exactly as SQLite itself would.
+func parseWAL(wal []byte, pageSize int) map[uint32][]byte {
+	if len(wal) < 32 {
+		return nil
+	}
+	magic := binary.BigEndian.Uint32(wal[0:4])
+	var order binary.ByteOrder
+	switch magic {
+	case 0x377f0682:
+		order = binary.LittleEndian
+	case 0x377f0683:
+		order = binary.BigEndian
+	default:
+		return nil
+	}
+	if int(binary.BigEndian.Uint32(wal[8:12])) != pageSize {
+		return nil
+	}
+
+	salt := wal[16:24]
+	s0, s1 := walChecksum(order, wal[0:24], 0, 0)
+	if s0 != binary.BigEndian.Uint32(wal[24:28]) || s1 != binary.BigEndian.Uint32(wal[28:32]) {
+		return nil
+	}
+
+	committed := make(map[uint32][]byte)
+	pending := make(map[uint32][]byte)
+	frameSize := 24 + pageSize
+	for offset := 32; offset+frameSize <= len(wal); offset += frameSize {
+		header := wal[offset : offset+24]
+		page := wal[offset+24 : offset+frameSize]
+		if !bytes.Equal(header[8:16], salt) {
+			break
+		}
+		s0, s1 = walChecksum(order, header[0:8], s0, s1)
+		s0, s1 = walChecksum(order, page, s0, s1)
+		if s0 != binary.BigEndian.Uint32(header[16:20]) || s1 != binary.BigEndian.Uint32(header[20:24]) {
+			break
+		}
+
+		pending[binary.BigEndian.Uint32(header[0:4])] = page
+		if binary.BigEndian.Uint32(header[4:8]) != 0 {
+			// A commit frame makes every frame since the last commit visible
+			for number, data := range pending {
+				committed[number] = data
+			}
+			clear(pending)
+		}
+	}
+	return committed
+}
+
+// walChecksum continues the WAL checksum over data, a multiple of 8 bytes
+func walChecksum(order binary.ByteOrder, data []byte, s0, s1 uint32) (uint32, uint32) {
+	for i := 0; i+8 <= len(data); i += 8 {
+		s0 += order.Uint32(data[i:]) + s1
+		s1 += order.Uint32(data[i+4:]) + s0
+	}
+	return s0, s1
+}
+
+// page returns page number n (1-based)
+func (db *sqliteDB) page(n uint32) ([]byte, error) {
+	if data, ok := db.wal[n]; ok {
+		return data, nil
+	}
+	start := int(n-1) * db.pageSize
+	if n == 0 || start+db.pageSize > len(db.data) {
+		return nil, errSQLiteCorrupt
+	}
+	return db.data[start : start+db.pageSize], nil
+}
+
+// table returns the column names and every row of the named table. Rows
+// written before a column was added are padded with nils.
+func (db *sqliteDB) table(name string) ([]string, [][]interface{}, error) {
+	// sqlite_schema(type, name, tbl_name, rootpage, sql) is rooted at page 1
+	schema, err := db.rows(1)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, row := range schema {
+		if len(row) < 5 {
+			continue
+		}
+		kind, _ := row[0].(string)
+		tableName, _ := row[1].(string)
+		root, _ := row[3].(int64)
+		sql, _ := row[4].(string)
+		if kind != "table" || !strings.EqualFold(tableName, name) {
+			continue
+		}
+		if root <= 0 || root > math.MaxUint32 {
+			return nil, nil, errSQLiteCorrupt
+		}
+
+		columns := parseColumns(sql)
+		rows, err := db.rows(uint32(root))
+		if err != nil {
+			return nil, nil, err
+		}
+		for i, row := range rows {
+			if len(row) < len(columns) {
+				rows[i] = append(row, make([]interface{}, len(columns)-len(row))...)
+			}
+		}
+		return columns, rows, nil
+	}
+	return nil, nil, fmt.Errorf("table %s not found", name)
+}
+
+// rows walks the table b-tree rooted at page root and decodes every record
+func (db *sqliteDB) rows(root uint32) ([][]interface{}, error) {
+	var rows [][]interface{}
+	visited := make(map[uint32]bool)
+
+	var walk func(n uint32) error
+	walk = func(n uint32) error {
+		if visited[n] {
+			return errSQLiteCorrupt
+		}
+		visited[n] = true
+
+		page, err := db.page(n)
+		if err != nil {
+			return err
+		}
+		headerStart := 0
+		if n == 1 {
+			headerStart = 100
+		}
+		if len(page) < headerStart+12 {
+			return errSQLiteCorrupt
+		}
+		header := page[headerStart:]
+		cells := int(binary.BigEndian.Uint16(header[3:5]))
+
+		switch header[0] {
+		case 0x05: // interior table page
+			pointers := header[12:]
+			if len(pointers) < cells*2 {
+				return errSQLiteCorrupt
+			}
+			for i := 0; i < cells; i++ {
+				offset := int(binary.BigEndian.Uint16(pointers[i*2:]))
+				if offset+4 > len(page) {
+					return errSQLiteCorrupt
+				}
+				if err := walk(binary.BigEndian.Uint32(page[offset:])); err != nil {
+					return err
+				}
+			}
+			return walk(binary.BigEndian.Uint32(header[8:12]))
+
+		case 0x0d: // leaf table page
+			pointers := header[8:]
+			if len(pointers) < cells*2 {
+				return errSQLiteCorrupt
+			}
+			for i := 0; i < cells; i++ {
+				offset := int(binary.BigEndian.Uint16(pointers[i*2:]))
+				payload, err := db.leafPayload(page, offset)
+				if err != nil {
+					return err
+				}
+				row, err := decodeRecord(payload)
+				if err != nil {
+					return err
+				}
+				rows = append(rows, row)
+			}
+			return nil
+
+		default:
+			return errSQLiteCorrupt
+		}
+	}
+
+	if err := walk(root); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// leafPayload returns the full payload of the table leaf cell at offset,
+// following overflow pages when it does not fit on the page
+func (db *sqliteDB) leafPayload(page []byte, offset int) ([]byte, error) {
+	if offset >= len(page) {
+		return nil, errSQLiteCorrupt
+	}
+	size, n := readVarint(page[offset:])
+	if n == 0 {
+		return nil, errSQLiteCorrupt
+	}
+	offset += n
+	if _, n = readVarint(page[offset:]); n == 0 { // rowid
+		return nil, errSQLiteCorrupt
+	}
+	offset += n
+
+	total := int(size)
+	local := db.localPayload(total)
+	if total < 0 || offset+local > len(page) {
+		return nil, errSQLiteCorrupt
+	}
+	payload := make([]byte, 0, total)
+	payload = append(payload, page[offset:offset+local]...)
+	if local == total {
+		return payload, nil
+	}
+
+	if offset+local+4 > len(page) {
+		return nil, errSQLiteCorrupt
+	}
+	next := binary.BigEndian.Uint32(page[offset+local:])
+	for len(payload) < total {
+		if next == 0 {
+			return nil, errSQLiteCorrupt
+		}
+		overflow, err := db.page(next)
+		if err != nil {
+			return nil, err
+		}
+		chunk := min(total-len(payload), db.usable-4)
+		payload = append(payload, overflow[4:4+chunk]...)
+		next = binary.BigEndian.Uint32(overflow[0:4])
+	}
+	return payload, nil
+}
+
+// localPayload returns how many bytes of a table leaf payload of the given
+// size are stored on the leaf page itself
+func (db *sqliteDB) localPayload(size int) int {
+	maxLocal := db.usable - 35
+	if size <= maxLocal {
+		return size
+	}
+	minLocal := (db.usable-12)*32/255 - 23
+	local := minLocal + (size-minLocal)%(db.usable-4)
+	if local > maxLocal {
+		local = minLocal
+	}
+	return local
+}
+
+// decodeRecord decodes a record into int64, float64, string, []byte and nil values
+func decodeRecord(payload []byte) ([]interface{}, error) {
+	headerSize, n := readVarint(payload)
+	if n == 0 || int(headerSize) > len(payload) || int(headerSize) < n {
+		return nil, errSQLiteCorrupt
+	}
+
+	var types []uint64
+	for pos := n; pos < int(headerSize); {
+		serialType, n := readVarint(payload[pos:headerSize])
+		if n == 0 {
+			return nil, errSQLiteCorrupt
+		}
+		types = append(types, serialType)
+		pos += n
+	}
+
+	values := make([]interface{}, 0, len(types))
+	body := payload[headerSize:]
+	for _, serialType := range types {
+		size := serialSize(serialType)
+		if size > len(body) {
+			return nil, errSQLiteCorrupt
+		}
+		field := body[:size]
+		body = body[size:]
+
+		switch {
+		case serialType == 0:
+			values = append(values, nil)
+		case serialType <= 6:
+			values = append(values, readInt(field))
+		case serialType == 7:
+			values = append(values, math.Float64frombits(binary.BigEndian.Uint64(field)))
+		case serialType == 8:
+			values = append(values, int64(0))
+		case serialType == 9:
+			values = append(values, int64(1))
+		case serialType >= 12 && serialType%2 == 0:
+			values = append(values, append([]byte(nil), field...))
+		case serialType >= 13:
+			values = append(values, string(field))
+		default:
+			return nil, errSQLiteCorrupt
+		}
+	}
+	return values, nil
+}
+
+// serialSize returns the number of body bytes used by a serial type
+func serialSize(serialType uint64) int {
+	switch {
+	case serialType <= 4:
+		return []int{0, 1, 2, 3, 4}[serialType]
+	case serialType == 5:
+		return 6
+	case serialType == 6 || serialType == 7:
+		return 8
+	case serialType >= 12:
+		return int((serialType - 12) / 2)
+	default:
+		return 0
+	}
+}
+
+// readInt decodes a big-endian two's complement integer of 1 to 8 bytes
+func readInt(b []byte) int64 {
+	var v int64
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		v = -1
+	}
+	for _, c := range b {
+		v = v<<8 | int64(c)
+	}
+	return v
+}
+
+// readVarint decodes a SQLite varint, returning 0 bytes read on truncation
+func readVarint(b []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < 9 && i < len(b); i++ {
+		if i == 8 {
+			return v<<8 | uint64(b[i]), 9
+		}
+		v = v<<7 | uint64(b[i]&0x7f)
+		if b[i]&0x80 == 0 {
+			return v, i + 1
+		}
+	}
+	return 0, 0
+}
+
+// parseColumns extracts the column names from a CREATE TABLE statement
+func parseColumns(sql string) []string {
+	start := strings.Index(sql, "(")
+	end := strings.LastIndex(sql, ")")
+	if start < 0 || end <= start {
+		return nil
+	}
+
+	var defs []string
+	depth, last := 0, start+1
+	for i := start + 1; i < end; i++ {
+		switch sql[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				defs = append(defs, sql[last:i])
+				last = i + 1
+			}
+		}
+	}
+	defs = append(defs, sql[last:end])
+
+	var columns []string
+	for _, def := range defs {
+		fields := strings.Fields(def)
+		if len(fields) == 0 {
+			continue
+		}
+		switch strings.ToUpper(fields[0]) {
+		case "CONSTRAINT", "PRIMARY", "UNIQUE", "CHECK", "FOREIGN":
+			continue
+		}
+		columns = append(columns, strings.Trim(fields[0], "\"`[]'"))
+	}
+	return columns
+}
@@ -0,0 +1,117 @@
+package browser
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"igscraper/pkg/errors"
+)
+
+// ErrUnsupported is returned by New in builds without the chromedp build tag
+var ErrUnsupported = stderrors.New("headless browser support is not compiled in; rebuild with -tags chromedp")
+
+// appID is the X-IG-App-ID of Instagram's web app
+const appID = "936619743392459"
+
+// Options configure the browser session
+type Options struct {
+	SessionID string
+	CSRFToken string
+	UserAgent string        // empty keeps Chrome's own
+	ExecPath  string        // Chrome or Chromium binary, searched for if empty
+	Timeout   time.Duration // for starting the browser and for each request
+}
+
+// withDefaults fills in the timeout
+func (o Options) withDefaults() Options {
+	if o.Timeout <= 0 {
+		o.Timeout = time.Minute
+	}
+	return o
+}
+
+// cookies returns the cookies that log the browser in. The account's user ID
+// is the part of the session ID before the first colon.
+func (o Options) cookies() map[string]string {
+	cookies := map[string]string{
+		"sessionid": o.SessionID,
+		"csrftoken": o.CSRFToken,
+	}
+	if id, err := url.QueryUnescape(o.SessionID); err == nil {
+		if userID, _, found := strings.Cut(id, ":"); found && userID != "" {
+			cookies["ds_user_id"] = userID
+		}
+	}
+	return cookies
+}
+
+// fetchResult is what the in-page fetch resolves to
+type fetchResult struct {
+	Status int    `json:"status"`
+	Body   string `json:"body"`
+}
+
+// decode checks the status of a fetch made by the browser and parses its
+// body into target, returning the same errors as the HTTP client
+func decode(result fetchResult, target interface{}) error {
+	switch {
+	case result.Status == http.StatusUnauthorized:
+		return &errors.Error{
+			Type:    errors.ErrorTypeAuth,
+			Message: "authentication required",
+			Code:    result.Status,
+		}
+	case result.Status == http.StatusNotFound:
+		return &errors.Error{
+			Type:    errors.ErrorTypeNotFound,
+			Message: "resource not found",
+			Code:    result.Status,
+		}
+	case result.Status == http.StatusTooManyRequests:
+		return &errors.Error{
+			Type:    errors.ErrorTypeRateLimit,
+			Message: "rate limit exceeded",
+			Code:    result.Status,
+		}
+	case result.Status >= 500:
+		return &errors.Error{
+			Type:    errors.ErrorTypeServerError,
+			Message: "server error",
+			Code:    result.Status,
+		}
+	case result.Status >= 400:
+		return &errors.Error{
+			Type:    errors.ErrorTypeUnknown,
+			Message: fmt.Sprintf("unexpected status code: %d", result.Status),
+			Code:    result.Status,
+		}
+	}
+
+	// A login wall is served as HTML with status 200
+	if err := json.Unmarshal([]byte(result.Body), target); err != nil {
+		return &errors.Error{
+			Type:    errors.ErrorTypeParsing,
+			Message: fmt.Sprintf("failed to parse JSON from browser: %v", err),
+			Code:    result.Status,
+		}
+	}
+	return nil
+}
+
+// fetchScript returns JavaScript that requests apiURL from the page with the
+// web app's headers and resolves to a fetchResult
+func fetchScript(apiURL, csrfToken string) string {
+	quotedURL, _ := json.Marshal(apiURL)
+	headers, _ := json.Marshal(map[string]string{
+		"X-IG-App-ID":      appID,
+		"X-CSRFToken":      csrfToken,
+		"X-Requested-With": "XMLHttpRequest",
+	})
+	return fmt.Sprintf(`fetch(%s, {credentials: "include", headers: %s})
+	.then(r => r.text().then(body => ({status: r.status, body: body})))`, quotedURL, headers)
+}
@@ -0,0 +1,61 @@
+package browser
+
+import (
+	stderrors "errors"
+	"strings"
+	"testing"
+
+	"igscraper/pkg/errors"
+)
+
+func TestDecode(t *testing.T) {
+	var target struct {
+		Status string `json:"status"`
+	}
+	if err := decode(fetchResult{Status: 200, Body: `{"status":"ok"}`}, &target); err != nil || target.Status != "ok" {
+		t.Fatalf("decode = %v, %+v", err, target)
+	}
+
+	tests := []struct {
+		result fetchResult
+		want   errors.ErrorType
+	}{
+		{fetchResult{Status: 200, Body: "<!DOCTYPE html><html>Log in</html>"}, errors.ErrorTypeParsing},
+		{fetchResult{Status: 401}, errors.ErrorTypeAuth},
+		{fetchResult{Status: 404}, errors.ErrorTypeNotFound},
+		{fetchResult{Status: 429}, errors.ErrorTypeRateLimit},
+		{fetchResult{Status: 503}, errors.ErrorTypeServerError},
+		{fetchResult{Status: 403}, errors.ErrorTypeUnknown},
+	}
+	for _, tt := range tests {
+		err := decode(tt.result, &target)
+		var igErr *errors.Error
+		if !stderrors.As(err, &igErr) || igErr.Type != tt.want {
+			t.Errorf("decode(status %d) = %v, want %s error", tt.result.Status, err, tt.want)
+		}
+	}
+}
+
+func TestCookies(t *testing.T) {
+	cookies := Options{SessionID: "12345%3Aabc%3A7", CSRFToken: "token"}.cookies()
+	if cookies["sessionid"] != "12345%3Aabc%3A7" || cookies["csrftoken"] != "token" {
+		t.Errorf("Unexpected session cookies %v", cookies)
+	}
+	if cookies["ds_user_id"] != "12345" {
+		t.Errorf("ds_user_id = %q, want 12345", cookies["ds_user_id"])
+	}
+
+	if _, ok := (Options{SessionID: "opaque"}).cookies()["ds_user_id"]; ok {
+		t.Error("Expected no ds_user_id for a session ID without a user ID")
+	}
+}
+
+func TestFetchScript(t *testing.T) {
+	script := fetchScript(`https://www.instagram.com/api/v1/feed/tag/a"b/`, "csrf")
+	if !strings.Contains(script, `"https://www.instagram.com/api/v1/feed/tag/a\"b/"`) {
+		t.Errorf("Expected the URL to be quoted, got %s", script)
+	}
+	if !strings.Contains(script, `"X-CSRFToken":"csrf"`) || !strings.Contains(script, appID) {
+		t.Errorf("Expected the web app headers, got %s", script)
+	}
+}
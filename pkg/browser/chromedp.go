@@ -0,0 +1,135 @@
+//go:build chromedp
+
+package browser
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+
+	"igscraper/pkg/errors"
+	"igscraper/pkg/instagram"
+)
+
+// Available reports whether this build can drive a headless browser
+const Available = true
+
+// Browser is a headless Chrome session logged in with the stored cookies.
+// Chrome is started by the first request, and requests are made one at a
+// time.
+type Browser struct {
+	opts   Options
+	ctx    context.Context
+	cancel context.CancelFunc
+	mu     sync.Mutex
+}
+
+// New creates a browser session. Chrome is not started until it is needed.
+func New(opts Options) (*Browser, error) {
+	return &Browser{opts: opts.withDefaults()}, nil
+}
+
+// start launches Chrome, sets the session cookies and loads instagram.com so
+// later fetches are same-origin
+func (b *Browser) start() error {
+	if b.ctx != nil {
+		return nil
+	}
+
+	allocOpts := chromedp.DefaultExecAllocatorOptions[:]
+	if b.opts.UserAgent != "" {
+		allocOpts = append(allocOpts, chromedp.UserAgent(b.opts.UserAgent))
+	}
+	if b.opts.ExecPath != "" {
+		allocOpts = append(allocOpts, chromedp.ExecPath(b.opts.ExecPath))
+	}
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(context.Background(), allocOpts...)
+	ctx, cancelCtx := chromedp.NewContext(allocCtx)
+	cancel := func() {
+		cancelCtx()
+		cancelAlloc()
+	}
+
+	// The first Run starts Chrome and ties it to ctx, so it must not have a
+	// timeout of its own
+	if err := chromedp.Run(ctx); err != nil {
+		cancel()
+		return err
+	}
+
+	expires := cdp.TimeSinceEpoch(time.Now().Add(24 * time.Hour))
+	setCookies := chromedp.ActionFunc(func(ctx context.Context) error {
+		for name, value := range b.opts.cookies() {
+			err := network.SetCookie(name, value).
+				WithDomain(".instagram.com").
+				WithPath("/").
+				WithSecure(true).
+				WithHTTPOnly(name == "sessionid").
+				WithExpires(&expires).
+				Do(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to set %s cookie: %w", name, err)
+			}
+		}
+		return nil
+	})
+
+	runCtx, done := context.WithTimeout(ctx, b.opts.Timeout)
+	defer done()
+	if err := chromedp.Run(runCtx, network.Enable(), setCookies, chromedp.Navigate(instagram.BaseURL+"/")); err != nil {
+		cancel()
+		return err
+	}
+
+	b.ctx, b.cancel = ctx, cancel
+	return nil
+}
+
+// GetJSON requests apiURL from inside the logged-in page and parses the JSON
+// response into target
+func (b *Browser) GetJSON(apiURL string, target interface{}) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.start(); err != nil {
+		return &errors.Error{
+			Type:    errors.ErrorTypeNetwork,
+			Message: fmt.Sprintf("failed to start headless browser: %v", err),
+			Code:    0,
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(b.ctx, b.opts.Timeout)
+	defer cancel()
+
+	var result fetchResult
+	awaitPromise := func(p *runtime.EvaluateParams) *runtime.EvaluateParams {
+		return p.WithAwaitPromise(true)
+	}
+	if err := chromedp.Run(ctx, chromedp.Evaluate(fetchScript(apiURL, b.opts.CSRFToken), &result, awaitPromise)); err != nil {
+		return &errors.Error{
+			Type:    errors.ErrorTypeNetwork,
+			Message: fmt.Sprintf("browser request failed: %v", err),
+			Code:    0,
+		}
+	}
+	return decode(result, target)
+}
+
+// Close shuts Chrome down
+func (b *Browser) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.cancel != nil {
+		b.cancel()
+		b.ctx, b.cancel = nil, nil
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+// Package browser fetches Instagram's JSON APIs through a headless Chrome
+// session, as a last-resort compatibility mode for when the scraper's own
+// requests are blocked.
+//
+// The browser is logged in with the stored session cookies and loads
+// instagram.com once, then requests each API URL with fetch() from inside
+// the page, so requests carry the browser's own fingerprint and headers.
+//
+// Driving Chrome needs the chromedp module, which is only compiled in with
+// the chromedp build tag:
+//
+//	go get github.com/chromedp/chromedp
+//	go build -tags chromedp ./cmd/igscraper
+//
+// Without the tag, New returns ErrUnsupported.
+//
+// Usage:
+//
+//	b, err := browser.New(browser.Options{
+//	    SessionID: sessionID,
+//	    CSRFToken: csrfToken,
+//	})
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer b.Close()
+//
+//	var resp instagram.InstagramResponse
+//	err = b.GetJSON(instagram.GetProfileURL("username"), &resp)
+package browser
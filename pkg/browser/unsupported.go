@@ -0,0 +1,24 @@
+//go:build !chromedp
+
+package browser
+
+// Available reports whether this build can drive a headless browser
+const Available = false
+
+// Browser is unavailable in builds without the chromedp build tag
+type Browser struct{}
+
+// New returns ErrUnsupported; rebuild with -tags chromedp to use a browser
+func New(opts Options) (*Browser, error) {
+	return nil, ErrUnsupported
+}
+
+// GetJSON returns ErrUnsupported
+func (b *Browser) GetJSON(apiURL string, target interface{}) error {
+	return ErrUnsupported
+}
+
+// Close does nothing
+func (b *Browser) Close() error {
+	return nil
+}
@@ -4,12 +4,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"maps"
 	"os"
 	"path/filepath"
-	"runtime"
+	"slices"
+	"sync"
 	"time"
 
 	"igscraper/pkg/logger"
+	"igscraper/pkg/platformdirs"
 )
 
 // Checkpoint represents the state of a download session
@@ -21,20 +24,71 @@ type Checkpoint struct {
 	DownloadedPhotos map[string]string `json:"downloaded_photos"` // shortcode -> filename
 	TotalQueued      int               `json:"total_queued"`
 	TotalDownloaded  int               `json:"total_downloaded"`
+	Skipped          map[string]int    `json:"skipped,omitempty"` // reason -> posts skipped before EndCursor
+	TotalPhotos      int               `json:"total_photos,omitempty"`     // the profile's post count, 0 if unknown
+	BytesDownloaded  int64             `json:"bytes_downloaded,omitempty"` // size of the downloads in DownloadedPhotos
+	Files            map[string]FileDigest `json:"files,omitempty"`         // shortcode -> saved file's size and hash
+	Pages            []PageStats       `json:"pages,omitempty"`            // one entry per page before EndCursor
+	Visited          []string          `json:"visited,omitempty"`          // shortcodes of the posts before EndCursor, for feeds that track them
+	ScrapeID         string            `json:"scrape_id,omitempty"`        // the scrape that last saved the checkpoint
 	CreatedAt        time.Time         `json:"created_at"`
 	UpdatedAt        time.Time         `json:"updated_at"`
 	Version          int               `json:"version"`
 }
 
-// Manager handles checkpoint operations
+// PageStats records what happened to the posts of one processed page
+type PageStats struct {
+	Page    int `json:"page"`
+	Posts   int `json:"posts"`   // posts on the page
+	Queued  int `json:"queued"`  // posts queued for download
+	Skipped int `json:"skipped"` // posts skipped, including those already downloaded
+}
+
+// FileDigest is the size and SHA-256 of a downloaded file as it was saved, so
+// that a later change to the file can be detected
+type FileDigest struct {
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// ScannedPosts returns the number of posts on the pages processed so far
+func (checkpoint *Checkpoint) ScannedPosts() int {
+	total := 0
+	for _, page := range checkpoint.Pages {
+		total += page.Posts
+	}
+	return total
+}
+
+// Default flush policy of AddDownload
+const (
+	DefaultFlushEvery    = 50
+	DefaultFlushInterval = 10 * time.Second
+)
+
+// Manager handles checkpoint operations. Progress updates and downloads may
+// be recorded from different goroutines, each holding its own copy of the
+// checkpoint; the manager merges them so neither overwrites the other.
+// Downloads are kept in memory and written in batches, see AddDownload.
 type Manager struct {
 	checkpointPath string
 	logger         logger.Logger
+	scrapeID       string
+	mu             sync.Mutex
+
+	// current is the checkpoint as last saved plus the downloads added
+	// since, which unflushed lists
+	current       *Checkpoint
+	unflushed     []string
+	lastFlush     time.Time
+	flushEvery    int
+	flushInterval time.Duration
+	onFlush       func(shortcodes []string)
 }
 
 // NewManager creates a new checkpoint manager
 func NewManager(username string) (*Manager, error) {
-	dataDir, err := getDataDirectory()
+	dataDir, err := DataDirectory()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get data directory: %w", err)
 	}
@@ -50,10 +104,45 @@ func NewManager(username string) (*Manager, error) {
 
 	return &Manager{
 		checkpointPath: checkpointPath,
-		logger:         logger.GetLogger(),
+		logger:         logger.Named(logger.ComponentCheckpoint),
+		lastFlush:      time.Now(),
+		flushEvery:     DefaultFlushEvery,
+		flushInterval:  DefaultFlushInterval,
 	}, nil
 }
 
+// SetFlushPolicy makes AddDownload write the checkpoint once every downloads
+// were added, or once interval passed since it was last written, whichever
+// comes first. Setting every to 1 writes each download.
+func (m *Manager) SetFlushPolicy(every int, interval time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.flushEvery = max(every, 1)
+	m.flushInterval = interval
+}
+
+// OnFlush registers fn to be called with the shortcodes of the downloads
+// added by AddDownload each time they are written to disk. It is called
+// with the manager's lock held, so it must not call the manager.
+func (m *Manager) OnFlush(fn func(shortcodes []string)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onFlush = fn
+}
+
+// SetScrapeID records id in the checkpoints saved from now on and in the
+// manager's log lines, to match them with the log lines of the scrape
+func (m *Manager) SetScrapeID(id string) {
+	m.scrapeID = id
+	m.logger = logger.Named(logger.ComponentCheckpoint).WithField(logger.FieldScrapeID, id)
+}
+
+// SetLogger replaces the manager's logger, which is the global checkpoint
+// logger by default
+func (m *Manager) SetLogger(log logger.Logger) {
+	m.logger = log
+}
+
 // Create creates a new checkpoint
 func (m *Manager) Create(username, userID string) (*Checkpoint, error) {
 	checkpoint := &Checkpoint{
@@ -83,6 +172,24 @@ func (m *Manager) Create(username, userID string) (*Checkpoint, error) {
 
 // Load loads an existing checkpoint
 func (m *Manager) Load() (*Checkpoint, error) {
+	checkpoint, err := m.load()
+	if err != nil || checkpoint == nil {
+		return nil, err
+	}
+
+	m.logger.InfoWithFields("Checkpoint loaded", map[string]interface{}{
+		"username":         checkpoint.Username,
+		"total_downloaded": checkpoint.TotalDownloaded,
+		"last_cursor":      checkpoint.EndCursor,
+		"updated_at":       checkpoint.UpdatedAt,
+		"saved_by":         checkpoint.ScrapeID,
+	})
+
+	return checkpoint, nil
+}
+
+// load reads the checkpoint file, returning nil if there is none
+func (m *Manager) load() (*Checkpoint, error) {
 	file, err := os.Open(m.checkpointPath)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -96,20 +203,15 @@ func (m *Manager) Load() (*Checkpoint, error) {
 	if err := json.NewDecoder(file).Decode(&checkpoint); err != nil {
 		return nil, fmt.Errorf("failed to decode checkpoint: %w", err)
 	}
-
-	m.logger.InfoWithFields("Checkpoint loaded", map[string]interface{}{
-		"username":         checkpoint.Username,
-		"total_downloaded": checkpoint.TotalDownloaded,
-		"last_cursor":      checkpoint.EndCursor,
-		"updated_at":       checkpoint.UpdatedAt,
-	})
-
 	return &checkpoint, nil
 }
 
 // Save saves the checkpoint to disk atomically
 func (m *Manager) Save(checkpoint *Checkpoint) error {
 	checkpoint.UpdatedAt = time.Now()
+	if m.scrapeID != "" {
+		checkpoint.ScrapeID = m.scrapeID
+	}
 
 	// Create temporary file
 	tempPath := m.checkpointPath + ".tmp"
@@ -151,27 +253,78 @@ func (m *Manager) Save(checkpoint *Checkpoint) error {
 		"last_cursor":      checkpoint.EndCursor,
 	})
 
+	// The copy AddDownload adds to is what was saved, which holds every
+	// download added so far
+	if checkpoint != m.current {
+		m.current = checkpoint.clone()
+	}
+	flushed := m.unflushed
+	m.unflushed = nil
+	m.lastFlush = time.Now()
+	if m.onFlush != nil && len(flushed) > 0 {
+		m.onFlush(flushed)
+	}
 	return nil
 }
 
 // Delete removes the checkpoint file
 func (m *Manager) Delete() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if err := os.Remove(m.checkpointPath); err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("failed to delete checkpoint: %w", err)
 	}
+	m.current = nil
+	m.unflushed = nil
 
 	m.logger.Info("Checkpoint deleted")
 	return nil
 }
 
+// Rename moves the checkpoint kept under oldName to newName, such as after a
+// profile's username changed, so the download can still be resumed. It does
+// nothing if there is no checkpoint under oldName, and fails if there is one
+// under newName already.
+func Rename(oldName, newName string) error {
+	from, err := NewManager(oldName)
+	if err != nil {
+		return err
+	}
+	checkpoint, err := from.Load()
+	if err != nil || checkpoint == nil {
+		return err
+	}
+	to, err := NewManager(newName)
+	if err != nil {
+		return err
+	}
+	if to.Exists() {
+		return fmt.Errorf("a checkpoint for %s already exists", newName)
+	}
+
+	checkpoint.Username = newName
+	if err := to.Save(checkpoint); err != nil {
+		return err
+	}
+	return from.Delete()
+}
+
 // Exists checks if a checkpoint file exists
 func (m *Manager) Exists() bool {
 	_, err := os.Stat(m.checkpointPath)
 	return err == nil
 }
 
-// UpdateProgress updates the checkpoint with current progress
+// UpdateProgress updates the checkpoint with current progress. Downloads
+// added since checkpoint was loaded, written yet or not, are kept.
 func (m *Manager) UpdateProgress(checkpoint *Checkpoint, endCursor string, pageNum int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if saved, err := m.latest(); err == nil && saved != nil {
+		checkpoint.mergeDownloads(saved)
+	}
 	checkpoint.EndCursor = endCursor
 	checkpoint.LastProcessedPage = pageNum
 	return m.Save(checkpoint)
@@ -179,11 +332,105 @@ func (m *Manager) UpdateProgress(checkpoint *Checkpoint, endCursor string, pageN
 
 // RecordDownload records a successfully downloaded photo
 func (m *Manager) RecordDownload(checkpoint *Checkpoint, shortcode, filename string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.current != nil {
+		checkpoint.mergeDownloads(m.current)
+	}
 	checkpoint.DownloadedPhotos[shortcode] = filename
 	checkpoint.TotalDownloaded++
 	return m.Save(checkpoint)
 }
 
+// AddDownload records a successfully downloaded photo of size bytes in the
+// saved checkpoint, keeping the progress saved by UpdateProgress, along with
+// the digest of its file unless that is empty. It does nothing if there is
+// no saved checkpoint. Rather than rewriting the file for each download, it
+// is written as set by SetFlushPolicy, by UpdateProgress and by Flush.
+func (m *Manager) AddDownload(shortcode, filename string, size int64, digest FileDigest) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	checkpoint, err := m.latest()
+	if err != nil || checkpoint == nil {
+		return err
+	}
+	m.current = checkpoint
+	if checkpoint.DownloadedPhotos == nil {
+		checkpoint.DownloadedPhotos = make(map[string]string)
+	}
+	if _, exists := checkpoint.DownloadedPhotos[shortcode]; !exists {
+		checkpoint.TotalDownloaded++
+		checkpoint.BytesDownloaded += size
+	}
+	checkpoint.DownloadedPhotos[shortcode] = filename
+	if digest.SHA256 != "" {
+		if checkpoint.Files == nil {
+			checkpoint.Files = make(map[string]FileDigest)
+		}
+		checkpoint.Files[shortcode] = digest
+	}
+	m.unflushed = append(m.unflushed, shortcode)
+	if len(m.unflushed) >= m.flushEvery || time.Since(m.lastFlush) >= m.flushInterval {
+		return m.Save(checkpoint)
+	}
+	return nil
+}
+
+// Flush writes the downloads added since the checkpoint was last written
+func (m *Manager) Flush() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.current == nil || len(m.unflushed) == 0 {
+		return nil
+	}
+	return m.Save(m.current)
+}
+
+// latest returns the checkpoint with the downloads added so far, reading it
+// from disk if none were. It is nil if there is no saved checkpoint.
+func (m *Manager) latest() (*Checkpoint, error) {
+	if m.current != nil {
+		return m.current, nil
+	}
+	return m.load()
+}
+
+// clone returns a copy of checkpoint that shares no maps or slices with it
+func (checkpoint *Checkpoint) clone() *Checkpoint {
+	c := *checkpoint
+	c.DownloadedPhotos = maps.Clone(checkpoint.DownloadedPhotos)
+	c.Files = maps.Clone(checkpoint.Files)
+	c.Skipped = maps.Clone(checkpoint.Skipped)
+	c.Pages = slices.Clone(checkpoint.Pages)
+	c.Visited = slices.Clone(checkpoint.Visited)
+	return &c
+}
+
+// mergeDownloads adds the downloads recorded in saved that checkpoint lacks
+func (checkpoint *Checkpoint) mergeDownloads(saved *Checkpoint) {
+	if checkpoint.DownloadedPhotos == nil {
+		checkpoint.DownloadedPhotos = make(map[string]string)
+	}
+	for shortcode, filename := range saved.DownloadedPhotos {
+		if _, exists := checkpoint.DownloadedPhotos[shortcode]; !exists {
+			checkpoint.DownloadedPhotos[shortcode] = filename
+		}
+	}
+	for shortcode, digest := range saved.Files {
+		if _, exists := checkpoint.Files[shortcode]; !exists {
+			if checkpoint.Files == nil {
+				checkpoint.Files = make(map[string]FileDigest)
+			}
+			checkpoint.Files[shortcode] = digest
+		}
+	}
+	checkpoint.TotalDownloaded = max(checkpoint.TotalDownloaded, saved.TotalDownloaded)
+	checkpoint.BytesDownloaded = max(checkpoint.BytesDownloaded, saved.BytesDownloaded)
+}
+
 // IsPhotoDownloaded checks if a photo has already been downloaded
 func (checkpoint *Checkpoint) IsPhotoDownloaded(shortcode string) bool {
 	_, exists := checkpoint.DownloadedPhotos[shortcode]
@@ -203,6 +450,8 @@ func (m *Manager) GetCheckpointInfo() (map[string]interface{}, error) {
 	return map[string]interface{}{
 		"username":          checkpoint.Username,
 		"total_downloaded":  checkpoint.TotalDownloaded,
+		"total_photos":      checkpoint.TotalPhotos,
+		"bytes_downloaded":  checkpoint.BytesDownloaded,
 		"last_cursor":       checkpoint.EndCursor,
 		"created_at":        checkpoint.CreatedAt,
 		"updated_at":        checkpoint.UpdatedAt,
@@ -239,38 +488,13 @@ func (m *Manager) BackupCheckpoint() error {
 	return nil
 }
 
-// getDataDirectory returns the appropriate data directory for the current OS
-func getDataDirectory() (string, error) {
-	var dataDir string
-
-	switch runtime.GOOS {
-	case "linux":
-		// Use XDG_DATA_HOME if set, otherwise ~/.local/share
-		if xdgDataHome := os.Getenv("XDG_DATA_HOME"); xdgDataHome != "" {
-			dataDir = filepath.Join(xdgDataHome, "igscraper")
-		} else {
-			home, err := os.UserHomeDir()
-			if err != nil {
-				return "", err
-			}
-			dataDir = filepath.Join(home, ".local", "share", "igscraper")
-		}
-	case "darwin":
-		// macOS: ~/Library/Application Support
-		home, err := os.UserHomeDir()
-		if err != nil {
-			return "", err
-		}
-		dataDir = filepath.Join(home, "Library", "Application Support", "igscraper")
-	case "windows":
-		// Windows: %APPDATA%
-		appData := os.Getenv("APPDATA")
-		if appData == "" {
-			return "", fmt.Errorf("APPDATA environment variable not set")
-		}
-		dataDir = filepath.Join(appData, "igscraper")
-	default:
-		return "", fmt.Errorf("unsupported operating system: %s", runtime.GOOS)
+// DataDirectory returns the data directory of the current OS, where
+// checkpoints and other state kept between runs are stored, creating it if
+// needed
+func DataDirectory() (string, error) {
+	dataDir, err := platformdirs.DataDir()
+	if err != nil {
+		return "", err
 	}
 
 	// Create the data directory if it doesn't exist
@@ -3,6 +3,7 @@ package checkpoint
 import (
 	"os"
 	"testing"
+	"time"
 )
 
 func TestCheckpointManager(t *testing.T) {
@@ -210,9 +211,129 @@ func TestCheckpointManager(t *testing.T) {
 	})
 }
 
+func TestRename(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	oldMgr, err := NewManager("oldname")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	cp, err := oldMgr.Create("oldname", "12345")
+	if err != nil {
+		t.Fatalf("Failed to create checkpoint: %v", err)
+	}
+	if err := oldMgr.UpdateProgress(cp, "cursor", 3); err != nil {
+		t.Fatalf("Failed to update checkpoint: %v", err)
+	}
+
+	if err := Rename("oldname", "newname"); err != nil {
+		t.Fatalf("Failed to rename checkpoint: %v", err)
+	}
+	if oldMgr.Exists() {
+		t.Error("Checkpoint still exists under the old name")
+	}
+	newMgr, _ := NewManager("newname")
+	loaded, err := newMgr.Load()
+	if err != nil || loaded == nil {
+		t.Fatalf("Failed to load renamed checkpoint: %v", err)
+	}
+	if loaded.Username != "newname" || loaded.UserID != "12345" || loaded.EndCursor != "cursor" || loaded.LastProcessedPage != 3 {
+		t.Errorf("Unexpected renamed checkpoint: %+v", loaded)
+	}
+
+	// Nothing to move
+	if err := Rename("oldname", "newname"); err != nil {
+		t.Errorf("Renaming a missing checkpoint failed: %v", err)
+	}
+
+	// An existing checkpoint is never overwritten
+	if _, err := oldMgr.Create("oldname", "67890"); err != nil {
+		t.Fatalf("Failed to create checkpoint: %v", err)
+	}
+	if err := Rename("oldname", "newname"); err == nil {
+		t.Error("Expected an error when a checkpoint exists under the new name")
+	}
+}
+
+func TestAddDownload(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	mgr, err := NewManager("testuser")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	cp, err := mgr.Create("testuser", "12345")
+	if err != nil {
+		t.Fatalf("Failed to create checkpoint: %v", err)
+	}
+	cp.TotalPhotos = 40
+
+	// Downloads are recorded while the feed holds an older copy
+	if err := mgr.AddDownload("ABC123", "ABC123.jpg", 1000, FileDigest{Size: 1000, SHA256: "9f86d081884c7d65"}); err != nil {
+		t.Fatalf("Failed to add download: %v", err)
+	}
+	if err := mgr.AddDownload("DEF456", "DEF456.jpg", 500, FileDigest{}); err != nil {
+		t.Fatalf("Failed to add download: %v", err)
+	}
+	if err := mgr.AddDownload("DEF456", "DEF456.jpg", 500, FileDigest{}); err != nil {
+		t.Fatalf("Failed to add download: %v", err)
+	}
+	cp.Pages = append(cp.Pages, PageStats{Page: 1, Posts: 12, Queued: 10, Skipped: 2})
+	if err := mgr.UpdateProgress(cp, "cursor", 1); err != nil {
+		t.Fatalf("Failed to update progress: %v", err)
+	}
+
+	loaded, err := mgr.Load()
+	if err != nil || loaded == nil {
+		t.Fatalf("Failed to load checkpoint: %v", err)
+	}
+	if loaded.TotalDownloaded != 2 || loaded.BytesDownloaded != 1500 || !loaded.IsPhotoDownloaded("DEF456") {
+		t.Errorf("Downloads were lost by the progress update: %+v", loaded)
+	}
+	if len(loaded.Files) != 1 || loaded.Files["ABC123"] != (FileDigest{Size: 1000, SHA256: "9f86d081884c7d65"}) {
+		t.Errorf("Expected the digest of ABC123 only, got %+v", loaded.Files)
+	}
+	if loaded.TotalPhotos != 40 || loaded.EndCursor != "cursor" || loaded.ScannedPosts() != 12 {
+		t.Errorf("Progress was lost by the downloads: %+v", loaded)
+	}
+}
+
+func TestScrapeID(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	first, err := NewManager("testuser")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	first.SetScrapeID("aaaa")
+	if _, err := first.Create("testuser", "12345"); err != nil {
+		t.Fatalf("Failed to create checkpoint: %v", err)
+	}
+
+	// A resumed scrape records itself on its first save
+	second, err := NewManager("testuser")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	second.SetScrapeID("bbbb")
+	cp, err := second.Load()
+	if err != nil || cp == nil {
+		t.Fatalf("Failed to load checkpoint: %v", err)
+	}
+	if cp.ScrapeID != "aaaa" {
+		t.Errorf("Loaded checkpoint was saved by %q, want aaaa", cp.ScrapeID)
+	}
+	if err := second.UpdateProgress(cp, "cursor", 1); err != nil {
+		t.Fatalf("Failed to update progress: %v", err)
+	}
+	if cp, _ := second.Load(); cp == nil || cp.ScrapeID != "bbbb" {
+		t.Errorf("Updated checkpoint = %+v, want scrape ID bbbb", cp)
+	}
+}
+
 func TestGetDataDirectory(t *testing.T) {
 	// Test actual implementation
-	dir, err := getDataDirectory()
+	dir, err := DataDirectory()
 	if err != nil {
 		t.Fatalf("Failed to get data directory: %v", err)
 	}
@@ -227,4 +348,59 @@ func TestGetDataDirectory(t *testing.T) {
 	if err != nil {
 		t.Errorf("Cannot create data directory: %v", err)
 	}
-}
\ No newline at end of file
+}
+func TestAddDownloadBatching(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	mgr, err := NewManager("testuser")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	if _, err := mgr.Create("testuser", "12345"); err != nil {
+		t.Fatalf("Failed to create checkpoint: %v", err)
+	}
+	mgr.SetFlushPolicy(3, time.Hour)
+	var flushed []string
+	mgr.OnFlush(func(shortcodes []string) {
+		flushed = append(flushed, shortcodes...)
+	})
+
+	saved := func() *Checkpoint {
+		reader, err := NewManager("testuser")
+		if err != nil {
+			t.Fatalf("Failed to create manager: %v", err)
+		}
+		cp, err := reader.Load()
+		if err != nil || cp == nil {
+			t.Fatalf("Failed to load checkpoint: %v", err)
+		}
+		return cp
+	}
+
+	// Downloads stay in memory until the batch is full
+	for _, shortcode := range []string{"A1", "B2"} {
+		if err := mgr.AddDownload(shortcode, shortcode+".jpg", 100, FileDigest{}); err != nil {
+			t.Fatalf("Failed to add download: %v", err)
+		}
+	}
+	if cp := saved(); cp.TotalDownloaded != 0 || len(flushed) != 0 {
+		t.Errorf("Expected nothing written yet, got %d downloads, flushed %v", cp.TotalDownloaded, flushed)
+	}
+	if err := mgr.AddDownload("C3", "C3.jpg", 100, FileDigest{}); err != nil {
+		t.Fatalf("Failed to add download: %v", err)
+	}
+	if cp := saved(); cp.TotalDownloaded != 3 || cp.BytesDownloaded != 300 || len(flushed) != 3 {
+		t.Errorf("Expected the batch written, got %d downloads, flushed %v", cp.TotalDownloaded, flushed)
+	}
+
+	// Flush writes what is left
+	if err := mgr.AddDownload("D4", "D4.jpg", 100, FileDigest{}); err != nil {
+		t.Fatalf("Failed to add download: %v", err)
+	}
+	if err := mgr.Flush(); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+	if cp := saved(); cp.TotalDownloaded != 4 || !cp.IsPhotoDownloaded("D4") || len(flushed) != 4 {
+		t.Errorf("Expected all downloads written, got %+v, flushed %v", cp, flushed)
+	}
+}
@@ -6,8 +6,9 @@
 //   - Downloaded photos (to avoid duplicates)
 //   - Overall progress statistics
 //
-// Checkpoints are stored in platform-specific data directories:
-//   - Linux: ~/.local/share/igscraper/checkpoints/
+// Checkpoints are stored in the checkpoints folder of the platform's data
+// directory, as located by the platformdirs package:
+//   - Linux: $XDG_DATA_HOME/igscraper/checkpoints/, or ~/.local/share/igscraper/checkpoints/
 //   - macOS: ~/Library/Application Support/igscraper/checkpoints/
 //   - Windows: %APPDATA%/igscraper/checkpoints/
 //
@@ -3,17 +3,26 @@ package config
 import (
 	"errors"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
 	"gopkg.in/yaml.v3"
+
+	"igscraper/pkg/platformdirs"
+	"igscraper/pkg/postprocess"
+	"igscraper/pkg/ratelimit"
 )
 
 // Config holds all configuration options for the Instagram scraper
 type Config struct {
+	// Layout of the config file, see CurrentVersion
+	Version int `yaml:"version" json:"version"`
+	
 	// Instagram credentials
 	Instagram InstagramConfig `yaml:"instagram" json:"instagram"`
 	
@@ -23,6 +32,12 @@ type Config struct {
 	// Retry configuration
 	Retry RetryConfig `yaml:"retry" json:"retry"`
 	
+	// API response cache
+	Cache CacheConfig `yaml:"cache" json:"cache"`
+	
+	// HTTP connection settings
+	Transport TransportConfig `yaml:"transport" json:"transport"`
+	
 	// Output settings
 	Output OutputConfig `yaml:"output" json:"output"`
 	
@@ -32,8 +47,21 @@ type Config struct {
 	// Notification preferences
 	Notifications NotificationConfig `yaml:"notifications" json:"notifications"`
 	
+	// Commands run at points of a scrape
+	Hooks HooksConfig `yaml:"hooks" json:"hooks"`
+	
 	// Logging configuration
 	Logging LoggingConfig `yaml:"logging" json:"logging"`
+	
+	// Daemon scheduler configuration
+	Daemon DaemonConfig `yaml:"daemon" json:"daemon"`
+	
+	// Named presets from the profiles section of the config file, each
+	// holding settings that UseProfile merges over the rest of the file
+	profiles map[string]yaml.Node
+	
+	// Settings of an older layout the config file was migrated from
+	deprecated []Deprecation
 }
 
 // InstagramConfig holds Instagram-specific configuration
@@ -42,6 +70,25 @@ type InstagramConfig struct {
 	CSRFToken  string `yaml:"csrf_token" json:"csrf_token"`
 	UserAgent  string `yaml:"user_agent" json:"user_agent"`
 	APIVersion string `yaml:"api_version" json:"api_version"`
+	
+	// Device fingerprint of the stored account in use, set from its device
+	// profile rather than from the config file
+	AppID          string `yaml:"-" json:"-"`
+	AcceptLanguage string `yaml:"-" json:"-"`
+	DeviceID       string `yaml:"-" json:"-"` // ig_did cookie
+	MachineID      string `yaml:"-" json:"-"` // mid cookie
+	
+	// Account rotation across the stored accounts during long scrapes
+	RotateAccounts bool `yaml:"rotate_accounts" json:"rotate_accounts"`
+	RotateAfter    int  `yaml:"rotate_after" json:"rotate_after"` // consecutive 429/401 responses before switching
+	
+	// Headless browser fallback for blocked API calls, in builds with the chromedp tag
+	BrowserFallback bool   `yaml:"browser_fallback" json:"browser_fallback"`
+	BrowserPath     string `yaml:"browser_path" json:"browser_path"` // Chrome or Chromium binary, searched for if empty
+	
+	// How often a long scrape checks that the session is still logged in,
+	// 0 to never check
+	SessionKeepAlive time.Duration `yaml:"session_keep_alive" json:"session_keep_alive"`
 }
 
 // RateLimitConfig holds rate limiting configuration
@@ -51,6 +98,14 @@ type RateLimitConfig struct {
 	BackoffMultiplier float64       `yaml:"backoff_multiplier" json:"backoff_multiplier"`
 	MaxRetries        int           `yaml:"max_retries" json:"max_retries"`
 	RetryDelay        time.Duration `yaml:"retry_delay" json:"retry_delay"`
+	
+	// File holding a request budget shared by every igscraper process that
+	// names it, such as several instances using the same account
+	SharedFile string `yaml:"shared_file" json:"shared_file"`
+	
+	// Keep each account's remaining budget between runs, so a restart does
+	// not begin with a full one
+	PersistState bool `yaml:"persist_state" json:"persist_state"`
 }
 
 // RetryConfig holds retry and backoff configuration
@@ -74,25 +129,133 @@ type RetryConfig struct {
 	
 	ServerErrorRetries   int           `yaml:"server_error_retries" json:"server_error_retries"`
 	ServerErrorBaseDelay time.Duration `yaml:"server_error_base_delay" json:"server_error_base_delay"`
+	
+	// Policies for individual endpoint classes: profile, media_page and
+	// photo_download. Settings left out keep the values above.
+	Endpoints map[string]EndpointRetryConfig `yaml:"endpoints" json:"endpoints"`
+}
+
+// EndpointRetryConfig overrides the retry settings for one endpoint class.
+// Zero values keep the general setting.
+type EndpointRetryConfig struct {
+	MaxAttempts  int           `yaml:"max_attempts" json:"max_attempts"`
+	BaseDelay    time.Duration `yaml:"base_delay" json:"base_delay"`
+	MaxDelay     time.Duration `yaml:"max_delay" json:"max_delay"`
+	Multiplier   float64       `yaml:"multiplier" json:"multiplier"`
+	JitterFactor float64       `yaml:"jitter_factor" json:"jitter_factor"`
+	
+	// Error types to retry, such as network, rate_limit or server_error.
+	// Empty keeps the class's default predicate.
+	RetryOn []string `yaml:"retry_on" json:"retry_on"`
+}
+
+// CacheConfig holds the on-disk cache of profile and media page responses,
+// which saves repeated, resumed and dry-run scrapes from fetching pages again
+type CacheConfig struct {
+	Enabled   bool          `yaml:"enabled" json:"enabled"`
+	TTL       time.Duration `yaml:"ttl" json:"ttl"`             // how long a response is served from the cache
+	Directory string        `yaml:"directory" json:"directory"` // defaults to the platform's cache directory
+}
+
+// TransportConfig tunes the HTTP connections used for API requests and
+// downloads. Zero values keep Go's defaults.
+type TransportConfig struct {
+	MaxIdleConns        int           `yaml:"max_idle_conns" json:"max_idle_conns"`
+	MaxIdleConnsPerHost int           `yaml:"max_idle_conns_per_host" json:"max_idle_conns_per_host"` // keep at least concurrent_downloads so connections are reused
+	MaxConnsPerHost     int           `yaml:"max_conns_per_host" json:"max_conns_per_host"`           // 0 for no limit
+	IdleConnTimeout     time.Duration `yaml:"idle_conn_timeout" json:"idle_conn_timeout"`
+	DialTimeout         time.Duration `yaml:"dial_timeout" json:"dial_timeout"`
+	KeepAlive           time.Duration `yaml:"keep_alive" json:"keep_alive"`
+	TLSHandshakeTimeout time.Duration `yaml:"tls_handshake_timeout" json:"tls_handshake_timeout"`
+	TLSMinVersion       string        `yaml:"tls_min_version" json:"tls_min_version"` // "1.2" or "1.3", empty for Go's default
+	DisableHTTP2        bool          `yaml:"disable_http2" json:"disable_http2"`
+	LocalAddress        string        `yaml:"local_address" json:"local_address"` // local IP to send requests from, empty for any
 }
 
 // OutputConfig holds output directory configuration
 type OutputConfig struct {
 	BaseDirectory     string `yaml:"base_directory" json:"base_directory"`
 	CreateUserFolders bool   `yaml:"create_user_folders" json:"create_user_folders"`
+	RenameFolders     bool   `yaml:"rename_folders" json:"rename_folders"` // move a profile's folder when its username changes, instead of keeping the old name
+	LinkFolders       bool   `yaml:"link_folders" json:"link_folders"`     // link the folder of a renamed profile under its other username too
 	FileNamePattern   string `yaml:"file_name_pattern" json:"file_name_pattern"`
-	OverwriteExisting bool   `yaml:"overwrite_existing" json:"overwrite_existing"`
+	OverwriteExisting bool   `yaml:"overwrite_existing" json:"overwrite_existing"` // download saved posts again, replacing their files
+
+	// GalleryThumbnails is the longest side in pixels of the small copy of
+	// each photo saved in .thumbnails/ for gallery browsing; 0 disables
+	GalleryThumbnails int `yaml:"gallery_thumbnails" json:"gallery_thumbnails"`
+
+	// RefreshNewerThan downloads saved posts taken within this long again
+	// when their caption, alt text, location or tags changed since they
+	// were saved; 0 disables
+	RefreshNewerThan time.Duration `yaml:"refresh_newer_than" json:"refresh_newer_than"`
+
+	// ExistingArchive is what a scrape does when its profile is already
+	// archived in another folder, as recorded in the data directory: "warn"
+	// about it, or "use" that folder instead of starting a new archive
+	ExistingArchive string `yaml:"existing_archive" json:"existing_archive"`
 }
 
 // DownloadConfig holds download-specific configuration
 type DownloadConfig struct {
 	ConcurrentDownloads int           `yaml:"concurrent_downloads" json:"concurrent_downloads"`
 	DownloadTimeout     time.Duration `yaml:"download_timeout" json:"download_timeout"`
-	RetryAttempts       int           `yaml:"retry_attempts" json:"retry_attempts"`
+	JobTimeout          time.Duration `yaml:"job_timeout" json:"job_timeout"`           // longest one download may take, retries included, before it is queued again; 0 for no limit
+	ShutdownTimeout     time.Duration `yaml:"shutdown_timeout" json:"shutdown_timeout"` // wait for downloads in progress on Ctrl+C or SIGTERM
+	RetryAttempts       int           `yaml:"retry_attempts" json:"retry_attempts"` // times a failed download is queued again
+	Order               string        `yaml:"order" json:"order"`                   // queued downloads by post date: newest or oldest first
 	SkipVideos          bool          `yaml:"skip_videos" json:"skip_videos"`
 	SkipImages          bool          `yaml:"skip_images" json:"skip_images"`
-	MinFileSize         int64         `yaml:"min_file_size" json:"min_file_size"`
-	MaxFileSize         int64         `yaml:"max_file_size" json:"max_file_size"`
+	MinFileSize         int64         `yaml:"min_file_size" json:"min_file_size"` // skip files smaller than this many bytes, 0 for no limit
+	MaxFileSize         int64         `yaml:"max_file_size" json:"max_file_size"` // skip files larger than this many bytes, 0 for no limit
+	SkipSyncedWithin    time.Duration `yaml:"skip_synced_within" json:"skip_synced_within"` // batch/daemon only, 0 disables
+	Since               time.Time     `yaml:"since,omitempty" json:"since,omitempty"`           // only media taken at or after this time
+	Until               time.Time     `yaml:"until,omitempty" json:"until,omitempty"`           // only media taken at or before this time
+	Filter              string        `yaml:"filter,omitempty" json:"filter,omitempty"`         // filter expression, see pkg/filter
+	MinLikes            int           `yaml:"min_likes" json:"min_likes"`                       // skip posts with fewer likes, 0 for none
+	MaxPosts            int           `yaml:"max_posts" json:"max_posts"`                       // stop once this many posts are queued, 0 for no limit
+	MaxPages            int           `yaml:"max_pages" json:"max_pages"`                       // stop after this many pages of the feed, 0 for no limit
+	EmbedMetadata       bool          `yaml:"embed_metadata" json:"embed_metadata"`             // write caption, author, URL and date into EXIF/XMP
+	Dedup               bool          `yaml:"dedup" json:"dedup"`                               // hardlink byte-identical photos via a SHA-256 index
+	VideoChunks         int           `yaml:"video_chunks" json:"video_chunks"`                 // parallel ranged requests per large video, below 2 disables
+	ChunkMinSize        int64         `yaml:"chunk_min_size" json:"chunk_min_size"`             // smallest video in bytes downloaded in chunks
+	Comments            bool          `yaml:"comments" json:"comments"`                         // save each post's comments to comments/<shortcode>.json
+	MaxComments         int           `yaml:"max_comments" json:"max_comments"`                 // comments saved per post, bounds the API requests
+	MaxBandwidth        string        `yaml:"max_bandwidth" json:"max_bandwidth"`               // media download limit such as "5MB/s", empty for none
+	MinFreeSpace        int64         `yaml:"min_free_space" json:"min_free_space"`             // bytes left free on the disk, downloads pause below it
+	MaxTotalSize        int64         `yaml:"max_total_size" json:"max_total_size"`             // quota in bytes on the output directory, 0 for none
+	PreferredResolution int           `yaml:"preferred_resolution" json:"preferred_resolution"` // photo width in pixels to download, 0 for the largest
+	PrefetchPages       int           `yaml:"prefetch_pages" json:"prefetch_pages"`             // media pages fetched ahead while earlier ones download, 0 disables
+
+	// Steps applied to each photo after it is downloaded, in order
+	PostProcess []postprocess.StepConfig `yaml:"postprocess,omitempty" json:"postprocess,omitempty"`
+	
+	// Adapts the number of concurrent downloads to Instagram's answers
+	Autoscale AutoscaleConfig `yaml:"autoscale" json:"autoscale"`
+}
+
+// AutoscaleConfig bounds the number of concurrent downloads when it adapts
+// to the error rate. Downloads start at concurrent_downloads, gain a worker
+// while they rarely fail and drop to MinWorkers when 429s or 5xxs spike.
+type AutoscaleConfig struct {
+	Enabled    bool `yaml:"enabled" json:"enabled"`
+	MinWorkers int  `yaml:"min_workers" json:"min_workers"`
+	MaxWorkers int  `yaml:"max_workers" json:"max_workers"`
+}
+
+// InDateRange reports whether media taken at t passes the since/until filter.
+// Media without a timestamp always passes.
+func (d DownloadConfig) InDateRange(t time.Time) bool {
+	if t.IsZero() {
+		return true
+	}
+	if !d.Since.IsZero() && t.Before(d.Since) {
+		return false
+	}
+	if !d.Until.IsZero() && t.After(d.Until) {
+		return false
+	}
+	return true
 }
 
 // NotificationConfig holds notification preferences
@@ -103,6 +266,29 @@ type NotificationConfig struct {
 	OnRateLimit       bool   `yaml:"on_rate_limit" json:"on_rate_limit"`
 	ProgressInterval  int    `yaml:"progress_interval" json:"progress_interval"`
 	NotificationType  string `yaml:"notification_type" json:"notification_type"`
+	
+	// Email sent through an SMTP server, for unattended runs
+	Email EmailConfig `yaml:"email" json:"email"`
+}
+
+// EmailConfig holds the SMTP server and recipients of email notifications
+type EmailConfig struct {
+	Enabled  bool     `yaml:"enabled" json:"enabled"`
+	Host     string   `yaml:"host" json:"host"`
+	Port     int      `yaml:"port" json:"port"` // a STARTTLS port such as 587
+	Username string   `yaml:"username" json:"username"`
+	Password string   `yaml:"password" json:"password"`
+	From     string   `yaml:"from" json:"from"`
+	To       []string `yaml:"to" json:"to"`
+}
+
+// HooksConfig holds the commands run at points of a scrape, with the hook's
+// context as JSON on stdin. An empty command runs nothing.
+type HooksConfig struct {
+	PostDownload string        `yaml:"post_download" json:"post_download"` // after every file is saved
+	OnComplete   string        `yaml:"on_complete" json:"on_complete"`     // when a profile or feed is scraped
+	OnError      string        `yaml:"on_error" json:"on_error"`           // when a download or a scrape fails
+	Timeout      time.Duration `yaml:"timeout" json:"timeout"`             // each command is stopped after this long
 }
 
 // LoggingConfig holds logging configuration
@@ -113,14 +299,55 @@ type LoggingConfig struct {
 	MaxBackups int    `yaml:"max_backups" json:"max_backups"`
 	MaxAge     int    `yaml:"max_age" json:"max_age"`
 	Compress   bool   `yaml:"compress" json:"compress"`
+	AuditFile  string `yaml:"audit_file" json:"audit_file"` // JSON-lines log of outbound requests, empty disables
+	Format     string `yaml:"format" json:"format"`         // console or json, for the console output
+	
+	// Keep only some lines of the chatty levels on long scrapes
+	Sampling LogSamplingConfig `yaml:"sampling" json:"sampling"`
+	
+	// Levels of single components, such as instagram: debug, overriding
+	// Level for their lines
+	Levels map[string]string `yaml:"levels" json:"levels"`
+}
+
+// LogSamplingConfig keeps 1 in N log lines of a level; 0 or 1 keeps every
+// line. Errors are never sampled.
+type LogSamplingConfig struct {
+	Debug int `yaml:"debug" json:"debug"`
+	Info  int `yaml:"info" json:"info"`
+	Warn  int `yaml:"warn" json:"warn"`
+}
+
+// DaemonConfig holds the profile schedule used by daemon mode
+type DaemonConfig struct {
+	Profiles        []ProfileSchedule `yaml:"profiles" json:"profiles"`
+	DefaultInterval time.Duration     `yaml:"default_interval" json:"default_interval"`
+	StatusInterval  time.Duration     `yaml:"status_interval" json:"status_interval"`
+	MetricsAddr     string            `yaml:"metrics_addr" json:"metrics_addr"`
+}
+
+// ProfileSchedule describes one profile watched by the daemon
+type ProfileSchedule struct {
+	Username string        `yaml:"username" json:"username"`
+	Interval time.Duration `yaml:"interval" json:"interval"` // 0 uses DefaultInterval
+}
+
+// IntervalFor returns the scrape interval for a profile
+func (d DaemonConfig) IntervalFor(profile ProfileSchedule) time.Duration {
+	if profile.Interval > 0 {
+		return profile.Interval
+	}
+	return d.DefaultInterval
 }
 
 // DefaultConfig returns a Config instance with sensible defaults
 func DefaultConfig() *Config {
 	return &Config{
+		Version: CurrentVersion,
 		Instagram: InstagramConfig{
 			UserAgent:  "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36",
 			APIVersion: "v1",
+			RotateAfter: 3,
 		},
 		RateLimit: RateLimitConfig{
 			RequestsPerMinute: 60,
@@ -128,6 +355,7 @@ func DefaultConfig() *Config {
 			BackoffMultiplier: 2.0,
 			MaxRetries:        3,
 			RetryDelay:        5 * time.Second,
+			PersistState:      true,
 		},
 		Retry: RetryConfig{
 			Enabled:              true,
@@ -143,20 +371,44 @@ func DefaultConfig() *Config {
 			ServerErrorRetries:   3,
 			ServerErrorBaseDelay: 5 * time.Second,
 		},
+		Cache: CacheConfig{
+			TTL: time.Hour,
+		},
+		Transport: TransportConfig{
+			MaxIdleConns:        100,
+			MaxIdleConnsPerHost: 10,
+			IdleConnTimeout:     90 * time.Second,
+			DialTimeout:         30 * time.Second,
+			KeepAlive:           30 * time.Second,
+			TLSHandshakeTimeout: 10 * time.Second,
+		},
 		Output: OutputConfig{
 			BaseDirectory:     "./downloads",
 			CreateUserFolders: true,
+			RenameFolders:     true,
 			FileNamePattern:   "{shortcode}.{ext}",
 			OverwriteExisting: false,
+			ExistingArchive:   "warn",
 		},
 		Download: DownloadConfig{
 			ConcurrentDownloads: 3,
 			DownloadTimeout:     30 * time.Second,
+			JobTimeout:          5 * time.Minute,
+			ShutdownTimeout:     30 * time.Second,
 			RetryAttempts:       3,
+			Order:               "newest",
 			SkipVideos:          false,
 			SkipImages:          false,
 			MinFileSize:         0,
 			MaxFileSize:         0, // 0 means no limit
+			VideoChunks:         4,
+			ChunkMinSize:        64 << 20,
+			MaxComments:         100,
+			MinFreeSpace:        512 << 20,
+			Autoscale: AutoscaleConfig{
+				MinWorkers: 1,
+				MaxWorkers: 10,
+			},
 		},
 		Notifications: NotificationConfig{
 			Enabled:          true,
@@ -165,6 +417,12 @@ func DefaultConfig() *Config {
 			OnRateLimit:      true,
 			ProgressInterval: 10,
 			NotificationType: "terminal",
+			Email: EmailConfig{
+				Port: 587,
+			},
+		},
+		Hooks: HooksConfig{
+			Timeout: time.Minute,
 		},
 		Logging: LoggingConfig{
 			Level:      "info",
@@ -173,6 +431,11 @@ func DefaultConfig() *Config {
 			MaxBackups: 3,
 			MaxAge:     7,
 			Compress:   false,
+			Format:     "console",
+		},
+		Daemon: DaemonConfig{
+			DefaultInterval: 6 * time.Hour,
+			StatusInterval:  5 * time.Minute,
 		},
 	}
 }
@@ -189,6 +452,17 @@ func (c *Config) LoadFromEnv() error {
 	if userAgent := os.Getenv("IGSCRAPER_USER_AGENT"); userAgent != "" {
 		c.Instagram.UserAgent = userAgent
 	}
+	if rotate := os.Getenv("IGSCRAPER_ROTATE_ACCOUNTS"); rotate != "" {
+		c.Instagram.RotateAccounts = strings.ToLower(rotate) == "true"
+	}
+	if fallback := os.Getenv("IGSCRAPER_BROWSER_FALLBACK"); fallback != "" {
+		c.Instagram.BrowserFallback = strings.ToLower(fallback) == "true"
+	}
+	if keepAlive := os.Getenv("IGSCRAPER_SESSION_KEEP_ALIVE"); keepAlive != "" {
+		if d, err := time.ParseDuration(keepAlive); err == nil {
+			c.Instagram.SessionKeepAlive = d
+		}
+	}
 	
 	// Rate limiting
 	if rpm := os.Getenv("IGSCRAPER_REQUESTS_PER_MINUTE"); rpm != "" {
@@ -198,6 +472,9 @@ func (c *Config) LoadFromEnv() error {
 			c.RateLimit.RequestsPerMinute = val
 		}
 	}
+	if sharedFile := os.Getenv("IGSCRAPER_RATE_LIMIT_FILE"); sharedFile != "" {
+		c.RateLimit.SharedFile = sharedFile
+	}
 	
 	// Output directory
 	if outputDir := os.Getenv("IGSCRAPER_OUTPUT_DIR"); outputDir != "" {
@@ -212,16 +489,38 @@ func (c *Config) LoadFromEnv() error {
 			c.Download.ConcurrentDownloads = val
 		}
 	}
+	if bandwidth := os.Getenv("IGSCRAPER_MAX_BANDWIDTH"); bandwidth != "" {
+		c.Download.MaxBandwidth = bandwidth
+	}
+	if prefetch := os.Getenv("IGSCRAPER_PREFETCH_PAGES"); prefetch != "" {
+		var val int
+		fmt.Sscanf(prefetch, "%d", &val)
+		if val >= 0 {
+			c.Download.PrefetchPages = val
+		}
+	}
+	if autoscale := os.Getenv("IGSCRAPER_AUTOSCALE"); autoscale != "" {
+		c.Download.Autoscale.Enabled = strings.ToLower(autoscale) == "true"
+	}
 	
 	// Notifications
 	if notifEnabled := os.Getenv("IGSCRAPER_NOTIFICATIONS_ENABLED"); notifEnabled != "" {
 		c.Notifications.Enabled = strings.ToLower(notifEnabled) == "true"
 	}
+	if smtpPassword := os.Getenv("IGSCRAPER_SMTP_PASSWORD"); smtpPassword != "" {
+		c.Notifications.Email.Password = smtpPassword
+	}
 	
 	// Logging level
 	if logLevel := os.Getenv("IGSCRAPER_LOG_LEVEL"); logLevel != "" {
 		c.Logging.Level = logLevel
 	}
+	if auditFile := os.Getenv("IGSCRAPER_AUDIT_LOG"); auditFile != "" {
+		c.Logging.AuditFile = auditFile
+	}
+	if logFormat := os.Getenv("IGSCRAPER_LOG_FORMAT"); logFormat != "" {
+		c.Logging.Format = logFormat
+	}
 	
 	return nil
 }
@@ -241,26 +540,128 @@ func (c *Config) LoadFromFile(path string) error {
 		return fmt.Errorf("failed to read config file: %w", err)
 	}
 	
+	// Files of an older layout are read as if they were migrated
+	data, c.deprecated, err = Migrate(data)
+	if err != nil {
+		return err
+	}
+	
 	if err := yaml.Unmarshal(data, c); err != nil {
 		return fmt.Errorf("failed to parse config file: %w", err)
 	}
 	
+	// Profiles are kept as written, to be merged over the settings above
+	var presets struct {
+		Profiles map[string]yaml.Node `yaml:"profiles"`
+	}
+	if err := yaml.Unmarshal(data, &presets); err != nil {
+		return fmt.Errorf("failed to parse config file: %w", err)
+	}
+	c.profiles = presets.Profiles
+	
+	c.resolvePaths()
 	return nil
 }
 
-// findConfigFile searches for config file in standard locations
-func (c *Config) findConfigFile() string {
-	// Check in order of precedence
+// Deprecated returns the settings of an older layout that were moved or
+// dropped when the config file was read. MigrateFile rewrites the file
+// without them.
+func (c *Config) Deprecated() []Deprecation {
+	return c.deprecated
+}
+
+// ProfileNames returns the names of the profiles in the config file, sorted
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.profiles))
+	for name := range c.profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// UseProfile merges the settings of the named profile over the
+// configuration. Settings the profile leaves out keep their values, sections
+// it names are merged setting by setting, and lists it sets replace the
+// configured ones.
+func (c *Config) UseProfile(name string) error {
+	node, ok := c.profiles[name]
+	if !ok {
+		if len(c.profiles) == 0 {
+			return fmt.Errorf("unknown profile %q: the config file has no profiles", name)
+		}
+		return fmt.Errorf("unknown profile %q (available: %s)", name, strings.Join(c.ProfileNames(), ", "))
+	}
+	if err := node.Decode(c); err != nil {
+		return fmt.Errorf("failed to parse profile %q: %w", name, err)
+	}
+	c.resolvePaths()
+	return nil
+}
+
+// resolvePaths expands a leading ~ in the paths set in the config file,
+// which no shell does for it, and puts log files named without a directory
+// in the platform's log directory
+func (c *Config) resolvePaths() {
+	for _, path := range []*string{
+		&c.Output.BaseDirectory,
+		&c.Cache.Directory,
+		&c.RateLimit.SharedFile,
+		&c.Instagram.BrowserPath,
+		&c.Logging.File,
+		&c.Logging.AuditFile,
+	} {
+		*path = platformdirs.ExpandHome(*path)
+	}
+	
+	for _, path := range []*string{&c.Logging.File, &c.Logging.AuditFile} {
+		if *path == "" || filepath.Base(*path) != *path {
+			continue
+		}
+		if dir, err := platformdirs.LogDir(); err == nil {
+			*path = filepath.Join(dir, *path)
+		}
+	}
+}
+
+// SearchPaths returns the config files Load looks for when it is given
+// none, in order of precedence: the current directory, the platform's
+// config directory, ~/.config/igscraper where that is not the config
+// directory, as on macOS and Windows, and the home directory
+func SearchPaths() []string {
 	locations := []string{
 		".igscraper.yaml",
 		".igscraper.yml",
-		filepath.Join(os.Getenv("HOME"), ".config", "igscraper", "config.yaml"),
-		filepath.Join(os.Getenv("HOME"), ".config", "igscraper", "config.yml"),
-		filepath.Join(os.Getenv("HOME"), ".igscraper.yaml"),
-		filepath.Join(os.Getenv("HOME"), ".igscraper.yml"),
 	}
 	
-	for _, loc := range locations {
+	configDir, _ := platformdirs.ConfigDir()
+	if configDir != "" {
+		locations = append(locations,
+			filepath.Join(configDir, "config.yaml"),
+			filepath.Join(configDir, "config.yml"),
+		)
+	}
+	
+	if home, err := platformdirs.Home(); err == nil {
+		// Where earlier versions looked on every platform
+		if legacy := filepath.Join(home, ".config", platformdirs.AppName); legacy != configDir {
+			locations = append(locations,
+				filepath.Join(legacy, "config.yaml"),
+				filepath.Join(legacy, "config.yml"),
+			)
+		}
+		locations = append(locations,
+			filepath.Join(home, ".igscraper.yaml"),
+			filepath.Join(home, ".igscraper.yml"),
+		)
+	}
+	
+	return locations
+}
+
+// findConfigFile searches for config file in standard locations
+func (c *Config) findConfigFile() string {
+	for _, loc := range SearchPaths() {
 		if _, err := os.Stat(loc); err == nil {
 			return loc
 		}
@@ -280,6 +681,12 @@ func (c *Config) Validate() error {
 	if c.Instagram.CSRFToken == "" {
 		errs = append(errs, errors.New("Instagram CSRF token is required"))
 	}
+	if c.Instagram.RotateAfter <= 0 {
+		errs = append(errs, errors.New("rotate after must be positive"))
+	}
+	if c.Instagram.SessionKeepAlive < 0 {
+		errs = append(errs, errors.New("session keep alive cannot be negative"))
+	}
 	
 	// Validate rate limiting
 	if c.RateLimit.RequestsPerMinute <= 0 {
@@ -292,6 +699,56 @@ func (c *Config) Validate() error {
 		errs = append(errs, errors.New("max retries cannot be negative"))
 	}
 	
+	// Validate retry policies
+	validRetryClasses := map[string]bool{
+		"profile": true, "media_page": true, "photo_download": true,
+	}
+	validErrorTypes := map[string]bool{
+		"network": true, "rate_limit": true, "server_error": true, "auth": true,
+		"not_found": true, "parsing": true, "unknown": true,
+	}
+	for class, policy := range c.Retry.Endpoints {
+		if !validRetryClasses[class] {
+			errs = append(errs, fmt.Errorf("unknown retry endpoint class %q", class))
+			continue
+		}
+		if policy.MaxAttempts < 0 || policy.MaxAttempts > 10 {
+			errs = append(errs, fmt.Errorf("retry max attempts for %s must be between 0 and 10", class))
+		}
+		if policy.BaseDelay < 0 || policy.MaxDelay < 0 {
+			errs = append(errs, fmt.Errorf("retry delays for %s cannot be negative", class))
+		}
+		if policy.Multiplier < 0 || policy.JitterFactor < 0 || policy.JitterFactor > 1 {
+			errs = append(errs, fmt.Errorf("retry multiplier and jitter factor for %s are out of range", class))
+		}
+		for _, errorType := range policy.RetryOn {
+			if !validErrorTypes[errorType] {
+				errs = append(errs, fmt.Errorf("unknown error type %q in retry_on for %s", errorType, class))
+			}
+		}
+	}
+	
+	// Validate response cache
+	if c.Cache.Enabled && c.Cache.TTL <= 0 {
+		errs = append(errs, errors.New("cache TTL must be positive"))
+	}
+	
+	// Validate transport settings
+	if c.Transport.MaxIdleConns < 0 || c.Transport.MaxIdleConnsPerHost < 0 || c.Transport.MaxConnsPerHost < 0 {
+		errs = append(errs, errors.New("transport connection limits cannot be negative"))
+	}
+	if c.Transport.IdleConnTimeout < 0 || c.Transport.DialTimeout < 0 || c.Transport.KeepAlive < 0 || c.Transport.TLSHandshakeTimeout < 0 {
+		errs = append(errs, errors.New("transport timeouts cannot be negative"))
+	}
+	switch c.Transport.TLSMinVersion {
+	case "", "1.2", "1.3":
+	default:
+		errs = append(errs, fmt.Errorf("unsupported TLS minimum version %q, use 1.2 or 1.3", c.Transport.TLSMinVersion))
+	}
+	if c.Transport.LocalAddress != "" && net.ParseIP(c.Transport.LocalAddress) == nil {
+		errs = append(errs, fmt.Errorf("transport local address %q is not an IP address", c.Transport.LocalAddress))
+	}
+	
 	// Validate download settings
 	if c.Download.ConcurrentDownloads <= 0 {
 		errs = append(errs, errors.New("concurrent downloads must be positive"))
@@ -302,6 +759,79 @@ func (c *Config) Validate() error {
 	if c.Download.DownloadTimeout <= 0 {
 		errs = append(errs, errors.New("download timeout must be positive"))
 	}
+	if c.Download.JobTimeout < 0 {
+		errs = append(errs, errors.New("job timeout cannot be negative"))
+	}
+	if c.Download.ShutdownTimeout < 0 {
+		errs = append(errs, errors.New("shutdown timeout cannot be negative"))
+	}
+	if c.Download.RetryAttempts < 0 {
+		errs = append(errs, errors.New("retry attempts cannot be negative"))
+	}
+	switch c.Download.Order {
+	case "", "newest", "oldest":
+	default:
+		errs = append(errs, fmt.Errorf("unknown download order %q, use newest or oldest", c.Download.Order))
+	}
+	if autoscale := c.Download.Autoscale; autoscale.Enabled {
+		if autoscale.MinWorkers <= 0 {
+			errs = append(errs, errors.New("autoscale min workers must be positive"))
+		}
+		if autoscale.MaxWorkers < autoscale.MinWorkers {
+			errs = append(errs, errors.New("autoscale max workers cannot be below min workers"))
+		}
+		if autoscale.MaxWorkers > 10 {
+			errs = append(errs, errors.New("autoscale max workers should not exceed 10"))
+		}
+	}
+	if c.Download.MinFileSize < 0 || c.Download.MaxFileSize < 0 {
+		errs = append(errs, errors.New("file size limits cannot be negative"))
+	}
+	if c.Download.MaxFileSize > 0 && c.Download.MaxFileSize < c.Download.MinFileSize {
+		errs = append(errs, errors.New("max file size cannot be below min file size"))
+	}
+	if c.Download.SkipVideos && c.Download.SkipImages {
+		errs = append(errs, errors.New("skip videos and skip images together leave nothing to download"))
+	}
+	if c.Download.VideoChunks < 0 || c.Download.VideoChunks > 16 {
+		errs = append(errs, errors.New("video chunks must be between 0 and 16"))
+	}
+	if c.Download.MaxComments <= 0 {
+		errs = append(errs, errors.New("max comments must be positive"))
+	}
+	if _, err := ratelimit.ParseBandwidth(c.Download.MaxBandwidth); err != nil {
+		errs = append(errs, err)
+	}
+	if c.Download.MinFreeSpace < 0 {
+		errs = append(errs, errors.New("min free space cannot be negative"))
+	}
+	if c.Download.MaxTotalSize < 0 {
+		errs = append(errs, errors.New("max total size cannot be negative"))
+	}
+	if c.Download.PreferredResolution < 0 {
+		errs = append(errs, errors.New("preferred resolution cannot be negative"))
+	}
+	if c.Download.MinLikes < 0 {
+		errs = append(errs, errors.New("min likes cannot be negative"))
+	}
+	if c.Download.MaxPosts < 0 {
+		errs = append(errs, errors.New("max posts cannot be negative"))
+	}
+	if c.Download.MaxPages < 0 {
+		errs = append(errs, errors.New("max pages cannot be negative"))
+	}
+	if c.Download.PrefetchPages < 0 || c.Download.PrefetchPages > 5 {
+		errs = append(errs, errors.New("prefetch pages must be between 0 and 5"))
+	}
+	if err := postprocess.Validate(c.Download.PostProcess); err != nil {
+		errs = append(errs, err)
+	}
+	if c.Download.SkipSyncedWithin < 0 {
+		errs = append(errs, errors.New("skip synced threshold cannot be negative"))
+	}
+	if !c.Download.Since.IsZero() && !c.Download.Until.IsZero() && c.Download.Until.Before(c.Download.Since) {
+		errs = append(errs, errors.New("until date must not be before since date"))
+	}
 	
 	// Validate output settings
 	if c.Output.BaseDirectory == "" {
@@ -310,6 +840,17 @@ func (c *Config) Validate() error {
 	if c.Output.FileNamePattern == "" {
 		errs = append(errs, errors.New("file name pattern is required"))
 	}
+	if c.Output.GalleryThumbnails < 0 {
+		errs = append(errs, errors.New("gallery thumbnail size cannot be negative"))
+	}
+	if c.Output.RefreshNewerThan < 0 {
+		errs = append(errs, errors.New("refresh window cannot be negative"))
+	}
+	switch c.Output.ExistingArchive {
+	case "", "warn", "use":
+	default:
+		errs = append(errs, fmt.Errorf("unknown existing archive action %q, use warn or use", c.Output.ExistingArchive))
+	}
 	
 	// Validate logging
 	validLogLevels := map[string]bool{
@@ -318,6 +859,26 @@ func (c *Config) Validate() error {
 	if !validLogLevels[strings.ToLower(c.Logging.Level)] {
 		errs = append(errs, errors.New("invalid log level"))
 	}
+	// The components pkg/logger names
+	validLogComponents := map[string]bool{
+		"instagram": true, "downloader": true, "ratelimit": true, "scraper": true,
+		"checkpoint": true, "storage": true, "daemon": true, "notify": true, "hooks": true,
+	}
+	for component, level := range c.Logging.Levels {
+		if !validLogComponents[strings.ToLower(component)] {
+			errs = append(errs, fmt.Errorf("unknown log component %q", component))
+		} else if !validLogLevels[strings.ToLower(level)] {
+			errs = append(errs, fmt.Errorf("invalid log level %q for %s", level, component))
+		}
+	}
+	switch strings.ToLower(c.Logging.Format) {
+	case "", "console", "json":
+	default:
+		errs = append(errs, errors.New("log format must be console or json"))
+	}
+	if s := c.Logging.Sampling; s.Debug < 0 || s.Info < 0 || s.Warn < 0 {
+		errs = append(errs, errors.New("log sampling rates cannot be negative"))
+	}
 	
 	// Validate notification type
 	validNotifTypes := map[string]bool{
@@ -326,6 +887,44 @@ func (c *Config) Validate() error {
 	if !validNotifTypes[strings.ToLower(c.Notifications.NotificationType)] {
 		errs = append(errs, errors.New("invalid notification type"))
 	}
+	if email := c.Notifications.Email; email.Enabled {
+		if email.Host == "" {
+			errs = append(errs, errors.New("email notifications need an SMTP host"))
+		}
+		if email.Port <= 0 || email.Port > 65535 {
+			errs = append(errs, errors.New("SMTP port must be between 1 and 65535"))
+		}
+		if email.From == "" || len(email.To) == 0 {
+			errs = append(errs, errors.New("email notifications need a sender and at least one recipient"))
+		}
+	}
+	
+	if c.Hooks.Timeout <= 0 {
+		errs = append(errs, errors.New("hook timeout must be positive"))
+	}
+	
+	// Validate daemon schedule
+	if c.Daemon.DefaultInterval <= 0 {
+		errs = append(errs, errors.New("daemon default interval must be positive"))
+	}
+	if c.Daemon.StatusInterval < 0 {
+		errs = append(errs, errors.New("daemon status interval cannot be negative"))
+	}
+	seenProfiles := make(map[string]bool)
+	for _, profile := range c.Daemon.Profiles {
+		username := strings.TrimSpace(profile.Username)
+		if username == "" {
+			errs = append(errs, errors.New("daemon profile username is required"))
+			continue
+		}
+		if seenProfiles[strings.ToLower(username)] {
+			errs = append(errs, fmt.Errorf("daemon profile %q is listed more than once", username))
+		}
+		seenProfiles[strings.ToLower(username)] = true
+		if profile.Interval < 0 {
+			errs = append(errs, fmt.Errorf("daemon interval for %q cannot be negative", username))
+		}
+	}
 	
 	if len(errs) > 0 {
 		return errors.Join(errs...)
@@ -369,27 +968,112 @@ func (c *Config) MergeCommandLineFlags(flags map[string]interface{}) {
 	if outputDir, ok := flags["output"].(string); ok && outputDir != "" {
 		c.Output.BaseDirectory = outputDir
 	}
+	if overwrite, ok := flags["overwrite"].(bool); ok && overwrite {
+		c.Output.OverwriteExisting = true
+	}
+	if refresh, ok := flags["refresh-newer-than"].(time.Duration); ok && refresh > 0 {
+		c.Output.RefreshNewerThan = refresh
+	}
+	if thumbs, ok := flags["gallery-thumbnails"].(int); ok && thumbs > 0 {
+		c.Output.GalleryThumbnails = thumbs
+	}
+	if existing, ok := flags["existing-archive"].(string); ok && existing != "" {
+		c.Output.ExistingArchive = existing
+	}
 	if concurrent, ok := flags["concurrent-downloads"].(int); ok && concurrent > 0 {
 		c.Download.ConcurrentDownloads = concurrent
 	}
 	if rateLimit, ok := flags["requests-per-minute"].(int); ok && rateLimit > 0 {
 		c.RateLimit.RequestsPerMinute = rateLimit
 	}
+	if sharedFile, ok := flags["rate-limit-file"].(string); ok && sharedFile != "" {
+		c.RateLimit.SharedFile = sharedFile
+	}
 	if notifications, ok := flags["notifications-enabled"].(bool); ok {
 		c.Notifications.Enabled = notifications
 	}
 	if logLevel, ok := flags["log-level"].(string); ok && logLevel != "" {
 		c.Logging.Level = logLevel
 	}
+	if auditFile, ok := flags["audit-log"].(string); ok && auditFile != "" {
+		c.Logging.AuditFile = auditFile
+	}
+	if skipSynced, ok := flags["skip-synced-within"].(time.Duration); ok && skipSynced >= 0 {
+		c.Download.SkipSyncedWithin = skipSynced
+	}
+	if since, ok := flags["since"].(time.Time); ok && !since.IsZero() {
+		c.Download.Since = since
+	}
+	if until, ok := flags["until"].(time.Time); ok && !until.IsZero() {
+		c.Download.Until = until
+	}
+	if filter, ok := flags["filter"].(string); ok && filter != "" {
+		c.Download.Filter = filter
+	}
+	if embed, ok := flags["embed-metadata"].(bool); ok && embed {
+		c.Download.EmbedMetadata = true
+	}
+	if skip, ok := flags["skip-videos"].(bool); ok && skip {
+		c.Download.SkipVideos = true
+	}
+	if skip, ok := flags["skip-images"].(bool); ok && skip {
+		c.Download.SkipImages = true
+	}
+	if dedup, ok := flags["dedup"].(bool); ok && dedup {
+		c.Download.Dedup = true
+	}
+	if comments, ok := flags["comments"].(bool); ok && comments {
+		c.Download.Comments = true
+	}
+	if maxComments, ok := flags["max-comments"].(int); ok && maxComments > 0 {
+		c.Download.MaxComments = maxComments
+	}
+	if bandwidth, ok := flags["max-bandwidth"].(string); ok && bandwidth != "" {
+		c.Download.MaxBandwidth = bandwidth
+	}
+	if quota, ok := flags["max-total-size"].(int64); ok && quota > 0 {
+		c.Download.MaxTotalSize = quota
+	}
+	if minLikes, ok := flags["min-likes"].(int); ok && minLikes > 0 {
+		c.Download.MinLikes = minLikes
+	}
+	if maxPosts, ok := flags["max-posts"].(int); ok && maxPosts > 0 {
+		c.Download.MaxPosts = maxPosts
+	}
+	if maxPages, ok := flags["max-pages"].(int); ok && maxPages > 0 {
+		c.Download.MaxPages = maxPages
+	}
+	if prefetch, ok := flags["prefetch-pages"].(int); ok && prefetch > 0 {
+		c.Download.PrefetchPages = prefetch
+	}
+	if rotate, ok := flags["rotate-accounts"].(bool); ok && rotate {
+		c.Instagram.RotateAccounts = true
+	}
+	if fallback, ok := flags["browser-fallback"].(bool); ok && fallback {
+		c.Instagram.BrowserFallback = true
+	}
+	if keepAlive, ok := flags["keep-alive"].(time.Duration); ok && keepAlive > 0 {
+		c.Instagram.SessionKeepAlive = keepAlive
+	}
+	if cache, ok := flags["cache"].(bool); ok && cache {
+		c.Cache.Enabled = true
+	}
+	if ttl, ok := flags["cache-ttl"].(time.Duration); ok && ttl > 0 {
+		c.Cache.Enabled = true
+		c.Cache.TTL = ttl
+	}
 }
 
 // Load loads configuration from all sources with proper precedence
-// Precedence order: Command line flags > Environment variables > .env file > Config file > Defaults
+// Precedence order: Command line flags > Environment variables > .env file > Profile > Config file > Defaults
+// The profile is named by the "profile" flag or IGSCRAPER_PROFILE.
 func Load(configPath string, flags map[string]interface{}) (*Config, error) {
 	// Try to load .env files (don't fail if they don't exist)
 	_ = godotenv.Load(".env")
-	_ = godotenv.Load(filepath.Join(os.Getenv("HOME"), ".env"))
-	_ = godotenv.Load(filepath.Join(os.Getenv("HOME"), ".igscraper.env"))
+	if home, err := platformdirs.Home(); err == nil {
+		_ = godotenv.Load(filepath.Join(home, ".env"))
+		_ = godotenv.Load(filepath.Join(home, ".igscraper.env"))
+	}
 	
 	// Start with defaults
 	config := DefaultConfig()
@@ -399,6 +1083,17 @@ func Load(configPath string, flags map[string]interface{}) (*Config, error) {
 		return nil, fmt.Errorf("failed to load config file: %w", err)
 	}
 	
+	// Merge the selected profile over the file
+	profile, _ := flags["profile"].(string)
+	if profile == "" {
+		profile = os.Getenv("IGSCRAPER_PROFILE")
+	}
+	if profile != "" {
+		if err := config.UseProfile(profile); err != nil {
+			return nil, err
+		}
+	}
+	
 	// Override with environment variables (includes values from .env)
 	if err := config.LoadFromEnv(); err != nil {
 		return nil, fmt.Errorf("failed to load environment variables: %w", err)
@@ -9,6 +9,9 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"gopkg.in/yaml.v3"
+
+	"igscraper/pkg/platformdirs"
+	"igscraper/pkg/postprocess"
 )
 
 func TestDefaultConfig(t *testing.T) {
@@ -25,6 +28,7 @@ func TestDefaultConfig(t *testing.T) {
 	assert.Equal(t, 10, cfg.RateLimit.BurstSize)
 	assert.Equal(t, 2.0, cfg.RateLimit.BackoffMultiplier)
 	assert.Equal(t, 3, cfg.RateLimit.MaxRetries)
+	assert.True(t, cfg.RateLimit.PersistState)
 	assert.Equal(t, 5*time.Second, cfg.RateLimit.RetryDelay)
 	
 	// Test Retry defaults
@@ -35,6 +39,15 @@ func TestDefaultConfig(t *testing.T) {
 	assert.Equal(t, 2.0, cfg.Retry.Multiplier)
 	assert.Equal(t, 0.1, cfg.Retry.JitterFactor)
 	
+	// Test Cache defaults
+	assert.False(t, cfg.Cache.Enabled)
+	assert.Equal(t, time.Hour, cfg.Cache.TTL)
+	
+	// Test Transport defaults
+	assert.Equal(t, 10, cfg.Transport.MaxIdleConnsPerHost)
+	assert.Equal(t, 90*time.Second, cfg.Transport.IdleConnTimeout)
+	assert.False(t, cfg.Transport.DisableHTTP2)
+	
 	// Test Output defaults
 	assert.Equal(t, "./downloads", cfg.Output.BaseDirectory)
 	assert.True(t, cfg.Output.CreateUserFolders)
@@ -49,6 +62,9 @@ func TestDefaultConfig(t *testing.T) {
 	assert.False(t, cfg.Download.SkipImages)
 	assert.Equal(t, int64(0), cfg.Download.MinFileSize)
 	assert.Equal(t, int64(0), cfg.Download.MaxFileSize)
+	assert.Equal(t, int64(512<<20), cfg.Download.MinFreeSpace)
+	assert.Equal(t, int64(0), cfg.Download.MaxTotalSize)
+	assert.Equal(t, 0, cfg.Download.PreferredResolution)
 	
 	// Test Notifications defaults
 	assert.True(t, cfg.Notifications.Enabled)
@@ -75,10 +91,13 @@ func TestLoadFromEnv(t *testing.T) {
 		"IGSCRAPER_CSRF_TOKEN",
 		"IGSCRAPER_USER_AGENT",
 		"IGSCRAPER_REQUESTS_PER_MINUTE",
+		"IGSCRAPER_RATE_LIMIT_FILE",
 		"IGSCRAPER_OUTPUT_DIR",
 		"IGSCRAPER_CONCURRENT_DOWNLOADS",
 		"IGSCRAPER_NOTIFICATIONS_ENABLED",
 		"IGSCRAPER_LOG_LEVEL",
+		"IGSCRAPER_AUDIT_LOG",
+		"IGSCRAPER_ROTATE_ACCOUNTS",
 	}
 	
 	for _, key := range envVars {
@@ -101,10 +120,13 @@ func TestLoadFromEnv(t *testing.T) {
 	os.Setenv("IGSCRAPER_CSRF_TOKEN", "env_csrf")
 	os.Setenv("IGSCRAPER_USER_AGENT", "env_agent")
 	os.Setenv("IGSCRAPER_REQUESTS_PER_MINUTE", "120")
+	os.Setenv("IGSCRAPER_RATE_LIMIT_FILE", "/env/requests.json")
 	os.Setenv("IGSCRAPER_OUTPUT_DIR", "/env/output")
 	os.Setenv("IGSCRAPER_CONCURRENT_DOWNLOADS", "5")
 	os.Setenv("IGSCRAPER_NOTIFICATIONS_ENABLED", "false")
 	os.Setenv("IGSCRAPER_LOG_LEVEL", "debug")
+	os.Setenv("IGSCRAPER_AUDIT_LOG", "/env/audit.jsonl")
+	os.Setenv("IGSCRAPER_ROTATE_ACCOUNTS", "true")
 	
 	cfg := DefaultConfig()
 	err := cfg.LoadFromEnv()
@@ -114,10 +136,13 @@ func TestLoadFromEnv(t *testing.T) {
 	assert.Equal(t, "env_csrf", cfg.Instagram.CSRFToken)
 	assert.Equal(t, "env_agent", cfg.Instagram.UserAgent)
 	assert.Equal(t, 120, cfg.RateLimit.RequestsPerMinute)
+	assert.Equal(t, "/env/requests.json", cfg.RateLimit.SharedFile)
 	assert.Equal(t, "/env/output", cfg.Output.BaseDirectory)
 	assert.Equal(t, 5, cfg.Download.ConcurrentDownloads)
 	assert.False(t, cfg.Notifications.Enabled)
 	assert.Equal(t, "debug", cfg.Logging.Level)
+	assert.Equal(t, "/env/audit.jsonl", cfg.Logging.AuditFile)
+	assert.True(t, cfg.Instagram.RotateAccounts)
 }
 
 func TestLoadFromFile(t *testing.T) {
@@ -297,6 +322,71 @@ func TestFindConfigFile(t *testing.T) {
 		found := cfg.findConfigFile()
 		assert.Empty(t, found)
 	})
+	
+	t.Run("finds config in the platform's config directory", func(t *testing.T) {
+		tempDir := t.TempDir()
+		oldDir, _ := os.Getwd()
+		defer os.Chdir(oldDir)
+		require.NoError(t, os.Chdir(tempDir))
+		
+		home := filepath.Join(tempDir, "home")
+		t.Setenv("HOME", home)
+		t.Setenv("USERPROFILE", home)
+		t.Setenv("XDG_CONFIG_HOME", filepath.Join(tempDir, "xdg"))
+		t.Setenv("APPDATA", filepath.Join(tempDir, "appdata"))
+		
+		// The home directory comes last
+		require.NoError(t, os.MkdirAll(home, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(home, ".igscraper.yaml"), []byte("test: true"), 0644))
+		assert.Equal(t, filepath.Join(home, ".igscraper.yaml"), DefaultConfig().findConfigFile())
+		
+		configDir, err := platformdirs.ConfigDir()
+		require.NoError(t, err)
+		require.NoError(t, os.MkdirAll(configDir, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(configDir, "config.yml"), []byte("test: true"), 0644))
+		assert.Equal(t, filepath.Join(configDir, "config.yml"), DefaultConfig().findConfigFile())
+	})
+}
+
+func TestResolvePaths(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `
+instagram:
+  session_id: file_session
+  csrf_token: file_csrf
+output:
+  base_directory: ~/Pictures/instagram
+logging:
+  file: igscraper.log
+  audit_file: ./audit.jsonl
+profiles:
+  server:
+    logging:
+      audit_file: audit.jsonl
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+	
+	logDir, err := platformdirs.LogDir()
+	require.NoError(t, err)
+	
+	cfg, err := Load(configPath, nil)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(home, "Pictures", "instagram"), cfg.Output.BaseDirectory)
+	assert.Equal(t, filepath.Join(logDir, "igscraper.log"), cfg.Logging.File)
+	assert.Equal(t, "./audit.jsonl", cfg.Logging.AuditFile) // relative to the working directory
+	
+	cfg, err = Load(configPath, map[string]interface{}{"profile": "server"})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(logDir, "audit.jsonl"), cfg.Logging.AuditFile)
+	
+	// Paths given as flags are left to the shell
+	cfg, err = Load(configPath, map[string]interface{}{"audit-log": "audit.jsonl"})
+	require.NoError(t, err)
+	assert.Equal(t, "audit.jsonl", cfg.Logging.AuditFile)
 }
 
 func TestValidate(t *testing.T) {
@@ -323,6 +413,17 @@ func TestValidate(t *testing.T) {
 			expectError: true,
 			errorContains: []string{"session ID is required", "CSRF token is required"},
 		},
+		{
+			name: "invalid account rotation",
+			setupConfig: func(cfg *Config) {
+				cfg.Instagram.SessionID = "valid"
+				cfg.Instagram.CSRFToken = "valid"
+				cfg.Instagram.RotateAccounts = true
+				cfg.Instagram.RotateAfter = 0
+			},
+			expectError: true,
+			errorContains: []string{"rotate after must be positive"},
+		},
 		{
 			name: "invalid rate limit",
 			setupConfig: func(cfg *Config) {
@@ -339,6 +440,70 @@ func TestValidate(t *testing.T) {
 				"max retries cannot be negative",
 			},
 		},
+		{
+			name: "invalid retry policies",
+			setupConfig: func(cfg *Config) {
+				cfg.Instagram.SessionID = "valid"
+				cfg.Instagram.CSRFToken = "valid"
+				cfg.Retry.Endpoints = map[string]EndpointRetryConfig{
+					"stories":        {MaxAttempts: 2},
+					"profile":        {MaxAttempts: 20, RetryOn: []string{"network", "timeout"}},
+					"photo_download": {BaseDelay: -time.Second, JitterFactor: 2},
+				}
+			},
+			expectError: true,
+			errorContains: []string{
+				`unknown retry endpoint class "stories"`,
+				"retry max attempts for profile must be between 0 and 10",
+				`unknown error type "timeout" in retry_on for profile`,
+				"retry delays for photo_download cannot be negative",
+				"retry multiplier and jitter factor for photo_download are out of range",
+			},
+		},
+		{
+			name: "invalid cache TTL",
+			setupConfig: func(cfg *Config) {
+				cfg.Instagram.SessionID = "valid"
+				cfg.Instagram.CSRFToken = "valid"
+				cfg.Cache.Enabled = true
+				cfg.Cache.TTL = 0
+			},
+			expectError:   true,
+			errorContains: []string{"cache TTL must be positive"},
+		},
+		{
+			name: "incomplete email settings",
+			setupConfig: func(cfg *Config) {
+				cfg.Instagram.SessionID = "valid"
+				cfg.Instagram.CSRFToken = "valid"
+				cfg.Notifications.Email.Enabled = true
+				cfg.Notifications.Email.Port = 0
+			},
+			expectError: true,
+			errorContains: []string{
+				"email notifications need an SMTP host",
+				"SMTP port must be between 1 and 65535",
+				"email notifications need a sender and at least one recipient",
+			},
+		},
+		{
+			name: "invalid transport settings",
+			setupConfig: func(cfg *Config) {
+				cfg.Instagram.SessionID = "valid"
+				cfg.Instagram.CSRFToken = "valid"
+				cfg.Transport.MaxIdleConnsPerHost = -1
+				cfg.Transport.DialTimeout = -time.Second
+				cfg.Transport.TLSMinVersion = "1.0"
+				cfg.Transport.LocalAddress = "eth0"
+			},
+			expectError: true,
+			errorContains: []string{
+				"transport connection limits cannot be negative",
+				"transport timeouts cannot be negative",
+				`unsupported TLS minimum version "1.0"`,
+				`transport local address "eth0" is not an IP address`,
+			},
+		},
 		{
 			name: "invalid download settings",
 			setupConfig: func(cfg *Config) {
@@ -346,11 +511,37 @@ func TestValidate(t *testing.T) {
 				cfg.Instagram.CSRFToken = "valid"
 				cfg.Download.ConcurrentDownloads = 0
 				cfg.Download.DownloadTimeout = 0
+				cfg.Download.SkipSyncedWithin = -time.Hour
+				cfg.Download.MaxComments = 0
+				cfg.Download.MaxBandwidth = "fast"
+				cfg.Download.MaxTotalSize = -1
+				cfg.Download.PreferredResolution = -1
+				cfg.Download.PrefetchPages = -1
+				cfg.Download.MinLikes = -1
+				cfg.Download.MaxPosts = -1
+				cfg.Download.MaxPages = -1
+				cfg.Download.SkipVideos = true
+				cfg.Download.SkipImages = true
+				cfg.Download.MinFileSize = 2048
+				cfg.Download.MaxFileSize = 1024
+				cfg.Download.PostProcess = []postprocess.StepConfig{{Step: "sharpen"}}
 			},
 			expectError: true,
 			errorContains: []string{
 				"concurrent downloads must be positive",
+				"skip videos and skip images together leave nothing to download",
+				"max file size cannot be below min file size",
 				"download timeout must be positive",
+				"max comments must be positive",
+				`invalid bandwidth "fast"`,
+				"max total size cannot be negative",
+				"preferred resolution cannot be negative",
+				"prefetch pages must be between 0 and 5",
+				"min likes cannot be negative",
+				"max posts cannot be negative",
+				"max pages cannot be negative",
+				`unknown step "sharpen"`,
+				"skip synced threshold cannot be negative",
 			},
 		},
 		{
@@ -363,6 +554,27 @@ func TestValidate(t *testing.T) {
 			expectError: true,
 			errorContains: []string{"concurrent downloads should not exceed 10"},
 		},
+		{
+			name: "invalid autoscale bounds",
+			setupConfig: func(cfg *Config) {
+				cfg.Instagram.SessionID = "valid"
+				cfg.Instagram.CSRFToken = "valid"
+				cfg.Download.Autoscale = AutoscaleConfig{Enabled: true, MinWorkers: 4, MaxWorkers: 2}
+			},
+			expectError: true,
+			errorContains: []string{"autoscale max workers cannot be below min workers"},
+		},
+		{
+			name: "invalid download order and retries",
+			setupConfig: func(cfg *Config) {
+				cfg.Instagram.SessionID = "valid"
+				cfg.Instagram.CSRFToken = "valid"
+				cfg.Download.Order = "random"
+				cfg.Download.RetryAttempts = -1
+			},
+			expectError: true,
+			errorContains: []string{"unknown download order", "retry attempts cannot be negative"},
+		},
 		{
 			name: "invalid output settings",
 			setupConfig: func(cfg *Config) {
@@ -370,11 +582,17 @@ func TestValidate(t *testing.T) {
 				cfg.Instagram.CSRFToken = "valid"
 				cfg.Output.BaseDirectory = ""
 				cfg.Output.FileNamePattern = ""
+				cfg.Output.RefreshNewerThan = -time.Hour
+				cfg.Output.GalleryThumbnails = -1
+				cfg.Output.ExistingArchive = "merge"
 			},
 			expectError: true,
 			errorContains: []string{
 				"output directory is required",
 				"file name pattern is required",
+				"refresh window cannot be negative",
+				"gallery thumbnail size cannot be negative",
+				"unknown existing archive action",
 			},
 		},
 		{
@@ -397,6 +615,69 @@ func TestValidate(t *testing.T) {
 			expectError: true,
 			errorContains: []string{"invalid notification type"},
 		},
+		{
+			name: "invalid hook timeout",
+			setupConfig: func(cfg *Config) {
+				cfg.Instagram.SessionID = "valid"
+				cfg.Instagram.CSRFToken = "valid"
+				cfg.Hooks.PostDownload = "cat"
+				cfg.Hooks.Timeout = 0
+			},
+			expectError: true,
+			errorContains: []string{"hook timeout must be positive"},
+		},
+		{
+			name: "invalid log format and sampling",
+			setupConfig: func(cfg *Config) {
+				cfg.Instagram.SessionID = "valid"
+				cfg.Instagram.CSRFToken = "valid"
+				cfg.Logging.Format = "xml"
+				cfg.Logging.Sampling.Debug = -1
+			},
+			expectError: true,
+			errorContains: []string{"log format must be console or json", "log sampling rates cannot be negative"},
+		},
+		{
+			name: "invalid component log levels",
+			setupConfig: func(cfg *Config) {
+				cfg.Instagram.SessionID = "valid"
+				cfg.Instagram.CSRFToken = "valid"
+				cfg.Logging.Levels = map[string]string{"instagram": "verbose", "uploader": "debug", "Downloader": "warn"}
+			},
+			expectError: true,
+			errorContains: []string{`invalid log level "verbose" for instagram`, `unknown log component "uploader"`},
+		},
+		{
+			name: "until before since",
+			setupConfig: func(cfg *Config) {
+				cfg.Instagram.SessionID = "valid"
+				cfg.Instagram.CSRFToken = "valid"
+				cfg.Download.Since = time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+				cfg.Download.Until = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			},
+			expectError: true,
+			errorContains: []string{"until date must not be before since date"},
+		},
+		{
+			name: "invalid daemon schedule",
+			setupConfig: func(cfg *Config) {
+				cfg.Instagram.SessionID = "valid"
+				cfg.Instagram.CSRFToken = "valid"
+				cfg.Daemon.DefaultInterval = 0
+				cfg.Daemon.Profiles = []ProfileSchedule{
+					{Username: ""},
+					{Username: "alice", Interval: -time.Minute},
+					{Username: "Alice"},
+				}
+			},
+			expectError: true,
+			errorContains: []string{
+				"daemon default interval must be positive",
+				"daemon profile username is required",
+				`daemon interval for "alice" cannot be negative`,
+				`daemon profile "Alice" is listed more than once`,
+			},
+		},
 	}
 	
 	for _, tt := range tests {
@@ -493,19 +774,50 @@ func TestMergeCommandLineFlags(t *testing.T) {
 				"session-id":           "flag_session",
 				"csrf-token":           "flag_csrf",
 				"output":               "/flag/output",
+				"overwrite":            true,
+				"refresh-newer-than":   48 * time.Hour,
+				"gallery-thumbnails":   320,
+				"existing-archive":     "use",
 				"concurrent-downloads": 7,
 				"requests-per-minute":  90,
 				"notifications-enabled": false,
 				"log-level":            "error",
+				"audit-log":            "/flag/audit.jsonl",
+				"skip-synced-within":   12 * time.Hour,
+				"filter":               "hashtag:sunset AND likes>100",
+				"embed-metadata":       true,
+				"skip-images":          true,
+				"dedup":                true,
+				"comments":             true,
+				"max-comments":         250,
+				"max-total-size":       int64(10 << 30),
+				"rotate-accounts":      true,
+				"cache-ttl":            30 * time.Minute,
 			},
 			expected: func(cfg *Config) {
 				cfg.Instagram.SessionID = "flag_session"
 				cfg.Instagram.CSRFToken = "flag_csrf"
 				cfg.Output.BaseDirectory = "/flag/output"
+				cfg.Output.OverwriteExisting = true
+				cfg.Output.RefreshNewerThan = 48 * time.Hour
+				cfg.Output.GalleryThumbnails = 320
+				cfg.Output.ExistingArchive = "use"
 				cfg.Download.ConcurrentDownloads = 7
 				cfg.RateLimit.RequestsPerMinute = 90
 				cfg.Notifications.Enabled = false
 				cfg.Logging.Level = "error"
+				cfg.Logging.AuditFile = "/flag/audit.jsonl"
+				cfg.Download.SkipSyncedWithin = 12 * time.Hour
+				cfg.Download.Filter = "hashtag:sunset AND likes>100"
+				cfg.Download.EmbedMetadata = true
+				cfg.Download.SkipImages = true
+				cfg.Download.Dedup = true
+				cfg.Download.Comments = true
+				cfg.Download.MaxComments = 250
+				cfg.Download.MaxTotalSize = 10 << 30
+				cfg.Instagram.RotateAccounts = true
+				cfg.Cache.Enabled = true
+				cfg.Cache.TTL = 30 * time.Minute
 			},
 		},
 		{
@@ -570,6 +882,19 @@ func TestMergeCommandLineFlags(t *testing.T) {
 			if logLevel, ok := tt.flags["log-level"].(string); ok && logLevel != "" {
 				assert.Equal(t, expectedCfg.Logging.Level, cfg.Logging.Level)
 			}
+			if _, ok := tt.flags["skip-synced-within"].(time.Duration); ok {
+				assert.Equal(t, expectedCfg.Download.SkipSyncedWithin, cfg.Download.SkipSyncedWithin)
+			}
+			if _, ok := tt.flags["filter"].(string); ok {
+				assert.Equal(t, expectedCfg.Download.Filter, cfg.Download.Filter)
+				assert.Equal(t, expectedCfg.Download.EmbedMetadata, cfg.Download.EmbedMetadata)
+				assert.Equal(t, expectedCfg.Download.Dedup, cfg.Download.Dedup)
+				assert.Equal(t, expectedCfg.Instagram.RotateAccounts, cfg.Instagram.RotateAccounts)
+				assert.Equal(t, expectedCfg.Logging.AuditFile, cfg.Logging.AuditFile)
+			}
+			if _, ok := tt.flags["cache-ttl"].(time.Duration); ok {
+				assert.Equal(t, expectedCfg.Cache, cfg.Cache)
+			}
 		})
 	}
 }
@@ -742,4 +1067,137 @@ func BenchmarkSaveAndLoad(b *testing.B) {
 		loadedCfg := DefaultConfig()
 		_ = loadedCfg.LoadFromFile(configPath)
 	}
-}
\ No newline at end of file
+}
+func TestDaemonConfig(t *testing.T) {
+	t.Run("load profiles from file", func(t *testing.T) {
+		configPath := filepath.Join(t.TempDir(), "config.yaml")
+		content := `
+daemon:
+  default_interval: 12h
+  status_interval: 1m
+  metrics_addr: "127.0.0.1:9090"
+  profiles:
+    - username: alice
+      interval: 30m
+    - username: bob
+`
+		require.NoError(t, os.WriteFile(configPath, []byte(content), 0644))
+		
+		cfg := DefaultConfig()
+		require.NoError(t, cfg.LoadFromFile(configPath))
+		
+		assert.Equal(t, 12*time.Hour, cfg.Daemon.DefaultInterval)
+		assert.Equal(t, time.Minute, cfg.Daemon.StatusInterval)
+		assert.Equal(t, "127.0.0.1:9090", cfg.Daemon.MetricsAddr)
+		require.Len(t, cfg.Daemon.Profiles, 2)
+		assert.Equal(t, 30*time.Minute, cfg.Daemon.IntervalFor(cfg.Daemon.Profiles[0]))
+		assert.Equal(t, 12*time.Hour, cfg.Daemon.IntervalFor(cfg.Daemon.Profiles[1]))
+	})
+}
+
+func TestInDateRange(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2024, 6, 30, 23, 59, 59, 0, time.UTC)
+	
+	tests := []struct {
+		name     string
+		cfg      DownloadConfig
+		takenAt  time.Time
+		expected bool
+	}{
+		{"no range", DownloadConfig{}, since.AddDate(-5, 0, 0), true},
+		{"missing timestamp", DownloadConfig{Since: since, Until: until}, time.Time{}, true},
+		{"before since", DownloadConfig{Since: since}, since.Add(-time.Second), false},
+		{"at since", DownloadConfig{Since: since}, since, true},
+		{"at until", DownloadConfig{Until: until}, until, true},
+		{"after until", DownloadConfig{Until: until}, until.Add(time.Second), false},
+		{"inside range", DownloadConfig{Since: since, Until: until}, since.AddDate(0, 3, 0), true},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.cfg.InDateRange(tt.takenAt))
+		})
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	configContent := `
+instagram:
+  session_id: file_session
+  csrf_token: file_csrf
+rate_limit:
+  requests_per_minute: 60
+  burst_size: 10
+download:
+  concurrent_downloads: 3
+  skip_videos: true
+logging:
+  levels:
+    instagram: debug
+profiles:
+  slow:
+    rate_limit:
+      requests_per_minute: 20
+    download:
+      concurrent_downloads: 1
+  archive:
+    download:
+      skip_videos: false
+      comments: true
+    logging:
+      levels:
+        downloader: warn
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	t.Run("merges over the file", func(t *testing.T) {
+		cfg, err := Load(configPath, map[string]interface{}{"profile": "slow"})
+		require.NoError(t, err)
+
+		assert.Equal(t, 20, cfg.RateLimit.RequestsPerMinute)
+		assert.Equal(t, 10, cfg.RateLimit.BurstSize) // left out of the profile
+		assert.Equal(t, 1, cfg.Download.ConcurrentDownloads)
+		assert.True(t, cfg.Download.SkipVideos)
+		assert.Equal(t, []string{"archive", "slow"}, cfg.ProfileNames())
+	})
+
+	t.Run("maps are merged", func(t *testing.T) {
+		cfg, err := Load(configPath, map[string]interface{}{"profile": "archive"})
+		require.NoError(t, err)
+
+		assert.False(t, cfg.Download.SkipVideos)
+		assert.True(t, cfg.Download.Comments)
+		assert.Equal(t, map[string]string{"instagram": "debug", "downloader": "warn"}, cfg.Logging.Levels)
+	})
+
+	t.Run("flags and environment win", func(t *testing.T) {
+		t.Setenv("IGSCRAPER_PROFILE", "slow")
+		t.Setenv("IGSCRAPER_CONCURRENT_DOWNLOADS", "5")
+		cfg, err := Load(configPath, map[string]interface{}{"requests-per-minute": 40})
+		require.NoError(t, err)
+
+		assert.Equal(t, 40, cfg.RateLimit.RequestsPerMinute)
+		assert.Equal(t, 5, cfg.Download.ConcurrentDownloads)
+	})
+
+	t.Run("without a profile", func(t *testing.T) {
+		cfg, err := Load(configPath, nil)
+		require.NoError(t, err)
+
+		assert.Equal(t, 60, cfg.RateLimit.RequestsPerMinute)
+		assert.Equal(t, 3, cfg.Download.ConcurrentDownloads)
+	})
+
+	t.Run("unknown profile", func(t *testing.T) {
+		_, err := Load(configPath, map[string]interface{}{"profile": "fast"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `unknown profile "fast" (available: archive, slow)`)
+
+		err = DefaultConfig().UseProfile("slow")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "no profiles")
+	})
+}
@@ -0,0 +1,310 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CurrentVersion is the version of the config file layout this build reads.
+// Files without a version setting are version 1.
+const CurrentVersion = 2
+
+// Deprecation is a setting of an older config file layout that was moved or
+// dropped when the file was migrated
+type Deprecation struct {
+	Line        int    // of the setting in the file, from 1
+	Setting     string // as written, such as download.output
+	Replacement string // the setting that took its value, "" if it was dropped
+}
+
+func (d Deprecation) String() string {
+	if d.Replacement == "" {
+		return fmt.Sprintf("line %d: %s is no longer used", d.Line, d.Setting)
+	}
+	return fmt.Sprintf("line %d: %s is now %s", d.Line, d.Setting, d.Replacement)
+}
+
+// change moves a setting of an older layout to its new place, converting
+// its value if convert is set, or drops it if to is empty
+type change struct {
+	from, to string
+	convert  func(value *yaml.Node)
+}
+
+// migrations are the changes to go from the version before each one, in
+// order
+var migrations = []struct {
+	version int
+	changes []change
+}{
+	{version: 2, changes: []change{
+		// The layout written by config init and described in the manual
+		// before there were versions
+		{from: "download.output", to: "output.base_directory"},
+		{from: "download.output_dir", to: "output.base_directory"},
+		{from: "download.overwrite_existing", to: "output.overwrite_existing"},
+		{from: "download.timeout", to: "download.download_timeout", convert: seconds},
+		{from: "download.retry_delay", to: "rate_limit.retry_delay"},
+		{from: "download.high_quality"},
+		{from: "download.save_metadata"},
+		{from: "rate_limit.burst_enabled"},
+		{from: "rate_limit.download_delay"},
+		{from: "retry.max_retries", to: "retry.max_attempts"},
+		{from: "retry.initial_backoff", to: "retry.base_delay", convert: seconds},
+		{from: "retry.max_backoff", to: "retry.max_delay", convert: seconds},
+		{from: "logging.format", to: "logging.format", convert: rename("text", "console")},
+		{from: "ui.notifications_enabled", to: "notifications.enabled"},
+		{from: "ui.show_notifications", to: "notifications.enabled"},
+		{from: "ui.color_enabled"},
+		{from: "ui.progress_enabled"},
+		{from: "ui.show_speed"},
+		{from: "ui.update_interval"},
+		{from: "ui.progress_bar_style"},
+		{from: "storage.create_user_dir", to: "output.create_user_folders"},
+		{from: "storage.dir_permissions"},
+		{from: "storage.file_permissions"},
+		{from: "storage.save_metadata"},
+		{from: "storage.metadata_format"},
+		{from: "auth.storage_type"},
+	}},
+}
+
+// MigrateFile upgrades the config file at path to the current layout,
+// keeping its comments, and returns the settings it moved or dropped. The
+// original is kept next to it with a .bak extension. A file that is already
+// current is left alone.
+func MigrateFile(path string) ([]Deprecation, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	migrated, deprecated, err := Migrate(data)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(migrated, data) {
+		return nil, nil
+	}
+
+	if err := os.WriteFile(path+".bak", data, 0600); err != nil {
+		return nil, fmt.Errorf("failed to back up config file: %w", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, migrated, info.Mode().Perm()); err != nil {
+		return nil, fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return nil, fmt.Errorf("failed to write config file: %w", err)
+	}
+	return deprecated, nil
+}
+
+// Migrate upgrades the contents of a config file to the current layout and
+// returns them with the settings it moved or dropped. Current files are
+// returned unchanged. It fails for files newer than this build.
+func Migrate(data []byte) ([]byte, []Deprecation, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return data, nil, nil
+	}
+	root := doc.Content[0]
+
+	version, err := fileVersion(root)
+	if err != nil {
+		return nil, nil, err
+	}
+	if version == CurrentVersion {
+		return data, nil, nil
+	}
+
+	deprecated := migrate(root, version)
+	setVersion(root)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, nil, fmt.Errorf("failed to write config file: %w", err)
+	}
+	encoder.Close()
+	return buf.Bytes(), deprecated, nil
+}
+
+// fileVersion returns the layout version of a config file
+func fileVersion(root *yaml.Node) (int, error) {
+	_, value := lookup(root, "version")
+	if value == nil {
+		return 1, nil
+	}
+	version, err := strconv.Atoi(value.Value)
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("line %d: invalid config file version %q", value.Line, value.Value)
+	}
+	if version > CurrentVersion {
+		return 0, fmt.Errorf("config file version %d is newer than this igscraper supports (%d), upgrade igscraper", version, CurrentVersion)
+	}
+	return version, nil
+}
+
+// migrate applies the migrations after version to the mapping at the top
+// of a config file
+func migrate(root *yaml.Node, version int) []Deprecation {
+	var deprecated []Deprecation
+	for _, m := range migrations {
+		if m.version <= version {
+			continue
+		}
+		for _, ch := range m.changes {
+			if ch.to == ch.from {
+				// Only the value changes
+				if _, value := lookup(root, ch.from); value != nil {
+					ch.convert(value)
+				}
+				continue
+			}
+			key, value := remove(root, ch.from)
+			if key == nil {
+				continue
+			}
+			if ch.convert != nil {
+				ch.convert(value)
+			}
+			deprecated = append(deprecated, Deprecation{Line: key.Line, Setting: ch.from, Replacement: ch.to})
+			if ch.to == "" {
+				continue
+			}
+			// A setting already in its new place wins over the old one
+			if _, existing := lookup(root, ch.to); existing == nil {
+				put(root, ch.to, key, value)
+			}
+		}
+	}
+	dropEmpty(root)
+	sort.SliceStable(deprecated, func(i, j int) bool { return deprecated[i].Line < deprecated[j].Line })
+	return deprecated
+}
+
+// lookup returns the key and value nodes of the setting at path, or nils
+func lookup(node *yaml.Node, path string) (*yaml.Node, *yaml.Node) {
+	name, rest, nested := strings.Cut(path, ".")
+	if node.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value != name {
+			continue
+		}
+		if !nested {
+			return node.Content[i], node.Content[i+1]
+		}
+		return lookup(node.Content[i+1], rest)
+	}
+	return nil, nil
+}
+
+// remove takes the setting at path out of the file, returning its key and
+// value nodes, or nils if it is not there
+func remove(node *yaml.Node, path string) (*yaml.Node, *yaml.Node) {
+	name, rest, nested := strings.Cut(path, ".")
+	if node.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value != name {
+			continue
+		}
+		if nested {
+			return remove(node.Content[i+1], rest)
+		}
+		key, value := node.Content[i], node.Content[i+1]
+		node.Content = append(node.Content[:i], node.Content[i+2:]...)
+		return key, value
+	}
+	return nil, nil
+}
+
+// put sets the setting at path, creating the sections it is in. The key
+// keeps the line and comments of the one it replaces.
+func put(node *yaml.Node, path string, key, value *yaml.Node) {
+	name, rest, nested := strings.Cut(path, ".")
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value != name {
+			continue
+		}
+		if !nested {
+			node.Content[i+1] = value
+			return
+		}
+		section := node.Content[i+1]
+		if section.Kind != yaml.MappingNode {
+			section = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			node.Content[i+1] = section
+		}
+		put(section, rest, key, value)
+		return
+	}
+
+	if !nested {
+		moved := *key
+		moved.Value = name
+		node.Content = append(node.Content, &moved, value)
+		return
+	}
+	section := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name, Line: key.Line}, section)
+	put(section, rest, key, value)
+}
+
+// dropEmpty removes the sections of the top mapping that migrations left
+// without settings
+func dropEmpty(root *yaml.Node) {
+	for i := 0; i+1 < len(root.Content); {
+		if value := root.Content[i+1]; value.Kind == yaml.MappingNode && len(value.Content) == 0 {
+			root.Content = append(root.Content[:i], root.Content[i+2:]...)
+			continue
+		}
+		i += 2
+	}
+}
+
+// setVersion writes the current version at the top of the file
+func setVersion(root *yaml.Node) {
+	remove(root, "version")
+	key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "version"}
+	value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(CurrentVersion)}
+	root.Content = append([]*yaml.Node{key, value}, root.Content...)
+}
+
+// seconds converts a number of seconds to a duration such as 30s
+func seconds(value *yaml.Node) {
+	if value.Kind != yaml.ScalarNode {
+		return
+	}
+	if n, err := strconv.Atoi(value.Value); err == nil {
+		value.Tag = "!!str"
+		value.Value = strconv.Itoa(n) + "s"
+	}
+}
+
+// rename returns a conversion that replaces the value old with new
+func rename(old, new string) func(*yaml.Node) {
+	return func(value *yaml.Node) {
+		if value.Kind == yaml.ScalarNode && strings.EqualFold(value.Value, old) {
+			value.Value = new
+		}
+	}
+}
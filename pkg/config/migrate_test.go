@@ -0,0 +1,137 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// oldConfig is a file in the layout config init wrote before versions
+const oldConfig = `# Instagram credentials
+instagram:
+  session_id: "file_session"
+  csrf_token: "file_csrf"
+
+download:
+  # Output directory for downloads
+  output: "/old/output"
+  concurrent_downloads: 2
+  timeout: 45
+  overwrite_existing: true
+
+rate_limit:
+  requests_per_minute: 30
+  burst_enabled: true
+
+retry:
+  max_retries: 5
+  initial_backoff: 2
+
+logging:
+  level: "info"
+  format: "text"
+
+ui:
+  color_enabled: true
+  notifications_enabled: false
+
+storage:
+  create_user_dir: false
+`
+
+func TestMigrate(t *testing.T) {
+	migrated, deprecated, err := Migrate([]byte(oldConfig))
+	require.NoError(t, err)
+
+	assert.Contains(t, deprecated, Deprecation{Line: 8, Setting: "download.output", Replacement: "output.base_directory"})
+	assert.Contains(t, deprecated, Deprecation{Line: 15, Setting: "rate_limit.burst_enabled"})
+	assert.Contains(t, deprecated, Deprecation{Line: 27, Setting: "ui.notifications_enabled", Replacement: "notifications.enabled"})
+	assert.Len(t, deprecated, 9)
+	for i := 1; i < len(deprecated); i++ {
+		assert.LessOrEqual(t, deprecated[i-1].Line, deprecated[i].Line)
+	}
+
+	// The result is a current file, keeping the comments
+	assert.Contains(t, string(migrated), "# Output directory for downloads")
+	issues, err := Check(migrated)
+	require.NoError(t, err)
+	assert.Empty(t, issues)
+
+	var cfg Config
+	require.NoError(t, yaml.Unmarshal(migrated, &cfg))
+	assert.Equal(t, CurrentVersion, cfg.Version)
+	assert.Equal(t, "/old/output", cfg.Output.BaseDirectory)
+	assert.True(t, cfg.Output.OverwriteExisting)
+	assert.False(t, cfg.Output.CreateUserFolders)
+	assert.Equal(t, 45*time.Second, cfg.Download.DownloadTimeout)
+	assert.Equal(t, 2, cfg.Download.ConcurrentDownloads)
+	assert.Equal(t, 5, cfg.Retry.MaxAttempts)
+	assert.Equal(t, 2*time.Second, cfg.Retry.BaseDelay)
+	assert.Equal(t, "console", cfg.Logging.Format)
+	assert.False(t, cfg.Notifications.Enabled)
+
+	// Migrating again changes nothing
+	again, deprecated, err := Migrate(migrated)
+	require.NoError(t, err)
+	assert.Empty(t, deprecated)
+	assert.Equal(t, string(migrated), string(again))
+}
+
+func TestMigrateKeepsCurrentSettings(t *testing.T) {
+	migrated, deprecated, err := Migrate([]byte("output:\n  base_directory: /new\ndownload:\n  output: /old\n"))
+	require.NoError(t, err)
+	assert.Len(t, deprecated, 1)
+
+	var cfg Config
+	require.NoError(t, yaml.Unmarshal(migrated, &cfg))
+	assert.Equal(t, "/new", cfg.Output.BaseDirectory)
+}
+
+func TestMigrateVersions(t *testing.T) {
+	_, _, err := Migrate([]byte("version: 99\n"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "newer than this igscraper supports")
+
+	_, _, err = Migrate([]byte("version: latest\n"))
+	assert.Error(t, err)
+}
+
+func TestLoadOldConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(oldConfig), 0600))
+
+	cfg, err := Load(path, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "/old/output", cfg.Output.BaseDirectory)
+	assert.Equal(t, 45*time.Second, cfg.Download.DownloadTimeout)
+	assert.Len(t, cfg.Deprecated(), 9)
+
+	// The file is only rewritten by MigrateFile
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, oldConfig, string(data))
+
+	deprecated, err := MigrateFile(path)
+	require.NoError(t, err)
+	assert.Len(t, deprecated, 9)
+	backup, err := os.ReadFile(path + ".bak")
+	require.NoError(t, err)
+	assert.Equal(t, oldConfig, string(backup))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	cfg, err = Load(path, nil)
+	require.NoError(t, err)
+	assert.Empty(t, cfg.Deprecated())
+	assert.Equal(t, "/old/output", cfg.Output.BaseDirectory)
+
+	deprecated, err = MigrateFile(path)
+	require.NoError(t, err)
+	assert.Empty(t, deprecated)
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// DefaultWatchInterval is how often Watch checks the config file for changes
+const DefaultWatchInterval = 2 * time.Second
+
+// reloadable are the settings, or whole sections of them, that a running
+// daemon applies when the config file changes. Others need a restart.
+var reloadable = []string{
+	"rate_limit.requests_per_minute",
+	"download.max_bandwidth",
+	"logging.level",
+	"logging.levels",
+	"notifications",
+}
+
+// FindFile returns the config file Load reads for configPath: configPath
+// itself, or the first file in the default locations if it is empty. It
+// returns "" if there is no such file.
+func FindFile(configPath string) string {
+	if configPath != "" {
+		return configPath
+	}
+	return (&Config{}).findConfigFile()
+}
+
+// Reloadable reports whether a setting named as by Changes can change while
+// the daemon runs
+func Reloadable(setting string) bool {
+	for _, name := range reloadable {
+		if setting == name || strings.HasPrefix(setting, name+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// Changes returns the settings that differ between two configurations, named
+// by their path in the config file, such as rate_limit.requests_per_minute.
+// Settings that are not read from the file are left out.
+func Changes(old, updated *Config) []string {
+	return changes("", reflect.ValueOf(*old), reflect.ValueOf(*updated))
+}
+
+// changes compares the fields of two structs of the same type, descending
+// into nested sections
+func changes(prefix string, old, updated reflect.Value) []string {
+	var changed []string
+	for i := 0; i < old.NumField(); i++ {
+		field := old.Type().Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		name = prefix + name
+
+		a, b := old.Field(i), updated.Field(i)
+		if a.Kind() == reflect.Struct && a.Type() != reflect.TypeOf(time.Time{}) {
+			changed = append(changed, changes(name+".", a, b)...)
+			continue
+		}
+		if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
+
+// Watch checks the config file at path every interval and calls onChange
+// when its modification time or size changes, until ctx is done. Editors
+// that replace the file rather than write to it are noticed too. A missing
+// file, as while an editor swaps it, is not a change.
+func Watch(ctx context.Context, path string, interval time.Duration, onChange func()) {
+	stamp := func() (time.Time, int64) {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, -1
+		}
+		return info.ModTime(), info.Size()
+	}
+
+	modTime, size := stamp()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		t, n := stamp()
+		if n < 0 || (t.Equal(modTime) && n == size) {
+			continue
+		}
+		modTime, size = t, n
+		onChange()
+	}
+}
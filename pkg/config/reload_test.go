@@ -0,0 +1,97 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChanges(t *testing.T) {
+	old := DefaultConfig()
+	updated := DefaultConfig()
+	assert.Empty(t, Changes(old, updated))
+
+	updated.RateLimit.RequestsPerMinute = 30
+	updated.Logging.Levels = map[string]string{"instagram": "debug"}
+	updated.Notifications.Email.To = []string{"me@example.com"}
+	updated.Download.Autoscale.Enabled = true
+	updated.Download.Since = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	updated.Output.BaseDirectory = "/elsewhere"
+	updated.Instagram.DeviceID = "not in the file"
+
+	changed := Changes(old, updated)
+	assert.ElementsMatch(t, []string{
+		"rate_limit.requests_per_minute",
+		"logging.levels",
+		"notifications.email.to",
+		"download.autoscale.enabled",
+		"download.since",
+		"output.base_directory",
+	}, changed)
+}
+
+func TestReloadable(t *testing.T) {
+	for _, setting := range []string{"rate_limit.requests_per_minute", "download.max_bandwidth", "logging.level", "logging.levels", "notifications.enabled", "notifications.email.to"} {
+		assert.True(t, Reloadable(setting), setting)
+	}
+	for _, setting := range []string{"rate_limit.shared_file", "download.concurrent_downloads", "logging.file", "logging.levels_extra", "output.base_directory", "daemon.profiles", "instagram.session_id"} {
+		assert.False(t, Reloadable(setting), setting)
+	}
+}
+
+func TestFindFile(t *testing.T) {
+	assert.Equal(t, "custom.yaml", FindFile("custom.yaml"))
+
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(tempDir))
+	defer os.Chdir(wd)
+
+	assert.Empty(t, FindFile(""))
+	require.NoError(t, os.WriteFile(".igscraper.yaml", []byte("{}"), 0644))
+	assert.Equal(t, ".igscraper.yaml", FindFile(""))
+}
+
+func TestWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("rate_limit:\n  requests_per_minute: 60\n"), 0644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changed := make(chan struct{}, 10)
+	done := make(chan struct{})
+	go func() {
+		Watch(ctx, path, 10*time.Millisecond, func() { changed <- struct{}{} })
+		close(done)
+	}()
+
+	// Nothing changes until the file does
+	select {
+	case <-changed:
+		t.Fatal("Expected no change before the file is written")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// An editor replaces the file
+	replacement := path + ".tmp"
+	require.NoError(t, os.WriteFile(replacement, []byte("rate_limit:\n  requests_per_minute: 30\n"), 0644))
+	require.NoError(t, os.Rename(replacement, path))
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the change to be noticed")
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected Watch to return when the context is done")
+	}
+}
@@ -0,0 +1,428 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"igscraper/pkg/ratelimit"
+)
+
+// Issue is a problem with one setting of a config file
+type Issue struct {
+	Line    int    // of the setting in the file, from 1
+	Setting string // path such as download.concurrent_downloads
+	Message string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("line %d: %s: %s", i.Line, i.Setting, i.Message)
+}
+
+// rule checks the value of a setting, returning what is wrong with it or ""
+type rule func(v reflect.Value) string
+
+// rules are the values settings may take, beyond their type. They are named
+// by the setting's path, with * for the keys of a map and nothing for the
+// items of a list, and are checked wherever the setting is written, profiles
+// included. Checks that involve several settings are left to Validate.
+var rules = map[string]rule{
+	"instagram.rotate_after":       positive,
+	"instagram.session_keep_alive": nonNegative,
+
+	"rate_limit.requests_per_minute": between(1, 120),
+	"rate_limit.burst_size":          positive,
+	"rate_limit.max_retries":         nonNegative,
+
+	"retry.max_attempts":              between(0, 10),
+	"retry.multiplier":                nonNegative,
+	"retry.jitter_factor":             between(0, 1),
+	"retry.endpoints.*.max_attempts":  between(0, 10),
+	"retry.endpoints.*.base_delay":    nonNegative,
+	"retry.endpoints.*.max_delay":     nonNegative,
+	"retry.endpoints.*.multiplier":    nonNegative,
+	"retry.endpoints.*.jitter_factor": between(0, 1),
+	"retry.endpoints.*.retry_on":      oneOf("network", "rate_limit", "server_error", "auth", "not_found", "parsing", "unknown"),
+
+	"transport.max_idle_conns":          nonNegative,
+	"transport.max_idle_conns_per_host": nonNegative,
+	"transport.max_conns_per_host":      nonNegative,
+	"transport.idle_conn_timeout":       nonNegative,
+	"transport.dial_timeout":            nonNegative,
+	"transport.keep_alive":              nonNegative,
+	"transport.tls_handshake_timeout":   nonNegative,
+	"transport.tls_min_version":         oneOf("", "1.2", "1.3"),
+
+	"output.base_directory":     nonEmpty,
+	"output.file_name_pattern":  nonEmpty,
+	"output.refresh_newer_than": nonNegative,
+	"output.gallery_thumbnails": nonNegative,
+	"output.existing_archive":   oneOf("", "warn", "use"),
+
+	"download.concurrent_downloads":  between(1, 10),
+	"download.download_timeout":      positive,
+	"download.job_timeout":           nonNegative,
+	"download.shutdown_timeout":      nonNegative,
+	"download.retry_attempts":        nonNegative,
+	"download.order":                 oneOf("", "newest", "oldest"),
+	"download.min_file_size":         nonNegative,
+	"download.max_file_size":         nonNegative,
+	"download.video_chunks":          between(0, 16),
+	"download.max_comments":          positive,
+	"download.max_bandwidth":         bandwidth,
+	"download.min_free_space":        nonNegative,
+	"download.max_total_size":        nonNegative,
+	"download.preferred_resolution":  nonNegative,
+	"download.min_likes":             nonNegative,
+	"download.max_posts":             nonNegative,
+	"download.max_pages":             nonNegative,
+	"download.prefetch_pages":        between(0, 5),
+	"download.skip_synced_within":    nonNegative,
+	"download.postprocess.quality":   between(0, 100),
+	"download.autoscale.max_workers": between(0, 10),
+
+	"logging.level":          oneOf("debug", "info", "warn", "error"),
+	"logging.levels.*":       oneOf("debug", "info", "warn", "error"),
+	"logging.format":         oneOf("", "console", "json"),
+	"logging.sampling.debug": nonNegative,
+	"logging.sampling.info":  nonNegative,
+	"logging.sampling.warn":  nonNegative,
+
+	"notifications.notification_type": oneOf("terminal", "desktop", "none"),
+	"notifications.email.port":        between(1, 65535),
+
+	"hooks.timeout": positive,
+
+	"daemon.default_interval":  positive,
+	"daemon.status_interval":   nonNegative,
+	"daemon.profiles.username": nonEmpty,
+	"daemon.profiles.interval": nonNegative,
+}
+
+// mapKeys are the keys allowed in the maps that have a fixed set of them
+var mapKeys = map[string][]string{
+	"retry.endpoints": {"profile", "media_page", "photo_download"},
+	"logging.levels":  {"instagram", "downloader", "ratelimit", "scraper", "checkpoint", "storage", "daemon", "notify", "hooks"},
+}
+
+// CheckFile reads the config file at path strictly, reporting the settings
+// Load would silently ignore, such as misspelt ones, and those whose values
+// have the wrong type or are out of range, with the lines they are on. It
+// fails only if the file cannot be read or is not YAML.
+func CheckFile(path string) ([]Issue, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	return Check(data)
+}
+
+// Check is CheckFile for the contents of a config file
+func Check(data []byte) ([]Issue, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+
+	// Settings of an older layout are checked where Load reads them, and
+	// keep the lines they are on
+	if root := doc.Content[0]; root.Kind == yaml.MappingNode {
+		version, err := fileVersion(root)
+		if err != nil {
+			return nil, err
+		}
+		migrate(root, version)
+	}
+
+	c := &checker{}
+	c.section(doc.Content[0], reflect.TypeOf(Config{}), "", "", true)
+	return c.issues, nil
+}
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+// checker walks a config file alongside the Config type
+type checker struct {
+	issues []Issue
+}
+
+func (c *checker) add(node *yaml.Node, setting, message string) {
+	c.issues = append(c.issues, Issue{Line: node.Line, Setting: setting, Message: message})
+}
+
+// section checks a mapping against the fields of a struct. setting names it
+// in issues, and name in rules. The top section may hold profiles.
+func (c *checker) section(node *yaml.Node, t reflect.Type, setting, name string, top bool) {
+	if node.Kind != yaml.MappingNode {
+		c.add(node, setting, "expected a section of settings")
+		return
+	}
+
+	fields := make(map[string]reflect.StructField)
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		fields[tag] = field
+		names = append(names, tag)
+	}
+	if top {
+		names = append(names, "profiles")
+	}
+
+	seen := make(map[string]bool)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		path := join(setting, key.Value)
+		if seen[key.Value] {
+			c.add(key, path, "set more than once")
+			continue
+		}
+		seen[key.Value] = true
+
+		if top && key.Value == "profiles" {
+			c.profiles(value)
+			continue
+		}
+		field, ok := fields[key.Value]
+		if !ok {
+			c.add(key, path, unknown("setting", key.Value, names))
+			continue
+		}
+		c.value(value, field.Type, path, join(name, key.Value))
+	}
+}
+
+// profiles checks each profile as a config file of its own
+func (c *checker) profiles(node *yaml.Node) {
+	if isNull(node) {
+		return
+	}
+	if node.Kind != yaml.MappingNode {
+		c.add(node, "profiles", "expected a section of profiles")
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if isNull(value) {
+			continue
+		}
+		c.section(value, reflect.TypeOf(Config{}), "profiles."+key.Value, "", false)
+	}
+}
+
+// value checks a setting of type t
+func (c *checker) value(node *yaml.Node, t reflect.Type, setting, name string) {
+	if node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	if isNull(node) {
+		return
+	}
+
+	switch {
+	case t == timeType || t == durationType:
+	case t.Kind() == reflect.Struct:
+		c.section(node, t, setting, name, false)
+		return
+	case t.Kind() == reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			c.add(node, setting, "expected a map")
+			return
+		}
+		allowed := mapKeys[name]
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if allowed != nil && !contains(allowed, strings.ToLower(key.Value)) {
+				c.add(key, join(setting, key.Value), unknown("key", key.Value, allowed))
+				continue
+			}
+			c.value(value, t.Elem(), join(setting, key.Value), name+".*")
+		}
+		return
+	case t.Kind() == reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			c.add(node, setting, "expected a list")
+			return
+		}
+		for i, item := range node.Content {
+			c.value(item, t.Elem(), fmt.Sprintf("%s[%d]", setting, i), name)
+		}
+		return
+	}
+
+	if node.Kind != yaml.ScalarNode {
+		c.add(node, setting, "expected "+describe(t))
+		return
+	}
+	v := reflect.New(t)
+	if err := node.Decode(v.Interface()); err != nil {
+		c.add(node, setting, "expected "+describe(t))
+		return
+	}
+	if check, ok := rules[name]; ok {
+		if message := check(v.Elem()); message != "" {
+			c.add(node, setting, message)
+		}
+	}
+}
+
+// describe names the values of type t for an issue
+func describe(t reflect.Type) string {
+	switch {
+	case t == timeType:
+		return "a date such as 2024-01-31"
+	case t == durationType:
+		return "a duration such as 30s"
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "a whole number"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	default:
+		return "a single value"
+	}
+}
+
+// unknown describes a key that is not one of names, suggesting the closest
+// if it looks like a typo
+func unknown(kind, key string, names []string) string {
+	message := "unknown " + kind
+	best, distance := "", 3
+	for _, name := range names {
+		if d := editDistance(strings.ToLower(key), name); d < distance {
+			best, distance = name, d
+		}
+	}
+	if best != "" {
+		message += fmt.Sprintf(", did you mean %s?", best)
+	}
+	return message
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+func join(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+func isNull(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.Tag == "!!null"
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// number returns a numeric value as a float64
+func number(v reflect.Value) float64 {
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint())
+	default:
+		return float64(v.Int())
+	}
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func positive(v reflect.Value) string {
+	if number(v) <= 0 {
+		return "must be positive"
+	}
+	return ""
+}
+
+func nonNegative(v reflect.Value) string {
+	if number(v) < 0 {
+		return "cannot be negative"
+	}
+	return ""
+}
+
+func between(lo, hi float64) rule {
+	return func(v reflect.Value) string {
+		if n := number(v); n < lo || n > hi {
+			return fmt.Sprintf("must be between %s and %s", formatNumber(lo), formatNumber(hi))
+		}
+		return ""
+	}
+}
+
+func oneOf(values ...string) rule {
+	return func(v reflect.Value) string {
+		if contains(values, strings.ToLower(v.String())) {
+			return ""
+		}
+		var named []string
+		for _, value := range values {
+			if value != "" {
+				named = append(named, value)
+			}
+		}
+		return fmt.Sprintf("must be one of %s", strings.Join(named, ", "))
+	}
+}
+
+func nonEmpty(v reflect.Value) string {
+	if strings.TrimSpace(v.String()) == "" {
+		return "cannot be empty"
+	}
+	return ""
+}
+
+func bandwidth(v reflect.Value) string {
+	if _, err := ratelimit.ParseBandwidth(v.String()); err != nil {
+		return err.Error()
+	}
+	return ""
+}
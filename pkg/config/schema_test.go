@@ -0,0 +1,148 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+	t.Run("valid file", func(t *testing.T) {
+		issues, err := Check([]byte(`
+instagram:
+  session_id: abc
+rate_limit:
+  requests_per_minute: 30
+  retry_delay: 10s
+download:
+  since: 2024-01-31
+  postprocess:
+    - step: resize
+      quality: 80
+logging:
+  levels:
+    Instagram: debug
+daemon:
+  profiles:
+    - username: natgeo
+      interval: 1h
+profiles:
+  slow:
+    rate_limit:
+      requests_per_minute: 10
+`))
+		require.NoError(t, err)
+		assert.Empty(t, issues)
+	})
+
+	t.Run("unknown keys", func(t *testing.T) {
+		issues, err := Check([]byte(`instagram:
+  session_id: abc
+download:
+  concurent_downloads: 5
+  destination: ./downloads
+storage:
+  compress: true
+logging:
+  levels:
+    instagarm: debug
+profiles:
+  slow:
+    rate_limits:
+      requests_per_minute: 10
+`))
+		require.NoError(t, err)
+		assert.Equal(t, []Issue{
+			{Line: 4, Setting: "download.concurent_downloads", Message: "unknown setting, did you mean concurrent_downloads?"},
+			{Line: 5, Setting: "download.destination", Message: "unknown setting"},
+			{Line: 6, Setting: "storage", Message: "unknown setting"},
+			{Line: 10, Setting: "logging.levels.instagarm", Message: "unknown key, did you mean instagram?"},
+			{Line: 13, Setting: "profiles.slow.rate_limits", Message: "unknown setting, did you mean rate_limit?"},
+		}, issues)
+	})
+
+	t.Run("types and ranges", func(t *testing.T) {
+		issues, err := Check([]byte(`rate_limit:
+  requests_per_minute: 500
+  retry_delay: soon
+download:
+  concurrent_downloads: many
+  skip_videos: maybe
+  order: random
+  max_bandwidth: fast
+  postprocess:
+    - step: convert
+      quality: 120
+retry:
+  endpoints:
+    profile:
+      retry_on: [network, timeout]
+output: ./downloads
+daemon:
+  profiles:
+    - username: ""
+rate_limit:
+  burst_size: 5
+`))
+		require.NoError(t, err)
+
+		lines := make(map[string]int)
+		for _, issue := range issues {
+			lines[issue.Setting] = issue.Line
+		}
+		assert.Equal(t, map[string]int{
+			"rate_limit.requests_per_minute":      2,
+			"rate_limit.retry_delay":              3,
+			"download.concurrent_downloads":       5,
+			"download.skip_videos":                6,
+			"download.order":                      7,
+			"download.max_bandwidth":              8,
+			"download.postprocess[0].quality":     11,
+			"retry.endpoints.profile.retry_on[1]": 15,
+			"output":                              16,
+			"daemon.profiles[0].username":         19,
+			"rate_limit":                          20,
+		}, lines)
+		for _, issue := range issues {
+			switch issue.Setting {
+			case "rate_limit.requests_per_minute":
+				assert.Equal(t, "must be between 1 and 120", issue.Message)
+			case "rate_limit.retry_delay":
+				assert.Equal(t, "expected a duration such as 30s", issue.Message)
+			case "download.skip_videos":
+				assert.Equal(t, "expected true or false", issue.Message)
+			case "output":
+				assert.Equal(t, "expected a section of settings", issue.Message)
+			case "rate_limit":
+				assert.Equal(t, "set more than once", issue.Message)
+			}
+		}
+	})
+
+	t.Run("invalid yaml", func(t *testing.T) {
+		_, err := Check([]byte("rate_limit:\n  requests_per_minute: [\n"))
+		assert.Error(t, err)
+	})
+
+	t.Run("empty file", func(t *testing.T) {
+		issues, err := Check(nil)
+		require.NoError(t, err)
+		assert.Empty(t, issues)
+	})
+}
+
+func TestCheckFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("download:\n  concurrent_downloads: 20\n"), 0644))
+
+	issues, err := CheckFile(path)
+	require.NoError(t, err)
+	require.Len(t, issues, 1)
+	assert.Equal(t, "line 2: download.concurrent_downloads: must be between 1 and 10", issues[0].String())
+
+	_, err = CheckFile(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
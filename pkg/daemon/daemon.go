@@ -0,0 +1,292 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"igscraper/pkg/config"
+	"igscraper/pkg/logger"
+)
+
+// ScrapeFunc performs one incremental scrape of a profile
+type ScrapeFunc func(ctx context.Context, username string) error
+
+// ProfileStatus is a snapshot of the schedule and history of one profile
+type ProfileStatus struct {
+	Username     string        `json:"username"`
+	Interval     time.Duration `json:"interval"`
+	Running      bool          `json:"running"`
+	Runs         int           `json:"runs"`
+	Failures     int           `json:"failures"`
+	LastRun      time.Time     `json:"last_run,omitempty"`
+	LastDuration time.Duration `json:"last_duration"`
+	LastError    string        `json:"last_error,omitempty"`
+	NextRun      time.Time     `json:"next_run"`
+}
+
+// Daemon schedules scrapes for the configured profiles
+type Daemon struct {
+	cfg     config.DaemonConfig
+	scrape  ScrapeFunc
+	logger  logger.Logger
+	started time.Time
+	now     func() time.Time
+
+	mu       sync.Mutex
+	profiles []*ProfileStatus
+}
+
+// New creates a daemon for the profiles in cfg
+func New(cfg config.DaemonConfig, scrape ScrapeFunc, log logger.Logger) (*Daemon, error) {
+	if scrape == nil {
+		return nil, errors.New("scrape function is required")
+	}
+	if len(cfg.Profiles) == 0 {
+		return nil, errors.New("no profiles configured in the daemon section")
+	}
+	if cfg.DefaultInterval <= 0 {
+		return nil, errors.New("daemon default interval must be positive")
+	}
+	if log == nil {
+		log = logger.NewNopLogger()
+	}
+
+	d := &Daemon{
+		cfg:    cfg,
+		scrape: scrape,
+		logger: log.WithField("component", "daemon"),
+		now:    time.Now,
+	}
+
+	for _, profile := range cfg.Profiles {
+		username := strings.TrimSpace(profile.Username)
+		if username == "" {
+			return nil, errors.New("daemon profile username is required")
+		}
+		d.profiles = append(d.profiles, &ProfileStatus{
+			Username: username,
+			Interval: cfg.IntervalFor(profile),
+		})
+	}
+
+	return d, nil
+}
+
+// Run scrapes every profile on its schedule until ctx is cancelled. A scrape
+// in progress is allowed to finish before Run returns.
+func (d *Daemon) Run(ctx context.Context) error {
+	d.started = d.now()
+
+	// Every profile is due as soon as the daemon starts
+	d.mu.Lock()
+	for _, p := range d.profiles {
+		p.NextRun = d.started
+	}
+	d.mu.Unlock()
+
+	if d.cfg.MetricsAddr != "" {
+		server, err := d.startMetricsServer()
+		if err != nil {
+			return err
+		}
+		defer server.Close()
+	}
+
+	var statusTick <-chan time.Time
+	if d.cfg.StatusInterval > 0 {
+		ticker := time.NewTicker(d.cfg.StatusInterval)
+		defer ticker.Stop()
+		statusTick = ticker.C
+	}
+
+	d.logger.InfoWithFields("Daemon started", map[string]interface{}{
+		"profiles":         len(d.profiles),
+		"default_interval": d.cfg.DefaultInterval.String(),
+	})
+
+	for {
+		next := d.nextDue()
+		wait := next.NextRun.Sub(d.now())
+		if wait < 0 {
+			wait = 0
+		}
+		timer := time.NewTimer(wait)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			d.logger.Info("Daemon stopped")
+			return nil
+		case <-statusTick:
+			timer.Stop()
+			d.logStatus()
+		case <-timer.C:
+			d.runProfile(ctx, next)
+		}
+	}
+}
+
+// nextDue returns the profile with the earliest next run
+func (d *Daemon) nextDue() *ProfileStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	next := d.profiles[0]
+	for _, p := range d.profiles[1:] {
+		if p.NextRun.Before(next.NextRun) {
+			next = p
+		}
+	}
+	return next
+}
+
+// runProfile scrapes one profile and schedules its next run
+func (d *Daemon) runProfile(ctx context.Context, p *ProfileStatus) {
+	d.mu.Lock()
+	p.Running = true
+	username := p.Username
+	d.mu.Unlock()
+
+	d.logger.WithField("username", username).Info("Scheduled scrape starting")
+
+	start := d.now()
+	err := d.scrape(ctx, username)
+	duration := d.now().Sub(start)
+
+	d.mu.Lock()
+	p.Running = false
+	p.Runs++
+	p.LastRun = start
+	p.LastDuration = duration
+	p.NextRun = start.Add(p.Interval)
+	if err != nil {
+		p.Failures++
+		p.LastError = err.Error()
+	} else {
+		p.LastError = ""
+	}
+	nextRun := p.NextRun
+	d.mu.Unlock()
+
+	fields := map[string]interface{}{
+		"username": username,
+		"duration": duration.String(),
+		"next_run": nextRun.Format(time.RFC3339),
+	}
+	if err != nil {
+		d.logger.WithError(err).WithFields(fields).Error("Scheduled scrape failed")
+		return
+	}
+	d.logger.InfoWithFields("Scheduled scrape completed", fields)
+}
+
+// Status returns a snapshot of every profile, ordered by next run
+func (d *Daemon) Status() []ProfileStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	status := make([]ProfileStatus, 0, len(d.profiles))
+	for _, p := range d.profiles {
+		status = append(status, *p)
+	}
+	sort.SliceStable(status, func(i, j int) bool {
+		return status[i].NextRun.Before(status[j].NextRun)
+	})
+	return status
+}
+
+// logStatus writes a summary of the schedule to the log
+func (d *Daemon) logStatus() {
+	var runs, failures, running int
+	for _, p := range d.Status() {
+		runs += p.Runs
+		failures += p.Failures
+		if p.Running {
+			running++
+		}
+		d.logger.DebugWithFields("Profile status", map[string]interface{}{
+			"username":   p.Username,
+			"runs":       p.Runs,
+			"failures":   p.Failures,
+			"last_error": p.LastError,
+			"next_run":   p.NextRun.Format(time.RFC3339),
+		})
+	}
+
+	logger.LogMetrics("daemon_status", map[string]interface{}{
+		"profiles": len(d.profiles),
+		"runs":     runs,
+		"failures": failures,
+		"running":  running,
+		"uptime":   d.now().Sub(d.started).Round(time.Second).String(),
+	})
+}
+
+// startMetricsServer exposes the status endpoints on the configured address
+func (d *Daemon) startMetricsServer() (*http.Server, error) {
+	listener, err := net.Listen("tcp", d.cfg.MetricsAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start metrics listener: %w", err)
+	}
+
+	server := &http.Server{Handler: d.Handler()}
+	go server.Serve(listener)
+
+	d.logger.WithField("addr", listener.Addr().String()).Info("Daemon metrics listening")
+	return server, nil
+}
+
+// Handler returns an HTTP handler serving /status and /metrics
+func (d *Daemon) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", d.handleStatus)
+	mux.HandleFunc("/metrics", d.handleMetrics)
+	return mux
+}
+
+// handleStatus serves the profile snapshot as JSON
+func (d *Daemon) handleStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"started":  d.started,
+		"profiles": d.Status(),
+	})
+}
+
+// handleMetrics serves per-profile counters in the Prometheus text format
+func (d *Daemon) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	status := d.Status()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	fmt.Fprintln(w, "# HELP igscraper_daemon_runs_total Scheduled scrapes run per profile.")
+	fmt.Fprintln(w, "# TYPE igscraper_daemon_runs_total counter")
+	for _, p := range status {
+		fmt.Fprintf(w, "igscraper_daemon_runs_total{username=%q} %d\n", p.Username, p.Runs)
+	}
+
+	fmt.Fprintln(w, "# HELP igscraper_daemon_failures_total Failed scheduled scrapes per profile.")
+	fmt.Fprintln(w, "# TYPE igscraper_daemon_failures_total counter")
+	for _, p := range status {
+		fmt.Fprintf(w, "igscraper_daemon_failures_total{username=%q} %d\n", p.Username, p.Failures)
+	}
+
+	fmt.Fprintln(w, "# HELP igscraper_daemon_last_run_seconds Duration of the last scrape per profile.")
+	fmt.Fprintln(w, "# TYPE igscraper_daemon_last_run_seconds gauge")
+	for _, p := range status {
+		fmt.Fprintf(w, "igscraper_daemon_last_run_seconds{username=%q} %g\n", p.Username, p.LastDuration.Seconds())
+	}
+
+	fmt.Fprintln(w, "# HELP igscraper_daemon_next_run_timestamp_seconds Unix time of the next scheduled scrape.")
+	fmt.Fprintln(w, "# TYPE igscraper_daemon_next_run_timestamp_seconds gauge")
+	for _, p := range status {
+		fmt.Fprintf(w, "igscraper_daemon_next_run_timestamp_seconds{username=%q} %d\n", p.Username, p.NextRun.Unix())
+	}
+}
@@ -0,0 +1,150 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"igscraper/pkg/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder counts scrapes per profile
+type recorder struct {
+	mu    sync.Mutex
+	calls map[string]int
+	fail  map[string]error
+}
+
+func newRecorder() *recorder {
+	return &recorder{calls: make(map[string]int), fail: make(map[string]error)}
+}
+
+func (r *recorder) scrape(ctx context.Context, username string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls[username]++
+	return r.fail[username]
+}
+
+func (r *recorder) count(username string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.calls[username]
+}
+
+func TestNew_Validation(t *testing.T) {
+	rec := newRecorder()
+
+	_, err := New(config.DaemonConfig{DefaultInterval: time.Hour}, rec.scrape, nil)
+	assert.Error(t, err, "no profiles")
+
+	_, err = New(config.DaemonConfig{
+		DefaultInterval: time.Hour,
+		Profiles:        []config.ProfileSchedule{{Username: "alice"}},
+	}, nil, nil)
+	assert.Error(t, err, "missing scrape function")
+
+	_, err = New(config.DaemonConfig{
+		Profiles: []config.ProfileSchedule{{Username: "alice"}},
+	}, rec.scrape, nil)
+	assert.Error(t, err, "missing default interval")
+
+	d, err := New(config.DaemonConfig{
+		DefaultInterval: time.Hour,
+		Profiles: []config.ProfileSchedule{
+			{Username: "alice", Interval: time.Minute},
+			{Username: " bob "},
+		},
+	}, rec.scrape, nil)
+	require.NoError(t, err)
+
+	status := d.Status()
+	require.Len(t, status, 2)
+	assert.Equal(t, time.Minute, status[0].Interval)
+	assert.Equal(t, "bob", status[1].Username)
+	assert.Equal(t, time.Hour, status[1].Interval)
+}
+
+func TestRun_SchedulesProfiles(t *testing.T) {
+	rec := newRecorder()
+	rec.fail["bob"] = errors.New("profile unavailable")
+
+	d, err := New(config.DaemonConfig{
+		DefaultInterval: time.Hour,
+		Profiles: []config.ProfileSchedule{
+			{Username: "alice", Interval: 20 * time.Millisecond},
+			{Username: "bob"},
+		},
+	}, rec.scrape, nil)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+	defer cancel()
+	require.NoError(t, d.Run(ctx))
+
+	// alice runs repeatedly, bob only once at startup
+	assert.GreaterOrEqual(t, rec.count("alice"), 3)
+	assert.Equal(t, 1, rec.count("bob"))
+
+	for _, p := range d.Status() {
+		assert.False(t, p.Running)
+		if p.Username == "bob" {
+			assert.Equal(t, 1, p.Failures)
+			assert.Equal(t, "profile unavailable", p.LastError)
+			assert.Equal(t, p.LastRun.Add(time.Hour), p.NextRun)
+		} else {
+			assert.Zero(t, p.Failures)
+			assert.Empty(t, p.LastError)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	rec := newRecorder()
+	d, err := New(config.DaemonConfig{
+		DefaultInterval: time.Hour,
+		Profiles:        []config.ProfileSchedule{{Username: "alice"}},
+	}, rec.scrape, nil)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	require.NoError(t, d.Run(ctx))
+
+	server := httptest.NewServer(d.Handler())
+	defer server.Close()
+
+	t.Run("status", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/status")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var body struct {
+			Profiles []ProfileStatus `json:"profiles"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		require.Len(t, body.Profiles, 1)
+		assert.Equal(t, "alice", body.Profiles[0].Username)
+		assert.Equal(t, 1, body.Profiles[0].Runs)
+	})
+
+	t.Run("metrics", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/metrics")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Contains(t, string(body), `igscraper_daemon_runs_total{username="alice"} 1`)
+		assert.Contains(t, string(body), `igscraper_daemon_failures_total{username="alice"} 0`)
+	})
+}
@@ -0,0 +1,44 @@
+// Package daemon runs scheduled, incremental scrapes of a list of profiles.
+//
+// The profiles and their intervals come from the daemon section of the
+// configuration file:
+//
+//	daemon:
+//	  default_interval: 6h
+//	  status_interval: 5m
+//	  metrics_addr: "127.0.0.1:9090"
+//	  profiles:
+//	    - username: natgeo
+//	      interval: 1h
+//	    - username: nasa       # uses default_interval
+//
+// Every profile is scraped once when the daemon starts and then again each
+// time its interval elapses. Scrapes run one at a time, so together with a
+// rate limiter shared by all scrapers they never exceed the global request
+// budget from the rate_limit section. Because downloads skip photos that are
+// already on disk, every run after the first only fetches new posts.
+//
+// The daemon reports its state in three ways:
+//   - a log line for every run, with its duration and outcome
+//   - a periodic status summary logged every status_interval
+//   - an optional HTTP listener on metrics_addr serving /status (JSON) and
+//     /metrics (Prometheus text format)
+//
+// The actual scrape is supplied by the caller as a ScrapeFunc, which keeps
+// this package independent of how the scraper is constructed:
+//
+//	d, err := daemon.New(cfg.Daemon, func(ctx context.Context, username string) error {
+//	    s, err := scraper.New(cfg)
+//	    if err != nil {
+//	        return err
+//	    }
+//	    s.SetRateLimiter(shared)
+//	    return s.DownloadUserPhotosWithResume(username, true, false)
+//	}, logger.GetLogger())
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//
+//	// Blocks until ctx is cancelled
+//	err = d.Run(ctx)
+package daemon
@@ -0,0 +1,26 @@
+// Package demo provides a credential-free, offline stand-in for Instagram.
+//
+// It serves a small fictional profile from fixtures embedded in the binary,
+// so the complete scraping pipeline (profile lookup, GraphQL pagination, the
+// download worker pool, storage, checkpoints and the TUI) can be exercised
+// without an Instagram account or network access.
+//
+// The Server listens on a loopback address. Its Transport rewrites every
+// outgoing request, whatever the host, to that server, which means the
+// regular instagram.Client can be used unchanged:
+//
+//	server, err := demo.NewServer()
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer server.Close()
+//
+//	client := instagram.NewClient(30*time.Second, nil)
+//	client.SetTransport(server.Transport())
+//
+//	// Fetches the embedded demo profile
+//	profile, err := client.FetchUserProfile(demo.Username)
+//
+// Photos are generated on the fly as small JPEG gradients, so the fixtures
+// stay tiny and every shortcode gets a distinct image.
+package demo
@@ -0,0 +1,223 @@
+package demo
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"net"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	// Username is the handle of the fictional demo profile
+	Username = "igscraper_demo"
+
+	// UserID is the numeric ID of the demo profile
+	UserID = "4242424242"
+
+	// imageSize is the edge length of generated demo photos in pixels
+	imageSize = 320
+)
+
+//go:embed fixtures/*.json
+var fixtures embed.FS
+
+// pageCursors maps the "after" cursor of a media request to its fixture
+var pageCursors = map[string]string{
+	"":              "fixtures/media_page_1.json",
+	"DEMO_CURSOR_1": "fixtures/media_page_2.json",
+	"DEMO_CURSOR_2": "fixtures/media_page_3.json",
+}
+
+// Server is a local HTTP server that mimics the Instagram endpoints used by the scraper
+type Server struct {
+	listener   net.Listener
+	httpServer *http.Server
+	latency    time.Duration
+}
+
+// NewServer starts a demo server on a random loopback port
+func NewServer() (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to start demo server: %w", err)
+	}
+
+	s := &Server{listener: listener}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/users/web_profile_info/", s.handleProfile)
+	mux.HandleFunc("/graphql/query/", s.handleMedia)
+	mux.HandleFunc("/v/demo/", s.handlePhoto)
+
+	s.httpServer = &http.Server{Handler: mux}
+	go s.httpServer.Serve(listener)
+
+	return s, nil
+}
+
+// SetLatency adds an artificial delay to every response so progress
+// displays have something to show
+func (s *Server) SetLatency(latency time.Duration) {
+	s.latency = latency
+}
+
+// URL returns the base URL of the server
+func (s *Server) URL() string {
+	return "http://" + s.listener.Addr().String()
+}
+
+// Close shuts the server down
+func (s *Server) Close() error {
+	return s.httpServer.Close()
+}
+
+// Transport returns a RoundTripper that sends every request to the demo server
+func (s *Server) Transport() http.RoundTripper {
+	return &rewriteTransport{
+		host: s.listener.Addr().String(),
+		base: http.DefaultTransport,
+	}
+}
+
+// rewriteTransport redirects requests for any host to the demo server
+type rewriteTransport struct {
+	host string
+	base http.RoundTripper
+}
+
+// RoundTrip rewrites the request URL and forwards it
+func (t *rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	clone := req.Clone(req.Context())
+	clone.URL.Scheme = "http"
+	clone.URL.Host = t.host
+	clone.Host = t.host
+	return t.base.RoundTrip(clone)
+}
+
+// handleProfile serves the web_profile_info endpoint
+func (s *Server) handleProfile(w http.ResponseWriter, r *http.Request) {
+	s.delay()
+
+	if r.URL.Query().Get("username") != Username {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{
+			"message": "User not found",
+			"status":  "fail",
+		})
+		return
+	}
+
+	s.serveFixture(w, "fixtures/profile.json")
+}
+
+// handleMedia serves paginated timeline media from the GraphQL endpoint
+func (s *Server) handleMedia(w http.ResponseWriter, r *http.Request) {
+	s.delay()
+
+	var variables struct {
+		ID    string `json:"id"`
+		After string `json:"after"`
+	}
+	if err := json.Unmarshal([]byte(r.URL.Query().Get("variables")), &variables); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"message": "invalid variables",
+			"status":  "fail",
+		})
+		return
+	}
+
+	fixture, ok := pageCursors[variables.After]
+	if !ok || variables.ID != UserID {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"message": "unknown cursor",
+			"status":  "fail",
+		})
+		return
+	}
+
+	s.serveFixture(w, fixture)
+}
+
+// handlePhoto generates a JPEG whose colours are derived from the shortcode
+func (s *Server) handlePhoto(w http.ResponseWriter, r *http.Request) {
+	s.delay()
+
+	shortcode := strings.TrimSuffix(path.Base(r.URL.Path), ".jpg")
+	data, err := GeneratePhoto(shortcode)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(data)))
+	w.Write(data)
+}
+
+// serveFixture writes an embedded fixture as a JSON response
+func (s *Server) serveFixture(w http.ResponseWriter, name string) {
+	data, err := fixtures.ReadFile(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// delay sleeps for the configured latency
+func (s *Server) delay() {
+	if s.latency > 0 {
+		time.Sleep(s.latency)
+	}
+}
+
+// writeJSON writes a JSON body with the given status code
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// GeneratePhoto renders a deterministic gradient JPEG for a shortcode
+func GeneratePhoto(shortcode string) ([]byte, error) {
+	h := fnv.New32a()
+	h.Write([]byte(shortcode))
+	seed := h.Sum32()
+
+	from := color.RGBA{R: uint8(seed), G: uint8(seed >> 8), B: uint8(seed >> 16), A: 255}
+	to := color.RGBA{R: 255 - from.R, G: 255 - from.G, B: 255 - from.B, A: 255}
+
+	img := image.NewRGBA(image.Rect(0, 0, imageSize, imageSize))
+	for y := 0; y < imageSize; y++ {
+		for x := 0; x < imageSize; x++ {
+			t := float64(x+y) / float64(2*imageSize)
+			img.Set(x, y, color.RGBA{
+				R: blend(from.R, to.R, t),
+				G: blend(from.G, to.G, t),
+				B: blend(from.B, to.B, t),
+				A: 255,
+			})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, fmt.Errorf("failed to encode demo photo: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// blend linearly interpolates between two colour channels
+func blend(a, b uint8, t float64) uint8 {
+	return uint8(float64(a) + (float64(b)-float64(a))*t)
+}
@@ -0,0 +1,115 @@
+package demo
+
+import (
+	"bytes"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"igscraper/pkg/config"
+	"igscraper/pkg/instagram"
+	"igscraper/pkg/logger"
+	"igscraper/pkg/scraper"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(t *testing.T, server *Server) *instagram.Client {
+	t.Helper()
+	client := instagram.NewClient(5*time.Second, logger.NewNopLogger())
+	client.SetTransport(server.Transport())
+	return client
+}
+
+func TestServer_Profile(t *testing.T) {
+	server, err := NewServer()
+	require.NoError(t, err)
+	defer server.Close()
+
+	client := newTestClient(t, server)
+
+	profile, err := client.FetchUserProfile(Username)
+	require.NoError(t, err)
+	assert.Equal(t, UserID, profile.Data.User.ID)
+	assert.Equal(t, 30, profile.Data.User.EdgeOwnerToTimelineMedia.Count)
+
+	_, err = client.FetchUserProfile("someone_else")
+	assert.Error(t, err)
+}
+
+func TestServer_MediaPagination(t *testing.T) {
+	server, err := NewServer()
+	require.NoError(t, err)
+	defer server.Close()
+
+	client := newTestClient(t, server)
+
+	var total, pages int
+	cursor := ""
+	for {
+		resp, err := client.FetchUserMedia(UserID, cursor)
+		require.NoError(t, err)
+
+		media := resp.Data.User.EdgeOwnerToTimelineMedia
+		total += len(media.Edges)
+		pages++
+
+		if !media.PageInfo.HasNextPage {
+			break
+		}
+		cursor = media.PageInfo.EndCursor
+	}
+
+	assert.Equal(t, 3, pages)
+	assert.Equal(t, 30, total)
+
+	_, err = client.FetchUserMedia(UserID, "UNKNOWN")
+	assert.Error(t, err)
+}
+
+func TestGeneratePhoto(t *testing.T) {
+	first, err := GeneratePhoto("DEMO010000")
+	require.NoError(t, err)
+
+	again, err := GeneratePhoto("DEMO010000")
+	require.NoError(t, err)
+	assert.Equal(t, first, again, "photos should be deterministic")
+
+	other, err := GeneratePhoto("DEMO010001")
+	require.NoError(t, err)
+	assert.NotEqual(t, first, other, "shortcodes should produce distinct photos")
+
+	img, err := jpeg.Decode(bytes.NewReader(first))
+	require.NoError(t, err)
+	assert.Equal(t, imageSize, img.Bounds().Dx())
+}
+
+func TestServer_ScraperEndToEnd(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	server, err := NewServer()
+	require.NoError(t, err)
+	defer server.Close()
+
+	outputDir := t.TempDir()
+	cfg := config.DefaultConfig()
+	cfg.Output.BaseDirectory = outputDir
+	cfg.RateLimit.RequestsPerMinute = 6000
+	cfg.Notifications.Enabled = false
+
+	s, err := scraper.New(cfg)
+	require.NoError(t, err)
+	s.SetClient(newTestClient(t, server))
+
+	require.NoError(t, s.DownloadUserPhotosWithResume(Username, false, true))
+
+	photos, err := filepath.Glob(filepath.Join(outputDir, Username+"_photos", "*.jpg"))
+	require.NoError(t, err)
+	assert.NotEmpty(t, photos)
+
+	_, err = os.Stat(filepath.Join(outputDir, Username+"_photos", "metadata.json"))
+	assert.NoError(t, err)
+}
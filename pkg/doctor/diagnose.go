@@ -0,0 +1,211 @@
+package doctor
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Severity ranks a finding
+type Severity string
+
+const (
+	SeverityOK      Severity = "ok"
+	SeverityWarning Severity = "warning"
+	SeverityProblem Severity = "problem"
+)
+
+// slowThreshold is the latency above which a probe is reported as slow
+const slowThreshold = 5 * time.Second
+
+// Finding is one conclusion drawn from the probe results
+type Finding struct {
+	Severity  Severity
+	Title     string
+	Detail    string
+	NextSteps []string
+}
+
+// Diagnose interprets probe results. Problems come first, then warnings; if
+// nothing is wrong a single OK finding is returned.
+func Diagnose(results []Result) []Finding {
+	byName := make(map[string]Result, len(results))
+	for _, r := range results {
+		byName[r.Name] = r
+	}
+	anon := byName[ProbeAnonymousAPI]
+	auth := byName[ProbeAuthenticatedAPI]
+	media := byName[ProbeAuthenticatedMedia]
+	cdn := byName[ProbeCDN]
+
+	if unreachable(results) {
+		return []Finding{{
+			Severity: SeverityProblem,
+			Title:    "Instagram is unreachable",
+			Detail:   fmt.Sprintf("No probe got a response: %v", anon.Err),
+			NextSteps: []string{
+				"Check your internet connection and DNS",
+				"If you use a proxy or VPN, make sure it allows instagram.com",
+			},
+		}}
+	}
+
+	var problems, warnings []Finding
+	authResults := []Result{auth, media}
+
+	switch {
+	case challenged(authResults) != "":
+		problems = append(problems, Finding{
+			Severity: SeverityProblem,
+			Title:    "Account flagged",
+			Detail:   fmt.Sprintf("Instagram answered with %q for the logged-in session.", challenged(authResults)),
+			NextSteps: []string{
+				"Log in at instagram.com in a browser and complete any security challenge",
+				"Pause scraping with this account for 24-48 hours",
+				"Use a lower --rate-limit once the account works again",
+			},
+		})
+	case auth.Skipped == "" && loginWall(authResults):
+		problems = append(problems, Finding{
+			Severity: SeverityProblem,
+			Title:    "Session expired",
+			Detail:   "Logged-in requests are sent to the login page, so the session cookie is no longer valid.",
+			NextSteps: []string{
+				"Log in at instagram.com in a browser and copy fresh cookies",
+				"Store them with: igscraper auth login",
+			},
+		})
+	}
+
+	anonLimited := anon.StatusCode == http.StatusTooManyRequests
+	authLimited := auth.StatusCode == http.StatusTooManyRequests || media.StatusCode == http.StatusTooManyRequests
+
+	switch {
+	case anonLimited && (authLimited || auth.Skipped != ""):
+		problems = append(problems, Finding{
+			Severity: SeverityProblem,
+			Title:    "IP address throttled",
+			Detail:   "Requests are rate limited even without cookies, so the limit applies to your network." + retryAfter(anon),
+			NextSteps: []string{
+				"Wait before trying again; limits usually lift within an hour",
+				"Lower --rate-limit (for example 30 requests per minute)",
+				"Try from a different network if the limit persists",
+			},
+		})
+	case authLimited:
+		problems = append(problems, Finding{
+			Severity: SeverityProblem,
+			Title:    "Account rate limited",
+			Detail:   "Logged-out requests work but the session is throttled, which points at the account rather than the network." + retryAfter(auth, media),
+			NextSteps: []string{
+				"Pause scraping with this account for a few hours",
+				"Lower --rate-limit and --concurrent",
+				"Switch accounts with --account if you have another one stored",
+			},
+		})
+	case anonLimited:
+		warnings = append(warnings, Finding{
+			Severity:  SeverityWarning,
+			Title:     "Logged-out traffic throttled",
+			Detail:    "Anonymous requests from this network are rate limited, but the logged-in session still works.",
+			NextSteps: []string{"Keep using stored credentials; avoid anonymous tools on this network for a while"},
+		})
+	}
+
+	if auth.Skipped != "" {
+		warnings = append(warnings, Finding{
+			Severity:  SeverityWarning,
+			Title:     "No credentials configured",
+			Detail:    "Only logged-out requests could be tested. Scraping requires a session.",
+			NextSteps: []string{"Store credentials with: igscraper auth login"},
+		})
+	}
+
+	if cdn.Skipped == "" && !cdn.OK() && (anon.OK() || auth.OK()) {
+		detail := "The API works but photos could not be downloaded from the CDN."
+		if cdn.Err != nil {
+			detail += fmt.Sprintf(" Error: %v", cdn.Err)
+		} else {
+			detail += fmt.Sprintf(" Status: %d.", cdn.StatusCode)
+		}
+		warnings = append(warnings, Finding{
+			Severity: SeverityWarning,
+			Title:    "Media CDN unreachable",
+			Detail:   detail,
+			NextSteps: []string{
+				"Check that your network does not block *.cdninstagram.com or *.fbcdn.net",
+				"Photo URLs expire; if this persists, re-run the scrape to get fresh URLs",
+			},
+		})
+	}
+
+	for _, r := range results {
+		if r.Skipped == "" && r.Err == nil && r.Latency > slowThreshold {
+			warnings = append(warnings, Finding{
+				Severity:  SeverityWarning,
+				Title:     "Slow responses",
+				Detail:    fmt.Sprintf("%s took %s.", r.Name, r.Latency.Round(time.Millisecond)),
+				NextSteps: []string{"Increase --download-timeout if downloads time out"},
+			})
+			break
+		}
+	}
+
+	findings := append(problems, warnings...)
+	if len(findings) == 0 {
+		findings = append(findings, Finding{
+			Severity: SeverityOK,
+			Title:    "Everything looks healthy",
+			Detail:   "The API, the logged-in session and the CDN all responded normally.",
+		})
+	}
+	return findings
+}
+
+// unreachable reports whether every probe that was sent failed without a response
+func unreachable(results []Result) bool {
+	sent := 0
+	for _, r := range results {
+		if r.Skipped != "" {
+			continue
+		}
+		sent++
+		if r.Err == nil || r.StatusCode != 0 {
+			return false
+		}
+	}
+	return sent > 0
+}
+
+// challenged returns the first challenge marker among results
+func challenged(results []Result) string {
+	for _, r := range results {
+		if r.Challenge != "" {
+			return r.Challenge
+		}
+	}
+	return ""
+}
+
+// loginWall reports whether any sent probe was answered with a login wall
+func loginWall(results []Result) bool {
+	for _, r := range results {
+		if r.Skipped != "" {
+			continue
+		}
+		if r.RequiresLogin || r.StatusCode == http.StatusUnauthorized || r.StatusCode == http.StatusForbidden {
+			return true
+		}
+	}
+	return false
+}
+
+// retryAfter formats the first Retry-After header found
+func retryAfter(results ...Result) string {
+	for _, r := range results {
+		if r.RetryAfter != "" {
+			return fmt.Sprintf(" Instagram asked to retry after %s seconds.", r.RetryAfter)
+		}
+	}
+	return ""
+}
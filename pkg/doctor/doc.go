@@ -0,0 +1,23 @@
+// Package doctor diagnoses why requests to Instagram are failing.
+//
+// A Doctor sends a short, fixed series of requests and records what came
+// back for each one:
+//   - the profile API without cookies (anonymous)
+//   - the profile API with the configured session (authenticated)
+//   - the media GraphQL API with the configured session
+//   - a photo from the CDN, which is served from a different host
+//
+// Diagnose compares the results. Because each failure mode affects the probes
+// differently, the combination points at the cause: an expired session fails
+// only the authenticated probes, IP throttling returns 429 even without
+// cookies, and a flagged account is answered with a checkpoint or challenge.
+//
+//	d := doctor.New(cfg, "instagram")
+//	results := d.Run()
+//	for _, finding := range doctor.Diagnose(results) {
+//	    fmt.Println(finding.Title)
+//	}
+//
+// The probes are sent once each, without retries, so the tool itself never
+// adds meaningfully to rate limiting.
+package doctor
@@ -0,0 +1,241 @@
+package doctor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"igscraper/pkg/audit"
+	"igscraper/pkg/config"
+	"igscraper/pkg/instagram"
+)
+
+// Probe names, in the order Run sends them
+const (
+	ProbeAnonymousAPI       = "anonymous_api"
+	ProbeAuthenticatedAPI   = "authenticated_api"
+	ProbeAuthenticatedMedia = "authenticated_media"
+	ProbeCDN                = "cdn"
+)
+
+// maxBodySize caps how much of a response body is inspected
+const maxBodySize = 256 * 1024
+
+// Result is the outcome of one diagnostic request
+type Result struct {
+	Name          string
+	URL           string
+	StatusCode    int
+	Latency       time.Duration
+	Err           error
+	RetryAfter    string
+	ContentType   string
+	RequiresLogin bool   // answered with a login wall instead of data
+	Challenge     string // checkpoint/challenge/feedback marker, if any
+	Skipped       string // reason the probe was not sent
+}
+
+// OK reports whether the probe got a usable answer
+func (r Result) OK() bool {
+	return r.Skipped == "" && r.Err == nil && r.StatusCode == http.StatusOK &&
+		!r.RequiresLogin && r.Challenge == ""
+}
+
+// Doctor runs the diagnostic probes
+type Doctor struct {
+	httpClient *http.Client
+	headers    map[string]string
+	sessionID  string
+	csrfToken  string
+	target     string
+}
+
+// New creates a Doctor that probes the public profile target using the
+// credentials and user agent from cfg
+func New(cfg *config.Config, target string) *Doctor {
+	timeout := cfg.Download.DownloadTimeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	headers := map[string]string{
+		"User-Agent":       cfg.Instagram.UserAgent,
+		"Accept":           "*/*",
+		"Accept-Language":  "en-US,en;q=0.9",
+		"X-IG-App-ID":      "936619743392459",
+		"X-Requested-With": "XMLHttpRequest",
+		"Referer":          "https://www.instagram.com/",
+	}
+	// The stored account's device, if one is in use
+	if cfg.Instagram.AppID != "" {
+		headers["X-IG-App-ID"] = cfg.Instagram.AppID
+	}
+	if cfg.Instagram.AcceptLanguage != "" {
+		headers["Accept-Language"] = cfg.Instagram.AcceptLanguage
+	}
+
+	return &Doctor{
+		httpClient: &http.Client{Timeout: timeout, Transport: audit.Transport(nil, instagram.EndpointCategory)},
+		headers:    headers,
+		sessionID:  cfg.Instagram.SessionID,
+		csrfToken:  cfg.Instagram.CSRFToken,
+		target:     target,
+	}
+}
+
+// SetTransport replaces the HTTP transport used by the probes
+func (d *Doctor) SetTransport(transport http.RoundTripper) {
+	d.httpClient.Transport = audit.Transport(transport, instagram.EndpointCategory)
+}
+
+// HasCredentials reports whether authenticated probes can be sent
+func (d *Doctor) HasCredentials() bool {
+	return d.sessionID != "" && d.csrfToken != "" &&
+		d.sessionID != "YOUR_SESSION_ID" && d.csrfToken != "YOUR_CSRF_TOKEN"
+}
+
+// Run sends every probe once and returns their results in order
+func (d *Doctor) Run() []Result {
+	var profile instagram.InstagramResponse
+
+	anonymous := d.probe(ProbeAnonymousAPI, instagram.GetProfileURL(d.target), false, &profile)
+	results := []Result{anonymous}
+
+	if d.HasCredentials() {
+		var authProfile instagram.InstagramResponse
+		authenticated := d.probe(ProbeAuthenticatedAPI, instagram.GetProfileURL(d.target), true, &authProfile)
+		results = append(results, authenticated)
+		if authenticated.OK() {
+			profile = authProfile
+		}
+	} else {
+		results = append(results, Result{Name: ProbeAuthenticatedAPI, Skipped: "no credentials configured"})
+	}
+
+	userID := profile.Data.User.ID
+	var media instagram.InstagramResponse
+	switch {
+	case !d.HasCredentials():
+		results = append(results, Result{Name: ProbeAuthenticatedMedia, Skipped: "no credentials configured"})
+	case userID == "":
+		results = append(results, Result{Name: ProbeAuthenticatedMedia, Skipped: "profile lookup failed"})
+	default:
+		results = append(results, d.probe(ProbeAuthenticatedMedia, instagram.GetMediaURL(userID, ""), true, &media))
+	}
+
+	if photoURL := firstPhotoURL(&profile, &media); photoURL != "" {
+		results = append(results, d.probeCDN(photoURL))
+	} else {
+		results = append(results, Result{Name: ProbeCDN, Skipped: "no photo URL available"})
+	}
+
+	return results
+}
+
+// probe sends an API request and decodes a successful JSON body into target
+func (d *Doctor) probe(name, url string, authenticated bool, target *instagram.InstagramResponse) Result {
+	result := Result{Name: name, URL: url}
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	for key, value := range d.headers {
+		req.Header.Set(key, value)
+	}
+	if authenticated {
+		req.Header.Set("Cookie", fmt.Sprintf("sessionid=%s; csrftoken=%s", d.sessionID, d.csrfToken))
+		req.Header.Set("X-CSRFToken", d.csrfToken)
+	}
+
+	body := d.send(req, &result)
+	if body == nil {
+		return result
+	}
+
+	inspectBody(body, &result)
+	if result.StatusCode == http.StatusOK && strings.Contains(result.ContentType, "html") {
+		// The API only answers with an HTML page when it shows a login wall
+		result.RequiresLogin = true
+	}
+
+	if result.StatusCode == http.StatusOK && !result.RequiresLogin {
+		if err := json.Unmarshal(body, target); err == nil && target.LoginRequired() {
+			result.RequiresLogin = true
+		}
+	}
+
+	return result
+}
+
+// probeCDN fetches a photo the same way downloads do
+func (d *Doctor) probeCDN(url string) Result {
+	result := Result{Name: ProbeCDN, URL: url}
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	req.Header.Set("User-Agent", d.headers["User-Agent"])
+
+	d.send(req, &result)
+	return result
+}
+
+// send performs the request, filling in status, latency and Retry-After, and
+// returns the start of the body
+func (d *Doctor) send(req *http.Request, result *Result) []byte {
+	start := time.Now()
+	resp, err := d.httpClient.Do(req)
+	result.Latency = time.Since(start)
+	if err != nil {
+		result.Err = err
+		return nil
+	}
+	defer resp.Body.Close()
+
+	result.StatusCode = resp.StatusCode
+	result.RetryAfter = resp.Header.Get("Retry-After")
+	result.ContentType = resp.Header.Get("Content-Type")
+	if strings.Contains(resp.Request.URL.Path, "/accounts/login") {
+		result.RequiresLogin = true
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		result.Err = err
+		return nil
+	}
+	return body
+}
+
+// inspectBody looks for login walls and account challenges in a response
+func inspectBody(body []byte, result *Result) {
+	text := string(body)
+	for _, marker := range []string{"checkpoint_required", "challenge_required", "feedback_required"} {
+		if strings.Contains(text, marker) {
+			result.Challenge = marker
+			return
+		}
+	}
+	if strings.Contains(text, "login_required") || strings.Contains(text, `"require_login":true`) {
+		result.RequiresLogin = true
+	}
+}
+
+// firstPhotoURL picks a photo URL for the CDN probe
+func firstPhotoURL(responses ...*instagram.InstagramResponse) string {
+	for _, response := range responses {
+		for _, edge := range response.Data.User.EdgeOwnerToTimelineMedia.Edges {
+			if edge.Node.DisplayURL != "" {
+				return edge.Node.DisplayURL
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,262 @@
+package doctor
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"igscraper/pkg/config"
+	"igscraper/pkg/demo"
+)
+
+// hostRewriter sends every request to a test server
+type hostRewriter struct {
+	target *url.URL
+}
+
+func (h hostRewriter) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = h.target.Scheme
+	req.URL.Host = h.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func newTestDoctor(t *testing.T, handler http.Handler, withCredentials bool) *Doctor {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	target, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	cfg := config.DefaultConfig()
+	if withCredentials {
+		cfg.Instagram.SessionID = "session"
+		cfg.Instagram.CSRFToken = "csrf"
+	}
+	d := New(cfg, "someone")
+	d.SetTransport(hostRewriter{target: target})
+	return d
+}
+
+func titles(findings []Finding) []string {
+	var out []string
+	for _, f := range findings {
+		out = append(out, f.Title)
+	}
+	return out
+}
+
+func TestDoctor_Healthy(t *testing.T) {
+	server, err := demo.NewServer()
+	require.NoError(t, err)
+	defer server.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Instagram.SessionID = "session"
+	cfg.Instagram.CSRFToken = "csrf"
+	d := New(cfg, demo.Username)
+	d.SetTransport(server.Transport())
+
+	results := d.Run()
+	require.Len(t, results, 4)
+	for _, r := range results {
+		assert.True(t, r.OK(), "probe %s: status %d err %v skipped %q", r.Name, r.StatusCode, r.Err, r.Skipped)
+	}
+
+	findings := Diagnose(results)
+	require.Len(t, findings, 1)
+	assert.Equal(t, SeverityOK, findings[0].Severity)
+}
+
+func TestDoctor_NoCredentials(t *testing.T) {
+	server, err := demo.NewServer()
+	require.NoError(t, err)
+	defer server.Close()
+
+	d := New(config.DefaultConfig(), demo.Username)
+	d.SetTransport(server.Transport())
+	assert.False(t, d.HasCredentials())
+
+	results := d.Run()
+	assert.True(t, results[0].OK())
+	assert.NotEmpty(t, results[1].Skipped)
+	assert.NotEmpty(t, results[2].Skipped)
+	assert.True(t, results[3].OK(), "CDN probe should use the anonymous profile")
+
+	assert.Equal(t, []string{"No credentials configured"}, titles(Diagnose(results)))
+}
+
+func TestDoctor_SessionExpired(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/users/web_profile_info/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Cookie") != "" {
+			http.Redirect(w, r, "/accounts/login/", http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":{"user":{"id":"1","edge_owner_to_timeline_media":{"edges":[]}}},"status":"ok"}`))
+	})
+	mux.HandleFunc("/accounts/login/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html>login</html>"))
+	})
+	mux.HandleFunc("/graphql/query/", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/accounts/login/", http.StatusFound)
+	})
+
+	d := newTestDoctor(t, mux, true)
+	results := d.Run()
+
+	assert.True(t, results[0].OK())
+	assert.True(t, results[1].RequiresLogin)
+	assert.Contains(t, titles(Diagnose(results)), "Session expired")
+}
+
+func TestDoctor_IPThrottled(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	d := newTestDoctor(t, handler, true)
+	results := d.Run()
+
+	assert.Equal(t, http.StatusTooManyRequests, results[0].StatusCode)
+	assert.Equal(t, "120", results[0].RetryAfter)
+
+	findings := Diagnose(results)
+	require.NotEmpty(t, findings)
+	assert.Equal(t, "IP address throttled", findings[0].Title)
+	assert.Contains(t, findings[0].Detail, "120 seconds")
+}
+
+func TestDoctor_AccountFlagged(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Header.Get("Cookie") != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"message":"checkpoint_required","status":"fail"}`))
+			return
+		}
+		w.Write([]byte(`{"data":{"user":{"id":"1"}},"status":"ok"}`))
+	})
+
+	d := newTestDoctor(t, handler, true)
+	results := d.Run()
+
+	assert.Equal(t, "checkpoint_required", results[1].Challenge)
+	assert.Equal(t, "Account flagged", Diagnose(results)[0].Title)
+}
+
+func TestDiagnose(t *testing.T) {
+	ok := func(name string) Result {
+		return Result{Name: name, StatusCode: http.StatusOK}
+	}
+	limited := func(name string) Result {
+		return Result{Name: name, StatusCode: http.StatusTooManyRequests}
+	}
+
+	tests := []struct {
+		name    string
+		results []Result
+		want    []string
+	}{
+		{
+			name: "unreachable",
+			results: []Result{
+				{Name: ProbeAnonymousAPI, Err: errors.New("dial tcp: no such host")},
+				{Name: ProbeAuthenticatedAPI, Err: errors.New("dial tcp: no such host")},
+				{Name: ProbeAuthenticatedMedia, Skipped: "profile lookup failed"},
+				{Name: ProbeCDN, Skipped: "no photo URL available"},
+			},
+			want: []string{"Instagram is unreachable"},
+		},
+		{
+			name:    "account rate limited",
+			results: []Result{ok(ProbeAnonymousAPI), ok(ProbeAuthenticatedAPI), limited(ProbeAuthenticatedMedia), ok(ProbeCDN)},
+			want:    []string{"Account rate limited"},
+		},
+		{
+			name:    "anonymous traffic throttled",
+			results: []Result{limited(ProbeAnonymousAPI), ok(ProbeAuthenticatedAPI), ok(ProbeAuthenticatedMedia), ok(ProbeCDN)},
+			want:    []string{"Logged-out traffic throttled"},
+		},
+		{
+			name: "cdn blocked",
+			results: []Result{ok(ProbeAnonymousAPI), ok(ProbeAuthenticatedAPI), ok(ProbeAuthenticatedMedia),
+				{Name: ProbeCDN, StatusCode: http.StatusForbidden}},
+			want: []string{"Media CDN unreachable"},
+		},
+		{
+			name: "slow",
+			results: []Result{ok(ProbeAnonymousAPI), ok(ProbeAuthenticatedAPI), ok(ProbeAuthenticatedMedia),
+				{Name: ProbeCDN, StatusCode: http.StatusOK, Latency: 6 * time.Second}},
+			want: []string{"Slow responses"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, titles(Diagnose(tt.results)))
+		})
+	}
+}
+
+func TestCheckSession(t *testing.T) {
+	server, err := demo.NewServer()
+	require.NoError(t, err)
+	defer server.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Instagram.SessionID = "session"
+	cfg.Instagram.CSRFToken = "csrf"
+	d := New(cfg, demo.Username)
+	d.SetTransport(server.Transport())
+	assert.Equal(t, SessionValid, d.CheckSession().State)
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    SessionState
+	}{
+		{"expired", func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/accounts/login/" {
+				w.Header().Set("Content-Type", "text/html")
+				w.Write([]byte("<html>login</html>"))
+				return
+			}
+			http.Redirect(w, r, "/accounts/login/", http.StatusFound)
+		}, SessionExpired},
+		{"unauthorized", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}, SessionExpired},
+		{"challenged", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"message":"challenge_required","status":"fail"}`))
+		}, SessionChallenged},
+		{"rate limited", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTooManyRequests)
+		}, SessionUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := newTestDoctor(t, tt.handler, true).CheckSession()
+			assert.Equal(t, tt.want, check.State)
+			assert.Equal(t, ProbeAuthenticatedAPI, check.Result.Name)
+		})
+	}
+
+	requests := 0
+	d = newTestDoctor(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}), false)
+	assert.Equal(t, SessionMissing, d.CheckSession().State)
+	assert.Zero(t, requests)
+}
@@ -0,0 +1,62 @@
+package doctor
+
+import (
+	"net/http"
+
+	"igscraper/pkg/instagram"
+)
+
+// SessionState is the verdict of a session check
+type SessionState string
+
+const (
+	// SessionValid means the session cookies were accepted
+	SessionValid SessionState = "valid"
+	// SessionExpired means Instagram answered with a login wall
+	SessionExpired SessionState = "expired"
+	// SessionChallenged means the account must pass a checkpoint first
+	SessionChallenged SessionState = "challenged"
+	// SessionMissing means no credentials are configured
+	SessionMissing SessionState = "missing"
+	// SessionUnknown means the answer said nothing about the cookies, for
+	// example a network error or rate limiting
+	SessionUnknown SessionState = "unknown"
+)
+
+// SessionCheck is the outcome of CheckSession
+type SessionCheck struct {
+	State  SessionState
+	Result Result
+}
+
+// CheckSession sends a single authenticated profile request for the target
+// to tell whether the configured sessionid and csrftoken are still accepted.
+// It is cheap enough to run before every scrape.
+func (d *Doctor) CheckSession() SessionCheck {
+	if !d.HasCredentials() {
+		return SessionCheck{
+			State:  SessionMissing,
+			Result: Result{Name: ProbeAuthenticatedAPI, Skipped: "no credentials configured"},
+		}
+	}
+
+	var profile instagram.InstagramResponse
+	result := d.probe(ProbeAuthenticatedAPI, instagram.GetProfileURL(d.target), true, &profile)
+	return SessionCheck{State: sessionState(result), Result: result}
+}
+
+// sessionState classifies an authenticated probe. Only a login wall or a
+// challenge says the session is unusable; anything else that is not a plain
+// success is inconclusive.
+func sessionState(r Result) SessionState {
+	switch {
+	case r.Challenge != "":
+		return SessionChallenged
+	case r.RequiresLogin || r.StatusCode == http.StatusUnauthorized:
+		return SessionExpired
+	case r.OK():
+		return SessionValid
+	default:
+		return SessionUnknown
+	}
+}
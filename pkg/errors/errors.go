@@ -1,6 +1,11 @@
 package errors
 
-import "fmt"
+import (
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"time"
+)
 
 // ErrorType represents different types of errors that can occur
 type ErrorType string
@@ -12,6 +17,7 @@ const (
 	ErrorTypeParsing      ErrorType = "parsing"
 	ErrorTypeNotFound     ErrorType = "not_found"
 	ErrorTypeServerError  ErrorType = "server_error"
+	ErrorTypeChallenge    ErrorType = "challenge"
 	ErrorTypeUnknown      ErrorType = "unknown"
 )
 
@@ -20,18 +26,105 @@ type Error struct {
 	Type    ErrorType
 	Message string
 	Code    int
+	// RetryAfter is the delay the server asked for in a Retry-After header,
+	// or zero if it sent none
+	RetryAfter time.Duration
+	// ChallengeURL is where the account's security check can be completed,
+	// for challenge errors that name one
+	ChallengeURL string
+	// Payload is the error body Instagram sent, if it sent one
+	Payload *Payload
 }
 
 func (e *Error) Error() string {
+	if said := e.Payload.Describe(); said != "" && said != e.Message {
+		return fmt.Sprintf("%s error (code %d): %s: %s", e.Type, e.Code, e.Message, said)
+	}
 	return fmt.Sprintf("%s error (code %d): %s", e.Type, e.Code, e.Message)
 }
 
+// Retryable reports whether the request that failed with e is worth
+// repeating: its type is retryable and Instagram did not flag it as spam,
+// which repeating would only make worse
+func (e *Error) Retryable() bool {
+	if e.Payload != nil && e.Payload.Spam {
+		return false
+	}
+	return IsRetryable(e.Type)
+}
+
+// Payload is the JSON body Instagram sends with a failed request, such as
+// {"message": "Please wait a few minutes before you try again.", "status": "fail"}
+type Payload struct {
+	Message         string `json:"message"`
+	Status          string `json:"status"`
+	Spam            bool   `json:"spam,omitempty"`
+	ErrorType       string `json:"error_type,omitempty"`
+	FeedbackTitle   string `json:"feedback_title,omitempty"`
+	FeedbackMessage string `json:"feedback_message,omitempty"`
+}
+
+// ParsePayload parses an error body, returning nil when body is not one of
+// Instagram's error payloads
+func ParsePayload(body []byte) *Payload {
+	var payload Payload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil
+	}
+	if payload.Message == "" && payload.Status != "fail" && !payload.Spam {
+		return nil
+	}
+	return &payload
+}
+
+// Describe returns what Instagram said about the error, preferring the
+// feedback shown to app users, or "" when it said nothing
+func (p *Payload) Describe() string {
+	switch {
+	case p == nil:
+		return ""
+	case p.FeedbackTitle != "" && p.FeedbackMessage != "":
+		return p.FeedbackTitle + ": " + p.FeedbackMessage
+	case p.FeedbackMessage != "":
+		return p.FeedbackMessage
+	}
+	return p.Message
+}
+
+// RetryAfter returns the delay the server asked for before err's request is
+// repeated, or zero if err is not an *Error carrying one
+func RetryAfter(err error) time.Duration {
+	var apiErr *Error
+	if stderrors.As(err, &apiErr) {
+		return apiErr.RetryAfter
+	}
+	return 0
+}
+
+// IsChallenge reports whether err means Instagram wants the account to pass
+// a security check before it answers any more requests
+func IsChallenge(err error) bool {
+	var apiErr *Error
+	return stderrors.As(err, &apiErr) && apiErr.Type == ErrorTypeChallenge
+}
+
+// IsThrottled reports whether err means Instagram is pushing back on the
+// request rate: a 429 or a 5xx, which it also answers floods with
+func IsThrottled(err error) bool {
+	var apiErr *Error
+	if !stderrors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.Type == ErrorTypeRateLimit || apiErr.Type == ErrorTypeServerError ||
+		apiErr.Code == 429 || apiErr.Code >= 500
+}
+
 // IsRetryable checks if an error type should be retried
 func IsRetryable(errorType ErrorType) bool {
 	switch errorType {
 	case ErrorTypeNetwork, ErrorTypeRateLimit, ErrorTypeServerError:
 		return true
-	case ErrorTypeAuth, ErrorTypeNotFound, ErrorTypeParsing:
+	case ErrorTypeAuth, ErrorTypeNotFound, ErrorTypeParsing, ErrorTypeChallenge:
 		return false
 	default:
 		return false
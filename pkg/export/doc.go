@@ -0,0 +1,24 @@
+// Package export converts a downloaded archive into formats other tools can
+// publish.
+//
+// An archive is a folder written by igscraper: the photos, saved as
+// <shortcode>.jpg, and the metadata.json next to them. Exporters are looked up
+// by name, so new targets only need to register a constructor:
+//
+//	archive, err := export.LoadArchive("./instagram_photos")
+//	if err != nil {
+//	    return err
+//	}
+//	exporter, err := export.New("markdown", export.Options{ImagePrefix: "/images"})
+//	if err != nil {
+//	    return err
+//	}
+//	n, err := exporter.Export(archive, "./site/_posts")
+//
+// The markdown target writes one file per post for static site generators
+// such as Hugo and Jekyll: YAML front matter with the date, likes, comments,
+// location and hashtags, the caption as the body, and a link to the photo.
+// The html target writes index.html, a static gallery of the posts in a
+// grid with their captions and dates, linking to the original files.
+// Posts whose photo is not on disk are skipped.
+package export
@@ -0,0 +1,124 @@
+package export
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"igscraper/pkg/metadata"
+)
+
+// Exporter writes a downloaded archive in another format
+type Exporter interface {
+	// Export writes the archive's posts into dir, creating it if needed, and
+	// returns how many posts were written
+	Export(archive *Archive, dir string) (int, error)
+}
+
+// Options configure an Exporter
+type Options struct {
+	// ImagePrefix, when set, replaces the relative image paths in exported
+	// files with ImagePrefix + "/" + the photo's file name, for sites that
+	// serve the photos from their own static folder
+	ImagePrefix string
+}
+
+// formats maps each export format to its constructor
+var formats = map[string]func(Options) Exporter{
+	"markdown": NewMarkdown,
+	"html":     NewHTML,
+}
+
+// New returns the exporter for format
+func New(format string, opts Options) (Exporter, error) {
+	newExporter, ok := formats[strings.ToLower(format)]
+	if !ok {
+		return nil, fmt.Errorf("unknown export format %q (available: %s)", format, strings.Join(Formats(), ", "))
+	}
+	return newExporter(opts), nil
+}
+
+// Formats returns the names of the available export formats
+func Formats() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Archive is a downloaded profile or feed: the folder holding the photos and
+// the metadata.json written next to them
+type Archive struct {
+	Dir      string
+	Metadata *metadata.UserMetadata
+}
+
+// Post is one exported post
+type Post struct {
+	Photo metadata.PhotoMetadata
+	Image string // path of the downloaded photo
+}
+
+// LoadArchive reads the metadata of the archive in dir
+func LoadArchive(dir string) (*Archive, error) {
+	meta, err := metadata.LoadUserMetadata(dir)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		return nil, fmt.Errorf("no metadata.json in %s; export needs a folder downloaded by igscraper", dir)
+	}
+	return &Archive{Dir: dir, Metadata: meta}, nil
+}
+
+// Posts returns the archive's posts whose photo is on disk, newest first.
+// A post recorded more than once keeps its latest metadata.
+func (a *Archive) Posts() []Post {
+	latest := make(map[string]int)
+	for i, photo := range a.Metadata.Photos {
+		latest[photo.Shortcode] = i
+	}
+
+	var posts []Post
+	for shortcode, i := range latest {
+		name := a.Metadata.Photos[i].File
+		if name == "" {
+			name = shortcode + ".jpg"
+		}
+		image := filepath.Join(a.Dir, name)
+		if _, err := os.Stat(image); err != nil {
+			continue
+		}
+		posts = append(posts, Post{Photo: a.Metadata.Photos[i], Image: image})
+	}
+
+	sort.Slice(posts, func(i, j int) bool {
+		if !posts[i].Photo.TakenAt.Equal(posts[j].Photo.TakenAt) {
+			return posts[i].Photo.TakenAt.After(posts[j].Photo.TakenAt)
+		}
+		return posts[i].Photo.Shortcode < posts[j].Photo.Shortcode
+	})
+	return posts
+}
+
+// imageLink returns how an exported file in dir refers to image. Spaces are
+// escaped so the link stays valid in Markdown.
+func imageLink(opts Options, dir, image string) string {
+	link := filepath.ToSlash(image)
+	if opts.ImagePrefix != "" {
+		link = strings.TrimSuffix(opts.ImagePrefix, "/") + "/" + filepath.Base(image)
+	} else {
+		absDir, errDir := filepath.Abs(dir)
+		absImage, errImage := filepath.Abs(image)
+		if errDir == nil && errImage == nil {
+			if rel, err := filepath.Rel(absDir, absImage); err == nil {
+				link = filepath.ToSlash(rel)
+			}
+		}
+	}
+	return strings.ReplaceAll(link, " ", "%20")
+}
@@ -0,0 +1,210 @@
+package export
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"igscraper/pkg/instagram"
+	"igscraper/pkg/metadata"
+	"igscraper/pkg/storage"
+)
+
+// GalleryFile is the name of the page the html exporter writes
+const GalleryFile = "index.html"
+
+// htmlExporter writes a single page showing every post in a grid
+type htmlExporter struct {
+	opts Options
+}
+
+// NewHTML returns an exporter that writes a static gallery, index.html, with
+// a grid of the posts and their captions, dates and likes, each linking to
+// its original file. Gallery thumbnails are shown in the grid when the
+// archive has them.
+func NewHTML(opts Options) Exporter {
+	return &htmlExporter{opts: opts}
+}
+
+// galleryPage is the data of the gallery template
+type galleryPage struct {
+	Username  string
+	Posts     []galleryPost
+	Generated time.Time
+}
+
+// galleryPost is one tile of the gallery
+type galleryPost struct {
+	Shortcode string
+	Original  string // link to the downloaded file
+	Preview   string // image shown in the grid
+	Video     bool
+	Alt       string
+	Caption   string
+	Date      time.Time
+	Likes     int
+	Comments  int
+	Location  string
+	URL       string
+}
+
+// Export writes index.html into dir, replacing the page of an earlier
+// export
+func (e *htmlExporter) Export(archive *Archive, dir string) (int, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	page := galleryPage{Username: archive.Metadata.Username, Generated: time.Now()}
+	posts := archive.Posts()
+	for _, post := range append(posts, unrecordedPosts(archive, posts)...) {
+		page.Posts = append(page.Posts, e.galleryPost(archive, dir, post))
+	}
+
+	path := filepath.Join(dir, GalleryFile)
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	err = galleryTemplate.Execute(f, page)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return len(page.Posts), nil
+}
+
+// unrecordedPosts returns the photos and videos in the archive folder that
+// metadata.json has no record of, such as those of runs from before it
+// covered the whole archive, so the gallery shows every saved post. Their
+// tiles come after the recorded posts and show only the file.
+func unrecordedPosts(archive *Archive, recorded []Post) []Post {
+	layout, err := storage.ParseLayout(archive.Metadata.FileNamePattern)
+	if err != nil {
+		return nil
+	}
+	files, err := storage.ListPhotos(archive.Dir, layout)
+	if err != nil {
+		return nil
+	}
+
+	shown := make(map[string]bool)
+	for _, post := range recorded {
+		shown[post.Photo.Shortcode] = true
+	}
+	var posts []Post
+	for shortcode, name := range files {
+		if shown[shortcode] {
+			continue
+		}
+		posts = append(posts, Post{
+			Photo: metadata.PhotoMetadata{Shortcode: shortcode, File: name, IsVideo: filepath.Ext(name) == ".mp4"},
+			Image: filepath.Join(archive.Dir, filepath.FromSlash(name)),
+		})
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		return posts[i].Photo.Shortcode < posts[j].Photo.Shortcode
+	})
+	return posts
+}
+
+// galleryPost returns the tile of post, preferring a small image to show in
+// the grid: the gallery thumbnail of a photo or the cover of a video
+func (e *htmlExporter) galleryPost(archive *Archive, dir string, post Post) galleryPost {
+	photo := post.Photo
+	tile := galleryPost{
+		Shortcode: photo.Shortcode,
+		Original:  imageLink(e.opts, dir, post.Image),
+		Video:     photo.IsVideo,
+		Alt:       strings.Join(strings.Fields(photo.AccessibilityCaption), " "),
+		Caption:   strings.TrimSpace(photo.Caption),
+		Date:      photo.TakenAt,
+		Likes:     photo.LikesCount,
+		Comments:  photo.CommentsCount,
+		URL:       instagram.GetPostURL(photo.Shortcode),
+	}
+	if tile.Alt == "" {
+		tile.Alt = "Instagram post " + photo.Shortcode
+	}
+	if photo.Location != nil {
+		tile.Location = photo.Location.Name
+	}
+
+	rel, _ := filepath.Rel(archive.Dir, post.Image)
+	var preview string
+	if photo.IsVideo {
+		if photo.Thumbnail != "" {
+			preview = filepath.Join(archive.Dir, filepath.FromSlash(photo.Thumbnail))
+		}
+	} else {
+		preview = filepath.Join(archive.Dir, storage.GalleryFolder, strings.TrimSuffix(rel, filepath.Ext(rel))+".jpg")
+	}
+	if preview != "" {
+		if _, err := os.Stat(preview); err == nil {
+			tile.Preview = imageLink(e.opts, dir, preview)
+		}
+	}
+	if tile.Preview == "" && !photo.IsVideo {
+		tile.Preview = tile.Original
+	}
+	return tile
+}
+
+var galleryTemplate = template.Must(template.New("gallery").Funcs(template.FuncMap{
+	"date": func(t time.Time) string {
+		if t.IsZero() || t.Unix() == 0 {
+			return ""
+		}
+		return t.Format("Jan 2, 2006")
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>@{{.Username}}</title>
+<style>
+  body { margin: 0; font: 14px/1.4 -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; color: #262626; background: #fafafa; }
+  header { padding: 24px 16px 8px; max-width: 960px; margin: 0 auto; }
+  header h1 { margin: 0; font-size: 22px; }
+  header p { margin: 4px 0 0; color: #8e8e8e; }
+  main { display: grid; grid-template-columns: repeat(auto-fill, minmax(220px, 1fr)); gap: 16px; padding: 16px; max-width: 960px; margin: 0 auto; }
+  figure { margin: 0; background: #fff; border: 1px solid #dbdbdb; border-radius: 4px; overflow: hidden; }
+  figure > a { display: block; aspect-ratio: 1; background: #efefef; }
+  figure img, figure video { width: 100%; height: 100%; object-fit: cover; display: block; }
+  figcaption { padding: 8px 10px 10px; }
+  .meta { color: #8e8e8e; font-size: 12px; }
+  .caption { margin: 6px 0 0; white-space: pre-line; display: -webkit-box; -webkit-line-clamp: 4; -webkit-box-orient: vertical; overflow: hidden; }
+  .caption:hover { -webkit-line-clamp: unset; }
+</style>
+</head>
+<body>
+<header>
+  <h1>@{{.Username}}</h1>
+  <p>{{len .Posts}} posts · generated {{date .Generated}}</p>
+</header>
+<main>
+{{- range .Posts}}
+  <figure id="{{.Shortcode}}">
+    <a href="{{.Original}}">
+      {{- if .Preview}}<img src="{{.Preview}}" alt="{{.Alt}}" loading="lazy">
+      {{- else}}<video src="{{.Original}}" preload="metadata" muted></video>{{end -}}
+    </a>
+    <figcaption>
+      <div class="meta">{{with date .Date}}{{.}} · {{end}}{{if .Video}}video · {{end}}{{.Likes}} likes · {{.Comments}} comments{{with .Location}} · {{.}}{{end}} · <a href="{{.URL}}">Instagram</a></div>
+      {{- with .Caption}}
+      <p class="caption">{{.}}</p>
+      {{- end}}
+    </figcaption>
+  </figure>
+{{- end}}
+</main>
+</body>
+</html>
+`))
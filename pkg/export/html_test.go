@@ -0,0 +1,103 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"igscraper/pkg/instagram"
+	"igscraper/pkg/metadata"
+	"igscraper/pkg/storage"
+)
+
+func TestHTMLExport(t *testing.T) {
+	taken := time.Date(2024, 3, 15, 18, 30, 0, 0, time.UTC)
+	archive := newTestArchive(t, []metadata.PhotoMetadata{
+		{
+			Shortcode:            "ABC123",
+			TakenAt:              taken,
+			Caption:              "Golden hour <at> the beach #sunset",
+			AccessibilityCaption: "Photo of a beach at sunset",
+			Location:             &metadata.Location{Name: "Santa Monica Beach"},
+			LikesCount:           120,
+			CommentsCount:        4,
+		},
+		{Shortcode: "THUMB", TakenAt: taken.AddDate(0, -1, 0)},
+		{Shortcode: "CLIP", File: "CLIP.mp4", IsVideo: true, TakenAt: taken.AddDate(0, -2, 0)},
+		{Shortcode: "GONE", TakenAt: taken},
+	}, "GONE")
+	require.NoError(t, os.WriteFile(filepath.Join(archive.Dir, "CLIP.mp4"), []byte("mp4"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(archive.Dir, storage.GalleryFolder), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(archive.Dir, storage.GalleryFolder, "THUMB.jpg"), []byte("jpeg"), 0644))
+
+	exporter, err := New("html", Options{})
+	require.NoError(t, err)
+	n, err := exporter.Export(archive, archive.Dir)
+	require.NoError(t, err)
+	assert.Equal(t, 3, n, "posts without a file on disk are left out")
+
+	data, err := os.ReadFile(filepath.Join(archive.Dir, GalleryFile))
+	require.NoError(t, err)
+	page := string(data)
+	assert.Contains(t, page, "<title>@alice</title>")
+	assert.Contains(t, page, `<a href="ABC123.jpg"><img src="ABC123.jpg" alt="Photo of a beach at sunset" loading="lazy">`)
+	assert.Contains(t, page, "Golden hour &lt;at&gt; the beach #sunset", "captions are escaped")
+	assert.Contains(t, page, "Mar 15, 2024 · 120 likes · 4 comments · Santa Monica Beach")
+	assert.Contains(t, page, `href="https://www.instagram.com/p/ABC123/"`)
+	assert.Contains(t, page, `<a href="THUMB.jpg"><img src=".thumbnails/THUMB.jpg"`, "the gallery thumbnail is shown in the grid")
+	assert.Contains(t, page, `<video src="CLIP.mp4" preload="metadata" muted></video>`)
+	assert.NotContains(t, page, "GONE")
+	assert.Less(t, strings.Index(page, "ABC123"), strings.Index(page, "THUMB"), "newest post first")
+}
+
+func TestHTMLExportAcrossRuns(t *testing.T) {
+	dir := t.TempDir()
+
+	// Each run saves one post; the second does not list the first again
+	for i, shortcode := range []string{"FIRST", "SECOND"} {
+		manager, err := storage.NewManager(dir)
+		require.NoError(t, err)
+		manager.InitializeUserMetadata("alice", "42", 2)
+		node := &instagram.Node{Shortcode: shortcode, TakenAtTimestamp: int64(1700000000 + i*86400)}
+		require.NoError(t, manager.SavePhotoWithMetadata(strings.NewReader(shortcode), shortcode, node))
+		require.NoError(t, manager.SaveUserMetadata())
+	}
+
+	archive, err := LoadArchive(dir)
+	require.NoError(t, err)
+	exporter, err := New("html", Options{})
+	require.NoError(t, err)
+	n, err := exporter.Export(archive, dir)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n, "the gallery keeps the posts of earlier runs")
+
+	data, err := os.ReadFile(filepath.Join(dir, GalleryFile))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `id="FIRST"`)
+	assert.Contains(t, string(data), `id="SECOND"`)
+}
+
+func TestHTMLExportUnrecordedFiles(t *testing.T) {
+	archive := newTestArchive(t, []metadata.PhotoMetadata{{Shortcode: "LATEST", TakenAt: time.Unix(1700000000, 0)}})
+	// Saved by a run whose record metadata.json no longer holds
+	require.NoError(t, os.WriteFile(filepath.Join(archive.Dir, "EARLIER.jpg"), []byte("jpeg"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(archive.Dir, "notes.txt"), []byte("notes"), 0644))
+
+	exporter, err := New("html", Options{})
+	require.NoError(t, err)
+	n, err := exporter.Export(archive, archive.Dir)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n, "files without a record get a tile")
+
+	data, err := os.ReadFile(filepath.Join(archive.Dir, GalleryFile))
+	require.NoError(t, err)
+	page := string(data)
+	assert.Contains(t, page, `<a href="EARLIER.jpg"><img src="EARLIER.jpg" alt="Instagram post EARLIER" loading="lazy">`)
+	assert.NotContains(t, page, "notes")
+	assert.Less(t, strings.Index(page, `id="LATEST"`), strings.Index(page, `id="EARLIER"`), "recorded posts first")
+}
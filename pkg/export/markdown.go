@@ -0,0 +1,198 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"igscraper/pkg/filter"
+	"igscraper/pkg/instagram"
+)
+
+// maxTitleLength is the number of characters of the caption used as a
+// post's title
+const maxTitleLength = 60
+
+// markdownExporter writes one Markdown file with YAML front matter per post
+type markdownExporter struct {
+	opts Options
+}
+
+// NewMarkdown returns an exporter that writes one Markdown file per post,
+// named <date>-<shortcode>.md as Jekyll expects in _posts. Hugo reads the
+// same files from a content section.
+func NewMarkdown(opts Options) Exporter {
+	return &markdownExporter{opts: opts}
+}
+
+// frontMatter is the YAML header of an exported post
+type frontMatter struct {
+	Title     string    `yaml:"title"`
+	Date      time.Time `yaml:"date"`
+	Shortcode string    `yaml:"shortcode"`
+	URL       string    `yaml:"instagram_url"`
+	Author    string    `yaml:"author,omitempty"`
+	Likes     int       `yaml:"likes"`
+	Comments  int       `yaml:"comments"`
+	Location  string    `yaml:"location,omitempty"`
+	Tags      []string  `yaml:"tags,omitempty"`
+	Image     string    `yaml:"image"`
+}
+
+// Export writes a Markdown file for each post of archive into dir,
+// replacing files from an earlier export
+func (e *markdownExporter) Export(archive *Archive, dir string) (int, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	written := 0
+	for _, post := range archive.Posts() {
+		content, err := e.render(post, imageLink(e.opts, dir, post.Image))
+		if err != nil {
+			return written, fmt.Errorf("failed to render post %s: %w", post.Photo.Shortcode, err)
+		}
+		path := filepath.Join(dir, markdownFileName(post))
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			return written, fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		written++
+	}
+	return written, nil
+}
+
+// render returns the Markdown file for post
+func (e *markdownExporter) render(post Post, image string) ([]byte, error) {
+	photo := post.Photo
+	fm := frontMatter{
+		Title:     postTitle(photo.Caption, photo.Shortcode),
+		Date:      photo.TakenAt,
+		Shortcode: photo.Shortcode,
+		URL:       instagram.GetPostURL(photo.Shortcode),
+		Author:    photo.Owner.Username,
+		Likes:     photo.LikesCount,
+		Comments:  photo.CommentsCount,
+		Tags:      uniqueTags(filter.Hashtags(photo.Caption)),
+		Image:     image,
+	}
+	if photo.Location != nil {
+		fm.Location = photo.Location.Name
+	}
+
+	header, err := yaml.Marshal(fm)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("---\n")
+	buf.Write(header)
+	buf.WriteString("---\n\n")
+	if body := markdownText(photo.Caption); body != "" {
+		buf.WriteString(body)
+		buf.WriteString("\n\n")
+	}
+
+	alt := photo.AccessibilityCaption
+	if alt == "" {
+		alt = "Instagram post " + photo.Shortcode
+	}
+	fmt.Fprintf(&buf, "![%s](%s)\n", escapeMarkdown(strings.Join(strings.Fields(alt), " ")), image)
+	return buf.Bytes(), nil
+}
+
+// markdownFileName names a post's file by its date and shortcode
+func markdownFileName(post Post) string {
+	date := post.Photo.TakenAt
+	if date.IsZero() {
+		date = post.Photo.DownloadedAt
+	}
+	return fmt.Sprintf("%s-%s.md", date.Format("2006-01-02"), post.Photo.Shortcode)
+}
+
+// postTitle returns the first line of the caption, shortened at a word
+// boundary, or a title built from the shortcode when there is no caption
+func postTitle(caption, shortcode string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(caption), "\n")
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return "Instagram post " + shortcode
+	}
+
+	runes := []rune(line)
+	if len(runes) <= maxTitleLength {
+		return line
+	}
+	title := string(runes[:maxTitleLength])
+	if i := strings.LastIndex(title, " "); i > maxTitleLength/2 {
+		title = title[:i]
+	}
+	return strings.TrimRight(title, " ,.;:-") + "…"
+}
+
+// uniqueTags removes repeated hashtags, keeping their first occurrence
+func uniqueTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	var unique []string
+	for _, tag := range tags {
+		if !seen[tag] {
+			seen[tag] = true
+			unique = append(unique, tag)
+		}
+	}
+	return unique
+}
+
+// markdownText turns a caption into Markdown that renders as it was written:
+// Markdown syntax is escaped, line breaks are kept as hard breaks and blank
+// lines separate paragraphs
+func markdownText(caption string) string {
+	paragraphs := strings.Split(strings.ReplaceAll(strings.TrimSpace(caption), "\r\n", "\n"), "\n\n")
+
+	var out []string
+	for _, paragraph := range paragraphs {
+		var lines []string
+		for _, line := range strings.Split(paragraph, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				lines = append(lines, escapeLine(line))
+			}
+		}
+		if len(lines) > 0 {
+			out = append(out, strings.Join(lines, "  \n"))
+		}
+	}
+	return strings.Join(out, "\n\n")
+}
+
+// markdownEscaper escapes characters that have a meaning anywhere in a line
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`,
+	"[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`, "|", `\|`,
+)
+
+// escapeMarkdown escapes inline Markdown syntax in text
+func escapeMarkdown(text string) string {
+	return markdownEscaper.Replace(text)
+}
+
+// escapeLine escapes a line of text, including markers that only start a
+// block at the beginning of a line: headings, list items and numbered lists
+func escapeLine(line string) string {
+	line = escapeMarkdown(line)
+	switch {
+	case strings.HasPrefix(line, "#"), strings.HasPrefix(line, "+"),
+		strings.HasPrefix(line, "-"), strings.HasPrefix(line, "="):
+		return `\` + line
+	}
+
+	digits := len(line) - len(strings.TrimLeft(line, "0123456789"))
+	if digits > 0 && digits < len(line) && (line[digits] == '.' || line[digits] == ')') {
+		return line[:digits] + `\` + line[digits:]
+	}
+	return line
+}
@@ -0,0 +1,172 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"igscraper/pkg/metadata"
+)
+
+// newTestArchive writes an archive with the given photos into a temporary
+// folder. Photos listed in missing get no image file.
+func newTestArchive(t *testing.T, photos []metadata.PhotoMetadata, missing ...string) *Archive {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "archive")
+	require.NoError(t, os.MkdirAll(dir, 0755))
+
+	meta := &metadata.UserMetadata{Username: "alice", Photos: photos}
+	require.NoError(t, meta.Save(dir))
+
+	skip := make(map[string]bool)
+	for _, shortcode := range missing {
+		skip[shortcode] = true
+	}
+	for _, photo := range photos {
+		if !skip[photo.Shortcode] {
+			require.NoError(t, os.WriteFile(filepath.Join(dir, photo.Shortcode+".jpg"), []byte("jpeg"), 0644))
+		}
+	}
+
+	archive, err := LoadArchive(dir)
+	require.NoError(t, err)
+	return archive
+}
+
+// readPost splits an exported file into its front matter and body
+func readPost(t *testing.T, path string) (map[string]interface{}, string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	content := string(data)
+	require.True(t, strings.HasPrefix(content, "---\n"), "file must start with front matter")
+	header, body, found := strings.Cut(strings.TrimPrefix(content, "---\n"), "\n---\n")
+	require.True(t, found, "front matter must be closed")
+
+	var fm map[string]interface{}
+	require.NoError(t, yaml.Unmarshal([]byte(header), &fm))
+	return fm, strings.TrimSpace(body)
+}
+
+func TestMarkdownExport(t *testing.T) {
+	taken := time.Date(2024, 3, 15, 18, 30, 0, 0, time.UTC)
+	archive := newTestArchive(t, []metadata.PhotoMetadata{
+		{
+			Shortcode:            "ABC123",
+			TakenAt:              taken,
+			Caption:              "Golden hour at the beach: #sunset #Beach\nSecond line\n\n- not a list #sunset",
+			AccessibilityCaption: "Photo of a beach at sunset",
+			Location:             &metadata.Location{Name: "Santa Monica Beach"},
+			LikesCount:           120,
+			CommentsCount:        4,
+			Owner:                metadata.Owner{Username: "alice"},
+		},
+		{Shortcode: "OLD1", TakenAt: taken.AddDate(0, -1, 0)},
+		{Shortcode: "GONE", TakenAt: taken},
+	}, "GONE")
+
+	out := filepath.Join(filepath.Dir(archive.Dir), "_posts")
+	exporter, err := New("markdown", Options{})
+	require.NoError(t, err)
+	n, err := exporter.Export(archive, out)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n, "posts without a photo on disk are skipped")
+
+	fm, body := readPost(t, filepath.Join(out, "2024-03-15-ABC123.md"))
+	assert.Equal(t, "Golden hour at the beach: #sunset #Beach", fm["title"])
+	assert.Equal(t, taken, fm["date"])
+	assert.Equal(t, "ABC123", fm["shortcode"])
+	assert.Equal(t, "https://www.instagram.com/p/ABC123/", fm["instagram_url"])
+	assert.Equal(t, "alice", fm["author"])
+	assert.Equal(t, 120, fm["likes"])
+	assert.Equal(t, 4, fm["comments"])
+	assert.Equal(t, "Santa Monica Beach", fm["location"])
+	assert.Equal(t, []interface{}{"sunset", "beach"}, fm["tags"])
+	assert.Equal(t, "../archive/ABC123.jpg", fm["image"])
+
+	assert.Equal(t, "Golden hour at the beach: #sunset #Beach  \nSecond line\n\n\\- not a list #sunset\n\n"+
+		"![Photo of a beach at sunset](../archive/ABC123.jpg)", body)
+
+	fm, body = readPost(t, filepath.Join(out, "2024-02-15-OLD1.md"))
+	assert.Equal(t, "Instagram post OLD1", fm["title"])
+	assert.NotContains(t, fm, "location")
+	assert.NotContains(t, fm, "tags")
+	assert.Equal(t, "![Instagram post OLD1](../archive/OLD1.jpg)", body)
+
+	_, err = os.Stat(filepath.Join(out, "2024-03-15-GONE.md"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestMarkdownExportImagePrefix(t *testing.T) {
+	archive := newTestArchive(t, []metadata.PhotoMetadata{
+		{Shortcode: "ABC123", TakenAt: time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)},
+	})
+
+	out := t.TempDir()
+	_, err := NewMarkdown(Options{ImagePrefix: "/images/instagram/"}).Export(archive, out)
+	require.NoError(t, err)
+
+	fm, body := readPost(t, filepath.Join(out, "2024-03-15-ABC123.md"))
+	assert.Equal(t, "/images/instagram/ABC123.jpg", fm["image"])
+	assert.Contains(t, body, "(/images/instagram/ABC123.jpg)")
+}
+
+func TestArchivePostsKeepsLatestMetadata(t *testing.T) {
+	taken := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+	archive := newTestArchive(t, []metadata.PhotoMetadata{
+		{Shortcode: "B", TakenAt: taken.Add(-time.Hour)},
+		{Shortcode: "A", TakenAt: taken, LikesCount: 1},
+		{Shortcode: "A", TakenAt: taken, LikesCount: 7},
+	})
+
+	posts := archive.Posts()
+	require.Len(t, posts, 2)
+	assert.Equal(t, "A", posts[0].Photo.Shortcode, "newest post first")
+	assert.Equal(t, 7, posts[0].Photo.LikesCount)
+	assert.Equal(t, "B", posts[1].Photo.Shortcode)
+}
+
+func TestLoadArchiveWithoutMetadata(t *testing.T) {
+	_, err := LoadArchive(t.TempDir())
+	assert.Error(t, err)
+}
+
+func TestNewUnknownFormat(t *testing.T) {
+	_, err := New("pdf", Options{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "markdown")
+}
+
+func TestPostTitle(t *testing.T) {
+	assert.Equal(t, "Short caption", postTitle("  Short caption\nmore", "X"))
+	assert.Equal(t, "Instagram post X", postTitle("\n\n", "X"))
+
+	long := postTitle(strings.Repeat("word ", 30), "X")
+	assert.True(t, strings.HasSuffix(long, "word…"), long)
+	assert.LessOrEqual(t, len([]rune(long)), maxTitleLength+1)
+}
+
+func TestMarkdownText(t *testing.T) {
+	tests := []struct {
+		caption string
+		want    string
+	}{
+		{"plain text", "plain text"},
+		{"*bold* and _em_ [link](x)", `\*bold\* and \_em\_ \[link\](x)`},
+		{"# heading", `\# heading`},
+		{"1. first\n2) second", "1\\. first  \n2\\) second"},
+		{"2024 was great", "2024 was great"},
+		{"one\r\n\r\n\r\n\r\ntwo", "one\n\ntwo"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, markdownText(tt.caption), "caption %q", tt.caption)
+	}
+}
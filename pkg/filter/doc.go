@@ -0,0 +1,27 @@
+// Package filter parses and evaluates rules that decide which posts are
+// downloaded.
+//
+// A filter is a boolean expression over the fields of a post:
+//
+//	hashtag:sunset        caption contains the hashtag #sunset
+//	#sunset               shorthand for hashtag:sunset
+//	caption:"golden hour" caption contains the text (case-insensitive)
+//	type:photo            photos only (type:video for videos)
+//	likes>100             like count comparison (>, >=, <, <=, =, !=)
+//	comments<=10          comment count comparison
+//
+// Terms are combined with AND, OR and NOT (case-insensitive) and grouped
+// with parentheses. AND binds tighter than OR, and adjacent terms without an
+// operator are joined with AND:
+//
+//	f, err := filter.Parse(`hashtag:sunset AND likes>100 AND NOT caption:ad`)
+//	if err != nil {
+//	    return err
+//	}
+//	if f.Match(&edge.Node) {
+//	    // queue the download
+//	}
+//
+// A nil *Filter matches every post, so callers can hold an optional filter
+// without checking for it.
+package filter
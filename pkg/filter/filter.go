@@ -0,0 +1,203 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"igscraper/pkg/instagram"
+)
+
+// hashtagPattern matches a hashtag in a caption
+var hashtagPattern = regexp.MustCompile(`#([\p{L}\p{N}_]+)`)
+
+// Filter is a parsed filter expression
+type Filter struct {
+	source string
+	root   expr
+}
+
+// Parse compiles a filter expression
+func Parse(source string) (*Filter, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("filter is empty")
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %s at position %d", tok, tok.pos+1)
+	}
+
+	return &Filter{source: source, root: root}, nil
+}
+
+// Match reports whether a post passes the filter. A nil filter matches
+// everything.
+func (f *Filter) Match(node *instagram.Node) bool {
+	if f == nil {
+		return true
+	}
+	return f.root.match(node)
+}
+
+// String returns the expression the filter was parsed from
+func (f *Filter) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.source
+}
+
+// Hashtags returns the lowercased hashtags in a caption, without the '#'
+func Hashtags(caption string) []string {
+	matches := hashtagPattern.FindAllStringSubmatch(caption, -1)
+	tags := make([]string, 0, len(matches))
+	for _, m := range matches {
+		tags = append(tags, strings.ToLower(m[1]))
+	}
+	return tags
+}
+
+// caption returns the text of a post's caption
+func caption(node *instagram.Node) string {
+	if len(node.EdgeMediaToCaption.Edges) == 0 {
+		return ""
+	}
+	return node.EdgeMediaToCaption.Edges[0].Node.Text
+}
+
+// expr is a node of the parsed expression tree
+type expr interface {
+	match(node *instagram.Node) bool
+}
+
+type andExpr struct{ left, right expr }
+
+func (e andExpr) match(node *instagram.Node) bool {
+	return e.left.match(node) && e.right.match(node)
+}
+
+type orExpr struct{ left, right expr }
+
+func (e orExpr) match(node *instagram.Node) bool {
+	return e.left.match(node) || e.right.match(node)
+}
+
+type notExpr struct{ inner expr }
+
+func (e notExpr) match(node *instagram.Node) bool {
+	return !e.inner.match(node)
+}
+
+type hashtagTerm struct{ tag string }
+
+func (t hashtagTerm) match(node *instagram.Node) bool {
+	for _, tag := range Hashtags(caption(node)) {
+		if tag == t.tag {
+			return true
+		}
+	}
+	return false
+}
+
+type captionTerm struct{ text string }
+
+func (t captionTerm) match(node *instagram.Node) bool {
+	return strings.Contains(strings.ToLower(caption(node)), t.text)
+}
+
+type typeTerm struct{ video bool }
+
+func (t typeTerm) match(node *instagram.Node) bool {
+	return node.IsVideo == t.video
+}
+
+type countTerm struct {
+	field string
+	op    string
+	value int
+}
+
+func (t countTerm) match(node *instagram.Node) bool {
+	var count int
+	switch t.field {
+	case "likes":
+		count = node.EdgeLikedBy.Count
+	case "comments":
+		count = node.EdgeMediaToComment.Count
+	}
+
+	switch t.op {
+	case ">":
+		return count > t.value
+	case ">=":
+		return count >= t.value
+	case "<":
+		return count < t.value
+	case "<=":
+		return count <= t.value
+	case "=":
+		return count == t.value
+	case "!=":
+		return count != t.value
+	}
+	return false
+}
+
+// newTerm builds a term from a field, an operator and a value
+func newTerm(field, op, value string, pos int) (expr, error) {
+	switch strings.ToLower(field) {
+	case "hashtag", "tag":
+		if op != ":" {
+			return nil, fmt.Errorf("%s takes ':' at position %d", field, pos+1)
+		}
+		tag := strings.ToLower(strings.TrimPrefix(value, "#"))
+		if tag == "" {
+			return nil, fmt.Errorf("empty hashtag at position %d", pos+1)
+		}
+		return hashtagTerm{tag: tag}, nil
+
+	case "caption":
+		if op != ":" {
+			return nil, fmt.Errorf("%s takes ':' at position %d", field, pos+1)
+		}
+		return captionTerm{text: strings.ToLower(value)}, nil
+
+	case "type":
+		if op != ":" {
+			return nil, fmt.Errorf("%s takes ':' at position %d", field, pos+1)
+		}
+		switch strings.ToLower(value) {
+		case "photo", "image":
+			return typeTerm{video: false}, nil
+		case "video":
+			return typeTerm{video: true}, nil
+		}
+		return nil, fmt.Errorf("unknown type %q at position %d: use photo or video", value, pos+1)
+
+	case "likes", "comments":
+		switch op {
+		case ":":
+			op = "="
+		case ">", ">=", "<", "<=", "=", "!=":
+		default:
+			return nil, fmt.Errorf("%s takes >, >=, <, <=, =, != or ':' at position %d, got '%s'", field, pos+1, op)
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s needs a number at position %d, got %q", field, pos+1, value)
+		}
+		return countTerm{field: strings.ToLower(field), op: op, value: n}, nil
+	}
+
+	return nil, fmt.Errorf("unknown field %q at position %d", field, pos+1)
+}
@@ -0,0 +1,103 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"igscraper/pkg/instagram"
+)
+
+func newNode(text string, likes, comments int, video bool) *instagram.Node {
+	node := &instagram.Node{IsVideo: video}
+	if text != "" {
+		node.EdgeMediaToCaption.Edges = []instagram.CaptionEdge{{Node: instagram.CaptionNode{Text: text}}}
+	}
+	node.EdgeLikedBy.Count = likes
+	node.EdgeMediaToComment.Count = comments
+	return node
+}
+
+func TestMatch(t *testing.T) {
+	sunset := newNode("Golden hour at the beach #Sunset #travel", 150, 12, false)
+	ad := newNode("New drop! #ad #sunset", 40, 3, false)
+	clip := newNode("Timelapse #sunset", 900, 80, true)
+	bare := newNode("", 0, 0, false)
+
+	tests := []struct {
+		filter string
+		want   map[*instagram.Node]bool
+	}{
+		{"hashtag:sunset", map[*instagram.Node]bool{sunset: true, ad: true, clip: true, bare: false}},
+		{"#travel", map[*instagram.Node]bool{sunset: true, ad: false, clip: false}},
+		{"hashtag:#SUNSET", map[*instagram.Node]bool{sunset: true, bare: false}},
+		{"hashtag:sun", map[*instagram.Node]bool{sunset: false}},
+		{`caption:"golden hour"`, map[*instagram.Node]bool{sunset: true, ad: false}},
+		{"type:photo", map[*instagram.Node]bool{sunset: true, clip: false}},
+		{"type:video", map[*instagram.Node]bool{sunset: false, clip: true}},
+		{"likes>100", map[*instagram.Node]bool{sunset: true, ad: false, clip: true}},
+		{"likes>=150", map[*instagram.Node]bool{sunset: true, ad: false}},
+		{"likes<50", map[*instagram.Node]bool{ad: true, sunset: false}},
+		{"comments=3", map[*instagram.Node]bool{ad: true, sunset: false}},
+		{"comments!=3", map[*instagram.Node]bool{ad: false, sunset: true}},
+		{"hashtag:sunset AND likes>100", map[*instagram.Node]bool{sunset: true, ad: false, clip: true}},
+		{"hashtag:sunset likes>100 type:photo", map[*instagram.Node]bool{sunset: true, ad: false, clip: false}},
+		{"hashtag:sunset AND NOT hashtag:ad", map[*instagram.Node]bool{sunset: true, ad: false, clip: true}},
+		{"#ad OR likes>500", map[*instagram.Node]bool{sunset: false, ad: true, clip: true}},
+		{"#travel OR #ad AND likes>100", map[*instagram.Node]bool{sunset: true, ad: false}},
+		{"(#travel OR #ad) AND likes>100", map[*instagram.Node]bool{sunset: true, ad: false}},
+		{"not (type:video or #ad)", map[*instagram.Node]bool{sunset: true, ad: false, clip: false, bare: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			f, err := Parse(tt.filter)
+			require.NoError(t, err)
+			for node, want := range tt.want {
+				assert.Equal(t, want, f.Match(node), "caption %q", caption(node))
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		filter string
+		errMsg string
+	}{
+		{"", "filter is empty"},
+		{"   ", "filter is empty"},
+		{"hashtag", "expected an operator"},
+		{"hashtag:", "expected a value"},
+		{"likes>many", "needs a number"},
+		{"views>10", "unknown field"},
+		{"type:reel", "unknown type"},
+		{"hashtag>3", "takes ':'"},
+		{"likes==100", "likes takes >, >=, <, <=, =, != or ':' at position 1, got '=='"},
+		{"(#sunset", "expected ')'"},
+		{"#sunset)", "unexpected ')'"},
+		{"#sunset AND", "unexpected end of filter"},
+		{`caption:"open`, "unterminated string"},
+		{"likes ! 3", "unexpected '!'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			_, err := Parse(tt.filter)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+}
+
+func TestNilFilterMatchesEverything(t *testing.T) {
+	var f *Filter
+	assert.True(t, f.Match(newNode("anything", 0, 0, true)))
+	assert.Equal(t, "", f.String())
+}
+
+func TestHashtags(t *testing.T) {
+	assert.Equal(t, []string{"sunset", "café", "no_filter"}, Hashtags("#Sunset at the #café #no_filter."))
+	assert.Empty(t, Hashtags("no tags here"))
+}
@@ -0,0 +1,224 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenString
+	tokenOp
+	tokenLParen
+	tokenRParen
+)
+
+// token is one lexical element of a filter expression
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of filter"
+	case tokenString:
+		return fmt.Sprintf("%q", t.text)
+	default:
+		return fmt.Sprintf("'%s'", t.text)
+	}
+}
+
+// keyword returns the upper-cased boolean operator a word token spells, if any
+func (t token) keyword() string {
+	if t.kind != tokenWord {
+		return ""
+	}
+	switch upper := strings.ToUpper(t.text); upper {
+	case "AND", "OR", "NOT":
+		return upper
+	}
+	return ""
+}
+
+// tokenize splits a filter expression into tokens
+func tokenize(source string) ([]token, error) {
+	var tokens []token
+	runes := []rune(source)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case r == ' ' || r == '\t' || r == '\n':
+			i++
+
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: i})
+			i++
+
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: i})
+			i++
+
+		case r == '"':
+			start := i
+			var sb strings.Builder
+			i++
+			for i < len(runes) && runes[i] != '"' {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				sb.WriteRune(runes[i])
+				i++
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", start+1)
+			}
+			i++
+			tokens = append(tokens, token{kind: tokenString, text: sb.String(), pos: start})
+
+		case strings.ContainsRune(":<>=!", r):
+			start := i
+			op := string(r)
+			if r != ':' && i+1 < len(runes) && runes[i+1] == '=' {
+				op += "="
+			}
+			if op == "!" {
+				return nil, fmt.Errorf("unexpected '!' at position %d", start+1)
+			}
+			i += len(op)
+			tokens = append(tokens, token{kind: tokenOp, text: op, pos: start})
+
+		default:
+			start := i
+			for i < len(runes) && !strings.ContainsRune(" \t\n()\":<>=!", runes[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenWord, text: string(runes[start:i]), pos: start})
+		}
+	}
+
+	return tokens, nil
+}
+
+// parser is a recursive descent parser over the token list. The grammar is
+//
+//	or      = and { "OR" and }
+//	and     = unary { ["AND"] unary }
+//	unary   = "NOT" unary | primary
+//	primary = "(" or ")" | term
+//	term    = "#" word | word op value
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	if p.pos >= len(p.tokens) {
+		return token{kind: tokenEOF, pos: p.endPos()}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.peek()
+	if p.pos < len(p.tokens) {
+		p.pos++
+	}
+	return tok
+}
+
+// endPos is the position just after the last token
+func (p *parser) endPos() int {
+	if len(p.tokens) == 0 {
+		return 0
+	}
+	last := p.tokens[len(p.tokens)-1]
+	return last.pos + len([]rune(last.text))
+}
+
+func (p *parser) parseOr() (expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().keyword() == "OR" {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orExpr{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		switch {
+		case tok.keyword() == "AND":
+			p.next()
+		case tok.kind == tokenEOF, tok.kind == tokenRParen, tok.keyword() == "OR":
+			return left, nil
+		}
+		// Anything else starts another operand, joined with an implicit AND
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andExpr{left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (expr, error) {
+	if p.peek().keyword() == "NOT" {
+		p.next()
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{inner: inner}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (expr, error) {
+	tok := p.next()
+	switch {
+	case tok.kind == tokenLParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, fmt.Errorf("expected ')' at position %d, got %s", closing.pos+1, closing)
+		}
+		return inner, nil
+
+	case tok.kind == tokenWord && tok.keyword() == "":
+		if strings.HasPrefix(tok.text, "#") && p.peek().kind != tokenOp {
+			return newTerm("hashtag", ":", tok.text, tok.pos)
+		}
+		op := p.next()
+		if op.kind != tokenOp {
+			return nil, fmt.Errorf("expected an operator after %q at position %d, got %s", tok.text, op.pos+1, op)
+		}
+		value := p.next()
+		if value.kind != tokenWord && value.kind != tokenString {
+			return nil, fmt.Errorf("expected a value after '%s' at position %d, got %s", op.text, value.pos+1, value)
+		}
+		return newTerm(tok.text, op.text, value.text, tok.pos)
+	}
+
+	return nil, fmt.Errorf("unexpected %s at position %d", tok, tok.pos+1)
+}
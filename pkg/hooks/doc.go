@@ -0,0 +1,32 @@
+// Package hooks runs commands of the user's at points of a scrape, so files
+// can be pushed on to another program without changing igscraper:
+//
+//   - post_download runs after every file is saved
+//   - on_complete runs when a profile or feed has been scraped
+//   - on_error runs when a download or a scrape fails
+//
+// Each command runs through the shell, sh -c or cmd /C on Windows, with the
+// hook's context as a JSON object on stdin and the hook's name in the
+// IGSCRAPER_HOOK environment variable:
+//
+//	hooks:
+//	  post_download: "rclone copy \"$(jq -r .path)\" remote:instagram"
+//	  on_complete: "/usr/local/bin/index-photos"
+//	  on_error: "logger -t igscraper"
+//	  timeout: 1m
+//
+// Hooks run one at a time in the order they happened, in the background, so
+// a slow command holds back the hooks after it but not the downloads. A
+// command that fails or runs past the timeout is logged and otherwise
+// ignored; Wait waits for the hooks queued so far.
+//
+// Usage:
+//
+//	runner := hooks.New(cfg.Hooks, log)
+//	runner.Run(hooks.PostDownload, map[string]interface{}{
+//	    "username":  "johndoe",
+//	    "shortcode": "C1a2b3",
+//	    "path":      "/photos/johndoe_photos/C1a2b3.jpg",
+//	})
+//	runner.Wait()
+package hooks
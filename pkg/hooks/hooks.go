@@ -0,0 +1,174 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"igscraper/pkg/config"
+	"igscraper/pkg/logger"
+)
+
+// Hook names, as the settings of the hooks section
+const (
+	PostDownload = "post_download"
+	OnComplete   = "on_complete"
+	OnError      = "on_error"
+)
+
+// EnvHook is the environment variable holding the name of the hook a command
+// runs for
+const EnvHook = "IGSCRAPER_HOOK"
+
+// maxOutput is how much of a failed command's output is logged
+const maxOutput = 1024
+
+// call is a hook waiting to run
+type call struct {
+	hook    string
+	command string
+	input   []byte
+}
+
+// Runner runs the configured hooks one at a time in the background. A nil
+// Runner runs nothing.
+type Runner struct {
+	commands map[string]string
+	timeout  time.Duration
+	logger   logger.Logger
+
+	mu      sync.Mutex
+	queue   []call
+	running bool
+	pending sync.WaitGroup
+}
+
+// New creates a Runner for the hooks settings
+func New(cfg config.HooksConfig, log logger.Logger) *Runner {
+	if log == nil {
+		log = logger.Named(logger.ComponentHooks)
+	}
+	return &Runner{
+		commands: map[string]string{
+			PostDownload: strings.TrimSpace(cfg.PostDownload),
+			OnComplete:   strings.TrimSpace(cfg.OnComplete),
+			OnError:      strings.TrimSpace(cfg.OnError),
+		},
+		timeout: cfg.Timeout,
+		logger:  log,
+	}
+}
+
+// Enabled reports whether a command is configured for hook
+func (r *Runner) Enabled(hook string) bool {
+	return r != nil && r.commands[hook] != ""
+}
+
+// Run queues the command of hook with fields as its context, along with the
+// hook's name and the time. Nothing runs when the hook has no command.
+func (r *Runner) Run(hook string, fields map[string]interface{}) {
+	if !r.Enabled(hook) {
+		return
+	}
+	context := make(map[string]interface{}, len(fields)+2)
+	for key, value := range fields {
+		context[key] = value
+	}
+	context["hook"] = hook
+	context["time"] = time.Now().UTC().Format(time.RFC3339)
+	input, err := json.Marshal(context)
+	if err != nil {
+		r.logger.WithError(err).WithField("hook", hook).Warn("Failed to encode hook context")
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pending.Add(1)
+	r.queue = append(r.queue, call{hook: hook, command: r.commands[hook], input: append(input, '\n')})
+	if !r.running {
+		r.running = true
+		go r.drain()
+	}
+}
+
+// Wait waits until the hooks queued so far have run
+func (r *Runner) Wait() {
+	if r == nil {
+		return
+	}
+	r.pending.Wait()
+}
+
+// drain runs queued hooks until the queue is empty
+func (r *Runner) drain() {
+	for {
+		r.mu.Lock()
+		if len(r.queue) == 0 {
+			r.running = false
+			r.mu.Unlock()
+			return
+		}
+		next := r.queue[0]
+		r.queue = r.queue[1:]
+		r.mu.Unlock()
+
+		r.run(next)
+		r.pending.Done()
+	}
+}
+
+// run runs the command of a hook and logs how it went
+func (r *Runner) run(c call) {
+	ctx := context.Background()
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+
+	cmd := shellCommand(ctx, c.command)
+	cmd.Stdin = bytes.NewReader(c.input)
+	cmd.Env = append(os.Environ(), EnvHook+"="+c.hook)
+	// Processes the command started may keep its output open
+	cmd.WaitDelay = time.Second
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	start := time.Now()
+	err := cmd.Run()
+	fields := map[string]interface{}{
+		"hook":     c.hook,
+		"duration": time.Since(start).String(),
+	}
+	if err == nil {
+		r.logger.DebugWithFields("Hook ran", fields)
+		return
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		fields["timeout"] = r.timeout.String()
+	}
+	fields["error"] = err.Error()
+	if out := strings.TrimSpace(output.String()); out != "" {
+		if len(out) > maxOutput {
+			out = out[len(out)-maxOutput:]
+		}
+		fields["output"] = out
+	}
+	r.logger.WarnWithFields("Hook failed", fields)
+}
+
+// shellCommand returns the command running command through the shell
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
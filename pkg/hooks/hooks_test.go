@@ -0,0 +1,84 @@
+package hooks
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"igscraper/pkg/config"
+	"igscraper/pkg/logger"
+)
+
+func TestRunner(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the commands are written for sh")
+	}
+
+	t.Run("context on stdin in order", func(t *testing.T) {
+		out := filepath.Join(t.TempDir(), "hooks.log")
+		r := New(config.HooksConfig{
+			PostDownload: `cat >> "` + out + `"`,
+			OnComplete:   `echo "$IGSCRAPER_HOOK" >> "` + out + `"`,
+			Timeout:      time.Minute,
+		}, logger.NewTestLogger())
+		assert.True(t, r.Enabled(PostDownload))
+		assert.False(t, r.Enabled(OnError))
+
+		fields := map[string]interface{}{"shortcode": "A"}
+		r.Run(PostDownload, fields)
+		r.Run(PostDownload, map[string]interface{}{"shortcode": "B"})
+		r.Run(OnError, map[string]interface{}{"shortcode": "C"})
+		r.Run(OnComplete, nil)
+		r.Wait()
+		assert.NotContains(t, fields, "hook", "the caller's fields are left alone")
+
+		data, err := os.ReadFile(out)
+		require.NoError(t, err)
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		require.Len(t, lines, 3)
+		for i, shortcode := range []string{"A", "B"} {
+			var context map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(lines[i]), &context))
+			assert.Equal(t, shortcode, context["shortcode"])
+			assert.Equal(t, PostDownload, context["hook"])
+			assert.NotEmpty(t, context["time"])
+		}
+		assert.Equal(t, OnComplete, lines[2])
+	})
+
+	t.Run("failures are logged", func(t *testing.T) {
+		log := logger.NewTestLogger()
+		r := New(config.HooksConfig{
+			OnError:    "echo broken pipeline >&2; exit 3",
+			OnComplete: "sleep 5",
+			Timeout:    100 * time.Millisecond,
+		}, log)
+
+		start := time.Now()
+		r.Run(OnError, nil)
+		r.Run(OnComplete, nil)
+		r.Wait()
+		assert.Less(t, time.Since(start), 3*time.Second, "the timeout stops the command")
+
+		failures := log.GetMessagesByLevel("WARN")
+		require.Len(t, failures, 2)
+		assert.Equal(t, OnError, failures[0].Fields["hook"])
+		assert.Equal(t, "broken pipeline", failures[0].Fields["output"])
+		assert.Equal(t, OnComplete, failures[1].Fields["hook"])
+		assert.Equal(t, "100ms", failures[1].Fields["timeout"])
+	})
+
+	t.Run("nil runner", func(t *testing.T) {
+		var r *Runner
+		assert.False(t, r.Enabled(PostDownload))
+		r.Run(PostDownload, nil)
+		r.Wait()
+	})
+}
@@ -0,0 +1,18 @@
+// Package igclient defines InstagramClient, the interface the scraper makes
+// its Instagram requests and downloads through. *instagram.Client implements
+// it; programs can pass a client of their own, such as one going through a
+// proxy pool or replaying recorded responses, to scraper.SetClient or
+// igscraper.WithClient.
+//
+// The mocks package holds a mock of the interface for tests, generated with
+// mockery rather than mockgen so that it builds on testify's mock package,
+// which the tests already use, instead of adding go.uber.org/mock:
+//
+//	go generate ./pkg/igclient
+//
+// Usage:
+//
+//	client := mocks.NewInstagramClient(t)
+//	client.On("DownloadPhoto", "https://cdn.example.com/C1a2b3.jpg").Return([]byte("..."), nil)
+//	s.SetClient(client)
+package igclient
@@ -0,0 +1,20 @@
+package igclient
+
+import (
+	"igscraper/pkg/instagram"
+)
+
+//go:generate mockery --name InstagramClient --output mocks --outpkg mocks --filename instagram_client.go
+
+// InstagramClient defines the interface for Instagram API operations.
+// Clients may also implement the optional interfaces of the downloader, such
+// as downloader.FileDownloader, which the scraper uses when they do.
+type InstagramClient interface {
+	GetJSON(url string, target interface{}) error
+	DownloadPhoto(photoURL string) ([]byte, error)
+	FetchUserProfile(username string) (*instagram.InstagramResponse, error)
+	FetchUserMedia(userID string, after string) (*instagram.InstagramResponse, error)
+}
+
+// The Instagram client is the one the scraper uses by default
+var _ InstagramClient = (*instagram.Client)(nil)
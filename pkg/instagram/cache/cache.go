@@ -0,0 +1,140 @@
+// Package cache keeps API responses on disk for a while, so scrapes that are
+// repeated, resumed or only estimated do not spend the request budget on
+// pages that were fetched moments ago.
+//
+// Each response is stored in a file of its own, named after a hash of its
+// key, together with the time it was stored. Entries older than the cache's
+// TTL are treated as missing and removed when next looked up.
+//
+// Example usage:
+//
+//	c, err := cache.New(dir, time.Hour)
+//	if data, ok := c.Get(url); ok {
+//	    // use data
+//	}
+//	c.Put(url, body)
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// entry is a response as stored on disk
+type entry struct {
+	Key      string          `json:"key"`
+	StoredAt time.Time       `json:"stored_at"`
+	Data     json.RawMessage `json:"data"`
+}
+
+// Cache is an on-disk store of JSON responses that expire after a TTL. It is
+// safe for concurrent use, also by several processes sharing a directory:
+// entries are replaced whole, so readers see an old entry or a new one.
+type Cache struct {
+	dir string
+	ttl time.Duration
+	now func() time.Time
+}
+
+// New creates a cache in dir whose entries expire after ttl
+func New(dir string, ttl time.Duration) (*Cache, error) {
+	if ttl <= 0 {
+		return nil, fmt.Errorf("cache TTL must be positive")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return &Cache{dir: dir, ttl: ttl, now: time.Now}, nil
+}
+
+// Dir returns the directory the cache is kept in
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// Get returns the JSON stored under key, if it has not expired
+func (c *Cache) Get(key string) ([]byte, bool) {
+	path := c.path(key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil || e.Key != key {
+		os.Remove(path)
+		return nil, false
+	}
+	if c.now().Sub(e.StoredAt) >= c.ttl {
+		os.Remove(path)
+		return nil, false
+	}
+	return e.Data, true
+}
+
+// Put stores data, which must be JSON, under key
+func (c *Cache) Put(key string, data []byte) error {
+	encoded, err := json.Marshal(entry{Key: key, StoredAt: c.now(), Data: data})
+	if err != nil {
+		return fmt.Errorf("failed to encode cache entry: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(c.dir, ".entry-*")
+	if err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if _, err := tmp.Write(encoded); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path(key)); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	return nil
+}
+
+// Prune removes the expired entries and returns how many there were
+func (c *Cache) Prune() (int, error) {
+	files, err := os.ReadDir(c.dir)
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(c.dir, file.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var e entry
+		if json.Unmarshal(data, &e) == nil && c.now().Sub(e.StoredAt) < c.ttl {
+			continue
+		}
+		if os.Remove(path) == nil {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// path returns the file an entry is stored in
+func (c *Cache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
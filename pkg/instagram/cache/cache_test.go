@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+	c, err := New(dir, time.Hour)
+	require.NoError(t, err)
+
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	_, ok := c.Get("https://example.com/a")
+	assert.False(t, ok)
+
+	require.NoError(t, c.Put("https://example.com/a", []byte(`{"id":"1"}`)))
+	data, ok := c.Get("https://example.com/a")
+	require.True(t, ok)
+	assert.JSONEq(t, `{"id":"1"}`, string(data))
+
+	// Another cache on the same directory sees the entry
+	other, err := New(dir, time.Hour)
+	require.NoError(t, err)
+	_, ok = other.Get("https://example.com/a")
+	assert.True(t, ok)
+
+	info, err := os.Stat(dir)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+
+	// Entries expire after the TTL and are removed
+	now = now.Add(time.Hour)
+	_, ok = c.Get("https://example.com/a")
+	assert.False(t, ok)
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+
+	_, err = New(dir, 0)
+	assert.Error(t, err)
+}
+
+func TestCacheCorruptEntry(t *testing.T) {
+	c, err := New(t.TempDir(), time.Hour)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(c.path("key"), []byte("not json"), 0600))
+	_, ok := c.Get("key")
+	assert.False(t, ok)
+	assert.NoFileExists(t, c.path("key"))
+}
+
+func TestCachePrune(t *testing.T) {
+	c, err := New(t.TempDir(), time.Hour)
+	require.NoError(t, err)
+
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	require.NoError(t, c.Put("old", []byte(`1`)))
+	now = now.Add(30 * time.Minute)
+	require.NoError(t, c.Put("new", []byte(`2`)))
+	now = now.Add(45 * time.Minute)
+
+	removed, err := c.Prune()
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	_, ok := c.Get("new")
+	assert.True(t, ok)
+}
@@ -0,0 +1,66 @@
+package instagram
+
+import (
+	"encoding/json"
+
+	"igscraper/pkg/instagram/cache"
+)
+
+// cachedResponse is a response as kept in the cache, with the endpoint
+// family that served it
+type cachedResponse struct {
+	Source   string             `json:"source"`
+	Response *InstagramResponse `json:"response"`
+}
+
+// SetCache keeps profile and media page responses in responses, so they are not
+// fetched again until they expire. Errors and empty profiles are not cached.
+func (c *Client) SetCache(responses *cache.Cache) {
+	c.cache = responses
+}
+
+// cached returns the response cached under key, if any
+func (c *Client) cached(key string) (*InstagramResponse, bool) {
+	if c.cache == nil {
+		return nil, false
+	}
+	data, ok := c.cache.Get(key)
+	if !ok {
+		return nil, false
+	}
+
+	var entry cachedResponse
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Response == nil {
+		return nil, false
+	}
+	resp := entry.Response
+	resp.Source = entry.Source
+	edges := resp.Data.User.EdgeOwnerToTimelineMedia.Edges
+	for i := range edges {
+		edges[i].Node.Source = entry.Source
+	}
+
+	c.logger.DebugWithFields("serving response from cache", map[string]interface{}{
+		"url":    key,
+		"source": entry.Source,
+	})
+	return resp, true
+}
+
+// store caches a response under key. Failing to is logged, not returned:
+// the response is still good.
+func (c *Client) store(key string, resp *InstagramResponse) {
+	if c.cache == nil {
+		return
+	}
+	data, err := json.Marshal(cachedResponse{Source: resp.Source, Response: resp})
+	if err == nil {
+		err = c.cache.Put(key, data)
+	}
+	if err != nil {
+		c.logger.WarnWithFields("failed to cache response", map[string]interface{}{
+			"url":   key,
+			"error": err.Error(),
+		})
+	}
+}
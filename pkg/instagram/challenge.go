@@ -0,0 +1,80 @@
+package instagram
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"igscraper/pkg/errors"
+)
+
+// maxErrorBodySize is how much of an error response is read to look for a
+// challenge
+const maxErrorBodySize = 64 << 10
+
+// Messages Instagram answers with when the account must pass a security
+// check before it can make more requests
+const (
+	MessageCheckpointRequired = "checkpoint_required"
+	MessageChallengeRequired  = "challenge_required"
+)
+
+// challengeResponse is the body of a checkpoint or challenge answer
+type challengeResponse struct {
+	Message       string `json:"message"`
+	CheckpointURL string `json:"checkpoint_url"`
+	Challenge     *struct {
+		URL     string `json:"url"`
+		APIPath string `json:"api_path"`
+	} `json:"challenge"`
+}
+
+// challengeError returns a challenge error if resp, whose body is body, is a
+// checkpoint_required or challenge_required answer or was redirected to a
+// challenge page. It returns nil for any other response.
+func (c *Client) challengeError(resp *http.Response, body []byte) *errors.Error {
+	var challengeURL string
+	switch {
+	case resp.Request != nil && strings.HasPrefix(resp.Request.URL.Path, "/challenge"):
+		challengeURL = resp.Request.URL.String()
+	case bytes.Contains(body, []byte("_required")) || bytes.Contains(body, []byte(`"challenge"`)):
+		var answer challengeResponse
+		if json.Unmarshal(body, &answer) != nil {
+			return nil
+		}
+		if answer.Message != MessageCheckpointRequired && answer.Message != MessageChallengeRequired && answer.Challenge == nil {
+			return nil
+		}
+		challengeURL = answer.CheckpointURL
+		if answer.Challenge != nil {
+			if answer.Challenge.URL != "" {
+				challengeURL = answer.Challenge.URL
+			} else if answer.Challenge.APIPath != "" {
+				challengeURL = answer.Challenge.APIPath
+			}
+		}
+		if strings.HasPrefix(challengeURL, "/") {
+			challengeURL = BaseURL + challengeURL
+		}
+	default:
+		return nil
+	}
+
+	fields := map[string]interface{}{
+		"status":        resp.StatusCode,
+		"challenge_url": challengeURL,
+	}
+	if resp.Request != nil {
+		fields["url"] = resp.Request.URL.String()
+	}
+	c.logger.ErrorWithFields("account challenged", fields)
+
+	return &errors.Error{
+		Type:         errors.ErrorTypeChallenge,
+		Message:      "Instagram requires the account to pass a security check",
+		Code:         resp.StatusCode,
+		ChallengeURL: challengeURL,
+		Payload:      errors.ParsePayload(body),
+	}
+}
@@ -6,11 +6,17 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"igscraper/pkg/audit"
 	"igscraper/pkg/config"
 	"igscraper/pkg/errors"
+	"igscraper/pkg/instagram/cache"
 	"igscraper/pkg/logger"
+	"igscraper/pkg/ratelimit"
 	"igscraper/pkg/retry"
 )
 
@@ -25,32 +31,60 @@ const (
 	ErrorTypeParsing     = errors.ErrorTypeParsing
 	ErrorTypeNotFound    = errors.ErrorTypeNotFound
 	ErrorTypeServerError = errors.ErrorTypeServerError
+	ErrorTypeChallenge   = errors.ErrorTypeChallenge
 	ErrorTypeUnknown     = errors.ErrorTypeUnknown
 )
 
+// DefaultUserAgent is the browser User-Agent sent when none is configured
+const DefaultUserAgent = "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/121.0.0.0 Safari/537.36"
+
 // Client represents an Instagram API client
 type Client struct {
 	httpClient *http.Client
+	headersMu  sync.RWMutex // headers may change while requests are in flight
 	headers    map[string]string
+	cookies    *cookieJar // instagram.com cookies, kept up to date from responses
 	baseURL    string
 	logger     logger.Logger
 	retrier    *retry.HTTPRetrier
 	retryConfig *config.RetryConfig
+	retries     *retry.Registry // per endpoint class policies, set with retryConfig
+
+	// pageEnds holds where the last GraphQL page of each profile's posts
+	// ended, so the mobile API can take over from there
+	pageEndsMu sync.Mutex
+	pageEnds   map[string]pageEnd
+
+	// cache, if set, keeps profile and media page responses on disk; see
+	// SetCache
+	cache *cache.Cache
+
+	// Videos of at least chunkMinSize bytes are fetched in chunks parallel
+	// ranged requests; see SetChunking
+	chunks       int
+	chunkMinSize int64
+
+	// bandwidth, if set, throttles the bodies of photo and video downloads;
+	// see SetBandwidth
+	bandwidth *ratelimit.Bandwidth
 }
 
 // NewClient creates a new Instagram API client
 func NewClient(timeout time.Duration, log logger.Logger) *Client {
 	// Use default logger if none provided
 	if log == nil {
-		log = logger.GetLogger()
+		log = logger.Named(logger.ComponentInstagram)
 	}
 
+	cookies := newCookieJar()
 	return &Client{
 		httpClient: &http.Client{
-			Timeout: timeout,
+			Timeout:   timeout,
+			Transport: audit.Transport(nil, EndpointCategory),
+			Jar:       cookies,
 		},
 		headers: map[string]string{
-			"User-Agent":       "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/121.0.0.0 Safari/537.36",
+			"User-Agent":       DefaultUserAgent,
 			"Accept":           "*/*",
 			"Accept-Language":  "en-US,en;q=0.9",
 			"Cache-Control":    "no-cache",
@@ -62,6 +96,7 @@ func NewClient(timeout time.Duration, log logger.Logger) *Client {
 			"X-Requested-With": "XMLHttpRequest",
 			"Referer":          "https://www.instagram.com/",
 		},
+		cookies: cookies,
 		baseURL: BaseURL,
 		logger:  log,
 		retrier: retry.NewHTTPRetrier(3, log), // Default 3 retries
@@ -73,7 +108,7 @@ func NewClient(timeout time.Duration, log logger.Logger) *Client {
 func NewClientWithConfig(timeout time.Duration, retryConfig *config.RetryConfig, log logger.Logger) *Client {
 	// Use default logger if none provided
 	if log == nil {
-		log = logger.GetLogger()
+		log = logger.Named(logger.ComponentInstagram)
 	}
 
 	// Create retrier based on config
@@ -83,13 +118,20 @@ func NewClientWithConfig(timeout time.Duration, retryConfig *config.RetryConfig,
 	} else {
 		retrier = retry.NewHTTPRetrier(0, log) // No retries
 	}
+	var retries *retry.Registry
+	if retryConfig != nil {
+		retries = retry.NewRegistryFromConfig(retryConfig)
+	}
 
+	cookies := newCookieJar()
 	return &Client{
 		httpClient: &http.Client{
-			Timeout: timeout,
+			Timeout:   timeout,
+			Transport: audit.Transport(nil, EndpointCategory),
+			Jar:       cookies,
 		},
 		headers: map[string]string{
-			"User-Agent":       "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/121.0.0.0 Safari/537.36",
+			"User-Agent":       DefaultUserAgent,
 			"Accept":           "*/*",
 			"Accept-Language":  "en-US,en;q=0.9",
 			"Cache-Control":    "no-cache",
@@ -101,20 +143,34 @@ func NewClientWithConfig(timeout time.Duration, retryConfig *config.RetryConfig,
 			"X-Requested-With": "XMLHttpRequest",
 			"Referer":          "https://www.instagram.com/",
 		},
+		cookies:     cookies,
 		baseURL:     BaseURL,
 		logger:      log,
 		retrier:     retrier,
 		retryConfig: retryConfig,
+		retries:     retries,
 	}
 }
 
 // SetHeader sets a custom header for the client
 func (c *Client) SetHeader(key, value string) {
+	c.headersMu.Lock()
+	defer c.headersMu.Unlock()
 	c.headers[key] = value
 }
 
-// SetHeaders sets multiple headers at once
+// SetTransport replaces the HTTP transport used for all requests.
+// This is mainly useful for pointing the client at a local server in
+// tests and in demo mode. Requests are still recorded in the audit log.
+func (c *Client) SetTransport(transport http.RoundTripper) {
+	c.httpClient.Transport = audit.Transport(transport, EndpointCategory)
+}
+
+// SetHeaders sets multiple headers at once. Requests already being sent keep
+// the headers they started with.
 func (c *Client) SetHeaders(headers map[string]string) {
+	c.headersMu.Lock()
+	defer c.headersMu.Unlock()
 	for key, value := range headers {
 		c.headers[key] = value
 	}
@@ -123,15 +179,22 @@ func (c *Client) SetHeaders(headers map[string]string) {
 // doRequest performs an HTTP request with the configured headers
 func (c *Client) doRequest(req *http.Request) (*http.Response, error) {
 	// Set all headers
+	c.headersMu.RLock()
 	for key, value := range c.headers {
 		req.Header.Set(key, value)
 	}
+	c.headersMu.RUnlock()
+	if token := c.csrfToken(req); token != "" {
+		req.Header.Set("X-CSRFToken", token)
+	}
 
 	// Log the request
 	start := time.Now()
+	requestID := requestIDOf(req)
 	c.logger.DebugWithFields("sending HTTP request", map[string]interface{}{
-		"method": req.Method,
-		"url":    req.URL.String(),
+		"method":              req.Method,
+		"url":                 req.URL.String(),
+		logger.FieldRequestID: requestID,
 	})
 
 	resp, err := c.httpClient.Do(req)
@@ -139,10 +202,11 @@ func (c *Client) doRequest(req *http.Request) (*http.Response, error) {
 
 	if err != nil {
 		c.logger.ErrorWithFields("HTTP request failed", map[string]interface{}{
-			"method":   req.Method,
-			"url":      req.URL.String(),
-			"error":    err.Error(),
-			"duration": duration,
+			"method":              req.Method,
+			"url":                 req.URL.String(),
+			"error":               err.Error(),
+			"duration":            duration,
+			logger.FieldRequestID: requestID,
 		})
 		return nil, &errors.Error{
 			Type:    errors.ErrorTypeNetwork,
@@ -153,17 +217,23 @@ func (c *Client) doRequest(req *http.Request) (*http.Response, error) {
 
 	// Log successful response
 	c.logger.DebugWithFields("HTTP request completed", map[string]interface{}{
-		"method":   req.Method,
-		"url":      req.URL.String(),
-		"status":   resp.StatusCode,
-		"duration": duration,
+		"method":              req.Method,
+		"url":                 req.URL.String(),
+		"status":              resp.StatusCode,
+		"duration":            duration,
+		logger.FieldRequestID: requestID,
 	})
 
 	return resp, nil
 }
 
-// doRequestWithRetry performs an HTTP request with retry logic using the retry package
+// doRequestWithRetry performs an HTTP request with retry logic using the retry package.
+// A request without an ID is given one, which its retries are logged under too.
 func (c *Client) doRequestWithRetry(req *http.Request) (*http.Response, error) {
+	if requestIDOf(req) == "" {
+		req = withRequestID(req, logger.NewRequestID())
+	}
+	
 	if c.retrier == nil || (c.retryConfig != nil && !c.retryConfig.Enabled) {
 		// No retry configured, just do the request
 		return c.doRequest(req)
@@ -183,24 +253,33 @@ func (c *Client) doRequestWithRetry(req *http.Request) (*http.Response, error) {
 		// Check if response indicates we should retry
 		if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
 			lastErr = &errors.Error{
-				Type:    errors.ErrorTypeServerError,
-				Message: fmt.Sprintf("server returned status %d", resp.StatusCode),
-				Code:    resp.StatusCode,
+				Type:       errors.ErrorTypeServerError,
+				Message:    fmt.Sprintf("server returned status %d", resp.StatusCode),
+				Code:       resp.StatusCode,
+				RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
 			}
 			if resp.StatusCode == http.StatusTooManyRequests {
 				lastErr.(*errors.Error).Type = errors.ErrorTypeRateLimit
 			}
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
 			resp.Body.Close()
+			lastErr = c.withPayload(lastErr, resp, body)
 			return lastErr
 		}
 		
 		// Check for other errors that shouldn't be retried
 		if resp.StatusCode == 401 || resp.StatusCode == 403 {
-			lastErr = &errors.Error{
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+			resp.Body.Close()
+			if challenge := c.challengeError(resp, body); challenge != nil {
+				lastErr = challenge
+				return lastErr
+			}
+			lastErr = c.withPayload(&errors.Error{
 				Type:    errors.ErrorTypeAuth,
 				Message: fmt.Sprintf("authentication error: %d", resp.StatusCode),
 				Code:    resp.StatusCode,
-			}
+			}, resp, body)
 			return lastErr
 		}
 		
@@ -225,6 +304,11 @@ func (c *Client) doRequestWithRetry(req *http.Request) (*http.Response, error) {
 
 // Get performs a GET request to the specified URL
 func (c *Client) Get(url string) (*http.Response, error) {
+	return c.get(url, logger.NewRequestID())
+}
+
+// get performs a GET request logged under requestID
+func (c *Client) get(url, requestID string) (*http.Response, error) {
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return nil, &errors.Error{
@@ -234,54 +318,71 @@ func (c *Client) Get(url string) (*http.Response, error) {
 		}
 	}
 
-	return c.doRequestWithRetry(req)
+	return c.doRequestWithRetry(withRequestID(req, requestID))
 }
 
 // GetJSON performs a GET request and decodes the JSON response
 func (c *Client) GetJSON(url string, target interface{}) error {
-	resp, err := c.Get(url)
+	_, err := c.getJSON(url, target)
+	return err
+}
+
+// getJSON performs a GET request and decodes the JSON response, returning
+// the ID the request was logged under
+func (c *Client) getJSON(url string, target interface{}) (string, error) {
+	requestID := logger.NewRequestID()
+	resp, err := c.get(url, requestID)
 	if err != nil {
-		return err
+		return requestID, err
 	}
 	defer resp.Body.Close()
 
-	// Check status code
+	// Check status code, unless the body says the account is challenged
 	if err := c.checkResponseStatus(resp); err != nil {
-		return err
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		if challenge := c.challengeError(resp, body); challenge != nil {
+			return requestID, challenge
+		}
+		return requestID, c.withPayload(err, resp, body)
 	}
 
 	// Read response body
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return &errors.Error{
+		return requestID, &errors.Error{
 			Type:    errors.ErrorTypeNetwork,
 			Message: fmt.Sprintf("failed to read response body: %v", err),
 			Code:    resp.StatusCode,
 		}
 	}
 
+	// A challenge may also come with 200 or after a redirect to its page,
+	// and other failures with 200 and a "fail" status
+	if challenge := c.challengeError(resp, body); challenge != nil {
+		return requestID, challenge
+	}
+	if err := c.failedStatus(resp, body); err != nil {
+		return requestID, err
+	}
+
 	// Decode JSON
 	if err := json.Unmarshal(body, target); err != nil {
-		// Create a preview of the body for debugging
-		bodyPreview := string(body)
-		if len(bodyPreview) > 200 {
-			bodyPreview = bodyPreview[:200] + "..."
-		}
-		
 		c.logger.ErrorWithFields("failed to parse JSON response", map[string]interface{}{
-			"url":          url,
-			"status":       resp.StatusCode,
-			"error":        err.Error(),
-			"body_preview": bodyPreview,
+			"url":                 url,
+			"status":              resp.StatusCode,
+			"error":               err.Error(),
+			"content_type":        resp.Header.Get("Content-Type"),
+			"body_size":           len(body),
+			logger.FieldRequestID: requestID,
 		})
-		return &errors.Error{
+		return requestID, &errors.Error{
 			Type:    errors.ErrorTypeParsing,
 			Message: fmt.Sprintf("failed to parse JSON: %v", err),
 			Code:    resp.StatusCode,
 		}
 	}
 
-	return nil
+	return requestID, nil
 }
 
 // checkResponseStatus checks the HTTP response status and returns appropriate errors
@@ -310,14 +411,17 @@ func (c *Client) checkResponseStatus(resp *http.Response) error {
 			Code:    resp.StatusCode,
 		}
 	case http.StatusTooManyRequests:
+		retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
 		c.logger.WarnWithFields("rate limit exceeded", map[string]interface{}{
-			"status": resp.StatusCode,
-			"url":    resp.Request.URL.String(),
+			"status":      resp.StatusCode,
+			"url":         resp.Request.URL.String(),
+			"retry_after": retryAfter.String(),
 		})
 		return &errors.Error{
-			Type:    errors.ErrorTypeRateLimit,
-			Message: "rate limit exceeded",
-			Code:    resp.StatusCode,
+			Type:       errors.ErrorTypeRateLimit,
+			Message:    "rate limit exceeded",
+			Code:       resp.StatusCode,
+			RetryAfter: retryAfter,
 		}
 	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable:
 		c.logger.ErrorWithFields("server error", map[string]interface{}{
@@ -325,9 +429,10 @@ func (c *Client) checkResponseStatus(resp *http.Response) error {
 			"url":    resp.Request.URL.String(),
 		})
 		return &errors.Error{
-			Type:    errors.ErrorTypeServerError,
-			Message: "server error",
-			Code:    resp.StatusCode,
+			Type:       errors.ErrorTypeServerError,
+			Message:    "server error",
+			Code:       resp.StatusCode,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
 		}
 	default:
 		if resp.StatusCode >= 400 {
@@ -345,8 +450,69 @@ func (c *Client) checkResponseStatus(resp *http.Response) error {
 	}
 }
 
-// FetchUserProfile fetches the Instagram user profile data
+// parseRetryAfter parses a Retry-After header, given either as a number of
+// seconds or as an HTTP date, into the delay from now. It returns zero when
+// the header is missing, invalid or in the past.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now).Round(time.Second)
+	}
+	return 0
+}
+
+// FetchUserProfile fetches the Instagram user profile data from
+// web_profile_info. If that endpoint answers with a 400, a body that cannot
+// be parsed or no profile, the profile page's GraphQL JSON is tried instead;
+// the response's Source names the endpoint used. With a cache set, a profile
+// fetched within its TTL is served from it.
 func (c *Client) FetchUserProfile(username string) (*InstagramResponse, error) {
+	key := GetProfileURL(username)
+	if resp, ok := c.cached(key); ok {
+		return resp, nil
+	}
+	resp, err := c.fetchProfile(username)
+	if err == nil && resp.Data.User.ID != "" {
+		c.store(key, resp)
+	}
+	return resp, err
+}
+
+// fetchProfile fetches a profile, falling back to the profile page's GraphQL
+// JSON
+func (c *Client) fetchProfile(username string) (*InstagramResponse, error) {
+	resp, err := c.fetchProfileInfo(username)
+	if !fallBack(err, err == nil && resp.Data.User.ID == "") {
+		return resp, err
+	}
+
+	c.logFallback("profile", username, SourceWebProfileInfo, SourceGraphQL, err)
+	alt, altErr := c.fetchProfilePage(username)
+	if altErr != nil || alt.Data.User.ID == "" {
+		c.logger.WarnWithFields("alternative profile endpoint failed", map[string]interface{}{
+			"username": username,
+			"error":    fmt.Sprint(altErr),
+		})
+		return resp, err
+	}
+	c.logger.InfoWithFields("fetched profile from alternative endpoint", map[string]interface{}{
+		"username": username,
+		"source":   alt.Source,
+	})
+	return alt, nil
+}
+
+// fetchProfileInfo fetches a profile from web_profile_info
+func (c *Client) fetchProfileInfo(username string) (*InstagramResponse, error) {
 	url := GetProfileURL(username)
 	
 	c.logger.DebugWithFields("fetching user profile", map[string]interface{}{
@@ -355,16 +521,18 @@ func (c *Client) FetchUserProfile(username string) (*InstagramResponse, error) {
 	})
 	
 	var response InstagramResponse
-	if err := c.GetJSON(url, &response); err != nil {
+	requestID, err := c.getJSON(url, &response)
+	if err != nil {
 		c.logger.ErrorWithFields("failed to fetch user profile", map[string]interface{}{
-			"username": username,
-			"error":    err.Error(),
+			"username":            username,
+			"error":               err.Error(),
+			logger.FieldRequestID: requestID,
 		})
 		return nil, err
 	}
 
 	// Check if login is required
-	if response.RequiresToLogin {
+	if response.LoginRequired() {
 		c.logger.WarnWithFields("authentication required for profile", map[string]interface{}{
 			"username": username,
 		})
@@ -379,11 +547,64 @@ func (c *Client) FetchUserProfile(username string) (*InstagramResponse, error) {
 		"username": username,
 	})
 
+	response.Source = SourceWebProfileInfo
+	response.RequestID = requestID
 	return &response, nil
 }
 
-// FetchUserMedia fetches paginated media for a user
+// FetchUserMedia fetches paginated media for a user from GraphQL. If GraphQL
+// answers with a 400, a body that cannot be parsed or an empty page, the
+// mobile API is tried instead, and the pages after one it served are fetched
+// from it too. The response's and each node's Source name the endpoint used.
+// With a cache set, a page fetched within its TTL is served from it.
 func (c *Client) FetchUserMedia(userID string, after string) (*InstagramResponse, error) {
+	key := GetMediaURL(userID, after)
+	if resp, ok := c.cached(key); ok {
+		if resp.Source == SourceGraphQL {
+			c.rememberPageEnd(userID, resp)
+		}
+		return resp, nil
+	}
+	resp, err := c.fetchMedia(userID, after)
+	if err == nil {
+		c.store(key, resp)
+	}
+	return resp, err
+}
+
+// fetchMedia fetches a page of posts, falling back to the mobile API
+func (c *Client) fetchMedia(userID string, after string) (*InstagramResponse, error) {
+	if maxID, ok := isFeedCursor(after); ok {
+		return c.fetchUserFeed(userID, maxID)
+	}
+
+	resp, err := c.fetchTimeline(userID, after)
+	if !fallBack(err, err == nil && emptyTimeline(resp)) {
+		return resp, err
+	}
+	maxID, ok := c.feedMaxID(userID, after)
+	if !ok {
+		return resp, err
+	}
+
+	c.logFallback("media", userID, SourceGraphQL, SourceMobileAPI, err)
+	alt, altErr := c.fetchUserFeed(userID, maxID)
+	if altErr != nil {
+		c.logger.WarnWithFields("alternative media endpoint failed", map[string]interface{}{
+			"user_id": userID,
+			"error":   altErr.Error(),
+		})
+		return resp, err
+	}
+	c.logger.InfoWithFields("fetched media from alternative endpoint", map[string]interface{}{
+		"user_id": userID,
+		"source":  alt.Source,
+	})
+	return alt, nil
+}
+
+// fetchTimeline fetches a page of a user's media from GraphQL
+func (c *Client) fetchTimeline(userID string, after string) (*InstagramResponse, error) {
 	url := GetMediaURL(userID, after)
 	
 	c.logger.DebugWithFields("fetching user media", map[string]interface{}{
@@ -393,11 +614,13 @@ func (c *Client) FetchUserMedia(userID string, after string) (*InstagramResponse
 	})
 	
 	var response InstagramResponse
-	if err := c.GetJSON(url, &response); err != nil {
+	requestID, err := c.getJSON(url, &response)
+	if err != nil {
 		c.logger.ErrorWithFields("failed to fetch user media", map[string]interface{}{
-			"user_id": userID,
-			"after":   after,
-			"error":   err.Error(),
+			"user_id":             userID,
+			"after":               after,
+			"error":               err.Error(),
+			logger.FieldRequestID: requestID,
 		})
 		return nil, err
 	}
@@ -406,13 +629,40 @@ func (c *Client) FetchUserMedia(userID string, after string) (*InstagramResponse
 		"user_id": userID,
 	})
 
+	response.Source = SourceGraphQL
+	response.RequestID = requestID
+	edges := response.Data.User.EdgeOwnerToTimelineMedia.Edges
+	for i := range edges {
+		edges[i].Node.Source = SourceGraphQL
+		edges[i].Node.RequestID = requestID
+	}
+	c.rememberPageEnd(userID, &response)
 	return &response, nil
 }
 
-// DownloadPhoto downloads a photo from the given URL with retry logic
+// SetBandwidth throttles photo and video downloads to the limiter's
+// bandwidth. API requests are not throttled. Clients sharing a limiter share
+// its bandwidth.
+func (c *Client) SetBandwidth(b *ratelimit.Bandwidth) {
+	c.bandwidth = b
+}
+
+// mediaBody returns the body of a photo or video response, throttled to the
+// bandwidth limit if there is one
+func (c *Client) mediaBody(resp *http.Response) io.Reader {
+	if c.bandwidth == nil {
+		return resp.Body
+	}
+	return c.bandwidth.Reader(resp.Body)
+}
+
+// DownloadPhoto downloads a photo from the given URL with retry logic. Its
+// attempts are logged under one request ID.
 func (c *Client) DownloadPhoto(photoURL string) ([]byte, error) {
+	requestID := logger.NewRequestID()
 	c.logger.DebugWithFields("downloading photo", map[string]interface{}{
-		"url": photoURL,
+		"url":                 photoURL,
+		logger.FieldRequestID: requestID,
 	})
 
 	// Use specific retry config for downloads if available
@@ -420,22 +670,11 @@ func (c *Client) DownloadPhoto(photoURL string) ([]byte, error) {
 	var downloadErr error
 	
 	if c.retryConfig != nil && c.retryConfig.Enabled {
-		// Create custom retry config for downloads
-		retryConfig := &retry.Config{
-			MaxAttempts: c.retryConfig.NetworkRetries,
-			Backoff: &retry.ExponentialBackoff{
-				BaseDelay:    c.retryConfig.NetworkBaseDelay,
-				MaxDelay:     c.retryConfig.MaxDelay,
-				Multiplier:   c.retryConfig.Multiplier,
-				JitterFactor: c.retryConfig.JitterFactor,
-			},
-			RetryIf: retry.DefaultRetryIf,
-			Context: context.Background(),
-			Logger:  c.logger,
-		}
+		// Downloads follow the photo download retry policy
+		retryConfig := c.retries.Config(retry.ClassPhotoDownload, context.Background(), c.logger)
 		
 		err := retry.Do(func() error {
-			resp, err := c.Get(photoURL)
+			resp, err := c.get(photoURL, requestID)
 			if err != nil {
 				downloadErr = err
 				return err
@@ -447,7 +686,7 @@ func (c *Client) DownloadPhoto(photoURL string) ([]byte, error) {
 				return err
 			}
 			
-			data, err = io.ReadAll(resp.Body)
+			data, err = io.ReadAll(c.mediaBody(resp))
 			if err != nil {
 				downloadErr = &errors.Error{
 					Type:    errors.ErrorTypeNetwork,
@@ -462,18 +701,20 @@ func (c *Client) DownloadPhoto(photoURL string) ([]byte, error) {
 		
 		if err != nil {
 			c.logger.ErrorWithFields("failed to download photo after retries", map[string]interface{}{
-				"url":   photoURL,
-				"error": err.Error(),
+				"url":                 photoURL,
+				"error":               err.Error(),
+				logger.FieldRequestID: requestID,
 			})
 			return nil, err
 		}
 	} else {
 		// No retry, just download once
-		resp, err := c.Get(photoURL)
+		resp, err := c.get(photoURL, requestID)
 		if err != nil {
 			c.logger.ErrorWithFields("failed to download photo", map[string]interface{}{
-				"url":   photoURL,
-				"error": err.Error(),
+				"url":                 photoURL,
+				"error":               err.Error(),
+				logger.FieldRequestID: requestID,
 			})
 			return nil, err
 		}
@@ -483,11 +724,12 @@ func (c *Client) DownloadPhoto(photoURL string) ([]byte, error) {
 			return nil, err
 		}
 		
-		data, err = io.ReadAll(resp.Body)
+		data, err = io.ReadAll(c.mediaBody(resp))
 		if err != nil {
 			c.logger.ErrorWithFields("failed to read photo data", map[string]interface{}{
-				"url":   photoURL,
-				"error": err.Error(),
+				"url":                 photoURL,
+				"error":               err.Error(),
+				logger.FieldRequestID: requestID,
 			})
 			return nil, &errors.Error{
 				Type:    errors.ErrorTypeNetwork,
@@ -498,8 +740,9 @@ func (c *Client) DownloadPhoto(photoURL string) ([]byte, error) {
 	}
 
 	c.logger.DebugWithFields("successfully downloaded photo", map[string]interface{}{
-		"url":  photoURL,
-		"size": len(data),
+		"url":                 photoURL,
+		"size":                len(data),
+		logger.FieldRequestID: requestID,
 	})
 
 	return data, nil
@@ -11,6 +11,7 @@ import (
 
 	"igscraper/pkg/config"
 	"igscraper/pkg/errors"
+	"igscraper/pkg/instagram/cache"
 	"igscraper/pkg/logger"
 
 	"github.com/stretchr/testify/assert"
@@ -23,7 +24,11 @@ type mockRoundTripper struct {
 }
 
 func (m *mockRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
-	return m.handler(req)
+	resp, err := m.handler(req)
+	if resp != nil && resp.Request == nil {
+		resp.Request = req // as real transports do
+	}
+	return resp, err
 }
 
 // Helper function to create a mock HTTP client
@@ -250,6 +255,41 @@ func TestCheckResponseStatus(t *testing.T) {
 	}
 }
 
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 3, 15, 18, 30, 0, 0, time.UTC)
+	assert.Equal(t, 120*time.Second, parseRetryAfter("120", now))
+	assert.Equal(t, 90*time.Second, parseRetryAfter(now.Add(90*time.Second).Format(http.TimeFormat), now))
+	for _, value := range []string{"", "0", "-5", "soon", now.Add(-time.Minute).Format(http.TimeFormat)} {
+		assert.Zero(t, parseRetryAfter(value, now), value)
+	}
+	
+	client := NewClient(30*time.Second, logger.NewTestLogger())
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	err := client.checkResponseStatus(&http.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Header:     http.Header{"Retry-After": []string{"45"}},
+		Request:    req,
+	})
+	assert.Equal(t, 45*time.Second, errors.RetryAfter(err))
+	
+	// The retried request keeps the header of its last response
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+	
+	retryClient := NewClientWithConfig(30*time.Second, &config.RetryConfig{Enabled: true, MaxAttempts: 2}, logger.NewTestLogger())
+	start := time.Now()
+	_, err = retryClient.Get(server.URL)
+	require.Error(t, err)
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, time.Second, errors.RetryAfter(err))
+	assert.GreaterOrEqual(t, time.Since(start), time.Second, "the retry waits as long as the server asked")
+}
+
 func TestGet(t *testing.T) {
 	log := logger.NewTestLogger()
 	client := NewClient(30*time.Second, log)
@@ -392,6 +432,23 @@ func TestFetchUserProfile(t *testing.T) {
 		assert.ErrorAs(t, err, &igErr)
 		assert.Equal(t, errors.ErrorTypeAuth, igErr.Type)
 	})
+	
+	t.Run("private profile followed by session", func(t *testing.T) {
+		response := &InstagramResponse{
+			RequiresToLogin: true,
+			Data: Data{
+				User: User{ID: "123456", IsPrivate: true, FollowedByViewer: true},
+			},
+		}
+		
+		client := newTestClient(log, map[string]interface{}{
+			GetProfileURL("followeduser"): response,
+		})
+		
+		result, err := client.FetchUserProfile("followeduser")
+		require.NoError(t, err)
+		assert.Equal(t, "123456", result.Data.User.ID)
+	})
 }
 
 func TestFetchUserMedia(t *testing.T) {
@@ -448,6 +505,288 @@ func TestFetchUserMedia(t *testing.T) {
 	})
 }
 
+func TestEndpointFallback(t *testing.T) {
+	log := logger.NewTestLogger()
+	
+	feedItem := func(id, code string) FeedItem {
+		return FeedItem{
+			ID:             id,
+			Code:           code,
+			MediaType:      1,
+			ImageVersions2: ImageVersions{Candidates: []ImageCandidate{{URL: "https://example.com/" + code + ".jpg"}}},
+		}
+	}
+	
+	t.Run("profile falls back on 400", func(t *testing.T) {
+		page := &ProfilePageResponse{GraphQL: Data{User: User{ID: "123456", Username: "testuser"}}}
+		client := newTestClient(log, map[string]interface{}{
+			GetProfileURL("testuser"):     http.StatusBadRequest,
+			GetProfilePageURL("testuser"): page,
+		})
+		
+		result, err := client.FetchUserProfile("testuser")
+		require.NoError(t, err)
+		assert.Equal(t, "123456", result.Data.User.ID)
+		assert.Equal(t, SourceGraphQL, result.Source)
+	})
+	
+	t.Run("profile does not fall back when not found", func(t *testing.T) {
+		page := &ProfilePageResponse{GraphQL: Data{User: User{ID: "123456"}}}
+		client := newTestClient(log, map[string]interface{}{
+			GetProfilePageURL("missing"): page,
+		})
+		
+		_, err := client.FetchUserProfile("missing")
+		var igErr *errors.Error
+		require.ErrorAs(t, err, &igErr)
+		assert.Equal(t, errors.ErrorTypeNotFound, igErr.Type)
+	})
+	
+	t.Run("original error when both fail", func(t *testing.T) {
+		client := newTestClient(log, map[string]interface{}{
+			GetProfileURL("testuser"):     http.StatusBadRequest,
+			GetProfilePageURL("testuser"): "<html>",
+		})
+		
+		_, err := client.FetchUserProfile("testuser")
+		var igErr *errors.Error
+		require.ErrorAs(t, err, &igErr)
+		assert.Equal(t, http.StatusBadRequest, igErr.Code)
+	})
+	
+	t.Run("media falls back to the mobile API", func(t *testing.T) {
+		first := &FeedResponse{
+			Items:         []FeedItem{feedItem("1_123", "AAA")},
+			MoreAvailable: true,
+			NextMaxID:     "1_123",
+		}
+		second := &FeedResponse{Items: []FeedItem{feedItem("2_123", "BBB")}}
+		client := newTestClient(log, map[string]interface{}{
+			GetMediaURL("123", ""):         "<html>",
+			GetUserFeedURL("123", ""):      first,
+			GetUserFeedURL("123", "1_123"): second,
+		})
+		
+		result, err := client.FetchUserMedia("123", "")
+		require.NoError(t, err)
+		media := result.Data.User.EdgeOwnerToTimelineMedia
+		assert.Equal(t, SourceMobileAPI, result.Source)
+		require.Len(t, media.Edges, 1)
+		assert.Equal(t, "AAA", media.Edges[0].Node.Shortcode)
+		assert.Equal(t, SourceMobileAPI, media.Edges[0].Node.Source)
+		assert.True(t, media.PageInfo.HasNextPage)
+		
+		// The next page comes from the mobile API without trying GraphQL
+		result, err = client.FetchUserMedia("123", media.PageInfo.EndCursor)
+		require.NoError(t, err)
+		media = result.Data.User.EdgeOwnerToTimelineMedia
+		require.Len(t, media.Edges, 1)
+		assert.Equal(t, "BBB", media.Edges[0].Node.Shortcode)
+		assert.False(t, media.PageInfo.HasNextPage)
+	})
+	
+	t.Run("media falls back mid-timeline", func(t *testing.T) {
+		timeline := &InstagramResponse{Data: Data{User: User{
+			EdgeOwnerToTimelineMedia: EdgeOwnerToTimelineMedia{
+				Count:    2,
+				PageInfo: PageInfo{HasNextPage: true, EndCursor: "cursor1"},
+				Edges:    []Edge{{Node: Node{ID: "1", Shortcode: "AAA"}}},
+			},
+		}}}
+		client := newTestClient(log, map[string]interface{}{
+			GetMediaURL("123", ""):         timeline,
+			GetMediaURL("123", "cursor1"):  http.StatusBadRequest,
+			GetUserFeedURL("123", "1_123"): &FeedResponse{Items: []FeedItem{feedItem("2_123", "BBB")}},
+		})
+		
+		result, err := client.FetchUserMedia("123", "")
+		require.NoError(t, err)
+		assert.Equal(t, SourceGraphQL, result.Source)
+		assert.Equal(t, SourceGraphQL, result.Data.User.EdgeOwnerToTimelineMedia.Edges[0].Node.Source)
+		
+		result, err = client.FetchUserMedia("123", "cursor1")
+		require.NoError(t, err)
+		assert.Equal(t, SourceMobileAPI, result.Source)
+		assert.Equal(t, "BBB", result.Data.User.EdgeOwnerToTimelineMedia.Edges[0].Node.Shortcode)
+		
+		// A cursor the client did not hand out cannot be translated
+		_, err = client.FetchUserMedia("123", "unknown")
+		assert.Error(t, err)
+	})
+}
+
+func TestResponseCache(t *testing.T) {
+	log := logger.NewTestLogger()
+	responses, err := cache.New(t.TempDir(), time.Hour)
+	require.NoError(t, err)
+	
+	requests := make(map[string]int)
+	client := NewClient(30*time.Second, log)
+	client.httpClient = newMockHTTPClient(func(req *http.Request) (*http.Response, error) {
+		requests[req.URL.String()]++
+		switch req.URL.String() {
+		case GetProfileURL("testuser"):
+			return newResponse(http.StatusOK, `{"data":{"user":{"id":"123","username":"testuser"}}}`), nil
+		case GetProfileURL("nobody"):
+			return newResponse(http.StatusOK, `{"data":{"user":{}}}`), nil
+		case GetMediaURL("123", "cursor1"):
+			return newResponse(http.StatusOK, `{"data":{"user":{"edge_owner_to_timeline_media":{"count":1,"edges":[{"node":{"id":"1","shortcode":"AAA"}}]}}}}`), nil
+		}
+		return newResponse(http.StatusNotFound, ""), nil
+	})
+	client.SetCache(responses)
+	
+	for i := 0; i < 2; i++ {
+		profile, err := client.FetchUserProfile("testuser")
+		require.NoError(t, err)
+		assert.Equal(t, "123", profile.Data.User.ID)
+		assert.Equal(t, SourceWebProfileInfo, profile.Source)
+		
+		media, err := client.FetchUserMedia("123", "cursor1")
+		require.NoError(t, err)
+		require.Len(t, media.Data.User.EdgeOwnerToTimelineMedia.Edges, 1)
+		assert.Equal(t, SourceGraphQL, media.Data.User.EdgeOwnerToTimelineMedia.Edges[0].Node.Source)
+		
+		_, err = client.FetchUserProfile("missing")
+		assert.Error(t, err)
+		_, err = client.FetchUserProfile("nobody")
+		assert.NoError(t, err)
+	}
+	
+	assert.Equal(t, 1, requests[GetProfileURL("testuser")])
+	assert.Equal(t, 1, requests[GetMediaURL("123", "cursor1")])
+	assert.Equal(t, 2, requests[GetProfileURL("missing")], "errors are not cached")
+	assert.Equal(t, 2, requests[GetProfileURL("nobody")], "empty profiles are not cached")
+}
+
+func TestChallengeDetection(t *testing.T) {
+	log := logger.NewTestLogger()
+	
+	t.Run("checkpoint required", func(t *testing.T) {
+		requests := 0
+		client := NewClient(30*time.Second, log)
+		client.httpClient = newMockHTTPClient(func(req *http.Request) (*http.Response, error) {
+			requests++
+			return newResponse(http.StatusBadRequest, `{"message":"checkpoint_required","checkpoint_url":"/challenge/123/abc/","lock":false,"status":"fail"}`), nil
+		})
+		
+		_, err := client.FetchUserProfile("testuser")
+		var igErr *errors.Error
+		require.ErrorAs(t, err, &igErr)
+		assert.Equal(t, errors.ErrorTypeChallenge, igErr.Type)
+		assert.Equal(t, "https://www.instagram.com/challenge/123/abc/", igErr.ChallengeURL)
+		assert.True(t, errors.IsChallenge(err))
+		assert.Equal(t, 1, requests, "challenges are neither retried nor repeated on another endpoint")
+	})
+	
+	t.Run("challenge required with retries", func(t *testing.T) {
+		client := NewClientWithConfig(30*time.Second, &config.RetryConfig{Enabled: true, MaxAttempts: 3}, log)
+		client.httpClient = newMockHTTPClient(func(req *http.Request) (*http.Response, error) {
+			return newResponse(http.StatusForbidden, `{"message":"challenge_required","challenge":{"url":"https://www.instagram.com/challenge/?next=/","api_path":"/challenge/"},"status":"fail"}`), nil
+		})
+		
+		_, err := client.FetchUserMedia("123", "")
+		var igErr *errors.Error
+		require.ErrorAs(t, err, &igErr)
+		assert.Equal(t, errors.ErrorTypeChallenge, igErr.Type)
+		assert.Equal(t, http.StatusForbidden, igErr.Code)
+		assert.Equal(t, "https://www.instagram.com/challenge/?next=/", igErr.ChallengeURL)
+	})
+	
+	t.Run("redirect to challenge page", func(t *testing.T) {
+		client := NewClient(30*time.Second, log)
+		client.httpClient = newMockHTTPClient(func(req *http.Request) (*http.Response, error) {
+			resp := newResponse(http.StatusOK, "<html>security check</html>")
+			resp.Request, _ = http.NewRequest(http.MethodGet, "https://www.instagram.com/challenge/?next=/api/v1/", nil)
+			return resp, nil
+		})
+		
+		var target InstagramResponse
+		err := client.GetJSON(GetProfileURL("testuser"), &target)
+		assert.True(t, errors.IsChallenge(err))
+	})
+	
+	t.Run("other errors are unchanged", func(t *testing.T) {
+		client := newTestClient(log, map[string]interface{}{
+			GetMediaURL("123", ""): &InstagramResponse{Data: Data{User: User{EdgeOwnerToTimelineMedia: EdgeOwnerToTimelineMedia{
+				Count: 1,
+				Edges: []Edge{{Node: Node{ID: "1", EdgeMediaToCaption: EdgeMediaToCaption{Edges: []CaptionEdge{{Node: CaptionNode{Text: "checkpoint_required"}}}}}}},
+			}}}},
+		})
+		
+		_, err := client.FetchUserMedia("123", "")
+		assert.NoError(t, err, "a caption mentioning a challenge is not one")
+		_, err = client.FetchUserProfile("missing")
+		assert.False(t, errors.IsChallenge(err))
+	})
+}
+
+func TestErrorPayload(t *testing.T) {
+	log := logger.NewTestLogger()
+	
+	t.Run("please wait is a rate limit", func(t *testing.T) {
+		client := NewClient(30*time.Second, log)
+		client.httpClient = newMockHTTPClient(func(req *http.Request) (*http.Response, error) {
+			return newResponse(http.StatusBadRequest, `{"message":"Please wait a few minutes before you try again.","status":"fail"}`), nil
+		})
+		
+		var target InstagramResponse
+		err := client.GetJSON(GetProfileURL("testuser"), &target)
+		var igErr *errors.Error
+		require.ErrorAs(t, err, &igErr)
+		assert.Equal(t, errors.ErrorTypeRateLimit, igErr.Type)
+		require.NotNil(t, igErr.Payload)
+		assert.Equal(t, "fail", igErr.Payload.Status)
+		assert.Contains(t, err.Error(), "Please wait a few minutes")
+	})
+	
+	t.Run("spam is not retried", func(t *testing.T) {
+		requests := 0
+		client := NewClientWithConfig(30*time.Second, &config.RetryConfig{Enabled: true, MaxAttempts: 3}, log)
+		client.httpClient = newMockHTTPClient(func(req *http.Request) (*http.Response, error) {
+			requests++
+			return newResponse(http.StatusTooManyRequests, `{"message":"feedback_required","spam":true,"feedback_title":"Try Again Later","feedback_message":"We limit how often you can do certain things.","status":"fail"}`), nil
+		})
+		
+		var target InstagramResponse
+		err := client.GetJSON(GetMediaURL("123", ""), &target)
+		var igErr *errors.Error
+		require.ErrorAs(t, err, &igErr)
+		assert.Equal(t, errors.ErrorTypeRateLimit, igErr.Type)
+		assert.True(t, igErr.Payload.Spam)
+		assert.False(t, igErr.Retryable())
+		assert.Contains(t, err.Error(), "Try Again Later: We limit how often")
+		assert.Equal(t, 1, requests)
+	})
+	
+	t.Run("login required", func(t *testing.T) {
+		client := NewClient(30*time.Second, log)
+		client.httpClient = newMockHTTPClient(func(req *http.Request) (*http.Response, error) {
+			return newResponse(http.StatusBadRequest, `{"message":"login_required","status":"fail"}`), nil
+		})
+		
+		var target InstagramResponse
+		err := client.GetJSON(GetProfileURL("testuser"), &target)
+		var igErr *errors.Error
+		require.ErrorAs(t, err, &igErr)
+		assert.Equal(t, errors.ErrorTypeAuth, igErr.Type)
+	})
+	
+	t.Run("failed status with 200", func(t *testing.T) {
+		client := NewClient(30*time.Second, log)
+		client.httpClient = newMockHTTPClient(func(req *http.Request) (*http.Response, error) {
+			return newResponse(http.StatusOK, `{"message":"Sorry, something went wrong","status":"fail"}`), nil
+		})
+		
+		var target InstagramResponse
+		err := client.GetJSON(GetProfileURL("testuser"), &target)
+		var igErr *errors.Error
+		require.ErrorAs(t, err, &igErr)
+		require.NotNil(t, igErr.Payload)
+		assert.Equal(t, "Sorry, something went wrong", igErr.Payload.Message)
+	})
+}
+
 func TestDownloadPhoto(t *testing.T) {
 	log := logger.NewTestLogger()
 	client := NewClient(30*time.Second, log)
@@ -573,6 +912,51 @@ func TestDoRequestWithRetry(t *testing.T) {
 	})
 }
 
+func TestRequestID(t *testing.T) {
+	log := logger.NewTestLogger()
+	page := &InstagramResponse{
+		Status: "ok",
+		Data: Data{User: User{EdgeOwnerToTimelineMedia: EdgeOwnerToTimelineMedia{
+			Edges: []Edge{{Node: Node{ID: "media1", Shortcode: "ABC123"}}},
+		}}},
+	}
+	body, err := json.Marshal(page)
+	require.NoError(t, err)
+	
+	attempts := 0
+	client := NewClientWithConfig(30*time.Second, &config.RetryConfig{
+		Enabled:     true,
+		MaxAttempts: 3,
+		BaseDelay:   10 * time.Millisecond,
+	}, log)
+	client.httpClient = newMockHTTPClient(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		if attempts == 1 {
+			return newResponse(http.StatusInternalServerError, ""), nil
+		}
+		return newResponse(http.StatusOK, string(body)), nil
+	})
+	
+	result, err := client.FetchUserMedia("123456", "")
+	require.NoError(t, err)
+	require.NotEmpty(t, result.RequestID)
+	assert.Equal(t, result.RequestID, result.Data.User.EdgeOwnerToTimelineMedia.Edges[0].Node.RequestID)
+	
+	// Both attempts are logged under the ID the response carries
+	var sent []interface{}
+	for _, msg := range log.GetMessages() {
+		if msg.Message == "sending HTTP request" {
+			sent = append(sent, msg.Fields[logger.FieldRequestID])
+		}
+	}
+	assert.Equal(t, []interface{}{result.RequestID, result.RequestID}, sent)
+	
+	// Another call gets an ID of its own
+	second, err := client.FetchUserMedia("123456", "")
+	require.NoError(t, err)
+	assert.NotEqual(t, result.RequestID, second.RequestID)
+}
+
 func TestDownloadPhotoWithRetry(t *testing.T) {
 	log := logger.NewTestLogger()
 	
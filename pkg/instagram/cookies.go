@@ -0,0 +1,116 @@
+package instagram
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"sync"
+)
+
+// Names of the cookies that authenticate a session
+const (
+	CookieSessionID = "sessionid"
+	CookieCSRFToken = "csrftoken"
+)
+
+// cookieDomain is the domain session cookies are set for, so the web and
+// mobile API hosts both receive them
+const cookieDomain = "instagram.com"
+
+// cookieJar holds a client's instagram.com cookies. Cookies set by responses
+// replace the ones sent, and a change of sessionid or csrftoken is reported
+// to the client's session hook. It is safe for concurrent use.
+type cookieJar struct {
+	mu        sync.RWMutex
+	jar       *cookiejar.Jar
+	onRefresh func(cookies map[string]string)
+}
+
+// instagramURL is where the session cookies are looked up
+var instagramURL, _ = url.Parse(BaseURL)
+
+// newCookieJar returns an empty jar
+func newCookieJar() *cookieJar {
+	jar, _ := cookiejar.New(nil) // only fails for a bad public suffix list
+	return &cookieJar{jar: jar}
+}
+
+// Cookies returns the cookies to send to u
+func (j *cookieJar) Cookies(u *url.URL) []*http.Cookie {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return j.jar.Cookies(u)
+}
+
+// SetCookies stores the cookies a response from u set and reports new
+// session cookies
+func (j *cookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.mu.Lock()
+	before := cookieValues(j.jar.Cookies(instagramURL))
+	j.jar.SetCookies(u, cookies)
+	after := cookieValues(j.jar.Cookies(instagramURL))
+	onRefresh := j.onRefresh
+	j.mu.Unlock()
+
+	renewed := after[CookieSessionID] != before[CookieSessionID] || after[CookieCSRFToken] != before[CookieCSRFToken]
+	if onRefresh != nil && renewed && after[CookieSessionID] != "" && after[CookieCSRFToken] != "" {
+		onRefresh(after)
+	}
+}
+
+// reset replaces every cookie with cookies, a map of name to value
+func (j *cookieJar) reset(cookies map[string]string) {
+	jar, _ := cookiejar.New(nil)
+	list := make([]*http.Cookie, 0, len(cookies))
+	for name, value := range cookies {
+		list = append(list, &http.Cookie{Name: name, Value: value, Domain: cookieDomain, Path: "/"})
+	}
+	jar.SetCookies(instagramURL, list)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.jar = jar
+}
+
+// cookieValues maps the names of cookies to their values
+func cookieValues(cookies []*http.Cookie) map[string]string {
+	values := make(map[string]string, len(cookies))
+	for _, cookie := range cookies {
+		values[cookie.Name] = cookie.Value
+	}
+	return values
+}
+
+// SetCookies replaces the cookies the client sends to Instagram with
+// cookies, a map of name to value such as sessionid and csrftoken. The
+// csrftoken is also sent as the X-CSRFToken header. Requests already being
+// sent keep the cookies they started with.
+func (c *Client) SetCookies(cookies map[string]string) {
+	c.cookies.reset(cookies)
+}
+
+// Cookies returns the cookies the client sends to Instagram, including the
+// ones Instagram has set since SetCookies
+func (c *Client) Cookies() map[string]string {
+	return cookieValues(c.cookies.Cookies(instagramURL))
+}
+
+// OnSessionRefresh sets fn to be called with the client's cookies whenever
+// Instagram renews the sessionid or csrftoken. fn may be called from several
+// requests at once.
+func (c *Client) OnSessionRefresh(fn func(cookies map[string]string)) {
+	c.cookies.mu.Lock()
+	defer c.cookies.mu.Unlock()
+	c.cookies.onRefresh = fn
+}
+
+// csrfToken returns the csrftoken cookie sent with req, or "" when there is
+// none
+func (c *Client) csrfToken(req *http.Request) string {
+	for _, cookie := range c.cookies.Cookies(req.URL) {
+		if cookie.Name == CookieCSRFToken {
+			return cookie.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,73 @@
+package instagram
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"igscraper/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCookieJar(t *testing.T) {
+	var mu sync.Mutex
+	var cookies, tokens []string
+	client := NewClient(30*time.Second, logger.NewTestLogger())
+	client.SetTransport(&mockRoundTripper{handler: func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		cookies = append(cookies, req.Header.Get("Cookie"))
+		tokens = append(tokens, req.Header.Get("X-CSRFToken"))
+
+		resp := newResponse(http.StatusOK, `{}`)
+		if len(cookies) == 1 {
+			// Instagram rotates the csrftoken on the first response
+			resp.Header.Add("Set-Cookie", "csrftoken=csrf-2; Domain=.instagram.com; Path=/; Secure")
+		}
+		return resp, nil
+	}})
+	client.SetCookies(map[string]string{
+		CookieSessionID: "session-1",
+		CookieCSRFToken: "csrf-1",
+		"mid":           "machine",
+	})
+
+	var refreshed []map[string]string
+	client.OnSessionRefresh(func(cookies map[string]string) {
+		refreshed = append(refreshed, cookies)
+	})
+
+	var target map[string]interface{}
+	require.NoError(t, client.GetJSON(BaseURL+"/api/v1/first/", &target))
+	require.NoError(t, client.GetJSON("https://i.instagram.com/api/v1/second/", &target))
+
+	// The renewed token is sent as cookie and header from then on, to the
+	// mobile API host too
+	require.Len(t, cookies, 2)
+	assert.Contains(t, cookies[0], "sessionid=session-1")
+	assert.Contains(t, cookies[0], "csrftoken=csrf-1")
+	assert.Contains(t, cookies[0], "mid=machine")
+	assert.Equal(t, "csrf-1", tokens[0])
+	assert.Contains(t, cookies[1], "sessionid=session-1")
+	assert.Contains(t, cookies[1], "csrftoken=csrf-2")
+	assert.Equal(t, "csrf-2", tokens[1])
+
+	require.Len(t, refreshed, 1)
+	assert.Equal(t, "session-1", refreshed[0][CookieSessionID])
+	assert.Equal(t, "csrf-2", refreshed[0][CookieCSRFToken])
+	assert.Equal(t, "csrf-2", client.Cookies()[CookieCSRFToken])
+
+	// Photos come from the CDN, which gets no session
+	_, err := client.DownloadPhoto("https://scontent.cdninstagram.com/photo.jpg")
+	require.NoError(t, err)
+	assert.Empty(t, cookies[2])
+	assert.Empty(t, tokens[2])
+
+	// Setting cookies replaces all of them without reporting a refresh
+	client.SetCookies(map[string]string{CookieSessionID: "session-b", CookieCSRFToken: "csrf-b"})
+	assert.Equal(t, map[string]string{CookieSessionID: "session-b", CookieCSRFToken: "csrf-b"}, client.Cookies())
+	assert.Len(t, refreshed, 1)
+}
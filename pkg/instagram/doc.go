@@ -5,6 +5,8 @@
 //   - Type-safe models for Instagram API responses
 //   - Helper functions for constructing API endpoints
 //   - Built-in error types for better error handling
+//   - Fallback between endpoint families (web_profile_info, GraphQL and the
+//     mobile API) when one answers with a 400 or no data
 //
 // Example usage:
 //
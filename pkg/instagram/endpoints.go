@@ -2,7 +2,9 @@ package instagram
 
 import (
 	"fmt"
+	"net/http"
 	"net/url"
+	"strings"
 )
 
 const (
@@ -23,6 +25,46 @@ const (
 
 	// MaxMediaLimit is the maximum number of media items that can be fetched per request
 	MaxMediaLimit = 50
+
+	// LikedFeedEndpoint is the endpoint for posts liked by the authenticated account
+	LikedFeedEndpoint = "/api/v1/feed/liked/"
+
+	// SavedFeedEndpoint is the endpoint for all posts saved by the authenticated account
+	SavedFeedEndpoint = "/api/v1/feed/saved/posts/"
+
+	// CollectionsEndpoint is the endpoint listing the authenticated account's collections
+	CollectionsEndpoint = "/api/v1/collections/list/"
+
+	// CollectionFeedEndpoint is the endpoint pattern for the posts in one collection
+	CollectionFeedEndpoint = "/api/v1/feed/collection/%s/posts/"
+
+	// MediaInfoEndpoint is the endpoint pattern for a single post, by media ID
+	MediaInfoEndpoint = "/api/v1/media/%s/info/"
+
+	// ClipsEndpoint is the endpoint for a profile's reels
+	ClipsEndpoint = "/api/v1/clips/user/"
+
+	// ClipsPageSize is the number of reels requested per page
+	ClipsPageSize = 12
+
+	// TagFeedEndpoint is the endpoint for a hashtag's posts
+	TagFeedEndpoint = "/api/v1/feed/tag/%s/"
+
+	// LocationFeedEndpoint is the endpoint for the posts geotagged at a location
+	LocationFeedEndpoint = "/api/v1/feed/location/%s/"
+
+	// CommentsEndpoint is the endpoint pattern for a post's comments, by media ID
+	CommentsEndpoint = "/api/v1/media/%s/comments/"
+
+	// ProfilePageEndpoint is the endpoint pattern for a profile page, served
+	// as GraphQL JSON with the __a parameter
+	ProfilePageEndpoint = "/%s/"
+
+	// UserFeedEndpoint is the mobile API endpoint pattern for a profile's posts
+	UserFeedEndpoint = "/api/v1/feed/user/%s/"
+
+	// CurrentUserEndpoint is the endpoint describing the authenticated account
+	CurrentUserEndpoint = "/api/v1/accounts/current_user/"
 )
 
 // GetProfileURL constructs the URL for fetching a user's profile
@@ -63,6 +105,116 @@ func GetMediaURLWithLimit(userID string, after string, limit int) string {
 	return fmt.Sprintf("%s%s?%s", BaseURL, MediaEndpoint, params.Encode())
 }
 
+// GetLikedFeedURL constructs the URL for a page of the authenticated account's
+// liked posts. maxID is the next_max_id of the previous page, empty for the first.
+func GetLikedFeedURL(maxID string) string {
+	return withMaxID(BaseURL+LikedFeedEndpoint, maxID)
+}
+
+// GetSavedFeedURL constructs the URL for a page of all saved posts
+func GetSavedFeedURL(maxID string) string {
+	return withMaxID(BaseURL+SavedFeedEndpoint, maxID)
+}
+
+// GetCollectionsURL constructs the URL for a page of the account's own
+// collections. The automatic "All posts" collection is not included.
+func GetCollectionsURL(maxID string) string {
+	params := url.Values{}
+	params.Set("collection_types", `["MEDIA"]`)
+	if maxID != "" {
+		params.Set("max_id", maxID)
+	}
+	
+	return fmt.Sprintf("%s%s?%s", BaseURL, CollectionsEndpoint, params.Encode())
+}
+
+// GetCollectionFeedURL constructs the URL for a page of a collection's posts
+func GetCollectionFeedURL(collectionID, maxID string) string {
+	return withMaxID(BaseURL+fmt.Sprintf(CollectionFeedEndpoint, url.PathEscape(collectionID)), maxID)
+}
+
+// GetClipsURL constructs the URL for a page of a profile's reels. maxID is
+// the max_id of the previous page's paging info, empty for the first.
+func GetClipsURL(userID, maxID string) string {
+	params := url.Values{}
+	params.Set("target_user_id", userID)
+	params.Set("page_size", fmt.Sprintf("%d", ClipsPageSize))
+	if maxID != "" {
+		params.Set("max_id", maxID)
+	}
+	
+	return fmt.Sprintf("%s%s?%s", BaseURL, ClipsEndpoint, params.Encode())
+}
+
+// GetTagFeedURL constructs the URL for a page of a hashtag's posts. tag is
+// given without the leading '#'.
+func GetTagFeedURL(tag, maxID string) string {
+	return withMaxID(BaseURL+fmt.Sprintf(TagFeedEndpoint, url.PathEscape(tag)), maxID)
+}
+
+// GetLocationFeedURL constructs the URL for a page of the posts geotagged at
+// a location
+func GetLocationFeedURL(locationID, maxID string) string {
+	return withMaxID(BaseURL+fmt.Sprintf(LocationFeedEndpoint, url.PathEscape(locationID)), maxID)
+}
+
+// GetMediaInfoURL constructs the URL for a single post's details
+func GetMediaInfoURL(mediaID string) string {
+	return BaseURL + fmt.Sprintf(MediaInfoEndpoint, url.PathEscape(mediaID))
+}
+
+// GetCommentsURL constructs the URL for a page of a post's comments. minID is
+// the cursor returned with the previous page.
+func GetCommentsURL(mediaID, minID string) string {
+	params := url.Values{}
+	params.Set("can_support_threading", "true")
+	if minID != "" {
+		params.Set("min_id", minID)
+	}
+	
+	return fmt.Sprintf("%s%s?%s", BaseURL, fmt.Sprintf(CommentsEndpoint, url.PathEscape(mediaID)), params.Encode())
+}
+
+// GetProfilePageURL constructs the URL for a profile page's GraphQL JSON, the
+// alternative to GetProfileURL
+func GetProfilePageURL(username string) string {
+	params := url.Values{}
+	params.Set("__a", "1")
+	params.Set("__d", "dis")
+	
+	return fmt.Sprintf("%s%s?%s", BaseURL, fmt.Sprintf(ProfilePageEndpoint, url.PathEscape(username)), params.Encode())
+}
+
+// GetUserFeedURL constructs the mobile API URL for a page of a profile's
+// posts, the alternative to GetMediaURL. maxID is the next_max_id of the
+// previous page, empty for the first.
+func GetUserFeedURL(userID, maxID string) string {
+	params := url.Values{}
+	params.Set("count", fmt.Sprintf("%d", DefaultMediaLimit))
+	if maxID != "" {
+		params.Set("max_id", maxID)
+	}
+	
+	return fmt.Sprintf("%s%s?%s", BaseURL, fmt.Sprintf(UserFeedEndpoint, url.PathEscape(userID)), params.Encode())
+}
+
+// GetCurrentUserURL constructs the URL describing the authenticated account
+func GetCurrentUserURL() string {
+	return BaseURL + CurrentUserEndpoint
+}
+
+// withMaxID adds the max_id pagination parameter to a feed URL
+func withMaxID(feedURL, maxID string) string {
+	if maxID == "" {
+		return feedURL
+	}
+	
+	params := url.Values{}
+	params.Set("max_id", maxID)
+	
+	return fmt.Sprintf("%s?%s", feedURL, params.Encode())
+}
+
 // GetPhotoURL returns the direct URL for a photo
 // This is typically the display_url from the Node struct
 func GetPhotoURL(node *Node) string {
@@ -124,4 +276,46 @@ func SanitizeUsername(username string) string {
 	}
 
 	return username
-}
\ No newline at end of file
+}
+
+// EndpointCategory names the kind of request, for the audit log: which API
+// endpoint it calls, or "media" for photo downloads from the CDN
+func EndpointCategory(req *http.Request) string {
+	host := req.URL.Hostname()
+	if strings.HasSuffix(host, ".cdninstagram.com") || strings.HasSuffix(host, ".fbcdn.net") {
+		return "media"
+	}
+
+	path := req.URL.Path
+	switch {
+	case path == ProfileEndpoint, req.URL.Query().Get("__a") == "1":
+		return "profile"
+	case path == MediaEndpoint:
+		return "timeline"
+	case path == LikedFeedEndpoint:
+		return "liked_feed"
+	case path == SavedFeedEndpoint:
+		return "saved_feed"
+	case path == CollectionsEndpoint:
+		return "collections"
+	case strings.HasPrefix(path, "/api/v1/feed/collection/"):
+		return "collection_feed"
+	case path == ClipsEndpoint:
+		return "reels"
+	case strings.HasPrefix(path, "/api/v1/feed/tag/"):
+		return "hashtag"
+	case strings.HasPrefix(path, "/api/v1/feed/location/"):
+		return "location"
+	case strings.HasPrefix(path, "/api/v1/media/") && strings.HasSuffix(path, "/info/"):
+		return "post"
+	case strings.HasPrefix(path, "/api/v1/media/") && strings.HasSuffix(path, "/comments/"):
+		return "comments"
+	case strings.HasPrefix(path, "/api/v1/feed/user/"):
+		return "user_feed"
+	case path == CurrentUserEndpoint:
+		return "current_user"
+	case strings.HasPrefix(path, "/accounts/login"):
+		return "login_redirect"
+	}
+	return "other"
+}
@@ -2,6 +2,7 @@ package instagram
 
 import (
 	"fmt"
+	"net/http"
 	"net/url"
 	"testing"
 
@@ -139,6 +140,90 @@ func TestGetMediaURLWithLimit(t *testing.T) {
 	}
 }
 
+func TestGetLikedFeedURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxID    string
+		expected string
+	}{
+		{
+			name:     "first page",
+			maxID:    "",
+			expected: BaseURL + LikedFeedEndpoint,
+		},
+		{
+			name:     "next page",
+			maxID:    "3141592653_42",
+			expected: BaseURL + LikedFeedEndpoint + "?max_id=3141592653_42",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, GetLikedFeedURL(tt.maxID))
+		})
+	}
+}
+
+func TestSavedFeedURLs(t *testing.T) {
+	assert.Equal(t, BaseURL+SavedFeedEndpoint, GetSavedFeedURL(""))
+	assert.Equal(t, BaseURL+SavedFeedEndpoint+"?max_id=abc", GetSavedFeedURL("abc"))
+	assert.Equal(t, BaseURL+"/api/v1/feed/collection/17890/posts/", GetCollectionFeedURL("17890", ""))
+	assert.Equal(t, BaseURL+"/api/v1/feed/collection/17890/posts/?max_id=abc", GetCollectionFeedURL("17890", "abc"))
+
+	collections, err := url.Parse(GetCollectionsURL("next"))
+	assert.NoError(t, err)
+	assert.Equal(t, CollectionsEndpoint, collections.Path)
+	assert.Equal(t, `["MEDIA"]`, collections.Query().Get("collection_types"))
+	assert.Equal(t, "next", collections.Query().Get("max_id"))
+}
+
+func TestGetTagFeedURL(t *testing.T) {
+	assert.Equal(t, BaseURL+"/api/v1/feed/tag/sunset/", GetTagFeedURL("sunset", ""))
+	assert.Equal(t, BaseURL+"/api/v1/feed/tag/sunset/?max_id=abc", GetTagFeedURL("sunset", "abc"))
+	assert.Equal(t, BaseURL+"/api/v1/feed/tag/caf%C3%A9/", GetTagFeedURL("café", ""))
+}
+
+func TestGetLocationFeedURL(t *testing.T) {
+	assert.Equal(t, BaseURL+"/api/v1/feed/location/212988663/", GetLocationFeedURL("212988663", ""))
+	assert.Equal(t, BaseURL+"/api/v1/feed/location/212988663/?max_id=abc", GetLocationFeedURL("212988663", "abc"))
+}
+
+func TestGetCommentsURL(t *testing.T) {
+	assert.Equal(t, BaseURL+"/api/v1/media/314159/comments/?can_support_threading=true", GetCommentsURL("314159", ""))
+	assert.Equal(t, BaseURL+"/api/v1/media/314159/comments/?can_support_threading=true&min_id=%7B%22cached%22%3Atrue%7D",
+		GetCommentsURL("314159", `{"cached":true}`))
+}
+
+func TestEndpointCategory(t *testing.T) {
+	tests := map[string]string{
+		GetProfileURL("someone"):                           "profile",
+		GetMediaURL("123", "cursor"):                       "timeline",
+		GetLikedFeedURL(""):                                "liked_feed",
+		GetSavedFeedURL("abc"):                             "saved_feed",
+		GetCollectionsURL(""):                              "collections",
+		GetCollectionFeedURL("17890", ""):                  "collection_feed",
+		GetClipsURL("123", "cursor"):                       "reels",
+		GetMediaInfoURL("3141592653589793238"):             "post",
+		GetTagFeedURL("sunset", "abc"):                     "hashtag",
+		GetLocationFeedURL("212988663", ""):                "location",
+		GetCommentsURL("3141592653589793238", "cursor"):    "comments",
+		GetProfilePageURL("someone"):                       "profile",
+		GetUserFeedURL("123", "abc"):                       "user_feed",
+		GetCurrentUserURL():                                "current_user",
+		"https://www.instagram.com/accounts/login/?next=/": "login_redirect",
+		"https://scontent.cdninstagram.com/v/t51/a.jpg":    "media",
+		"https://scontent-lhr8-1.xx.fbcdn.net/v/b.jpg":     "media",
+		"https://www.instagram.com/p/ABC/":                 "other",
+		"https://example.com/api/v1/other/":                "other",
+	}
+	for rawURL, want := range tests {
+		req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, want, EndpointCategory(req), rawURL)
+	}
+}
+
 func TestGetPhotoURL(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -0,0 +1,148 @@
+package instagram
+
+import (
+	stderrors "errors"
+	"net/http"
+	"strings"
+
+	"igscraper/pkg/errors"
+)
+
+// Endpoint families a profile or its posts can be served by
+const (
+	SourceWebProfileInfo = "web_profile_info"
+	SourceGraphQL        = "graphql"
+	SourceMobileAPI      = "mobile_api"
+)
+
+// feedCursorPrefix marks the end cursors of pages served by the mobile API,
+// so the pages after them are fetched from it too
+const feedCursorPrefix = "feed:"
+
+// pageEnd is the end cursor of the last GraphQL page fetched for a profile
+// and the ID of the last post on it, from which the mobile API can carry on
+type pageEnd struct {
+	cursor  string
+	mediaID string
+}
+
+// fallBack reports whether an endpoint's answer calls for trying the other
+// endpoint family: a 400, a body that is not the JSON expected, or no data
+// at all. Authentication, challenge, rate limit and not found errors would
+// come from either family alike, so they are returned as they are.
+func fallBack(err error, empty bool) bool {
+	if err == nil {
+		return empty
+	}
+	var apiErr *errors.Error
+	if !stderrors.As(err, &apiErr) || apiErr.Type == errors.ErrorTypeChallenge {
+		return false
+	}
+	return apiErr.Type == errors.ErrorTypeParsing || apiErr.Code == http.StatusBadRequest
+}
+
+// emptyTimeline reports whether a page of posts carries nothing at all
+func emptyTimeline(resp *InstagramResponse) bool {
+	media := resp.Data.User.EdgeOwnerToTimelineMedia
+	return len(media.Edges) == 0 && media.Count == 0 && !media.PageInfo.HasNextPage
+}
+
+// logFallback records that a request is repeated through the other endpoint
+// family, and why
+func (c *Client) logFallback(kind, target, from, to string, err error) {
+	reason := "empty response"
+	if err != nil {
+		reason = err.Error()
+	}
+	c.logger.WarnWithFields("falling back to alternative endpoint", map[string]interface{}{
+		"endpoint": kind,
+		"target":   target,
+		"from":     from,
+		"to":       to,
+		"reason":   reason,
+	})
+}
+
+// fetchProfilePage fetches a profile from its profile page's GraphQL JSON
+func (c *Client) fetchProfilePage(username string) (*InstagramResponse, error) {
+	var page ProfilePageResponse
+	requestID, err := c.getJSON(GetProfilePageURL(username), &page)
+	if err != nil {
+		return nil, err
+	}
+	return &InstagramResponse{Data: page.GraphQL, Status: "ok", Source: SourceGraphQL, RequestID: requestID}, nil
+}
+
+// fetchUserFeed fetches a page of a profile's posts from the mobile API and
+// converts it to the GraphQL timeline form. The end cursor is marked with
+// feedCursorPrefix.
+func (c *Client) fetchUserFeed(userID, maxID string) (*InstagramResponse, error) {
+	var feed FeedResponse
+	requestID, err := c.getJSON(GetUserFeedURL(userID, maxID), &feed)
+	if err != nil {
+		return nil, err
+	}
+
+	edges := feed.Edges()
+	for i := range edges {
+		edges[i].Node.Source = SourceMobileAPI
+		edges[i].Node.RequestID = requestID
+	}
+	pageInfo := feed.PageInfo()
+	if pageInfo.EndCursor != "" {
+		pageInfo.EndCursor = feedCursorPrefix + pageInfo.EndCursor
+	}
+
+	return &InstagramResponse{
+		Status:    feed.Status,
+		Source:    SourceMobileAPI,
+		RequestID: requestID,
+		Data: Data{User: User{
+			ID: userID,
+			EdgeOwnerToTimelineMedia: EdgeOwnerToTimelineMedia{
+				PageInfo: pageInfo,
+				Edges:    edges,
+			},
+		}},
+	}, nil
+}
+
+// rememberPageEnd records where a GraphQL page of a profile's posts ended
+func (c *Client) rememberPageEnd(userID string, resp *InstagramResponse) {
+	media := resp.Data.User.EdgeOwnerToTimelineMedia
+	if media.PageInfo.EndCursor == "" || len(media.Edges) == 0 {
+		return
+	}
+
+	c.pageEndsMu.Lock()
+	defer c.pageEndsMu.Unlock()
+	if c.pageEnds == nil {
+		c.pageEnds = make(map[string]pageEnd)
+	}
+	c.pageEnds[userID] = pageEnd{
+		cursor:  media.PageInfo.EndCursor,
+		mediaID: media.Edges[len(media.Edges)-1].Node.ID,
+	}
+}
+
+// feedMaxID translates a GraphQL cursor to the mobile API's max_id. Only the
+// first page and the page after the last one fetched can be translated.
+func (c *Client) feedMaxID(userID, after string) (string, bool) {
+	if after == "" {
+		return "", true
+	}
+
+	c.pageEndsMu.Lock()
+	defer c.pageEndsMu.Unlock()
+	end, ok := c.pageEnds[userID]
+	if !ok || end.cursor != after {
+		return "", false
+	}
+	return end.mediaID + "_" + userID, true
+}
+
+// isFeedCursor reports whether a cursor came from the mobile API, returning
+// its max_id
+func isFeedCursor(after string) (string, bool) {
+	return strings.CutPrefix(after, feedCursorPrefix)
+}
@@ -0,0 +1,23 @@
+package instagram
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ParseHashtag returns a hashtag in the form Instagram uses in URLs: without
+// the leading '#' and in lower case. Hashtags hold only letters, digits and
+// underscores.
+func ParseHashtag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+	if tag == "" {
+		return "", fmt.Errorf("empty hashtag")
+	}
+	for _, r := range tag {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			return "", fmt.Errorf("invalid hashtag %q: only letters, digits and underscores are allowed", tag)
+		}
+	}
+	return tag, nil
+}
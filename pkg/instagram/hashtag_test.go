@@ -0,0 +1,49 @@
+package instagram
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHashtag(t *testing.T) {
+	valid := map[string]string{
+		"sunset":      "sunset",
+		"#Sunset":     "sunset",
+		" #no_filter": "no_filter",
+		"café2024":    "café2024",
+	}
+	for tag, want := range valid {
+		got, err := ParseHashtag(tag)
+		require.NoError(t, err, tag)
+		assert.Equal(t, want, got, tag)
+	}
+
+	for _, tag := range []string{"", "#", "two words", "sun-set", "../etc"} {
+		_, err := ParseHashtag(tag)
+		assert.Error(t, err, tag)
+	}
+}
+
+func TestTagFeedResponse(t *testing.T) {
+	var page TagFeedResponse
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"items": [{"code": "RECENT", "media_type": 1}],
+		"ranked_items": [{"code": "TOP1", "media_type": 1}, {"code": "TOP2", "media_type": 1}],
+		"more_available": true,
+		"next_max_id": "next",
+		"status": "ok"
+	}`), &page))
+
+	recent := page.Feed(false)
+	require.Len(t, recent.Items, 1)
+	assert.Equal(t, "RECENT", recent.Items[0].Code)
+	assert.Equal(t, PageInfo{HasNextPage: true, EndCursor: "next"}, recent.PageInfo())
+
+	top := page.Feed(true)
+	require.Len(t, top.Items, 2)
+	assert.Equal(t, "TOP1", top.Items[0].Code)
+	assert.False(t, top.PageInfo().HasNextPage, "top posts are not paginated")
+}
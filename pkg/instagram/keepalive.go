@@ -0,0 +1,81 @@
+package instagram
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"igscraper/pkg/errors"
+	"igscraper/pkg/logger"
+)
+
+// currentUserResponse is the answer of the current user endpoint
+type currentUserResponse struct {
+	User *struct {
+		Username string `json:"username"`
+	} `json:"user"`
+}
+
+// KeepAlive sends one small authenticated request, so a session left idle
+// by long downloads or cooldowns is used and the cookies Instagram renews in
+// its answer are picked up. It returns an authentication error when
+// Instagram asks to log in again and a challenge error when the account must
+// pass a security check; other errors say nothing about the session.
+func (c *Client) KeepAlive() error {
+	requestID := logger.NewRequestID()
+	resp, err := c.get(GetCurrentUserURL(), requestID)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	if err != nil {
+		return &errors.Error{
+			Type:    errors.ErrorTypeNetwork,
+			Message: "failed to read response body: " + err.Error(),
+			Code:    resp.StatusCode,
+		}
+	}
+	if challenge := c.challengeError(resp, body); challenge != nil {
+		return challenge
+	}
+	if err := c.checkResponseStatus(resp); err != nil {
+		return c.withPayload(err, resp, body)
+	}
+	if err := c.failedStatus(resp, body); err != nil {
+		return err
+	}
+
+	// The API answers with the login page rather than JSON once the session
+	// is gone
+	var answer currentUserResponse
+	loggedOut := strings.HasPrefix(resp.Request.URL.Path, "/accounts/login") || strings.Contains(resp.Header.Get("Content-Type"), "html")
+	if !loggedOut {
+		if err := json.Unmarshal(body, &answer); err != nil {
+			return &errors.Error{
+				Type:    errors.ErrorTypeParsing,
+				Message: "failed to parse JSON: " + err.Error(),
+				Code:    resp.StatusCode,
+			}
+		}
+		loggedOut = answer.User == nil
+	}
+	if loggedOut {
+		c.logger.WarnWithFields("session is logged out", map[string]interface{}{
+			"status":              resp.StatusCode,
+			logger.FieldRequestID: requestID,
+		})
+		return &errors.Error{
+			Type:    errors.ErrorTypeAuth,
+			Message: "Instagram asks to log in again",
+			Code:    http.StatusUnauthorized,
+		}
+	}
+
+	c.logger.DebugWithFields("session is logged in", map[string]interface{}{
+		logger.FieldRequestID: requestID,
+	})
+	return nil
+}
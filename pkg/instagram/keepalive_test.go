@@ -0,0 +1,85 @@
+package instagram
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"igscraper/pkg/config"
+	"igscraper/pkg/errors"
+	"igscraper/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeepAlive(t *testing.T) {
+	tests := []struct {
+		name    string
+		respond func(req *http.Request) *http.Response
+		want    errors.ErrorType // "" for no error
+	}{
+		{
+			name: "logged in",
+			respond: func(req *http.Request) *http.Response {
+				return newResponse(http.StatusOK, `{"user":{"username":"me"},"status":"ok"}`)
+			},
+		},
+		{
+			name: "redirected to the login page",
+			respond: func(req *http.Request) *http.Response {
+				resp := newResponse(http.StatusOK, `<html>Login</html>`)
+				resp.Header.Set("Content-Type", "text/html; charset=utf-8")
+				resp.Request, _ = http.NewRequest(http.MethodGet, BaseURL+"/accounts/login/?next=/api/v1/accounts/current_user/", nil)
+				return resp
+			},
+			want: errors.ErrorTypeAuth,
+		},
+		{
+			name: "login required payload",
+			respond: func(req *http.Request) *http.Response {
+				return newResponse(http.StatusForbidden, `{"message":"login_required","status":"fail"}`)
+			},
+			want: errors.ErrorTypeAuth,
+		},
+		{
+			name: "no account in the answer",
+			respond: func(req *http.Request) *http.Response {
+				return newResponse(http.StatusOK, `{"status":"ok"}`)
+			},
+			want: errors.ErrorTypeAuth,
+		},
+		{
+			name: "challenged",
+			respond: func(req *http.Request) *http.Response {
+				return newResponse(http.StatusBadRequest, `{"message":"challenge_required","challenge":{"url":"https://www.instagram.com/challenge/1/"},"status":"fail"}`)
+			},
+			want: errors.ErrorTypeChallenge,
+		},
+		{
+			name: "server error",
+			respond: func(req *http.Request) *http.Response {
+				return newResponse(http.StatusServiceUnavailable, ``)
+			},
+			want: errors.ErrorTypeServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClientWithConfig(time.Second, &config.RetryConfig{}, logger.NewTestLogger())
+			client.SetTransport(&mockRoundTripper{handler: func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, GetCurrentUserURL(), req.URL.String())
+				return tt.respond(req), nil
+			}})
+
+			err := client.KeepAlive()
+			if tt.want == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var apiErr *errors.Error
+			require.ErrorAs(t, err, &apiErr)
+			assert.Equal(t, tt.want, apiErr.Type)
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package instagram
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ParseLocationID returns the numeric ID of a location given as an ID or as a
+// location page URL such as
+// https://www.instagram.com/explore/locations/212988663/new-york-new-york/
+func ParseLocationID(ref string) (string, error) {
+	ref = strings.TrimSpace(ref)
+	id := ref
+	if strings.Contains(ref, "/") {
+		if !strings.Contains(ref, "://") {
+			ref = "https://" + ref
+		}
+		u, err := url.Parse(ref)
+		if err != nil {
+			return "", fmt.Errorf("invalid location URL %q: %w", ref, err)
+		}
+		parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+		// Paths are /explore/locations/<id>/<slug>/
+		id = ""
+		for i := 0; i+1 < len(parts); i++ {
+			if parts[i] == "locations" {
+				id = parts[i+1]
+				break
+			}
+		}
+		if id == "" {
+			return "", fmt.Errorf("%q is not a link to a location", ref)
+		}
+	}
+
+	if id == "" || strings.Trim(id, "0123456789") != "" {
+		return "", fmt.Errorf("invalid location ID %q", id)
+	}
+	return id, nil
+}
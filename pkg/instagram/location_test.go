@@ -0,0 +1,52 @@
+package instagram
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLocationID(t *testing.T) {
+	valid := map[string]string{
+		"212988663": "212988663",
+		"https://www.instagram.com/explore/locations/212988663/new-york-new-york/": "212988663",
+		"instagram.com/explore/locations/212988663":                                "212988663",
+	}
+	for ref, want := range valid {
+		got, err := ParseLocationID(ref)
+		require.NoError(t, err, ref)
+		assert.Equal(t, want, got, ref)
+	}
+
+	for _, ref := range []string{"", "new-york", "https://www.instagram.com/explore/tags/sunset/", "https://www.instagram.com/explore/locations/"} {
+		_, err := ParseLocationID(ref)
+		assert.Error(t, err, ref)
+	}
+}
+
+func TestLocationFeedResponse(t *testing.T) {
+	var page LocationFeedResponse
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"items": [{"code": "POST", "media_type": 1,
+			"location": {"pk": 212988663, "name": "New York", "slug": "new-york-new-york", "lat": 40.7142, "lng": -74.0064}}],
+		"location": {"pk": 212988663, "name": "New York", "address": "", "city": "New York", "lat": 40.7142, "lng": -74.0064},
+		"more_available": true,
+		"next_max_id": "next",
+		"status": "ok"
+	}`), &page))
+
+	require.NotNil(t, page.Location)
+	location := page.Location.ToLocation()
+	assert.Equal(t, "212988663", location.ID)
+	assert.Equal(t, "New York", location.City)
+	assert.InDelta(t, 40.7142, location.Lat, 1e-9)
+
+	feed := page.Feed()
+	assert.Equal(t, PageInfo{HasNextPage: true, EndCursor: "next"}, feed.PageInfo())
+	node := feed.Edges()[0].Node
+	require.NotNil(t, node.Location)
+	assert.Equal(t, "new-york-new-york", node.Location.Slug)
+	assert.InDelta(t, -74.0064, node.Location.Lng, 1e-9)
+}
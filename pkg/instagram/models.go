@@ -1,10 +1,31 @@
 package instagram
 
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
 // InstagramResponse represents the top-level response from Instagram API
 type InstagramResponse struct {
 	RequiresToLogin bool   `json:"requires_to_login"`
 	Data            Data   `json:"data"`
 	Status          string `json:"status"`
+
+	// Source names the endpoint family that served the response, such as
+	// SourceGraphQL; it is not part of the API response
+	Source string `json:"-"`
+
+	// RequestID is the ID the request for the response was logged under,
+	// empty if it was served from the cache
+	RequestID string `json:"-"`
+}
+
+// LoginRequired reports whether the profile's posts cannot be fetched with
+// the current session. Instagram also asks to log in for private profiles
+// the session follows, whose posts are available to it.
+func (r *InstagramResponse) LoginRequired() bool {
+	return r.RequiresToLogin && !r.Data.User.FollowedPrivate()
 }
 
 // Data wraps the user information in the response
@@ -15,9 +36,40 @@ type Data struct {
 // User represents an Instagram user profile
 type User struct {
 	ID                       string                   `json:"id"`
+	Username                 string                   `json:"username"`
+	FullName                 string                   `json:"full_name"`
+	Biography                string                   `json:"biography"`
+	ExternalURL              string                   `json:"external_url"`
+	ProfilePicURL            string                   `json:"profile_pic_url"`
+	ProfilePicURLHD          string                   `json:"profile_pic_url_hd"`
+	IsPrivate                bool                     `json:"is_private"`
+	IsVerified               bool                     `json:"is_verified"`
+	FollowedByViewer         bool                     `json:"followed_by_viewer"`
+	EdgeFollowedBy           EdgeCount                `json:"edge_followed_by"`
+	EdgeFollow               EdgeCount                `json:"edge_follow"`
 	EdgeOwnerToTimelineMedia EdgeOwnerToTimelineMedia `json:"edge_owner_to_timeline_media"`
 }
 
+// EdgeCount is the size of one of a profile's lists, such as its followers
+type EdgeCount struct {
+	Count int `json:"count"`
+}
+
+// ProfilePicture returns the URL of the profile picture, in HD when
+// available
+func (u *User) ProfilePicture() string {
+	if u.ProfilePicURLHD != "" {
+		return u.ProfilePicURLHD
+	}
+	return u.ProfilePicURL
+}
+
+// FollowedPrivate reports whether the profile is private and followed by the
+// session that fetched it
+func (u *User) FollowedPrivate() bool {
+	return u.IsPrivate && u.FollowedByViewer
+}
+
 // EdgeOwnerToTimelineMedia contains the user's media information
 type EdgeOwnerToTimelineMedia struct {
 	Count    int      `json:"count"`
@@ -38,10 +90,13 @@ type Edge struct {
 
 // Node represents a single media item (photo or video)
 type Node struct {
+	Typename              string               `json:"__typename,omitempty"` // kind of post, such as TypenameSidecar
 	ID                    string               `json:"id"`
 	Shortcode             string               `json:"shortcode"`
 	DisplayURL            string               `json:"display_url"`
+	DisplayResources      []DisplayResource    `json:"display_resources,omitempty"`
 	IsVideo               bool                 `json:"is_video"`
+	VideoURL              string               `json:"video_url,omitempty"`
 	TakenAtTimestamp      int64                `json:"taken_at_timestamp"`
 	Dimensions            MediaDimensions      `json:"dimensions"`
 	EdgeMediaToCaption    EdgeMediaToCaption   `json:"edge_media_to_caption"`
@@ -51,9 +106,107 @@ type Node struct {
 	Owner                 Owner                `json:"owner"`
 	AccessibilityCaption  string               `json:"accessibility_caption,omitempty"`
 	VideoViewCount        *int                 `json:"video_view_count,omitempty"`
+	VideoPlayCount        *int                 `json:"video_play_count,omitempty"`
+	AudioTitle            string               `json:"audio_title,omitempty"` // reels only
 	VideoDuration         *float64             `json:"video_duration,omitempty"`
 	EdgeMediaToTaggedUser EdgeMediaToTaggedUser `json:"edge_media_to_tagged_user"`
 	CommentsDisabled      bool                 `json:"comments_disabled"`
+	PinnedForUsers        []PinnedUser         `json:"pinned_for_users,omitempty"` // profiles showing the post at the top of their grid
+
+	// Rendition is the size of the image DisplayURL points at after
+	// SelectImage; it is not part of the API response
+	Rendition *MediaDimensions `json:"-"`
+
+	// CarouselIndex is the position of the media in a carousel post, from 1,
+	// or 0 for the first or only media of a post in a feed
+	CarouselIndex int `json:"-"`
+
+	// Source names the endpoint family the node was listed by, when known
+	Source string `json:"-"`
+
+	// RequestID is the ID the request that listed the node was logged
+	// under, when known
+	RequestID string `json:"-"`
+}
+
+// TypenameSidecar is the __typename of a carousel post in timeline
+// responses; newer responses prefix it with "XDT"
+const TypenameSidecar = "GraphSidecar"
+
+// IsCarousel reports whether the post is a carousel of several photos or
+// videos. Only the first of them is part of the node.
+func (n *Node) IsCarousel() bool {
+	return strings.HasSuffix(n.Typename, TypenameSidecar)
+}
+
+// PinnedUser is a profile that pinned a post to the top of its grid
+type PinnedUser struct {
+	ID       string `json:"id"`
+	Username string `json:"username,omitempty"`
+}
+
+// Pinned reports whether the post is pinned, so it is listed at the top of
+// the feed regardless of its date
+func (n *Node) Pinned() bool {
+	return len(n.PinnedForUsers) > 0
+}
+
+// TakenAt returns the time the media was posted, or the zero time when the
+// timestamp is missing
+func (n *Node) TakenAt() time.Time {
+	if n.TakenAtTimestamp <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(n.TakenAtTimestamp, 0)
+}
+
+// DisplayResource is one of the sizes an image is served in
+type DisplayResource struct {
+	Src    string `json:"src"`
+	Width  int    `json:"config_width"`
+	Height int    `json:"config_height"`
+}
+
+// SelectImage points DisplayURL at the rendition to download: the smallest
+// at least preferredWidth pixels wide, or the largest when preferredWidth is
+// zero or no rendition is that wide. Rendition records the chosen size.
+func (n *Node) SelectImage(preferredWidth int) {
+	candidates := n.DisplayResources
+	if n.DisplayURL != "" && !n.hasResource(n.DisplayURL) {
+		candidates = append([]DisplayResource{{
+			Src:    n.DisplayURL,
+			Width:  n.Dimensions.Width,
+			Height: n.Dimensions.Height,
+		}}, candidates...)
+	}
+	if len(candidates) == 0 {
+		return
+	}
+
+	best := candidates[0]
+	for _, c := range candidates[1:] {
+		switch {
+		case preferredWidth > 0 && best.Width >= preferredWidth:
+			// Keep the smallest rendition that is wide enough
+			if c.Width >= preferredWidth && c.Width < best.Width {
+				best = c
+			}
+		case c.Width > best.Width:
+			best = c
+		}
+	}
+	n.DisplayURL = best.Src
+	n.Rendition = &MediaDimensions{Width: best.Width, Height: best.Height}
+}
+
+// hasResource reports whether url is one of the node's display resources
+func (n *Node) hasResource(url string) bool {
+	for _, r := range n.DisplayResources {
+		if r.Src == url {
+			return true
+		}
+	}
+	return false
 }
 
 // MediaDimensions represents the dimensions of the media
@@ -89,16 +242,21 @@ type EdgeMediaToComment struct {
 
 // Location represents geographic location
 type Location struct {
-	ID            string `json:"id"`
-	Name          string `json:"name"`
-	Slug          string `json:"slug"`
-	HasPublicPage bool   `json:"has_public_page"`
+	ID            string  `json:"id"`
+	Name          string  `json:"name"`
+	Slug          string  `json:"slug"`
+	HasPublicPage bool    `json:"has_public_page"`
+	Address       string  `json:"address,omitempty"`
+	City          string  `json:"city,omitempty"`
+	Lat           float64 `json:"lat,omitempty"`
+	Lng           float64 `json:"lng,omitempty"`
 }
 
 // Owner represents the media owner
 type Owner struct {
 	ID       string `json:"id"`
 	Username string `json:"username"`
+	FullName string `json:"full_name,omitempty"`
 }
 
 // EdgeMediaToTaggedUser contains tagged users information
@@ -123,4 +281,357 @@ type TaggedUser struct {
 	ID       string `json:"id"`
 	Username string `json:"username"`
 	FullName string `json:"full_name"`
-}
\ No newline at end of file
+}
+
+// ProfilePageResponse is a profile as served by its profile page's GraphQL
+// JSON
+type ProfilePageResponse struct {
+	GraphQL Data `json:"graphql"`
+}
+
+// FeedResponse is a page of one of the authenticated account's own media
+// feeds, such as liked posts. These feeds use the private API item format
+// rather than GraphQL edges.
+type FeedResponse struct {
+	Items         []FeedItem `json:"items"`
+	MoreAvailable bool       `json:"more_available"`
+	NextMaxID     string     `json:"next_max_id"`
+	Status        string     `json:"status"`
+}
+
+// FeedItem is a media item in the private API format
+type FeedItem struct {
+	ID             string         `json:"id"`
+	Code           string         `json:"code"`
+	TakenAt        int64          `json:"taken_at"`
+	MediaType      int            `json:"media_type"` // 1 photo, 2 video, 8 carousel
+	ImageVersions2 ImageVersions  `json:"image_versions2"`
+	VideoVersions  []VideoVersion `json:"video_versions,omitempty"`
+	OriginalWidth  int            `json:"original_width"`
+	OriginalHeight int            `json:"original_height"`
+	User           FeedUser       `json:"user"`
+	Caption        *FeedCaption   `json:"caption"`
+	LikeCount      int            `json:"like_count"`
+	CommentCount   int            `json:"comment_count"`
+	CarouselMedia  []FeedItem     `json:"carousel_media,omitempty"`
+	Location       *FeedLocation  `json:"location,omitempty"`
+	PlayCount      int            `json:"play_count,omitempty"`
+	ViewCount      int            `json:"view_count,omitempty"`
+	ClipsMetadata  *ClipsMetadata `json:"clips_metadata,omitempty"`
+
+	// Profiles that pinned the item to their grid or their reels tab
+	TimelinePinnedUserIDs []json.Number `json:"timeline_pinned_user_ids,omitempty"`
+	ClipsTabPinnedUserIDs []json.Number `json:"clips_tab_pinned_user_ids,omitempty"`
+}
+
+// ImageVersions lists the available renditions of an image, largest first
+type ImageVersions struct {
+	Candidates []ImageCandidate `json:"candidates"`
+}
+
+// ImageCandidate is one rendition of an image
+type ImageCandidate struct {
+	URL    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// VideoVersion is one rendition of a video, largest first
+type VideoVersion struct {
+	URL    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// ClipsMetadata holds the reel-specific details of a feed item
+type ClipsMetadata struct {
+	MusicInfo *struct {
+		MusicAssetInfo struct {
+			Title         string `json:"title"`
+			DisplayArtist string `json:"display_artist"`
+		} `json:"music_asset_info"`
+	} `json:"music_info,omitempty"`
+	OriginalSoundInfo *struct {
+		OriginalAudioTitle string `json:"original_audio_title"`
+	} `json:"original_sound_info,omitempty"`
+}
+
+// AudioTitle returns the title of the reel's audio: the song and artist for
+// licensed music, or the name of the original sound
+func (c *ClipsMetadata) AudioTitle() string {
+	switch {
+	case c == nil:
+		return ""
+	case c.MusicInfo != nil && c.MusicInfo.MusicAssetInfo.Title != "":
+		info := c.MusicInfo.MusicAssetInfo
+		if info.DisplayArtist == "" {
+			return info.Title
+		}
+		return info.DisplayArtist + " - " + info.Title
+	case c.OriginalSoundInfo != nil:
+		return c.OriginalSoundInfo.OriginalAudioTitle
+	}
+	return ""
+}
+
+// FeedUser is the owner of a feed item
+type FeedUser struct {
+	PK       json.Number `json:"pk"`
+	Username string      `json:"username"`
+	FullName string      `json:"full_name"`
+}
+
+// FeedCaption is the caption of a feed item
+type FeedCaption struct {
+	Text string `json:"text"`
+}
+
+// FeedLocation is the location attached to a feed item
+type FeedLocation struct {
+	PK      json.Number `json:"pk"`
+	Name    string      `json:"name"`
+	Slug    string      `json:"slug"`
+	Address string      `json:"address"`
+	City    string      `json:"city"`
+	Lat     float64     `json:"lat"`
+	Lng     float64     `json:"lng"`
+}
+
+// ToLocation converts the location to the form used for timeline media
+func (l *FeedLocation) ToLocation() *Location {
+	return &Location{
+		ID:      l.PK.String(),
+		Name:    l.Name,
+		Slug:    l.Slug,
+		Address: l.Address,
+		City:    l.City,
+		Lat:     l.Lat,
+		Lng:     l.Lng,
+	}
+}
+
+// ToNode converts a feed item to the Node used for timeline media, so feed
+// posts go through the same download and metadata path. Carousels are
+// represented by their first image. Items pinned to a grid are pinned
+// nodes.
+func (item *FeedItem) ToNode() Node {
+	node := Node{
+		ID:                 item.ID,
+		Shortcode:          item.Code,
+		IsVideo:            item.MediaType == 2,
+		TakenAtTimestamp:   item.TakenAt,
+		Dimensions:         MediaDimensions{Width: item.OriginalWidth, Height: item.OriginalHeight},
+		EdgeLikedBy:        EdgeLikedBy{Count: item.LikeCount},
+		EdgeMediaToComment: EdgeMediaToComment{Count: item.CommentCount},
+		Owner: Owner{
+			ID:       item.User.PK.String(),
+			Username: item.User.Username,
+			FullName: item.User.FullName,
+		},
+	}
+
+	image := item.ImageVersions2.Candidates
+	video := item.VideoVersions
+	if item.MediaType == 8 || len(item.CarouselMedia) > 0 {
+		node.Typename = TypenameSidecar
+	}
+	if len(image) == 0 && len(item.CarouselMedia) > 0 {
+		first := item.CarouselMedia[0]
+		image = first.ImageVersions2.Candidates
+		video = first.VideoVersions
+		node.IsVideo = first.MediaType == 2
+		node.Dimensions = MediaDimensions{Width: first.OriginalWidth, Height: first.OriginalHeight}
+	}
+	if node.IsVideo && len(video) > 0 {
+		node.VideoURL = video[0].URL
+	}
+	if len(image) > 0 {
+		node.DisplayURL = image[0].URL
+		if node.Dimensions.Width == 0 {
+			node.Dimensions = MediaDimensions{Width: image[0].Width, Height: image[0].Height}
+		}
+		for _, candidate := range image {
+			node.DisplayResources = append(node.DisplayResources, DisplayResource{
+				Src:    candidate.URL,
+				Width:  candidate.Width,
+				Height: candidate.Height,
+			})
+		}
+	}
+
+	if item.Caption != nil && item.Caption.Text != "" {
+		node.EdgeMediaToCaption.Edges = []CaptionEdge{{Node: CaptionNode{Text: item.Caption.Text}}}
+	}
+
+	if item.Location != nil {
+		node.Location = item.Location.ToLocation()
+	}
+
+	if item.PlayCount > 0 {
+		plays := item.PlayCount
+		node.VideoPlayCount = &plays
+	}
+	if item.ViewCount > 0 {
+		views := item.ViewCount
+		node.VideoViewCount = &views
+	}
+	node.AudioTitle = item.ClipsMetadata.AudioTitle()
+
+	for _, id := range item.TimelinePinnedUserIDs {
+		node.PinnedForUsers = append(node.PinnedForUsers, PinnedUser{ID: id.String()})
+	}
+
+	return node
+}
+
+// Edges converts the page's items to timeline edges
+func (r *FeedResponse) Edges() []Edge {
+	edges := make([]Edge, 0, len(r.Items))
+	for i := range r.Items {
+		edges = append(edges, Edge{Node: r.Items[i].ToNode()})
+	}
+	return edges
+}
+
+// PageInfo returns the page's pagination state in timeline form
+func (r *FeedResponse) PageInfo() PageInfo {
+	return PageInfo{
+		HasNextPage: r.MoreAvailable && r.NextMaxID != "",
+		EndCursor:   r.NextMaxID,
+	}
+}
+
+// SavedFeedResponse is a page of saved posts, either all of them or one
+// collection's. Each item wraps the media.
+type SavedFeedResponse struct {
+	Items []struct {
+		Media FeedItem `json:"media"`
+	} `json:"items"`
+	MoreAvailable bool   `json:"more_available"`
+	NextMaxID     string `json:"next_max_id"`
+	Status        string `json:"status"`
+}
+
+// Feed unwraps the page into the plain feed form
+func (r *SavedFeedResponse) Feed() *FeedResponse {
+	feed := &FeedResponse{
+		Items:         make([]FeedItem, 0, len(r.Items)),
+		MoreAvailable: r.MoreAvailable,
+		NextMaxID:     r.NextMaxID,
+		Status:        r.Status,
+	}
+	for _, item := range r.Items {
+		feed.Items = append(feed.Items, item.Media)
+	}
+	return feed
+}
+
+// ClipsResponse is a page of a profile's reels. Each item wraps the media.
+type ClipsResponse struct {
+	Items []struct {
+		Media FeedItem `json:"media"`
+	} `json:"items"`
+	PagingInfo struct {
+		MaxID         string `json:"max_id"`
+		MoreAvailable bool   `json:"more_available"`
+	} `json:"paging_info"`
+	Status string `json:"status"`
+}
+
+// Feed unwraps the page into the plain feed form. The reels tab pins reels
+// of its own, so those are the items pinned in the feed rather than the
+// ones pinned to the grid.
+func (r *ClipsResponse) Feed() *FeedResponse {
+	feed := &FeedResponse{
+		Items:         make([]FeedItem, 0, len(r.Items)),
+		MoreAvailable: r.PagingInfo.MoreAvailable,
+		NextMaxID:     r.PagingInfo.MaxID,
+		Status:        r.Status,
+	}
+	for _, item := range r.Items {
+		media := item.Media
+		media.TimelinePinnedUserIDs = media.ClipsTabPinnedUserIDs
+		feed.Items = append(feed.Items, media)
+	}
+	return feed
+}
+
+// TagFeedResponse is a page of a hashtag's posts. Items are the most recent
+// posts; the first page also carries the hashtag's top posts as ranked items.
+type TagFeedResponse struct {
+	Items         []FeedItem `json:"items"`
+	RankedItems   []FeedItem `json:"ranked_items"`
+	MoreAvailable bool       `json:"more_available"`
+	NextMaxID     string     `json:"next_max_id"`
+	Status        string     `json:"status"`
+}
+
+// Feed returns the page's recent posts in the plain feed form, or with top
+// its top posts, which are not paginated
+func (r *TagFeedResponse) Feed(top bool) *FeedResponse {
+	if top {
+		return &FeedResponse{Items: r.RankedItems, Status: r.Status}
+	}
+	return &FeedResponse{
+		Items:         r.Items,
+		MoreAvailable: r.MoreAvailable,
+		NextMaxID:     r.NextMaxID,
+		Status:        r.Status,
+	}
+}
+
+// LocationFeedResponse is a page of the most recent posts geotagged at a
+// location. The location's details come with the page.
+type LocationFeedResponse struct {
+	Items         []FeedItem    `json:"items"`
+	MoreAvailable bool          `json:"more_available"`
+	NextMaxID     string        `json:"next_max_id"`
+	Location      *FeedLocation `json:"location,omitempty"`
+	Status        string        `json:"status"`
+}
+
+// Feed returns the page in the plain feed form
+func (r *LocationFeedResponse) Feed() *FeedResponse {
+	return &FeedResponse{
+		Items:         r.Items,
+		MoreAvailable: r.MoreAvailable,
+		NextMaxID:     r.NextMaxID,
+		Status:        r.Status,
+	}
+}
+
+// CollectionsResponse is a page of the authenticated account's collections
+type CollectionsResponse struct {
+	Items         []Collection `json:"items"`
+	MoreAvailable bool         `json:"more_available"`
+	NextMaxID     string       `json:"next_max_id"`
+	Status        string       `json:"status"`
+}
+
+// Collection is a named group of saved posts
+type Collection struct {
+	ID         string `json:"collection_id"`
+	Name       string `json:"collection_name"`
+	Type       string `json:"collection_type"`
+	MediaCount int    `json:"collection_media_count"`
+}
+
+// CommentsResponse is a page of a post's comments. Replies are not included,
+// only their number.
+type CommentsResponse struct {
+	Comments        []Comment `json:"comments"`
+	CommentCount    int       `json:"comment_count"`
+	HasMoreComments bool      `json:"has_more_comments"`
+	NextMinID       string    `json:"next_min_id"`
+	Status          string    `json:"status"`
+}
+
+// Comment is a comment on a post
+type Comment struct {
+	PK                json.Number `json:"pk"`
+	Text              string      `json:"text"`
+	CreatedAt         int64       `json:"created_at"`
+	User              FeedUser    `json:"user"`
+	LikeCount         int         `json:"comment_like_count"`
+	ChildCommentCount int         `json:"child_comment_count"`
+}
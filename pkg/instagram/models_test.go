@@ -0,0 +1,112 @@
+package instagram
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectImage(t *testing.T) {
+	var node Node
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"shortcode":"ABC","display_url":"http://example.com/640.jpg",
+		"dimensions":{"width":640,"height":800},
+		"display_resources":[
+			{"src":"http://example.com/640.jpg","config_width":640,"config_height":800},
+			{"src":"http://example.com/1080.jpg","config_width":1080,"config_height":1350},
+			{"src":"http://example.com/750.jpg","config_width":750,"config_height":937}
+		]}`), &node))
+
+	tests := []struct {
+		preferred int
+		want      string
+		rendition MediaDimensions
+	}{
+		{0, "http://example.com/1080.jpg", MediaDimensions{Width: 1080, Height: 1350}},
+		{700, "http://example.com/750.jpg", MediaDimensions{Width: 750, Height: 937}},
+		{640, "http://example.com/640.jpg", MediaDimensions{Width: 640, Height: 800}},
+		{2000, "http://example.com/1080.jpg", MediaDimensions{Width: 1080, Height: 1350}},
+	}
+	for _, tt := range tests {
+		n := node
+		n.SelectImage(tt.preferred)
+		assert.Equal(t, tt.want, n.DisplayURL, "preferred %d", tt.preferred)
+		require.NotNil(t, n.Rendition)
+		assert.Equal(t, tt.rendition, *n.Rendition, "preferred %d", tt.preferred)
+	}
+
+	// Without display resources the display URL is kept
+	plain := Node{DisplayURL: "http://example.com/only.jpg", Dimensions: MediaDimensions{Width: 320, Height: 320}}
+	plain.SelectImage(0)
+	assert.Equal(t, "http://example.com/only.jpg", plain.DisplayURL)
+	assert.Equal(t, MediaDimensions{Width: 320, Height: 320}, *plain.Rendition)
+
+	empty := Node{}
+	empty.SelectImage(0)
+	assert.Nil(t, empty.Rendition)
+}
+
+func TestToNodeImageVersions(t *testing.T) {
+	var item FeedItem
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"id":"1_10","code":"POST","media_type":1,"original_width":1440,"original_height":1800,
+		"image_versions2":{"candidates":[
+			{"url":"http://example.com/1440.jpg","width":1440,"height":1800},
+			{"url":"http://example.com/320.jpg","width":320,"height":400}
+		]}}`), &item))
+
+	node := item.ToNode()
+	assert.Len(t, node.DisplayResources, 2)
+	node.SelectImage(300)
+	assert.Equal(t, "http://example.com/320.jpg", node.DisplayURL)
+	assert.Equal(t, MediaDimensions{Width: 320, Height: 400}, *node.Rendition)
+}
+
+func TestPinned(t *testing.T) {
+	var node Node
+	require.NoError(t, json.Unmarshal([]byte(`{"shortcode":"POST","pinned_for_users":[{"id":"10","username":"owner"}]}`), &node))
+	assert.True(t, node.Pinned())
+	assert.Equal(t, []PinnedUser{{ID: "10", Username: "owner"}}, node.PinnedForUsers)
+	require.NoError(t, json.Unmarshal([]byte(`{"shortcode":"POST","pinned_for_users":[]}`), &node))
+	assert.False(t, node.Pinned())
+
+	var feed FeedResponse
+	require.NoError(t, json.Unmarshal([]byte(`{"items":[
+		{"code":"PINNED","timeline_pinned_user_ids":[10]},
+		{"code":"PLAIN"}
+	]}`), &feed))
+	edges := feed.Edges()
+	assert.True(t, edges[0].Node.Pinned())
+	assert.Equal(t, "10", edges[0].Node.PinnedForUsers[0].ID)
+	assert.False(t, edges[1].Node.Pinned())
+
+	// On the reels tab only the reels pinned to the tab come first
+	var clips ClipsResponse
+	require.NoError(t, json.Unmarshal([]byte(`{"items":[
+		{"media":{"code":"TAB","clips_tab_pinned_user_ids":[10]}},
+		{"media":{"code":"GRID","timeline_pinned_user_ids":[10]}}
+	]}`), &clips))
+	edges = clips.Feed().Edges()
+	assert.True(t, edges[0].Node.Pinned())
+	assert.False(t, edges[1].Node.Pinned())
+}
+
+func TestIsCarousel(t *testing.T) {
+	var node Node
+	require.NoError(t, json.Unmarshal([]byte(`{"__typename":"GraphSidecar","shortcode":"POST"}`), &node))
+	assert.True(t, node.IsCarousel())
+	require.NoError(t, json.Unmarshal([]byte(`{"__typename":"XDTGraphSidecar","shortcode":"POST"}`), &node))
+	assert.True(t, node.IsCarousel())
+	require.NoError(t, json.Unmarshal([]byte(`{"__typename":"GraphImage","shortcode":"POST"}`), &node))
+	assert.False(t, node.IsCarousel())
+
+	var item FeedItem
+	require.NoError(t, json.Unmarshal([]byte(`{"code":"POST","media_type":8,"carousel_media":[{"media_type":1}]}`), &item))
+	node = item.ToNode()
+	assert.True(t, node.IsCarousel())
+	item = FeedItem{Code: "POST", MediaType: 1}
+	node = item.ToNode()
+	assert.False(t, node.IsCarousel())
+}
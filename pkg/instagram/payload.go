@@ -0,0 +1,78 @@
+package instagram
+
+import (
+	"bytes"
+	stderrors "errors"
+	"net/http"
+	"strings"
+
+	"igscraper/pkg/errors"
+)
+
+// Messages and error types in Instagram's error payloads that say more about
+// a failure than its status code
+const (
+	MessageLoginRequired    = "login_required"
+	MessageFeedbackRequired = "feedback_required"
+	MessagePleaseWait       = "please wait a few minutes"
+)
+
+// withPayload attaches the error payload in body, if there is one, to err
+// and reclassifies err when the payload says more than the status code did:
+// a login wall is an authentication error, and a request to slow down or a
+// spam block is a rate limit. Spam blocks are not retried.
+func (c *Client) withPayload(err error, resp *http.Response, body []byte) error {
+	var apiErr *errors.Error
+	if !stderrors.As(err, &apiErr) {
+		return err
+	}
+	payload := errors.ParsePayload(body)
+	if payload == nil {
+		return err
+	}
+	apiErr.Payload = payload
+
+	message := strings.ToLower(payload.Message)
+	switch {
+	case message == MessageLoginRequired || payload.ErrorType == MessageLoginRequired:
+		apiErr.Type = errors.ErrorTypeAuth
+	case payload.Spam || message == MessageFeedbackRequired || strings.Contains(message, MessagePleaseWait):
+		apiErr.Type = errors.ErrorTypeRateLimit
+	}
+
+	fields := map[string]interface{}{
+		"status":     resp.StatusCode,
+		"type":       string(apiErr.Type),
+		"message":    payload.Message,
+		"api_status": payload.Status,
+		"spam":       payload.Spam,
+	}
+	if payload.ErrorType != "" {
+		fields["error_type"] = payload.ErrorType
+	}
+	if payload.FeedbackTitle != "" || payload.FeedbackMessage != "" {
+		fields["feedback"] = payload.Describe()
+	}
+	if resp.Request != nil {
+		fields["url"] = resp.Request.URL.String()
+	}
+	c.logger.WarnWithFields("instagram returned an error payload", fields)
+	return err
+}
+
+// failedStatus returns an error for a 200 response whose body is an error
+// payload with the "fail" status, or nil for any other response
+func (c *Client) failedStatus(resp *http.Response, body []byte) error {
+	if !bytes.Contains(body, []byte(`"fail"`)) {
+		return nil
+	}
+	payload := errors.ParsePayload(body)
+	if payload == nil || payload.Status != "fail" {
+		return nil
+	}
+	return c.withPayload(&errors.Error{
+		Type:    errors.ErrorTypeUnknown,
+		Message: "request failed",
+		Code:    resp.StatusCode,
+	}, resp, body)
+}
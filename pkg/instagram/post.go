@@ -0,0 +1,91 @@
+package instagram
+
+import (
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+)
+
+// shortcodeAlphabet is the base64 alphabet shortcodes encode media IDs in
+const shortcodeAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
+
+// ParseShortcode returns the shortcode of a post given as a shortcode or as
+// a post, reel or IGTV URL such as https://www.instagram.com/p/C1a2B3c4D5e/
+func ParseShortcode(ref string) (string, error) {
+	ref = strings.TrimSpace(ref)
+	shortcode := ref
+	if strings.Contains(ref, "/") {
+		if !strings.Contains(ref, "://") {
+			ref = "https://" + ref
+		}
+		u, err := url.Parse(ref)
+		if err != nil {
+			return "", fmt.Errorf("invalid post URL %q: %w", ref, err)
+		}
+		parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+		// Paths are /p/<code>/, /reel/<code>/, /tv/<code>/ or <user>/p/<code>/
+		shortcode = ""
+		for i := 0; i+1 < len(parts); i++ {
+			if parts[i] == "p" || parts[i] == "reel" || parts[i] == "reels" || parts[i] == "tv" {
+				shortcode = parts[i+1]
+				break
+			}
+		}
+		if shortcode == "" {
+			return "", fmt.Errorf("%q is not a link to a post", ref)
+		}
+	}
+
+	if shortcode == "" || strings.Trim(shortcode, shortcodeAlphabet) != "" {
+		return "", fmt.Errorf("invalid shortcode %q", shortcode)
+	}
+	return shortcode, nil
+}
+
+// MediaID returns the numeric media ID a shortcode encodes. Private posts
+// have longer shortcodes whose extra characters are not part of the ID.
+func MediaID(shortcode string) (string, error) {
+	if len(shortcode) > 11 {
+		shortcode = shortcode[:11]
+	}
+	id := new(big.Int)
+	for _, r := range shortcode {
+		digit := strings.IndexRune(shortcodeAlphabet, r)
+		if digit < 0 {
+			return "", fmt.Errorf("invalid shortcode %q", shortcode)
+		}
+		id.Mul(id, big.NewInt(64))
+		id.Add(id, big.NewInt(int64(digit)))
+	}
+	return id.String(), nil
+}
+
+// MediaNodes returns a Node for every photo or video in the item. The media
+// of a carousel get the shortcode of the post followed by their position,
+// starting at 1, so each is saved to its own file.
+func (item *FeedItem) MediaNodes() []Node {
+	post := item.ToNode()
+	if len(item.CarouselMedia) == 0 {
+		return []Node{post}
+	}
+
+	nodes := make([]Node, 0, len(item.CarouselMedia))
+	for i := range item.CarouselMedia {
+		child := item.CarouselMedia[i]
+		node := child.ToNode()
+		node.Shortcode = fmt.Sprintf("%s_%d", item.Code, i+1)
+		node.CarouselIndex = i + 1
+		if node.ID == "" {
+			node.ID = post.ID
+		}
+		node.TakenAtTimestamp = post.TakenAtTimestamp
+		node.Owner = post.Owner
+		node.EdgeMediaToCaption = post.EdgeMediaToCaption
+		node.EdgeLikedBy = post.EdgeLikedBy
+		node.EdgeMediaToComment = post.EdgeMediaToComment
+		node.Location = post.Location
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
@@ -0,0 +1,82 @@
+package instagram
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseShortcode(t *testing.T) {
+	valid := map[string]string{
+		"C1a2B3c4D5e": "C1a2B3c4D5e",
+		"https://www.instagram.com/p/C1a2B3c4D5e/":             "C1a2B3c4D5e",
+		"https://www.instagram.com/p/C1a2B3c4D5e/?img_index=2": "C1a2B3c4D5e",
+		"instagram.com/reel/C1a2B3c4D5e":                       "C1a2B3c4D5e",
+		"https://www.instagram.com/tv/B-x_y/":                  "B-x_y",
+		"https://www.instagram.com/someone/p/C1a2B3c4D5e/":     "C1a2B3c4D5e",
+	}
+	for ref, want := range valid {
+		got, err := ParseShortcode(ref)
+		require.NoError(t, err, ref)
+		assert.Equal(t, want, got, ref)
+	}
+
+	for _, ref := range []string{"", "https://www.instagram.com/someone/", "not a shortcode", "https://www.instagram.com/p/"} {
+		_, err := ParseShortcode(ref)
+		assert.Error(t, err, ref)
+	}
+}
+
+func TestMediaID(t *testing.T) {
+	id, err := MediaID("B")
+	require.NoError(t, err)
+	assert.Equal(t, "1", id)
+
+	id, err = MediaID("BA")
+	require.NoError(t, err)
+	assert.Equal(t, "64", id)
+
+	// Only the first 11 characters encode the ID
+	long, err := MediaID("CzABCDEFGHIxyzPrivateSuffix")
+	require.NoError(t, err)
+	short, err := MediaID("CzABCDEFGHI")
+	require.NoError(t, err)
+	assert.Equal(t, short, long)
+	assert.Equal(t, "3224581871972409800", short)
+
+	_, err = MediaID("bad!")
+	assert.Error(t, err)
+}
+
+func TestMediaNodes(t *testing.T) {
+	var item FeedItem
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"id":"1_10","code":"POST","taken_at":1700000000,"media_type":8,
+		"user":{"pk":10,"username":"alice"},"caption":{"text":"hello"},"like_count":5,
+		"carousel_media":[
+			{"id":"11","media_type":1,"image_versions2":{"candidates":[{"url":"http://example.com/1.jpg"}]}},
+			{"id":"12","media_type":2,"image_versions2":{"candidates":[{"url":"http://example.com/2.jpg"}]},
+			 "video_versions":[{"url":"http://example.com/2.mp4"}]}
+		]}`), &item))
+
+	nodes := item.MediaNodes()
+	require.Len(t, nodes, 2)
+	assert.Equal(t, "POST_1", nodes[0].Shortcode)
+	assert.Equal(t, "http://example.com/1.jpg", nodes[0].DisplayURL)
+	assert.False(t, nodes[0].IsVideo)
+	assert.Equal(t, "POST_2", nodes[1].Shortcode)
+	assert.True(t, nodes[1].IsVideo)
+	assert.Equal(t, "http://example.com/2.mp4", nodes[1].VideoURL)
+	for _, node := range nodes {
+		assert.Equal(t, "alice", node.Owner.Username)
+		assert.Equal(t, int64(1700000000), node.TakenAtTimestamp)
+		assert.Equal(t, 5, node.EdgeLikedBy.Count)
+		assert.Equal(t, "hello", node.EdgeMediaToCaption.Edges[0].Node.Text)
+	}
+
+	single := FeedItem{Code: "ONE", MediaType: 1}
+	assert.Len(t, single.MediaNodes(), 1)
+	assert.Equal(t, "ONE", single.MediaNodes()[0].Shortcode)
+}
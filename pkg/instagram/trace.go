@@ -0,0 +1,21 @@
+package instagram
+
+import (
+	"context"
+	"net/http"
+)
+
+// requestIDKey is the context key of the ID a request is logged under
+type requestIDKey struct{}
+
+// withRequestID returns req carrying id, which its log lines and those of
+// its retries are logged under
+func withRequestID(req *http.Request, id string) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), requestIDKey{}, id))
+}
+
+// requestIDOf returns the ID req is logged under, or "" if it has none
+func requestIDOf(req *http.Request) string {
+	id, _ := req.Context().Value(requestIDKey{}).(string)
+	return id
+}
@@ -0,0 +1,65 @@
+package instagram
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+
+	"igscraper/pkg/config"
+)
+
+// NewTransport creates an HTTP transport tuned by cfg, starting from Go's
+// default transport so settings left at zero keep their defaults. Pass it to
+// SetTransport. Callers that need their own dialer can set DialContext on
+// the result before doing so.
+func NewTransport(cfg config.TransportConfig) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if cfg.MaxIdleConns > 0 {
+		transport.MaxIdleConns = cfg.MaxIdleConns
+	}
+	if cfg.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	}
+	if cfg.MaxConnsPerHost > 0 {
+		transport.MaxConnsPerHost = cfg.MaxConnsPerHost
+	}
+	if cfg.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = cfg.IdleConnTimeout
+	}
+	if cfg.TLSHandshakeTimeout > 0 {
+		transport.TLSHandshakeTimeout = cfg.TLSHandshakeTimeout
+	}
+
+	if cfg.DialTimeout > 0 || cfg.KeepAlive > 0 || cfg.LocalAddress != "" {
+		dialer := &net.Dialer{Timeout: cfg.DialTimeout, KeepAlive: cfg.KeepAlive}
+		if cfg.LocalAddress != "" {
+			ip := net.ParseIP(cfg.LocalAddress)
+			if ip == nil {
+				return nil, fmt.Errorf("local address %q is not an IP address", cfg.LocalAddress)
+			}
+			dialer.LocalAddr = &net.TCPAddr{IP: ip}
+		}
+		transport.DialContext = dialer.DialContext
+	}
+
+	switch cfg.TLSMinVersion {
+	case "":
+	case "1.2":
+		transport.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	case "1.3":
+		transport.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS13}
+	default:
+		return nil, fmt.Errorf("unsupported TLS minimum version %q", cfg.TLSMinVersion)
+	}
+
+	// A non-nil, empty TLSNextProto keeps the transport from upgrading to
+	// HTTP/2
+	if cfg.DisableHTTP2 {
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	}
+
+	return transport, nil
+}
@@ -0,0 +1,47 @@
+package instagram
+
+import (
+	"crypto/tls"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"igscraper/pkg/config"
+)
+
+func TestNewTransport(t *testing.T) {
+	defaults := http.DefaultTransport.(*http.Transport)
+
+	transport, err := NewTransport(config.TransportConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, defaults.MaxIdleConns, transport.MaxIdleConns)
+	assert.Equal(t, defaults.IdleConnTimeout, transport.IdleConnTimeout)
+	assert.True(t, transport.ForceAttemptHTTP2)
+
+	transport, err = NewTransport(config.TransportConfig{
+		MaxIdleConnsPerHost: 8,
+		MaxConnsPerHost:     16,
+		IdleConnTimeout:     time.Minute,
+		DialTimeout:         5 * time.Second,
+		TLSMinVersion:       "1.3",
+		DisableHTTP2:        true,
+		LocalAddress:        "127.0.0.1",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 8, transport.MaxIdleConnsPerHost)
+	assert.Equal(t, 16, transport.MaxConnsPerHost)
+	assert.Equal(t, time.Minute, transport.IdleConnTimeout)
+	assert.Equal(t, uint16(tls.VersionTLS13), transport.TLSClientConfig.MinVersion)
+	assert.False(t, transport.ForceAttemptHTTP2)
+	assert.NotNil(t, transport.TLSNextProto)
+	assert.Empty(t, transport.TLSNextProto)
+	assert.NotNil(t, transport.DialContext)
+
+	_, err = NewTransport(config.TransportConfig{TLSMinVersion: "1.1"})
+	assert.Error(t, err)
+	_, err = NewTransport(config.TransportConfig{LocalAddress: "not-an-ip"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,258 @@
+package instagram
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"igscraper/pkg/errors"
+	"igscraper/pkg/retry"
+)
+
+// SetChunking makes DownloadVideo fetch videos of at least minSize bytes as
+// chunks parallel ranged requests instead of one long request. Fewer than
+// two chunks disables chunked downloads.
+func (c *Client) SetChunking(chunks int, minSize int64) {
+	c.chunks = chunks
+	c.chunkMinSize = minSize
+}
+
+// DownloadVideo downloads a video into w and returns its size. Large videos
+// are split into ranged requests made in parallel, each written to its own
+// part of w, which speeds up downloads over high-latency links and keeps
+// every request well within the client timeout. Servers that do not support
+// ranges get a single request.
+func (c *Client) DownloadVideo(videoURL string, w io.WriterAt) (int64, error) {
+	c.logger.DebugWithFields("downloading video", map[string]interface{}{
+		"url": videoURL,
+	})
+
+	if c.chunks < 2 {
+		return c.fetchRange(videoURL, 0, -1, w)
+	}
+
+	size, ranged, err := c.probeSize(videoURL)
+	if err != nil {
+		return 0, err
+	}
+	if !ranged || size < c.chunkMinSize || size < int64(c.chunks) {
+		return c.fetchRange(videoURL, 0, -1, w)
+	}
+
+	chunkSize := (size + int64(c.chunks) - 1) / int64(c.chunks)
+	errs := make([]error, c.chunks)
+	var wg sync.WaitGroup
+	for i := 0; i < c.chunks; i++ {
+		start := int64(i) * chunkSize
+		if start >= size {
+			break
+		}
+		end := min(start+chunkSize, size) - 1
+
+		wg.Add(1)
+		go func(i int, start, end int64) {
+			defer wg.Done()
+			_, errs[i] = c.fetchRange(videoURL, start, end, w)
+		}(i, start, end)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			c.logger.ErrorWithFields("failed to download video chunk", map[string]interface{}{
+				"url":   videoURL,
+				"error": err.Error(),
+			})
+			return 0, err
+		}
+	}
+
+	c.logger.DebugWithFields("successfully downloaded video in chunks", map[string]interface{}{
+		"url":    videoURL,
+		"size":   size,
+		"chunks": c.chunks,
+	})
+	return size, nil
+}
+
+// DownloadFile downloads a photo, or any other file, into w in a single
+// request and returns its size. The body is streamed to w as it arrives, so
+// it is never held in memory.
+func (c *Client) DownloadFile(fileURL string, w io.WriterAt) (int64, error) {
+	c.logger.DebugWithFields("downloading file", map[string]interface{}{
+		"url": fileURL,
+	})
+
+	size, err := c.fetchRange(fileURL, 0, -1, w)
+	if err != nil {
+		c.logger.ErrorWithFields("failed to download file", map[string]interface{}{
+			"url":   fileURL,
+			"error": err.Error(),
+		})
+		return 0, err
+	}
+
+	c.logger.DebugWithFields("successfully downloaded file", map[string]interface{}{
+		"url":  fileURL,
+		"size": size,
+	})
+	return size, nil
+}
+
+// ContentLength asks for the size of a file with a HEAD request, without
+// downloading it. It returns -1 when the server does not say.
+func (c *Client) ContentLength(fileURL string) (int64, error) {
+	req, err := http.NewRequest(http.MethodHead, fileURL, nil)
+	if err != nil {
+		return -1, &errors.Error{
+			Type:    errors.ErrorTypeUnknown,
+			Message: fmt.Sprintf("failed to create request: %v", err),
+			Code:    0,
+		}
+	}
+
+	resp, err := c.doRequestWithRetry(req)
+	if err != nil {
+		return -1, err
+	}
+	resp.Body.Close()
+
+	if err := c.checkResponseStatus(resp); err != nil {
+		return -1, err
+	}
+	return resp.ContentLength, nil
+}
+
+// probeSize asks for the first byte of a file to learn its size and whether
+// the server accepts ranged requests
+func (c *Client) probeSize(fileURL string) (int64, bool, error) {
+	req, err := http.NewRequest("GET", fileURL, nil)
+	if err != nil {
+		return 0, false, &errors.Error{
+			Type:    errors.ErrorTypeUnknown,
+			Message: fmt.Sprintf("failed to create request: %v", err),
+			Code:    0,
+		}
+	}
+	req.Header.Set("Range", "bytes=0-0")
+
+	resp, err := c.doRequestWithRetry(req)
+	if err != nil {
+		return 0, false, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusPartialContent {
+		// Content-Range: bytes 0-0/<size>
+		_, total, found := strings.Cut(resp.Header.Get("Content-Range"), "/")
+		size, err := strconv.ParseInt(total, 10, 64)
+		if found && err == nil {
+			return size, true, nil
+		}
+		return 0, false, nil
+	}
+	if err := c.checkResponseStatus(resp); err != nil {
+		return 0, false, err
+	}
+	return resp.ContentLength, false, nil
+}
+
+// fetchRange downloads bytes start to end of a file, inclusive, into the
+// same offsets of w. An end below zero downloads the whole file. Network
+// failures are retried as for photos.
+func (c *Client) fetchRange(fileURL string, start, end int64, w io.WriterAt) (int64, error) {
+	var written int64
+	fetch := func() error {
+		req, err := http.NewRequest("GET", fileURL, nil)
+		if err != nil {
+			return &errors.Error{
+				Type:    errors.ErrorTypeUnknown,
+				Message: fmt.Sprintf("failed to create request: %v", err),
+				Code:    0,
+			}
+		}
+		if end >= 0 {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+		}
+
+		resp, err := c.doRequestWithRetry(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if end >= 0 && resp.StatusCode != http.StatusPartialContent {
+			if err := c.checkResponseStatus(resp); err != nil {
+				return err
+			}
+			return &errors.Error{
+				Type:    errors.ErrorTypeUnknown,
+				Message: fmt.Sprintf("server ignored range request (status %d)", resp.StatusCode),
+				Code:    resp.StatusCode,
+			}
+		}
+		if err := c.checkResponseStatus(resp); err != nil {
+			return err
+		}
+
+		dst := &destWriter{w: io.NewOffsetWriter(w, start)}
+		written, err = io.Copy(dst, c.mediaBody(resp))
+		if dst.err != nil {
+			// The destination refused the data, e.g. because the download
+			// was skipped; fetching it again would not help
+			return &errors.Error{
+				Type:    errors.ErrorTypeUnknown,
+				Message: fmt.Sprintf("failed to write media data: %v", dst.err),
+				Code:    0,
+			}
+		}
+		if err == nil && end >= 0 && written != end-start+1 {
+			err = fmt.Errorf("got %d of %d bytes", written, end-start+1)
+		}
+		if err != nil {
+			return &errors.Error{
+				Type:    errors.ErrorTypeNetwork,
+				Message: fmt.Sprintf("failed to read media data: %v", err),
+				Code:    0,
+			}
+		}
+		return nil
+	}
+
+	if c.retryConfig == nil || !c.retryConfig.Enabled {
+		return written, fetch()
+	}
+	err := retry.Do(fetch, &retry.Config{
+		MaxAttempts: c.retryConfig.NetworkRetries,
+		Backoff: &retry.ExponentialBackoff{
+			BaseDelay:    c.retryConfig.NetworkBaseDelay,
+			MaxDelay:     c.retryConfig.MaxDelay,
+			Multiplier:   c.retryConfig.Multiplier,
+			JitterFactor: c.retryConfig.JitterFactor,
+		},
+		RetryIf: retry.DefaultRetryIf,
+		Context: context.Background(),
+		Logger:  c.logger,
+	})
+	return written, err
+}
+
+// destWriter remembers a failure of the destination, so it can be told
+// apart from a failed connection
+type destWriter struct {
+	w   io.Writer
+	err error
+}
+
+// Write writes p to the destination
+func (d *destWriter) Write(p []byte) (int, error) {
+	n, err := d.w.Write(p)
+	if err != nil {
+		d.err = err
+	}
+	return n, err
+}
@@ -0,0 +1,198 @@
+package instagram
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"igscraper/pkg/config"
+	"igscraper/pkg/errors"
+	"igscraper/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownloadVideo(t *testing.T) {
+	video := bytes.Repeat([]byte("0123456789abcdef"), 1000)
+
+	var ranged, whole int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/noranges.mp4" {
+			atomic.AddInt32(&whole, 1)
+			w.Write(video)
+			return
+		}
+		if r.Header.Get("Range") != "" {
+			atomic.AddInt32(&ranged, 1)
+		} else {
+			atomic.AddInt32(&whole, 1)
+		}
+		http.ServeContent(w, r, "video.mp4", time.Time{}, bytes.NewReader(video))
+	}))
+	defer server.Close()
+
+	download := func(t *testing.T, client *Client, path string) []byte {
+		file, err := os.Create(filepath.Join(t.TempDir(), "video.mp4"))
+		require.NoError(t, err)
+		defer file.Close()
+
+		size, err := client.DownloadVideo(server.URL+path, file)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(video)), size)
+
+		data, err := os.ReadFile(file.Name())
+		require.NoError(t, err)
+		return data
+	}
+
+	t.Run("large videos are fetched in chunks", func(t *testing.T) {
+		atomic.StoreInt32(&ranged, 0)
+		atomic.StoreInt32(&whole, 0)
+		client := NewClient(30*time.Second, logger.NewTestLogger())
+		client.SetChunking(3, 1024)
+
+		assert.Equal(t, video, download(t, client, "/video.mp4"))
+		assert.Equal(t, int32(4), atomic.LoadInt32(&ranged), "one probe and three chunks")
+		assert.Equal(t, int32(0), atomic.LoadInt32(&whole))
+	})
+
+	t.Run("small videos are fetched whole", func(t *testing.T) {
+		atomic.StoreInt32(&ranged, 0)
+		atomic.StoreInt32(&whole, 0)
+		client := NewClient(30*time.Second, logger.NewTestLogger())
+		client.SetChunking(3, int64(len(video)+1))
+
+		assert.Equal(t, video, download(t, client, "/video.mp4"))
+		assert.Equal(t, int32(1), atomic.LoadInt32(&ranged), "only the probe")
+		assert.Equal(t, int32(1), atomic.LoadInt32(&whole))
+	})
+
+	t.Run("chunking disabled", func(t *testing.T) {
+		atomic.StoreInt32(&ranged, 0)
+		atomic.StoreInt32(&whole, 0)
+		client := NewClient(30*time.Second, logger.NewTestLogger())
+
+		assert.Equal(t, video, download(t, client, "/video.mp4"))
+		assert.Equal(t, int32(0), atomic.LoadInt32(&ranged))
+		assert.Equal(t, int32(1), atomic.LoadInt32(&whole))
+	})
+
+	t.Run("server without range support", func(t *testing.T) {
+		atomic.StoreInt32(&whole, 0)
+		client := NewClient(30*time.Second, logger.NewTestLogger())
+		client.SetChunking(3, 1024)
+
+		assert.Equal(t, video, download(t, client, "/noranges.mp4"))
+		assert.Equal(t, int32(2), atomic.LoadInt32(&whole), "probe and full download")
+	})
+
+	t.Run("missing video", func(t *testing.T) {
+		missing := httptest.NewServer(http.NotFoundHandler())
+		defer missing.Close()
+		client := NewClient(30*time.Second, logger.NewTestLogger())
+		client.SetChunking(3, 1024)
+
+		file, err := os.Create(filepath.Join(t.TempDir(), "video.mp4"))
+		require.NoError(t, err)
+		defer file.Close()
+
+		_, err = client.DownloadVideo(missing.URL+"/video.mp4", file)
+		var igErr *errors.Error
+		require.ErrorAs(t, err, &igErr)
+		assert.Equal(t, errors.ErrorTypeNotFound, igErr.Type)
+	})
+}
+
+func TestDownloadFile(t *testing.T) {
+	photo := bytes.Repeat([]byte("photo"), 1000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") != "" {
+			t.Error("photos are fetched in a single request")
+		}
+		w.Write(photo)
+	}))
+	defer server.Close()
+	
+	client := NewClient(30*time.Second, logger.NewTestLogger())
+	client.SetChunking(3, 1024)
+	
+	file, err := os.Create(filepath.Join(t.TempDir(), "photo.jpg"))
+	require.NoError(t, err)
+	defer file.Close()
+	
+	size, err := client.DownloadFile(server.URL+"/photo.jpg", file)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(photo)), size)
+	
+	data, err := os.ReadFile(file.Name())
+	require.NoError(t, err)
+	assert.Equal(t, photo, data)
+}
+
+func TestContentLength(t *testing.T) {
+	photo := bytes.Repeat([]byte("photo"), 1000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			t.Errorf("expected a HEAD request, got %s", r.Method)
+		}
+		switch r.URL.Path {
+		case "/gone.jpg":
+			w.WriteHeader(http.StatusNotFound)
+		case "/chunked.jpg":
+			w.Header().Set("Transfer-Encoding", "chunked")
+		default:
+			w.Header().Set("Content-Length", strconv.Itoa(len(photo)))
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(30*time.Second, logger.NewTestLogger())
+	size, err := client.ContentLength(server.URL + "/photo.jpg")
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(photo)), size)
+
+	size, err = client.ContentLength(server.URL + "/chunked.jpg")
+	require.NoError(t, err)
+	assert.Equal(t, int64(-1), size, "a size the server does not give is unknown")
+
+	_, err = client.ContentLength(server.URL + "/gone.jpg")
+	assert.Error(t, err)
+}
+
+// failingWriter refuses every write
+type failingWriter struct{}
+
+func (failingWriter) WriteAt(p []byte, off int64) (int, error) {
+	return 0, os.ErrClosed
+}
+
+func TestDownloadFileWriteError(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte("photo"))
+	}))
+	defer server.Close()
+	
+	retryConfig := &config.RetryConfig{
+		Enabled:          true,
+		MaxAttempts:      3,
+		NetworkRetries:   3,
+		NetworkBaseDelay: time.Millisecond,
+		MaxDelay:         time.Millisecond,
+		Multiplier:       2,
+	}
+	client := NewClientWithConfig(30*time.Second, retryConfig, logger.NewTestLogger())
+	
+	_, err := client.DownloadFile(server.URL+"/photo.jpg", failingWriter{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to write media data")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests), "write errors are not retried")
+}
@@ -0,0 +1,106 @@
+package logger
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+// Components that log through a logger of their own, named in the
+// logging.levels setting
+const (
+	ComponentInstagram  = "instagram"
+	ComponentDownloader = "downloader"
+	ComponentRateLimit  = "ratelimit"
+	ComponentScraper    = "scraper"
+	ComponentCheckpoint = "checkpoint"
+	ComponentStorage    = "storage"
+	ComponentDaemon     = "daemon"
+	ComponentNotify     = "notify"
+	ComponentHooks      = "hooks"
+)
+
+// levels holds the level of the base logger and of the components given
+// their own. They are read as each line is logged, so SetLevel applies to
+// loggers already handed out.
+var levels = struct {
+	sync.RWMutex
+	base       zerolog.Level
+	components map[string]zerolog.Level
+}{base: zerolog.InfoLevel}
+
+// setLevels replaces the base and component levels
+func setLevels(base zerolog.Level, components map[string]zerolog.Level) {
+	levels.Lock()
+	defer levels.Unlock()
+	levels.base = base
+	levels.components = components
+	applyGlobalLevel()
+}
+
+// setBaseLevel changes the level of the base logger and of the components
+// without one of their own
+func setBaseLevel(base zerolog.Level) {
+	levels.Lock()
+	defer levels.Unlock()
+	levels.base = base
+	applyGlobalLevel()
+}
+
+// baseLevel returns the level of the base logger
+func baseLevel() zerolog.Level {
+	levels.RLock()
+	defer levels.RUnlock()
+	return levels.base
+}
+
+// levelOf returns the level of component, or the base level if it has none
+// of its own or component is empty
+func levelOf(component string) zerolog.Level {
+	levels.RLock()
+	defer levels.RUnlock()
+	if level, ok := levels.components[component]; ok {
+		return level
+	}
+	return levels.base
+}
+
+// applyGlobalLevel lets through the lowest level any logger wants, leaving
+// the rest to levelHook. The caller holds levels.
+func applyGlobalLevel() {
+	lowest := levels.base
+	for _, level := range levels.components {
+		lowest = min(lowest, level)
+	}
+	zerolog.SetGlobalLevel(lowest)
+}
+
+// levelHook drops the lines below the level of its component
+type levelHook struct {
+	component string
+}
+
+func (h levelHook) Run(e *zerolog.Event, level zerolog.Level, msg string) {
+	if level < levelOf(h.component) {
+		e.Discard()
+	}
+}
+
+// Named returns a logger for one component, such as ComponentInstagram. Its
+// lines carry a component field and follow the component's level from
+// logging.levels, or the base level if it has none.
+func Named(component string) Logger {
+	component = strings.ToLower(component)
+	base, ok := GetLogger().(*zerologLogger)
+	if !ok || base.root == nil {
+		return GetLogger().WithField("component", component)
+	}
+
+	zlog := base.root.With().Str("component", component).Logger().Hook(levelHook{component: component})
+	return &zerologLogger{
+		logger: &zlog,
+		fields: make(map[string]interface{}),
+		root:   base.root,
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"path/filepath"
 	"strings"
 	"time"
 
@@ -44,27 +43,37 @@ type Logger interface {
 type zerologLogger struct {
 	logger *zerolog.Logger
 	fields map[string]interface{}
+	root   *zerolog.Logger // without the level hook, for Named
+}
+
+// consoleOutput receives the console log lines. It is stdout unless
+// SetConsoleOutput changed it.
+var consoleOutput io.Writer = os.Stdout
+
+// SetConsoleOutput sends the console log lines of loggers created from now on
+// to w, such as stderr when stdout carries machine-readable output
+func SetConsoleOutput(w io.Writer) {
+	consoleOutput = w
 }
 
 // New creates a new Logger instance based on the provided configuration
 func New(cfg *config.LoggingConfig) (Logger, error) {
 	// Set up the log level
-	level, err := parseLogLevel(cfg.Level)
-	if err != nil {
-		return nil, fmt.Errorf("invalid log level: %w", err)
+	if err := SetLevels(cfg); err != nil {
+		return nil, err
 	}
-	zerolog.SetGlobalLevel(level)
 
 	// Configure time format
 	zerolog.TimeFieldFormat = time.RFC3339
 
 	// Create the base logger with pretty console output
-	var output io.Writer = os.Stdout
+	var output io.Writer = consoleOutput
+	json := strings.EqualFold(cfg.Format, "json")
 	
 	// If console output, use pretty formatting
-	if cfg.File == "" {
+	if cfg.File == "" && !json {
 		output = zerolog.ConsoleWriter{
-			Out:        os.Stdout,
+			Out:        consoleOutput,
 			TimeFormat: "15:04:05",
 			FieldsExclude: []string{},
 			FormatLevel: func(i interface{}) string {
@@ -100,59 +109,125 @@ func New(cfg *config.LoggingConfig) (Logger, error) {
 				return fmt.Sprintf("%s", i)
 			},
 		}
-	} else {
+	} else if cfg.File != "" {
 		// Set up file output if configured
 		fileOutput, err := setupFileOutput(cfg)
 		if err != nil {
 			return nil, fmt.Errorf("failed to setup file output: %w", err)
 		}
 		
-		// If both file and console output are needed, use multi-writer
-		if cfg.File != "" {
-			consoleWriter := zerolog.ConsoleWriter{
-				Out:        os.Stdout,
+		// The file always gets JSON lines; the console gets them too with
+		// format json
+		var consoleWriter io.Writer = consoleOutput
+		if !json {
+			consoleWriter = zerolog.ConsoleWriter{
+				Out:        consoleOutput,
 				TimeFormat: "15:04:05",
 			}
-			output = zerolog.MultiLevelWriter(consoleWriter, fileOutput)
-		} else {
-			output = fileOutput
 		}
+		output = zerolog.MultiLevelWriter(consoleWriter, fileOutput)
 	}
 
 	// Create the logger
 	zlog := zerolog.New(output).With().Timestamp().Logger()
+	if sampler := newSampler(cfg.Sampling); sampler != nil {
+		zlog = zlog.Sample(sampler)
+	}
 
 	// Add default fields
 	zlog = zlog.With().
 		Str("app", "igscraper").
 		Str("version", "1.0.0").
 		Logger()
+	base := zlog.Hook(levelHook{})
 
 	return &zerologLogger{
-		logger: &zlog,
+		logger: &base,
 		fields: make(map[string]interface{}),
+		root:   &zlog,
 	}, nil
 }
 
-// setupFileOutput creates a file writer for logging
+// setupFileOutput creates the log file writer, rotated as the max_size,
+// max_backups, max_age and compress settings say
 func setupFileOutput(cfg *config.LoggingConfig) (io.Writer, error) {
-	// Create log directory if it doesn't exist
-	dir := filepath.Dir(cfg.File)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	file := &RotatingFile{
+		Filename:   cfg.File,
+		MaxSize:    cfg.MaxSize,
+		MaxBackups: cfg.MaxBackups,
+		MaxAge:     cfg.MaxAge,
+		Compress:   cfg.Compress,
 	}
 
-	// Open or create the log file
-	file, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	// Open the file now, so a bad path fails at startup rather than on the
+	// first log line
+	file.mu.Lock()
+	err := file.open()
+	file.mu.Unlock()
 	if err != nil {
-		return nil, fmt.Errorf("failed to open log file: %w", err)
+		return nil, err
 	}
-
-	// Note: In a production environment, you might want to use a more sophisticated
-	// file rotation mechanism like lumberjack. For now, we'll use a simple file.
 	return file, nil
 }
 
+// newSampler returns a sampler keeping 1 in N lines of each level the
+// sampling settings name, or nil if every line is kept. Errors are never
+// sampled.
+func newSampler(cfg config.LogSamplingConfig) zerolog.Sampler {
+	basic := func(n int) zerolog.Sampler {
+		if n <= 1 {
+			return nil
+		}
+		return &zerolog.BasicSampler{N: uint32(n)}
+	}
+	if cfg.Debug <= 1 && cfg.Info <= 1 && cfg.Warn <= 1 {
+		return nil
+	}
+	return &zerolog.LevelSampler{
+		DebugSampler: basic(cfg.Debug),
+		InfoSampler:  basic(cfg.Info),
+		WarnSampler:  basic(cfg.Warn),
+	}
+}
+
+// SetLevel changes the level of every logger while the program runs, as New
+// does when it starts. It takes the same names as the logging.level setting.
+// Components given a level of their own in logging.levels keep it.
+func SetLevel(level string) error {
+	parsed, err := parseLogLevel(level)
+	if err != nil {
+		return fmt.Errorf("invalid log level: %w", err)
+	}
+	setBaseLevel(parsed)
+	return nil
+}
+
+// SetLevels changes the level of every logger and those of the components
+// while the program runs, as the level and levels settings of cfg say.
+// Components left out of cfg.Levels go back to the base level. Nothing
+// changes if a level is unknown.
+func SetLevels(cfg *config.LoggingConfig) error {
+	level, err := parseLogLevel(cfg.Level)
+	if err != nil {
+		return fmt.Errorf("invalid log level: %w", err)
+	}
+	components := make(map[string]zerolog.Level, len(cfg.Levels))
+	for component, name := range cfg.Levels {
+		componentLevel, err := parseLogLevel(name)
+		if err != nil {
+			return fmt.Errorf("invalid log level for %s: %w", component, err)
+		}
+		components[strings.ToLower(component)] = componentLevel
+	}
+	setLevels(level, components)
+	return nil
+}
+
+// Level returns the name of the current log level
+func Level() string {
+	return baseLevel().String()
+}
+
 // parseLogLevel converts string log level to zerolog.Level
 func parseLogLevel(level string) (zerolog.Level, error) {
 	switch strings.ToLower(level) {
@@ -210,6 +285,7 @@ func (l *zerologLogger) WithField(key string, value interface{}) Logger {
 	newLogger := &zerologLogger{
 		logger: l.logger,
 		fields: make(map[string]interface{}),
+		root:   l.root,
 	}
 	
 	// Copy existing fields
@@ -228,6 +304,7 @@ func (l *zerologLogger) WithFields(fields map[string]interface{}) Logger {
 	newLogger := &zerologLogger{
 		logger: l.logger,
 		fields: make(map[string]interface{}),
+		root:   l.root,
 	}
 	
 	// Copy existing fields
@@ -257,6 +334,7 @@ func (l *zerologLogger) WithContext(ctx context.Context) Logger {
 	return &zerologLogger{
 		logger: &ctxLogger,
 		fields: l.fields,
+		root:   l.root,
 	}
 }
 
@@ -362,4 +362,148 @@ type testError struct {
 
 func (e *testError) Error() string {
 	return e.msg
-}
\ No newline at end of file
+}
+func TestSetLevel(t *testing.T) {
+	defer zerolog.SetGlobalLevel(zerolog.GlobalLevel())
+
+	var buf bytes.Buffer
+	zlog := zerolog.New(&buf)
+	logger := &zerologLogger{logger: &zlog, fields: make(map[string]interface{})}
+
+	if err := SetLevel("warning"); err != nil {
+		t.Fatalf("SetLevel failed: %v", err)
+	}
+	if Level() != "warn" {
+		t.Errorf("Expected level warn, got %s", Level())
+	}
+	logger.Info("hidden")
+	if buf.Len() != 0 {
+		t.Errorf("Expected info messages to be left out, got %q", buf.String())
+	}
+
+	if err := SetLevel("debug"); err != nil {
+		t.Fatalf("SetLevel failed: %v", err)
+	}
+	logger.Debug("shown")
+	if !strings.Contains(buf.String(), "shown") {
+		t.Error("Expected debug messages to be logged")
+	}
+
+	if err := SetLevel("verbose"); err == nil {
+		t.Error("Expected an error for an unknown level")
+	}
+	if Level() != "debug" {
+		t.Errorf("Expected an unknown level to keep debug, got %s", Level())
+	}
+}
+
+func TestFormatAndSampling(t *testing.T) {
+	defer zerolog.SetGlobalLevel(zerolog.GlobalLevel())
+	defer SetConsoleOutput(consoleOutput)
+
+	var buf bytes.Buffer
+	SetConsoleOutput(&buf)
+	log, err := New(&config.LoggingConfig{
+		Level:    "debug",
+		Format:   "json",
+		Sampling: config.LogSamplingConfig{Debug: 10},
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	for i := 0; i < 100; i++ {
+		log.Debug("chatty")
+	}
+	log.Error("failed")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 11 {
+		t.Errorf("Expected 1 in 10 debug lines and the error, got %d lines", len(lines))
+	}
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "{") {
+			t.Errorf("Expected JSON lines, got %q", line)
+			break
+		}
+	}
+	if !strings.Contains(lines[len(lines)-1], `"message":"failed"`) {
+		t.Errorf("Expected the error to be kept, got %q", lines[len(lines)-1])
+	}
+}
+
+func TestComponentLevels(t *testing.T) {
+	defer zerolog.SetGlobalLevel(zerolog.GlobalLevel())
+	defer SetConsoleOutput(consoleOutput)
+	defer func(previous Logger) { globalLogger = previous }(globalLogger)
+
+	var buf bytes.Buffer
+	SetConsoleOutput(&buf)
+	log, err := New(&config.LoggingConfig{
+		Level:  "info",
+		Format: "json",
+		Levels: map[string]string{"instagram": "debug", "Downloader": "warn"},
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	globalLogger = log
+	defer setLevels(zerolog.InfoLevel, nil)
+
+	instagram := Named(ComponentInstagram)
+	downloader := Named(ComponentDownloader)
+	scraper := Named(ComponentScraper)
+
+	log.Debug("base debug")
+	instagram.WithField("page", 2).Debug("instagram debug")
+	downloader.Info("downloader info")
+	downloader.Warn("downloader warn")
+	scraper.Debug("scraper debug")
+	scraper.Info("scraper info")
+
+	out := buf.String()
+	for _, shown := range []string{"instagram debug", "downloader warn", "scraper info", `"component":"instagram"`} {
+		if !strings.Contains(out, shown) {
+			t.Errorf("Expected %q to be logged, got %s", shown, out)
+		}
+	}
+	for _, hidden := range []string{"base debug", "downloader info", "scraper debug"} {
+		if strings.Contains(out, hidden) {
+			t.Errorf("Expected %q to be left out", hidden)
+		}
+	}
+
+	// Changing the level at runtime keeps the component levels
+	buf.Reset()
+	if err := SetLevel("error"); err != nil {
+		t.Fatalf("SetLevel failed: %v", err)
+	}
+	scraper.Warn("scraper warn")
+	instagram.Debug("still debugging")
+	out = buf.String()
+	if strings.Contains(out, "scraper warn") || !strings.Contains(out, "still debugging") {
+		t.Errorf("Expected only the instagram line after SetLevel, got %s", out)
+	}
+
+	// SetLevels replaces the component levels too
+	buf.Reset()
+	if err := SetLevels(&config.LoggingConfig{Level: "info", Levels: map[string]string{"scraper": "debug"}}); err != nil {
+		t.Fatalf("SetLevels failed: %v", err)
+	}
+	scraper.Debug("scraper debug again")
+	instagram.Debug("instagram debug again")
+	out = buf.String()
+	if !strings.Contains(out, "scraper debug again") || strings.Contains(out, "instagram debug again") {
+		t.Errorf("Expected only the scraper line after SetLevels, got %s", out)
+	}
+	if err := SetLevels(&config.LoggingConfig{Level: "info", Levels: map[string]string{"instagram": "loud"}}); err == nil {
+		t.Error("Expected an error for an invalid component level")
+	}
+	if levelOf(ComponentScraper) != zerolog.DebugLevel {
+		t.Error("Expected an invalid level to leave the levels unchanged")
+	}
+
+	if _, err := New(&config.LoggingConfig{Level: "info", Levels: map[string]string{"instagram": "loud"}}); err == nil {
+		t.Error("Expected an error for an invalid component level")
+	}
+}
@@ -0,0 +1,277 @@
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// backupTimeFormat stamps rotated files, as in app-2024-03-15T18-30-00.000.log
+	backupTimeFormat = "2006-01-02T15-04-05.000"
+
+	// compressSuffix is added to rotated files once they are gzipped
+	compressSuffix = ".gz"
+
+	// defaultMaxSize is the size in MB a log file rotates at when max_size
+	// is not set
+	defaultMaxSize = 100
+
+	megabyte = 1024 * 1024
+)
+
+// RotatingFile is a log file that is rotated once it would grow beyond
+// MaxSize megabytes. The full file is renamed with the time of rotation and
+// a new one started. Rotated files are gzipped when Compress is set, and
+// removed once there are more than MaxBackups of them or they are older than
+// MaxAge days; 0 keeps them regardless. Files are named as lumberjack names
+// them, so existing log directories are cleaned up alike.
+type RotatingFile struct {
+	Filename   string
+	MaxSize    int
+	MaxBackups int
+	MaxAge     int
+	Compress   bool
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	now    func() time.Time
+	mill   sync.Mutex     // one cleanup at a time
+	milled sync.WaitGroup // cleanups in progress, awaited by Close
+}
+
+// Write appends p to the log file, rotating it first if p would take it
+// beyond MaxSize
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+	if r.size > 0 && r.size+int64(len(p)) > r.maxBytes() {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Rotate starts a new log file now, regardless of the size of the current one
+func (r *RotatingFile) Rotate() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rotate()
+}
+
+// Close closes the log file, waiting for the cleanup of rotated files
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	var err error
+	if r.file != nil {
+		err = r.file.Close()
+		r.file = nil
+	}
+	r.mu.Unlock()
+
+	r.milled.Wait()
+	return err
+}
+
+// open opens the log file for appending, creating it and its directory if
+// needed. The caller holds r.mu.
+func (r *RotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(r.Filename), 0755); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+	file, err := os.OpenFile(r.Filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	r.file = file
+	r.size = info.Size()
+	return nil
+}
+
+// rotate renames the log file with the current time, opens a new one and
+// cleans up rotated files in the background. The caller holds r.mu.
+func (r *RotatingFile) rotate() error {
+	if r.file != nil {
+		if err := r.file.Close(); err != nil {
+			return fmt.Errorf("failed to close log file: %w", err)
+		}
+		r.file = nil
+	}
+	if _, err := os.Stat(r.Filename); err == nil {
+		if err := os.Rename(r.Filename, r.backupName(r.clock())); err != nil {
+			return fmt.Errorf("failed to rotate log file: %w", err)
+		}
+	}
+	if err := r.open(); err != nil {
+		return err
+	}
+
+	r.milled.Add(1)
+	go func() {
+		defer r.milled.Done()
+		r.cleanup()
+	}()
+	return nil
+}
+
+// backup is a rotated log file
+type backup struct {
+	path    string
+	rotated time.Time
+}
+
+// cleanup removes rotated files beyond MaxBackups or older than MaxAge and
+// compresses the rest if Compress is set. Failures are not fatal to logging,
+// so they are skipped; the next rotation tries again.
+func (r *RotatingFile) cleanup() {
+	r.mill.Lock()
+	defer r.mill.Unlock()
+
+	backups := r.backups()
+	var remove []backup
+	if r.MaxBackups > 0 && len(backups) > r.MaxBackups {
+		remove = append(remove, backups[r.MaxBackups:]...)
+		backups = backups[:r.MaxBackups]
+	}
+	if r.MaxAge > 0 {
+		cutoff := r.clock().Add(-time.Duration(r.MaxAge) * 24 * time.Hour)
+		kept := backups[:0]
+		for _, b := range backups {
+			if b.rotated.Before(cutoff) {
+				remove = append(remove, b)
+			} else {
+				kept = append(kept, b)
+			}
+		}
+		backups = kept
+	}
+
+	for _, b := range remove {
+		os.Remove(b.path)
+	}
+	if r.Compress {
+		for _, b := range backups {
+			if !strings.HasSuffix(b.path, compressSuffix) {
+				compressFile(b.path)
+			}
+		}
+	}
+}
+
+// backups lists the rotated log files, newest first
+func (r *RotatingFile) backups() []backup {
+	dir := filepath.Dir(r.Filename)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	prefix, ext := r.nameParts()
+	var backups []backup
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), compressSuffix)
+		if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext)
+		rotated, err := time.Parse(backupTimeFormat, stamp)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{path: filepath.Join(dir, entry.Name()), rotated: rotated})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].rotated.After(backups[j].rotated)
+	})
+	return backups
+}
+
+// backupName returns the name the log file is rotated to at t
+func (r *RotatingFile) backupName(t time.Time) string {
+	prefix, ext := r.nameParts()
+	return filepath.Join(filepath.Dir(r.Filename), prefix+t.UTC().Format(backupTimeFormat)+ext)
+}
+
+// nameParts splits the log file's name into the part before the time of
+// rotated files, such as "app-", and its extension
+func (r *RotatingFile) nameParts() (string, string) {
+	base := filepath.Base(r.Filename)
+	ext := filepath.Ext(base)
+	return strings.TrimSuffix(base, ext) + "-", ext
+}
+
+// maxBytes returns the size the log file rotates at
+func (r *RotatingFile) maxBytes() int64 {
+	if r.MaxSize <= 0 {
+		return defaultMaxSize * megabyte
+	}
+	return int64(r.MaxSize) * megabyte
+}
+
+// clock returns the current time
+func (r *RotatingFile) clock() time.Time {
+	if r.now != nil {
+		return r.now()
+	}
+	return time.Now()
+}
+
+// compressFile gzips path to path.gz and removes path. A partly written
+// archive is removed, leaving path to be tried again.
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	dst, err := os.OpenFile(path+compressSuffix, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode())
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(dst)
+	_, err = io.Copy(gz, src)
+	if err == nil {
+		err = gz.Close()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path + compressSuffix)
+		return err
+	}
+
+	src.Close()
+	return os.Remove(path)
+}
@@ -0,0 +1,118 @@
+package logger
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// listDir returns the names of the files in dir, sorted
+func listDir(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("reading %s: %v", dir, err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestRotatingFile(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 3, 15, 18, 30, 0, 0, time.UTC)
+	r := &RotatingFile{
+		Filename:   filepath.Join(dir, "logs", "igscraper.log"),
+		MaxSize:    1,
+		MaxBackups: 2,
+		Compress:   true,
+		now:        func() time.Time { return now },
+	}
+
+	line := []byte(strings.Repeat("x", 400*1024) + "\n")
+	for i := 0; i < 8; i++ {
+		if _, err := r.Write(line); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		now = now.Add(time.Minute)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// Two lines fit in a megabyte, so the 8 writes rotate 3 times
+	want := []string{
+		"igscraper-2024-03-15T18-34-00.000.log.gz",
+		"igscraper-2024-03-15T18-36-00.000.log.gz",
+		"igscraper.log",
+	}
+	got := listDir(t, filepath.Join(dir, "logs"))
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("files = %v, want %v", got, want)
+	}
+
+	f, err := os.Open(filepath.Join(dir, "logs", want[1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("rotated file is not gzipped: %v", err)
+	}
+	data, err := io.ReadAll(gz)
+	if err != nil || len(data) != 2*len(line) {
+		t.Errorf("rotated file holds %d bytes (%v), want %d", len(data), err, 2*len(line))
+	}
+
+	info, err := os.Stat(r.Filename)
+	if err != nil || info.Size() != int64(2*len(line)) {
+		t.Errorf("current log file = %v (%v), want %d bytes", info, err, 2*len(line))
+	}
+}
+
+func TestRotatingFileMaxAge(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 3, 15, 18, 30, 0, 0, time.UTC)
+	r := &RotatingFile{
+		Filename: filepath.Join(dir, "igscraper.log"),
+		MaxAge:   7,
+		now:      func() time.Time { return now },
+	}
+
+	old := filepath.Join(dir, "igscraper-2024-03-01T12-00-00.000.log.gz")
+	recent := filepath.Join(dir, "igscraper-2024-03-14T12-00-00.000.log")
+	other := filepath.Join(dir, "notes-2024-03-01T12-00-00.000.log")
+	for _, path := range []string{old, recent, other} {
+		if err := os.WriteFile(path, []byte("old\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := r.Write([]byte("first\n")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := r.Rotate(); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	r.Close()
+
+	want := []string{
+		"igscraper-2024-03-14T12-00-00.000.log",
+		"igscraper-2024-03-15T18-30-00.000.log",
+		"igscraper.log",
+		"notes-2024-03-01T12-00-00.000.log",
+	}
+	got := listDir(t, dir)
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("files = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,37 @@
+package logger
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+// Fields that tie log lines to the scrape and the API call they belong to.
+// Checkpoints and metadata files record the same IDs, so a file can be
+// traced back to the lines logged while it was written.
+const (
+	FieldScrapeID  = "scrape_id"
+	FieldRequestID = "request_id"
+)
+
+// NewScrapeID returns a random ID for a scrape, such as "3f9a1c0e7b24d5a8"
+func NewScrapeID() string {
+	return newID(8)
+}
+
+// NewRequestID returns a random ID for an API call, such as "9c1e04b7a2f3".
+// It is shorter than a scrape ID, as it only needs to be unique within one.
+func NewRequestID() string {
+	return newID(6)
+}
+
+// newID returns n random bytes in hex, or the current time in hex if there is
+// no randomness to be had
+func newID(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
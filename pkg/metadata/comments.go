@@ -0,0 +1,79 @@
+package metadata
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CommentsFolder is the directory in an output directory that holds the
+// comments of its posts, one <shortcode>.json file per post
+const CommentsFolder = "comments"
+
+// PostComments are the comments of one post, saved when downloading with
+// comments enabled
+type PostComments struct {
+	Shortcode string `json:"shortcode"`
+	MediaID   string `json:"media_id"`
+
+	// Total is the post's comment count when fetched. Comments holds at most
+	// the configured maximum of them, and Truncated is set when there were
+	// more.
+	Total     int       `json:"total"`
+	Comments  []Comment `json:"comments"`
+	Truncated bool      `json:"truncated,omitempty"`
+
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// Comment is a top-level comment on a post
+type Comment struct {
+	ID        string    `json:"id"`
+	Username  string    `json:"username"`
+	UserID    string    `json:"user_id,omitempty"`
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"created_at"`
+	Likes     int       `json:"likes"`
+	Replies   int       `json:"replies,omitempty"`
+}
+
+// CommentsPath returns the file the comments of a post are saved to
+func CommentsPath(outputDir, shortcode string) string {
+	return filepath.Join(outputDir, CommentsFolder, shortcode+".json")
+}
+
+// Save writes the comments to the comments folder of the output directory
+func (c *PostComments) Save(outputDir string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal comments: %w", err)
+	}
+	path := CommentsPath(outputDir, c.Shortcode)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create comments folder: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write comments file: %w", err)
+	}
+	return nil
+}
+
+// LoadComments reads the saved comments of a post. It returns nil if they
+// have not been saved.
+func LoadComments(outputDir, shortcode string) (*PostComments, error) {
+	data, err := os.ReadFile(CommentsPath(outputDir, shortcode))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read comments file: %w", err)
+	}
+
+	var comments PostComments
+	if err := json.Unmarshal(data, &comments); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal comments: %w", err)
+	}
+	return &comments, nil
+}
@@ -0,0 +1,95 @@
+package metadata
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"igscraper/pkg/instagram"
+)
+
+// FailedFileName is the file in a profile's output directory listing the
+// downloads that still failed after being retried
+const FailedFileName = "failed.json"
+
+// FailedDownloads lists the downloads of a profile that failed every attempt,
+// so that `igscraper retry-failed` can try them again later
+type FailedDownloads struct {
+	Downloads []FailedDownload `json:"downloads"`
+}
+
+// FailedDownload is a download that failed every attempt for a reason that
+// may pass, such as a network error or a 5xx
+type FailedDownload struct {
+	Shortcode    string          `json:"shortcode"`
+	URL          string          `json:"url"`
+	ThumbnailURL string          `json:"thumbnail_url,omitempty"`
+	Attempts     int             `json:"attempts"`
+	Error        string          `json:"error"`
+	FailedAt     time.Time       `json:"failed_at"`
+	Node         *instagram.Node `json:"node,omitempty"` // the post, for its metadata once saved
+}
+
+// LoadFailedDownloads reads the failed downloads listed in outputDir. A
+// directory without the file has none.
+func LoadFailedDownloads(outputDir string) (*FailedDownloads, error) {
+	data, err := os.ReadFile(filepath.Join(outputDir, FailedFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &FailedDownloads{}, nil
+		}
+		return nil, fmt.Errorf("failed to read failed downloads: %w", err)
+	}
+
+	var failed FailedDownloads
+	if err := json.Unmarshal(data, &failed); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal failed downloads: %w", err)
+	}
+	return &failed, nil
+}
+
+// Save writes the failed downloads to outputDir, or removes the file when
+// none are left
+func (f *FailedDownloads) Save(outputDir string) error {
+	path := filepath.Join(outputDir, FailedFileName)
+	if len(f.Downloads) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove failed downloads: %w", err)
+		}
+		return nil
+	}
+
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal failed downloads: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write failed downloads: %w", err)
+	}
+	return nil
+}
+
+// Add records a failed download, replacing any earlier record for the same
+// shortcode
+func (f *FailedDownloads) Add(download FailedDownload) {
+	for i := range f.Downloads {
+		if f.Downloads[i].Shortcode == download.Shortcode {
+			f.Downloads[i] = download
+			return
+		}
+	}
+	f.Downloads = append(f.Downloads, download)
+}
+
+// Remove drops the record for shortcode, reporting whether there was one
+func (f *FailedDownloads) Remove(shortcode string) bool {
+	for i := range f.Downloads {
+		if f.Downloads[i].Shortcode == shortcode {
+			f.Downloads = append(f.Downloads[:i], f.Downloads[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -2,11 +2,14 @@ package metadata
 
 import (
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
 	"time"
 
+	"igscraper/pkg/errors"
 	"igscraper/pkg/instagram"
 )
 
@@ -21,12 +24,59 @@ type UserMetadata struct {
 	
 	// Download information
 	DownloadStarted   time.Time `json:"download_started"`
+	// DownloadCompleted is when a run last finished going through every
+	// post, zero if none did; interrupted runs leave it unchanged
 	DownloadCompleted time.Time `json:"download_completed"`
+	// SyncedPosts is the profile's post count when DownloadCompleted was
+	// recorded, counting the posts the run skipped too
+	SyncedPosts       int       `json:"synced_posts,omitempty"`
 	TotalPhotos       int       `json:"total_photos"`
 	DownloadedPhotos  int       `json:"downloaded_photos"`
 	
+	// ID of the scrape that last wrote the file, as logged in its scrape_id
+	// field
+	ScrapeID string `json:"scrape_id,omitempty"`
+	
+	// Pattern the photo files are named after, such as "{shortcode}.{ext}"
+	FileNamePattern string `json:"file_name_pattern,omitempty"`
+	
+	// Location the photos are geotagged at, for location archives
+	Location *Location `json:"location,omitempty"`
+	
 	// Photos array
 	Photos []PhotoMetadata `json:"photos"`
+	
+	// Posts that exist in the timeline but could not be downloaded
+	Failures []PostFailure `json:"failures,omitempty"`
+
+	// photoIndex maps the shortcodes of the first indexed photos to their
+	// position in Photos, so AddPhoto finds earlier records without a scan
+	photoIndex map[string]int
+	indexed    int
+}
+
+// FailureReason classifies why a post permanently failed to download
+type FailureReason string
+
+const (
+	// FailureNotFound means the post or its media was deleted
+	FailureNotFound FailureReason = "not_found"
+	
+	// FailureBlocked means the media is geo-blocked or otherwise restricted
+	FailureBlocked FailureReason = "blocked"
+)
+
+// PostFailure records a post that was listed in the timeline but whose media
+// could not be fetched, so it is not mistaken for a post that never existed
+type PostFailure struct {
+	ID         string        `json:"id,omitempty"`
+	Shortcode  string        `json:"shortcode"`
+	URL        string        `json:"url,omitempty"`
+	TakenAt    *time.Time    `json:"taken_at,omitempty"`
+	Reason     FailureReason `json:"reason"`
+	StatusCode int           `json:"status_code,omitempty"`
+	Message    string        `json:"message"`
+	FailedAt   time.Time     `json:"failed_at"`
 }
 
 // PhotoMetadata represents all metadata for a downloaded photo
@@ -36,11 +86,17 @@ type PhotoMetadata struct {
 	Shortcode string `json:"shortcode"`
 	URL       string `json:"url"`
 	
+	// Name of the photo's file in the archive folder
+	File string `json:"file,omitempty"`
+	
 	// Media properties
 	Width      int    `json:"width"`
 	Height     int    `json:"height"`
 	IsVideo    bool   `json:"is_video"`
 	FileSize   int64  `json:"file_size,omitempty"`
+	SHA256     string `json:"sha256,omitempty"` // hex SHA-256 of the file as saved
+	Resolution string `json:"resolution,omitempty"` // size of the rendition downloaded, such as "1080x1350"
+	Index      int    `json:"index,omitempty"`      // position in a carousel post, from 1
 	
 	// Timestamps
 	TakenAt     time.Time `json:"taken_at"`
@@ -55,6 +111,11 @@ type PhotoMetadata struct {
 	LikesCount    int `json:"likes_count"`
 	CommentsCount int `json:"comments_count"`
 	VideoViews    int `json:"video_views,omitempty"`
+	VideoPlays    int `json:"play_count,omitempty"`
+	
+	// Reels
+	AudioTitle string `json:"audio_title,omitempty"`
+	Thumbnail  string `json:"thumbnail,omitempty"` // cover image, relative to the archive folder
 	
 	// People
 	Owner       Owner        `json:"owner"`
@@ -62,19 +123,41 @@ type PhotoMetadata struct {
 	
 	// Settings
 	CommentsDisabled bool `json:"comments_disabled"`
+	Pinned           bool `json:"pinned,omitempty"` // pinned to the top of the grid it was listed from
+	
+	// Saved-post collections the photo belongs to, when archived from saved posts
+	Collections []string `json:"collections,omitempty"`
+	
+	// Path of an earlier download with identical content, relative to this
+	// photo's folder, when the photo was deduplicated
+	DuplicateOf string `json:"duplicate_of,omitempty"`
+	
+	// Endpoint family the post was listed by, such as "graphql" or
+	// "mobile_api"
+	Source string `json:"source,omitempty"`
+	
+	// IDs the photo's scrape and the request that listed the post were
+	// logged under
+	ScrapeID  string `json:"scrape_id,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
 }
 
 // Location represents geographic location
 type Location struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
-	Slug string `json:"slug"`
+	ID      string  `json:"id"`
+	Name    string  `json:"name"`
+	Slug    string  `json:"slug"`
+	Address string  `json:"address,omitempty"`
+	City    string  `json:"city,omitempty"`
+	Lat     float64 `json:"lat,omitempty"`
+	Lng     float64 `json:"lng,omitempty"`
 }
 
 // Owner represents the media owner
 type Owner struct {
 	ID       string `json:"id"`
 	Username string `json:"username"`
+	FullName string `json:"full_name,omitempty"`
 }
 
 // TaggedUser represents a tagged user with position
@@ -105,9 +188,18 @@ func FromInstagramNode(node *instagram.Node, fileSize int64) *PhotoMetadata {
 		Owner: Owner{
 			ID:       node.Owner.ID,
 			Username: node.Owner.Username,
+			FullName: node.Owner.FullName,
 		},
 	}
 
+	meta.Index = node.CarouselIndex
+	meta.Pinned = node.Pinned()
+	meta.Source = node.Source
+	meta.RequestID = node.RequestID
+	if node.Rendition != nil {
+		meta.Resolution = fmt.Sprintf("%dx%d", node.Rendition.Width, node.Rendition.Height)
+	}
+
 	// Extract caption
 	if len(node.EdgeMediaToCaption.Edges) > 0 {
 		meta.Caption = node.EdgeMediaToCaption.Edges[0].Node.Text
@@ -116,9 +208,13 @@ func FromInstagramNode(node *instagram.Node, fileSize int64) *PhotoMetadata {
 	// Extract location
 	if node.Location != nil {
 		meta.Location = &Location{
-			ID:   node.Location.ID,
-			Name: node.Location.Name,
-			Slug: node.Location.Slug,
+			ID:      node.Location.ID,
+			Name:    node.Location.Name,
+			Slug:    node.Location.Slug,
+			Address: node.Location.Address,
+			City:    node.Location.City,
+			Lat:     node.Location.Lat,
+			Lng:     node.Location.Lng,
 		}
 	}
 
@@ -126,6 +222,10 @@ func FromInstagramNode(node *instagram.Node, fileSize int64) *PhotoMetadata {
 	if node.VideoViewCount != nil {
 		meta.VideoViews = *node.VideoViewCount
 	}
+	if node.VideoPlayCount != nil {
+		meta.VideoPlays = *node.VideoPlayCount
+	}
+	meta.AudioTitle = node.AudioTitle
 
 	// Extract tagged users
 	for _, edge := range node.EdgeMediaToTaggedUser.Edges {
@@ -141,12 +241,63 @@ func FromInstagramNode(node *instagram.Node, fileSize int64) *PhotoMetadata {
 	return meta
 }
 
+// ClassifyFailure returns the reason for a download error that will not go away
+// on retry. Transient errors (network, rate limits, server errors) report false.
+func ClassifyFailure(err error) (FailureReason, bool) {
+	var apiErr *errors.Error
+	if !stderrors.As(err, &apiErr) {
+		return "", false
+	}
+	
+	switch {
+	case apiErr.Type == errors.ErrorTypeNotFound,
+		apiErr.Code == http.StatusNotFound,
+		apiErr.Code == http.StatusGone:
+		return FailureNotFound, true
+	case apiErr.Code == http.StatusForbidden,
+		apiErr.Code == http.StatusUnavailableForLegalReasons:
+		return FailureBlocked, true
+	default:
+		return "", false
+	}
+}
+
+// NewPostFailure builds a failure record for a post from its download error.
+// It returns nil when the error is not permanent.
+func NewPostFailure(shortcode string, node *instagram.Node, err error) *PostFailure {
+	reason, permanent := ClassifyFailure(err)
+	if !permanent {
+		return nil
+	}
+	
+	failure := &PostFailure{
+		Shortcode: shortcode,
+		Reason:    reason,
+		Message:   err.Error(),
+		FailedAt:  time.Now(),
+	}
+	
+	var apiErr *errors.Error
+	if stderrors.As(err, &apiErr) {
+		failure.StatusCode = apiErr.Code
+	}
+	
+	if node != nil {
+		failure.ID = node.ID
+		failure.URL = node.DisplayURL
+		if node.TakenAtTimestamp > 0 {
+			takenAt := time.Unix(node.TakenAtTimestamp, 0)
+			failure.TakenAt = &takenAt
+		}
+	}
+	
+	return failure
+}
+
 // Save writes the user metadata to a JSON file in the output directory
 func (m *UserMetadata) Save(outputDir string) error {
 	metadataPath := filepath.Join(outputDir, "metadata.json")
 	
-	// Update completion time
-	m.DownloadCompleted = time.Now()
 	m.DownloadedPhotos = len(m.Photos)
 	
 	data, err := json.MarshalIndent(m, "", "  ")
@@ -154,16 +305,84 @@ func (m *UserMetadata) Save(outputDir string) error {
 		return fmt.Errorf("failed to marshal metadata: %w", err)
 	}
 
-	if err := os.WriteFile(metadataPath, data, 0644); err != nil {
+	// Written aside and renamed into place, so a crash never leaves the
+	// file half written
+	tempPath := metadataPath + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write metadata file: %w", err)
+	}
+	if err := os.Rename(tempPath, metadataPath); err != nil {
+		os.Remove(tempPath)
 		return fmt.Errorf("failed to write metadata file: %w", err)
 	}
 
 	return nil
 }
 
-// AddPhoto adds a photo to the user metadata
+// AddPhoto adds a photo to the user metadata, replacing an earlier record of
+// the same shortcode, such as that of a post downloaded again. A failure
+// recorded for the shortcode is dropped, the photo having been saved since.
 func (m *UserMetadata) AddPhoto(photo PhotoMetadata) {
+	for i := range m.Failures {
+		if m.Failures[i].Shortcode == photo.Shortcode {
+			m.Failures = append(m.Failures[:i], m.Failures[i+1:]...)
+			break
+		}
+	}
+	m.indexPhotos()
+	if i, ok := m.photoIndex[photo.Shortcode]; ok {
+		m.Photos[i] = photo
+		return
+	}
+	m.photoIndex[photo.Shortcode] = len(m.Photos)
 	m.Photos = append(m.Photos, photo)
+	m.indexed = len(m.Photos)
+}
+
+// indexPhotos brings photoIndex up to date with Photos. Photos appended
+// directly are indexed on top; the index is rebuilt when Photos was replaced.
+// Of photos recorded twice, the last is the one replaced.
+func (m *UserMetadata) indexPhotos() {
+	stale := m.photoIndex == nil || m.indexed > len(m.Photos)
+	if !stale && m.indexed > 0 {
+		last := m.indexed - 1
+		i, ok := m.photoIndex[m.Photos[last].Shortcode]
+		stale = !ok || i != last
+	}
+	if stale {
+		m.photoIndex = make(map[string]int, len(m.Photos))
+		m.indexed = 0
+	}
+	for ; m.indexed < len(m.Photos); m.indexed++ {
+		m.photoIndex[m.Photos[m.indexed].Shortcode] = m.indexed
+	}
+}
+
+// AddFailure records a permanently failed post, replacing any earlier record
+// for the same shortcode
+func (m *UserMetadata) AddFailure(failure PostFailure) {
+	for i := range m.Failures {
+		if m.Failures[i].Shortcode == failure.Shortcode {
+			m.Failures[i] = failure
+			return
+		}
+	}
+	m.Failures = append(m.Failures, failure)
+}
+
+// SetCollections records which saved-post collections each photo belongs to,
+// keyed by shortcode. It reports whether any photo changed.
+func (m *UserMetadata) SetCollections(membership map[string][]string) bool {
+	changed := false
+	for i := range m.Photos {
+		collections, ok := membership[m.Photos[i].Shortcode]
+		if !ok {
+			continue
+		}
+		m.Photos[i].Collections = append([]string(nil), collections...)
+		changed = true
+	}
+	return changed
 }
 
 // Save writes the metadata to a JSON file (deprecated - for individual photos)
@@ -189,6 +408,7 @@ func LoadUserMetadata(outputDir string) (*UserMetadata, error) {
 	if err := json.Unmarshal(data, &meta); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
 	}
+	meta.indexPhotos()
 
 	return &meta, nil
 }
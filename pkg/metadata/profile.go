@@ -0,0 +1,66 @@
+package metadata
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ProfileFile is the name of the profile snapshot in a profile's output
+// directory
+const ProfileFile = "profile.json"
+
+// Profile is a snapshot of a profile's public details, taken when the
+// profile is scraped
+type Profile struct {
+	UserID      string `json:"user_id"`
+	Username    string `json:"username"`
+	FullName    string `json:"full_name,omitempty"`
+	Biography   string `json:"biography,omitempty"`
+	ExternalURL string `json:"external_url,omitempty"`
+	IsPrivate   bool   `json:"is_private"`
+	IsVerified  bool   `json:"is_verified"`
+
+	Followers int `json:"followers"`
+	Following int `json:"following"`
+	Posts     int `json:"posts"`
+
+	// ProfilePicURL is where the picture was downloaded from and
+	// ProfilePic the saved file, relative to the output directory
+	ProfilePicURL string `json:"profile_pic_url,omitempty"`
+	ProfilePic    string `json:"profile_pic,omitempty"`
+
+	SnapshotAt time.Time `json:"snapshot_at"`
+}
+
+// Save writes the snapshot to profile.json in the output directory
+func (p *Profile) Save(outputDir string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal profile: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(outputDir, ProfileFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to write profile file: %w", err)
+	}
+	return nil
+}
+
+// LoadProfile reads the snapshot in profile.json. It returns nil if the
+// profile has not been snapshotted.
+func LoadProfile(outputDir string) (*Profile, error) {
+	data, err := os.ReadFile(filepath.Join(outputDir, ProfileFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read profile file: %w", err)
+	}
+
+	var profile Profile
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal profile: %w", err)
+	}
+	return &profile, nil
+}
@@ -0,0 +1,166 @@
+package notify
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// appName is the application notifications are shown under
+const appName = "igscraper"
+
+// NotificationSender interface for platform-specific notification implementations
+type NotificationSender interface {
+	Send(title, message string) error
+}
+
+// LinuxNotificationSender sends notifications on Linux and the BSDs using
+// notify-send
+type LinuxNotificationSender struct{}
+
+func (l *LinuxNotificationSender) Send(title, message string) error {
+	cmd := exec.Command("notify-send", "--app-name="+appName, "--", title, message)
+	return cmd.Run()
+}
+
+// DBusNotificationSender sends notifications over D-Bus with gdbus, for
+// desktops without notify-send
+type DBusNotificationSender struct{}
+
+func (d *DBusNotificationSender) Send(title, message string) error {
+	cmd := exec.Command("gdbus", "call", "--session",
+		"--dest", "org.freedesktop.Notifications",
+		"--object-path", "/org/freedesktop/Notifications",
+		"--method", "org.freedesktop.Notifications.Notify",
+		gvariantString(appName), "0", gvariantString(""),
+		gvariantString(title), gvariantString(message),
+		"@as []", "@a{sv} {}", "5000")
+	return cmd.Run()
+}
+
+// gvariantString quotes s as a string in the GVariant text format gdbus
+// parses its arguments with
+func gvariantString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\n", `\n`).Replace(s) + "'"
+}
+
+// MacOSNotificationSender sends notifications on macOS using osascript
+type MacOSNotificationSender struct{}
+
+func (m *MacOSNotificationSender) Send(title, message string) error {
+	// The text is passed as arguments rather than spliced into the script,
+	// so quotes in a caption or error cannot break it
+	cmd := exec.Command("osascript",
+		"-e", "on run argv",
+		"-e", "display notification (item 2 of argv) with title (item 1 of argv)",
+		"-e", "end run",
+		title, message)
+	return cmd.Run()
+}
+
+// windowsAppID is the application ID of PowerShell, which Windows lets show
+// toasts without registering an application of our own
+const windowsAppID = `{1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}\WindowsPowerShell\v1.0\powershell.exe`
+
+// windowsToastScript shows a toast with the title and message from the
+// environment, escaped for the toast XML
+const windowsToastScript = `
+	[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null
+	[Windows.Data.Xml.Dom.XmlDocument, Windows.Data.Xml.Dom.XmlDocument, ContentType = WindowsRuntime] | Out-Null
+	$title = [Security.SecurityElement]::Escape($env:IGSCRAPER_NOTIFY_TITLE)
+	$message = [Security.SecurityElement]::Escape($env:IGSCRAPER_NOTIFY_MESSAGE)
+	$doc = [Windows.Data.Xml.Dom.XmlDocument]::new()
+	$xml = '<toast><visual><binding template="ToastText02"><text id="1">{0}</text><text id="2">{1}</text></binding></visual></toast>' -f $title, $message
+	$doc.LoadXml($xml)
+	$toast = [Windows.UI.Notifications.ToastNotification]::new($doc)
+	[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier($env:IGSCRAPER_NOTIFY_APP).Show($toast)
+`
+
+// WindowsNotificationSender sends notifications on Windows as toasts using
+// PowerShell
+type WindowsNotificationSender struct{}
+
+func (w *WindowsNotificationSender) Send(title, message string) error {
+	cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", windowsToastScript)
+	cmd.Env = append(os.Environ(),
+		"IGSCRAPER_NOTIFY_TITLE="+title,
+		"IGSCRAPER_NOTIFY_MESSAGE="+message,
+		"IGSCRAPER_NOTIFY_APP="+windowsAppID,
+	)
+	return cmd.Run()
+}
+
+// detectSender picks the notification mechanism for goos, or returns nil if
+// there is none, such as on a server without a desktop session
+func detectSender(goos string, lookPath func(string) (string, error), getenv func(string) string) NotificationSender {
+	has := func(name string) bool {
+		_, err := lookPath(name)
+		return err == nil
+	}
+
+	switch goos {
+	case "darwin":
+		if has("osascript") {
+			return &MacOSNotificationSender{}
+		}
+	case "windows":
+		if has("powershell") {
+			return &WindowsNotificationSender{}
+		}
+	case "linux", "freebsd", "openbsd", "netbsd", "dragonfly":
+		if getenv("DBUS_SESSION_BUS_ADDRESS") == "" && getenv("DISPLAY") == "" && getenv("WAYLAND_DISPLAY") == "" {
+			return nil
+		}
+		if has("notify-send") {
+			return &LinuxNotificationSender{}
+		}
+		if has("gdbus") {
+			return &DBusNotificationSender{}
+		}
+	}
+	return nil
+}
+
+// Desktop shows events as native desktop notifications. When the platform
+// has none, or showing one fails, events are printed in the terminal instead.
+type Desktop struct {
+	mu       sync.Mutex
+	sender   NotificationSender
+	fallback Notifier
+}
+
+// NewDesktop creates a Desktop notifier using the notification mechanism of
+// the current platform
+func NewDesktop() *Desktop {
+	return &Desktop{
+		sender:   detectSender(runtime.GOOS, exec.LookPath, os.Getenv),
+		fallback: NewTerminal(),
+	}
+}
+
+// Notify shows the event as a desktop notification. After the first failure
+// every event goes to the terminal, so a broken notification daemon is only
+// reported once.
+func (d *Desktop) Notify(event Event) error {
+	d.mu.Lock()
+	sender := d.sender
+	d.mu.Unlock()
+
+	if sender != nil {
+		err := sender.Send(event.Title, event.Message)
+		if err == nil {
+			return nil
+		}
+		d.mu.Lock()
+		d.sender = nil
+		d.mu.Unlock()
+		if fallbackErr := d.fallback.Notify(event); fallbackErr != nil {
+			return fallbackErr
+		}
+		return fmt.Errorf("desktop notification failed, showing notifications in the terminal: %w", err)
+	}
+	return d.fallback.Notify(event)
+}
@@ -0,0 +1,33 @@
+// Package notify tells the user about events of a scrape, such as a finished
+// scrape, a failure or a rate-limit cooldown, through desktop notifications
+// and email.
+//
+// Each channel is a Notifier. A Dispatcher built from the notification
+// settings sends every event to the enabled channels, leaving out the kinds
+// the user turned off with on_complete, on_error and on_rate_limit:
+//
+//	notifier := notify.New(cfg.Notifications, log)
+//	notifier.Send(notify.Event{
+//	    Kind:    notify.KindComplete,
+//	    Title:   "Scrape complete",
+//	    Message: "johndoe: 42 photos downloaded",
+//	})
+//
+// Desktop notifications use notify-send or D-Bus on Linux and the BSDs,
+// osascript on macOS and toasts on Windows, picked when the Dispatcher is
+// created. Where there is no desktop session, or showing a notification
+// fails, they are printed in the terminal instead.
+//
+// Email goes through an SMTP server, which lets unattended runs on a server
+// report back:
+//
+//	notifications:
+//	  email:
+//	    enabled: true
+//	    host: smtp.example.com
+//	    port: 587
+//	    username: igscraper@example.com
+//	    password: ""          # or IGSCRAPER_SMTP_PASSWORD
+//	    from: igscraper@example.com
+//	    to: [me@example.com]
+package notify
@@ -0,0 +1,100 @@
+package notify
+
+import (
+	"strings"
+
+	"igscraper/pkg/config"
+	"igscraper/pkg/logger"
+)
+
+// Kind says what an event is about, so it can be filtered by the
+// notification settings
+type Kind string
+
+// Event kinds
+const (
+	KindInfo      Kind = "info"
+	KindComplete  Kind = "complete"
+	KindError     Kind = "error"
+	KindRateLimit Kind = "rate_limit"
+)
+
+// Event is something the user is told about
+type Event struct {
+	Kind    Kind
+	Title   string
+	Message string
+}
+
+// Notifier delivers events to the user by one channel
+type Notifier interface {
+	Notify(event Event) error
+}
+
+// Dispatcher sends events to every notifier the settings enable, leaving out
+// kinds the user turned off. Notifications are not critical, so failures are
+// logged and otherwise ignored.
+type Dispatcher struct {
+	cfg       config.NotificationConfig
+	notifiers []Notifier
+	logger    logger.Logger
+}
+
+// New creates a Dispatcher for the notification settings. notification_type
+// "desktop" shows native notifications, falling back to the terminal where
+// there are none, "terminal" only prints them and "none" shows nothing. Email
+// is sent when it is enabled. Nothing is sent when notifications are
+// disabled.
+func New(cfg config.NotificationConfig, log logger.Logger) *Dispatcher {
+	if log == nil {
+		log = logger.Named(logger.ComponentNotify)
+	}
+	d := &Dispatcher{cfg: cfg, logger: log}
+	if !cfg.Enabled {
+		return d
+	}
+	switch strings.ToLower(cfg.NotificationType) {
+	case "none":
+	case "terminal":
+		d.Add(NewTerminal())
+	default:
+		d.Add(NewDesktop())
+	}
+	if cfg.Email.Enabled {
+		d.Add(NewEmail(cfg.Email))
+	}
+	return d
+}
+
+// Add sends events to n as well
+func (d *Dispatcher) Add(n Notifier) {
+	d.notifiers = append(d.notifiers, n)
+}
+
+// Send delivers the event to every notifier, unless the settings turn its
+// kind off
+func (d *Dispatcher) Send(event Event) {
+	if d == nil || !d.wants(event.Kind) {
+		return
+	}
+	for _, n := range d.notifiers {
+		if err := n.Notify(event); err != nil {
+			d.logger.WithError(err).WarnWithFields("Failed to send notification", map[string]interface{}{
+				"title": event.Title,
+			})
+		}
+	}
+}
+
+// wants returns true if events of kind are sent
+func (d *Dispatcher) wants(kind Kind) bool {
+	switch kind {
+	case KindComplete:
+		return d.cfg.OnComplete
+	case KindError:
+		return d.cfg.OnError
+	case KindRateLimit:
+		return d.cfg.OnRateLimit
+	}
+	return true
+}
@@ -0,0 +1,203 @@
+package notify
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"igscraper/pkg/config"
+	"igscraper/pkg/logger"
+)
+
+// recorder is a Notifier that keeps the events it is sent
+type recorder struct {
+	events []Event
+	err    error
+}
+
+func (r *recorder) Notify(event Event) error {
+	r.events = append(r.events, event)
+	return r.err
+}
+
+func TestDispatcher(t *testing.T) {
+	cfg := config.NotificationConfig{Enabled: true, NotificationType: "none", OnError: true}
+	d := New(cfg, logger.NewTestLogger())
+	assert.Empty(t, d.notifiers, "notification type none shows no desktop notifications")
+
+	r := &recorder{}
+	failing := &recorder{err: errors.New("unreachable")}
+	d.Add(failing)
+	d.Add(r)
+
+	d.Send(Event{Kind: KindComplete, Title: "SCRAPE COMPLETE"})
+	d.Send(Event{Kind: KindRateLimit, Title: "RATE LIMIT"})
+	d.Send(Event{Kind: KindError, Title: "SCRAPE FAILED"})
+	d.Send(Event{Kind: KindInfo, Title: "INFO"})
+
+	require.Len(t, r.events, 2, "kinds turned off are left out")
+	assert.Equal(t, "SCRAPE FAILED", r.events[0].Title)
+	assert.Equal(t, "INFO", r.events[1].Title)
+	assert.Len(t, failing.events, 2, "a failing notifier does not stop the others")
+
+	cfg.Enabled = false
+	cfg.NotificationType = "desktop"
+	cfg.Email = config.EmailConfig{Enabled: true, Host: "localhost", Port: 25, From: "a@example.com", To: []string{"b@example.com"}}
+	assert.Empty(t, New(cfg, logger.NewTestLogger()).notifiers, "nothing is sent when notifications are disabled")
+
+	var nilDispatcher *Dispatcher
+	nilDispatcher.Send(Event{Kind: KindInfo})
+}
+
+// fakeSMTP accepts one message on a loopback port and returns the port and a
+// channel that receives the message data
+func fakeSMTP(t *testing.T) (int, <-chan string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(line string) { fmt.Fprintf(conn, "%s\r\n", line) }
+
+		reply("220 localhost ESMTP")
+		var data strings.Builder
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(cmd, "EHLO"):
+				reply("250 localhost")
+			case strings.HasPrefix(cmd, "DATA"):
+				reply("354 go ahead")
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				received <- data.String()
+				reply("250 queued")
+			case strings.HasPrefix(cmd, "QUIT"):
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+
+	return listener.Addr().(*net.TCPAddr).Port, received
+}
+
+func TestEmail(t *testing.T) {
+	port, received := fakeSMTP(t)
+	email := NewEmail(config.EmailConfig{
+		Enabled: true,
+		Host:    "127.0.0.1",
+		Port:    port,
+		From:    "igscraper@example.com",
+		To:      []string{"me@example.com", "you@example.com"},
+	})
+	email.now = func() time.Time { return time.Date(2024, 3, 15, 18, 30, 0, 0, time.UTC) }
+
+	err := email.Notify(Event{Kind: KindError, Title: "SCRAPE FAILED\r\nBcc: evil@example.com", Message: "johndoe: rate limited"})
+	require.NoError(t, err)
+
+	select {
+	case msg := <-received:
+		assert.Contains(t, msg, "To: me@example.com, you@example.com\r\n")
+		assert.Contains(t, msg, "Subject: igscraper: SCRAPE FAILED  Bcc: evil@example.com\r\n")
+		assert.NotContains(t, msg, "\r\nBcc:")
+		assert.Contains(t, msg, "\r\n\r\njohndoe: rate limited\r\n")
+		assert.Contains(t, msg, "Time: 2024-03-15T18:30:00Z")
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+	}
+}
+
+func TestDetectSender(t *testing.T) {
+	lookPath := func(found ...string) func(string) (string, error) {
+		return func(name string) (string, error) {
+			for _, f := range found {
+				if f == name {
+					return "/usr/bin/" + name, nil
+				}
+			}
+			return "", errors.New("not found")
+		}
+	}
+	desktopEnv := func(key string) string {
+		if key == "DBUS_SESSION_BUS_ADDRESS" {
+			return "unix:path=/run/user/1000/bus"
+		}
+		return ""
+	}
+	noEnv := func(string) string { return "" }
+
+	assert.IsType(t, &LinuxNotificationSender{}, detectSender("linux", lookPath("notify-send", "gdbus"), desktopEnv))
+	assert.IsType(t, &DBusNotificationSender{}, detectSender("freebsd", lookPath("gdbus"), desktopEnv))
+	assert.Nil(t, detectSender("linux", lookPath("notify-send"), noEnv), "a server without a desktop session has no notifications")
+	assert.Nil(t, detectSender("linux", lookPath(), desktopEnv))
+	assert.IsType(t, &MacOSNotificationSender{}, detectSender("darwin", lookPath("osascript"), noEnv))
+	assert.IsType(t, &WindowsNotificationSender{}, detectSender("windows", lookPath("powershell"), noEnv))
+	assert.Nil(t, detectSender("plan9", lookPath("notify-send"), desktopEnv))
+
+	assert.Equal(t, `'it\'s \\ done\nnow'`, gvariantString("it's \\ done\nnow"))
+}
+
+// failingSender is a NotificationSender that fails every time
+type failingSender struct{ calls int }
+
+func (f *failingSender) Send(title, message string) error {
+	f.calls++
+	return errors.New("no notification daemon")
+}
+
+func TestDesktopFallback(t *testing.T) {
+	sender := &failingSender{}
+	fallback := &recorder{}
+	d := &Desktop{sender: sender, fallback: fallback}
+
+	err := d.Notify(Event{Kind: KindComplete, Title: "SCRAPE COMPLETE"})
+	assert.ErrorContains(t, err, "no notification daemon", "the first failure is reported")
+	assert.NoError(t, d.Notify(Event{Kind: KindError, Title: "SCRAPE FAILED"}))
+
+	assert.Equal(t, 1, sender.calls, "a failing sender is not tried again")
+	require.Len(t, fallback.events, 2)
+	assert.Equal(t, "SCRAPE FAILED", fallback.events[1].Title)
+}
+
+func TestTerminal(t *testing.T) {
+	var out strings.Builder
+	terminal := &Terminal{out: &out}
+
+	require.NoError(t, terminal.Notify(Event{Kind: KindComplete, Title: "SCRAPE COMPLETE", Message: "johndoe: 42 photos downloaded"}))
+	assert.True(t, strings.HasPrefix(out.String(), "\a"), "the bell is rung")
+	assert.Contains(t, out.String(), "SCRAPE COMPLETE")
+	assert.Contains(t, out.String(), "johndoe: 42 photos downloaded")
+
+	d := New(config.NotificationConfig{Enabled: true, NotificationType: "terminal"}, logger.NewTestLogger())
+	require.Len(t, d.notifiers, 1)
+	assert.IsType(t, &Terminal{}, d.notifiers[0])
+}
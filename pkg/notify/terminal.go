@@ -0,0 +1,41 @@
+package notify
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"igscraper/pkg/ui"
+)
+
+// Terminal prints events in the terminal, ringing the bell so they are
+// noticed in another window. It writes to stderr, keeping stdout for the
+// progress output; with --output-format json events are emitted as
+// "notification" lines on stdout instead.
+type Terminal struct {
+	out io.Writer
+}
+
+// NewTerminal creates a Terminal notifier
+func NewTerminal() *Terminal {
+	return &Terminal{out: os.Stderr}
+}
+
+// Notify prints the event
+func (t *Terminal) Notify(event Event) error {
+	if ui.IsJSONOutput() {
+		ui.EmitEvent("notification", map[string]interface{}{
+			"kind":    string(event.Kind),
+			"title":   event.Title,
+			"message": event.Message,
+		})
+		return nil
+	}
+
+	title := ui.Cyan(event.Title)
+	if event.Kind == KindError {
+		title = ui.Red(event.Title)
+	}
+	_, err := fmt.Fprintf(t.out, "\a\n%s: %s\n", title, ui.Yellow(event.Message))
+	return err
+}
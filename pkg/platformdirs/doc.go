@@ -0,0 +1,23 @@
+// Package platformdirs locates the directories igscraper keeps its files in,
+// following the conventions of each operating system.
+//
+// On Linux and the BSDs they follow the XDG Base Directory specification:
+//   - Config: $XDG_CONFIG_HOME/igscraper, or ~/.config/igscraper
+//   - Data: $XDG_DATA_HOME/igscraper, or ~/.local/share/igscraper
+//   - Cache: $XDG_CACHE_HOME/igscraper, or ~/.cache/igscraper
+//   - Logs: $XDG_STATE_HOME/igscraper, or ~/.local/state/igscraper
+//
+// On macOS:
+//   - Config and data: ~/Library/Application Support/igscraper
+//   - Cache: ~/Library/Caches/igscraper
+//   - Logs: ~/Library/Logs/igscraper
+//
+// On Windows, where the variables default to the AppData folders of the
+// user's profile:
+//   - Config and data: %APPDATA%\igscraper
+//   - Cache: %LOCALAPPDATA%\igscraper\Cache
+//   - Logs: %LOCALAPPDATA%\igscraper\Logs
+//
+// The directories are returned without being created; callers create the
+// ones they write to, with the permissions their files need.
+package platformdirs
@@ -0,0 +1,155 @@
+package platformdirs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// AppName is the name of igscraper's directory in each location
+const AppName = "igscraper"
+
+// env is what the directories are worked out from, so that those of every
+// platform can be tested on any of them
+type env struct {
+	goos   string
+	home   string // "" if unknown
+	getenv func(string) string
+}
+
+// current returns the environment of the running process
+func current() env {
+	home, _ := os.UserHomeDir()
+	return env{goos: runtime.GOOS, home: home, getenv: os.Getenv}
+}
+
+// ConfigDir returns the directory of the config file and stored credentials
+func ConfigDir() (string, error) {
+	return current().configDir()
+}
+
+// DataDir returns the directory of checkpoints and other state kept between
+// runs
+func DataDir() (string, error) {
+	return current().dataDir()
+}
+
+// CacheDir returns the directory of files that can be fetched again, such as
+// cached responses
+func CacheDir() (string, error) {
+	return current().cacheDir()
+}
+
+// LogDir returns the directory log files are written to when the settings
+// name them without a directory
+func LogDir() (string, error) {
+	return current().logDir()
+}
+
+// Home returns the user's home directory
+func Home() (string, error) {
+	return current().homeDir()
+}
+
+// ExpandHome replaces a leading ~ in path with the user's home directory.
+// Other paths, and paths when the home directory is unknown, are returned
+// unchanged.
+func ExpandHome(path string) string {
+	return current().expandHome(path)
+}
+
+func (e env) configDir() (string, error) {
+	switch e.goos {
+	case "darwin":
+		return e.inHome("Library", "Application Support", AppName)
+	case "windows":
+		return e.windowsDir("APPDATA", []string{"AppData", "Roaming"}, AppName)
+	default:
+		return e.xdgDir("XDG_CONFIG_HOME", []string{".config"}, AppName)
+	}
+}
+
+func (e env) dataDir() (string, error) {
+	switch e.goos {
+	case "darwin":
+		return e.inHome("Library", "Application Support", AppName)
+	case "windows":
+		return e.windowsDir("APPDATA", []string{"AppData", "Roaming"}, AppName)
+	default:
+		return e.xdgDir("XDG_DATA_HOME", []string{".local", "share"}, AppName)
+	}
+}
+
+func (e env) cacheDir() (string, error) {
+	switch e.goos {
+	case "darwin":
+		return e.inHome("Library", "Caches", AppName)
+	case "windows":
+		return e.windowsDir("LOCALAPPDATA", []string{"AppData", "Local"}, AppName, "Cache")
+	default:
+		return e.xdgDir("XDG_CACHE_HOME", []string{".cache"}, AppName)
+	}
+}
+
+func (e env) logDir() (string, error) {
+	switch e.goos {
+	case "darwin":
+		return e.inHome("Library", "Logs", AppName)
+	case "windows":
+		return e.windowsDir("LOCALAPPDATA", []string{"AppData", "Local"}, AppName, "Logs")
+	default:
+		return e.xdgDir("XDG_STATE_HOME", []string{".local", "state"}, AppName)
+	}
+}
+
+func (e env) homeDir() (string, error) {
+	if e.home == "" {
+		return "", errors.New("cannot determine the home directory")
+	}
+	return e.home, nil
+}
+
+func (e env) expandHome(path string) string {
+	if e.home == "" || !strings.HasPrefix(path, "~") {
+		return path
+	}
+	rest := path[1:]
+	if rest == "" {
+		return e.home
+	}
+	// ~user is left alone
+	if rest[0] != '/' && !(e.goos == "windows" && rest[0] == '\\') {
+		return path
+	}
+	return filepath.Join(e.home, rest[1:])
+}
+
+// inHome joins elem to the home directory
+func (e env) inHome(elem ...string) (string, error) {
+	home, err := e.homeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(append([]string{home}, elem...)...), nil
+}
+
+// xdgDir joins elem to the base directory in the XDG variable, or to
+// fallback in the home directory if it is unset. Relative values are
+// ignored, as the XDG Base Directory specification requires.
+func (e env) xdgDir(variable string, fallback []string, elem ...string) (string, error) {
+	if base := e.getenv(variable); base != "" && filepath.IsAbs(base) {
+		return filepath.Join(append([]string{base}, elem...)...), nil
+	}
+	return e.inHome(append(fallback, elem...)...)
+}
+
+// windowsDir joins elem to the folder in the environment variable, or to
+// fallback in the user's profile if it is unset
+func (e env) windowsDir(variable string, fallback []string, elem ...string) (string, error) {
+	if base := e.getenv(variable); base != "" {
+		return filepath.Join(append([]string{base}, elem...)...), nil
+	}
+	return e.inHome(append(fallback, elem...)...)
+}
@@ -0,0 +1,126 @@
+package platformdirs
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func testEnv(goos, home string, vars map[string]string) env {
+	return env{goos: goos, home: home, getenv: func(name string) string { return vars[name] }}
+}
+
+func TestDirectories(t *testing.T) {
+	tests := []struct {
+		name                      string
+		env                       env
+		config, data, cache, logs string
+	}{
+		{
+			name:   "linux defaults",
+			env:    testEnv("linux", "/home/me", nil),
+			config: "/home/me/.config/igscraper",
+			data:   "/home/me/.local/share/igscraper",
+			cache:  "/home/me/.cache/igscraper",
+			logs:   "/home/me/.local/state/igscraper",
+		},
+		{
+			name: "linux XDG variables",
+			env: testEnv("linux", "/home/me", map[string]string{
+				"XDG_CONFIG_HOME": "/xdg/config",
+				"XDG_DATA_HOME":   "/xdg/data",
+				"XDG_CACHE_HOME":  "/xdg/cache",
+				"XDG_STATE_HOME":  "relative/state",
+			}),
+			config: "/xdg/config/igscraper",
+			data:   "/xdg/data/igscraper",
+			cache:  "/xdg/cache/igscraper",
+			logs:   "/home/me/.local/state/igscraper",
+		},
+		{
+			name:   "freebsd",
+			env:    testEnv("freebsd", "/home/me", nil),
+			config: "/home/me/.config/igscraper",
+			data:   "/home/me/.local/share/igscraper",
+			cache:  "/home/me/.cache/igscraper",
+			logs:   "/home/me/.local/state/igscraper",
+		},
+		{
+			name:   "macos",
+			env:    testEnv("darwin", "/Users/me", map[string]string{"XDG_CONFIG_HOME": "/xdg/config"}),
+			config: "/Users/me/Library/Application Support/igscraper",
+			data:   "/Users/me/Library/Application Support/igscraper",
+			cache:  "/Users/me/Library/Caches/igscraper",
+			logs:   "/Users/me/Library/Logs/igscraper",
+		},
+		{
+			name: "windows",
+			env: testEnv("windows", "C:/Users/me", map[string]string{
+				"APPDATA":      "C:/Users/me/AppData/Roaming",
+				"LOCALAPPDATA": "D:/Local",
+			}),
+			config: "C:/Users/me/AppData/Roaming/igscraper",
+			data:   "C:/Users/me/AppData/Roaming/igscraper",
+			cache:  "D:/Local/igscraper/Cache",
+			logs:   "D:/Local/igscraper/Logs",
+		},
+		{
+			name:   "windows without variables",
+			env:    testEnv("windows", "C:/Users/me", nil),
+			config: "C:/Users/me/AppData/Roaming/igscraper",
+			data:   "C:/Users/me/AppData/Roaming/igscraper",
+			cache:  "C:/Users/me/AppData/Local/igscraper/Cache",
+			logs:   "C:/Users/me/AppData/Local/igscraper/Logs",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := func(kind string, get func() (string, error), want string) {
+				got, err := get()
+				if err != nil {
+					t.Fatalf("%s: unexpected error: %v", kind, err)
+				}
+				if got != filepath.FromSlash(want) {
+					t.Errorf("%s: got %q, want %q", kind, got, filepath.FromSlash(want))
+				}
+			}
+			check("config", tt.env.configDir, tt.config)
+			check("data", tt.env.dataDir, tt.data)
+			check("cache", tt.env.cacheDir, tt.cache)
+			check("logs", tt.env.logDir, tt.logs)
+		})
+	}
+}
+
+func TestNoHome(t *testing.T) {
+	e := testEnv("linux", "", nil)
+	if _, err := e.dataDir(); err == nil {
+		t.Error("Expected an error without a home directory")
+	}
+	// A directory from the environment needs no home
+	e = testEnv("linux", "", map[string]string{"XDG_DATA_HOME": "/xdg/data"})
+	if dir, err := e.dataDir(); err != nil || dir != filepath.FromSlash("/xdg/data/igscraper") {
+		t.Errorf("Expected the XDG directory, got %q, %v", dir, err)
+	}
+}
+
+func TestExpandHome(t *testing.T) {
+	e := testEnv("linux", "/home/me", nil)
+	tests := map[string]string{
+		"~":                "/home/me",
+		"~/downloads":      "/home/me/downloads",
+		"~other/downloads": "~other/downloads",
+		"./downloads":      "./downloads",
+		"/srv/~/downloads": "/srv/~/downloads",
+		"":                 "",
+	}
+	for path, want := range tests {
+		if got := e.expandHome(path); got != filepath.FromSlash(want) && got != want {
+			t.Errorf("expandHome(%q) = %q, want %q", path, got, want)
+		}
+	}
+
+	if got := testEnv("linux", "", nil).expandHome("~/downloads"); got != "~/downloads" {
+		t.Errorf("Expected the path unchanged without a home directory, got %q", got)
+	}
+}
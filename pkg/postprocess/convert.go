@@ -0,0 +1,118 @@
+package postprocess
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// encoder describes the external program that writes a format
+type encoder struct {
+	command string
+	ext     string
+	args    func(quality int, in, out string) []string
+}
+
+// encoders are the formats convert can write
+var encoders = map[string]encoder{
+	"webp": {
+		command: "cwebp",
+		ext:     ".webp",
+		args: func(quality int, in, out string) []string {
+			args := []string{"-quiet"}
+			if quality > 0 {
+				args = append(args, "-q", strconv.Itoa(quality))
+			}
+			return append(args, in, "-o", out)
+		},
+	},
+	"avif": {
+		command: "avifenc",
+		ext:     ".avif",
+		args: func(quality int, in, out string) []string {
+			var args []string
+			if quality > 0 {
+				args = append(args, "-q", strconv.Itoa(quality))
+			}
+			return append(args, in, out)
+		},
+	},
+}
+
+// convertStep re-encodes photos with an external encoder
+type convertStep struct {
+	encoder
+	path    string
+	quality int
+}
+
+// newConvertStep finds the encoder for the configured format
+func newConvertStep(c StepConfig) (convertStep, error) {
+	enc := encoders[c.Format]
+	command := c.Command
+	if command == "" {
+		command = enc.command
+	}
+	path, err := exec.LookPath(command)
+	if err != nil {
+		return convertStep{}, fmt.Errorf("converting to %s needs %s: %w", c.Format, command, err)
+	}
+	return convertStep{encoder: enc, path: path, quality: c.Quality}, nil
+}
+
+func (c convertStep) apply(path string) (string, error) {
+	// Encoders may go by the input's extension, which the downloaded file
+	// lacks
+	inExt, err := imageExt(path)
+	if err != nil {
+		return "", err
+	}
+	in, out := path+inExt, path+c.ext
+	if err := os.Rename(path, in); err != nil {
+		return "", err
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(c.path, c.args(c.quality, in, out)...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		os.Remove(out)
+		os.Rename(in, path)
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s failed: %w: %s", c.command, err, msg)
+		}
+		return "", fmt.Errorf("%s failed: %w", c.command, err)
+	}
+	if err := os.Rename(out, path); err != nil {
+		os.Remove(out)
+		os.Rename(in, path)
+		return "", err
+	}
+	os.Remove(in)
+	return c.ext, nil
+}
+
+// imageExt returns the extension of the JPEG or PNG file at path, going by
+// its content
+func imageExt(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	header := make([]byte, len(pngSignature))
+	n, _ := io.ReadFull(f, header)
+	switch {
+	case n >= 2 && header[0] == 0xFF && header[1] == 0xD8:
+		return ".jpg", nil
+	case bytes.HasPrefix(header[:n], pngSignature):
+		return ".png", nil
+	}
+	return "", errors.New("only JPEG and PNG photos can be converted")
+}
@@ -0,0 +1,30 @@
+// Package postprocess transforms downloaded photos before they are saved,
+// through an ordered pipeline of steps:
+//
+//   - resize scales a photo down so its longer side is at most
+//     max_dimension pixels
+//   - strip_metadata removes EXIF, XMP, IPTC and comments from JPEG and PNG
+//     files without re-encoding them
+//   - convert re-encodes the photo as WebP or AVIF with an external encoder,
+//     cwebp or avifenc, which must be installed
+//
+// Steps run in the order they are configured, and convert must come last
+// since the other steps only read JPEG and PNG. Each step replaces the file
+// atomically, so a failed step leaves the result of the steps before it.
+//
+// Usage:
+//
+//	p, err := postprocess.New([]postprocess.StepConfig{
+//	    {Step: postprocess.StepResize, MaxDimension: 1080},
+//	    {Step: postprocess.StepStripMetadata},
+//	    {Step: postprocess.StepConvert, Format: "webp", Quality: 80},
+//	})
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//
+//	ext, err := p.Run("photo.jpg.tmp") // ext is ".webp"
+//
+// Thumbnail writes a small JPEG copy of a saved photo for gallery browsing,
+// outside the pipeline, leaving the photo itself unchanged.
+package postprocess
@@ -0,0 +1,137 @@
+package postprocess
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Step names accepted in StepConfig.Step
+const (
+	StepResize        = "resize"
+	StepStripMetadata = "strip_metadata"
+	StepConvert       = "convert"
+)
+
+// StepConfig configures one step of the pipeline
+type StepConfig struct {
+	Step         string `yaml:"step" json:"step"`                                       // resize, strip_metadata or convert
+	MaxDimension int    `yaml:"max_dimension,omitempty" json:"max_dimension,omitempty"` // resize: longest side in pixels
+	Format       string `yaml:"format,omitempty" json:"format,omitempty"`               // convert: webp or avif
+	Quality      int    `yaml:"quality,omitempty" json:"quality,omitempty"`             // resize and convert: 1-100, 0 for the default
+	Command      string `yaml:"command,omitempty" json:"command,omitempty"`             // convert: encoder binary, cwebp or avifenc if empty
+}
+
+// step transforms the photo at path in place and returns its extension when
+// it changes the file's format
+type step interface {
+	apply(path string) (ext string, err error)
+}
+
+// Pipeline runs the configured steps over downloaded photos. A nil or empty
+// Pipeline leaves photos unchanged.
+type Pipeline struct {
+	steps []step
+	names []string
+}
+
+// Validate checks the step configuration without looking for encoders
+func Validate(configs []StepConfig) error {
+	for i, c := range configs {
+		if c.Quality < 0 || c.Quality > 100 {
+			return fmt.Errorf("postprocess step %d (%s): quality must be between 0 and 100", i+1, c.Step)
+		}
+		switch c.Step {
+		case StepResize:
+			if c.MaxDimension <= 0 {
+				return fmt.Errorf("postprocess step %d (resize): max_dimension must be positive", i+1)
+			}
+		case StepStripMetadata:
+		case StepConvert:
+			if _, ok := encoders[c.Format]; !ok {
+				return fmt.Errorf("postprocess step %d (convert): unsupported format %q, use webp or avif", i+1, c.Format)
+			}
+			if i != len(configs)-1 {
+				return fmt.Errorf("postprocess step %d (convert): convert must be the last step", i+1)
+			}
+		default:
+			return fmt.Errorf("postprocess step %d: unknown step %q, use resize, strip_metadata or convert", i+1, c.Step)
+		}
+	}
+	return nil
+}
+
+// New checks the configuration and builds its pipeline. Converting needs
+// the encoder to be installed.
+func New(configs []StepConfig) (*Pipeline, error) {
+	if err := Validate(configs); err != nil {
+		return nil, err
+	}
+
+	p := &Pipeline{}
+	for _, c := range configs {
+		var s step
+		switch c.Step {
+		case StepResize:
+			s = resizeStep{maxDimension: c.MaxDimension, quality: c.Quality}
+		case StepStripMetadata:
+			s = stripStep{}
+		case StepConvert:
+			conv, err := newConvertStep(c)
+			if err != nil {
+				return nil, err
+			}
+			s = conv
+		}
+		p.steps = append(p.steps, s)
+		p.names = append(p.names, c.Step)
+	}
+	return p, nil
+}
+
+// Empty reports whether the pipeline has no steps
+func (p *Pipeline) Empty() bool {
+	return p == nil || len(p.steps) == 0
+}
+
+// Run applies the steps to the photo at path, replacing the file. It returns
+// the photo's new extension, such as ".webp", or an empty string when the
+// format did not change. When a step fails, the extension of what is left in
+// the file is returned with the error.
+func (p *Pipeline) Run(path string) (string, error) {
+	if p.Empty() {
+		return "", nil
+	}
+
+	var ext string
+	for i, s := range p.steps {
+		changed, err := s.apply(path)
+		if err != nil {
+			return ext, fmt.Errorf("postprocess %s: %w", p.names[i], err)
+		}
+		if changed != "" {
+			ext = changed
+		}
+	}
+	return ext, nil
+}
+
+// replaceFile writes data to a temporary file next to path and renames it
+// over path, so the photo is never left half written
+func replaceFile(path string, write func(f *os.File) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".postprocess-*")
+	if err != nil {
+		return err
+	}
+	err = write(tmp)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
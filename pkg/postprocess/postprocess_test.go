@@ -0,0 +1,188 @@
+package postprocess
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeJPEG writes a width x height JPEG with an EXIF segment and a comment
+func writeJPEG(t *testing.T, path string, width, height int) {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, nil))
+
+	data := buf.Bytes()
+	exif := append([]byte{0xFF, 0xE1, 0x00, 0x0E}, []byte("Exif\x00\x00secret")...)
+	comment := append([]byte{0xFF, 0xFE, 0x00, 0x08}, []byte("secret")...)
+	out := append([]byte{}, data[:2]...)
+	out = append(out, exif...)
+	out = append(out, comment...)
+	out = append(out, data[2:]...)
+	require.NoError(t, os.WriteFile(path, out, 0644))
+}
+
+func TestValidate(t *testing.T) {
+	valid := []StepConfig{
+		{Step: StepResize, MaxDimension: 1080},
+		{Step: StepStripMetadata},
+		{Step: StepConvert, Format: "webp", Quality: 80},
+	}
+	assert.NoError(t, Validate(valid))
+	assert.NoError(t, Validate(nil))
+
+	invalid := map[string][]StepConfig{
+		"unknown step":       {{Step: "sharpen"}},
+		"max_dimension":      {{Step: StepResize}},
+		"unsupported format": {{Step: StepConvert, Format: "heic"}},
+		"must be the last":   {{Step: StepConvert, Format: "webp"}, {Step: StepStripMetadata}},
+		"between 0 and 100":  {{Step: StepConvert, Format: "avif", Quality: 101}},
+	}
+	for want, configs := range invalid {
+		err := Validate(configs)
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), want)
+		}
+	}
+}
+
+func TestResize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "photo.tmp")
+	writeJPEG(t, path, 200, 100)
+
+	p, err := New([]StepConfig{{Step: StepResize, MaxDimension: 50}})
+	require.NoError(t, err)
+	ext, err := p.Run(path)
+	require.NoError(t, err)
+	assert.Empty(t, ext)
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	cfg, format, err := image.DecodeConfig(f)
+	require.NoError(t, err)
+	assert.Equal(t, "jpeg", format)
+	assert.Equal(t, 50, cfg.Width)
+	assert.Equal(t, 25, cfg.Height)
+
+	// Photos that already fit are not re-encoded
+	before, err := os.ReadFile(path)
+	require.NoError(t, err)
+	_, err = p.Run(path)
+	require.NoError(t, err)
+	after, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, before, after)
+}
+
+func TestFitWithin(t *testing.T) {
+	w, h := fitWithin(1080, 1350, 1080)
+	assert.Equal(t, []int{864, 1080}, []int{w, h})
+	w, h = fitWithin(640, 480, 1080)
+	assert.Equal(t, []int{640, 480}, []int{w, h})
+	w, h = fitWithin(4000, 1, 100)
+	assert.Equal(t, []int{100, 1}, []int{w, h})
+}
+
+func TestStripMetadata(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "photo.tmp")
+	writeJPEG(t, path, 16, 16)
+
+	p, err := New([]StepConfig{{Step: StepStripMetadata}})
+	require.NoError(t, err)
+	_, err = p.Run(path)
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "secret")
+	_, err = jpeg.Decode(bytes.NewReader(data))
+	assert.NoError(t, err, "stripped photo should still decode")
+
+	// PNG text chunks are dropped too
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, 4, 4))))
+	pngData := buf.Bytes()
+	text := []byte{0, 0, 0, 6, 't', 'E', 'X', 't', 's', 'e', 'c', 'r', 'e', 't', 0, 0, 0, 0}
+	withText := append(append(append([]byte{}, pngData[:33]...), text...), pngData[33:]...)
+	stripped, err := stripPNG(withText)
+	require.NoError(t, err)
+	assert.Equal(t, pngData, stripped)
+}
+
+func TestConvert(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake encoder is a shell script")
+	}
+	dir := t.TempDir()
+
+	// The fake encoder copies its input to the file after -o
+	encoder := filepath.Join(dir, "fake-cwebp")
+	script := "#!/bin/sh\nwhile [ $# -gt 0 ]; do case $1 in -o) shift; out=$1;; -*) ;; *) in=$1;; esac; shift; done\n" +
+		"case $in in *.jpg) ;; *) exit 1;; esac\ncp \"$in\" \"$out\"\n"
+	require.NoError(t, os.WriteFile(encoder, []byte(script), 0755))
+
+	path := filepath.Join(dir, "photo.tmp")
+	writeJPEG(t, path, 8, 8)
+	p, err := New([]StepConfig{{Step: StepConvert, Format: "webp", Quality: 80, Command: encoder}})
+	require.NoError(t, err)
+
+	ext, err := p.Run(path)
+	require.NoError(t, err)
+	assert.Equal(t, ".webp", ext)
+	assert.FileExists(t, path)
+	assert.NoFileExists(t, path+".jpg")
+
+	// A file that is not a JPEG or PNG is left as it was
+	require.NoError(t, os.WriteFile(path, []byte("not an image"), 0644))
+	_, err = p.Run(path)
+	assert.Error(t, err)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "not an image", string(data))
+
+	_, err = New([]StepConfig{{Step: StepConvert, Format: "avif", Command: filepath.Join(dir, "missing")}})
+	assert.Error(t, err)
+}
+
+func TestThumbnail(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "photo.jpg")
+	writeJPEG(t, src, 200, 100)
+	before, err := os.ReadFile(src)
+	require.NoError(t, err)
+
+	dst := filepath.Join(dir, ".thumbnails", "photo.jpg")
+	require.NoError(t, Thumbnail(src, dst, 64))
+
+	f, err := os.Open(dst)
+	require.NoError(t, err)
+	defer f.Close()
+	cfg, format, err := image.DecodeConfig(f)
+	require.NoError(t, err)
+	assert.Equal(t, "jpeg", format)
+	assert.Equal(t, 64, cfg.Width)
+	assert.Equal(t, 32, cfg.Height)
+
+	after, err := os.ReadFile(src)
+	require.NoError(t, err)
+	assert.Equal(t, before, after, "the photo is left unchanged")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "photo.webp"), []byte("RIFF"), 0644))
+	assert.Error(t, Thumbnail(filepath.Join(dir, "photo.webp"), filepath.Join(dir, ".thumbnails", "photo-webp.jpg"), 64))
+}
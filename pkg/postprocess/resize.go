@@ -0,0 +1,97 @@
+package postprocess
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"os"
+)
+
+// defaultJPEGQuality is used to re-encode resized JPEGs when no quality is
+// configured
+const defaultJPEGQuality = 92
+
+// resizeStep scales photos down so the longer side fits maxDimension.
+// Smaller photos are left alone. Resizing re-encodes the photo, which drops
+// its metadata.
+type resizeStep struct {
+	maxDimension int
+	quality      int
+}
+
+func (r resizeStep) apply(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	src, format, err := image.Decode(f)
+	f.Close()
+	if err != nil {
+		return "", fmt.Errorf("failed to decode photo: %w", err)
+	}
+
+	width, height := fitWithin(src.Bounds().Dx(), src.Bounds().Dy(), r.maxDimension)
+	if width == src.Bounds().Dx() && height == src.Bounds().Dy() {
+		return "", nil
+	}
+	dst := scaleDown(src, width, height)
+
+	quality := r.quality
+	if quality == 0 {
+		quality = defaultJPEGQuality
+	}
+	return "", replaceFile(path, func(f *os.File) error {
+		if format == "png" {
+			return png.Encode(f, dst)
+		}
+		return jpeg.Encode(f, dst, &jpeg.Options{Quality: quality})
+	})
+}
+
+// fitWithin returns the size of a width x height image scaled down, keeping
+// its aspect ratio, so neither side exceeds limit
+func fitWithin(width, height, limit int) (int, int) {
+	if width <= limit && height <= limit {
+		return width, height
+	}
+	if width >= height {
+		return limit, max(1, height*limit/width)
+	}
+	return max(1, width*limit/height), limit
+}
+
+// scaleDown shrinks src to width x height by averaging the source pixels
+// that fall into each destination pixel
+func scaleDown(src image.Image, width, height int) *image.RGBA {
+	bounds := src.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+
+	sw, sh := bounds.Dx(), bounds.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := y*sh/height, max((y+1)*sh/height, y*sh/height+1)
+		for x := 0; x < width; x++ {
+			x0, x1 := x*sw/width, max((x+1)*sw/width, x*sw/width+1)
+
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := rgba.Pix[sy*rgba.Stride+x0*4 : sy*rgba.Stride+x1*4]
+				for i := 0; i < len(row); i += 4 {
+					sum[0] += int(row[i])
+					sum[1] += int(row[i+1])
+					sum[2] += int(row[i+2])
+					sum[3] += int(row[i+3])
+				}
+			}
+			n := (y1 - y0) * (x1 - x0)
+			i := dst.PixOffset(x, y)
+			for c := 0; c < 4; c++ {
+				dst.Pix[i+c] = uint8((sum[c] + n/2) / n)
+			}
+		}
+	}
+	return dst
+}
@@ -0,0 +1,107 @@
+package postprocess
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+)
+
+// errTruncated is returned for JPEG and PNG files that end mid-segment
+var errTruncated = errors.New("truncated image")
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// pngMetadataChunks are the PNG chunks that carry text, EXIF or timestamps
+var pngMetadataChunks = map[string]bool{
+	"tEXt": true, "zTXt": true, "iTXt": true, "eXIf": true, "tIME": true,
+}
+
+// stripStep removes metadata from JPEG and PNG photos without re-encoding
+// them. The JFIF header, ICC colour profile and Adobe colour transform are
+// kept, since they change how the image is displayed. Other formats are left
+// alone.
+type stripStep struct{}
+
+func (stripStep) apply(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	var stripped []byte
+	switch {
+	case len(data) >= 2 && data[0] == 0xFF && data[1] == 0xD8:
+		stripped, err = stripJPEG(data)
+	case bytes.HasPrefix(data, pngSignature):
+		stripped, err = stripPNG(data)
+	default:
+		return "", nil
+	}
+	if err != nil || len(stripped) == len(data) {
+		return "", err
+	}
+	return "", replaceFile(path, func(f *os.File) error {
+		_, err := f.Write(stripped)
+		return err
+	})
+}
+
+// stripJPEG drops the APP and comment segments before the image data, except
+// JFIF (APP0), ICC profiles (APP2) and the Adobe marker (APP14)
+func stripJPEG(data []byte) ([]byte, error) {
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:2]...)
+	pos := 2
+	for pos+4 <= len(data) && data[pos] == 0xFF {
+		marker := data[pos+1]
+		if marker == 0xFF {
+			pos++ // fill byte
+			continue
+		}
+		if marker == 0xDA { // start of scan: the rest is image data
+			break
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2 : pos+4]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			return nil, errTruncated
+		}
+		payload := data[pos+4 : end]
+		keep := true
+		switch {
+		case marker == 0xE2:
+			keep = bytes.HasPrefix(payload, []byte("ICC_PROFILE\x00"))
+		case marker == 0xE0, marker == 0xEE:
+		case marker >= 0xE1 && marker <= 0xEF, marker == 0xFE:
+			keep = false
+		}
+		if keep {
+			out = append(out, data[pos:end]...)
+		}
+		pos = end
+	}
+	return append(out, data[pos:]...), nil
+}
+
+// stripPNG drops the text, EXIF and timestamp chunks
+func stripPNG(data []byte) ([]byte, error) {
+	out := make([]byte, 0, len(data))
+	out = append(out, pngSignature...)
+	pos := len(pngSignature)
+	for pos < len(data) {
+		if pos+8 > len(data) {
+			return nil, errTruncated
+		}
+		// length, type, data, CRC
+		end := pos + 12 + int(binary.BigEndian.Uint32(data[pos:pos+4]))
+		if end > len(data) || end < pos {
+			return nil, errTruncated
+		}
+		if !pngMetadataChunks[string(data[pos+4:pos+8])] {
+			out = append(out, data[pos:end]...)
+		}
+		pos = end
+	}
+	return out, nil
+}
@@ -0,0 +1,40 @@
+package postprocess
+
+import (
+	"fmt"
+	"image"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+)
+
+// thumbnailQuality is the JPEG quality of thumbnails, which are only looked
+// at small
+const thumbnailQuality = 80
+
+// Thumbnail writes a JPEG copy of the photo at src to dst, scaled down so
+// its longer side is at most size pixels, creating dst's folder. The photo
+// must be a JPEG or PNG; it is left unchanged.
+func Thumbnail(src, dst string, size int) error {
+	if size <= 0 {
+		return fmt.Errorf("thumbnail size must be positive")
+	}
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	img, _, err := image.Decode(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("failed to decode photo: %w", err)
+	}
+
+	width, height := fitWithin(img.Bounds().Dx(), img.Bounds().Dy(), size)
+	thumb := scaleDown(img, width, height)
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	return replaceFile(dst, func(f *os.File) error {
+		return jpeg.Encode(f, thumb, &jpeg.Options{Quality: thumbnailQuality})
+	})
+}
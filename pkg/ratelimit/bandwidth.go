@@ -0,0 +1,147 @@
+package ratelimit
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// bandwidthChunk is the most a throttled reader reads at once, so a low limit
+// is enforced smoothly rather than in long bursts
+const bandwidthChunk = 32 << 10
+
+// Bandwidth is a token bucket of bytes that limits how fast its readers read.
+// Every reader shares the bucket, so concurrent downloads split the limit
+// between them. The limit can be changed while downloads are running.
+type Bandwidth struct {
+	limit  int64   // bytes per second, 0 for no limit
+	tokens float64 // bytes that may be read right away; negative is debt
+	last   time.Time
+	mu     sync.Mutex
+}
+
+// NewBandwidth creates a bandwidth limiter of bytesPerSecond. Zero or less
+// means no limit.
+func NewBandwidth(bytesPerSecond int64) *Bandwidth {
+	return &Bandwidth{
+		limit: max(bytesPerSecond, 0),
+		last:  time.Now(),
+	}
+}
+
+// Limit returns the current limit in bytes per second, 0 meaning no limit
+func (b *Bandwidth) Limit() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.limit
+}
+
+// SetLimit changes the limit to bytesPerSecond. Zero or less removes it.
+// Reads already waiting finish their wait under the old limit.
+func (b *Bandwidth) SetLimit(bytesPerSecond int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill()
+	b.limit = max(bytesPerSecond, 0)
+	b.tokens = min(b.tokens, float64(b.limit))
+}
+
+// WaitN takes n bytes from the bucket, blocking while it is in debt. The
+// bucket holds up to one second of bytes.
+func (b *Bandwidth) WaitN(n int) {
+	b.mu.Lock()
+	if b.limit == 0 {
+		b.mu.Unlock()
+		return
+	}
+	b.refill()
+	b.tokens -= float64(n)
+	var wait time.Duration
+	if b.tokens < 0 {
+		wait = time.Duration(-b.tokens / float64(b.limit) * float64(time.Second))
+	}
+	b.mu.Unlock()
+
+	time.Sleep(wait)
+}
+
+// refill adds the bytes earned since the last call
+func (b *Bandwidth) refill() {
+	now := time.Now()
+	if b.limit > 0 {
+		b.tokens = min(b.tokens+now.Sub(b.last).Seconds()*float64(b.limit), float64(b.limit))
+	}
+	b.last = now
+}
+
+// Reader returns r throttled to the limit
+func (b *Bandwidth) Reader(r io.Reader) io.Reader {
+	return &bandwidthReader{r: r, b: b}
+}
+
+// bandwidthReader is an io.Reader throttled by a Bandwidth
+type bandwidthReader struct {
+	r io.Reader
+	b *Bandwidth
+}
+
+// Read reads at most bandwidthChunk bytes, then waits until the limit allows
+// them
+func (r *bandwidthReader) Read(p []byte) (int, error) {
+	if len(p) > bandwidthChunk && r.b.Limit() > 0 {
+		p = p[:bandwidthChunk]
+	}
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.b.WaitN(n)
+	}
+	return n, err
+}
+
+// ParseBandwidth parses a bandwidth such as "5MB/s", "512KB/s" or "1.5M" into
+// bytes per second. Units are powers of 1024, and a bare number is bytes.
+// An empty string or zero means no limit.
+func ParseBandwidth(s string) (int64, error) {
+	value := strings.ToUpper(strings.TrimSpace(s))
+	value = strings.TrimSuffix(value, "/S")
+	value = strings.TrimSuffix(strings.TrimSuffix(value, "IB"), "B")
+	if value == "" {
+		if s = strings.TrimSpace(s); s != "" {
+			return 0, fmt.Errorf("invalid bandwidth %q", s)
+		}
+		return 0, nil
+	}
+
+	multiplier := int64(1)
+	if i := strings.IndexAny(value, "KMG"); i == len(value)-1 {
+		multiplier = 1 << (10 * (strings.IndexByte("KMG", value[i]) + 1))
+		value = value[:i]
+	}
+	number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("invalid bandwidth %q: use a size per second such as 5MB/s", s)
+	}
+	return int64(number * float64(multiplier)), nil
+}
+
+// FormatBandwidth formats bytes per second the way ParseBandwidth reads them
+func FormatBandwidth(bytesPerSecond int64) string {
+	if bytesPerSecond <= 0 {
+		return "unlimited"
+	}
+	const unit = 1024
+	if bytesPerSecond < unit {
+		return fmt.Sprintf("%dB/s", bytesPerSecond)
+	}
+	div, exp := int64(unit), 0
+	for n := bytesPerSecond / unit; n >= unit && exp < 2; n /= unit {
+		div *= unit
+		exp++
+	}
+	value := strings.TrimSuffix(strconv.FormatFloat(float64(bytesPerSecond)/float64(div), 'f', 1, 64), ".0")
+	return value + string("KMG"[exp]) + "B/s"
+}
@@ -0,0 +1,82 @@
+package ratelimit
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestBandwidth(t *testing.T) {
+	// The bucket starts empty, so 64KB at 256KB/s takes about a quarter second
+	b := NewBandwidth(256 << 10)
+	start := time.Now()
+	n, err := io.Copy(io.Discard, b.Reader(bytes.NewReader(make([]byte, 64<<10))))
+	elapsed := time.Since(start)
+	if err != nil || n != 64<<10 {
+		t.Fatalf("Copy = %d, %v", n, err)
+	}
+	if elapsed < 200*time.Millisecond || elapsed > time.Second {
+		t.Errorf("Expected about 250ms at 256KB/s, took %v", elapsed)
+	}
+
+	// Lifting the limit takes effect for the next read
+	b.SetLimit(0)
+	start = time.Now()
+	io.Copy(io.Discard, b.Reader(bytes.NewReader(make([]byte, 1<<20))))
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("Expected unlimited copy to be immediate, took %v", elapsed)
+	}
+	if b.Limit() != 0 {
+		t.Errorf("Limit = %d, want 0", b.Limit())
+	}
+}
+
+func TestParseBandwidth(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int64
+	}{
+		{"", 0},
+		{"0", 0},
+		{"500", 500},
+		{"5MB/s", 5 << 20},
+		{"512KB/s", 512 << 10},
+		{"1.5M", 3 << 19},
+		{"2 MiB/s", 2 << 20},
+		{"1gb/s", 1 << 30},
+	}
+	for _, tt := range tests {
+		got, err := ParseBandwidth(tt.input)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseBandwidth(%q) = %d, %v; want %d", tt.input, got, err, tt.want)
+		}
+	}
+
+	for _, input := range []string{"fast", "MB/s", "-1MB/s", "5TB/s", "1K5"} {
+		if _, err := ParseBandwidth(input); err == nil {
+			t.Errorf("ParseBandwidth(%q) should fail", input)
+		}
+	}
+}
+
+func TestFormatBandwidth(t *testing.T) {
+	tests := map[int64]string{
+		0:         "unlimited",
+		500:       "500B/s",
+		512 << 10: "512KB/s",
+		5 << 20:   "5MB/s",
+		3 << 19:   "1.5MB/s",
+		2 << 30:   "2GB/s",
+	}
+	for input, want := range tests {
+		if got := FormatBandwidth(input); got != want {
+			t.Errorf("FormatBandwidth(%d) = %q, want %q", input, got, want)
+		}
+		if input > 0 {
+			if parsed, err := ParseBandwidth(want); err != nil || parsed != input {
+				t.Errorf("ParseBandwidth(%q) = %d, %v; want %d", want, parsed, err, input)
+			}
+		}
+	}
+}
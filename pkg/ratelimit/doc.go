@@ -15,13 +15,32 @@
 //   - More accurate rate limiting over time
 //   - Better for consistent request patterns
 //
+// Shared Bucket:
+//   - Token bucket kept in a file and locked on each request
+//   - Shares one request budget between processes on the same machine
+//
 // Interface:
 //
 // All rate limiters implement the Limiter interface:
 //   - Allow() bool - Check if a request is allowed
 //   - Wait() - Block until a request is allowed
+//   - WaitContext(ctx) error - Block until allowed or ctx is done
+//   - TryWait(maxWait) bool - Wait at most maxWait for a request to be allowed
 //   - Reset() - Reset the limiter state
 //
+// Each of them is also Adjustable: SetCapacity changes the number of
+// requests allowed per period while the limiter is in use, with the requests
+// already made in the period counting against the new capacity.
+//
+// Bandwidth:
+//
+// Bandwidth limits bytes rather than requests. Its Reader wraps an io.Reader
+// so reads wait for the shared byte budget, and its limit can be changed
+// while downloads are running:
+//
+//	bw := ratelimit.NewBandwidth(5 << 20) // 5MB/s
+//	io.Copy(file, bw.Reader(resp.Body))
+//
 // Usage:
 //
 //	// Token bucket: 50 requests per hour
@@ -40,4 +59,12 @@
 //	// Block until allowed
 //	limiter.Wait()
 //	// Proceed with request
+//	
+//	// Give up on shutdown or after a deadline
+//	if err := limiter.WaitContext(ctx); err != nil {
+//	    return err
+//	}
+//	if !limiter.TryWait(30 * time.Second) {
+//	    // Skip the request
+//	}
 package ratelimit
\ No newline at end of file
@@ -1,6 +1,8 @@
 package ratelimit
 
 import (
+	"context"
+	"slices"
 	"sync"
 	"time"
 )
@@ -11,10 +13,32 @@ type Limiter interface {
 	Allow() bool
 	// Wait blocks until the rate limit allows another request
 	Wait()
+	// WaitContext blocks like Wait, but returns ctx's error if ctx is done
+	// first. It fails right away when ctx's deadline comes before the next
+	// request is allowed.
+	WaitContext(ctx context.Context) error
+	// TryWait waits at most maxWait for the rate limit to allow another
+	// request and reports whether it did
+	TryWait(maxWait time.Duration) bool
 	// Reset resets the rate limiter state
 	Reset()
 }
 
+// Adjustable is a limiter whose number of requests per period can be changed
+// while it is in use
+type Adjustable interface {
+	Limiter
+	// Capacity returns the number of requests allowed per period
+	Capacity() int
+	// SetCapacity changes the number of requests allowed per period.
+	// Requests already made in the current period count against it.
+	SetCapacity(capacity int)
+}
+
+// maxSleep is the longest a waiting request sleeps before checking the
+// limiter again, so a raised capacity lets it through soon
+const maxSleep = time.Second
+
 // TokenBucket implements a token bucket rate limiter
 type TokenBucket struct {
 	capacity     int           // Maximum number of tokens
@@ -51,18 +75,28 @@ func (tb *TokenBucket) Allow() bool {
 
 // Wait blocks until a token is available
 func (tb *TokenBucket) Wait() {
-	for !tb.Allow() {
-		tb.mu.Lock()
-		timeUntilRefill := tb.refillPeriod - time.Since(tb.lastRefill)
-		tb.mu.Unlock()
-
-		if timeUntilRefill > 0 {
-			time.Sleep(timeUntilRefill)
-		} else {
-			// Small sleep to prevent busy waiting
-			time.Sleep(100 * time.Millisecond)
-		}
+	tb.WaitContext(context.Background())
+}
+
+// WaitContext blocks until a token is available or ctx is done
+func (tb *TokenBucket) WaitContext(ctx context.Context) error {
+	return waitFor(ctx, tb.take)
+}
+
+// TryWait waits at most maxWait for a token
+func (tb *TokenBucket) TryWait(maxWait time.Duration) bool {
+	return tryWait(maxWait, tb.take)
+}
+
+// take takes a token if one is left, and otherwise returns how long until
+// the bucket is refilled
+func (tb *TokenBucket) take() (bool, time.Duration) {
+	if tb.Allow() {
+		return true, 0
 	}
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	return false, tb.refillPeriod - time.Since(tb.lastRefill)
 }
 
 // Reset resets the token bucket to full capacity
@@ -74,6 +108,26 @@ func (tb *TokenBucket) Reset() {
 	tb.lastRefill = time.Now()
 }
 
+// Capacity returns the number of tokens the bucket refills to
+func (tb *TokenBucket) Capacity() int {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	return tb.capacity
+}
+
+// SetCapacity changes the number of tokens the bucket refills to. The tokens
+// used since the last refill stay used, so raising the capacity adds tokens
+// right away and lowering it may leave none.
+func (tb *TokenBucket) SetCapacity(capacity int) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	tb.refill()
+	capacity = max(capacity, 1)
+	tb.tokens = min(max(tb.tokens+capacity-tb.capacity, 0), capacity)
+	tb.capacity = capacity
+}
+
 // refill adds tokens based on elapsed time
 func (tb *TokenBucket) refill() {
 	now := time.Now()
@@ -85,6 +139,29 @@ func (tb *TokenBucket) refill() {
 	}
 }
 
+// State returns the bucket's tokens and last refill, to be restored by a
+// later run
+func (tb *TokenBucket) State() State {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	return State{Tokens: tb.tokens, LastRefill: tb.lastRefill}
+}
+
+// Restore continues from a state saved by State. Tokens beyond the capacity
+// are dropped, and a refill time in the future is taken as now.
+func (tb *TokenBucket) Restore(state State) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	now := time.Now()
+	tb.tokens = min(max(state.Tokens, 0), tb.capacity)
+	tb.lastRefill = state.LastRefill
+	if tb.lastRefill.After(now) {
+		tb.lastRefill = now
+	}
+	tb.refill()
+}
+
 // SlidingWindow implements a sliding window rate limiter
 type SlidingWindow struct {
 	windowSize   time.Duration
@@ -120,21 +197,31 @@ func (sw *SlidingWindow) Allow() bool {
 
 // Wait blocks until a request is allowed
 func (sw *SlidingWindow) Wait() {
-	for !sw.Allow() {
-		sw.mu.Lock()
-		if len(sw.requests) > 0 {
-			oldestRequest := sw.requests[0]
-			timeToWait := sw.windowSize - time.Since(oldestRequest)
-			sw.mu.Unlock()
-
-			if timeToWait > 0 {
-				time.Sleep(timeToWait)
-			}
-		} else {
-			sw.mu.Unlock()
-			time.Sleep(100 * time.Millisecond)
-		}
+	sw.WaitContext(context.Background())
+}
+
+// WaitContext blocks until a request is allowed or ctx is done
+func (sw *SlidingWindow) WaitContext(ctx context.Context) error {
+	return waitFor(ctx, sw.take)
+}
+
+// TryWait waits at most maxWait for a request to be allowed
+func (sw *SlidingWindow) TryWait(maxWait time.Duration) bool {
+	return tryWait(maxWait, sw.take)
+}
+
+// take records a request if one is allowed, and otherwise returns how long
+// until the oldest request leaves the window
+func (sw *SlidingWindow) take() (bool, time.Duration) {
+	if sw.Allow() {
+		return true, 0
 	}
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	if len(sw.requests) == 0 {
+		return false, 0
+	}
+	return false, sw.windowSize - time.Since(sw.requests[0])
 }
 
 // Reset clears all recorded requests
@@ -145,6 +232,47 @@ func (sw *SlidingWindow) Reset() {
 	sw.requests = sw.requests[:0]
 }
 
+// State returns the requests in the window, to be restored by a later run
+func (sw *SlidingWindow) State() State {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return State{Requests: append([]time.Time(nil), sw.requests...)}
+}
+
+// Restore continues from a state saved by State, keeping the most recent
+// requests that are still in the window
+func (sw *SlidingWindow) Restore(state State) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	now := time.Now()
+	sw.requests = sw.requests[:0]
+	for _, request := range state.Requests {
+		if !request.After(now) {
+			sw.requests = append(sw.requests, request)
+		}
+	}
+	slices.SortFunc(sw.requests, time.Time.Compare)
+	sw.cleanOldRequests(now)
+	if extra := len(sw.requests) - sw.maxRequests; extra > 0 {
+		sw.requests = append(sw.requests[:0], sw.requests[extra:]...)
+	}
+}
+
+// Capacity returns the number of requests allowed in the window
+func (sw *SlidingWindow) Capacity() int {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.maxRequests
+}
+
+// SetCapacity changes the number of requests allowed in the window
+func (sw *SlidingWindow) SetCapacity(capacity int) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	sw.maxRequests = max(capacity, 1)
+}
+
 // cleanOldRequests removes requests outside the sliding window
 func (sw *SlidingWindow) cleanOldRequests(now time.Time) {
 	cutoff := now.Add(-sw.windowSize)
@@ -161,3 +289,57 @@ func (sw *SlidingWindow) cleanOldRequests(now time.Time) {
 		sw.requests = sw.requests[:len(sw.requests)-i]
 	}
 }
+
+// waitFor calls take until it allows a request, sleeping in between for as
+// long as take says the next one is away. It gives up once ctx is done, or
+// right away when ctx's deadline comes before the next request is allowed.
+func waitFor(ctx context.Context, take func() (bool, time.Duration)) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		ok, wait := take()
+		if ok {
+			return nil
+		}
+		if deadline, set := ctx.Deadline(); set && wait > 0 && time.Now().Add(wait).After(deadline) {
+			return context.DeadlineExceeded
+		}
+		if wait <= 0 {
+			// Small sleep to prevent busy waiting
+			wait = 100 * time.Millisecond
+		}
+		wait = min(wait, maxSleep)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// tryWait waits at most maxWait for take to allow a request. A request
+// allowed right away goes through even with no time to wait.
+func tryWait(maxWait time.Duration, take func() (bool, time.Duration)) bool {
+	if ok, _ := take(); ok {
+		return true
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), maxWait)
+	defer cancel()
+	return waitFor(ctx, take) == nil
+}
+
+// TokenBucketDuration returns how long a full token bucket of capacity tokens
+// per refillPeriod takes to allow n requests, ignoring the time the requests
+// themselves take. The first capacity requests go through at once and every
+// further batch waits for the next refill.
+func TokenBucketDuration(n, capacity int, refillPeriod time.Duration) time.Duration {
+	if n <= 0 || capacity <= 0 {
+		return 0
+	}
+	refills := (n - 1) / capacity
+	return time.Duration(refills) * refillPeriod
+}
@@ -1,6 +1,10 @@
 package ratelimit
 
 import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -60,4 +64,226 @@ func TestSlidingWindow(t *testing.T) {
 	if len(sw.requests) != 0 {
 		t.Error("Expected requests to be cleared after reset")
 	}
-}
\ No newline at end of file
+}
+func TestTokenBucketDuration(t *testing.T) {
+	tests := []struct {
+		n    int
+		want time.Duration
+	}{
+		{0, 0},
+		{1, 0},
+		{60, 0},
+		{61, time.Minute},
+		{120, time.Minute},
+		{121, 2 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := TokenBucketDuration(tt.n, 60, time.Minute); got != tt.want {
+			t.Errorf("TokenBucketDuration(%d) = %v, want %v", tt.n, got, tt.want)
+		}
+	}
+	if got := TokenBucketDuration(100, 0, time.Minute); got != 0 {
+		t.Errorf("Expected no wait without a limit, got %v", got)
+	}
+}
+
+func TestSharedBucket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "limits", "requests.json")
+
+	// Two processes' buckets on the same file draw from one budget
+	a, err := NewSharedBucket(path, 3, time.Second)
+	if err != nil {
+		t.Fatalf("NewSharedBucket: %v", err)
+	}
+	b, err := NewSharedBucket(path, 3, time.Second)
+	if err != nil {
+		t.Fatalf("NewSharedBucket: %v", err)
+	}
+	if !a.Allow() || !b.Allow() || !a.Allow() {
+		t.Error("Expected the first 3 requests to be allowed")
+	}
+	if b.Allow() || a.Allow() {
+		t.Error("Expected the shared budget to be used up")
+	}
+
+	// Resetting one fills the bucket for both
+	a.Reset()
+	if !b.Allow() {
+		t.Error("Expected a token after reset")
+	}
+
+	// Wait blocks until the bucket refills
+	b.Allow()
+	b.Allow()
+	start := time.Now()
+	a.Wait()
+	if elapsed := time.Since(start); elapsed < 500*time.Millisecond {
+		t.Errorf("Expected Wait to block until the refill, took %v", elapsed)
+	}
+
+	// A corrupt file falls back to this process's own bucket
+	if err := os.WriteFile(path, []byte("not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !a.Allow() {
+		t.Error("Expected the local bucket to allow the request")
+	}
+}
+
+func TestWaitContext(t *testing.T) {
+	limiters := map[string]Limiter{
+		"token bucket":   NewTokenBucket(1, time.Hour),
+		"sliding window": NewSlidingWindow(1, time.Hour),
+	}
+	shared, err := NewSharedBucket(filepath.Join(t.TempDir(), "requests.json"), 1, time.Hour)
+	if err != nil {
+		t.Fatalf("NewSharedBucket: %v", err)
+	}
+	limiters["shared bucket"] = shared
+
+	for name, limiter := range limiters {
+		t.Run(name, func(t *testing.T) {
+			if err := limiter.WaitContext(context.Background()); err != nil {
+				t.Fatalf("Expected the first request to go through, got %v", err)
+			}
+
+			// Cancelling stops the wait for the next refill
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(50*time.Millisecond, cancel)
+			start := time.Now()
+			if err := limiter.WaitContext(ctx); !errors.Is(err, context.Canceled) {
+				t.Errorf("Expected context.Canceled, got %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("Expected the wait to end on cancel, took %v", elapsed)
+			}
+
+			// A deadline before the refill fails without waiting for it
+			start = time.Now()
+			if limiter.TryWait(time.Minute) {
+				t.Error("Expected TryWait to fail before the refill")
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("Expected TryWait to give up at once, took %v", elapsed)
+			}
+
+			limiter.Reset()
+			if !limiter.TryWait(0) {
+				t.Error("Expected TryWait to succeed after reset")
+			}
+		})
+	}
+}
+
+func TestPersistent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ratelimit", "12345.json")
+
+	// A new state file starts with a full bucket
+	p, err := NewPersistent(NewTokenBucket(3, time.Hour), path)
+	if err != nil {
+		t.Fatalf("NewPersistent: %v", err)
+	}
+	p.Allow()
+	p.Allow()
+
+	// The next run continues with what is left
+	p, err = NewPersistent(NewTokenBucket(3, time.Hour), path)
+	if err != nil {
+		t.Fatalf("NewPersistent: %v", err)
+	}
+	if !p.Allow() {
+		t.Error("Expected the remaining token to be allowed")
+	}
+	if p.Allow() {
+		t.Error("Expected the restored budget to be used up")
+	}
+
+	// A sliding window keeps the requests still in its window
+	windowPath := filepath.Join(t.TempDir(), "window.json")
+	w, _ := NewPersistent(NewSlidingWindow(2, time.Hour), windowPath)
+	w.Allow()
+	w, _ = NewPersistent(NewSlidingWindow(2, time.Hour), windowPath)
+	if !w.Allow() || w.Allow() {
+		t.Error("Expected one request left in the restored window")
+	}
+
+	// A corrupt state file is reported and the bucket starts full
+	if err := os.WriteFile(path, []byte("not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	p, err = NewPersistent(NewTokenBucket(3, time.Hour), path)
+	if err == nil {
+		t.Error("Expected an error for a corrupt state file")
+	}
+	if !p.Allow() {
+		t.Error("Expected a full bucket after a corrupt state file")
+	}
+}
+
+func TestTokenBucketRestore(t *testing.T) {
+	tb := NewTokenBucket(5, time.Minute)
+
+	// A refill that is due happens on restore
+	tb.Restore(State{Tokens: 0, LastRefill: time.Now().Add(-2 * time.Minute)})
+	if tb.State().Tokens != 5 {
+		t.Errorf("Expected a refilled bucket, got %d tokens", tb.State().Tokens)
+	}
+
+	// Tokens are capped at the capacity and refills are never in the future
+	tb.Restore(State{Tokens: 50, LastRefill: time.Now().Add(time.Hour)})
+	state := tb.State()
+	if state.Tokens != 5 || state.LastRefill.After(time.Now()) {
+		t.Errorf("Expected 5 tokens refilled by now, got %+v", state)
+	}
+}
+
+func TestSetCapacity(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "requests.json")
+	shared, err := NewSharedBucket(path, 3, time.Hour)
+	if err != nil {
+		t.Fatalf("NewSharedBucket: %v", err)
+	}
+	persistent, _ := NewPersistent(NewTokenBucket(3, time.Hour), filepath.Join(t.TempDir(), "state.json"))
+	limiters := map[string]Adjustable{
+		"token bucket":   NewTokenBucket(3, time.Hour),
+		"sliding window": NewSlidingWindow(3, time.Hour),
+		"shared bucket":  shared,
+		"persistent":     persistent,
+	}
+
+	for name, limiter := range limiters {
+		// Two of three requests used, so lowering to 2 leaves none
+		limiter.Allow()
+		limiter.Allow()
+		limiter.SetCapacity(2)
+		if limiter.Capacity() != 2 {
+			t.Errorf("%s: expected capacity 2, got %d", name, limiter.Capacity())
+		}
+		if limiter.Allow() {
+			t.Errorf("%s: expected the lowered capacity to be used up", name)
+		}
+
+		// Raising it to 4 allows the two requests more right away
+		limiter.SetCapacity(4)
+		if !limiter.Allow() || !limiter.Allow() {
+			t.Errorf("%s: expected the raised capacity to allow 2 more requests", name)
+		}
+		if limiter.Allow() {
+			t.Errorf("%s: expected the raised capacity to be used up", name)
+		}
+	}
+
+	// A waiting request goes through soon after the capacity is raised
+	tb := NewTokenBucket(1, time.Hour)
+	tb.Allow()
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		tb.SetCapacity(2)
+	}()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	time.AfterFunc(3*time.Second, cancel)
+	if err := tb.WaitContext(ctx); err != nil {
+		t.Errorf("Expected the raised capacity to let the waiting request through, got %v", err)
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !(linux || darwin || freebsd || openbsd || netbsd || dragonfly || windows)
+
+package ratelimit
+
+import "os"
+
+// lockFile does nothing on platforms without file locks, so a shared bucket
+// only keeps this process's requests in step
+func lockFile(f *os.File) error {
+	return nil
+}
+
+// unlockFile does nothing on platforms without file locks
+func unlockFile(f *os.File) error {
+	return nil
+}
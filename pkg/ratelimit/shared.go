@@ -0,0 +1,190 @@
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// sharedState is the bucket as stored in the shared file
+type sharedState struct {
+	Tokens     int       `json:"tokens"`
+	LastRefill time.Time `json:"last_refill"`
+}
+
+// SharedBucket is a token bucket kept in a file, so every process that uses
+// the same file draws from one request budget, as when several igscraper
+// instances scrape with the same account. Each request opens and locks the
+// file, takes a token and writes the bucket back, so no file stays open
+// between requests. Like TokenBucket, the bucket fills up again once
+// refillPeriod has passed since the last refill.
+//
+// If the file cannot be read or written later on, requests fall back to a
+// token bucket of this process alone rather than stopping.
+type SharedBucket struct {
+	path         string
+	capacity     int
+	refillPeriod time.Duration
+	local        *TokenBucket
+	mu           sync.Mutex // file locks may not exclude this process's own goroutines
+}
+
+// NewSharedBucket creates a bucket kept in the file at path, checking that
+// the file can be created and locked. A new file starts full.
+func NewSharedBucket(path string, capacity int, refillPeriod time.Duration) (*SharedBucket, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create rate limit directory: %w", err)
+		}
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open rate limit file: %w", err)
+	}
+	if err := lockFile(file); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to lock rate limit file: %w", err)
+	}
+	unlockFile(file)
+	file.Close()
+
+	return &SharedBucket{
+		path:         path,
+		capacity:     capacity,
+		refillPeriod: refillPeriod,
+		local:        NewTokenBucket(capacity, refillPeriod),
+	}, nil
+}
+
+// Allow checks if a request can proceed
+func (sb *SharedBucket) Allow() bool {
+	ok, _, err := sb.take()
+	if err != nil {
+		return sb.local.Allow()
+	}
+	return ok
+}
+
+// Wait blocks until a token is available
+func (sb *SharedBucket) Wait() {
+	sb.WaitContext(context.Background())
+}
+
+// WaitContext blocks until a token is available or ctx is done
+func (sb *SharedBucket) WaitContext(ctx context.Context) error {
+	return waitFor(ctx, sb.takeOrFallBack)
+}
+
+// TryWait waits at most maxWait for a token
+func (sb *SharedBucket) TryWait(maxWait time.Duration) bool {
+	return tryWait(maxWait, sb.takeOrFallBack)
+}
+
+// takeOrFallBack takes a token from the shared bucket, or from this process's
+// own bucket when the file cannot be used
+func (sb *SharedBucket) takeOrFallBack() (bool, time.Duration) {
+	ok, untilRefill, err := sb.take()
+	if err != nil {
+		return sb.local.take()
+	}
+	return ok, untilRefill
+}
+
+// Reset fills the bucket for every process sharing it
+func (sb *SharedBucket) Reset() {
+	sb.local.Reset()
+	sb.update(func(state *sharedState) {
+		state.Tokens = sb.capacity
+		state.LastRefill = time.Now()
+	})
+}
+
+// Capacity returns the number of tokens the bucket refills to
+func (sb *SharedBucket) Capacity() int {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	return sb.capacity
+}
+
+// SetCapacity changes the number of tokens the bucket refills to, keeping
+// the tokens used since the last refill used. Other processes sharing the
+// file keep their own capacity and apply it at their next refill.
+func (sb *SharedBucket) SetCapacity(capacity int) {
+	capacity = max(capacity, 1)
+	sb.local.SetCapacity(capacity)
+	err := sb.update(func(state *sharedState) {
+		state.Tokens = min(max(state.Tokens+capacity-sb.capacity, 0), capacity)
+		sb.capacity = capacity
+	})
+	if err != nil {
+		sb.mu.Lock()
+		sb.capacity = capacity
+		sb.mu.Unlock()
+	}
+}
+
+// take takes a token if one is left, and otherwise returns how long until
+// the bucket is refilled
+func (sb *SharedBucket) take() (bool, time.Duration, error) {
+	var ok bool
+	var untilRefill time.Duration
+	err := sb.update(func(state *sharedState) {
+		now := time.Now()
+		if now.Sub(state.LastRefill) >= sb.refillPeriod {
+			state.Tokens = sb.capacity
+			state.LastRefill = now
+		}
+		if state.Tokens > 0 {
+			state.Tokens--
+			ok = true
+			return
+		}
+		untilRefill = sb.refillPeriod - now.Sub(state.LastRefill)
+	})
+	return ok, untilRefill, err
+}
+
+// update reads the bucket, applies fn and writes it back while holding the
+// file lock. An empty file is a full bucket.
+func (sb *SharedBucket) update(fn func(*sharedState)) error {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+
+	file, err := os.OpenFile(sb.path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := lockFile(file); err != nil {
+		return err
+	}
+	defer unlockFile(file)
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+	state := sharedState{Tokens: sb.capacity, LastRefill: time.Now()}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &state); err != nil {
+			return fmt.Errorf("corrupt rate limit file: %w", err)
+		}
+	}
+
+	fn(&state)
+
+	data, err = json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if err := file.Truncate(0); err != nil {
+		return err
+	}
+	_, err = file.WriteAt(data, 0)
+	return err
+}
@@ -0,0 +1,144 @@
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// State is a limiter's state saved between runs. Token buckets use Tokens
+// and LastRefill, sliding windows Requests.
+type State struct {
+	Tokens     int         `json:"tokens"`
+	LastRefill time.Time   `json:"last_refill"`
+	Requests   []time.Time `json:"requests,omitempty"`
+}
+
+// Stateful is a limiter whose state can be saved and restored
+type Stateful interface {
+	Limiter
+	// State returns the current state
+	State() State
+	// Restore continues from a saved state
+	Restore(state State)
+}
+
+// Persistent keeps a limiter's state in a file, so a run started right after
+// a heavy one continues with what is left of its budget instead of a full
+// one. The state is restored when the file is opened and saved after every
+// request the limiter allows, so it survives runs that exit without cleaning
+// up.
+type Persistent struct {
+	Stateful
+	path string
+	mu   sync.Mutex // orders saves
+}
+
+// NewPersistent restores limiter from the state file at path, if there is
+// one, and saves its state there from then on. An unreadable state file is
+// reported and replaced on the next save.
+func NewPersistent(limiter Stateful, path string) (*Persistent, error) {
+	p := &Persistent{Stateful: limiter, path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return p, nil
+	}
+	if err != nil {
+		return p, fmt.Errorf("failed to read rate limit state: %w", err)
+	}
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return p, fmt.Errorf("corrupt rate limit state: %w", err)
+	}
+	limiter.Restore(state)
+	return p, nil
+}
+
+// Allow checks if a request is allowed, saving the state if it is
+func (p *Persistent) Allow() bool {
+	if !p.Stateful.Allow() {
+		return false
+	}
+	p.Save()
+	return true
+}
+
+// Wait blocks until a request is allowed and saves the state
+func (p *Persistent) Wait() {
+	p.Stateful.Wait()
+	p.Save()
+}
+
+// WaitContext blocks until a request is allowed or ctx is done, saving the
+// state if the request was allowed
+func (p *Persistent) WaitContext(ctx context.Context) error {
+	if err := p.Stateful.WaitContext(ctx); err != nil {
+		return err
+	}
+	p.Save()
+	return nil
+}
+
+// TryWait waits at most maxWait for a request to be allowed, saving the state
+// if it was
+func (p *Persistent) TryWait(maxWait time.Duration) bool {
+	if !p.Stateful.TryWait(maxWait) {
+		return false
+	}
+	p.Save()
+	return true
+}
+
+// Reset resets the limiter and saves its state
+func (p *Persistent) Reset() {
+	p.Stateful.Reset()
+	p.Save()
+}
+
+// Capacity returns the capacity of the limiter, or 0 if it cannot be
+// adjusted
+func (p *Persistent) Capacity() int {
+	if adjustable, ok := p.Stateful.(Adjustable); ok {
+		return adjustable.Capacity()
+	}
+	return 0
+}
+
+// SetCapacity changes the capacity of the limiter, if it can be adjusted,
+// and saves its state
+func (p *Persistent) SetCapacity(capacity int) {
+	if adjustable, ok := p.Stateful.(Adjustable); ok {
+		adjustable.SetCapacity(capacity)
+		p.Save()
+	}
+}
+
+// Save writes the limiter's state to the file
+func (p *Persistent) Save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	data, err := json.Marshal(p.State())
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p.path), 0700); err != nil {
+		return fmt.Errorf("failed to create rate limit state directory: %w", err)
+	}
+	tempFile := p.path + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0600); err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("failed to save rate limit state: %w", err)
+	}
+	if err := os.Rename(tempFile, p.path); err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("failed to save rate limit state: %w", err)
+	}
+	return nil
+}
@@ -43,4 +43,11 @@
 //   - Rate limit errors: Longer delays with less aggressive backoff
 //   - Server errors: Moderate delays with exponential backoff
 //   - Auth/NotFound errors: No retry (non-retryable)
+//
+// Endpoint classes (profile, media_page, photo_download) can each have their
+// own policy. NewRegistryFromConfig builds a Registry from the retry settings,
+// and Registry.Config returns a Config for one class.
+//
+// When the server sends a Retry-After header, the Instagram client records it
+// on the error and the next attempt waits exactly that long instead.
 package retry
\ No newline at end of file
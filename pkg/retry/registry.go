@@ -0,0 +1,188 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"igscraper/pkg/config"
+	errs "igscraper/pkg/errors"
+	"igscraper/pkg/logger"
+)
+
+// Class names a kind of request that can be retried its own way
+type Class string
+
+// Endpoint classes with their own retry policies
+const (
+	ClassProfile       Class = "profile"
+	ClassMediaPage     Class = "media_page"
+	ClassPhotoDownload Class = "photo_download"
+)
+
+// DefaultProfileAttempts is how many times a profile lookup is made unless
+// configured otherwise. The client already retries each request, so this
+// only covers failures that outlast those retries.
+const DefaultProfileAttempts = 2
+
+// Policy is how requests of one endpoint class are retried
+type Policy struct {
+	// MaxAttempts is the maximum number of attempts (0 means unlimited)
+	MaxAttempts int
+	// Backoff strategy to use
+	Backoff BackoffStrategy
+	// RetryIf determines if an error should be retried
+	RetryIf func(error) bool
+}
+
+// Registry maps endpoint classes to retry policies. Classes without a policy
+// of their own use the fallback policy.
+type Registry struct {
+	mu       sync.RWMutex
+	policies map[Class]Policy
+	fallback Policy
+}
+
+// NewRegistry creates a registry that uses fallback for every class until
+// another policy is registered
+func NewRegistry(fallback Policy) *Registry {
+	return &Registry{
+		policies: make(map[Class]Policy),
+		fallback: fallback,
+	}
+}
+
+// NewRegistryFromConfig creates a registry from the retry settings. Media
+// pages use the general settings, photo downloads the network error settings
+// and profile lookups make DefaultProfileAttempts attempts on transient
+// errors. Each class's entry in cfg.Endpoints then overrides those defaults.
+// When retries are disabled, every class makes a single attempt.
+func NewRegistryFromConfig(cfg *config.RetryConfig) *Registry {
+	general := Policy{
+		MaxAttempts: max(cfg.MaxAttempts, 1),
+		Backoff: &ExponentialBackoff{
+			BaseDelay:    cfg.BaseDelay,
+			MaxDelay:     cfg.MaxDelay,
+			Multiplier:   cfg.Multiplier,
+			JitterFactor: cfg.JitterFactor,
+		},
+		RetryIf: DefaultRetryIf,
+	}
+	if !cfg.Enabled {
+		general.MaxAttempts = 1
+		return NewRegistry(general)
+	}
+
+	r := NewRegistry(general)
+	defaults := map[Class]Policy{
+		ClassMediaPage: general,
+		ClassPhotoDownload: {
+			MaxAttempts: max(cfg.NetworkRetries, 1),
+			Backoff: &ExponentialBackoff{
+				BaseDelay:    cfg.NetworkBaseDelay,
+				MaxDelay:     cfg.MaxDelay,
+				Multiplier:   cfg.Multiplier,
+				JitterFactor: cfg.JitterFactor,
+			},
+			RetryIf: DefaultRetryIf,
+		},
+		ClassProfile: {
+			MaxAttempts: DefaultProfileAttempts,
+			Backoff:     general.Backoff,
+			RetryIf:     RetryOn(errs.ErrorTypeNetwork, errs.ErrorTypeRateLimit, errs.ErrorTypeServerError),
+		},
+	}
+	for class, policy := range defaults {
+		if override, ok := cfg.Endpoints[string(class)]; ok {
+			policy = applyOverride(policy, override)
+		}
+		r.Register(class, policy)
+	}
+	return r
+}
+
+// applyOverride returns policy with the settings override sets
+func applyOverride(policy Policy, override config.EndpointRetryConfig) Policy {
+	if override.MaxAttempts > 0 {
+		policy.MaxAttempts = override.MaxAttempts
+	}
+
+	backoff := &ExponentialBackoff{}
+	if exp, ok := policy.Backoff.(*ExponentialBackoff); ok {
+		*backoff = *exp
+	}
+	if override.BaseDelay > 0 {
+		backoff.BaseDelay = override.BaseDelay
+	}
+	if override.MaxDelay > 0 {
+		backoff.MaxDelay = override.MaxDelay
+	}
+	if override.Multiplier > 0 {
+		backoff.Multiplier = override.Multiplier
+	}
+	if override.JitterFactor > 0 {
+		backoff.JitterFactor = override.JitterFactor
+	}
+	policy.Backoff = backoff
+
+	if len(override.RetryOn) > 0 {
+		types := make([]errs.ErrorType, len(override.RetryOn))
+		for i, name := range override.RetryOn {
+			types[i] = errs.ErrorType(name)
+		}
+		policy.RetryIf = RetryOn(types...)
+	}
+	return policy
+}
+
+// Register sets the policy for an endpoint class
+func (r *Registry) Register(class Class, policy Policy) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.policies[class] = policy
+}
+
+// Policy returns the policy for an endpoint class, or the fallback policy if
+// the class has none
+func (r *Registry) Policy(class Class) Policy {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if policy, ok := r.policies[class]; ok {
+		return policy
+	}
+	return r.fallback
+}
+
+// Config returns a retry configuration following the policy for an endpoint
+// class. Callers may set OnRetry on the result.
+func (r *Registry) Config(class Class, ctx context.Context, log logger.Logger) *Config {
+	policy := r.Policy(class)
+	retryIf := policy.RetryIf
+	if retryIf == nil {
+		retryIf = DefaultRetryIf
+	}
+	return &Config{
+		MaxAttempts: policy.MaxAttempts,
+		Backoff:     policy.Backoff,
+		RetryIf:     retryIf,
+		Context:     ctx,
+		Logger:      log,
+	}
+}
+
+// RetryOn returns a predicate that retries errors of the given types only.
+// Errors that are not API errors are not retried.
+func RetryOn(types ...errs.ErrorType) func(error) bool {
+	return func(err error) bool {
+		var apiErr *errs.Error
+		if !errors.As(err, &apiErr) {
+			return false
+		}
+		for _, t := range types {
+			if apiErr.Type == t {
+				return true
+			}
+		}
+		return false
+	}
+}
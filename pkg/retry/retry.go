@@ -53,7 +53,7 @@ func DefaultRetryIf(err error) bool {
 	// Check if it's an API error
 	var apiErr *errs.Error
 	if errors.As(err, &apiErr) {
-		return errs.IsRetryable(apiErr.Type)
+		return apiErr.Retryable()
 	}
 	
 	// Check for context errors (don't retry)
@@ -81,7 +81,8 @@ func IsRetryable(statusCode int) bool {
 	}
 }
 
-// Do executes an operation with retry logic
+// Do executes an operation with retry logic. Errors carrying a Retry-After
+// delay are retried after that delay instead of the backoff's.
 func Do(op Operation, cfg *Config) error {
 	if cfg == nil {
 		cfg = DefaultConfig()
@@ -128,8 +129,12 @@ func Do(op Operation, cfg *Config) error {
 			return err
 		}
 		
-		// Calculate delay
+		// Calculate delay; a delay the server asked for in a Retry-After
+		// header replaces the backoff
 		delay := cfg.Backoff.NextDelay(attempt)
+		if retryAfter := errs.RetryAfter(err); retryAfter > 0 {
+			delay = retryAfter
+		}
 		
 		// Call OnRetry callback if provided
 		if cfg.OnRetry != nil {
@@ -241,7 +246,8 @@ func NewHTTPRetrier(maxAttempts int, logger logger.Logger) *HTTPRetrier {
 	}
 }
 
-// DoWithErrorType executes an operation with error-type specific backoff
+// DoWithErrorType executes an operation with error-type specific backoff,
+// or after the server's Retry-After delay when the error carries one
 func (hr *HTTPRetrier) DoWithErrorType(op Operation) error {
 	return Do(op, &Config{
 		MaxAttempts: hr.config.MaxAttempts,
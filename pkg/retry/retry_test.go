@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"igscraper/pkg/config"
 	errs "igscraper/pkg/errors"
 )
 
@@ -141,6 +142,50 @@ func TestRetryWithNonRetryableError(t *testing.T) {
 	}
 }
 
+func TestDefaultRetryIfSpam(t *testing.T) {
+	rateLimited := &errs.Error{Type: errs.ErrorTypeRateLimit, Code: 429}
+	if !DefaultRetryIf(rateLimited) {
+		t.Error("Expected rate limit error to be retried")
+	}
+	
+	rateLimited.Payload = &errs.Payload{Message: "feedback_required", Spam: true}
+	if DefaultRetryIf(rateLimited) {
+		t.Error("Expected rate limit error flagged as spam not to be retried")
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	attempts := 0
+	op := func() error {
+		attempts++
+		if attempts == 1 {
+			return &errs.Error{Type: errs.ErrorTypeRateLimit, Code: 429, RetryAfter: 30 * time.Millisecond}
+		}
+		return &errs.Error{Type: errs.ErrorTypeRateLimit, Code: 429}
+	}
+
+	var delays []time.Duration
+	cfg := &Config{
+		MaxAttempts: 3,
+		Backoff:     &ConstantBackoff{Delay: 10 * time.Millisecond},
+		RetryIf:     DefaultRetryIf,
+		OnRetry: func(attempt int, err error, delay time.Duration) {
+			delays = append(delays, delay)
+		},
+		Context: context.Background(),
+	}
+
+	if err := Do(op, cfg); err == nil {
+		t.Error("Expected error when max attempts exceeded")
+	}
+	// The server's delay replaces the backoff, which applies again once it
+	// stops sending one
+	want := []time.Duration{30 * time.Millisecond, 10 * time.Millisecond, 10 * time.Millisecond}
+	if len(delays) != len(want) || delays[0] != want[0] || delays[1] != want[1] {
+		t.Errorf("Expected delays %v, got %v", want, delays)
+	}
+}
+
 func TestRetryWithContextCancellation(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	attempts := 0
@@ -248,4 +293,78 @@ func TestDoWithResult(t *testing.T) {
 	if attempts != 2 {
 		t.Errorf("Expected 2 attempts, got %d", attempts)
 	}
-}
\ No newline at end of file
+}
+func TestRegistryFromConfig(t *testing.T) {
+	cfg := config.DefaultConfig().Retry
+	cfg.Endpoints = map[string]config.EndpointRetryConfig{
+		"media_page": {MaxAttempts: 6, BaseDelay: 10 * time.Second, RetryOn: []string{"rate_limit"}},
+	}
+	r := NewRegistryFromConfig(&cfg)
+
+	page := r.Policy(ClassMediaPage)
+	if page.MaxAttempts != 6 {
+		t.Errorf("Expected 6 media page attempts, got %d", page.MaxAttempts)
+	}
+	backoff := page.Backoff.(*ExponentialBackoff)
+	if backoff.BaseDelay != 10*time.Second || backoff.MaxDelay != cfg.MaxDelay {
+		t.Errorf("Expected the base delay overridden and the max delay kept, got %+v", backoff)
+	}
+	if page.RetryIf(&errs.Error{Type: errs.ErrorTypeNetwork}) || !page.RetryIf(&errs.Error{Type: errs.ErrorTypeRateLimit}) {
+		t.Error("Expected media pages to retry rate limits only")
+	}
+
+	photo := r.Policy(ClassPhotoDownload)
+	if photo.MaxAttempts != cfg.NetworkRetries {
+		t.Errorf("Expected %d photo download attempts, got %d", cfg.NetworkRetries, photo.MaxAttempts)
+	}
+	if photo.Backoff.(*ExponentialBackoff).BaseDelay != cfg.NetworkBaseDelay {
+		t.Error("Expected photo downloads to use the network base delay")
+	}
+
+	profile := r.Policy(ClassProfile)
+	if profile.MaxAttempts != DefaultProfileAttempts {
+		t.Errorf("Expected %d profile attempts, got %d", DefaultProfileAttempts, profile.MaxAttempts)
+	}
+	if profile.RetryIf(errors.New("unknown")) {
+		t.Error("Expected profile lookups not to retry errors that are not API errors")
+	}
+
+	if other := r.Policy("stories"); other.MaxAttempts != cfg.MaxAttempts {
+		t.Errorf("Expected unknown classes to use the general policy, got %d attempts", other.MaxAttempts)
+	}
+
+	cfg.Enabled = false
+	r = NewRegistryFromConfig(&cfg)
+	for _, class := range []Class{ClassProfile, ClassMediaPage, ClassPhotoDownload} {
+		if attempts := r.Config(class, context.Background(), nil).MaxAttempts; attempts != 1 {
+			t.Errorf("Expected a single %s attempt with retries disabled, got %d", class, attempts)
+		}
+	}
+}
+
+func TestRegistryConfig(t *testing.T) {
+	r := NewRegistry(Policy{MaxAttempts: 2, Backoff: &ConstantBackoff{Delay: time.Millisecond}})
+	r.Register(ClassPhotoDownload, Policy{
+		MaxAttempts: 4,
+		Backoff:     &ConstantBackoff{Delay: time.Millisecond},
+		RetryIf:     RetryOn(errs.ErrorTypeNetwork),
+	})
+
+	attempts := 0
+	err := Do(func() error {
+		attempts++
+		return &errs.Error{Type: errs.ErrorTypeNetwork}
+	}, r.Config(ClassPhotoDownload, context.Background(), nil))
+	if err == nil || attempts != 4 {
+		t.Errorf("Expected 4 attempts and an error, got %d attempts and %v", attempts, err)
+	}
+
+	attempts = 0
+	Do(func() error {
+		attempts++
+		return errors.New("temporary error")
+	}, r.Config(ClassMediaPage, context.Background(), nil))
+	if attempts != 2 {
+		t.Errorf("Expected the fallback policy's 2 attempts, got %d", attempts)
+	}
+}
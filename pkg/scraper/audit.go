@@ -0,0 +1,335 @@
+package scraper
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"igscraper/internal/downloader"
+	"igscraper/pkg/checkpoint"
+	"igscraper/pkg/instagram"
+	"igscraper/pkg/logger"
+	"igscraper/pkg/metadata"
+	"igscraper/pkg/storage"
+)
+
+// ProblemKind classifies what is wrong with a file of an archive
+type ProblemKind string
+
+const (
+	// ProblemMissing means a recorded download has no file
+	ProblemMissing ProblemKind = "missing"
+
+	// ProblemEmpty means the file has no content
+	ProblemEmpty ProblemKind = "empty"
+
+	// ProblemCorrupt means a .jpg file does not start like a JPEG image
+	ProblemCorrupt ProblemKind = "corrupt"
+
+	// ProblemSizeMismatch means the file's size differs from the one
+	// recorded in metadata.json
+	ProblemSizeMismatch ProblemKind = "size_mismatch"
+
+	// ProblemChecksumMismatch means the file's SHA-256 differs from the one
+	// recorded when it was saved, so it was damaged or altered since
+	ProblemChecksumMismatch ProblemKind = "checksum_mismatch"
+)
+
+// ArchiveProblem is a file of an archive that is missing or damaged
+type ArchiveProblem struct {
+	Shortcode string
+	File      string // relative to the archive folder
+	Kind      ProblemKind
+	Detail    string
+}
+
+// AuditReport is the outcome of AuditArchive
+type AuditReport struct {
+	Username  string
+	OutputDir string
+
+	// Checked is the number of downloads recorded in metadata.json or the
+	// checkpoint, or found in the folder, that were checked
+	Checked int
+
+	// Problems lists the damaged and missing files, by shortcode
+	Problems []ArchiveProblem
+
+	// Repaired lists the shortcodes RepairArchive downloaded again, and
+	// Unrepaired maps those it could not to the error
+	Repaired   []string
+	Unrepaired map[string]error
+}
+
+// OK reports whether the archive has no problems left
+func (r *AuditReport) OK() bool {
+	return len(r.Problems) == len(r.Repaired)
+}
+
+// jpegHeader is how every JPEG file starts: the start-of-image marker and
+// the first segment's marker
+var jpegHeader = []byte{0xFF, 0xD8, 0xFF}
+
+// AuditArchive checks a profile's folder against its metadata.json and its
+// checkpoint, if a scrape of it is unfinished, without any request. A
+// download they record is missing if its file is not there, and a file is
+// damaged if it is empty, if it is a .jpg without a JPEG header, or if its
+// size or SHA-256 differs from the one recorded when it was saved. Files in
+// the folder that are not recorded are checked too.
+func (s *Scraper) AuditArchive(username string) (*AuditReport, error) {
+	outputDir := s.getOutputDir(username)
+	report := &AuditReport{Username: username, OutputDir: outputDir, Unrepaired: make(map[string]error)}
+
+	layout, err := storage.ParseLayout(s.config.Output.FileNamePattern)
+	if err != nil {
+		return nil, err
+	}
+	local, err := storage.ListPhotos(outputDir, layout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read output directory: %w", err)
+	}
+	meta, err := metadata.LoadUserMetadata(outputDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// The files the records expect, by shortcode, and their recorded sizes
+	// and hashes
+	files := make(map[string]string)
+	sizes := make(map[string]int64)
+	sums := make(map[string]string)
+	if meta != nil {
+		for _, photo := range meta.Photos {
+			switch {
+			case photo.DuplicateOf != "" && local[photo.Shortcode] == "":
+				// Skipped as a duplicate, so the earlier file is checked
+				files[photo.Shortcode] = photo.DuplicateOf
+			case photo.File != "":
+				files[photo.Shortcode] = photo.File
+			default:
+				files[photo.Shortcode] = local[photo.Shortcode]
+			}
+			sizes[photo.Shortcode] = photo.FileSize
+			sums[photo.Shortcode] = photo.SHA256
+		}
+	}
+	if cp, err := s.loadCheckpoint(username); err != nil {
+		s.logger.WithError(err).WithField("username", username).Warn("Failed to read checkpoint, checking metadata.json only")
+	} else if cp != nil {
+		for shortcode, name := range cp.DownloadedPhotos {
+			if _, ok := files[shortcode]; !ok {
+				files[shortcode] = name
+			}
+		}
+		for shortcode, digest := range cp.Files {
+			if sums[shortcode] == "" {
+				sizes[shortcode] = digest.Size
+				sums[shortcode] = digest.SHA256
+			}
+		}
+	}
+	for shortcode, name := range local {
+		if _, ok := files[shortcode]; !ok {
+			files[shortcode] = name
+		}
+	}
+
+	shortcodes := make([]string, 0, len(files))
+	for shortcode := range files {
+		shortcodes = append(shortcodes, shortcode)
+	}
+	sort.Strings(shortcodes)
+	for _, shortcode := range shortcodes {
+		report.Checked++
+		name := files[shortcode]
+		if name == "" {
+			report.Problems = append(report.Problems, ArchiveProblem{Shortcode: shortcode, Kind: ProblemMissing, Detail: "no file"})
+			continue
+		}
+		if problem, ok := checkFile(filepath.Join(outputDir, filepath.FromSlash(name)), sizes[shortcode], sums[shortcode]); ok {
+			problem.Shortcode = shortcode
+			problem.File = name
+			report.Problems = append(report.Problems, problem)
+		}
+	}
+
+	s.logger.InfoWithFields("Archive audited", map[string]interface{}{
+		"username":   username,
+		"output_dir": outputDir,
+		"checked":    report.Checked,
+		"problems":   len(report.Problems),
+	})
+	return report, nil
+}
+
+// checkFile looks for a problem with the file at path, whose recorded size is
+// size and hex SHA-256 is sum, or 0 and "" if none were recorded
+func checkFile(path string, size int64, sum string) (ArchiveProblem, bool) {
+	info, err := os.Stat(path)
+	switch {
+	case os.IsNotExist(err):
+		return ArchiveProblem{Kind: ProblemMissing, Detail: "file not found"}, true
+	case err != nil:
+		return ArchiveProblem{Kind: ProblemMissing, Detail: err.Error()}, true
+	case info.Size() == 0:
+		return ArchiveProblem{Kind: ProblemEmpty, Detail: "0 bytes"}, true
+	case size > 0 && info.Size() != size:
+		return ArchiveProblem{Kind: ProblemSizeMismatch, Detail: fmt.Sprintf("%d bytes, %d recorded", info.Size(), size)}, true
+	}
+
+	if ext := strings.ToLower(filepath.Ext(path)); ext == ".jpg" || ext == ".jpeg" {
+		file, err := os.Open(path)
+		if err != nil {
+			return ArchiveProblem{Kind: ProblemCorrupt, Detail: err.Error()}, true
+		}
+		defer file.Close()
+		header := make([]byte, len(jpegHeader))
+		if _, err := io.ReadFull(file, header); err != nil || !bytes.Equal(header, jpegHeader) {
+			return ArchiveProblem{Kind: ProblemCorrupt, Detail: "not a JPEG image"}, true
+		}
+	}
+	if sum != "" {
+		actual, _, err := storage.HashFile(path)
+		if err != nil {
+			return ArchiveProblem{Kind: ProblemCorrupt, Detail: err.Error()}, true
+		}
+		if actual != sum {
+			return ArchiveProblem{Kind: ProblemChecksumMismatch, Detail: fmt.Sprintf("SHA-256 %.12s…, %.12s… recorded", actual, sum)}, true
+		}
+	}
+	return ArchiveProblem{}, false
+}
+
+// loadCheckpoint returns the checkpoint of an unfinished scrape of username,
+// or nil if there is none
+func (s *Scraper) loadCheckpoint(username string) (*checkpoint.Checkpoint, error) {
+	mgr, err := checkpoint.NewManager(username)
+	if err != nil {
+		return nil, err
+	}
+	mgr.SetLogger(s.namedLogger(logger.ComponentCheckpoint))
+	if !mgr.Exists() {
+		return nil, nil
+	}
+	return mgr.Load()
+}
+
+// RepairArchive downloads again the files AuditArchive found missing or
+// damaged. Each post is fetched anew, as the media links recorded when it
+// was first downloaded expire, and damaged files are removed first. The
+// media saved replace their entries in metadata.json.
+func (s *Scraper) RepairArchive(report *AuditReport) error {
+	if len(report.Problems) == 0 {
+		return nil
+	}
+	for _, problem := range report.Problems {
+		if problem.Kind != ProblemMissing && problem.File != "" {
+			path := filepath.Join(report.OutputDir, filepath.FromSlash(problem.File))
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				report.Unrepaired[problem.Shortcode] = fmt.Errorf("failed to remove damaged file: %w", err)
+			}
+		}
+	}
+
+	storageManager, err := storage.NewManagerWithLogger(report.OutputDir, s.namedLogger(logger.ComponentStorage))
+	if err != nil {
+		return fmt.Errorf("failed to create storage manager: %w", err)
+	}
+	layout, err := storage.ParseLayout(s.config.Output.FileNamePattern)
+	if err != nil {
+		return err
+	}
+	if err := storageManager.SetLayout(layout); err != nil {
+		return fmt.Errorf("failed to create storage manager: %w", err)
+	}
+	storageManager.SetEmbedMetadata(s.config.Download.EmbedMetadata)
+	storageManager.SetPostProcess(s.postProcess)
+	storageManager.SetGalleryThumbnails(s.config.Output.GalleryThumbnails)
+	storageManager.InitializeUserMetadata(report.Username, "", len(report.Problems))
+	storageManager.SetScrapeID(s.scrapeID)
+
+	// Fresh links are fetched up front, one request per post
+	var jobs []downloader.DownloadJob
+	for _, problem := range report.Problems {
+		if report.Unrepaired[problem.Shortcode] != nil {
+			continue
+		}
+		node, err := s.fetchMediaNode(problem.Shortcode)
+		if err != nil {
+			report.Unrepaired[problem.Shortcode] = err
+			continue
+		}
+		node.SelectImage(s.config.Download.PreferredResolution)
+		jobs = append(jobs, downloader.DownloadJob{
+			URL:       mediaURL(node),
+			Shortcode: problem.Shortcode,
+			Username:  report.Username,
+			Node:      node,
+		})
+	}
+
+	pool := downloader.NewWorkerPool(s.config.Download.ConcurrentDownloads, s.client, storageManager, s.rateLimiter, s.namedLogger(logger.ComponentDownloader))
+	configurePool(pool, s.config.Download)
+	pool.Start()
+	go func() {
+		for _, job := range jobs {
+			if err := pool.Submit(job); err != nil {
+				s.logger.WithError(err).WithField("shortcode", job.Shortcode).Error("Failed to submit download job")
+			}
+		}
+		pool.Stop()
+	}()
+
+	for result := range pool.Results() {
+		if result.Success {
+			report.Repaired = append(report.Repaired, result.Job.Shortcode)
+		} else {
+			report.Unrepaired[result.Job.Shortcode] = result.Error
+		}
+	}
+	s.recordSaved(report.OutputDir, storageManager.GetUserMetadata())
+
+	s.logger.InfoWithFields("Archive repaired", map[string]interface{}{
+		"username":   report.Username,
+		"repaired":   len(report.Repaired),
+		"unrepaired": len(report.Unrepaired),
+	})
+	return nil
+}
+
+// fetchMediaNode fetches the post of a shortcode, such as "C1a2B3c4D5e", or
+// of one medium of a carousel, such as "C1a2B3c4D5e_2", and returns its node
+func (s *Scraper) fetchMediaNode(shortcode string) (*instagram.Node, error) {
+	code := shortcode
+	if i := strings.LastIndex(shortcode, "_"); i > 0 {
+		code = shortcode[:i]
+	}
+	mediaID, err := instagram.MediaID(code)
+	if err != nil {
+		return nil, err
+	}
+	var info instagram.FeedResponse
+	if err := s.client.GetJSON(instagram.GetMediaInfoURL(mediaID), &info); err != nil {
+		return nil, fmt.Errorf("failed to fetch post: %w", err)
+	}
+	if len(info.Items) == 0 {
+		return nil, fmt.Errorf("post %s was not found", code)
+	}
+
+	item := &info.Items[0]
+	if shortcode == item.Code {
+		node := item.ToNode()
+		return &node, nil
+	}
+	nodes := item.MediaNodes()
+	for i := range nodes {
+		if nodes[i].Shortcode == shortcode {
+			return &nodes[i], nil
+		}
+	}
+	return nil, fmt.Errorf("post %s has no medium %s", code, shortcode)
+}
@@ -0,0 +1,136 @@
+package scraper
+
+import (
+	stderrors "errors"
+	"io"
+	"net/http"
+	"sync"
+
+	"igscraper/pkg/errors"
+	"igscraper/pkg/instagram"
+	"igscraper/pkg/ratelimit"
+	"igscraper/pkg/ui"
+)
+
+// browserClient passes calls to an InstagramClient and repeats the API calls
+// Instagram refuses through a fallback, normally a headless browser. Photo
+// and video downloads always use the client.
+type browserClient struct {
+	InstagramClient
+	fallback   JSONFetcher
+	onFallback func(err error)
+	once       sync.Once
+}
+
+// SetBrowserFallback makes the scraper repeat API calls that are blocked,
+// by a login wall, 401 or 403, through fallback. It is a last resort: the
+// browser is far slower than the client. Call it after SetAccountRotator so
+// other accounts are tried first.
+func (s *Scraper) SetBrowserFallback(fallback JSONFetcher) {
+	s.client = &browserClient{
+		InstagramClient: s.client,
+		fallback:        fallback,
+		onFallback:      s.reportBrowserFallback,
+	}
+	s.logger.Info("Headless browser fallback enabled")
+}
+
+// GetJSON performs a GET request, through the fallback if it is blocked
+func (c *browserClient) GetJSON(url string, target interface{}) error {
+	err := c.InstagramClient.GetJSON(url, target)
+	if !blocked(err) {
+		return err
+	}
+	c.report(err)
+	return c.fallback.GetJSON(url, target)
+}
+
+// FetchUserProfile fetches a profile, through the fallback if it is blocked
+func (c *browserClient) FetchUserProfile(username string) (*instagram.InstagramResponse, error) {
+	resp, err := c.InstagramClient.FetchUserProfile(username)
+	if !blocked(err) {
+		return resp, err
+	}
+	c.report(err)
+
+	var response instagram.InstagramResponse
+	if err := c.fallback.GetJSON(instagram.GetProfileURL(username), &response); err != nil {
+		return nil, err
+	}
+	if response.LoginRequired() {
+		return nil, &errors.Error{
+			Type:    errors.ErrorTypeAuth,
+			Message: "Instagram requires authentication to view this profile",
+			Code:    http.StatusUnauthorized,
+		}
+	}
+	return &response, nil
+}
+
+// FetchUserMedia fetches a page of posts, through the fallback if it is
+// blocked
+func (c *browserClient) FetchUserMedia(userID string, after string) (*instagram.InstagramResponse, error) {
+	resp, err := c.InstagramClient.FetchUserMedia(userID, after)
+	if !blocked(err) {
+		return resp, err
+	}
+	c.report(err)
+
+	var response instagram.InstagramResponse
+	if err := c.fallback.GetJSON(instagram.GetMediaURL(userID, after), &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// KeepAlive checks the client's session. The browser shares its cookies, so
+// a session the client lost is not tried there.
+func (c *browserClient) KeepAlive() error {
+	return pingSession(c.InstagramClient)
+}
+
+// DownloadFile streams a photo from the CDN into w
+func (c *browserClient) DownloadFile(url string, w io.WriterAt) (int64, error) {
+	return downloadFile(c.InstagramClient, url, w)
+}
+
+// DownloadVideo streams a video from the CDN into w
+func (c *browserClient) DownloadVideo(url string, w io.WriterAt) (int64, error) {
+	return downloadVideo(c.InstagramClient, url, w)
+}
+
+// SetBandwidth passes the bandwidth limiter to the client
+func (c *browserClient) SetBandwidth(b *ratelimit.Bandwidth) {
+	setBandwidth(c.InstagramClient, b)
+}
+
+// report tells the scraper the first time the fallback is used
+func (c *browserClient) report(err error) {
+	if c.onFallback != nil {
+		c.once.Do(func() { c.onFallback(err) })
+	}
+}
+
+// blocked reports whether err means the JSON APIs refused the session: a
+// login wall served instead of JSON, 401 or 403. Rate limits and security
+// checks are not blocks; a browser would be limited or challenged too.
+func blocked(err error) bool {
+	var apiErr *errors.Error
+	if !stderrors.As(err, &apiErr) || apiErr.Type == errors.ErrorTypeChallenge {
+		return false
+	}
+	return apiErr.Type == errors.ErrorTypeAuth ||
+		apiErr.Type == errors.ErrorTypeParsing ||
+		apiErr.Code == http.StatusForbidden
+}
+
+// reportBrowserFallback logs the switch to the browser and shows it to the
+// user
+func (s *Scraper) reportBrowserFallback(err error) {
+	s.logger.WithError(err).Warn("Instagram API blocked, retrying through the headless browser")
+	if s.tui != nil {
+		s.tui.LogWarning("Instagram API blocked (%v), retrying through the headless browser", err)
+		return
+	}
+	ui.PrintWarning("Instagram API blocked, using the headless browser", err)
+}
@@ -0,0 +1,96 @@
+package scraper
+
+import (
+	"fmt"
+	"time"
+
+	"igscraper/pkg/instagram"
+	"igscraper/pkg/metadata"
+)
+
+// saveComments saves the comments of a post queued for download into the
+// comments folder of outputDir, unless they were saved before. Failures are
+// logged and do not stop the download.
+func (s *Scraper) saveComments(outputDir, shortcode string) {
+	if saved, err := metadata.LoadComments(outputDir, shortcode); err == nil && saved != nil {
+		return
+	}
+
+	comments, err := s.fetchComments(shortcode)
+	if err != nil {
+		s.logger.WithError(err).WithField("shortcode", shortcode).Warn("Failed to fetch comments")
+		if s.tui != nil {
+			s.tui.LogWarning("Failed to fetch comments of %s: %v", shortcode, err)
+		}
+		return
+	}
+	if err := comments.Save(outputDir); err != nil {
+		s.logger.WithError(err).WithField("shortcode", shortcode).Warn("Failed to save comments")
+		return
+	}
+	s.logger.DebugWithFields("Comments saved", map[string]interface{}{
+		"shortcode": shortcode,
+		"comments":  len(comments.Comments),
+		"total":     comments.Total,
+		"truncated": comments.Truncated,
+	})
+}
+
+// fetchComments pages through a post's comments until there are no more or
+// max_comments of them have been fetched. Every page waits for the rate
+// limiter, like any other API request.
+func (s *Scraper) fetchComments(shortcode string) (*metadata.PostComments, error) {
+	mediaID, err := instagram.MediaID(shortcode)
+	if err != nil {
+		return nil, err
+	}
+	limit := s.config.Download.MaxComments
+
+	comments := &metadata.PostComments{
+		Shortcode: shortcode,
+		MediaID:   mediaID,
+		Comments:  []metadata.Comment{},
+	}
+	minID := ""
+	for {
+		if err := s.rateLimiter.WaitContext(s.runContext()); err != nil {
+			return nil, err
+		}
+		var page instagram.CommentsResponse
+		if err := s.client.GetJSON(instagram.GetCommentsURL(mediaID, minID), &page); err != nil {
+			return nil, fmt.Errorf("failed to fetch comments: %w", err)
+		}
+		if page.Status != "" && page.Status != "ok" {
+			return nil, fmt.Errorf("failed to fetch comments: status %q", page.Status)
+		}
+		comments.Total = max(comments.Total, page.CommentCount)
+
+		for _, comment := range page.Comments {
+			if len(comments.Comments) >= limit {
+				comments.Truncated = true
+				break
+			}
+			comments.Comments = append(comments.Comments, metadata.Comment{
+				ID:        comment.PK.String(),
+				Username:  comment.User.Username,
+				UserID:    comment.User.PK.String(),
+				Text:      comment.Text,
+				CreatedAt: time.Unix(comment.CreatedAt, 0).UTC(),
+				Likes:     comment.LikeCount,
+				Replies:   comment.ChildCommentCount,
+			})
+		}
+
+		if !page.HasMoreComments || page.NextMinID == "" {
+			break
+		}
+		if len(comments.Comments) >= limit {
+			comments.Truncated = true
+			break
+		}
+		minID = page.NextMinID
+	}
+
+	comments.FetchedAt = time.Now().UTC()
+	return comments, nil
+}
@@ -0,0 +1,110 @@
+package scraper
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"igscraper/pkg/metadata"
+	"igscraper/pkg/ratelimit"
+)
+
+const (
+	// timelinePageSize is the number of posts fetchMediaBatch asks for per page
+	timelinePageSize = 50
+
+	// estimatedRequestTime and estimatedDownloadTime are the typical times
+	// of one timeline page and one photo download, used to estimate how long
+	// a run takes when the rate limit is not what holds it back
+	estimatedRequestTime  = time.Second
+	estimatedDownloadTime = 2 * time.Second
+)
+
+// Estimate is what a scrape of one profile would cost, worked out by
+// EstimateProfile without downloading anything
+type Estimate struct {
+	Username string
+
+	// Posts is the profile's post count and Archived the media already in
+	// its output directory, which are not downloaded again
+	Posts    int
+	Archived int
+
+	// APICalls counts the profile request and every timeline page
+	APICalls int
+
+	// Downloads counts the CDN requests for photos and videos. Carousels
+	// hold several media per post, so this is a lower bound.
+	Downloads int
+
+	// RateLimitWait is the time spent waiting for the rate limiter and
+	// Duration the projected wall-clock time of the whole run
+	RateLimitWait time.Duration
+	Duration      time.Duration
+}
+
+// FinishAt returns when a run started at start would finish
+func (e *Estimate) FinishAt(start time.Time) time.Time {
+	return start.Add(e.Duration)
+}
+
+// EstimateProfile works out the API calls, downloads and time a scrape of
+// username would take under the configured rate limit and concurrency. Only
+// the profile request is made. max_posts and max_pages cap the posts looked
+// at; date ranges, filters and min_likes are not taken into account, since
+// they can only be applied to fetched posts.
+func (s *Scraper) EstimateProfile(username string) (*Estimate, error) {
+	outputDir := s.getOutputDir(username)
+	_, total, err := s.profileInfo(username, outputDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user info: %w", err)
+	}
+	if total < 0 {
+		return nil, fmt.Errorf("the post count of %s is unavailable", username)
+	}
+
+	estimate := &Estimate{
+		Username: username,
+		Posts:    total,
+	}
+	if meta, err := metadata.LoadUserMetadata(outputDir); err == nil && meta != nil && strings.EqualFold(meta.Username, username) {
+		estimate.Archived = len(meta.Photos)
+	}
+
+	// Every page is fetched, even when all of its posts are already archived
+	posts := total
+	if maxPosts := s.config.Download.MaxPosts; maxPosts > 0 {
+		posts = min(posts, maxPosts)
+	}
+	if maxPages := s.config.Download.MaxPages; maxPages > 0 {
+		posts = min(posts, maxPages*timelinePageSize)
+	}
+	pages := max((posts+timelinePageSize-1)/timelinePageSize, 1)
+	estimate.APICalls = 1 + pages
+	// Posts since the last sync are the newest, so those are the ones missing
+	estimate.Downloads = min(posts, max(total-estimate.Archived, 0))
+
+	// Timeline pages and downloads take tokens from the same bucket
+	rpm := s.config.RateLimit.RequestsPerMinute
+	if rpm <= 0 {
+		rpm = 60
+	}
+	estimate.RateLimitWait = ratelimit.TokenBucketDuration(pages+estimate.Downloads, rpm, time.Minute)
+
+	// Pages are fetched one at a time while the workers download in parallel
+	workers := max(s.config.Download.ConcurrentDownloads, 1)
+	work := time.Duration(pages)*estimatedRequestTime +
+		time.Duration((estimate.Downloads+workers-1)/workers)*estimatedDownloadTime
+	estimate.Duration = max(estimate.RateLimitWait, work)
+
+	s.logger.InfoWithFields("Estimated scrape", map[string]interface{}{
+		"username":        username,
+		"posts":           estimate.Posts,
+		"archived":        estimate.Archived,
+		"api_calls":       estimate.APICalls,
+		"downloads":       estimate.Downloads,
+		"rate_limit_wait": estimate.RateLimitWait.String(),
+		"duration":        estimate.Duration.String(),
+	})
+	return estimate, nil
+}
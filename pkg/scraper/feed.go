@@ -0,0 +1,186 @@
+package scraper
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"igscraper/pkg/instagram"
+)
+
+const (
+	// LikedFolder is the directory under the output directory that holds the
+	// liked-posts archive
+	LikedFolder = "liked"
+
+	// SavedFolder is the directory under the output directory that holds
+	// saved posts, with one subfolder per collection
+	SavedFolder = "saved"
+)
+
+// feed is a paginated list of posts the scraper can archive: a profile's
+// timeline or one of the authenticated account's own feeds
+type feed struct {
+	name      string // shown in output and stored as the metadata username
+	key       string // checkpoint name; must not collide with a username
+	outputDir string
+
+	// chronological feeds are ordered by post date, newest first, after
+	// any pinned posts, so pagination can stop once it passes the since date
+	chronological bool
+
+	// resolve, if set, runs before the checkpoint is looked up and may point
+	// the feed at another name, checkpoint and output directory
+	resolve func() error
+
+	// info returns the feed's ID and post count; the count is -1 when the
+	// feed does not report one
+	info func() (id string, total int, err error)
+
+	// page fetches the page after cursor
+	page func(id, cursor string) ([]instagram.Edge, instagram.PageInfo, error)
+
+	// visit, if set, sees every post on every page before any filtering. On
+	// a resume it sees the posts of the pages before the checkpoint again,
+	// with only their shortcodes.
+	visit func(node *instagram.Node)
+
+	// exclude lists shortcodes that are never downloaded from this feed
+	exclude map[string]bool
+
+	// thumbnails saves the cover image of every video next to it
+	thumbnails bool
+
+	// limit stops pagination once this many posts have been queued; zero
+	// means no limit
+	limit int
+}
+
+// profileFeed is the timeline of a public or followed profile. With
+// snapshot, the first time the profile's details are fetched they are saved
+// as its profile snapshot. The profile is resolved by its user ID, so it is
+// archived in the same place after its username changes.
+func (s *Scraper) profileFeed(username string, snapshot bool) *feed {
+	f := &feed{
+		name:          username,
+		key:           username,
+		outputDir:     s.getOutputDir(username),
+		chronological: true,
+	}
+
+	// The details are fetched once, so resolving the profile costs no request
+	var user *instagram.User
+	var total int
+	fetch := func() error {
+		if user != nil {
+			return nil
+		}
+		var err error
+		user, total, err = s.profileInfo(f.name, f.outputDir)
+		return err
+	}
+
+	snapshotted := !snapshot
+	f.resolve = func() error {
+		return s.resolveProfile(f, func() (string, error) {
+			if err := fetch(); err != nil {
+				return "", err
+			}
+			return user.ID, nil
+		})
+	}
+	f.info = func() (string, int, error) {
+		if err := fetch(); err != nil {
+			return "", 0, err
+		}
+		// Without a post count the details came from the last sync
+		if total >= 0 && !snapshotted {
+			snapshotted = true
+			if _, err := s.saveProfile(user, f.outputDir); err != nil {
+				s.logger.WithError(err).WithField("username", f.name).Warn("Failed to save profile snapshot")
+			}
+		}
+		return user.ID, total, nil
+	}
+	f.page = func(userID, cursor string) ([]instagram.Edge, instagram.PageInfo, error) {
+		return s.fetchMediaBatch(f.name, userID, cursor)
+	}
+	return f
+}
+
+// userIDFeed is the timeline of a profile given by its numeric user ID,
+// which is fetched without looking up its username. A profile in the profile
+// index is archived under its recorded username and folder; otherwise the
+// user ID stands in for the username until the profile is scraped by name.
+// Without the profile request the post count is unknown.
+func (s *Scraper) userIDFeed(userID string) *feed {
+	f := &feed{
+		name:          userID,
+		key:           userID,
+		chronological: true,
+	}
+	idx, err := s.loadProfileIndex()
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to load profile index")
+	} else if entry, ok := idx.ByID(userID); ok && entry.Username != "" {
+		f.name = entry.Username
+		f.key = entry.Username
+		f.outputDir = idx.Dir(entry)
+	}
+	if f.outputDir == "" {
+		f.outputDir = s.getOutputDir(f.name)
+	}
+
+	f.resolve = func() error {
+		return s.resolveProfile(f, func() (string, error) {
+			return userID, nil
+		})
+	}
+	f.info = func() (string, int, error) {
+		return userID, -1, nil
+	}
+	f.page = func(userID, cursor string) ([]instagram.Edge, instagram.PageInfo, error) {
+		return s.fetchMediaBatch(f.name, userID, cursor)
+	}
+	return f
+}
+
+// likedFeed is the posts liked by the authenticated account, most recently
+// liked first. Instagram does not report its size.
+func (s *Scraper) likedFeed() *feed {
+	return &feed{
+		name: LikedFolder,
+		// Usernames cannot contain '-', so this never clashes with a profile
+		key:       "feed-liked",
+		outputDir: filepath.Join(s.config.Output.BaseDirectory, LikedFolder),
+		info: func() (string, int, error) {
+			return "", -1, nil
+		},
+		page: func(_, cursor string) ([]instagram.Edge, instagram.PageInfo, error) {
+			return s.fetchFeedPage(instagram.GetLikedFeedURL(cursor))
+		},
+	}
+}
+
+// DownloadLikedPosts archives the posts liked by the authenticated account
+// into the liked folder. Each post's metadata names its owner.
+func (s *Scraper) DownloadLikedPosts(resume bool, forceRestart bool) error {
+	return s.downloadFeed(s.likedFeed(), resume, forceRestart)
+}
+
+// fetchFeedPage fetches one page of a private API feed
+func (s *Scraper) fetchFeedPage(endpoint string) ([]instagram.Edge, instagram.PageInfo, error) {
+	s.logger.DebugWithFields("Fetching feed page", map[string]interface{}{
+		"endpoint": endpoint,
+	})
+
+	var result instagram.FeedResponse
+	if err := s.client.GetJSON(endpoint, &result); err != nil {
+		s.logger.WithError(err).WithField("endpoint", endpoint).Error("Failed to fetch feed page")
+		return nil, instagram.PageInfo{}, fmt.Errorf("failed to fetch feed: %w", err)
+	}
+	if result.Status != "" && result.Status != "ok" {
+		return nil, instagram.PageInfo{}, fmt.Errorf("failed to fetch feed: status %q", result.Status)
+	}
+
+	return result.Edges(), result.PageInfo(), nil
+}
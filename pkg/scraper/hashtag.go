@@ -0,0 +1,63 @@
+package scraper
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"igscraper/pkg/instagram"
+)
+
+// DownloadHashtag archives posts tagged with tag into a #<tag> folder under
+// the output directory: the most recent posts, or with top the hashtag's
+// top posts. A limit above zero stops after that many posts. Each post's
+// metadata names its owner.
+func (s *Scraper) DownloadHashtag(tag string, top bool, limit int, resume bool, forceRestart bool) error {
+	tag, err := instagram.ParseHashtag(tag)
+	if err != nil {
+		return err
+	}
+	return s.downloadFeed(s.hashtagFeed(tag, top, limit), resume, forceRestart)
+}
+
+// hashtagFeed is the recent or top posts of a hashtag. Recent posts are
+// newest first; top posts are ranked by engagement.
+func (s *Scraper) hashtagFeed(tag string, top bool, limit int) *feed {
+	// Usernames cannot contain '-', so these never clash with a profile
+	key := "tag-" + tag
+	if top {
+		key = "tag-top-" + tag
+	}
+	return &feed{
+		name:          "#" + tag,
+		key:           key,
+		outputDir:     filepath.Join(s.config.Output.BaseDirectory, "#"+tag),
+		chronological: !top,
+		info: func() (string, int, error) {
+			return "", -1, nil
+		},
+		page: func(_, cursor string) ([]instagram.Edge, instagram.PageInfo, error) {
+			return s.fetchHashtagPage(instagram.GetTagFeedURL(tag, cursor), top)
+		},
+		limit: limit,
+	}
+}
+
+// fetchHashtagPage fetches one page of a hashtag's posts
+func (s *Scraper) fetchHashtagPage(endpoint string, top bool) ([]instagram.Edge, instagram.PageInfo, error) {
+	s.logger.DebugWithFields("Fetching hashtag page", map[string]interface{}{
+		"endpoint": endpoint,
+		"top":      top,
+	})
+
+	var result instagram.TagFeedResponse
+	if err := s.client.GetJSON(endpoint, &result); err != nil {
+		s.logger.WithError(err).WithField("endpoint", endpoint).Error("Failed to fetch hashtag page")
+		return nil, instagram.PageInfo{}, fmt.Errorf("failed to fetch hashtag: %w", err)
+	}
+	if result.Status != "" && result.Status != "ok" {
+		return nil, instagram.PageInfo{}, fmt.Errorf("failed to fetch hashtag: status %q", result.Status)
+	}
+
+	page := result.Feed(top)
+	return page.Edges(), page.PageInfo(), nil
+}
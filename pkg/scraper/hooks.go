@@ -0,0 +1,101 @@
+package scraper
+
+import (
+	"context"
+	stderrors "errors"
+	"path/filepath"
+
+	"igscraper/internal/downloader"
+	"igscraper/pkg/hooks"
+	"igscraper/pkg/instagram"
+	"igscraper/pkg/storage"
+)
+
+// Scopes of the on_error hook: a download that failed every attempt, or a
+// scrape that stopped
+const (
+	hookScopeDownload = "download"
+	hookScopeScrape   = "scrape"
+)
+
+// downloadHook runs the post_download hook for a file saved by storageManager,
+// or the on_error hook when the download failed. Cancelled downloads run
+// neither.
+func (s *Scraper) downloadHook(username string, storageManager *storage.Manager, result downloader.DownloadResult) {
+	shortcode := result.Job.Shortcode
+	fields := map[string]interface{}{
+		"username":  username,
+		"shortcode": shortcode,
+		"post_url":  instagram.GetPostURL(shortcode),
+		"scrape_id": s.scrapeID,
+	}
+
+	switch {
+	case result.Success:
+		if !s.hooks.Enabled(hooks.PostDownload) {
+			return
+		}
+		fields["bytes"] = result.Size
+		if storageManager != nil {
+			if name := storageManager.FileName(shortcode); name != "" {
+				fields["path"] = filepath.Join(storageManager.GetOutputDir(), filepath.FromSlash(name))
+			}
+		}
+		if node := result.Job.Node; node != nil {
+			fields["media_type"] = mediaType(node)
+			if takenAt := node.TakenAt(); !takenAt.IsZero() {
+				fields["taken_at"] = takenAt
+			}
+		}
+		s.hooks.Run(hooks.PostDownload, fields)
+	case stderrors.Is(result.Error, downloader.ErrCancelled), stderrors.Is(result.Error, downloader.ErrSizeLimit):
+	default:
+		fields["scope"] = hookScopeDownload
+		fields["error"] = result.Error.Error()
+		fields["kind"] = failureKind(result.Error)
+		s.hooks.Run(hooks.OnError, fields)
+	}
+}
+
+// resultHook runs the on_complete hook of a finished scrape, or the on_error
+// hook of one that stopped early, after the hooks of its downloads. Scrapes
+// the user stopped run neither.
+func (s *Scraper) resultHook(name string, err error) {
+	defer s.hooks.Wait()
+	if stderrors.Is(err, context.Canceled) {
+		return
+	}
+
+	fields := map[string]interface{}{
+		"username":  name,
+		"scrape_id": s.scrapeID,
+	}
+	if s.storageManager != nil {
+		fields["output_dir"] = s.storageManager.GetOutputDir()
+	}
+	// The summary is only of this scrape if it got as far as starting one
+	if summary := s.summary; summary.Target == name {
+		fields["status"] = summary.Status
+		fields["downloaded"] = summary.Downloaded
+		fields["bytes"] = summary.Bytes
+		fields["skipped"] = summary.SkippedCount()
+		fields["failed"] = summary.FailedCount()
+		fields["duration_seconds"] = summary.Elapsed.Seconds()
+	}
+
+	if err == nil {
+		s.hooks.Run(hooks.OnComplete, fields)
+		return
+	}
+	fields["scope"] = hookScopeScrape
+	fields["error"] = err.Error()
+	s.hooks.Run(hooks.OnError, fields)
+}
+
+// mediaType names the kind of a post's media for hooks
+func mediaType(node *instagram.Node) string {
+	if node.IsVideo {
+		return "video"
+	}
+	return "photo"
+}
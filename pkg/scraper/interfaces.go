@@ -1,11 +1,52 @@
 package scraper
 
-import "igscraper/pkg/instagram"
+import (
+	"io"
 
-// InstagramClient defines the interface for Instagram API operations
-type InstagramClient interface {
+	"igscraper/internal/downloader"
+	"igscraper/pkg/igclient"
+	"igscraper/pkg/ratelimit"
+)
+
+// InstagramClient defines the interface for Instagram API operations. It is
+// igclient.InstagramClient, which clients of other packages can implement.
+type InstagramClient = igclient.InstagramClient
+
+// JSONFetcher fetches an Instagram JSON API by other means than the
+// InstagramClient, such as *browser.Browser
+type JSONFetcher interface {
 	GetJSON(url string, target interface{}) error
-	DownloadPhoto(photoURL string) ([]byte, error)
-	FetchUserProfile(username string) (*instagram.InstagramResponse, error)
-	FetchUserMedia(userID string, after string) (*instagram.InstagramResponse, error)
-}
\ No newline at end of file
+}
+
+// The helpers below let clients that wrap an InstagramClient pass on the
+// optional download features of the client they wrap.
+
+// downloadFile streams a photo from the CDN into w. Clients that cannot
+// stream download it into memory first.
+func downloadFile(client InstagramClient, url string, w io.WriterAt) (int64, error) {
+	if files, ok := client.(downloader.FileDownloader); ok {
+		return files.DownloadFile(url, w)
+	}
+	data, err := client.DownloadPhoto(url)
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.WriteAt(data, 0)
+	return int64(n), err
+}
+
+// downloadVideo streams a video from the CDN into w, in chunks when the
+// client supports them
+func downloadVideo(client InstagramClient, url string, w io.WriterAt) (int64, error) {
+	if videos, ok := client.(downloader.VideoDownloader); ok {
+		return videos.DownloadVideo(url, w)
+	}
+	return downloadFile(client, url, w)
+}
+
+// setBandwidth passes a bandwidth limiter to clients that throttle downloads
+func setBandwidth(client InstagramClient, b *ratelimit.Bandwidth) {
+	if c, ok := client.(bandwidthSetter); ok {
+		c.SetBandwidth(b)
+	}
+}
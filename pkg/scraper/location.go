@@ -0,0 +1,108 @@
+package scraper
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"igscraper/pkg/instagram"
+	"igscraper/pkg/metadata"
+)
+
+// LocationsFolder is the directory under the output directory that holds
+// location archives, with one subfolder per location ID
+const LocationsFolder = "locations"
+
+// DownloadLocation archives the posts geotagged at a location, given by its
+// ID or page URL, into locations/<id> under the output directory, most
+// recent first. The location's name, address and coordinates are recorded
+// in the folder's metadata.json, and each post's own location in its entry.
+func (s *Scraper) DownloadLocation(ref string, resume bool, forceRestart bool) error {
+	locationID, err := instagram.ParseLocationID(ref)
+	if err != nil {
+		return err
+	}
+
+	var location *instagram.Location
+	f := s.locationFeed(locationID, func(details *instagram.Location) {
+		if location == nil {
+			location = details
+		}
+	})
+	if err := s.downloadFeed(f, resume, forceRestart); err != nil {
+		return err
+	}
+	if location != nil {
+		s.recordLocation(f.outputDir, location)
+	}
+	return nil
+}
+
+// locationFeed is the most recent posts geotagged at a location. details is
+// called with the location's details from every page that has them.
+func (s *Scraper) locationFeed(locationID string, details func(*instagram.Location)) *feed {
+	return &feed{
+		// Usernames cannot contain '-', so this never clashes with a profile
+		name:          "location-" + locationID,
+		key:           "location-" + locationID,
+		outputDir:     filepath.Join(s.config.Output.BaseDirectory, LocationsFolder, locationID),
+		chronological: true,
+		info: func() (string, int, error) {
+			return "", -1, nil
+		},
+		page: func(_, cursor string) ([]instagram.Edge, instagram.PageInfo, error) {
+			edges, pageInfo, location, err := s.fetchLocationPage(instagram.GetLocationFeedURL(locationID, cursor))
+			if location != nil {
+				details(location)
+			}
+			return edges, pageInfo, err
+		},
+	}
+}
+
+// fetchLocationPage fetches one page of a location's posts, along with the
+// location's details when the page has them
+func (s *Scraper) fetchLocationPage(endpoint string) ([]instagram.Edge, instagram.PageInfo, *instagram.Location, error) {
+	s.logger.DebugWithFields("Fetching location page", map[string]interface{}{
+		"endpoint": endpoint,
+	})
+
+	var result instagram.LocationFeedResponse
+	if err := s.client.GetJSON(endpoint, &result); err != nil {
+		s.logger.WithError(err).WithField("endpoint", endpoint).Error("Failed to fetch location page")
+		return nil, instagram.PageInfo{}, nil, fmt.Errorf("failed to fetch location: %w", err)
+	}
+	if result.Status != "" && result.Status != "ok" {
+		return nil, instagram.PageInfo{}, nil, fmt.Errorf("failed to fetch location: status %q", result.Status)
+	}
+
+	var location *instagram.Location
+	if result.Location != nil {
+		location = result.Location.ToLocation()
+	}
+	page := result.Feed()
+	return page.Edges(), page.PageInfo(), location, nil
+}
+
+// recordLocation writes a location's details into the metadata of its folder
+func (s *Scraper) recordLocation(dir string, location *instagram.Location) {
+	meta, err := metadata.LoadUserMetadata(dir)
+	if err != nil {
+		s.logger.WithError(err).WithField("output_dir", dir).Warn("Failed to read metadata for location details")
+		return
+	}
+	if meta == nil {
+		return
+	}
+	meta.Location = &metadata.Location{
+		ID:      location.ID,
+		Name:    location.Name,
+		Slug:    location.Slug,
+		Address: location.Address,
+		City:    location.City,
+		Lat:     location.Lat,
+		Lng:     location.Lng,
+	}
+	if err := meta.Save(dir); err != nil {
+		s.logger.WithError(err).WithField("output_dir", dir).Warn("Failed to save location details")
+	}
+}
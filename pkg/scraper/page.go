@@ -0,0 +1,168 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"igscraper/pkg/errors"
+	"igscraper/pkg/instagram"
+	"igscraper/pkg/logger"
+	"igscraper/pkg/notify"
+	"igscraper/pkg/retry"
+	"igscraper/pkg/ui"
+)
+
+// PageError is returned when a page of a feed still cannot be fetched after
+// every retry. The checkpoint is kept, so the download can be resumed from
+// the failed page with --resume.
+type PageError struct {
+	Feed     string // name of the feed, usually the username
+	Page     int    // 1-based number of the page
+	Cursor   string // cursor the page was requested with
+	Attempts int
+	Err      error
+}
+
+func (e *PageError) Error() string {
+	return fmt.Sprintf("failed to fetch page %d of %s after %d attempts: %v", e.Page, e.Feed, e.Attempts, e.Err)
+}
+
+func (e *PageError) Unwrap() error {
+	return e.Err
+}
+
+// coolDown reports a wait of delay that Instagram asked for with a
+// Retry-After header
+func (s *Scraper) coolDown(delay time.Duration) {
+	s.summary.RateLimitPauses++
+	s.summary.RateLimitWait += delay
+	if sink := s.sink(); sink != nil {
+		sink.RateLimited(delay, s.requestsPerMinute())
+	} else {
+		ui.PrintWarning("Rate limited by Instagram, cooling down as asked", delay.Round(time.Second))
+	}
+}
+
+// SetContext sets the context that cancels waits between page retries and
+// for the rate limit, such as the daemon's shutdown. Cancelling it stops the
+// scrape with ErrInterrupted once the downloads in progress are done.
+func (s *Scraper) SetContext(ctx context.Context) {
+	s.ctx = ctx
+}
+
+// runContext returns the context set with SetContext, or a background
+// context if none was
+func (s *Scraper) runContext() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
+}
+
+// retryPolicies returns the retry policy for each endpoint class, following
+// the current retry settings
+func (s *Scraper) retryPolicies() *retry.Registry {
+	return retry.NewRegistryFromConfig(&s.config.Retry)
+}
+
+// nextPage fetches the page of a feed at cursor once the hourly API rate
+// limit allows it, cooling down until it does. Cancelling ctx ends the
+// cooldown and the retries with its error.
+func (s *Scraper) nextPage(ctx context.Context, f *feed, username, userID, cursor string, page int) ([]instagram.Edge, instagram.PageInfo, error) {
+	// Rate limit check for API calls (not downloads)
+	if !s.rateLimiter.Allow() {
+		s.namedLogger(logger.ComponentRateLimit).WarnWithFields("Rate limit reached, cooling down", map[string]interface{}{
+			"username":      username,
+			"endpoint":      "instagram_api",
+			"retry_after":   3600, // 1 hour in seconds
+			"action":        "rate_limited",
+			"cooldown_time": "1 hour",
+		})
+		s.notifier.Send(notify.Event{Kind: notify.KindRateLimit, Title: "RATE LIMIT", Message: "Cooling down for 1 hour..."})
+
+		if sink := s.sink(); sink != nil {
+			sink.RateLimited(time.Hour, s.requestsPerMinute())
+		} else {
+			ui.PrintWarning("\n[COOLING DOWN FOR 1 HOUR]\n")
+		}
+
+		pauseStart := time.Now()
+		err := s.rateLimiter.WaitContext(ctx)
+		s.summary.RateLimitPauses++
+		s.summary.RateLimitWait += time.Since(pauseStart)
+		if err != nil {
+			return nil, instagram.PageInfo{}, err
+		}
+
+		s.namedLogger(logger.ComponentRateLimit).Info("Rate limit cooldown completed, resuming")
+		s.notifier.Send(notify.Event{Kind: notify.KindRateLimit, Title: "RESUMING", Message: "Continuing extraction process"})
+		if s.tui != nil {
+			s.tui.LogInfo("Rate limit cooldown completed, resuming")
+			s.tui.UpdateRateLimit(0, s.requestsPerMinute(), time.Now().Add(time.Minute))
+		} else if s.progress == nil {
+			ui.PrintInfo("\nRESUMING", "Continuing extraction process")
+		}
+	}
+
+	s.logger.DebugWithFields("Fetching media batch", map[string]interface{}{
+		"username":   username,
+		"user_id":    userID,
+		"end_cursor": cursor,
+	})
+	return s.fetchPage(ctx, f, userID, cursor, page)
+}
+
+// fetchPage fetches one page of a feed, retrying failures as the media page
+// retry policy says: by default those that may be transient, with
+// exponential backoff up to the configured number of attempts. When
+// Instagram rate limits the request with a Retry-After header, the scraper
+// cools down for that long instead. Failures that will not go away, such as
+// a deleted profile, are not retried. Once the attempts are used up, or ctx
+// is cancelled, a *PageError is returned.
+func (s *Scraper) fetchPage(ctx context.Context, f *feed, userID, cursor string, page int) ([]instagram.Edge, instagram.PageInfo, error) {
+	cfg := s.retryPolicies().Config(retry.ClassMediaPage, ctx, s.logger)
+	cfg.OnRetry = func(attempt int, err error, delay time.Duration) {
+		s.logger.WithError(err).WithFields(map[string]interface{}{
+			"username":   f.name,
+			"page":       page,
+			"end_cursor": cursor,
+			"attempt":    attempt,
+			"delay":      delay.String(),
+		}).Warn("Error fetching media batch, retrying")
+		if errors.RetryAfter(err) > 0 {
+			s.coolDown(delay)
+			return
+		}
+		if s.tui != nil {
+			s.tui.LogWarning("Error fetching page %d (%v), retrying in %s", page, err, delay.Round(time.Second))
+		} else {
+			ui.PrintWarning("Error fetching media, retrying", fmt.Sprintf("%v (in %s)", err, delay.Round(time.Second)))
+		}
+	}
+
+	var media []instagram.Edge
+	var pageInfo instagram.PageInfo
+	attempts := 0
+	var lastErr error
+	err := retry.Do(func() error {
+		attempts++
+		var err error
+		media, pageInfo, err = f.page(userID, cursor)
+		lastErr = err
+		return err
+	}, cfg)
+	if err != nil {
+		if lastErr == nil || ctx.Err() != nil {
+			lastErr = err
+		}
+		return nil, instagram.PageInfo{}, &PageError{
+			Feed:     f.name,
+			Page:     page,
+			Cursor:   cursor,
+			Attempts: attempts,
+			Err:      lastErr,
+		}
+	}
+	return media, pageInfo, nil
+}
@@ -0,0 +1,153 @@
+package scraper
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"igscraper/internal/downloader"
+	"igscraper/pkg/instagram"
+	"igscraper/pkg/logger"
+	"igscraper/pkg/metadata"
+	"igscraper/pkg/storage"
+)
+
+// PostsFolder is the directory under the output directory that holds posts
+// downloaded one at a time
+const PostsFolder = "posts"
+
+// Post is a single post saved by DownloadPost
+type Post struct {
+	Shortcode string
+	OutputDir string
+
+	// Media has an entry for every photo or video of the post, in order,
+	// with the name of the file it was saved to
+	Media []metadata.PhotoMetadata
+
+	// Skipped lists media that were not downloaded because of skip_videos,
+	// skip_images or a missing video URL
+	Skipped []string
+
+	// Failed maps the shortcodes of media that could not be saved to the error
+	Failed map[string]error
+}
+
+// DownloadPost saves every photo and video of one post, given by its URL or
+// shortcode, into the posts folder. The media of a carousel are saved as
+// <shortcode>_1, <shortcode>_2 and so on. Media already in the folder are
+// not downloaded again.
+func (s *Scraper) DownloadPost(ref string) (*Post, error) {
+	shortcode, err := instagram.ParseShortcode(ref)
+	if err != nil {
+		return nil, err
+	}
+	mediaID, err := instagram.MediaID(shortcode)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.InfoWithFields("Fetching post", map[string]interface{}{
+		"shortcode": shortcode,
+		"media_id":  mediaID,
+	})
+	var info instagram.FeedResponse
+	if err := s.client.GetJSON(instagram.GetMediaInfoURL(mediaID), &info); err != nil {
+		s.logger.WithError(err).WithField("shortcode", shortcode).Error("Failed to fetch post")
+		return nil, fmt.Errorf("failed to fetch post: %w", err)
+	}
+	if info.Status != "" && info.Status != "ok" {
+		return nil, fmt.Errorf("failed to fetch post: status %q", info.Status)
+	}
+	if len(info.Items) == 0 {
+		return nil, fmt.Errorf("post %s was not found", shortcode)
+	}
+	item := &info.Items[0]
+	nodes := item.MediaNodes()
+
+	post := &Post{
+		Shortcode: shortcode,
+		OutputDir: filepath.Join(s.config.Output.BaseDirectory, PostsFolder),
+		Failed:    make(map[string]error),
+	}
+	storageManager, err := storage.NewManagerWithLogger(post.OutputDir, s.namedLogger(logger.ComponentStorage))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage manager: %w", err)
+	}
+	layout, err := storage.ParseLayout(s.config.Output.FileNamePattern)
+	if err != nil {
+		return nil, err
+	}
+	if err := storageManager.SetLayout(layout); err != nil {
+		return nil, fmt.Errorf("failed to create storage manager: %w", err)
+	}
+	storageManager.SetEmbedMetadata(s.config.Download.EmbedMetadata)
+	storageManager.SetPostProcess(s.postProcess)
+	storageManager.SetGalleryThumbnails(s.config.Output.GalleryThumbnails)
+	// Collects the media's metadata; the posts folder has no metadata.json
+	storageManager.InitializeUserMetadata(item.User.Username, item.User.PK.String(), len(nodes))
+
+	pool := downloader.NewWorkerPool(s.config.Download.ConcurrentDownloads, s.client, storageManager, s.rateLimiter, s.namedLogger(logger.ComponentDownloader))
+	configurePool(pool, s.config.Download)
+	pool.Start()
+	for i := range nodes {
+		node := &nodes[i]
+		if s.skipMedia(node) != "" {
+			post.Skipped = append(post.Skipped, node.Shortcode)
+			continue
+		}
+		node.SelectImage(s.config.Download.PreferredResolution)
+		job := downloader.DownloadJob{
+			URL:       mediaURL(node),
+			Shortcode: node.Shortcode,
+			Username:  item.User.Username,
+			Node:      node,
+			Overwrite: s.config.Output.OverwriteExisting,
+		}
+		if err := pool.Submit(job); err != nil {
+			post.Failed[node.Shortcode] = err
+		}
+	}
+	go pool.Stop()
+	if s.config.Download.Comments {
+		s.saveComments(post.OutputDir, shortcode)
+	}
+
+	saved := make(map[string]bool)
+	for result := range pool.Results() {
+		s.downloadHook(item.User.Username, storageManager, result)
+		if !result.Success {
+			post.Failed[result.Job.Shortcode] = result.Error
+			continue
+		}
+		saved[result.Job.Shortcode] = true
+	}
+	s.hooks.Wait()
+
+	// Media that were already downloaded have no new metadata entry
+	recorded := make(map[string]metadata.PhotoMetadata)
+	for _, media := range storageManager.GetUserMetadata().Photos {
+		recorded[media.Shortcode] = media
+	}
+	for i := range nodes {
+		node := &nodes[i]
+		if !saved[node.Shortcode] {
+			continue
+		}
+		media, ok := recorded[node.Shortcode]
+		if !ok {
+			media = *metadata.FromInstagramNode(node, 0)
+			media.File = storageManager.FileName(node.Shortcode)
+		}
+		post.Media = append(post.Media, media)
+	}
+
+	s.logger.InfoWithFields("Post downloaded", map[string]interface{}{
+		"shortcode": shortcode,
+		"media":     len(post.Media),
+		"failed":    len(post.Failed),
+	})
+	if len(post.Media) == 0 {
+		return post, fmt.Errorf("none of the media of post %s could be saved", shortcode)
+	}
+	return post, nil
+}
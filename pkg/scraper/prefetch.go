@@ -0,0 +1,105 @@
+package scraper
+
+import (
+	"context"
+
+	"igscraper/pkg/instagram"
+)
+
+// pageFetcher fetches the page of a feed at cursor, the page-th of the scrape
+type pageFetcher func(ctx context.Context, cursor string, page int) ([]instagram.Edge, instagram.PageInfo, error)
+
+// fetchedPage is a page fetched ahead, or the error fetching it
+type fetchedPage struct {
+	cursor   string
+	page     int
+	media    []instagram.Edge
+	pageInfo instagram.PageInfo
+	err      error
+}
+
+// pagePrefetcher fetches the pages of a feed ahead of the scrape, so the
+// next pages are at hand once the downloads of the current one are queued.
+// Pages are fetched one after the other, as each needs the cursor of the one
+// before, and every fetch goes through the rate limit as usual. At most
+// depth pages are fetched ahead, and none after the last page the scrape
+// will ask for; with a depth of 0 each page is only fetched when asked for.
+type pagePrefetcher struct {
+	ctx   context.Context
+	depth int
+	last  int // number of the last page fetched ahead, 0 for no limit
+	fetch pageFetcher
+
+	cancel context.CancelFunc
+	pages  chan fetchedPage
+	done   chan struct{}
+}
+
+// newPagePrefetcher returns a prefetcher fetching depth pages ahead with
+// fetch, up to page last unless it is 0, until ctx is cancelled or it is
+// stopped
+func newPagePrefetcher(ctx context.Context, depth, last int, fetch pageFetcher) *pagePrefetcher {
+	return &pagePrefetcher{ctx: ctx, depth: depth, last: last, fetch: fetch}
+}
+
+// next returns the page at cursor, the page-th of the scrape. The first call
+// starts fetching ahead from it; a call for another page than the one
+// fetched next, as after a cursor the scrape did not follow, starts over.
+func (p *pagePrefetcher) next(cursor string, page int) ([]instagram.Edge, instagram.PageInfo, error) {
+	if p.depth <= 0 {
+		return p.fetch(p.ctx, cursor, page)
+	}
+
+	if p.pages != nil {
+		fetched, ok := <-p.pages
+		if ok && fetched.cursor == cursor && fetched.page == page {
+			return fetched.media, fetched.pageInfo, fetched.err
+		}
+		p.stop()
+	}
+
+	p.start(cursor, page)
+	fetched := <-p.pages
+	return fetched.media, fetched.pageInfo, fetched.err
+}
+
+// start fetches pages from cursor in the background
+func (p *pagePrefetcher) start(cursor string, page int) {
+	ctx, cancel := context.WithCancel(p.ctx)
+	p.cancel = cancel
+	// The page waiting to be handed over is one of those fetched ahead
+	p.pages = make(chan fetchedPage, p.depth-1)
+	p.done = make(chan struct{})
+	go p.run(ctx, p.pages, p.done, cursor, page)
+}
+
+// run fetches pages from cursor until the last one, an error or ctx is
+// cancelled
+func (p *pagePrefetcher) run(ctx context.Context, pages chan<- fetchedPage, done chan<- struct{}, cursor string, page int) {
+	defer close(done)
+	defer close(pages)
+
+	for {
+		media, pageInfo, err := p.fetch(ctx, cursor, page)
+		select {
+		case pages <- fetchedPage{cursor: cursor, page: page, media: media, pageInfo: pageInfo, err: err}:
+		case <-ctx.Done():
+			return
+		}
+		if err != nil || !pageInfo.HasNextPage || p.last > 0 && page >= p.last {
+			return
+		}
+		cursor, page = pageInfo.EndCursor, page+1
+	}
+}
+
+// stop cancels the pages being fetched ahead and waits until fetching has
+// ended. It may be called more than once.
+func (p *pagePrefetcher) stop() {
+	if p.pages == nil {
+		return
+	}
+	p.cancel()
+	<-p.done
+	p.cancel, p.pages, p.done = nil, nil, nil
+}
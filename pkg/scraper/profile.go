@@ -0,0 +1,168 @@
+package scraper
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"igscraper/pkg/errors"
+	"igscraper/pkg/instagram"
+	"igscraper/pkg/metadata"
+	"igscraper/pkg/retry"
+	"igscraper/pkg/ui"
+)
+
+// profileInfo returns a profile's details and post count. Transient failures
+// of the profile endpoint are retried as the profile retry policy says, and
+// if they persist, the user ID from the last sync into outputDir is used with
+// an unknown post count and no other details. Permanent failures, such as a
+// missing or private profile, are returned as they are.
+func (s *Scraper) profileInfo(username, outputDir string) (*instagram.User, int, error) {
+	var user *instagram.User
+	attempts := 0
+	cfg := s.retryPolicies().Config(retry.ClassProfile, context.Background(), s.logger)
+	// Count attempts here rather than in Do, so the last error is returned
+	// as it is for the fallback below
+	maxAttempts, retryIf := cfg.MaxAttempts, cfg.RetryIf
+	cfg.MaxAttempts = 0
+	cfg.RetryIf = func(err error) bool {
+		return attempts < maxAttempts && retryIf(err)
+	}
+	err := retry.Do(func() error {
+		attempts++
+		var err error
+		user, err = s.fetchProfile(username)
+		return err
+	}, cfg)
+	if err == nil {
+		return user, user.EdgeOwnerToTimelineMedia.Count, nil
+	}
+	if !transientError(err) {
+		return nil, 0, err
+	}
+
+	cachedID := cachedUserID(username, outputDir)
+	if cachedID == "" {
+		return nil, 0, err
+	}
+
+	s.logger.WithError(err).WithFields(map[string]interface{}{
+		"username": username,
+		"user_id":  cachedID,
+	}).Warn("Profile info unavailable, using user ID from last sync")
+	if s.tui != nil {
+		s.tui.LogWarning("Profile info for %s unavailable (%v), using the user ID from the last sync", username, err)
+	} else {
+		ui.PrintWarning("Profile info unavailable", fmt.Sprintf("using the user ID of %s from the last sync", username))
+	}
+	return &instagram.User{ID: cachedID, Username: username}, -1, nil
+}
+
+// transientError reports whether err may go away when the request is made
+// again: network failures, rate limits and server errors
+func transientError(err error) bool {
+	var apiErr *errors.Error
+	return stderrors.As(err, &apiErr) && errors.IsRetryable(apiErr.Type)
+}
+
+// cachedUserID returns the user ID recorded for username by an earlier sync
+// into outputDir, or an empty string if there is none
+func cachedUserID(username, outputDir string) string {
+	meta, err := metadata.LoadUserMetadata(outputDir)
+	if err != nil || meta == nil || !strings.EqualFold(meta.Username, username) {
+		return ""
+	}
+	return meta.UserID
+}
+
+// AvatarFolder is the folder in a profile's output directory that holds its
+// profile picture
+const AvatarFolder = "avatar"
+
+// SaveProfile saves a snapshot of a profile's details to profile.json in its
+// output directory, and its profile picture to the avatar folder, without
+// downloading any posts
+func (s *Scraper) SaveProfile(username string) (*metadata.Profile, error) {
+	outputDir := s.getOutputDir(username)
+	user, total, err := s.profileInfo(username, outputDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user info: %w", err)
+	}
+	if total < 0 {
+		return nil, fmt.Errorf("the profile details of %s are unavailable", username)
+	}
+	return s.saveProfile(user, outputDir)
+}
+
+// saveProfile writes the snapshot of user into outputDir. A profile picture
+// that cannot be downloaded is logged and left out of the snapshot.
+func (s *Scraper) saveProfile(user *instagram.User, outputDir string) (*metadata.Profile, error) {
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	profile := &metadata.Profile{
+		UserID:        user.ID,
+		Username:      user.Username,
+		FullName:      user.FullName,
+		Biography:     user.Biography,
+		ExternalURL:   user.ExternalURL,
+		IsPrivate:     user.IsPrivate,
+		IsVerified:    user.IsVerified,
+		Followers:     user.EdgeFollowedBy.Count,
+		Following:     user.EdgeFollow.Count,
+		Posts:         user.EdgeOwnerToTimelineMedia.Count,
+		ProfilePicURL: user.ProfilePicture(),
+		SnapshotAt:    time.Now(),
+	}
+	if profile.ProfilePicURL != "" {
+		name, err := s.saveProfilePicture(profile.ProfilePicURL, outputDir)
+		if err != nil {
+			s.logger.WithError(err).WithField("username", user.Username).Warn("Failed to download profile picture")
+		} else {
+			profile.ProfilePic = name
+		}
+	}
+
+	if err := profile.Save(outputDir); err != nil {
+		return nil, err
+	}
+	s.logger.InfoWithFields("Profile snapshot saved", map[string]interface{}{
+		"username":  user.Username,
+		"followers": profile.Followers,
+		"following": profile.Following,
+	})
+	return profile, nil
+}
+
+// saveProfilePicture downloads a profile picture into the avatar folder and
+// returns its path relative to outputDir
+func (s *Scraper) saveProfilePicture(pictureURL, outputDir string) (string, error) {
+	if err := s.rateLimiter.WaitContext(s.runContext()); err != nil {
+		return "", err
+	}
+	data, err := s.client.DownloadPhoto(pictureURL)
+	if err != nil {
+		return "", err
+	}
+
+	name := filepath.Join(AvatarFolder, "profile_pic.jpg")
+	filename := filepath.Join(outputDir, name)
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return "", fmt.Errorf("failed to create avatar folder: %w", err)
+	}
+	tempFile := filename + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		os.Remove(tempFile)
+		return "", fmt.Errorf("failed to save profile picture: %w", err)
+	}
+	if err := os.Rename(tempFile, filename); err != nil {
+		os.Remove(tempFile)
+		return "", fmt.Errorf("failed to save profile picture: %w", err)
+	}
+	return filepath.ToSlash(name), nil
+}
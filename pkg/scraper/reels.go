@@ -0,0 +1,63 @@
+package scraper
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"igscraper/pkg/instagram"
+)
+
+// ReelsFolder is the directory under a profile's output directory that holds
+// its reels
+const ReelsFolder = "reels"
+
+// DownloadReels archives a profile's reels into the reels folder of its
+// output directory. Each reel's cover image is saved in the folder's
+// thumbnails directory, and its metadata records the play count and audio.
+func (s *Scraper) DownloadReels(username string, resume bool, forceRestart bool) error {
+	return s.downloadFeed(s.reelsFeed(username), resume, forceRestart)
+}
+
+// reelsFeed is the reels tab of a profile, newest first after the reels
+// pinned to it
+func (s *Scraper) reelsFeed(username string) *feed {
+	outputDir := filepath.Join(s.getOutputDir(username), ReelsFolder)
+	return &feed{
+		name: username,
+		// Usernames cannot contain '-', so this never clashes with a profile
+		key:           "reels-" + username,
+		outputDir:     outputDir,
+		chronological: true,
+		info: func() (string, int, error) {
+			// The profile's post count includes more than reels
+			user, _, err := s.profileInfo(username, outputDir)
+			if err != nil {
+				return "", -1, err
+			}
+			return user.ID, -1, nil
+		},
+		page: func(userID, cursor string) ([]instagram.Edge, instagram.PageInfo, error) {
+			return s.fetchReelsPage(instagram.GetClipsURL(userID, cursor))
+		},
+		thumbnails: true,
+	}
+}
+
+// fetchReelsPage fetches one page of a profile's reels
+func (s *Scraper) fetchReelsPage(endpoint string) ([]instagram.Edge, instagram.PageInfo, error) {
+	s.logger.DebugWithFields("Fetching reels page", map[string]interface{}{
+		"endpoint": endpoint,
+	})
+
+	var result instagram.ClipsResponse
+	if err := s.client.GetJSON(endpoint, &result); err != nil {
+		s.logger.WithError(err).WithField("endpoint", endpoint).Error("Failed to fetch reels page")
+		return nil, instagram.PageInfo{}, fmt.Errorf("failed to fetch reels: %w", err)
+	}
+	if result.Status != "" && result.Status != "ok" {
+		return nil, instagram.PageInfo{}, fmt.Errorf("failed to fetch reels: status %q", result.Status)
+	}
+
+	page := result.Feed()
+	return page.Edges(), page.PageInfo(), nil
+}
@@ -0,0 +1,90 @@
+package scraper
+
+import (
+	"slices"
+	"time"
+
+	"igscraper/pkg/instagram"
+	"igscraper/pkg/metadata"
+)
+
+// refresher picks the posts of a feed that are downloaded again although
+// they are saved: every post with output.overwrite_existing, or with
+// output.refresh_newer_than those taken within it that were edited since
+// they were saved, as recorded in metadata.json by whichever run saved them
+type refresher struct {
+	all     bool
+	since   time.Time
+	records map[string]*metadata.PhotoMetadata // by shortcode
+}
+
+// newRefresher returns the refresher for the archive in outputDir, or nil if
+// saved posts are never downloaded again
+func (s *Scraper) newRefresher(outputDir string) *refresher {
+	if s.config.Output.OverwriteExisting {
+		return &refresher{all: true}
+	}
+	window := s.config.Output.RefreshNewerThan
+	if window <= 0 {
+		return nil
+	}
+	r := &refresher{since: time.Now().Add(-window), records: make(map[string]*metadata.PhotoMetadata)}
+	meta, err := metadata.LoadUserMetadata(outputDir)
+	if err != nil {
+		s.logger.WithError(err).WithField("output_dir", outputDir).Warn("Failed to read metadata, not refreshing edited posts")
+		return r
+	}
+	if meta != nil {
+		for i := range meta.Photos {
+			if _, ok := r.records[meta.Photos[i].Shortcode]; !ok {
+				r.records[meta.Photos[i].Shortcode] = &meta.Photos[i]
+			}
+		}
+	}
+	return r
+}
+
+// overwrite reports whether node is downloaded even if it is saved
+func (r *refresher) overwrite(node *instagram.Node) bool {
+	if r == nil {
+		return false
+	}
+	if r.all {
+		return true
+	}
+	if node.TakenAt().Before(r.since) {
+		return false
+	}
+	// Without a record there is nothing to tell an edit by
+	record, ok := r.records[node.Shortcode]
+	return ok && edited(record, metadata.FromInstagramNode(node, 0))
+}
+
+// edited reports whether the details of a post a user can change after
+// posting differ between what was recorded and what is listed now
+func edited(recorded, listed *metadata.PhotoMetadata) bool {
+	if recorded.Caption != listed.Caption || recorded.AccessibilityCaption != listed.AccessibilityCaption {
+		return true
+	}
+	if locationID(recorded.Location) != locationID(listed.Location) {
+		return true
+	}
+	return !slices.Equal(taggedUsernames(recorded.TaggedUsers), taggedUsernames(listed.TaggedUsers))
+}
+
+func locationID(location *metadata.Location) string {
+	if location == nil {
+		return ""
+	}
+	return location.ID
+}
+
+// taggedUsernames returns the usernames of users tagged in a post, sorted
+func taggedUsernames(users []metadata.TaggedUser) []string {
+	names := make([]string, len(users))
+	for i, user := range users {
+		names[i] = user.Username
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,138 @@
+package scraper
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"igscraper/pkg/ui"
+	"igscraper/pkg/ui/tui"
+)
+
+// ReportFile and ReportTextFile are written to a feed's output directory
+// after each scrape, describing the last one
+const (
+	ReportFile     = "report.json"
+	ReportTextFile = "report.txt"
+)
+
+// Report is the layout of report.json
+type Report struct {
+	ScrapeID             string         `json:"scrape_id"`
+	Target               string         `json:"target"`
+	Status               string         `json:"status"`
+	Error                string         `json:"error,omitempty"`
+	StartedAt            time.Time      `json:"started_at"`
+	FinishedAt           time.Time      `json:"finished_at"`
+	DurationSeconds      float64        `json:"duration_seconds"`
+	Pages                int            `json:"pages"`
+	Downloaded           int            `json:"downloaded"`
+	Bytes                int64          `json:"bytes"`
+	BytesPerSecond       float64        `json:"bytes_per_second"`
+	Skipped              int            `json:"skipped"`
+	SkippedReasons       map[string]int `json:"skipped_reasons"`
+	Failed               int            `json:"failed"`
+	Failures             map[string]int `json:"failures"`
+	Cancelled            int            `json:"cancelled"`
+	RateLimitPauses      int            `json:"rate_limit_pauses"`
+	RateLimitWaitSeconds float64        `json:"rate_limit_wait_seconds"`
+}
+
+// Report returns the summary in the layout of report.json, for the scrape
+// with scrapeID
+func (s Summary) Report(scrapeID string) Report {
+	skipped, failures := s.Skipped, s.Failed
+	if skipped == nil {
+		skipped = map[string]int{}
+	}
+	if failures == nil {
+		failures = map[string]int{}
+	}
+	return Report{
+		ScrapeID:             scrapeID,
+		Target:               s.Target,
+		Status:               s.Status,
+		Error:                s.Error,
+		StartedAt:            s.StartedAt,
+		FinishedAt:           s.StartedAt.Add(s.Elapsed),
+		DurationSeconds:      s.Elapsed.Seconds(),
+		Pages:                s.Pages,
+		Downloaded:           s.Downloaded,
+		Bytes:                s.Bytes,
+		BytesPerSecond:       s.Speed(),
+		Skipped:              s.SkippedCount(),
+		SkippedReasons:       skipped,
+		Failed:               s.FailedCount(),
+		Failures:             failures,
+		Cancelled:            s.Cancelled,
+		RateLimitPauses:      s.RateLimitPauses,
+		RateLimitWaitSeconds: s.RateLimitWait.Seconds(),
+	}
+}
+
+// Table returns the summary as a compact table, as printed when a scrape
+// ends and written to report.txt
+func (s Summary) Table() string {
+	var b strings.Builder
+	row := func(label, format string, args ...interface{}) {
+		fmt.Fprintf(&b, "  %-12s %s\n", label, fmt.Sprintf(format, args...))
+	}
+	withReasons := func(count int, reasons map[string]int) string {
+		if count == 0 {
+			return "0"
+		}
+		return fmt.Sprintf("%d (%s)", count, ui.FormatSkipped(reasons))
+	}
+
+	fmt.Fprintf(&b, "%s: %s\n", s.Target, s.Status)
+	if s.Error != "" {
+		row("Stopped by", "%s", s.Error)
+	}
+	row("Started", "%s", s.StartedAt.Format("2006-01-02 15:04:05"))
+	row("Duration", "%s", s.Elapsed.Round(time.Second))
+	row("Pages", "%d", s.Pages)
+	row("Downloaded", "%d (%s, %s)", s.Downloaded, tui.FormatBytes(s.Bytes), tui.FormatSpeed(s.Speed()))
+	row("Skipped", "%s", withReasons(s.SkippedCount(), s.Skipped))
+	row("Failed", "%s", withReasons(s.FailedCount(), s.Failed))
+	if s.Cancelled > 0 {
+		row("Cancelled", "%d", s.Cancelled)
+	}
+	if s.RateLimitPauses > 0 {
+		row("Rate limits", "%d pauses, %s", s.RateLimitPauses, s.RateLimitWait.Round(time.Second))
+	} else {
+		row("Rate limits", "no pauses")
+	}
+	return b.String()
+}
+
+// WriteReport writes the summary to report.json and report.txt in dir,
+// replacing the report of an earlier scrape
+func (s Summary) WriteReport(dir, scrapeID string) error {
+	data, err := json.MarshalIndent(s.Report(scrapeID), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ReportFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ReportTextFile), []byte(s.Table()), 0644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+// writeReport writes the summary of the scrape that just ended to the
+// feed's output directory. A report that cannot be written is only logged.
+func (s *Scraper) writeReport(outputDir string) {
+	if err := s.summary.WriteReport(outputDir, s.scrapeID); err != nil {
+		s.logger.WithError(err).Warn("Failed to write scrape report")
+		return
+	}
+	s.logger.InfoWithFields("Scrape report written", map[string]interface{}{
+		"file":   filepath.Join(outputDir, ReportFile),
+		"status": s.summary.Status,
+	})
+}
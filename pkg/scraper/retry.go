@@ -0,0 +1,161 @@
+package scraper
+
+import (
+	"fmt"
+	"time"
+
+	"igscraper/internal/downloader"
+	"igscraper/pkg/logger"
+	"igscraper/pkg/metadata"
+	"igscraper/pkg/storage"
+)
+
+// RetryReport is the outcome of RetryFailed
+type RetryReport struct {
+	Username  string
+	OutputDir string
+
+	// Saved lists the shortcodes that were downloaded this time
+	Saved []string
+
+	// Failed maps the shortcodes that failed again to the error; those that
+	// may still pass stay in failed.json
+	Failed map[string]error
+}
+
+// RetryFailed downloads again the media listed in a profile's failed.json,
+// the downloads that failed every attempt of an earlier scrape. Media that
+// are saved are added to metadata.json and dropped from failed.json.
+// Instagram's media links expire after a while, so old entries may only be
+// fetched by scraping the profile again.
+func (s *Scraper) RetryFailed(username string) (*RetryReport, error) {
+	outputDir := s.getOutputDir(username)
+	report := &RetryReport{
+		Username:  username,
+		OutputDir: outputDir,
+		Failed:    make(map[string]error),
+	}
+	failed, err := metadata.LoadFailedDownloads(outputDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(failed.Downloads) == 0 {
+		return report, nil
+	}
+
+	s.logger.InfoWithFields("Retrying failed downloads", map[string]interface{}{
+		"username":   username,
+		"output_dir": outputDir,
+		"downloads":  len(failed.Downloads),
+	})
+	storageManager, err := storage.NewManagerWithLogger(outputDir, s.namedLogger(logger.ComponentStorage))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage manager: %w", err)
+	}
+	layout, err := storage.ParseLayout(s.config.Output.FileNamePattern)
+	if err != nil {
+		return nil, err
+	}
+	if err := storageManager.SetLayout(layout); err != nil {
+		return nil, fmt.Errorf("failed to create storage manager: %w", err)
+	}
+	storageManager.SetEmbedMetadata(s.config.Download.EmbedMetadata)
+	storageManager.SetPostProcess(s.postProcess)
+	storageManager.SetGalleryThumbnails(s.config.Output.GalleryThumbnails)
+	// Collects the metadata of the media saved now, added to metadata.json
+	// below
+	storageManager.InitializeUserMetadata(username, "", len(failed.Downloads))
+	storageManager.SetScrapeID(s.scrapeID)
+
+	pool := downloader.NewWorkerPool(s.config.Download.ConcurrentDownloads, s.client, storageManager, s.rateLimiter, s.namedLogger(logger.ComponentDownloader))
+	configurePool(pool, s.config.Download)
+	pool.Start()
+	go func() {
+		for _, download := range failed.Downloads {
+			job := downloader.DownloadJob{
+				URL:          download.URL,
+				Shortcode:    download.Shortcode,
+				Username:     username,
+				Node:         download.Node,
+				ThumbnailURL: download.ThumbnailURL,
+			}
+			if err := pool.Submit(job); err != nil {
+				s.logger.WithError(err).WithField("shortcode", download.Shortcode).Error("Failed to submit download job")
+			}
+		}
+		pool.Stop()
+	}()
+
+	for result := range pool.Results() {
+		s.downloadHook(username, storageManager, result)
+		shortcode := result.Job.Shortcode
+		if result.Success {
+			report.Saved = append(report.Saved, shortcode)
+			storageManager.ClearFailedDownload(shortcode)
+			continue
+		}
+		report.Failed[shortcode] = result.Error
+		if failure := metadata.NewPostFailure(shortcode, result.Job.Node, result.Error); failure != nil {
+			// Gone for good, so kept in metadata.json rather than retried
+			storageManager.RecordFailure(*failure)
+			storageManager.ClearFailedDownload(shortcode)
+			continue
+		}
+		for _, download := range failed.Downloads {
+			if download.Shortcode == shortcode {
+				download.Attempts += result.Job.Retries + 1
+				download.Error = result.Error.Error()
+				download.FailedAt = time.Now()
+				storageManager.RecordFailedDownload(download)
+			}
+		}
+	}
+
+	if err := storageManager.SaveFailedDownloads(); err != nil {
+		s.logger.WithError(err).Error("Failed to save failed downloads")
+	}
+	s.recordSaved(outputDir, storageManager.GetUserMetadata())
+	s.hooks.Wait()
+
+	s.logger.InfoWithFields("Failed downloads retried", map[string]interface{}{
+		"username": username,
+		"saved":    len(report.Saved),
+		"failed":   len(report.Failed),
+	})
+	return report, nil
+}
+
+// recordSaved adds the media saved and the posts found gone outside a scrape,
+// such as by RetryFailed, to the metadata of the profile's folder. Media
+// already recorded there are replaced.
+func (s *Scraper) recordSaved(dir string, saved *metadata.UserMetadata) {
+	if len(saved.Photos) == 0 && len(saved.Failures) == 0 {
+		return
+	}
+	meta, err := metadata.LoadUserMetadata(dir)
+	if err != nil {
+		s.logger.WithError(err).WithField("output_dir", dir).Warn("Failed to read metadata for saved media")
+		return
+	}
+	if meta == nil {
+		meta = saved
+	} else {
+		recorded := make(map[string]int)
+		for i, photo := range meta.Photos {
+			recorded[photo.Shortcode] = i
+		}
+		for _, photo := range saved.Photos {
+			if i, ok := recorded[photo.Shortcode]; ok {
+				meta.Photos[i] = photo
+			} else {
+				meta.AddPhoto(photo)
+			}
+		}
+		for _, failure := range saved.Failures {
+			meta.AddFailure(failure)
+		}
+	}
+	if err := meta.Save(dir); err != nil {
+		s.logger.WithError(err).WithField("output_dir", dir).Warn("Failed to save metadata for saved media")
+	}
+}
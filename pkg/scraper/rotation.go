@@ -0,0 +1,188 @@
+package scraper
+
+import (
+	stderrors "errors"
+	"io"
+	"net/http"
+
+	"igscraper/pkg/auth"
+	"igscraper/pkg/errors"
+	"igscraper/pkg/instagram"
+	"igscraper/pkg/ratelimit"
+	"igscraper/pkg/ui"
+)
+
+// sessionSetter is implemented by clients whose headers and cookies can be
+// replaced while requests are in flight, such as *instagram.Client
+type sessionSetter interface {
+	SetHeaders(headers map[string]string)
+	SetCookies(cookies map[string]string)
+}
+
+// bandwidthSetter is implemented by clients that throttle downloads, such as
+// *instagram.Client
+type bandwidthSetter interface {
+	SetBandwidth(b *ratelimit.Bandwidth)
+}
+
+// rotatingClient passes API calls to an InstagramClient and switches the
+// account it authenticates as when the current one keeps being rate limited
+// or logged out. A call that triggered a switch is retried once as the new
+// account. Photo downloads come from the CDN and do not count; they are
+// passed through so photos and videos are still streamed to disk.
+type rotatingClient struct {
+	InstagramClient
+	session  sessionSetter
+	rotator  *auth.AccountRotator
+	onRotate func(from, to *auth.Account, status int)
+}
+
+// SetAccountRotator makes the scraper switch to the rotator's next account
+// after repeated 429 or 401 responses, updating the client's cookies without
+// interrupting downloads in progress. The rotator may be shared by several
+// scrapers; each starts with its current account. Call it after SetClient.
+func (s *Scraper) SetAccountRotator(rotator *auth.AccountRotator) {
+	session, ok := s.client.(sessionSetter)
+	if !ok {
+		s.logger.Warn("Instagram client does not support changing accounts, rotation disabled")
+		return
+	}
+
+	current := rotator.Current()
+	setAccount(session, current)
+	s.client = &rotatingClient{
+		InstagramClient: s.client,
+		session:         session,
+		rotator:         rotator,
+		onRotate:        s.reportRotation,
+	}
+	s.logger.InfoWithFields("Account rotation enabled", map[string]interface{}{
+		"accounts": rotator.Len(),
+		"current":  current.Username,
+	})
+}
+
+// GetJSON performs a GET request as the current account
+func (c *rotatingClient) GetJSON(url string, target interface{}) error {
+	return c.do(func() error {
+		return c.InstagramClient.GetJSON(url, target)
+	})
+}
+
+// FetchUserProfile fetches a profile as the current account
+func (c *rotatingClient) FetchUserProfile(username string) (*instagram.InstagramResponse, error) {
+	var resp *instagram.InstagramResponse
+	err := c.do(func() error {
+		var err error
+		resp, err = c.InstagramClient.FetchUserProfile(username)
+		return err
+	})
+	return resp, err
+}
+
+// FetchUserMedia fetches a page of media as the current account
+func (c *rotatingClient) FetchUserMedia(userID string, after string) (*instagram.InstagramResponse, error) {
+	var resp *instagram.InstagramResponse
+	err := c.do(func() error {
+		var err error
+		resp, err = c.InstagramClient.FetchUserMedia(userID, after)
+		return err
+	})
+	return resp, err
+}
+
+// KeepAlive checks the session of the current account
+func (c *rotatingClient) KeepAlive() error {
+	return c.do(func() error {
+		return pingSession(c.InstagramClient)
+	})
+}
+
+// DownloadFile streams a photo from the CDN into w
+func (c *rotatingClient) DownloadFile(url string, w io.WriterAt) (int64, error) {
+	return downloadFile(c.InstagramClient, url, w)
+}
+
+// DownloadVideo streams a video from the CDN into w
+func (c *rotatingClient) DownloadVideo(url string, w io.WriterAt) (int64, error) {
+	return downloadVideo(c.InstagramClient, url, w)
+}
+
+// SetBandwidth passes the bandwidth limiter to the client
+func (c *rotatingClient) SetBandwidth(b *ratelimit.Bandwidth) {
+	setBandwidth(c.InstagramClient, b)
+}
+
+// do runs call, and runs it once more if its failure switched accounts
+func (c *rotatingClient) do(call func() error) error {
+	err := call()
+	if c.record(err) {
+		err = call()
+		c.record(err)
+	}
+	return err
+}
+
+// record tells the rotator how a call went and applies the new account's
+// headers and cookies when it switched. It reports whether the account changed.
+func (c *rotatingClient) record(err error) bool {
+	if err == nil {
+		c.rotator.Success()
+		return false
+	}
+	status := rotationStatus(err)
+	if status == 0 {
+		return false
+	}
+
+	from := c.rotator.Current()
+	next, rotated := c.rotator.Failure()
+	if !rotated {
+		return false
+	}
+	setAccount(c.session, next)
+	if c.onRotate != nil {
+		c.onRotate(from, next, status)
+	}
+	return true
+}
+
+// setAccount makes session authenticate as account, presenting its device
+func setAccount(session sessionSetter, account *auth.Account) {
+	device := account.Fingerprint()
+	session.SetHeaders(sessionHeaders(device))
+	session.SetCookies(sessionCookies(account.SessionID, account.CSRFToken, device))
+}
+
+// rotationStatus returns the status code of an error that counts against the
+// current account: 429 when rate limited, 401 when logged out. Other errors
+// return 0.
+func rotationStatus(err error) int {
+	var apiErr *errors.Error
+	if !stderrors.As(err, &apiErr) {
+		return 0
+	}
+	switch {
+	case apiErr.Code == http.StatusTooManyRequests, apiErr.Type == errors.ErrorTypeRateLimit:
+		return http.StatusTooManyRequests
+	case apiErr.Code == http.StatusUnauthorized:
+		return http.StatusUnauthorized
+	}
+	return 0
+}
+
+// reportRotation logs an account switch and shows it to the user
+func (s *Scraper) reportRotation(from, to *auth.Account, status int) {
+	s.logger.WarnWithFields("Rotating to another account", map[string]interface{}{
+		"from":   from.Username,
+		"to":     to.Username,
+		"status": status,
+	})
+	if s.tui != nil {
+		s.tui.LogWarning("Account %s keeps receiving HTTP %d, switching to %s", from.Username, status, to.Username)
+	} else if s.progress != nil {
+		s.progress.AccountSwitched(from.Username, to.Username, status)
+	} else {
+		ui.PrintWarning("Switching account", from.Username+" → "+to.Username)
+	}
+}
@@ -0,0 +1,173 @@
+package scraper
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"igscraper/pkg/instagram"
+	"igscraper/pkg/metadata"
+)
+
+// DownloadSavedPosts archives the authenticated account's saved posts. Each
+// collection is downloaded into its own folder under the saved folder, and
+// posts that are not in any collection go into the saved folder itself. A
+// post saved to several collections is stored in each of their folders, and
+// every copy's metadata lists all the collections it belongs to.
+func (s *Scraper) DownloadSavedPosts(resume bool, forceRestart bool) error {
+	collections, err := s.fetchCollections()
+	if err != nil {
+		return err
+	}
+	s.logger.InfoWithFields("Fetched saved collections", map[string]interface{}{
+		"collections": len(collections),
+	})
+
+	membership := make(map[string][]string)
+	inCollection := make(map[string]bool)
+	usedFolders := make(map[string]bool)
+	var collectionDirs []string
+
+	for _, collection := range collections {
+		f := s.collectionFeed(collection, collectionFolder(collection, usedFolders))
+		name := collection.Name
+		f.visit = func(node *instagram.Node) {
+			names := membership[node.Shortcode]
+			if len(names) == 0 || names[len(names)-1] != name {
+				membership[node.Shortcode] = append(names, name)
+			}
+			inCollection[node.Shortcode] = true
+		}
+
+		if err := s.downloadFeed(f, resume, forceRestart); err != nil {
+			return fmt.Errorf("failed to download collection %q: %w", collection.Name, err)
+		}
+		collectionDirs = append(collectionDirs, f.outputDir)
+	}
+
+	unsorted := s.savedFeed()
+	unsorted.exclude = inCollection
+	if err := s.downloadFeed(unsorted, resume, forceRestart); err != nil {
+		return fmt.Errorf("failed to download saved posts: %w", err)
+	}
+
+	// Membership is only complete once every collection has been paged
+	for _, dir := range collectionDirs {
+		s.recordCollections(dir, membership)
+	}
+
+	return nil
+}
+
+// savedFeed is every post saved by the authenticated account
+func (s *Scraper) savedFeed() *feed {
+	return &feed{
+		name:      SavedFolder,
+		key:       "feed-saved",
+		outputDir: filepath.Join(s.config.Output.BaseDirectory, SavedFolder),
+		info: func() (string, int, error) {
+			return "", -1, nil
+		},
+		page: func(_, cursor string) ([]instagram.Edge, instagram.PageInfo, error) {
+			return s.fetchSavedPage(instagram.GetSavedFeedURL(cursor))
+		},
+	}
+}
+
+// collectionFeed is the posts in one saved collection, stored in folder
+func (s *Scraper) collectionFeed(collection instagram.Collection, folder string) *feed {
+	return &feed{
+		name:      collection.Name,
+		key:       "feed-saved-" + collection.ID,
+		outputDir: filepath.Join(s.config.Output.BaseDirectory, SavedFolder, folder),
+		info: func() (string, int, error) {
+			return collection.ID, collection.MediaCount, nil
+		},
+		page: func(_, cursor string) ([]instagram.Edge, instagram.PageInfo, error) {
+			return s.fetchSavedPage(instagram.GetCollectionFeedURL(collection.ID, cursor))
+		},
+	}
+}
+
+// collectionFolder turns a collection name into a folder name that is safe on
+// every platform and unique among the folders used so far
+func collectionFolder(collection instagram.Collection, used map[string]bool) string {
+	folder := strings.Map(func(r rune) rune {
+		if r < 0x20 || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, collection.Name)
+	folder = strings.Trim(folder, " .")
+	if folder == "" {
+		folder = collection.ID
+	}
+	if used[strings.ToLower(folder)] {
+		folder = folder + "_" + collection.ID
+	}
+	used[strings.ToLower(folder)] = true
+	return folder
+}
+
+// recordCollections writes collection membership into the metadata of a
+// collection folder
+func (s *Scraper) recordCollections(dir string, membership map[string][]string) {
+	meta, err := metadata.LoadUserMetadata(dir)
+	if err != nil {
+		s.logger.WithError(err).WithField("output_dir", dir).Warn("Failed to read metadata for collection membership")
+		return
+	}
+	if meta == nil || !meta.SetCollections(membership) {
+		return
+	}
+	if err := meta.Save(dir); err != nil {
+		s.logger.WithError(err).WithField("output_dir", dir).Warn("Failed to save collection membership")
+	}
+}
+
+// fetchCollections lists the authenticated account's collections
+func (s *Scraper) fetchCollections() ([]instagram.Collection, error) {
+	var collections []instagram.Collection
+	maxID := ""
+	for {
+		var result instagram.CollectionsResponse
+		if err := s.client.GetJSON(instagram.GetCollectionsURL(maxID), &result); err != nil {
+			s.logger.WithError(err).Error("Failed to fetch collections")
+			return nil, fmt.Errorf("failed to fetch collections: %w", err)
+		}
+		if result.Status != "" && result.Status != "ok" {
+			return nil, fmt.Errorf("failed to fetch collections: status %q", result.Status)
+		}
+
+		for _, collection := range result.Items {
+			// Only user-made collections; "All posts" is fetched as the saved feed
+			if collection.Type == "" || collection.Type == "MEDIA" {
+				collections = append(collections, collection)
+			}
+		}
+
+		if !result.MoreAvailable || result.NextMaxID == "" {
+			return collections, nil
+		}
+		maxID = result.NextMaxID
+	}
+}
+
+// fetchSavedPage fetches one page of saved posts
+func (s *Scraper) fetchSavedPage(endpoint string) ([]instagram.Edge, instagram.PageInfo, error) {
+	s.logger.DebugWithFields("Fetching saved posts page", map[string]interface{}{
+		"endpoint": endpoint,
+	})
+
+	var result instagram.SavedFeedResponse
+	if err := s.client.GetJSON(endpoint, &result); err != nil {
+		s.logger.WithError(err).WithField("endpoint", endpoint).Error("Failed to fetch saved posts page")
+		return nil, instagram.PageInfo{}, fmt.Errorf("failed to fetch saved posts: %w", err)
+	}
+	if result.Status != "" && result.Status != "ok" {
+		return nil, instagram.PageInfo{}, fmt.Errorf("failed to fetch saved posts: status %q", result.Status)
+	}
+
+	page := result.Feed()
+	return page.Edges(), page.PageInfo(), nil
+}
@@ -2,24 +2,37 @@ package scraper
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	stderrors "errors"
 	"fmt"
+	"maps"
+	"net/url"
+	"slices"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"igscraper/internal/downloader"
+	"igscraper/pkg/auth"
 	"igscraper/pkg/checkpoint"
 	"igscraper/pkg/config"
+	"igscraper/pkg/filter"
+	"igscraper/pkg/hooks"
 	"igscraper/pkg/instagram"
+	"igscraper/pkg/instagram/cache"
 	"igscraper/pkg/logger"
+	"igscraper/pkg/notify"
+	"igscraper/pkg/platformdirs"
+	"igscraper/pkg/metadata"
+	"igscraper/pkg/postprocess"
 	"igscraper/pkg/ratelimit"
 	"igscraper/pkg/storage"
 	"igscraper/pkg/ui"
-)
-
-const (
-	retryDelay = time.Second * 2
+	"igscraper/pkg/ui/tui"
 )
 
 // Scraper orchestrates the Instagram photo download process
@@ -27,92 +40,500 @@ type Scraper struct {
 	client         InstagramClient
 	storageManager *storage.Manager
 	rateLimiter    ratelimit.Limiter
+	bandwidth      *ratelimit.Bandwidth
 	tracker        *ui.StatusTracker
 	progress       *ui.ProgressDisplay
-	notifier       *ui.Notifier
+	progressSink   ui.ProgressSink
+	tuiSink        ui.ProgressSink // reports to the TUI set with SetTUI
+	notifier       *notify.Dispatcher
+	hooks          *hooks.Runner
 	config         *config.Config
 	logger         logger.Logger
+	baseLogger     logger.Logger
 	checkpointMgr  *checkpoint.Manager
 	tui            ui.TUI
+	skipSynced     time.Duration
+	filter         *filter.Filter
+	postProcess    *postprocess.Pipeline
+	hashIndex      *storage.HashIndex
+	profileIndex   *storage.ProfileIndex
+	guard          *storage.Guard
+	workerPool     *downloader.WorkerPool
+	ctx            context.Context
+	scrapeID       string
+	summary        Summary
+
+	// sessions, if set, saves the cookies Instagram renews for account; see
+	// SetSessionStore
+	sessionsMu sync.Mutex
+	sessions   SessionStore
+	account    string
 }
 
-// New creates a new Scraper instance
+// New creates a new Scraper instance. Its log lines, checkpoints and
+// metadata files carry a scrape ID of its own.
 func New(cfg *config.Config) (*Scraper, error) {
-	// Get logger
-	log := logger.GetLogger()
+	return NewWithLogger(cfg, nil)
+}
+
+// NewWithLogger creates a new Scraper whose components all log to log, with
+// a component field telling them apart, instead of to the global logger. A
+// nil log uses the global logger, as New does.
+func NewWithLogger(cfg *config.Config, log logger.Logger) (*Scraper, error) {
+	scrapeID := logger.NewScrapeID()
+	named := func(component string) logger.Logger {
+		return componentLogger(log, component, scrapeID)
+	}
 	
 	// Create Instagram client with retry configuration
-	client := instagram.NewClientWithConfig(cfg.Download.DownloadTimeout, &cfg.Retry, log)
-	
-	// Build cookie string with all necessary cookies
-	var cookies []string
-	if cfg.Instagram.SessionID != "" {
-		cookies = append(cookies, fmt.Sprintf("sessionid=%s", cfg.Instagram.SessionID))
-	}
-	if cfg.Instagram.CSRFToken != "" {
-		cookies = append(cookies, fmt.Sprintf("csrftoken=%s", cfg.Instagram.CSRFToken))
-		client.SetHeader("x-csrftoken", cfg.Instagram.CSRFToken)
+	client := instagram.NewClientWithConfig(cfg.Download.DownloadTimeout, &cfg.Retry, named(logger.ComponentInstagram))
+	transport, err := instagram.NewTransport(cfg.Transport)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transport: %w", err)
 	}
+	client.SetTransport(transport)
 	
-	// Add other required cookies for Instagram
-	cookies = append(cookies, "ig_did=B989A751-1974-4530-B367-030C95169F23")
-	cookies = append(cookies, "mid=Z5NxAAAEAAHNiER_fWDXTvFWFM3t")
-	cookies = append(cookies, "ds_user_id=192008031")
-	
-	if len(cookies) > 0 {
-		client.SetHeader("Cookie", strings.Join(cookies, "; "))
+	client.SetHeaders(sessionHeaders(configDevice(cfg)))
+	client.SetCookies(sessionCookies(cfg.Instagram.SessionID, cfg.Instagram.CSRFToken, configDevice(cfg)))
+	client.SetChunking(cfg.Download.VideoChunks, cfg.Download.ChunkMinSize)
+
+	// Downloads always go through a bandwidth limiter so the TUI can impose
+	// a limit mid-scrape
+	maxBandwidth, err := ratelimit.ParseBandwidth(cfg.Download.MaxBandwidth)
+	if err != nil {
+		return nil, fmt.Errorf("invalid max_bandwidth: %w", err)
 	}
-	
-	if cfg.Instagram.UserAgent != "" {
-		client.SetHeader("User-Agent", cfg.Instagram.UserAgent)
+	bandwidth := ratelimit.NewBandwidth(maxBandwidth)
+	client.SetBandwidth(bandwidth)
+
+	if cfg.Cache.Enabled {
+		if responses, err := newResponseCache(cfg.Cache); err != nil {
+			named(logger.ComponentScraper).WithError(err).Warn("Responses will not be cached")
+		} else {
+			client.SetCache(responses)
+		}
 	}
 
 	// Rate limiter based on config
-	var rateLimiter ratelimit.Limiter
-	if cfg.RateLimit.RequestsPerMinute > 0 {
-		rateLimiter = ratelimit.NewTokenBucket(
-			cfg.RateLimit.RequestsPerMinute,
-			time.Minute,
-		)
-	} else {
-		rateLimiter = ratelimit.NewTokenBucket(60, time.Minute) // Default 60/min
+	rateLimiter, err := newRateLimiter(cfg, named(logger.ComponentRateLimit))
+	if err != nil {
+		return nil, err
+	}
+
+	// Compile the post filter up front so a bad expression fails fast
+	var postFilter *filter.Filter
+	if cfg.Download.Filter != "" {
+		f, err := filter.Parse(cfg.Download.Filter)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter: %w", err)
+		}
+		postFilter = f
 	}
 
-	return &Scraper{
+	// Look for the encoders now rather than after the first download
+	postProcess, err := postprocess.New(cfg.Download.PostProcess)
+	if err != nil {
+		return nil, fmt.Errorf("invalid postprocess: %w", err)
+	}
+
+	s := &Scraper{
 		client:      client,
 		rateLimiter: rateLimiter,
+		bandwidth:   bandwidth,
 		tracker:     ui.NewStatusTracker(),
-		notifier:    ui.NewNotifier(),
+		notifier:    notify.New(cfg.Notifications, named(logger.ComponentNotify)),
+		hooks:       hooks.New(cfg.Hooks, named(logger.ComponentHooks)),
 		config:      cfg,
-		logger:      logger.GetLogger(),
-	}, nil
+		logger:      named(logger.ComponentScraper),
+		baseLogger:  log,
+		filter:      postFilter,
+		postProcess: postProcess,
+		scrapeID:    scrapeID,
+	}
+	client.OnSessionRefresh(s.sessionRefreshed)
+	return s, nil
+}
+
+// namedLogger returns the logger of a component whose lines carry the
+// scrape ID
+func (s *Scraper) namedLogger(component string) logger.Logger {
+	return componentLogger(s.baseLogger, component, s.scrapeID)
+}
+
+// componentLogger returns the logger of a component whose lines carry
+// scrapeID: the named global logger when base is nil, and otherwise base with
+// a component field
+func componentLogger(base logger.Logger, component, scrapeID string) logger.Logger {
+	if base == nil {
+		return logger.Named(component).WithField(logger.FieldScrapeID, scrapeID)
+	}
+	return base.WithField("component", component).WithField(logger.FieldScrapeID, scrapeID)
+}
+
+// ScrapeID returns the ID the scraper's log lines, checkpoints and metadata
+// files carry
+func (s *Scraper) ScrapeID() string {
+	return s.scrapeID
+}
+
+// NewRateLimiter returns the request limiter the settings ask for: a budget
+// shared through a file with other processes when rate_limit.shared_file is
+// set, and otherwise one of this process alone, which carries on from the
+// account's last run when rate_limit.persist_state is set
+func NewRateLimiter(cfg *config.Config) (ratelimit.Limiter, error) {
+	return newRateLimiter(cfg, logger.Named(logger.ComponentRateLimit))
+}
+
+// newRateLimiter is NewRateLimiter logging to log
+func newRateLimiter(cfg *config.Config, log logger.Logger) (ratelimit.Limiter, error) {
+	requestsPerMinute := cfg.RateLimit.RequestsPerMinute
+	if requestsPerMinute <= 0 {
+		requestsPerMinute = 60 // Default 60/min
+	}
+	if cfg.RateLimit.SharedFile != "" {
+		limiter, err := ratelimit.NewSharedBucket(cfg.RateLimit.SharedFile, requestsPerMinute, time.Minute)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate_limit.shared_file: %w", err)
+		}
+		return limiter, nil
+	}
+
+	bucket := ratelimit.NewTokenBucket(requestsPerMinute, time.Minute)
+	if !cfg.RateLimit.PersistState {
+		return bucket, nil
+	}
+	path, err := rateLimitStatePath(cfg.Instagram.SessionID)
+	if err != nil {
+		log.WithError(err).Warn("Rate limit budget will not be kept between runs")
+		return bucket, nil
+	}
+	limiter, err := ratelimit.NewPersistent(bucket, path)
+	if err != nil {
+		log.WithError(err).Warn("Starting with a full rate limit budget")
+	}
+	return limiter, nil
+}
+
+// NewWorkerPool returns a download pool of concurrent_downloads workers,
+// which adapts their number to the error rate between download.autoscale's
+// bounds when that is enabled. The caller starts and stops the pool.
+func NewWorkerPool(cfg *config.Config, log logger.Logger) *downloader.WorkerPool {
+	pool := downloader.NewSharedPool(cfg.Download.ConcurrentDownloads, log)
+	configurePool(pool, cfg.Download)
+	return pool
+}
+
+// configurePool applies the download settings to a pool before it starts:
+// the order of its queue, how often failed jobs are retried, how long each
+// may take, the sizes of the files it keeps and whether it autoscales
+func configurePool(pool *downloader.WorkerPool, cfg config.DownloadConfig) {
+	if cfg.Order != "" {
+		pool.SetOrder(downloader.Order(cfg.Order))
+	}
+	pool.SetRequeue(cfg.RetryAttempts)
+	pool.SetJobTimeout(cfg.JobTimeout)
+	pool.SetSizeLimits(cfg.MinFileSize, cfg.MaxFileSize)
+	if cfg.Autoscale.Enabled {
+		pool.SetAutoscale(cfg.Autoscale.MinWorkers, cfg.Autoscale.MaxWorkers)
+	}
+}
+
+// rateLimitStatePath returns the file keeping the rate limit state of the
+// account signed in with sessionID. The session cookie starts with the
+// account's numeric ID; if it does not, a hash of the cookie stands in.
+func rateLimitStatePath(sessionID string) (string, error) {
+	dataDir, err := checkpoint.DataDirectory()
+	if err != nil {
+		return "", err
+	}
+
+	key := ""
+	if decoded, err := url.QueryUnescape(sessionID); err == nil {
+		if id, _, found := strings.Cut(decoded, ":"); found && id != "" && strings.Trim(id, "0123456789") == "" {
+			key = id
+		}
+	}
+	if key == "" {
+		sum := sha256.Sum256([]byte(sessionID))
+		key = hex.EncodeToString(sum[:8])
+	}
+	return filepath.Join(dataDir, "ratelimit", key+".json"), nil
+}
+
+// newResponseCache opens the response cache the settings describe, in the
+// platform's cache directory unless cache.directory is set, and clears out
+// the entries that have expired since the last run
+func newResponseCache(cfg config.CacheConfig) (*cache.Cache, error) {
+	dir := cfg.Directory
+	if dir == "" {
+		cacheDir, err := platformdirs.CacheDir()
+		if err != nil {
+			return nil, err
+		}
+		dir = cacheDir
+	}
+	responses, err := cache.New(dir, cfg.TTL)
+	if err != nil {
+		return nil, err
+	}
+	responses.Prune()
+	return responses, nil
+}
+
+// sessionHeaders returns the request headers that present device. Fields of
+// device that are empty select the defaults, so switching accounts replaces
+// every one.
+func sessionHeaders(device *auth.Device) map[string]string {
+	headers := map[string]string{
+		"User-Agent":      instagram.DefaultUserAgent,
+		"X-IG-App-ID":     auth.DefaultAppID,
+		"Accept-Language": auth.DefaultAcceptLanguage,
+	}
+	for key, value := range device.Headers() {
+		headers[key] = value
+	}
+	return headers
+}
+
+// sessionCookies returns the cookies that authenticate as the account with
+// the given sessionid and csrftoken from device
+func sessionCookies(sessionID, csrfToken string, device *auth.Device) map[string]string {
+	cookies := map[string]string{"ds_user_id": "192008031"}
+	if sessionID != "" {
+		cookies[instagram.CookieSessionID] = sessionID
+	}
+	if csrfToken != "" {
+		cookies[instagram.CookieCSRFToken] = csrfToken
+	}
+	
+	// Add other required cookies for Instagram
+	if deviceCookies := device.Cookies(); len(deviceCookies) == 2 {
+		for _, cookie := range deviceCookies {
+			name, value, _ := strings.Cut(cookie, "=")
+			cookies[name] = value
+		}
+	} else {
+		cookies["ig_did"] = "B989A751-1974-4530-B367-030C95169F23"
+		cookies["mid"] = "Z5NxAAAEAAHNiER_fWDXTvFWFM3t"
+	}
+	return cookies
+}
+
+// configDevice returns the device fingerprint set in cfg
+func configDevice(cfg *config.Config) *auth.Device {
+	return &auth.Device{
+		UserAgent:      cfg.Instagram.UserAgent,
+		AppID:          cfg.Instagram.AppID,
+		DeviceID:       cfg.Instagram.DeviceID,
+		MachineID:      cfg.Instagram.MachineID,
+		AcceptLanguage: cfg.Instagram.AcceptLanguage,
+	}
 }
 
 // SetTUI sets the terminal UI for the scraper
 func (s *Scraper) SetTUI(tui ui.TUI) {
 	s.tui = tui
+	s.tuiSink = nil
+	if tui != nil {
+		s.tuiSink = ui.NewTUISink(tui)
+	}
+}
+
+// SetProgressSink sends the pages fetched, the downloads and the rate limit
+// pauses of scrapes to sink instead of the TUI or the progress display,
+// which is then not shown
+func (s *Scraper) SetProgressSink(sink ui.ProgressSink) {
+	s.progressSink = sink
+	s.progress = nil
+}
+
+// sink returns where the progress of a scrape is reported: the sink set with
+// SetProgressSink, otherwise the TUI or the progress display of the current
+// scrape. It is nil when there is none of them.
+func (s *Scraper) sink() ui.ProgressSink {
+	switch {
+	case s.progressSink != nil:
+		return s.progressSink
+	case s.tuiSink != nil:
+		return s.tuiSink
+	case s.progress != nil:
+		return s.progress
+	}
+	return nil
+}
+
+// progressItem returns what a download of username's is reported as
+func (s *Scraper) progressItem(username string, job downloader.DownloadJob) ui.Item {
+	item := ui.Item{Username: username, Shortcode: job.Shortcode, File: job.Shortcode + ".jpg"}
+	if node := job.Node; node != nil {
+		if node.IsVideo {
+			item.File = job.Shortcode + ".mp4"
+		}
+		if len(node.EdgeMediaToCaption.Edges) > 0 {
+			item.Caption = node.EdgeMediaToCaption.Edges[0].Node.Text
+		}
+		item.Likes = node.EdgeLikedBy.Count
+	}
+	if s.storageManager != nil {
+		if name := s.storageManager.FileName(job.Shortcode); name != "" {
+			item.File = name
+		}
+	}
+	return item
+}
+
+// SetClient replaces the Instagram client used for API calls and downloads
+func (s *Scraper) SetClient(client InstagramClient) {
+	s.client = client
+	if refresher, ok := client.(sessionRefresher); ok {
+		refresher.OnSessionRefresh(s.sessionRefreshed)
+	}
+}
+
+// SetRateLimiter replaces the rate limiter, letting several scrapers share one
+// request budget
+func (s *Scraper) SetRateLimiter(limiter ratelimit.Limiter) {
+	s.rateLimiter = limiter
 }
 
-// getOutputDir determines the output directory for a username
+// RateLimiter returns the limiter that paces the scraper's requests
+func (s *Scraper) RateLimiter() ratelimit.Limiter {
+	return s.rateLimiter
+}
+
+// requestsPerMinute returns the current request limit, which the TUI may
+// have changed from the configured one
+func (s *Scraper) requestsPerMinute() int {
+	if limiter, ok := s.rateLimiter.(ratelimit.Adjustable); ok {
+		return limiter.Capacity()
+	}
+	return s.config.RateLimit.RequestsPerMinute
+}
+
+// SetBandwidth replaces the download bandwidth limiter, letting several
+// scrapers share one limit
+func (s *Scraper) SetBandwidth(b *ratelimit.Bandwidth) {
+	s.bandwidth = b
+	if client, ok := s.client.(bandwidthSetter); ok {
+		client.SetBandwidth(b)
+	}
+}
+
+// Bandwidth returns the limiter that throttles the scraper's downloads
+func (s *Scraper) Bandwidth() *ratelimit.Bandwidth {
+	return s.bandwidth
+}
+
+// SetStorageGuard replaces the guard on free disk space and the download
+// quota, letting several scrapers share one quota
+func (s *Scraper) SetStorageGuard(guard *storage.Guard) {
+	s.guard = guard
+}
+
+// SetWorkerPool makes the scraper download through a pool shared with other
+// scrapers instead of starting its own workers. The caller starts and stops
+// the pool.
+func (s *Scraper) SetWorkerPool(pool *downloader.WorkerPool) {
+	s.workerPool = pool
+}
+
+// SetSkipSynced makes the scraper skip a profile whose last complete sync is
+// newer than threshold and whose post count has not changed since. Batch and
+// daemon runs use this to avoid re-walking profiles with nothing new.
+func (s *Scraper) SetSkipSynced(threshold time.Duration) {
+	s.skipSynced = threshold
+}
+
+// getOutputDir determines the output directory for a username. Profiles in
+// the profile index are archived in their recorded folder, which may still
+// be named after an earlier username.
 func (s *Scraper) getOutputDir(username string) string {
 	if s.config.Output.CreateUserFolders {
-		return filepath.Join(s.config.Output.BaseDirectory, username+"_photos")
+		if idx, err := s.loadProfileIndex(); err == nil {
+			if entry, ok := idx.ByUsername(username); ok {
+				return idx.Dir(entry)
+			}
+		}
+		return s.userFolder(username)
 	}
 	return s.config.Output.BaseDirectory
 }
 
+// userFolder returns the folder a profile is archived in by default
+func (s *Scraper) userFolder(username string) string {
+	return filepath.Join(s.config.Output.BaseDirectory, username+"_photos")
+}
+
+// OutputDir returns the directory a profile is saved in
+func (s *Scraper) OutputDir(username string) string {
+	return s.getOutputDir(username)
+}
+
 // DownloadUserPhotos downloads all photos from a user's profile
 func (s *Scraper) DownloadUserPhotos(username string) error {
-	return s.downloadUserPhotosWithOptions(username, false, false)
+	return s.downloadFeed(s.profileFeed(username, true), false, false)
 }
 
 // DownloadUserPhotosWithResume downloads photos with checkpoint support
 func (s *Scraper) DownloadUserPhotosWithResume(username string, resume bool, forceRestart bool) error {
-	return s.downloadUserPhotosWithOptions(username, resume, forceRestart)
+	return s.downloadFeed(s.profileFeed(username, true), resume, forceRestart)
+}
+
+// DownloadUserID downloads the photos of a profile given by its numeric user
+// ID, skipping the username lookup. This reaches profiles whose username
+// changed or is unknown, such as from the user_id of earlier metadata.
+func (s *Scraper) DownloadUserID(userID string, resume bool, forceRestart bool) error {
+	if _, err := strconv.ParseUint(userID, 10, 64); err != nil {
+		return fmt.Errorf("invalid user ID %q: must be numeric", userID)
+	}
+	return s.downloadFeed(s.userIDFeed(userID), resume, forceRestart)
+}
+
+// downloadFeed downloads a feed and notifies the user of the outcome
+func (s *Scraper) downloadFeed(f *feed, resume bool, forceRestart bool) error {
+	err := s.scrapeFeed(f, resume, forceRestart)
+	if s.tui != nil {
+		s.tui.FinishProfile(f.name, err)
+	}
+	s.notifyResult(f.name, err)
+	s.resultHook(f.name, err)
+	return err
 }
 
-// downloadUserPhotosWithOptions is the internal implementation with checkpoint support
-func (s *Scraper) downloadUserPhotosWithOptions(username string, resume bool, forceRestart bool) error {
+// notifyResult tells the user a scrape finished, paused or failed. Scrapes
+// the user stopped are not reported.
+func (s *Scraper) notifyResult(name string, err error) {
+	var spaceErr *storage.SpaceError
+	switch {
+	case err == nil:
+		s.notifier.Send(notify.Event{
+			Kind:    notify.KindComplete,
+			Title:   "SCRAPE COMPLETE",
+			Message: fmt.Sprintf("%s: %d downloaded", name, s.tracker.GetDownloadedCount()),
+		})
+	case stderrors.Is(err, context.Canceled):
+	case stderrors.As(err, &spaceErr):
+		s.notifier.Send(notify.Event{Kind: notify.KindError, Title: "DOWNLOADS PAUSED", Message: fmt.Sprintf("%s: %v", name, spaceErr)})
+	default:
+		s.notifier.Send(notify.Event{Kind: notify.KindError, Title: "SCRAPE FAILED", Message: fmt.Sprintf("%s: %v", name, err)})
+	}
+}
+
+// scrapeFeed is the internal implementation with checkpoint support
+func (s *Scraper) scrapeFeed(f *feed, resume bool, forceRestart bool) error {
+	if f.resolve != nil {
+		if err := f.resolve(); err != nil {
+			s.logger.WithError(err).WithField("username", f.name).Error("Failed to get user info")
+			return fmt.Errorf("failed to get user info: %w", err)
+		}
+	}
+	username := f.name
+	// max_posts caps a limit of the feed's own, such as a hashtag's --limit
+	if maxPosts := s.config.Download.MaxPosts; maxPosts > 0 && (f.limit == 0 || maxPosts < f.limit) {
+		f.limit = maxPosts
+	}
+	s.summary = Summary{Target: username, StartedAt: time.Now(), Failed: make(map[string]int)}
+	
 	if s.tui == nil {
 		ui.PrintHighlight("\n[INITIATING EXTRACTION SEQUENCE]\n")
 	} else {
@@ -120,11 +541,13 @@ func (s *Scraper) downloadUserPhotosWithOptions(username string, resume bool, fo
 	}
 	
 	// Initialize checkpoint manager
-	checkpointMgr, err := checkpoint.NewManager(username)
+	checkpointMgr, err := checkpoint.NewManager(f.key)
 	if err != nil {
 		s.logger.WithError(err).WithField("username", username).Error("Failed to create checkpoint manager")
 		return fmt.Errorf("failed to create checkpoint manager: %w", err)
 	}
+	checkpointMgr.SetScrapeID(s.scrapeID)
+	checkpointMgr.SetLogger(s.namedLogger(logger.ComponentCheckpoint))
 	s.checkpointMgr = checkpointMgr
 	
 	// Handle checkpoint logic
@@ -134,7 +557,11 @@ func (s *Scraper) downloadUserPhotosWithOptions(username string, resume bool, fo
 		if err := checkpointMgr.Delete(); err != nil {
 			s.logger.WithError(err).Warn("Failed to delete existing checkpoint")
 		}
-		ui.PrintInfo("Force restart", "Ignoring existing checkpoint")
+		if s.tui != nil {
+			s.tui.LogInfo("Ignoring the checkpoint of %s", username)
+		} else {
+			ui.PrintInfo("Force restart", "Ignoring existing checkpoint")
+		}
 	} else if resume && checkpointMgr.Exists() {
 		// Resume from checkpoint
 		cp, err = checkpointMgr.Load()
@@ -143,11 +570,20 @@ func (s *Scraper) downloadUserPhotosWithOptions(username string, resume bool, fo
 			return fmt.Errorf("failed to load checkpoint: %w", err)
 		}
 		if cp != nil {
-			ui.PrintInfo("Resuming from checkpoint", fmt.Sprintf("Downloaded: %d photos", cp.TotalDownloaded))
+			summary := fmt.Sprintf("Downloaded: %d photos", cp.TotalDownloaded)
+			if scanned := cp.ScannedPosts(); scanned > 0 && cp.TotalPhotos > 0 {
+				summary += fmt.Sprintf(", %d of %d posts scanned", scanned, cp.TotalPhotos)
+			}
+			if s.tui != nil {
+				s.tui.LogInfo("Resuming %s from checkpoint: %s", username, summary)
+			} else {
+				ui.PrintInfo("Resuming from checkpoint", summary)
+			}
 			s.logger.InfoWithFields("Resuming from checkpoint", map[string]interface{}{
 				"username":         username,
 				"total_downloaded": cp.TotalDownloaded,
 				"last_cursor":      cp.EndCursor,
+				"pages":            len(cp.Pages),
 			})
 		}
 	} else if checkpointMgr.Exists() && !resume {
@@ -155,7 +591,7 @@ func (s *Scraper) downloadUserPhotosWithOptions(username string, resume bool, fo
 		info, _ := checkpointMgr.GetCheckpointInfo()
 		if info != nil {
 			// Only show checkpoint message if not in quiet mode
-			if !ui.IsQuietMode() {
+			if s.tui == nil && !ui.IsQuietMode() && !ui.IsJSONOutput() {
 				fmt.Printf("\n%s Previous download found (%d photos)\n", ui.Yellow("►"), info["total_downloaded"])
 				fmt.Printf("  Use: %s to continue where you left off\n", ui.Green("--resume"))
 				fmt.Printf("  Use: %s to start fresh\n\n", ui.Yellow("--force-restart"))
@@ -164,6 +600,34 @@ func (s *Scraper) downloadUserPhotosWithOptions(username string, resume bool, fo
 		}
 	}
 	
+	// Setup output directory
+	outputDir := f.outputDir
+	
+	// Skip profiles that are already fully synced, at the cost of one profile request
+	var userID string
+	var totalPhotos int
+	if cp == nil && s.skipSynced > 0 {
+		userID, totalPhotos, err = f.info()
+		if err != nil {
+			s.logger.WithError(err).WithField("username", username).Error("Failed to get user info")
+			return fmt.Errorf("failed to get user info: %w", err)
+		}
+		
+		if s.isSynced(outputDir, totalPhotos) {
+			s.logger.InfoWithFields("Profile already synced, skipping", map[string]interface{}{
+				"username":     username,
+				"total_photos": totalPhotos,
+				"threshold":    s.skipSynced.String(),
+			})
+			if s.tui != nil {
+				s.tui.LogInfo("Skipping %s: already synced with %d posts", username, totalPhotos)
+			} else {
+				ui.PrintInfo("Already synced", fmt.Sprintf("%s (%d posts)", username, totalPhotos))
+			}
+			return nil
+		}
+	}
+	
 	// Log the start of download process
 	s.logger.InfoWithFields("Starting photo download for user", map[string]interface{}{
 		"username": username,
@@ -171,58 +635,155 @@ func (s *Scraper) downloadUserPhotosWithOptions(username string, resume bool, fo
 		"resume":   resume && cp != nil,
 	})
 	
-	// Setup output directory
-	outputDir := s.getOutputDir(username)
 	s.logger.DebugWithFields("Setting up output directory", map[string]interface{}{
 		"username":   username,
 		"output_dir": outputDir,
 	})
 	
-	storageManager, err := storage.NewManager(outputDir)
+	storageManager, err := storage.NewManagerWithLogger(outputDir, s.namedLogger(logger.ComponentStorage))
 	if err != nil {
 		s.logger.WithError(err).WithField("username", username).Error("Failed to create storage manager")
 		return fmt.Errorf("failed to create storage manager: %w", err)
 	}
+	layout, err := storage.ParseLayout(s.config.Output.FileNamePattern)
+	if err != nil {
+		return err
+	}
+	if err := storageManager.SetLayout(layout); err != nil {
+		return fmt.Errorf("failed to create storage manager: %w", err)
+	}
+	s.warnLayoutChange(outputDir, layout)
+	storageManager.SetEmbedMetadata(s.config.Download.EmbedMetadata)
+	storageManager.SetPostProcess(s.postProcess)
+	storageManager.SetGalleryThumbnails(s.config.Output.GalleryThumbnails)
+	storageManager.SetScrapeID(s.scrapeID)
+	if s.config.Download.Dedup {
+		if idx, err := s.loadHashIndex(); err != nil {
+			s.logger.WithError(err).Warn("Failed to load hash index, continuing without deduplication")
+		} else {
+			storageManager.SetHashIndex(idx)
+		}
+	}
+	// Finish what an interrupted scrape of this folder left in its journal
+	recovered, err := storageManager.OpenJournal()
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to open journal, continuing without it")
+	}
+	// Downloads are committed once the checkpoint holding them is written
+	checkpointMgr.OnFlush(func(shortcodes []string) {
+		for _, shortcode := range shortcodes {
+			if err := storageManager.CommitDownload(shortcode); err != nil {
+				s.logger.WithError(err).Warn("Failed to commit download in journal")
+				return
+			}
+		}
+	})
+	for _, entry := range recovered {
+		if entry.Committed {
+			continue
+		}
+		digest := checkpoint.FileDigest{Size: entry.Size, SHA256: entry.SHA256}
+		if err := checkpointMgr.AddDownload(entry.Shortcode, storageManager.FileName(entry.Shortcode), entry.Size, digest); err != nil {
+			s.logger.WithError(err).WithField("shortcode", entry.Shortcode).Warn("Failed to record recovered download in checkpoint")
+		}
+	}
+	defer storageManager.CloseJournal()
 	s.storageManager = storageManager
+	guard := s.storageGuard()
 	
-	// Create worker pool for concurrent downloads
-	workerPool := downloader.NewWorkerPool(
-		s.config.Download.ConcurrentDownloads,
-		s.client,
-		s.storageManager,
-		s.rateLimiter,
-		s.logger,
-	)
-	workerPool.Start()
+	// Download through the shared worker pool, or start one for this feed
+	pool := s.workerPool
+	if pool == nil {
+		pool = NewWorkerPool(s.config, s.namedLogger(logger.ComponentDownloader))
+		pool.Start()
+		defer pool.Stop()
+	}
+	downloads, err := pool.AddTarget(username, s.client, s.storageManager, s.rateLimiter)
+	if err != nil {
+		return fmt.Errorf("failed to start downloads: %w", err)
+	}
+	downloads.SetLogger(s.namedLogger(logger.ComponentDownloader))
+	defer downloads.Close()
+	
+	// A cancelled run context, as on Ctrl+C, stops the scrape like an abort
+	// from the TUI, but lets the downloads in progress finish first
+	shutdown := s.runContext()
+	interrupted := func() bool { return shutdown.Err() != nil }
+	
+	// Apply the pause, skip and abort keys of the TUI. Aborting cancels the
+	// context the scrape runs under, which also ends a rate limit cooldown.
+	aborted := func() bool { return false }
+	if s.tui != nil {
+		s.tui.UpdateWorkers(pool.Workers())
+		pool.OnScale(s.tui.UpdateWorkers)
+		
+		parent := s.ctx
+		ctx, abort := context.WithCancelCause(s.runContext())
+		s.ctx = ctx
+		stop := make(chan struct{})
+		go s.followControls(pool, downloads, func() { abort(ErrAborted) }, stop)
+		defer func() {
+			close(stop)
+			abort(nil)
+			s.ctx = parent
+		}()
+		aborted = func() bool { return context.Cause(ctx) == ErrAborted }
+	}
+	
+	// Check the session every so often during long scrapes. Losing it stops
+	// the scrape between pages, and ends a rate limit cooldown.
+	sessionLost := func() error { return nil }
+	if interval := s.config.Instagram.SessionKeepAlive; interval > 0 {
+		parent := s.ctx
+		ctx, lose := context.WithCancelCause(s.runContext())
+		s.ctx = ctx
+		stop := make(chan struct{})
+		go s.keepAlive(interval, username, lose, stop)
+		defer func() {
+			close(stop)
+			lose(nil)
+			s.ctx = parent
+		}()
+		sessionLost = func() error {
+			if err := context.Cause(ctx); stderrors.Is(err, ErrSessionLost) {
+				return err
+			}
+			return nil
+		}
+	}
 	
 	// Start result processor goroutine
 	var wg sync.WaitGroup
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		s.processDownloadResults(workerPool.Results(), username)
+		s.processDownloadResults(downloads.Results(), username)
 	}()
 	
 	// Get initial user data or use from checkpoint
-	var userID string
-	var totalPhotos int
 	if cp != nil && cp.UserID != "" {
 		userID = cp.UserID
 		s.logger.InfoWithFields("Using user ID from checkpoint", map[string]interface{}{
 			"username": username,
 			"user_id":  userID,
 		})
-		// We don't have total photos from checkpoint, will update later
+		// Checkpoints without the post count get it from the first page
 		totalPhotos = -1
+		if cp.TotalPhotos > 0 {
+			totalPhotos = cp.TotalPhotos
+			s.storageManager.InitializeUserMetadata(username, userID, totalPhotos)
+		}
 	} else {
-		s.logger.DebugWithFields("Fetching user info", map[string]interface{}{
-			"username": username,
-		})
-		
-		userID, totalPhotos, err = s.getUserInfo(username)
-		if err != nil {
-			s.logger.WithError(err).WithField("username", username).Error("Failed to get user info")
-			return fmt.Errorf("failed to get user info: %w", err)
+		if userID == "" {
+			s.logger.DebugWithFields("Fetching user info", map[string]interface{}{
+				"username": username,
+			})
+			
+			userID, totalPhotos, err = f.info()
+			if err != nil {
+				s.logger.WithError(err).WithField("username", username).Error("Failed to get user info")
+				return fmt.Errorf("failed to get user info: %w", err)
+			}
 		}
 		
 		s.logger.InfoWithFields("Successfully fetched user info", map[string]interface{}{
@@ -232,7 +793,7 @@ func (s *Scraper) downloadUserPhotosWithOptions(username string, resume bool, fo
 		})
 		
 		// Initialize metadata collection
-		s.storageManager.InitializeUserMetadata(username, userID, totalPhotos)
+		s.storageManager.InitializeUserMetadata(username, userID, max(totalPhotos, 0))
 		
 		// Create new checkpoint if needed
 		if cp == nil {
@@ -247,86 +808,98 @@ func (s *Scraper) downloadUserPhotosWithOptions(username string, resume bool, fo
 				}
 			}
 		}
+		cp.TotalPhotos = max(totalPhotos, 0)
 	}
 	
-	// Initialize progress display if not using TUI
-	if s.tui == nil {
+	// Initialize progress display if not using TUI or another sink
+	if s.tui == nil && s.progressSink == nil {
 		debugMode := strings.ToLower(s.config.Logging.Level) == "debug"
 		s.progress = ui.NewProgressDisplay(username, totalPhotos, debugMode)
 		if cp != nil && cp.TotalDownloaded > 0 {
 			s.progress.SetDownloadedCount(cp.TotalDownloaded)
+			s.progress.SetDownloadedBytes(cp.BytesDownloaded)
 		}
 	}
 
+	refresh := s.newRefresher(outputDir)
 	hasMore := true
 	endCursor := ""
 	totalQueued := 0
 	pageNum := 0
+	skipped := make(map[string]int)
+	var pages []checkpoint.PageStats
+	var visited []string
 	
 	// Resume from checkpoint if available
 	if cp != nil && cp.EndCursor != "" {
 		endCursor = cp.EndCursor
 		totalQueued = cp.TotalQueued
 		pageNum = cp.LastProcessedPage
+		pages = slices.Clone(cp.Pages)
+		// The posts of the pages before the cursor are seen again, as if
+		// they were paged
+		if f.visit != nil {
+			visited = slices.Clone(cp.Visited)
+			for _, shortcode := range visited {
+				f.visit(&instagram.Node{Shortcode: shortcode})
+			}
+		}
 		s.tracker.SetDownloadedCount(cp.TotalDownloaded)
+		for reason, count := range cp.Skipped {
+			skipped[reason] = count
+			if s.progress != nil && reason != skipDownloaded {
+				s.progress.Skip(reason, count)
+			}
+		}
 	}
+	
+	// skip counts a post that is not downloaded. Posts downloaded before a
+	// resume are already part of the progress display's downloaded count.
+	pageSkipped := 0
+	skip := func(node *instagram.Node, reason string) {
+		pageSkipped++
+		skipped[reason]++
+		if sink := s.sink(); sink != nil && reason != skipDownloaded {
+			sink.ItemSkipped(s.progressItem(username, downloader.DownloadJob{Shortcode: node.Shortcode, Node: node}), reason)
+		}
+	}
+
+	// Fetch the next pages while the downloads of this one are queued. It
+	// runs under the context of the scrape, so an abort or a lost session
+	// also stops it.
+	prefetch := newPagePrefetcher(s.runContext(), s.config.Download.PrefetchPages, s.config.Download.MaxPages, func(ctx context.Context, cursor string, page int) ([]instagram.Edge, instagram.PageInfo, error) {
+		return s.nextPage(ctx, f, username, userID, cursor, page)
+	})
+	defer prefetch.stop()
 
+	var pageErr error
 	for hasMore {
-		if s.progress != nil {
-			s.progress.ScanningBatch(pageNum + 1)
-		} else {
-			s.tracker.PrintBatchStatus()
+		if aborted() {
+			pageErr = ErrAborted
+			break
+		}
+		if err := sessionLost(); err != nil {
+			pageErr = err
+			break
 		}
 
-		// Rate limit check for API calls (not downloads)
-		if !s.rateLimiter.Allow() {
-			logger.LogRateLimit("instagram_api", 3600) // 1 hour in seconds
-			s.logger.WarnWithFields("Rate limit reached, cooling down", map[string]interface{}{
-				"username":      username,
-				"cooldown_time": "1 hour",
-			})
-			
-			if s.tui != nil {
-				// Update rate limit in TUI
-				resetTime := time.Now().Add(time.Hour)
-				s.tui.UpdateRateLimit(s.config.RateLimit.RequestsPerMinute, s.config.RateLimit.RequestsPerMinute, resetTime)
-				s.tui.LogWarning("Rate limit reached, cooling down for 1 hour")
-			} else if s.progress != nil {
-				s.progress.RateLimitWarning(time.Hour)
-			} else {
-				s.notifier.SendNotification("RATE LIMIT", "Cooling down for 1 hour...")
-				ui.PrintWarning("\n[COOLING DOWN FOR 1 HOUR]\n")
-			}
-			
-			s.rateLimiter.Wait()
-			
-			s.logger.Info("Rate limit cooldown completed, resuming")
-			if s.tui != nil {
-				s.tui.LogInfo("Rate limit cooldown completed, resuming")
-				s.tui.UpdateRateLimit(0, s.config.RateLimit.RequestsPerMinute, time.Now().Add(time.Minute))
-			} else if s.progress == nil {
-				s.notifier.SendNotification("RESUMING", "Continuing extraction process")
-			}
+		// Stop between pages rather than let a write fail on a full disk
+		if err := guard.Check(); err != nil {
+			s.logger.WithError(err).WithField("username", username).Warn("Pausing downloads to protect disk space")
+			pageErr = err
+			break
 		}
 
-		// Fetch media batch
-		s.logger.DebugWithFields("Fetching media batch", map[string]interface{}{
-			"username":   username,
-			"user_id":    userID,
-			"end_cursor": endCursor,
-		})
-		
-		media, pageInfo, err := s.fetchMediaBatch(username, userID, endCursor)
+		media, pageInfo, err := prefetch.next(endCursor, pageNum+1)
 		if err != nil {
 			s.logger.WithError(err).WithFields(map[string]interface{}{
 				"username":   username,
 				"end_cursor": endCursor,
 			}).Error("Error fetching media batch")
-			
-			ui.PrintError("\nError fetching media: %v. Retrying...\n", err)
-			time.Sleep(retryDelay)
-			continue
+			pageErr = err
+			break
 		}
+		s.summary.Pages++
 		
 		s.logger.InfoWithFields("Media batch fetched successfully", map[string]interface{}{
 			"username":    username,
@@ -337,25 +910,88 @@ func (s *Scraper) downloadUserPhotosWithOptions(username string, resume bool, fo
 		// Update total photos if we didn't have it before (from checkpoint)
 		if s.progress != nil && totalPhotos == -1 {
 			// Get total from first API call
-			_, newTotal, _ := s.getUserInfo(username)
+			_, newTotal, _ := f.info()
 			if newTotal > 0 {
 				totalPhotos = newTotal
 				s.progress.UpdateTotal(totalPhotos)
+				if cp != nil {
+					cp.TotalPhotos = totalPhotos
+				}
 				// Initialize metadata if not already done
 				if s.storageManager.GetUserMetadata() == nil {
 					s.storageManager.InitializeUserMetadata(username, userID, totalPhotos)
 				}
 			}
 		}
+		if sink := s.sink(); sink != nil {
+			sink.PageFetched(username, pageNum+1, totalPhotos)
+		}
 
+		// The checkpoint resumes from the start of this page, so it keeps the
+		// skips and stats of the pages before it
+		if cp != nil {
+			cp.Skipped = maps.Clone(skipped)
+			cp.Pages = slices.Clone(pages)
+			cp.Visited = slices.Clone(visited)
+		}
+		
 		// Queue media items for download
+		pageQueued := totalQueued
+		pageSkipped = 0
 		for _, edge := range media {
-			if edge.Node.IsVideo {
-				s.logger.DebugWithFields("Skipping video", map[string]interface{}{
+			if f.limit > 0 && totalQueued >= f.limit || aborted() || interrupted() {
+				break
+			}
+			if f.visit != nil {
+				f.visit(&edge.Node)
+				visited = append(visited, edge.Node.Shortcode)
+			}
+			if f.exclude[edge.Node.Shortcode] {
+				s.logger.DebugWithFields("Skipping media excluded from feed", map[string]interface{}{
+					"username":  username,
+					"shortcode": edge.Node.Shortcode,
+				})
+				skip(&edge.Node, skipExcluded)
+				continue
+			}
+			
+			if !s.config.Download.InDateRange(edge.Node.TakenAt()) {
+				s.logger.DebugWithFields("Skipping media outside date range", map[string]interface{}{
+					"username":  username,
+					"shortcode": edge.Node.Shortcode,
+					"taken_at":  edge.Node.TakenAt().Format(time.RFC3339),
+				})
+				skip(&edge.Node, skipDateRange)
+				continue
+			}
+			
+			if !s.filter.Match(&edge.Node) {
+				s.logger.DebugWithFields("Skipping media rejected by filter", map[string]interface{}{
+					"username":  username,
+					"shortcode": edge.Node.Shortcode,
+					"filter":    s.filter.String(),
+				})
+				skip(&edge.Node, skipFiltered)
+				continue
+			}
+			
+			if edge.Node.EdgeLikedBy.Count < s.config.Download.MinLikes {
+				s.logger.DebugWithFields("Skipping media below min likes", map[string]interface{}{
 					"username":  username,
 					"shortcode": edge.Node.Shortcode,
-					"media_type": "video",
+					"likes":     edge.Node.EdgeLikedBy.Count,
+				})
+				skip(&edge.Node, skipMinLikes)
+				continue
+			}
+			
+			if reason := s.skipMedia(&edge.Node); reason != "" {
+				s.logger.DebugWithFields("Skipping media of a skipped kind", map[string]interface{}{
+					"username":   username,
+					"shortcode":  edge.Node.Shortcode,
+					"media_type": mediaType(&edge.Node),
 				})
+				skip(&edge.Node, reason)
 				continue
 			}
 			
@@ -365,18 +1001,29 @@ func (s *Scraper) downloadUserPhotosWithOptions(username string, resume bool, fo
 					"username":  username,
 					"shortcode": edge.Node.Shortcode,
 				})
+				skip(&edge.Node, skipDownloaded)
 				continue
 			}
 
 			// Submit job to worker pool
+			edge.Node.SelectImage(s.config.Download.PreferredResolution)
 			job := downloader.DownloadJob{
-				URL:       edge.Node.DisplayURL,
+				URL:       mediaURL(&edge.Node),
 				Shortcode: edge.Node.Shortcode,
 				Username:  username,
 				Node:      &edge.Node,
+				Overwrite: refresh.overwrite(&edge.Node),
+			}
+			if f.thumbnails && edge.Node.IsVideo {
+				job.ThumbnailURL = edge.Node.DisplayURL
+			}
+			if s.tui != nil {
+				job.Progress = s.downloadProgress(edge.Node.Shortcode)
+			} else if s.progress != nil {
+				job.Progress = s.progress.DownloadProgress(edge.Node.Shortcode)
 			}
 			
-			err := workerPool.Submit(job)
+			err := downloads.Submit(job)
 			if err != nil {
 				s.logger.WithError(err).WithFields(map[string]interface{}{
 					"username":  username,
@@ -386,25 +1033,42 @@ func (s *Scraper) downloadUserPhotosWithOptions(username string, resume bool, fo
 			}
 			
 			// Notify about new download
-			if s.tui != nil {
-				// Estimate size (we don't have actual size until download starts)
-				estimatedSize := int64(500000) // 500KB estimate
-				s.tui.StartDownload(edge.Node.Shortcode, username, edge.Node.Shortcode+".jpg", estimatedSize)
-			} else if s.progress != nil {
-				s.progress.StartDownload(edge.Node.Shortcode)
+			if sink := s.sink(); sink != nil {
+				sink.ItemQueued(s.progressItem(username, job))
 			}
 			
 			totalQueued++
 			s.logger.DebugWithFields("Download job queued", map[string]interface{}{
 				"username":      username,
 				"shortcode":     edge.Node.Shortcode,
-				"queue_size":    workerPool.GetQueueSize(),
+				"queue_size":    downloads.QueueSize(),
 				"total_queued":  totalQueued,
 			})
+			
+			if s.config.Download.Comments {
+				s.saveComments(outputDir, edge.Node.Shortcode)
+			}
+		}
+
+		// An abort leaves the page unfinished, so the checkpoint still points
+		// at the page before it
+		if aborted() {
+			pageErr = ErrAborted
+			break
+		}
+		if interrupted() {
+			pageErr = ErrInterrupted
+			break
 		}
 
 		// Update checkpoint after processing batch
 		pageNum++
+		pages = append(pages, checkpoint.PageStats{
+			Page:    len(pages) + 1,
+			Posts:   len(media),
+			Queued:  totalQueued - pageQueued,
+			Skipped: pageSkipped,
+		})
 		if cp != nil {
 			cp.TotalQueued = totalQueued
 			if err := checkpointMgr.UpdateProgress(cp, endCursor, pageNum); err != nil {
@@ -413,7 +1077,25 @@ func (s *Scraper) downloadUserPhotosWithOptions(username string, resume bool, fo
 		}
 		
 		// Handle pagination
-		if pageInfo.HasNextPage {
+		if f.limit > 0 && totalQueued >= f.limit {
+			hasMore = false
+			s.logger.InfoWithFields("Reached download limit, stopping", map[string]interface{}{
+				"username": username,
+				"limit":    f.limit,
+			})
+		} else if maxPages := s.config.Download.MaxPages; maxPages > 0 && pageNum >= maxPages {
+			hasMore = false
+			s.logger.InfoWithFields("Reached page limit, stopping", map[string]interface{}{
+				"username":  username,
+				"max_pages": maxPages,
+			})
+		} else if f.chronological && s.pastSince(media) {
+			hasMore = false
+			s.logger.InfoWithFields("Reached posts older than since date, stopping", map[string]interface{}{
+				"username": username,
+				"since":    s.config.Download.Since.Format(time.RFC3339),
+			})
+		} else if pageInfo.HasNextPage {
 			endCursor = pageInfo.EndCursor
 			s.logger.DebugWithFields("Moving to next page", map[string]interface{}{
 				"username":    username,
@@ -427,23 +1109,144 @@ func (s *Scraper) downloadUserPhotosWithOptions(username string, resume bool, fo
 		}
 	}
 
+	prefetch.stop()
+
 	// Wait for downloads to complete
 	s.logger.InfoWithFields("All jobs queued, waiting for downloads to complete", map[string]interface{}{
 		"username":     username,
 		"total_queued": totalQueued,
+		"skipped":      skipped,
 	})
+	if s.progress != nil && pageErr == nil {
+		s.progress.QueueComplete()
+	}
+	
+	// A page request the interruption cut short failed because of it, and
+	// one failing once the session was lost failed for want of it
+	if pageErr != nil && pageErr != ErrInterrupted && interrupted() {
+		pageErr = fmt.Errorf("%w: %w", ErrInterrupted, pageErr)
+	} else if err := sessionLost(); err != nil && pageErr != nil {
+		pageErr = err
+	}
+	
+	// Let the queued downloads finish and wait for result processor
+	downloads.Close()
+	finished := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-shutdown.Done():
+		s.finishInFlight(downloads, finished, username)
+		if !stderrors.Is(pageErr, ErrInterrupted) {
+			pageErr = ErrInterrupted
+		}
+	}
+	
+	stats := downloads.Stats()
+	s.summary.Downloaded = stats.Completed
+	s.summary.Cancelled = stats.Cancelled
+	s.summary.Bytes = stats.Bytes
+	s.summary.Skipped = maps.Clone(skipped)
+	if stats.Skipped > 0 {
+		s.summary.Skipped[skipFileSize] += stats.Skipped
+	}
+	s.summary.Elapsed = time.Since(s.summary.StartedAt)
+	stopErr := pageErr
+	if stopErr == nil && aborted() {
+		stopErr = ErrAborted
+	}
+	s.summary.Status = scrapeStatus(stopErr)
+	if stopErr != nil {
+		s.summary.Error = stopErr.Error()
+	}
+	s.writeReport(outputDir)
 	
-	// Stop the worker pool and wait for result processor
-	workerPool.Stop()
-	wg.Wait()
+	// Write the downloads the checkpoint still holds in memory
+	if err := checkpointMgr.Flush(); err != nil {
+		s.logger.WithError(err).Error("Failed to save checkpoint")
+	}
 	
-	// Save all collected metadata to a single JSON file
+	// Save all collected metadata to a single JSON file, noting the sync
+	// complete only if every post was gone through
+	if s.summary.Status == StatusComplete {
+		s.storageManager.MarkSyncComplete(totalPhotos)
+	}
 	if err := s.storageManager.SaveUserMetadata(); err != nil {
 		s.logger.WithError(err).Error("Failed to save metadata file")
 		// Don't fail the entire operation if metadata save fails
 	} else {
 		s.logger.Info("Metadata saved to metadata.json")
 	}
+	if err := s.storageManager.SaveFailedDownloads(); err != nil {
+		s.logger.WithError(err).Error("Failed to save failed downloads")
+	} else if failed := s.storageManager.FailedDownloadCount(); failed > 0 {
+		s.logger.WarnWithFields("Downloads failed after retries", map[string]interface{}{
+			"username": username,
+			"failed":   failed,
+			"file":     metadata.FailedFileName,
+		})
+		if s.tui != nil {
+			s.tui.LogWarning("%d downloads of %s failed, run igscraper retry-failed %s to try them again", failed, username, username)
+		} else {
+			ui.PrintInfo("Failed downloads", fmt.Sprintf("%d listed in %s, run igscraper retry-failed %s to try them again", failed, metadata.FailedFileName, username))
+		}
+	}
+	
+	if s.hashIndex != nil {
+		if err := s.hashIndex.Save(); err != nil {
+			s.logger.WithError(err).Error("Failed to save hash index")
+		}
+		s.reportDedup(username)
+	}
+	
+	// Keep the checkpoint so the download can resume from the failed page
+	var spaceErr *storage.SpaceError
+	if stderrors.As(pageErr, &spaceErr) {
+		if s.tui != nil {
+			s.tui.LogWarning("Paused %s: %v. Free up space or raise the quota, then run again with --resume", username, spaceErr)
+		} else {
+			ui.PrintWarning("\n[DOWNLOADS PAUSED]", spaceErr)
+			ui.PrintInfo("Checkpoint kept", "free up space or raise --max-total-size, then run again with --resume")
+		}
+		return pageErr
+	}
+	if stderrors.Is(pageErr, ErrSessionLost) {
+		if s.tui != nil {
+			s.tui.LogWarning("Paused %s: %v. Log in again, then run again with --resume", username, pageErr)
+		} else {
+			ui.PrintWarning("\n[DOWNLOADS PAUSED]", pageErr)
+			ui.PrintInfo("Checkpoint kept", "log in at instagram.com, refresh your cookies with 'igscraper auth login', then run again with --resume")
+		}
+		return pageErr
+	}
+	if s.summary.Interrupted() {
+		s.logger.InfoWithFields("Scrape interrupted, checkpoint kept", map[string]interface{}{
+			"username":   username,
+			"downloaded": s.summary.Downloaded,
+			"cancelled":  s.summary.Cancelled,
+		})
+		if s.tui != nil {
+			s.tui.LogWarning("Stopped %s, run again with --resume to continue", username)
+		}
+		return pageErr
+	}
+	if aborted() {
+		if s.tui != nil {
+			s.tui.LogWarning("Stopped %s, run again with --resume to continue", username)
+		}
+		return ErrAborted
+	}
+	if pageErr != nil {
+		if s.tui != nil {
+			s.tui.LogError("Giving up on %s: %v", username, pageErr)
+		} else {
+			ui.PrintInfo("Checkpoint kept", "run again with --resume to continue from the failed page")
+		}
+		return pageErr
+	}
 
 	s.logger.InfoWithFields("Photo download completed successfully", map[string]interface{}{
 		"username":        username,
@@ -468,12 +1271,268 @@ func (s *Scraper) downloadUserPhotosWithOptions(username string, resume bool, fo
 		}
 	} else {
 		s.tui.LogSuccess("Extraction completed successfully for user: %s", username)
+		if len(skipped) > 0 {
+			s.tui.LogInfo("Skipped posts of %s: %s", username, ui.FormatSkipped(skipped))
+		}
 	}
 	return nil
 }
 
+// storageGuard returns the guard on disk space and the download quota,
+// creating one for the output directory on first use
+func (s *Scraper) storageGuard() *storage.Guard {
+	if s.guard != nil {
+		return s.guard
+	}
+	dl := s.config.Download
+	guard, err := storage.NewGuard(s.config.Output.BaseDirectory, dl.MinFreeSpace, dl.MaxTotalSize)
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to measure output directory, continuing without a download quota")
+		guard, _ = storage.NewGuard(s.config.Output.BaseDirectory, dl.MinFreeSpace, 0)
+	}
+	s.guard = guard
+	return guard
+}
+
+// ErrAborted is returned by a scrape the user stopped from the TUI. It wraps
+// context.Canceled.
+var ErrAborted = fmt.Errorf("scrape aborted by user: %w", context.Canceled)
+
+// ErrInterrupted is returned by a scrape stopped by cancelling the context
+// set with SetContext, such as on Ctrl+C. It wraps context.Canceled.
+var ErrInterrupted = fmt.Errorf("scrape interrupted: %w", context.Canceled)
+
+// finishInFlight winds down the downloads of an interrupted scrape: queued
+// ones are dropped and those in progress get download.shutdown_timeout to
+// finish before they are stopped too. It returns once finished is closed.
+func (s *Scraper) finishInFlight(downloads *downloader.Target, finished <-chan struct{}, username string) {
+	dropped := downloads.CancelPending(func(downloader.DownloadJob) bool { return true })
+	inProgress := downloads.Stats().Active
+	timeout := s.config.Download.ShutdownTimeout
+	s.logger.InfoWithFields("Interrupted, finishing downloads in progress", map[string]interface{}{
+		"username":    username,
+		"in_progress": inProgress,
+		"dropped":     dropped,
+		"timeout":     timeout.String(),
+	})
+	if inProgress > 0 {
+		if s.tui != nil {
+			s.tui.LogWarning("Interrupted, finishing %d downloads in progress", inProgress)
+		} else {
+			ui.PrintWarning("\n[SHUTTING DOWN]", fmt.Sprintf("finishing %d downloads in progress, up to %s; interrupt again to quit now", inProgress, timeout))
+		}
+	}
+	
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-finished:
+	case <-timer.C:
+		stopped := downloads.SkipRunning()
+		s.logger.WarnWithFields("Shutdown timeout reached, stopping downloads in progress", map[string]interface{}{
+			"username": username,
+			"stopped":  stopped,
+		})
+		<-finished
+	}
+}
+
+// followControls applies the user's pause, resume, skip and abort requests
+// from the TUI to the feed's downloads until stop is closed. Aborting stops
+// the scrape from queuing more posts; downloads already queued finish, so a
+// paused pool is resumed for them.
+func (s *Scraper) followControls(pool *downloader.WorkerPool, downloads *downloader.Target, abort func(), stop <-chan struct{}) {
+	controls := s.tui.Controls()
+	for {
+		select {
+		case <-stop:
+			return
+		case control := <-controls:
+			switch control.Action {
+			case ui.ControlPause:
+				pool.Pause()
+			case ui.ControlResume:
+				pool.Resume()
+			case ui.ControlSkip:
+				if !downloads.Skip(control.Shortcode) {
+					s.tui.LogInfo("%s already finished", control.Shortcode)
+				}
+			case ui.ControlAbort:
+				s.logger.Info("Scrape aborted by user")
+				abort()
+				pool.Resume()
+			}
+		}
+	}
+}
+
+// downloadProgress returns a progress callback that shows how much of a
+// download has been written, and how fast, in the TUI
+func (s *Scraper) downloadProgress(shortcode string) func(written int64) {
+	var start time.Time
+	var once sync.Once
+	return func(written int64) {
+		once.Do(func() { start = time.Now() })
+		speed := 0.0
+		if elapsed := time.Since(start).Seconds(); elapsed > 0 {
+			speed = float64(written) / elapsed
+		}
+		s.tui.UpdateDownloadProgress(shortcode, written, speed)
+	}
+}
+
+// loadHashIndex returns the deduplication index shared by every feed saved
+// under the output directory, loading it on first use
+func (s *Scraper) loadHashIndex() (*storage.HashIndex, error) {
+	if s.hashIndex != nil {
+		return s.hashIndex, nil
+	}
+	idx, err := storage.LoadHashIndex(filepath.Join(s.config.Output.BaseDirectory, storage.HashIndexFile))
+	if err != nil {
+		return nil, err
+	}
+	s.logger.DebugWithFields("Loaded hash index", map[string]interface{}{
+		"files": idx.Len(),
+	})
+	s.hashIndex = idx
+	return idx, nil
+}
+
+// reportDedup logs and displays how much deduplication saved for a feed
+func (s *Scraper) reportDedup(username string) {
+	stats := s.storageManager.DedupStats()
+	if stats.Files == 0 {
+		return
+	}
+	
+	s.logger.InfoWithFields("Deduplicated identical photos", map[string]interface{}{
+		"username":    username,
+		"duplicates":  stats.Files,
+		"hardlinked":  stats.Linked,
+		"bytes_saved": stats.BytesSaved,
+	})
+	if s.tui != nil {
+		s.tui.LogInfo("Deduplicated %d photos, saving %s", stats.Files, tui.FormatBytes(stats.BytesSaved))
+	} else if s.progress != nil {
+		s.progress.SetDeduplicated(stats.Files, stats.BytesSaved)
+	}
+}
+
+// pastSince reports whether pagination has moved beyond the since date. The
+// feed is newest first apart from pinned posts, which come first and can be
+// arbitrarily old, so once the last post of a page that is not pinned is
+// older than since, every following page is too. A page of only pinned posts
+// says nothing about the pages after it.
+func (s *Scraper) pastSince(media []instagram.Edge) bool {
+	if s.config.Download.Since.IsZero() {
+		return false
+	}
+	for i := len(media) - 1; i >= 0; i-- {
+		if media[i].Node.Pinned() {
+			continue
+		}
+		takenAt := media[i].Node.TakenAt()
+		return !takenAt.IsZero() && takenAt.Before(s.config.Download.Since)
+	}
+	return false
+}
+
+// warnLayoutChange points out archives whose files were named after a
+// different pattern than the configured one. They are still recognised, but
+// the archive mixes both layouts until it is migrated.
+func (s *Scraper) warnLayoutChange(outputDir string, layout *storage.Layout) {
+	meta, err := metadata.LoadUserMetadata(outputDir)
+	if err != nil || meta == nil || meta.FileNamePattern == "" || meta.FileNamePattern == layout.Pattern() {
+		return
+	}
+	s.logger.WarnWithFields("Archive uses a different file name pattern", map[string]interface{}{
+		"output_dir": outputDir,
+		"archive":    meta.FileNamePattern,
+		"configured": layout.Pattern(),
+	})
+	hint := fmt.Sprintf("run igscraper migrate-layout %s --to %q", outputDir, layout.Pattern())
+	if s.tui != nil {
+		s.tui.LogWarning("Files in %s are named %s; %s", outputDir, meta.FileNamePattern, hint)
+	} else {
+		ui.PrintWarning("File name pattern changed", hint)
+	}
+}
+
+// isSynced reports whether the archive in outputDir finished a sync within the
+// skip threshold, when the profile had at least as many posts as it has now.
+// Posts that sync skipped, such as filtered ones, count as synced.
+func (s *Scraper) isSynced(outputDir string, remoteCount int) bool {
+	if remoteCount < 0 {
+		return false
+	}
+	meta, err := metadata.LoadUserMetadata(outputDir)
+	if err != nil {
+		s.logger.WithError(err).WithField("output_dir", outputDir).Warn("Failed to read metadata for sync check")
+		return false
+	}
+	if meta == nil || meta.DownloadCompleted.IsZero() {
+		return false
+	}
+	return remoteCount <= meta.SyncedPosts && time.Since(meta.DownloadCompleted) < s.skipSynced
+}
+
+// Reasons a post of a feed is not downloaded, as shown in the progress
+// display's summary
+const (
+	skipExcluded   = "excluded"
+	skipDateRange  = "outside date range"
+	skipFiltered   = "filtered out"
+	skipMinLikes   = "below min likes"
+	skipVideos     = "videos"
+	skipImages     = "images"
+	skipFileSize   = "outside size limits"
+	skipDownloaded = "already downloaded"
+)
+
+// skipVideo reports whether a post is a video that is not downloaded, either
+// because skip_videos is set or because the API did not return its URL
+func (s *Scraper) skipVideo(node *instagram.Node) bool {
+	return node.IsVideo && (s.config.Download.SkipVideos || node.VideoURL == "")
+}
+
+// skipImage reports whether a post is a photo, or a carousel, that is not
+// downloaded because skip_images is set
+func (s *Scraper) skipImage(node *instagram.Node) bool {
+	return !node.IsVideo && s.config.Download.SkipImages
+}
+
+// skipMedia returns the reason a post is not downloaded for its kind of
+// media, or "" if it is
+func (s *Scraper) skipMedia(node *instagram.Node) string {
+	switch {
+	case s.skipVideo(node):
+		return skipVideos
+	case s.skipImage(node):
+		return skipImages
+	}
+	return ""
+}
+
+// mediaURL returns the URL of the file downloaded for a post
+func mediaURL(node *instagram.Node) string {
+	if node.IsVideo {
+		return node.VideoURL
+	}
+	return node.DisplayURL
+}
+
 // getUserInfo fetches the user ID and total photo count for the given username
 func (s *Scraper) getUserInfo(username string) (string, int, error) {
+	user, err := s.fetchProfile(username)
+	if err != nil {
+		return "", 0, err
+	}
+	return user.ID, user.EdgeOwnerToTimelineMedia.Count, nil
+}
+
+// fetchProfile fetches a profile's details. It fails for profiles whose
+// posts the session cannot see.
+func (s *Scraper) fetchProfile(username string) (*instagram.User, error) {
 	endpoint := fmt.Sprintf("https://www.instagram.com/api/v1/users/web_profile_info/?username=%s", username)
 	
 	s.logger.DebugWithFields("Making API request for user info", map[string]interface{}{
@@ -485,25 +1544,37 @@ func (s *Scraper) getUserInfo(username string) (string, int, error) {
 	err := s.client.GetJSON(endpoint, &result)
 	if err != nil {
 		s.logger.WithError(err).WithField("username", username).Error("Failed to get user info")
-		return "", 0, fmt.Errorf("failed to fetch user profile: %w", err)
+		return nil, fmt.Errorf("failed to fetch user profile: %w", err)
 	}
 
-	if result.RequiresToLogin {
+	if result.LoginRequired() {
 		s.logger.WarnWithFields("Profile requires authentication", map[string]interface{}{
 			"username": username,
 		})
-		return "", 0, fmt.Errorf("this profile requires authentication")
+		return nil, fmt.Errorf("this profile requires authentication")
 	}
-
-	photoCount := result.Data.User.EdgeOwnerToTimelineMedia.Count
 	
+	// Posts of private profiles are only visible to their followers
+	user := result.Data.User
+	if user.IsPrivate && !user.FollowedByViewer {
+		s.logger.WarnWithFields("Private profile not followed by session", map[string]interface{}{
+			"username": username,
+		})
+		return nil, fmt.Errorf("this profile is private and the logged in account does not follow it")
+	}
+	if user.IsPrivate {
+		s.logger.InfoWithFields("Private profile followed by session, paging as follower", map[string]interface{}{
+			"username": username,
+		})
+	}
+
 	s.logger.DebugWithFields("Successfully fetched user info", map[string]interface{}{
 		"username":    username,
-		"user_id":     result.Data.User.ID,
-		"photo_count": photoCount,
+		"user_id":     user.ID,
+		"photo_count": user.EdgeOwnerToTimelineMedia.Count,
 	})
 	
-	return result.Data.User.ID, photoCount, nil
+	return &user, nil
 }
 
 // getUserID fetches the user ID for the given username (backward compatibility)
@@ -546,44 +1617,53 @@ func (s *Scraper) fetchMediaBatch(username, userID, endCursor string) ([]instagr
 	return media.Edges, media.PageInfo, nil
 }
 
+// logDownload logs the outcome of a download of username's
+func (s *Scraper) logDownload(username string, result downloader.DownloadResult) {
+	kind := "photo"
+	if result.Job.Node != nil {
+		kind = mediaType(result.Job.Node)
+	}
+	log := s.logger.WithFields(map[string]interface{}{
+		"username":   username,
+		"media_id":   result.Job.Shortcode,
+		"media_type": kind,
+		"success":    result.Success,
+	})
+	if result.Error != nil {
+		log.WithError(result.Error).Error("Download failed")
+	} else {
+		log.Info("Download completed")
+	}
+}
+
 // processDownloadResults processes results from the worker pool
 func (s *Scraper) processDownloadResults(results <-chan downloader.DownloadResult, username string) {
 	for result := range results {
+		// The worker has saved the file by now; hooks run in the background
+		s.downloadHook(username, s.storageManager, result)
+		
 		if result.Success {
-			logger.LogDownload(username, result.Job.Shortcode, "photo", true, nil)
-			
-			// Extract metadata for progress display
-			var metadata map[string]interface{}
-			if result.Job.Node != nil {
-				metadata = make(map[string]interface{})
-				if len(result.Job.Node.EdgeMediaToCaption.Edges) > 0 {
-					metadata["caption"] = result.Job.Node.EdgeMediaToCaption.Edges[0].Node.Text
-				}
-				metadata["likes"] = result.Job.Node.EdgeLikedBy.Count
-				metadata["comments"] = result.Job.Node.EdgeMediaToComment.Count
+			s.logDownload(username, result)
+			if sink := s.sink(); sink != nil {
+				sink.ItemCompleted(s.progressItem(username, result.Job), int64(result.Size))
 			}
 			
-			if s.tui != nil {
-				// Complete the download in TUI
-				s.tui.CompleteDownload(result.Job.Shortcode)
-			} else if s.progress != nil {
-				// Use new progress display
-				s.progress.CompleteDownload(result.Job.Shortcode, int64(result.Size), metadata)
-			} else {
-				// Fallback to old tracker
-				s.tracker.IncrementDownloaded()
-				s.tracker.PrintProgress()
+			if s.guard != nil {
+				s.guard.Add(int64(result.Size))
+			}
+			if s.storageManager != nil {
+				s.storageManager.ClearFailedDownload(result.Job.Shortcode)
 			}
 			
 			// Record successful download in checkpoint
 			if s.checkpointMgr != nil {
-				// Load current checkpoint to get latest state
-				cp, err := s.checkpointMgr.Load()
-				if err == nil && cp != nil {
-					filename := fmt.Sprintf("%s.jpg", result.Job.Shortcode)
-					if err := s.checkpointMgr.RecordDownload(cp, result.Job.Shortcode, filename); err != nil {
-						s.logger.WithError(err).Warn("Failed to record download in checkpoint")
-					}
+				filename := s.storageManager.FileName(result.Job.Shortcode)
+				var digest checkpoint.FileDigest
+				if size, sum, ok := s.storageManager.FileDigest(result.Job.Shortcode); ok {
+					digest = checkpoint.FileDigest{Size: size, SHA256: sum}
+				}
+				if err := s.checkpointMgr.AddDownload(result.Job.Shortcode, filename, int64(result.Size), digest); err != nil {
+					s.logger.WithError(err).Warn("Failed to record download in checkpoint")
 				}
 			}
 			
@@ -593,17 +1673,37 @@ func (s *Scraper) processDownloadResults(results <-chan downloader.DownloadResul
 				"duration":  result.Duration,
 				"size":      result.Size,
 			})
+		} else if stderrors.Is(result.Error, downloader.ErrSizeLimit) {
+			// Queued, then found too small or too large; not a failure
+			if sink := s.sink(); sink != nil {
+				item := s.progressItem(username, result.Job)
+				item.Queued = true
+				sink.ItemSkipped(item, skipFileSize)
+			}
+			s.logger.DebugWithFields("Download skipped for its size", map[string]interface{}{
+				"username":  username,
+				"shortcode": result.Job.Shortcode,
+				"reason":    result.Error.Error(),
+			})
+		} else if stderrors.Is(result.Error, downloader.ErrCancelled) {
+			// Dropped from the queue on request; not a failure of the post
+			if sink := s.sink(); sink != nil {
+				sink.ItemFailed(s.progressItem(username, result.Job), result.Error)
+			}
+			s.logger.InfoWithFields("Download cancelled", map[string]interface{}{
+				"username":  username,
+				"shortcode": result.Job.Shortcode,
+			})
 		} else {
-			logger.LogDownload(username, result.Job.Shortcode, "photo", false, result.Error)
+			s.logDownload(username, result)
+			if s.summary.Failed == nil {
+				s.summary.Failed = make(map[string]int)
+			}
+			s.summary.Failed[failureKind(result.Error)]++
 			
-			if s.tui != nil {
-				// Fail the download in TUI
-				s.tui.FailDownload(result.Job.Shortcode, result.Error)
-			} else if s.progress != nil {
-				// Use new progress display
-				s.progress.FailDownload(result.Job.Shortcode, result.Error)
+			if sink := s.sink(); sink != nil {
+				sink.ItemFailed(s.progressItem(username, result.Job), result.Error)
 			} else {
-				// Use regular error printing
 				ui.PrintError("\nError downloading %s: %v\n", result.Job.Shortcode, result.Error)
 			}
 			
@@ -613,6 +1713,31 @@ func (s *Scraper) processDownloadResults(results <-chan downloader.DownloadResul
 				"error":     result.Error.Error(),
 				"duration":  result.Duration,
 			})
+			
+			// Keep permanent failures in metadata.json so the post isn't silently missing
+			// and list the others in failed.json for retry-failed
+			if s.storageManager == nil {
+				continue
+			}
+			if failure := metadata.NewPostFailure(result.Job.Shortcode, result.Job.Node, result.Error); failure != nil {
+				s.storageManager.RecordFailure(*failure)
+				s.storageManager.ClearFailedDownload(result.Job.Shortcode)
+				s.logger.WarnWithFields("Post permanently unavailable", map[string]interface{}{
+					"username":  username,
+					"shortcode": result.Job.Shortcode,
+					"reason":    string(failure.Reason),
+				})
+			} else {
+				s.storageManager.RecordFailedDownload(metadata.FailedDownload{
+					Shortcode:    result.Job.Shortcode,
+					URL:          result.Job.URL,
+					ThumbnailURL: result.Job.ThumbnailURL,
+					Attempts:     result.Job.Retries + 1,
+					Error:        result.Error.Error(),
+					FailedAt:     time.Now(),
+					Node:         result.Job.Node,
+				})
+			}
 		}
 	}
 }
@@ -1,6 +1,7 @@
 package scraper
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -8,23 +9,46 @@ import (
 	"net/http/httptest"
 	neturl "net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"igscraper/internal/downloader"
+	"igscraper/pkg/auth"
+	"igscraper/pkg/checkpoint"
 	"igscraper/pkg/config"
 	"igscraper/pkg/errors"
+	"igscraper/pkg/igclient/mocks"
 	"igscraper/pkg/instagram"
+	"igscraper/pkg/metadata"
 	"igscraper/pkg/ratelimit"
 	"igscraper/pkg/storage"
+	"igscraper/pkg/ui"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
 
+// TestMain keeps the checkpoints and rate limit state of tests that do not
+// set their own data directory out of the user's
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "igscraper-test")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("XDG_DATA_HOME", dir)
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
 // mockInstagramServer creates a test server that mimics Instagram API
 type mockInstagramServer struct {
 	server          *httptest.Server
@@ -35,6 +59,8 @@ type mockInstagramServer struct {
 	failMedia       bool
 	failDownload    bool
 	requiresLogin   bool
+	isPrivate       bool
+	followed        bool
 	mu              sync.Mutex
 }
 
@@ -61,7 +87,9 @@ func newMockInstagramServer() *mockInstagramServer {
 			RequiresToLogin: m.requiresLogin,
 			Data: instagram.Data{
 				User: instagram.User{
-					ID: "123456",
+					ID:               "123456",
+					IsPrivate:        m.isPrivate,
+					FollowedByViewer: m.followed,
 					EdgeOwnerToTimelineMedia: instagram.EdgeOwnerToTimelineMedia{
 						Edges: []instagram.Edge{
 							{
@@ -201,40 +229,20 @@ func (m *mockInstagramServer) GetCallCounts() (profile, media, download int32) {
 		atomic.LoadInt32(&m.downloadCalls)
 }
 
-// mockInstagramClient is a mock implementation of InstagramClient interface
-type mockInstagramClient struct {
-	getJSON       func(url string, target interface{}) error
-	downloadPhoto func(photoURL string) ([]byte, error)
-}
-
-func (m *mockInstagramClient) GetJSON(url string, target interface{}) error {
-	if m.getJSON != nil {
-		return m.getJSON(url, target)
+// newMockClient returns the generated mock client answering every request
+// with getJSON and every download with downloadPhoto. Either may be nil, in
+// which case the calls succeed with nothing.
+func newMockClient(t testing.TB, getJSON func(url string, target interface{}) error, downloadPhoto func(photoURL string) ([]byte, error)) *mocks.InstagramClient {
+	client := mocks.NewInstagramClient(t)
+	if getJSON == nil {
+		getJSON = func(string, interface{}) error { return nil }
 	}
-	return nil
-}
-
-func (m *mockInstagramClient) DownloadPhoto(photoURL string) ([]byte, error) {
-	if m.downloadPhoto != nil {
-		return m.downloadPhoto(photoURL)
+	if downloadPhoto == nil {
+		downloadPhoto = func(string) ([]byte, error) { return nil, nil }
 	}
-	return nil, nil
-}
-
-func (m *mockInstagramClient) FetchUserProfile(username string) (*instagram.InstagramResponse, error) {
-	// For simplicity in tests, we can use GetJSON internally
-	var response instagram.InstagramResponse
-	url := instagram.GetProfileURL(username)
-	err := m.GetJSON(url, &response)
-	return &response, err
-}
-
-func (m *mockInstagramClient) FetchUserMedia(userID string, after string) (*instagram.InstagramResponse, error) {
-	// For simplicity in tests, we can use GetJSON internally
-	var response instagram.InstagramResponse
-	url := instagram.GetMediaURL(userID, after)
-	err := m.GetJSON(url, &response)
-	return &response, err
+	client.On("GetJSON", mock.Anything, mock.Anything).Return(getJSON).Maybe()
+	client.On("DownloadPhoto", mock.Anything).Return(downloadPhoto).Maybe()
+	return client
 }
 
 func TestNew(t *testing.T) {
@@ -370,8 +378,10 @@ type mockTransport struct {
 }
 
 func (t *mockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
-	// Parse the original URL and redirect to test server
+	// Parse the original URL and redirect to test server. The request is
+	// copied so cookies set by the response are kept for the original URL.
 	testURL, _ := neturl.Parse(t.testServerURL)
+	req = req.Clone(req.Context())
 	req.URL.Scheme = testURL.Scheme
 	req.URL.Host = testURL.Host
 	
@@ -388,38 +398,36 @@ func TestGetUserID(t *testing.T) {
 	require.NoError(t, err)
 	
 	// Create a mock client that redirects to test server
-	scraper.client = &mockInstagramClient{
-		getJSON: func(url string, target interface{}) error {
-			// Replace Instagram URL with test server URL
-			testURL := url
-			if strings.Contains(url, "/api/v1/users/web_profile_info/") {
-				testURL = server.URL() + "/api/v1/users/web_profile_info/?username=" + strings.Split(url, "username=")[1]
-			} else if strings.Contains(url, "/graphql/query/") {
-				testURL = server.URL() + "/graphql/query/"
-			}
-			
-			resp, err := http.Get(testURL)
-			if err != nil {
-				return err
-			}
-			defer resp.Body.Close()
-			
-			if resp.StatusCode != http.StatusOK {
-				return &errors.Error{
-					Type:    errors.ErrorTypeServerError,
-					Message: "server error",
-					Code:    resp.StatusCode,
-				}
-			}
-			
-			body, err := io.ReadAll(resp.Body)
-			if err != nil {
-				return err
+	scraper.client = newMockClient(t, func(url string, target interface{}) error {
+		// Replace Instagram URL with test server URL
+		testURL := url
+		if strings.Contains(url, "/api/v1/users/web_profile_info/") {
+			testURL = server.URL() + "/api/v1/users/web_profile_info/?username=" + strings.Split(url, "username=")[1]
+		} else if strings.Contains(url, "/graphql/query/") {
+			testURL = server.URL() + "/graphql/query/"
+		}
+		
+		resp, err := http.Get(testURL)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		
+		if resp.StatusCode != http.StatusOK {
+			return &errors.Error{
+				Type:    errors.ErrorTypeServerError,
+				Message: "server error",
+				Code:    resp.StatusCode,
 			}
-			
-			return json.Unmarshal(body, target)
-		},
-	}
+		}
+		
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		
+		return json.Unmarshal(body, target)
+	}, nil)
 	
 	t.Run("successful fetch", func(t *testing.T) {
 		userID, err := scraper.getUserID("testuser")
@@ -441,10 +449,34 @@ func TestGetUserID(t *testing.T) {
 		assert.Contains(t, err.Error(), "authentication")
 	})
 	
+	t.Run("private profile followed by session", func(t *testing.T) {
+		server.mu.Lock()
+		server.isPrivate = true
+		server.followed = true
+		server.mu.Unlock()
+		
+		userID, err := scraper.getUserID("privateuser")
+		require.NoError(t, err)
+		assert.Equal(t, "123456", userID)
+	})
+	
+	t.Run("private profile not followed", func(t *testing.T) {
+		server.mu.Lock()
+		server.requiresLogin = false
+		server.followed = false
+		server.mu.Unlock()
+		
+		userID, err := scraper.getUserID("privateuser")
+		assert.Error(t, err)
+		assert.Empty(t, userID)
+		assert.Contains(t, err.Error(), "does not follow")
+	})
+	
 	t.Run("server error", func(t *testing.T) {
 		server.mu.Lock()
 		server.failProfile = true
 		server.requiresLogin = false
+		server.isPrivate = false
 		server.mu.Unlock()
 		
 		userID, err := scraper.getUserID("testuser")
@@ -462,41 +494,39 @@ func TestFetchMediaBatch(t *testing.T) {
 	require.NoError(t, err)
 	
 	// Create a test-specific client
-	scraper.client = &mockInstagramClient{
-		getJSON: func(url string, target interface{}) error {
-			var testURL string
-			if strings.Contains(url, "/api/v1/users/web_profile_info/") {
-				testURL = server.URL() + "/api/v1/users/web_profile_info/?username=testuser"
-			} else if strings.Contains(url, "/graphql/query/") {
-				// Parse the URL to get query parameters
-				u, _ := neturl.Parse(url)
-				testURL = server.URL() + "/graphql/query/?" + u.RawQuery
-			} else {
-				testURL = server.URL() + url
-			}
-			
-			resp, err := http.Get(testURL)
-			if err != nil {
-				return err
-			}
-			defer resp.Body.Close()
-			
-			if resp.StatusCode != http.StatusOK {
-				return &errors.Error{
-					Type:    errors.ErrorTypeServerError,
-					Message: "server error",
-					Code:    resp.StatusCode,
-				}
-			}
-			
-			body, err := io.ReadAll(resp.Body)
-			if err != nil {
-				return err
+	scraper.client = newMockClient(t, func(url string, target interface{}) error {
+		var testURL string
+		if strings.Contains(url, "/api/v1/users/web_profile_info/") {
+			testURL = server.URL() + "/api/v1/users/web_profile_info/?username=testuser"
+		} else if strings.Contains(url, "/graphql/query/") {
+			// Parse the URL to get query parameters
+			u, _ := neturl.Parse(url)
+			testURL = server.URL() + "/graphql/query/?" + u.RawQuery
+		} else {
+			testURL = server.URL() + url
+		}
+		
+		resp, err := http.Get(testURL)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		
+		if resp.StatusCode != http.StatusOK {
+			return &errors.Error{
+				Type:    errors.ErrorTypeServerError,
+				Message: "server error",
+				Code:    resp.StatusCode,
 			}
-			
-			return json.Unmarshal(body, target)
-		},
-	}
+		}
+		
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		
+		return json.Unmarshal(body, target)
+	}, nil)
 	
 	t.Run("first page from profile", func(t *testing.T) {
 		media, pageInfo, err := scraper.fetchMediaBatch("testuser", "123456", "")
@@ -548,25 +578,23 @@ func TestDownloadPhoto(t *testing.T) {
 	require.NoError(t, err)
 	
 	// Create test client
-	scraper.client = &mockInstagramClient{
-		downloadPhoto: func(url string) ([]byte, error) {
-			resp, err := http.Get(url)
-			if err != nil {
-				return nil, err
-			}
-			defer resp.Body.Close()
-			
-			if resp.StatusCode != http.StatusOK {
-				return nil, &errors.Error{
-					Type:    errors.ErrorTypeServerError,
-					Message: "download failed",
-					Code:    resp.StatusCode,
-				}
+	scraper.client = newMockClient(t, nil, func(url string) ([]byte, error) {
+		resp, err := http.Get(url)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		
+		if resp.StatusCode != http.StatusOK {
+			return nil, &errors.Error{
+				Type:    errors.ErrorTypeServerError,
+				Message: "download failed",
+				Code:    resp.StatusCode,
 			}
-			
-			return io.ReadAll(resp.Body)
-		},
-	}
+		}
+		
+		return io.ReadAll(resp.Body)
+	})
 	
 	t.Run("successful download", func(t *testing.T) {
 		photoURL := server.URL() + "/photos/photo1.jpg"
@@ -604,6 +632,34 @@ func TestDownloadPhoto(t *testing.T) {
 	})
 }
 
+func TestDownloadPhotoWithMockClient(t *testing.T) {
+	tempDir := t.TempDir()
+	cfg := config.DefaultConfig()
+	cfg.Output.BaseDirectory = tempDir
+
+	s, err := New(cfg)
+	require.NoError(t, err)
+	s.storageManager, err = storage.NewManager(tempDir)
+	require.NoError(t, err)
+
+	client := mocks.NewInstagramClient(t)
+	client.On("DownloadPhoto", "http://example.com/ok.jpg").Return([]byte("photo"), nil).Once()
+	client.On("DownloadPhoto", "http://example.com/gone.jpg").Return(nil, &errors.Error{
+		Type:    errors.ErrorTypeNotFound,
+		Message: "photo not found",
+		Code:    http.StatusNotFound,
+	})
+	s.SetClient(client)
+
+	require.NoError(t, s.downloadPhoto("http://example.com/ok.jpg", "OK1"))
+	data, err := os.ReadFile(filepath.Join(tempDir, "OK1.jpg"))
+	require.NoError(t, err)
+	assert.Equal(t, "photo", string(data))
+
+	assert.Error(t, s.downloadPhoto("http://example.com/gone.jpg", "GONE1"))
+	assert.NoFileExists(t, filepath.Join(tempDir, "GONE1.jpg"))
+}
+
 func TestRateLimiting(t *testing.T) {
 	cfg := config.DefaultConfig()
 	cfg.RateLimit.RequestsPerMinute = 2 // Very low for testing
@@ -680,15 +736,13 @@ func TestErrorRecovery(t *testing.T) {
 	
 	t.Run("download failure", func(t *testing.T) {
 		// Test that download errors are properly propagated
-		scraper.client = &mockInstagramClient{
-			downloadPhoto: func(url string) ([]byte, error) {
-				return nil, &errors.Error{
-					Type:    errors.ErrorTypeNetwork,
-					Message: "network error",
-					Code:    0,
-				}
-			},
-		}
+		scraper.client = newMockClient(t, nil, func(url string) ([]byte, error) {
+			return nil, &errors.Error{
+				Type:    errors.ErrorTypeNetwork,
+				Message: "network error",
+				Code:    0,
+			}
+		})
 		
 		err := scraper.downloadPhoto("http://example.com/photo.jpg", "FAIL123")
 		assert.Error(t, err)
@@ -697,11 +751,9 @@ func TestErrorRecovery(t *testing.T) {
 	
 	t.Run("successful download after client retry", func(t *testing.T) {
 		// Test successful download (retry logic is in the real client)
-		scraper.client = &mockInstagramClient{
-			downloadPhoto: func(url string) ([]byte, error) {
-				return []byte("success data"), nil
-			},
-		}
+		scraper.client = newMockClient(t, nil, func(url string) ([]byte, error) {
+			return []byte("success data"), nil
+		})
 		
 		err := scraper.downloadPhoto("http://example.com/photo.jpg", "SUCCESS123")
 		require.NoError(t, err)
@@ -726,11 +778,9 @@ func BenchmarkDownloadPhoto(b *testing.B) {
 	scraper, _ := New(cfg)
 	scraper.storageManager, _ = storage.NewManager(tempDir)
 	
-	scraper.client = &mockInstagramClient{
-		downloadPhoto: func(url string) ([]byte, error) {
-			return []byte("benchmark image data"), nil
-		},
-	}
+	scraper.client = newMockClient(b, nil, func(url string) ([]byte, error) {
+		return []byte("benchmark image data"), nil
+	})
 	
 	b.ResetTimer()
 	
@@ -753,11 +803,9 @@ func BenchmarkConcurrentDownloads(b *testing.B) {
 	scraper, _ := New(cfg)
 	scraper.storageManager, _ = storage.NewManager(tempDir)
 	
-	scraper.client = &mockInstagramClient{
-		downloadPhoto: func(url string) ([]byte, error) {
-			return []byte("benchmark image data"), nil
-		},
-	}
+	scraper.client = newMockClient(b, nil, func(url string) ([]byte, error) {
+		return []byte("benchmark image data"), nil
+	})
 	
 	b.ResetTimer()
 	
@@ -776,4 +824,3248 @@ func BenchmarkConcurrentDownloads(b *testing.B) {
 		}
 	}
 	wg.Wait()
-}
\ No newline at end of file
+}
+func TestSkipSynced(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	
+	// writeMetadata records a sync of a profile with posts posts, of which
+	// saved were saved and the others skipped
+	writeMetadata := func(t *testing.T, dir string, posts, saved int, completed time.Time) {
+		t.Helper()
+		require.NoError(t, os.MkdirAll(dir, 0755))
+		meta := metadata.UserMetadata{Username: "synced_user", TotalPhotos: posts, DownloadCompleted: completed}
+		if !completed.IsZero() {
+			meta.SyncedPosts = posts
+		}
+		for i := 1; i <= saved; i++ {
+			meta.Photos = append(meta.Photos, metadata.PhotoMetadata{Shortcode: fmt.Sprintf("POST%d", i)})
+		}
+		data, err := json.Marshal(meta)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "metadata.json"), data, 0644))
+	}
+	
+	newScraper := func(t *testing.T, remoteCount int, mediaCalls *int32) *Scraper {
+		t.Helper()
+		cfg := config.DefaultConfig()
+		cfg.Output.BaseDirectory = t.TempDir()
+		cfg.Notifications.Enabled = false
+		
+		s, err := New(cfg)
+		require.NoError(t, err)
+		s.SetSkipSynced(time.Hour)
+		s.SetClient(newMockClient(t, func(url string, target interface{}) error {
+			resp := target.(*instagram.InstagramResponse)
+			if strings.Contains(url, "graphql") {
+				atomic.AddInt32(mediaCalls, 1)
+				resp.Status = "ok"
+				return nil
+			}
+			resp.Status = "ok"
+			resp.Data.User.ID = "42"
+			resp.Data.User.EdgeOwnerToTimelineMedia.Count = remoteCount
+			return nil
+		}, nil))
+		return s
+	}
+	
+	t.Run("isSynced", func(t *testing.T) {
+		var calls int32
+		s := newScraper(t, 10, &calls)
+		dir := t.TempDir()
+		
+		assert.False(t, s.isSynced(dir, 10), "no metadata yet")
+		
+		writeMetadata(t, dir, 10, 10, time.Now().Add(-time.Minute))
+		assert.True(t, s.isSynced(dir, 10))
+		assert.True(t, s.isSynced(dir, 9), "posts deleted since last sync")
+		assert.False(t, s.isSynced(dir, 11), "new posts since last sync")
+		
+		writeMetadata(t, dir, 10, 10, time.Now().Add(-2*time.Hour))
+		assert.False(t, s.isSynced(dir, 10), "last sync older than threshold")
+		
+		writeMetadata(t, dir, 10, 10, time.Time{})
+		assert.False(t, s.isSynced(dir, 10), "no run went through every post")
+		
+		writeMetadata(t, dir, 10, 6, time.Now().Add(-time.Minute))
+		assert.True(t, s.isSynced(dir, 10), "posts the sync skipped count")
+	})
+	
+	t.Run("completion kept across runs", func(t *testing.T) {
+		dir := t.TempDir()
+		run := func(complete bool) *metadata.UserMetadata {
+			manager, err := storage.NewManager(dir)
+			require.NoError(t, err)
+			manager.InitializeUserMetadata("synced_user", "42", 10)
+			if complete {
+				manager.MarkSyncComplete(10)
+			}
+			require.NoError(t, manager.SaveUserMetadata())
+			meta, err := metadata.LoadUserMetadata(dir)
+			require.NoError(t, err)
+			return meta
+		}
+		
+		assert.True(t, run(false).DownloadCompleted.IsZero(), "an interrupted run is not a sync")
+		synced := run(true)
+		assert.False(t, synced.DownloadCompleted.IsZero())
+		assert.Equal(t, 10, synced.SyncedPosts)
+		interrupted := run(false)
+		assert.True(t, synced.DownloadCompleted.Equal(interrupted.DownloadCompleted), "an interrupted run keeps the last sync")
+		assert.Equal(t, 10, interrupted.SyncedPosts)
+	})
+	
+	t.Run("skips fresh profile after profile request", func(t *testing.T) {
+		var calls int32
+		s := newScraper(t, 10, &calls)
+		writeMetadata(t, s.getOutputDir("synced_user"), 10, 10, time.Now())
+		
+		require.NoError(t, s.DownloadUserPhotosWithResume("synced_user", false, false))
+		assert.Equal(t, int32(0), atomic.LoadInt32(&calls), "timeline should not be fetched")
+	})
+	
+	t.Run("scrapes profile with new posts", func(t *testing.T) {
+		var calls int32
+		s := newScraper(t, 12, &calls)
+		writeMetadata(t, s.getOutputDir("synced_user"), 10, 10, time.Now())
+		
+		require.NoError(t, s.DownloadUserPhotosWithResume("synced_user", false, false))
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	})
+	
+	t.Run("skips profile synced with a filtered post", func(t *testing.T) {
+		var calls int32
+		client := newMockClient(t, func(url string, target interface{}) error {
+			resp := target.(*instagram.InstagramResponse)
+			resp.Status = "ok"
+			resp.Data.User.ID = "42"
+			resp.Data.User.EdgeOwnerToTimelineMedia.Count = 2
+			if !strings.Contains(url, "graphql") {
+				return nil
+			}
+			atomic.AddInt32(&calls, 1)
+			for shortcode, caption := range map[string]string{"BEACH": "Beach #sunset", "PEAKS": "Mountains #hiking"} {
+				node := instagram.Node{Shortcode: shortcode, DisplayURL: "http://example.com/" + shortcode + ".jpg"}
+				node.EdgeMediaToCaption.Edges = []instagram.CaptionEdge{{Node: instagram.CaptionNode{Text: caption}}}
+				resp.Data.User.EdgeOwnerToTimelineMedia.Edges = append(resp.Data.User.EdgeOwnerToTimelineMedia.Edges, instagram.Edge{Node: node})
+			}
+			return nil
+		}, func(url string) ([]byte, error) {
+			return []byte("photo"), nil
+		})
+		
+		cfg := config.DefaultConfig()
+		cfg.Output.BaseDirectory = t.TempDir()
+		cfg.Notifications.Enabled = false
+		cfg.Download.Filter = "hashtag:sunset"
+		s, err := New(cfg)
+		require.NoError(t, err)
+		s.SetSkipSynced(time.Hour)
+		s.SetClient(client)
+		
+		require.NoError(t, s.DownloadUserPhotosWithResume("synced_user", false, false))
+		require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+		meta, err := metadata.LoadUserMetadata(s.getOutputDir("synced_user"))
+		require.NoError(t, err)
+		require.Len(t, meta.Photos, 1, "the #hiking post is filtered out")
+		
+		require.NoError(t, s.DownloadUserPhotosWithResume("synced_user", false, false))
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls), "timeline should not be fetched again")
+	})
+}
+
+func TestProcessDownloadResultsRecordsFailures(t *testing.T) {
+	tempDir := t.TempDir()
+	
+	cfg := config.DefaultConfig()
+	cfg.Output.BaseDirectory = tempDir
+	
+	scraper, err := New(cfg)
+	require.NoError(t, err)
+	scraper.storageManager, err = storage.NewManager(tempDir)
+	require.NoError(t, err)
+	scraper.storageManager.InitializeUserMetadata("testuser", "42", 3)
+	
+	results := make(chan downloader.DownloadResult, 3)
+	results <- downloader.DownloadResult{
+		Job: downloader.DownloadJob{
+			Shortcode: "DELETED1",
+			Node:      &instagram.Node{ID: "1", Shortcode: "DELETED1", TakenAtTimestamp: 1700000000},
+		},
+		Error: fmt.Errorf("download failed: %w", &errors.Error{Type: errors.ErrorTypeNotFound, Message: "resource not found", Code: 404}),
+	}
+	results <- downloader.DownloadResult{
+		Job:   downloader.DownloadJob{Shortcode: "GEO1"},
+		Error: fmt.Errorf("download failed: %w", &errors.Error{Type: errors.ErrorTypeUnknown, Message: "unexpected status code: 403", Code: 403}),
+	}
+	results <- downloader.DownloadResult{
+		Job:   downloader.DownloadJob{Shortcode: "FLAKY1"},
+		Error: fmt.Errorf("download failed: %w", &errors.Error{Type: errors.ErrorTypeNetwork, Message: "connection reset"}),
+	}
+	close(results)
+	
+	scraper.processDownloadResults(results, "testuser")
+	
+	failures := scraper.storageManager.GetUserMetadata().Failures
+	require.Len(t, failures, 2, "transient failures should not be recorded")
+	
+	assert.Equal(t, "DELETED1", failures[0].Shortcode)
+	assert.Equal(t, metadata.FailureNotFound, failures[0].Reason)
+	assert.Equal(t, 404, failures[0].StatusCode)
+	require.NotNil(t, failures[0].TakenAt)
+	
+	assert.Equal(t, "GEO1", failures[1].Shortcode)
+	assert.Equal(t, metadata.FailureBlocked, failures[1].Reason)
+	
+	// The transient failure is listed for retry-failed
+	require.NoError(t, scraper.storageManager.SaveFailedDownloads())
+	failed, err := metadata.LoadFailedDownloads(tempDir)
+	require.NoError(t, err)
+	require.Len(t, failed.Downloads, 1)
+	assert.Equal(t, "FLAKY1", failed.Downloads[0].Shortcode)
+	assert.Equal(t, 1, failed.Downloads[0].Attempts)
+	assert.Contains(t, failed.Downloads[0].Error, "connection reset")
+}
+
+func TestRetryFailed(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	
+	cfg := config.DefaultConfig()
+	cfg.Output.BaseDirectory = t.TempDir()
+	cfg.Notifications.Enabled = false
+	cfg.Download.RetryAttempts = 0
+	s, err := New(cfg)
+	require.NoError(t, err)
+	
+	// Nothing to retry in a folder without failed.json
+	report, err := s.RetryFailed("alice")
+	require.NoError(t, err)
+	assert.Empty(t, report.Saved)
+	assert.Empty(t, report.Failed)
+	
+	outputDir := s.OutputDir("alice")
+	require.NoError(t, os.MkdirAll(outputDir, 0755))
+	meta := &metadata.UserMetadata{Username: "alice", Photos: []metadata.PhotoMetadata{{Shortcode: "OLD1"}}}
+	require.NoError(t, meta.Save(outputDir))
+	failed := &metadata.FailedDownloads{Downloads: []metadata.FailedDownload{
+		{Shortcode: "OK1", URL: "http://example.com/ok.jpg", Attempts: 4, Node: &instagram.Node{ID: "1", Shortcode: "OK1"}},
+		{Shortcode: "FLAKY1", URL: "http://example.com/flaky.jpg", Attempts: 4},
+		{Shortcode: "GONE1", URL: "http://example.com/gone.jpg", Attempts: 4},
+	}}
+	require.NoError(t, failed.Save(outputDir))
+	
+	s.SetClient(newMockClient(t, nil, func(url string) ([]byte, error) {
+		switch {
+		case strings.Contains(url, "flaky"):
+			return nil, &errors.Error{Type: errors.ErrorTypeServerError, Message: "bad gateway", Code: 502}
+		case strings.Contains(url, "gone"):
+			return nil, &errors.Error{Type: errors.ErrorTypeNotFound, Message: "resource not found", Code: 404}
+		}
+		return []byte("photo " + url), nil
+	}))
+	
+	report, err = s.RetryFailed("alice")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"OK1"}, report.Saved)
+	assert.Len(t, report.Failed, 2)
+	assert.FileExists(t, filepath.Join(outputDir, "OK1.jpg"))
+	
+	// Only the download that may still pass stays listed
+	failed, err = metadata.LoadFailedDownloads(outputDir)
+	require.NoError(t, err)
+	require.Len(t, failed.Downloads, 1)
+	assert.Equal(t, "FLAKY1", failed.Downloads[0].Shortcode)
+	assert.Equal(t, 5, failed.Downloads[0].Attempts)
+	
+	// The saved photo and the deleted post join the existing metadata
+	meta, err = metadata.LoadUserMetadata(outputDir)
+	require.NoError(t, err)
+	require.Len(t, meta.Photos, 2)
+	assert.Equal(t, "OLD1", meta.Photos[0].Shortcode)
+	assert.Equal(t, "OK1", meta.Photos[1].Shortcode)
+	require.Len(t, meta.Failures, 1)
+	assert.Equal(t, "GONE1", meta.Failures[0].Shortcode)
+}
+
+func TestDateRangeFiltering(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	
+	day := func(d int) int64 {
+		return time.Date(2024, 1, d, 12, 0, 0, 0, time.UTC).Unix()
+	}
+	
+	// Two pages, newest first: Jan 20..16 then Jan 15..11
+	pages := map[string][]int{"": {20, 19, 18, 17, 16}, "page2": {15, 14, 13, 12, 11}}
+	
+	var mediaCalls int32
+	var downloaded sync.Map
+	client := newMockClient(t, func(url string, target interface{}) error {
+		resp := target.(*instagram.InstagramResponse)
+		resp.Status = "ok"
+		if !strings.Contains(url, "graphql") {
+			resp.Data.User.ID = "42"
+			resp.Data.User.EdgeOwnerToTimelineMedia.Count = 10
+			return nil
+		}
+		
+		atomic.AddInt32(&mediaCalls, 1)
+		cursor := ""
+		if strings.Contains(url, "page2") {
+			cursor = "page2"
+		}
+		media := &resp.Data.User.EdgeOwnerToTimelineMedia
+		for _, d := range pages[cursor] {
+			media.Edges = append(media.Edges, instagram.Edge{Node: instagram.Node{
+				Shortcode:        fmt.Sprintf("DAY%02d", d),
+				DisplayURL:       fmt.Sprintf("http://example.com/%02d.jpg", d),
+				TakenAtTimestamp: day(d),
+			}})
+		}
+		media.PageInfo.HasNextPage = cursor == ""
+		media.PageInfo.EndCursor = "page2"
+		return nil
+	}, func(url string) ([]byte, error) {
+		downloaded.Store(url, true)
+		return []byte("photo"), nil
+	})
+	
+	cfg := config.DefaultConfig()
+	cfg.Output.BaseDirectory = t.TempDir()
+	cfg.Notifications.Enabled = false
+	cfg.Download.Since = time.Date(2024, 1, 17, 0, 0, 0, 0, time.UTC)
+	cfg.Download.Until = time.Date(2024, 1, 18, 23, 59, 59, 0, time.UTC)
+	
+	s, err := New(cfg)
+	require.NoError(t, err)
+	s.SetClient(client)
+	
+	require.NoError(t, s.DownloadUserPhotosWithResume("ranged_user", false, true))
+	
+	var urls []string
+	downloaded.Range(func(key, _ interface{}) bool {
+		urls = append(urls, key.(string))
+		return true
+	})
+	assert.ElementsMatch(t, []string{"http://example.com/17.jpg", "http://example.com/18.jpg"}, urls)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&mediaCalls), "pagination should stop once posts predate since")
+}
+
+func TestPinnedPostsDoNotStopPagination(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	day := func(d int) int64 {
+		return time.Date(2024, 1, d, 12, 0, 0, 0, time.UTC).Unix()
+	}
+	pinned := []instagram.PinnedUser{{ID: "42"}}
+
+	// The first page holds only the pinned posts, one of them years old
+	var mediaCalls atomic.Int32
+	client := newMockClient(t, func(url string, target interface{}) error {
+		resp := target.(*instagram.InstagramResponse)
+		resp.Status = "ok"
+		resp.Data.User.ID = "42"
+		media := &resp.Data.User.EdgeOwnerToTimelineMedia
+		if !strings.Contains(url, "graphql") {
+			media.Count = 6
+			return nil
+		}
+
+		mediaCalls.Add(1)
+		node := func(shortcode string, d int) instagram.Edge {
+			return instagram.Edge{Node: instagram.Node{
+				Shortcode:        shortcode,
+				DisplayURL:       "http://example.com/" + shortcode + ".jpg",
+				TakenAtTimestamp: day(d),
+			}}
+		}
+		switch {
+		case strings.Contains(url, "page3"):
+			media.Edges = []instagram.Edge{node("DAY10", 10)}
+		case strings.Contains(url, "page2"):
+			media.Edges = []instagram.Edge{node("DAY20", 20), node("DAY19", 19), node("DAY16", 16)}
+			media.PageInfo = instagram.PageInfo{HasNextPage: true, EndCursor: "page3"}
+		default:
+			recent, old := node("PINNED18", 18), node("PINNED01", 1)
+			recent.Node.PinnedForUsers = pinned
+			old.Node.PinnedForUsers = pinned
+			old.Node.TakenAtTimestamp = time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC).Unix()
+			media.Edges = []instagram.Edge{recent, old}
+			media.PageInfo = instagram.PageInfo{HasNextPage: true, EndCursor: "page2"}
+		}
+		return nil
+	}, func(url string) ([]byte, error) {
+		return []byte("photo"), nil
+	})
+
+	cfg := config.DefaultConfig()
+	cfg.Output.BaseDirectory = t.TempDir()
+	cfg.Notifications.Enabled = false
+	cfg.Download.Since = time.Date(2024, 1, 17, 0, 0, 0, 0, time.UTC)
+	s, err := New(cfg)
+	require.NoError(t, err)
+	s.SetClient(client)
+
+	require.NoError(t, s.DownloadUserPhotosWithResume("pinned_user", false, true))
+	assert.Equal(t, int32(2), mediaCalls.Load(), "pagination should stop at the posts that predate since, not the pinned ones")
+
+	outputDir := s.getOutputDir("pinned_user")
+	meta, err := metadata.LoadUserMetadata(outputDir)
+	require.NoError(t, err)
+	got := make(map[string]bool)
+	for _, photo := range meta.Photos {
+		got[photo.Shortcode] = photo.Pinned
+	}
+	assert.Equal(t, map[string]bool{"PINNED18": true, "DAY20": false, "DAY19": false}, got)
+}
+
+func TestStopConditions(t *testing.T) {
+	// Three pages of three posts, newest first
+	likes := map[string][]int{"": {50, 900, 10}, "page2": {1200, 300, 5}, "page3": {2000, 40, 700}}
+	scrape := func(t *testing.T, setup func(cfg *config.Config)) (pages int32, downloaded []string, summary Summary) {
+		t.Setenv("XDG_DATA_HOME", t.TempDir())
+		cfg := config.DefaultConfig()
+		cfg.Output.BaseDirectory = t.TempDir()
+		cfg.Notifications.Enabled = false
+		setup(cfg)
+		s, err := New(cfg)
+		require.NoError(t, err)
+
+		var mu sync.Mutex
+		s.SetClient(newMockClient(t, func(url string, target interface{}) error {
+			resp := target.(*instagram.InstagramResponse)
+			resp.Status = "ok"
+			resp.Data.User.ID = "42"
+			media := &resp.Data.User.EdgeOwnerToTimelineMedia
+			if !strings.Contains(url, "graphql") {
+				media.Count = 9
+				return nil
+			}
+			atomic.AddInt32(&pages, 1)
+			cursor, next := "", "page2"
+			switch {
+			case strings.Contains(url, "page3"):
+				cursor, next = "page3", ""
+			case strings.Contains(url, "page2"):
+				cursor, next = "page2", "page3"
+			}
+			for _, count := range likes[cursor] {
+				shortcode := fmt.Sprintf("LIKES%d", count)
+				media.Edges = append(media.Edges, instagram.Edge{Node: instagram.Node{
+					Shortcode:   shortcode,
+					DisplayURL:  "http://example.com/" + shortcode + ".jpg",
+					EdgeLikedBy: instagram.EdgeLikedBy{Count: count},
+				}})
+			}
+			media.PageInfo = instagram.PageInfo{HasNextPage: next != "", EndCursor: next}
+			return nil
+		}, func(url string) ([]byte, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			downloaded = append(downloaded, strings.TrimSuffix(strings.TrimPrefix(url, "http://example.com/"), ".jpg"))
+			return []byte("photo"), nil
+		}))
+		require.NoError(t, s.DownloadUserPhotosWithResume("popular_user", false, true))
+		return atomic.LoadInt32(&pages), downloaded, s.Summary()
+	}
+
+	t.Run("min likes and max posts", func(t *testing.T) {
+		pages, downloaded, summary := scrape(t, func(cfg *config.Config) {
+			cfg.Download.MinLikes = 100
+			cfg.Download.MaxPosts = 3
+		})
+		assert.Equal(t, int32(2), pages, "pagination stops once enough posts are queued")
+		assert.ElementsMatch(t, []string{"LIKES900", "LIKES1200", "LIKES300"}, downloaded)
+		assert.Equal(t, 2, summary.Skipped[skipMinLikes])
+	})
+
+	t.Run("max pages", func(t *testing.T) {
+		pages, downloaded, summary := scrape(t, func(cfg *config.Config) {
+			cfg.Download.MaxPages = 2
+			cfg.Download.PrefetchPages = 2
+		})
+		assert.Equal(t, int32(2), pages, "no page after the last is fetched, not even ahead")
+		assert.Len(t, downloaded, 6)
+		assert.Equal(t, 2, summary.Pages)
+	})
+}
+
+func TestPostFilter(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	
+	posts := []struct {
+		shortcode string
+		caption   string
+		likes     int
+	}{
+		{"POPULAR", "Beach #sunset", 250},
+		{"QUIET", "Another #sunset", 12},
+		{"OTHER", "Mountains #hiking", 500},
+	}
+	
+	var downloaded sync.Map
+	client := newMockClient(t, func(url string, target interface{}) error {
+		resp := target.(*instagram.InstagramResponse)
+		resp.Status = "ok"
+		resp.Data.User.ID = "42"
+		resp.Data.User.EdgeOwnerToTimelineMedia.Count = len(posts)
+		if !strings.Contains(url, "graphql") {
+			return nil
+		}
+		for _, p := range posts {
+			node := instagram.Node{Shortcode: p.shortcode, DisplayURL: "http://example.com/" + p.shortcode + ".jpg"}
+			node.EdgeMediaToCaption.Edges = []instagram.CaptionEdge{{Node: instagram.CaptionNode{Text: p.caption}}}
+			node.EdgeLikedBy.Count = p.likes
+			resp.Data.User.EdgeOwnerToTimelineMedia.Edges = append(resp.Data.User.EdgeOwnerToTimelineMedia.Edges, instagram.Edge{Node: node})
+		}
+		return nil
+	}, func(url string) ([]byte, error) {
+		downloaded.Store(url, true)
+		return []byte("photo"), nil
+	})
+	
+	cfg := config.DefaultConfig()
+	cfg.Output.BaseDirectory = t.TempDir()
+	cfg.Notifications.Enabled = false
+	cfg.Download.Filter = "hashtag:sunset AND likes>100"
+	
+	s, err := New(cfg)
+	require.NoError(t, err)
+	s.SetClient(client)
+	
+	require.NoError(t, s.DownloadUserPhotosWithResume("filtered_user", false, true))
+	
+	var urls []string
+	downloaded.Range(func(key, _ interface{}) bool {
+		urls = append(urls, key.(string))
+		return true
+	})
+	assert.Equal(t, []string{"http://example.com/POPULAR.jpg"}, urls)
+	
+	t.Run("invalid filter", func(t *testing.T) {
+		cfg := config.DefaultConfig()
+		cfg.Download.Filter = "likes>lots"
+		_, err := New(cfg)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid filter")
+	})
+}
+
+func TestDedup(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	
+	content := map[string]string{
+		"ORIGINAL": "same image",
+		"REPOST":   "same image",
+		"UNIQUE":   "another image",
+	}
+	
+	client := newMockClient(t, func(url string, target interface{}) error {
+		resp := target.(*instagram.InstagramResponse)
+		resp.Status = "ok"
+		resp.Data.User.ID = "42"
+		resp.Data.User.EdgeOwnerToTimelineMedia.Count = len(content)
+		if !strings.Contains(url, "graphql") {
+			return nil
+		}
+		for _, shortcode := range []string{"ORIGINAL", "REPOST", "UNIQUE"} {
+			node := instagram.Node{Shortcode: shortcode, DisplayURL: "http://example.com/" + shortcode + ".jpg"}
+			resp.Data.User.EdgeOwnerToTimelineMedia.Edges = append(resp.Data.User.EdgeOwnerToTimelineMedia.Edges, instagram.Edge{Node: node})
+		}
+		return nil
+	}, func(url string) ([]byte, error) {
+		shortcode := strings.TrimSuffix(strings.TrimPrefix(url, "http://example.com/"), ".jpg")
+		return []byte(content[shortcode]), nil
+	})
+	
+	cfg := config.DefaultConfig()
+	cfg.Output.BaseDirectory = t.TempDir()
+	cfg.Notifications.Enabled = false
+	cfg.Download.ConcurrentDownloads = 1
+	cfg.Download.Dedup = true
+	
+	s, err := New(cfg)
+	require.NoError(t, err)
+	s.SetClient(client)
+	
+	require.NoError(t, s.DownloadUserPhotosWithResume("dedup_user", false, true))
+	
+	outputDir := s.getOutputDir("dedup_user")
+	original, err := os.Stat(filepath.Join(outputDir, "ORIGINAL.jpg"))
+	require.NoError(t, err)
+	repost, err := os.Stat(filepath.Join(outputDir, "REPOST.jpg"))
+	require.NoError(t, err)
+	unique, err := os.Stat(filepath.Join(outputDir, "UNIQUE.jpg"))
+	require.NoError(t, err)
+	assert.True(t, os.SameFile(original, repost), "identical photos should be hardlinked")
+	assert.False(t, os.SameFile(original, unique))
+	
+	stats := s.storageManager.DedupStats()
+	assert.Equal(t, 1, stats.Files)
+	assert.Equal(t, int64(len("same image")), stats.BytesSaved)
+	
+	// The index persists, so a later run still recognizes the content
+	idx, err := storage.LoadHashIndex(filepath.Join(cfg.Output.BaseDirectory, storage.HashIndexFile))
+	require.NoError(t, err)
+	assert.Equal(t, 2, idx.Len())
+}
+
+func TestPreferredResolution(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	
+	var downloaded []string
+	client := newMockClient(t, func(url string, target interface{}) error {
+		resp := target.(*instagram.InstagramResponse)
+		resp.Status = "ok"
+		resp.Data.User.ID = "42"
+		resp.Data.User.EdgeOwnerToTimelineMedia.Count = 1
+		if !strings.Contains(url, "graphql") {
+			return nil
+		}
+		node := instagram.Node{
+			Shortcode:  "POST",
+			DisplayURL: "http://example.com/640.jpg",
+			Dimensions: instagram.MediaDimensions{Width: 640, Height: 640},
+			DisplayResources: []instagram.DisplayResource{
+				{Src: "http://example.com/640.jpg", Width: 640, Height: 640},
+				{Src: "http://example.com/1080.jpg", Width: 1080, Height: 1080},
+			},
+		}
+		resp.Data.User.EdgeOwnerToTimelineMedia.Edges = []instagram.Edge{{Node: node}}
+		return nil
+	}, func(url string) ([]byte, error) {
+		downloaded = append(downloaded, url)
+		return []byte("image"), nil
+	})
+	
+	cfg := config.DefaultConfig()
+	cfg.Output.BaseDirectory = t.TempDir()
+	cfg.Notifications.Enabled = false
+	cfg.Download.ConcurrentDownloads = 1
+	
+	s, err := New(cfg)
+	require.NoError(t, err)
+	s.SetClient(client)
+	
+	require.NoError(t, s.DownloadUserPhotosWithResume("res_user", false, true))
+	assert.Equal(t, []string{"http://example.com/1080.jpg"}, downloaded)
+	
+	photos := s.storageManager.GetUserMetadata().Photos
+	require.Len(t, photos, 1)
+	assert.Equal(t, "1080x1080", photos[0].Resolution)
+	assert.Equal(t, "http://example.com/1080.jpg", photos[0].URL)
+}
+
+func TestDownloadLikedPosts(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	
+	pages := map[string]string{
+		"": `{"items":[
+			{"id":"1_10","code":"LIKEDA","taken_at":1700000000,"media_type":1,
+			 "image_versions2":{"candidates":[{"url":"http://example.com/a.jpg","width":1080,"height":1350}]},
+			 "user":{"pk":10,"username":"alice","full_name":"Alice A"},"caption":{"text":"hello"},"like_count":5},
+			{"id":"2_20","code":"LIKEDB","taken_at":1600000000,"media_type":8,
+			 "carousel_media":[{"media_type":1,"image_versions2":{"candidates":[{"url":"http://example.com/b.jpg"}]}}],
+			 "user":{"pk":20,"username":"bob"}}
+		],"more_available":true,"next_max_id":"cursor2","status":"ok"}`,
+		"cursor2": `{"items":[
+			{"id":"3_30","code":"LIKEDC","taken_at":1500000000,"media_type":1,
+			 "image_versions2":{"candidates":[{"url":"http://example.com/c.jpg"}]},
+			 "user":{"pk":30,"username":"carol"}}
+		],"more_available":false,"status":"ok"}`,
+	}
+	
+	var requested []string
+	var mu sync.Mutex
+	client := newMockClient(t, func(url string, target interface{}) error {
+		mu.Lock()
+		requested = append(requested, url)
+		mu.Unlock()
+		
+		parsed, err := neturl.Parse(url)
+		require.NoError(t, err)
+		require.Equal(t, instagram.LikedFeedEndpoint, parsed.Path)
+		return json.Unmarshal([]byte(pages[parsed.Query().Get("max_id")]), target)
+	}, func(url string) ([]byte, error) {
+		return []byte("photo " + url), nil
+	})
+	
+	cfg := config.DefaultConfig()
+	cfg.Output.BaseDirectory = t.TempDir()
+	cfg.Notifications.Enabled = false
+	// Liked posts are not ordered by post date, so an old post must not stop pagination
+	cfg.Download.Since = time.Unix(1400000000, 0)
+	
+	s, err := New(cfg)
+	require.NoError(t, err)
+	s.SetClient(client)
+	
+	require.NoError(t, s.DownloadLikedPosts(false, true))
+	assert.Len(t, requested, 2)
+	
+	likedDir := filepath.Join(cfg.Output.BaseDirectory, LikedFolder)
+	for _, shortcode := range []string{"LIKEDA", "LIKEDB", "LIKEDC"} {
+		assert.FileExists(t, filepath.Join(likedDir, shortcode+".jpg"))
+	}
+	
+	meta, err := metadata.LoadUserMetadata(likedDir)
+	require.NoError(t, err)
+	require.NotNil(t, meta)
+	assert.Equal(t, LikedFolder, meta.Username)
+	
+	owners := make(map[string]metadata.Owner)
+	for _, photo := range meta.Photos {
+		owners[photo.Shortcode] = photo.Owner
+	}
+	assert.Equal(t, metadata.Owner{ID: "10", Username: "alice", FullName: "Alice A"}, owners["LIKEDA"])
+	assert.Equal(t, "bob", owners["LIKEDB"].Username)
+	assert.Equal(t, "carol", owners["LIKEDC"].Username)
+}
+
+func TestDownloadSavedPosts(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	
+	savedPage := func(shortcodes ...string) string {
+		var items []string
+		for _, code := range shortcodes {
+			items = append(items, fmt.Sprintf(`{"media":{"id":"%[1]s_1","code":"%[1]s","media_type":1,
+				"image_versions2":{"candidates":[{"url":"http://example.com/%[1]s.jpg"}]},
+				"user":{"pk":1,"username":"owner"}}}`, code))
+		}
+		return `{"items":[` + strings.Join(items, ",") + `],"more_available":false,"status":"ok"}`
+	}
+	
+	responses := map[string]string{
+		instagram.CollectionsEndpoint: `{"items":[
+			{"collection_id":"100","collection_name":"Travel","collection_type":"MEDIA","collection_media_count":2},
+			{"collection_id":"200","collection_name":"Food/Drinks","collection_type":"MEDIA","collection_media_count":2}
+		],"more_available":false,"status":"ok"}`,
+		fmt.Sprintf(instagram.CollectionFeedEndpoint, "100"): savedPage("POSTA", "POSTB"),
+		fmt.Sprintf(instagram.CollectionFeedEndpoint, "200"): savedPage("POSTB", "POSTC"),
+		instagram.SavedFeedEndpoint:                          savedPage("POSTA", "POSTB", "POSTC", "POSTD"),
+	}
+	
+	client := newMockClient(t, func(url string, target interface{}) error {
+		parsed, err := neturl.Parse(url)
+		require.NoError(t, err)
+		body, ok := responses[parsed.Path]
+		require.True(t, ok, "unexpected request %s", url)
+		return json.Unmarshal([]byte(body), target)
+	}, func(url string) ([]byte, error) {
+		return []byte("photo"), nil
+	})
+	
+	cfg := config.DefaultConfig()
+	cfg.Output.BaseDirectory = t.TempDir()
+	cfg.Notifications.Enabled = false
+	
+	s, err := New(cfg)
+	require.NoError(t, err)
+	s.SetClient(client)
+	
+	require.NoError(t, s.DownloadSavedPosts(false, true))
+	
+	savedDir := filepath.Join(cfg.Output.BaseDirectory, SavedFolder)
+	expected := map[string][]string{
+		"Travel":      {"POSTA", "POSTB"},
+		"Food_Drinks": {"POSTB", "POSTC"},
+		"":            {"POSTD"},
+	}
+	for folder, shortcodes := range expected {
+		entries, err := filepath.Glob(filepath.Join(savedDir, folder, "*.jpg"))
+		require.NoError(t, err)
+		var got []string
+		for _, entry := range entries {
+			got = append(got, strings.TrimSuffix(filepath.Base(entry), ".jpg"))
+		}
+		assert.ElementsMatch(t, shortcodes, got, "folder %q", folder)
+	}
+	
+	for _, folder := range []string{"Travel", "Food_Drinks"} {
+		meta, err := metadata.LoadUserMetadata(filepath.Join(savedDir, folder))
+		require.NoError(t, err)
+		require.NotNil(t, meta)
+		for _, photo := range meta.Photos {
+			switch photo.Shortcode {
+			case "POSTA":
+				assert.Equal(t, []string{"Travel"}, photo.Collections)
+			case "POSTB":
+				assert.Equal(t, []string{"Travel", "Food/Drinks"}, photo.Collections)
+			case "POSTC":
+				assert.Equal(t, []string{"Food/Drinks"}, photo.Collections)
+			}
+		}
+	}
+}
+
+func TestDownloadSavedPostsResume(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	
+	item := func(code string) string {
+		return fmt.Sprintf(`{"media":{"id":"%[1]s_1","code":"%[1]s","media_type":1,
+			"image_versions2":{"candidates":[{"url":"http://example.com/%[1]s.jpg"}]},
+			"user":{"pk":1,"username":"owner"}}}`, code)
+	}
+	
+	// The collection's third page fails in the first run, which leaves the
+	// checkpoint at the second
+	failing := true
+	client := newMockClient(t, func(url string, target interface{}) error {
+		parsed, err := neturl.Parse(url)
+		require.NoError(t, err)
+		var body string
+		switch parsed.Path {
+		case instagram.CollectionsEndpoint:
+			body = `{"items":[{"collection_id":"100","collection_name":"Travel","collection_type":"MEDIA","collection_media_count":2}],
+				"more_available":false,"status":"ok"}`
+		case fmt.Sprintf(instagram.CollectionFeedEndpoint, "100"):
+			switch parsed.Query().Get("max_id") {
+			case "":
+				body = `{"items":[` + item("POSTA") + `],"more_available":true,"next_max_id":"page2","status":"ok"}`
+			case "page2":
+				body = `{"items":[` + item("POSTB") + `],"more_available":true,"next_max_id":"page3","status":"ok"}`
+			default:
+				if failing {
+					return &errors.Error{Type: errors.ErrorTypeNotFound, Code: http.StatusNotFound}
+				}
+				body = `{"items":[],"more_available":false,"status":"ok"}`
+			}
+		case instagram.SavedFeedEndpoint:
+			body = `{"items":[` + item("POSTA") + "," + item("POSTB") + "," + item("POSTC") + `],"more_available":false,"status":"ok"}`
+		default:
+			t.Errorf("unexpected request %s", url)
+		}
+		return json.Unmarshal([]byte(body), target)
+	}, func(url string) ([]byte, error) {
+		return []byte("photo"), nil
+	})
+	
+	cfg := config.DefaultConfig()
+	cfg.Output.BaseDirectory = t.TempDir()
+	cfg.Notifications.Enabled = false
+	cfg.Retry.Enabled = false
+	
+	s, err := New(cfg)
+	require.NoError(t, err)
+	s.SetClient(client)
+	require.Error(t, s.DownloadSavedPosts(false, true))
+	
+	failing = false
+	s, err = New(cfg)
+	require.NoError(t, err)
+	s.SetClient(client)
+	require.NoError(t, s.DownloadSavedPosts(true, false))
+	
+	// The post on the page before the resume is still in the collection
+	savedDir := filepath.Join(cfg.Output.BaseDirectory, SavedFolder)
+	unsorted, err := filepath.Glob(filepath.Join(savedDir, "*.jpg"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(savedDir, "POSTC.jpg")}, unsorted)
+	
+	meta, err := metadata.LoadUserMetadata(filepath.Join(savedDir, "Travel"))
+	require.NoError(t, err)
+	require.NotNil(t, meta)
+	require.Len(t, meta.Photos, 2)
+	for _, photo := range meta.Photos {
+		assert.Equal(t, []string{"Travel"}, photo.Collections, photo.Shortcode)
+	}
+}
+
+func TestCollectionFolder(t *testing.T) {
+	used := make(map[string]bool)
+	assert.Equal(t, "Travel", collectionFolder(instagram.Collection{ID: "1", Name: "Travel"}, used))
+	assert.Equal(t, "travel_2", collectionFolder(instagram.Collection{ID: "2", Name: "travel"}, used))
+	assert.Equal(t, "a_b_c", collectionFolder(instagram.Collection{ID: "3", Name: "a/b:c"}, used))
+	assert.Equal(t, "4", collectionFolder(instagram.Collection{ID: "4", Name: " .. "}, used))
+}
+
+func TestVerifyRemote(t *testing.T) {
+	pages := map[string][]instagram.Node{
+		"": {
+			{Shortcode: "HAVE1", TakenAtTimestamp: 1700000000},
+			{Shortcode: "MISSING1", TakenAtTimestamp: 1690000000},
+			{Shortcode: "VIDEO1", TakenAtTimestamp: 1680000000, IsVideo: true},
+		},
+		"page2": {
+			{Shortcode: "HAVE2", TakenAtTimestamp: 1600000000},
+			{Shortcode: "OLD1", TakenAtTimestamp: 1500000000},
+		},
+	}
+	
+	client := newMockClient(t, func(url string, target interface{}) error {
+		resp := target.(*instagram.InstagramResponse)
+		resp.Status = "ok"
+		resp.Data.User.ID = "42"
+		resp.Data.User.EdgeOwnerToTimelineMedia.Count = 5
+		if !strings.Contains(url, "graphql") {
+			return nil
+		}
+		cursor := ""
+		if strings.Contains(url, `"after":"page2"`) {
+			cursor = "page2"
+		}
+		for _, node := range pages[cursor] {
+			resp.Data.User.EdgeOwnerToTimelineMedia.Edges = append(resp.Data.User.EdgeOwnerToTimelineMedia.Edges, instagram.Edge{Node: node})
+		}
+		if cursor == "" {
+			resp.Data.User.EdgeOwnerToTimelineMedia.PageInfo = instagram.PageInfo{HasNextPage: true, EndCursor: "page2"}
+		}
+		return nil
+	}, func(url string) ([]byte, error) {
+		t.Errorf("verify must not download, got %s", url)
+		return nil, nil
+	})
+	
+	cfg := config.DefaultConfig()
+	cfg.Output.BaseDirectory = t.TempDir()
+	cfg.Notifications.Enabled = false
+	cfg.Download.Since = time.Unix(1550000000, 0)
+	
+	s, err := New(cfg)
+	require.NoError(t, err)
+	s.SetClient(client)
+	
+	outputDir := s.getOutputDir("verify_user")
+	require.NoError(t, os.MkdirAll(outputDir, 0755))
+	for _, shortcode := range []string{"HAVE1", "HAVE2", "DELETED1"} {
+		require.NoError(t, os.WriteFile(filepath.Join(outputDir, shortcode+".jpg"), []byte("photo"), 0644))
+	}
+	
+	var scanned []int
+	report, err := s.VerifyRemote("verify_user", func(n int) { scanned = append(scanned, n) })
+	require.NoError(t, err)
+	
+	assert.Equal(t, outputDir, report.OutputDir)
+	assert.Equal(t, 5, report.RemotePosts)
+	assert.Equal(t, 3, report.RemotePhotos, "video and posts before since are not expected")
+	assert.Equal(t, 3, report.LocalPhotos)
+	assert.Equal(t, []string{"MISSING1"}, report.Missing)
+	assert.Equal(t, []string{"DELETED1"}, report.Orphaned)
+	assert.False(t, report.InSync())
+	assert.Equal(t, []int{3, 5}, scanned)
+	
+	t.Run("missing archive", func(t *testing.T) {
+		report, err := s.VerifyRemote("never_scraped", nil)
+		require.NoError(t, err)
+		assert.Zero(t, report.LocalPhotos)
+		assert.Len(t, report.Missing, 3)
+		assert.Empty(t, report.Orphaned)
+		_, err = os.Stat(s.getOutputDir("never_scraped"))
+		assert.True(t, os.IsNotExist(err), "verify must not create the output directory")
+	})
+}
+
+func TestAuditArchive(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	
+	cfg := config.DefaultConfig()
+	cfg.Output.BaseDirectory = t.TempDir()
+	cfg.Notifications.Enabled = false
+	s, err := New(cfg)
+	require.NoError(t, err)
+	
+	jpeg := []byte("\xff\xd8\xff\xe0 photo")
+	outputDir := s.OutputDir("alice")
+	require.NoError(t, os.MkdirAll(outputDir, 0755))
+	files := map[string][]byte{
+		"GOOD1.jpg":  jpeg,
+		"EMPTY1.jpg": {},
+		"BAD1.jpg":   []byte("<html>"),
+		"SIZE1.jpg":  jpeg[:5],
+		"EXTRA1.jpg": {},
+		"EDIT1.jpg":  []byte("\xff\xd8\xff\xe0 phot0"),
+	}
+	for name, data := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(outputDir, name), data, 0644))
+	}
+	meta := &metadata.UserMetadata{Username: "alice"}
+	sum, _, err := storage.HashFile(filepath.Join(outputDir, "GOOD1.jpg"))
+	require.NoError(t, err)
+	for _, shortcode := range []string{"GOOD1", "EMPTY1", "BAD1", "SIZE1", "GONE1", "EDIT1"} {
+		meta.Photos = append(meta.Photos, metadata.PhotoMetadata{Shortcode: shortcode, File: shortcode + ".jpg", FileSize: int64(len(jpeg)), SHA256: sum})
+	}
+	meta.Photos[1].FileSize = 0
+	meta.Photos[2].FileSize = 0
+	require.NoError(t, meta.Save(outputDir))
+	
+	report, err := s.AuditArchive("alice")
+	require.NoError(t, err)
+	assert.Equal(t, 7, report.Checked)
+	kinds := make(map[string]ProblemKind)
+	for _, problem := range report.Problems {
+		kinds[problem.Shortcode] = problem.Kind
+	}
+	assert.Equal(t, map[string]ProblemKind{
+		"EMPTY1": ProblemEmpty,
+		"BAD1":   ProblemCorrupt,
+		"SIZE1":  ProblemSizeMismatch,
+		"GONE1":  ProblemMissing,
+		"EXTRA1": ProblemEmpty,
+		"EDIT1":  ProblemChecksumMismatch,
+	}, kinds)
+	assert.False(t, report.OK())
+	
+	// Repairing fetches each post again; GONE1 has been deleted
+	codes := make(map[string]string)
+	for shortcode := range kinds {
+		id, err := instagram.MediaID(shortcode)
+		require.NoError(t, err)
+		codes[instagram.GetMediaInfoURL(id)] = shortcode
+	}
+	s.SetClient(newMockClient(t, func(url string, target interface{}) error {
+		if codes[url] == "GONE1" {
+			return &errors.Error{Type: errors.ErrorTypeNotFound, Message: "resource not found", Code: 404}
+		}
+		return json.Unmarshal([]byte(fmt.Sprintf(`{"items":[{"id":"1_10","code":%q,"media_type":1,
+			"image_versions2":{"candidates":[{"url":"http://example.com/%s.jpg"}]}}],"status":"ok"}`, codes[url], codes[url])), target)
+	}, func(url string) ([]byte, error) {
+		return jpeg, nil
+	}))
+	require.NoError(t, s.RepairArchive(report))
+	assert.ElementsMatch(t, []string{"EMPTY1", "BAD1", "SIZE1", "EXTRA1", "EDIT1"}, report.Repaired)
+	assert.Contains(t, report.Unrepaired, "GONE1")
+	
+	for _, shortcode := range report.Repaired {
+		data, err := os.ReadFile(filepath.Join(outputDir, shortcode+".jpg"))
+		require.NoError(t, err)
+		assert.Equal(t, jpeg, data, shortcode)
+	}
+	
+	// The repaired files pass a second audit, and metadata.json has their
+	// new entries
+	report, err = s.AuditArchive("alice")
+	require.NoError(t, err)
+	require.Len(t, report.Problems, 1)
+	assert.Equal(t, "GONE1", report.Problems[0].Shortcode)
+	meta, err = metadata.LoadUserMetadata(outputDir)
+	require.NoError(t, err)
+	assert.Len(t, meta.Photos, 7)
+}
+
+func TestAccountRotation(t *testing.T) {
+	var mu sync.Mutex
+	var cookies, agents, languages []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		cookies = append(cookies, r.Header.Get("Cookie"))
+		agents = append(agents, r.Header.Get("User-Agent"))
+		languages = append(languages, r.Header.Get("Accept-Language"))
+		mu.Unlock()
+		
+		// Only the second account is allowed through
+		if !strings.Contains(r.Header.Get("Cookie"), "sessionid=session-b") || r.Header.Get("x-csrftoken") != "csrf-b" {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprint(w, `{"data":{"user":{"id":"42","edge_owner_to_timeline_media":{"count":7}}},"status":"ok"}`)
+	}))
+	defer server.Close()
+	
+	cfg := config.DefaultConfig()
+	cfg.Notifications.Enabled = false
+	cfg.Retry.Enabled = false
+	s, err := New(cfg)
+	require.NoError(t, err)
+	
+	client := instagram.NewClientWithConfig(time.Second, &cfg.Retry, nil)
+	client.SetTransport(&mockTransport{testServerURL: server.URL})
+	s.SetClient(client)
+	
+	rotator, err := auth.NewAccountRotator([]*auth.Account{
+		{Username: "a", SessionID: "session-a", CSRFToken: "csrf-a"},
+		{Username: "b", SessionID: "session-b", CSRFToken: "csrf-b", UserAgent: "AgentB/1.0", Device: &auth.Device{
+			UserAgent:      "GeneratedAgent/1.0",
+			AppID:          auth.DefaultAppID,
+			DeviceID:       "6F9619FF-8B86-4011-B42D-00C04FC964FF",
+			MachineID:      "ZxYwVuTsRqPoNmLkJiHgFeDcBa",
+			AcceptLanguage: "en-GB,en;q=0.9",
+		}},
+	}, 2)
+	require.NoError(t, err)
+	s.SetAccountRotator(rotator)
+	
+	// The first 429 is below the threshold and is returned
+	_, _, err = s.getUserInfo("someone")
+	assert.Error(t, err)
+	assert.Equal(t, "a", rotator.Current().Username)
+	
+	// The second switches accounts and the request is retried as b
+	userID, count, err := s.getUserInfo("someone")
+	require.NoError(t, err)
+	assert.Equal(t, "42", userID)
+	assert.Equal(t, 7, count)
+	assert.Equal(t, "b", rotator.Current().Username)
+	
+	require.Len(t, cookies, 3)
+	assert.Contains(t, cookies[0], "sessionid=session-a")
+	assert.Contains(t, cookies[2], "sessionid=session-b")
+	assert.Equal(t, instagram.DefaultUserAgent, agents[0])
+	assert.Equal(t, "AgentB/1.0", agents[2])
+	
+	// Each account presents its own device
+	assert.Equal(t, auth.DefaultAcceptLanguage, languages[0])
+	assert.Equal(t, "en-GB,en;q=0.9", languages[2])
+	assert.Contains(t, cookies[2], "ig_did=6F9619FF-8B86-4011-B42D-00C04FC964FF")
+	assert.Contains(t, cookies[2], "mid=ZxYwVuTsRqPoNmLkJiHgFeDcBa")
+	assert.NotContains(t, cookies[0], "ig_did=6F9619FF")
+	
+	// Errors that are not the account's fault do not count
+	assert.Equal(t, 0, rotationStatus(&errors.Error{Type: errors.ErrorTypeNotFound, Code: http.StatusNotFound}))
+	assert.Equal(t, http.StatusUnauthorized, rotationStatus(fmt.Errorf("wrapped: %w", &errors.Error{Type: errors.ErrorTypeAuth, Code: http.StatusUnauthorized})))
+}
+
+func TestSessionRefreshIsSaved(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-CSRFToken") == "csrf-1" {
+			http.SetCookie(w, &http.Cookie{Name: "csrftoken", Value: "csrf-2", Domain: ".instagram.com", Path: "/"})
+			http.SetCookie(w, &http.Cookie{Name: "sessionid", Value: "session-2", Domain: ".instagram.com", Path: "/"})
+		}
+		fmt.Fprint(w, `{"data":{"user":{"id":"42","edge_owner_to_timeline_media":{"count":7}}},"status":"ok"}`)
+	}))
+	defer server.Close()
+	
+	manager, store := auth.NewMockManager()
+	require.NoError(t, store.Store(&auth.Account{Username: "alice", SessionID: "session-1", CSRFToken: "csrf-1"}))
+	
+	cfg := config.DefaultConfig()
+	cfg.Notifications.Enabled = false
+	cfg.Retry.Enabled = false
+	cfg.Instagram.SessionID = "session-1"
+	cfg.Instagram.CSRFToken = "csrf-1"
+	s, err := New(cfg)
+	require.NoError(t, err)
+	s.client.(*instagram.Client).SetTransport(&mockTransport{testServerURL: server.URL})
+	s.SetSessionStore(manager, "alice")
+	
+	_, _, err = s.getUserInfo("someone")
+	require.NoError(t, err)
+	stored, err := store.GetAccount("alice")
+	require.NoError(t, err)
+	assert.Equal(t, "session-2", stored.SessionID)
+	assert.Equal(t, "csrf-2", stored.CSRFToken)
+	
+	// Credentials that are not stored are left alone
+	s.SetSessionStore(manager, "")
+	s.sessionRefreshed(map[string]string{"sessionid": "session-3", "csrftoken": "csrf-3"})
+	stored, _ = store.GetAccount("alice")
+	assert.Equal(t, "session-2", stored.SessionID)
+}
+
+// fakeBrowser records the URLs fetched through the browser fallback
+type fakeBrowser struct {
+	urls []string
+	body string
+}
+
+func (b *fakeBrowser) GetJSON(url string, target interface{}) error {
+	b.urls = append(b.urls, url)
+	return json.Unmarshal([]byte(b.body), target)
+}
+
+func TestBrowserFallback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The API serves the login page instead of JSON
+		if strings.Contains(r.URL.Path, "blocked") || r.URL.Query().Get("username") == "someone" {
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, "<!DOCTYPE html><html>Log in</html>")
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Notifications.Enabled = false
+	cfg.Retry.Enabled = false
+	s, err := New(cfg)
+	require.NoError(t, err)
+
+	client := instagram.NewClientWithConfig(time.Second, &cfg.Retry, nil)
+	client.SetTransport(&mockTransport{testServerURL: server.URL})
+	s.SetClient(client)
+
+	browser := &fakeBrowser{body: `{"data":{"user":{"id":"42","edge_owner_to_timeline_media":{"count":7}}},"status":"ok"}`}
+	s.SetBrowserFallback(browser)
+	var reported int
+	s.client.(*browserClient).onFallback = func(error) { reported++ }
+
+	userID, count, err := s.getUserInfo("someone")
+	require.NoError(t, err)
+	assert.Equal(t, "42", userID)
+	assert.Equal(t, 7, count)
+	assert.Equal(t, []string{instagram.GetProfileURL("someone")}, browser.urls)
+
+	var page map[string]interface{}
+	require.NoError(t, s.client.GetJSON(instagram.BaseURL+"/api/v1/blocked/", &page))
+	assert.Equal(t, "ok", page["status"])
+	assert.Equal(t, 1, reported, "the switch to the browser is reported once")
+
+	// Errors other than blocks are returned without trying the browser
+	err = s.client.GetJSON(instagram.BaseURL+"/api/v1/missing/", &page)
+	assert.Error(t, err)
+	assert.Len(t, browser.urls, 2)
+	assert.True(t, blocked(&errors.Error{Type: errors.ErrorTypeUnknown, Code: http.StatusForbidden}))
+	assert.False(t, blocked(&errors.Error{Type: errors.ErrorTypeRateLimit, Code: http.StatusTooManyRequests}))
+}
+
+func TestSharedWorkerPool(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	
+	var downloads int32
+	client := newMockClient(t, func(url string, target interface{}) error {
+		resp := target.(*instagram.InstagramResponse)
+		resp.Status = "ok"
+		resp.Data.User.ID = "42"
+		if strings.Contains(url, "second_user") {
+			resp.Data.User.ID = "43"
+		}
+		resp.Data.User.EdgeOwnerToTimelineMedia.Count = 3
+		if !strings.Contains(url, "graphql") {
+			return nil
+		}
+		for i := 0; i < 3; i++ {
+			node := instagram.Node{Shortcode: fmt.Sprintf("POST%d", i), DisplayURL: fmt.Sprintf("http://example.com/%d.jpg", i)}
+			resp.Data.User.EdgeOwnerToTimelineMedia.Edges = append(resp.Data.User.EdgeOwnerToTimelineMedia.Edges, instagram.Edge{Node: node})
+		}
+		return nil
+	}, func(url string) ([]byte, error) {
+		atomic.AddInt32(&downloads, 1)
+		return []byte(url), nil
+	})
+	
+	cfg := config.DefaultConfig()
+	cfg.Output.BaseDirectory = t.TempDir()
+	cfg.Notifications.Enabled = false
+	
+	pool := downloader.NewSharedPool(2, nil)
+	pool.Start()
+	defer pool.Stop()
+	
+	// Two profiles scraped at once download through the same workers
+	var wg sync.WaitGroup
+	scrapers := make(map[string]*Scraper)
+	for _, username := range []string{"first_user", "second_user"} {
+		s, err := New(cfg)
+		require.NoError(t, err)
+		s.SetClient(client)
+		s.SetWorkerPool(pool)
+		scrapers[username] = s
+		
+		wg.Add(1)
+		go func(s *Scraper, username string) {
+			defer wg.Done()
+			assert.NoError(t, s.DownloadUserPhotosWithResume(username, false, true))
+		}(s, username)
+	}
+	wg.Wait()
+	
+	assert.Equal(t, int32(6), atomic.LoadInt32(&downloads))
+	for username, s := range scrapers {
+		entries, err := os.ReadDir(s.getOutputDir(username))
+		require.NoError(t, err)
+		photos := 0
+		for _, entry := range entries {
+			if filepath.Ext(entry.Name()) == ".jpg" {
+				photos++
+			}
+		}
+		assert.Equal(t, 3, photos, username)
+	}
+	assert.Empty(t, pool.Stats(), "finished profiles should leave the pool")
+}
+
+func TestProfileInfoFallback(t *testing.T) {
+	var profileErr error
+	client := newMockClient(t, func(url string, target interface{}) error {
+		return profileErr
+	}, nil)
+	
+	cfg := config.DefaultConfig()
+	cfg.Output.BaseDirectory = t.TempDir()
+	cfg.Notifications.Enabled = false
+	cfg.Retry.Enabled = false
+	s, err := New(cfg)
+	require.NoError(t, err)
+	s.SetClient(client)
+	outputDir := s.getOutputDir("someone")
+	require.NoError(t, os.MkdirAll(outputDir, 0755))
+	
+	// Without an earlier sync there is nothing to fall back to
+	profileErr = &errors.Error{Type: errors.ErrorTypeServerError, Code: http.StatusServiceUnavailable}
+	_, _, err = s.profileInfo("someone", outputDir)
+	assert.Error(t, err)
+	
+	meta := &metadata.UserMetadata{Username: "someone", UserID: "42"}
+	require.NoError(t, meta.Save(outputDir))
+	
+	// Transient failures use the user ID from the last sync
+	user, total, err := s.profileInfo("someone", outputDir)
+	require.NoError(t, err)
+	assert.Equal(t, "42", user.ID)
+	assert.Equal(t, -1, total)
+	
+	// Permanent failures are not masked by the cache
+	profileErr = &errors.Error{Type: errors.ErrorTypeNotFound, Code: http.StatusNotFound}
+	_, _, err = s.profileInfo("someone", outputDir)
+	assert.Error(t, err)
+	
+	// A different profile's archive is never used
+	profileErr = &errors.Error{Type: errors.ErrorTypeNetwork}
+	_, _, err = s.profileInfo("someone_else", outputDir)
+	assert.Error(t, err)
+}
+
+func TestDownloadReels(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	
+	pages := map[string]string{
+		"": `{"items":[{"media":
+			{"id":"1_42","code":"REELA","taken_at":1700000000,"media_type":2,"play_count":1500,
+			 "image_versions2":{"candidates":[{"url":"http://example.com/a.jpg"}]},
+			 "video_versions":[{"url":"http://example.com/a.mp4"}],
+			 "clips_metadata":{"music_info":{"music_asset_info":{"title":"Song","display_artist":"Artist"}}},
+			 "user":{"pk":42,"username":"someone"}}}
+		],"paging_info":{"max_id":"cursor2","more_available":true},"status":"ok"}`,
+		"cursor2": `{"items":[{"media":
+			{"id":"2_42","code":"REELB","taken_at":1600000000,"media_type":2,"play_count":20,
+			 "image_versions2":{"candidates":[{"url":"http://example.com/b.jpg"}]},
+			 "video_versions":[{"url":"http://example.com/b.mp4"}],
+			 "clips_metadata":{"original_sound_info":{"original_audio_title":"Original audio"}},
+			 "user":{"pk":42,"username":"someone"}}}
+		],"paging_info":{"more_available":false},"status":"ok"}`,
+	}
+	
+	client := newMockClient(t, func(url string, target interface{}) error {
+		parsed, err := neturl.Parse(url)
+		require.NoError(t, err)
+		if parsed.Path == instagram.ProfileEndpoint {
+			resp := target.(*instagram.InstagramResponse)
+			resp.Status = "ok"
+			resp.Data.User.ID = "42"
+			return nil
+		}
+		require.Equal(t, instagram.ClipsEndpoint, parsed.Path)
+		require.Equal(t, "42", parsed.Query().Get("target_user_id"))
+		return json.Unmarshal([]byte(pages[parsed.Query().Get("max_id")]), target)
+	}, func(url string) ([]byte, error) {
+		return []byte("media " + url), nil
+	})
+	
+	cfg := config.DefaultConfig()
+	cfg.Output.BaseDirectory = t.TempDir()
+	cfg.Notifications.Enabled = false
+	
+	s, err := New(cfg)
+	require.NoError(t, err)
+	s.SetClient(client)
+	
+	require.NoError(t, s.DownloadReels("someone", false, true))
+	
+	reelsDir := filepath.Join(s.getOutputDir("someone"), ReelsFolder)
+	data, err := os.ReadFile(filepath.Join(reelsDir, "REELA.mp4"))
+	require.NoError(t, err)
+	assert.Equal(t, "media http://example.com/a.mp4", string(data))
+	data, err = os.ReadFile(filepath.Join(reelsDir, "thumbnails", "REELB.jpg"))
+	require.NoError(t, err)
+	assert.Equal(t, "media http://example.com/b.jpg", string(data))
+	
+	meta, err := metadata.LoadUserMetadata(reelsDir)
+	require.NoError(t, err)
+	require.NotNil(t, meta)
+	require.Len(t, meta.Photos, 2)
+	reels := make(map[string]metadata.PhotoMetadata)
+	for _, photo := range meta.Photos {
+		reels[photo.Shortcode] = photo
+	}
+	assert.Equal(t, 1500, reels["REELA"].VideoPlays)
+	assert.Equal(t, "Artist - Song", reels["REELA"].AudioTitle)
+	assert.Equal(t, "thumbnails/REELA.jpg", reels["REELA"].Thumbnail)
+	assert.Equal(t, "REELA.mp4", reels["REELA"].File)
+	assert.Equal(t, "Original audio", reels["REELB"].AudioTitle)
+}
+
+func TestDownloadPost(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	
+	var requested []string
+	client := newMockClient(t, func(url string, target interface{}) error {
+		requested = append(requested, url)
+		return json.Unmarshal([]byte(`{"items":[
+			{"id":"1_10","code":"POST","taken_at":1700000000,"media_type":8,
+			 "user":{"pk":10,"username":"alice"},"caption":{"text":"hello"},
+			 "carousel_media":[
+				{"media_type":1,"image_versions2":{"candidates":[{"url":"http://example.com/1.jpg"}]}},
+				{"media_type":2,"image_versions2":{"candidates":[{"url":"http://example.com/2.jpg"}]},
+				 "video_versions":[{"url":"http://example.com/2.mp4"}]}
+			 ]}
+		],"status":"ok"}`), target)
+	}, func(url string) ([]byte, error) {
+		return []byte("media " + url), nil
+	})
+	
+	cfg := config.DefaultConfig()
+	cfg.Output.BaseDirectory = t.TempDir()
+	cfg.Notifications.Enabled = false
+	s, err := New(cfg)
+	require.NoError(t, err)
+	s.SetClient(client)
+	
+	post, err := s.DownloadPost("https://www.instagram.com/p/POST/")
+	require.NoError(t, err)
+	require.Len(t, requested, 1)
+	mediaID, _ := instagram.MediaID("POST")
+	assert.Equal(t, instagram.GetMediaInfoURL(mediaID), requested[0])
+	
+	assert.Equal(t, filepath.Join(cfg.Output.BaseDirectory, PostsFolder), post.OutputDir)
+	require.Len(t, post.Media, 2)
+	assert.Equal(t, "POST_1.jpg", post.Media[0].File)
+	assert.Equal(t, "POST_2.mp4", post.Media[1].File)
+	assert.Equal(t, "alice", post.Media[0].Owner.Username)
+	assert.Empty(t, post.Failed)
+	
+	data, err := os.ReadFile(filepath.Join(post.OutputDir, "POST_2.mp4"))
+	require.NoError(t, err)
+	assert.Equal(t, "media http://example.com/2.mp4", string(data))
+	
+	// Downloading again reports the saved files without fetching them
+	post, err = s.DownloadPost("POST")
+	require.NoError(t, err)
+	require.Len(t, post.Media, 2)
+	assert.Equal(t, "POST_2.mp4", post.Media[1].File)
+	client.AssertNumberOfCalls(t, "DownloadPhoto", 2)
+	
+	_, err = s.DownloadPost("https://www.instagram.com/someone/")
+	assert.Error(t, err)
+}
+
+func TestEstimateProfile(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Output.BaseDirectory = t.TempDir()
+	cfg.Notifications.Enabled = false
+	cfg.RateLimit.RequestsPerMinute = 60
+	cfg.Download.ConcurrentDownloads = 3
+	s, err := New(cfg)
+	require.NoError(t, err)
+	
+	var mediaCalls int32
+	s.SetClient(newMockClient(t, func(url string, target interface{}) error {
+		if strings.Contains(url, "graphql") {
+			atomic.AddInt32(&mediaCalls, 1)
+		}
+		resp := target.(*instagram.InstagramResponse)
+		resp.Status = "ok"
+		resp.Data.User.ID = "42"
+		resp.Data.User.EdgeOwnerToTimelineMedia.Count = 120
+		return nil
+	}, nil))
+	
+	estimate, err := s.EstimateProfile("someone")
+	require.NoError(t, err)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&mediaCalls), "the timeline is not fetched")
+	assert.Equal(t, 120, estimate.Posts)
+	assert.Equal(t, 4, estimate.APICalls, "profile and three pages")
+	assert.Equal(t, 120, estimate.Downloads)
+	// 123 tokens at 60 per minute need two refills
+	assert.Equal(t, 2*time.Minute, estimate.RateLimitWait)
+	assert.Equal(t, 2*time.Minute, estimate.Duration)
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, start.Add(2*time.Minute), estimate.FinishAt(start))
+	
+	// Archived media are not downloaded again
+	meta := &metadata.UserMetadata{Username: "someone", Photos: make([]metadata.PhotoMetadata, 100)}
+	require.NoError(t, os.MkdirAll(s.getOutputDir("someone"), 0755))
+	require.NoError(t, meta.Save(s.getOutputDir("someone")))
+	estimate, err = s.EstimateProfile("someone")
+	require.NoError(t, err)
+	assert.Equal(t, 100, estimate.Archived)
+	assert.Equal(t, 20, estimate.Downloads)
+	assert.Equal(t, time.Duration(0), estimate.RateLimitWait)
+	// Three pages, then 20 downloads over three workers
+	assert.Equal(t, 3*time.Second+7*2*time.Second, estimate.Duration)
+	
+	// Only the newest posts are looked at with max_posts or max_pages
+	cfg.Download.MaxPosts = 60
+	cfg.Download.MaxPages = 1
+	estimate, err = s.EstimateProfile("someone")
+	require.NoError(t, err)
+	assert.Equal(t, 2, estimate.APICalls, "profile and one page")
+	assert.Equal(t, 20, estimate.Downloads)
+	cfg.Download.MaxPages = 0
+	cfg.Download.MaxPosts = 10
+	estimate, err = s.EstimateProfile("someone")
+	require.NoError(t, err)
+	assert.Equal(t, 2, estimate.APICalls)
+	assert.Equal(t, 10, estimate.Downloads)
+}
+
+func TestScanProfile(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Output.BaseDirectory = t.TempDir()
+	cfg.Notifications.Enabled = false
+	s, err := New(cfg)
+	require.NoError(t, err)
+	
+	day := func(d int) int64 {
+		return time.Date(2024, 1, d, 12, 0, 0, 0, time.UTC).Unix()
+	}
+	duration := 10.0
+	var downloads int32
+	s.SetClient(newMockClient(t, func(url string, target interface{}) error {
+		resp := target.(*instagram.InstagramResponse)
+		resp.Status = "ok"
+		resp.Data.User.ID = "42"
+		media := &resp.Data.User.EdgeOwnerToTimelineMedia
+		if !strings.Contains(url, "graphql") {
+			media.Count = 5
+			return nil
+		}
+		if strings.Contains(url, "page2") {
+			media.Edges = []instagram.Edge{
+				{Node: instagram.Node{Shortcode: "VIDEO", IsVideo: true, VideoDuration: &duration, TakenAtTimestamp: day(5), EdgeLikedBy: instagram.EdgeLikedBy{Count: 150}}},
+				{Node: instagram.Node{Shortcode: "OLD", TakenAtTimestamp: day(2), EdgeLikedBy: instagram.EdgeLikedBy{Count: 20000}}},
+			}
+			return nil
+		}
+		media.Edges = []instagram.Edge{
+			{Node: instagram.Node{Shortcode: "PINNED", TakenAtTimestamp: day(1), PinnedForUsers: []instagram.PinnedUser{{ID: "42"}}, EdgeLikedBy: instagram.EdgeLikedBy{Count: 5}}},
+			{Node: instagram.Node{Shortcode: "CAROUSEL", Typename: "XDTGraphSidecar", TakenAtTimestamp: day(20), EdgeLikedBy: instagram.EdgeLikedBy{Count: 1200}}},
+			{Node: instagram.Node{Shortcode: "VIDEO2", IsVideo: true, TakenAtTimestamp: day(10), EdgeLikedBy: instagram.EdgeLikedBy{Count: 40}}},
+		}
+		media.PageInfo = instagram.PageInfo{HasNextPage: true, EndCursor: "page2"}
+		return nil
+	}, func(url string) ([]byte, error) {
+		atomic.AddInt32(&downloads, 1)
+		return []byte("photo"), nil
+	}))
+	
+	var scanned []int
+	stats, err := s.ScanProfile("someone", func(n int) { scanned = append(scanned, n) })
+	require.NoError(t, err)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&downloads), "nothing is downloaded")
+	assert.Equal(t, []int{3, 5}, scanned)
+	assert.Equal(t, 5, stats.Posts)
+	assert.Equal(t, 5, stats.Scanned)
+	assert.Equal(t, 2, stats.Pages)
+	assert.Equal(t, 2, stats.Photos)
+	assert.Equal(t, 2, stats.Videos)
+	assert.Equal(t, 1, stats.Carousels)
+	assert.Equal(t, 1, stats.Pinned)
+	assert.Equal(t, time.Unix(day(1), 0), stats.Oldest)
+	assert.Equal(t, time.Unix(day(20), 0), stats.Newest)
+	// Three photos, a 10 second video and one of unknown length
+	assert.Equal(t, int64(3*estimatedPhotoBytes+10*estimatedVideoBitrate+estimatedVideoBytes), stats.EstimatedBytes)
+	
+	likes := stats.Likes
+	assert.Equal(t, 5, likes.Min)
+	assert.Equal(t, 20000, likes.Max)
+	assert.Equal(t, 150, likes.Median)
+	assert.Equal(t, 20000, likes.P90)
+	assert.InDelta(t, 4279.0, likes.Mean, 0.01)
+	assert.Equal(t, []LikeBucket{
+		{Min: 0, Max: 100, Posts: 2},
+		{Min: 100, Max: 1000, Posts: 1},
+		{Min: 1000, Max: 10000, Posts: 1},
+		{Min: 10000, Max: 100000, Posts: 1},
+		{Min: 100000, Posts: 0},
+	}, likes.Buckets)
+	
+	// max_pages samples the newest posts
+	cfg.Download.MaxPages = 1
+	stats, err = s.ScanProfile("someone", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Pages)
+	assert.Equal(t, 3, stats.Scanned)
+}
+
+func TestDownloadHashtag(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	
+	item := func(code string) string {
+		return fmt.Sprintf(`{"id":"%s_1","code":"%s","taken_at":1700000000,"media_type":1,
+			"image_versions2":{"candidates":[{"url":"http://example.com/%s.jpg"}]},
+			"user":{"pk":1,"username":"owner"}}`, code, code, code)
+	}
+	pages := map[string]string{
+		"": fmt.Sprintf(`{"items":[%s,%s],"ranked_items":[%s],"more_available":true,"next_max_id":"cursor2","status":"ok"}`,
+			item("RECENTA"), item("RECENTB"), item("TOPA")),
+		"cursor2": fmt.Sprintf(`{"items":[%s,%s],"more_available":true,"next_max_id":"cursor3","status":"ok"}`,
+			item("RECENTC"), item("RECENTD")),
+	}
+	
+	var requested []string
+	var mu sync.Mutex
+	newScraper := func(t *testing.T) (*Scraper, string) {
+		requested = nil
+		cfg := config.DefaultConfig()
+		cfg.Output.BaseDirectory = t.TempDir()
+		cfg.Notifications.Enabled = false
+		s, err := New(cfg)
+		require.NoError(t, err)
+		s.SetClient(newMockClient(t, func(url string, target interface{}) error {
+			mu.Lock()
+			requested = append(requested, url)
+			mu.Unlock()
+			
+			parsed, err := neturl.Parse(url)
+			require.NoError(t, err)
+			require.Equal(t, "/api/v1/feed/tag/sunset/", parsed.Path)
+			return json.Unmarshal([]byte(pages[parsed.Query().Get("max_id")]), target)
+		}, func(url string) ([]byte, error) {
+			return []byte("photo " + url), nil
+		}))
+		return s, filepath.Join(cfg.Output.BaseDirectory, "#sunset")
+	}
+	
+	t.Run("recent posts up to the limit", func(t *testing.T) {
+		s, tagDir := newScraper(t)
+		require.NoError(t, s.DownloadHashtag("#Sunset", false, 3, false, true))
+		assert.Len(t, requested, 2, "pagination stops at the limit")
+		
+		for _, shortcode := range []string{"RECENTA", "RECENTB", "RECENTC"} {
+			assert.FileExists(t, filepath.Join(tagDir, shortcode+".jpg"))
+		}
+		assert.NoFileExists(t, filepath.Join(tagDir, "RECENTD.jpg"))
+		assert.NoFileExists(t, filepath.Join(tagDir, "TOPA.jpg"))
+		
+		meta, err := metadata.LoadUserMetadata(tagDir)
+		require.NoError(t, err)
+		require.NotNil(t, meta)
+		assert.Equal(t, "#sunset", meta.Username)
+		assert.Len(t, meta.Photos, 3)
+	})
+	
+	t.Run("top posts", func(t *testing.T) {
+		s, tagDir := newScraper(t)
+		require.NoError(t, s.DownloadHashtag("sunset", true, 0, false, true))
+		assert.Len(t, requested, 1)
+		assert.FileExists(t, filepath.Join(tagDir, "TOPA.jpg"))
+		assert.NoFileExists(t, filepath.Join(tagDir, "RECENTA.jpg"))
+	})
+	
+	t.Run("invalid hashtag", func(t *testing.T) {
+		s, _ := newScraper(t)
+		assert.Error(t, s.DownloadHashtag("sun set", false, 0, false, true))
+		assert.Empty(t, requested)
+	})
+}
+
+func TestDownloadLocation(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	
+	item := func(code string, takenAt int64) string {
+		return fmt.Sprintf(`{"id":"%s_1","code":"%s","taken_at":%d,"media_type":1,
+			"image_versions2":{"candidates":[{"url":"http://example.com/%s.jpg"}]},
+			"location":{"pk":212988663,"name":"New York","slug":"new-york-new-york","lat":40.7142,"lng":-74.0064},
+			"user":{"pk":1,"username":"owner"}}`, code, code, takenAt, code)
+	}
+	pages := map[string]string{
+		"": fmt.Sprintf(`{"items":[%s,%s],"more_available":true,"next_max_id":"cursor2",
+			"location":{"pk":212988663,"name":"New York","city":"New York","lat":40.7142,"lng":-74.0064},"status":"ok"}`,
+			item("PLACEA", 1700000000), item("PLACEB", 1690000000)),
+		"cursor2": fmt.Sprintf(`{"items":[%s],"more_available":false,"status":"ok"}`, item("PLACEC", 1600000000)),
+	}
+	
+	var requested []string
+	cfg := config.DefaultConfig()
+	cfg.Output.BaseDirectory = t.TempDir()
+	cfg.Notifications.Enabled = false
+	s, err := New(cfg)
+	require.NoError(t, err)
+	s.SetClient(newMockClient(t, func(url string, target interface{}) error {
+		requested = append(requested, url)
+		parsed, err := neturl.Parse(url)
+		require.NoError(t, err)
+		require.Equal(t, "/api/v1/feed/location/212988663/", parsed.Path)
+		return json.Unmarshal([]byte(pages[parsed.Query().Get("max_id")]), target)
+	}, func(url string) ([]byte, error) {
+		return []byte("photo " + url), nil
+	}))
+	
+	require.Error(t, s.DownloadLocation("new-york", false, true))
+	require.Empty(t, requested)
+	
+	require.NoError(t, s.DownloadLocation("https://www.instagram.com/explore/locations/212988663/new-york-new-york/", false, true))
+	assert.Len(t, requested, 2)
+	
+	dir := filepath.Join(cfg.Output.BaseDirectory, LocationsFolder, "212988663")
+	for _, shortcode := range []string{"PLACEA", "PLACEB", "PLACEC"} {
+		assert.FileExists(t, filepath.Join(dir, shortcode+".jpg"))
+	}
+	
+	meta, err := metadata.LoadUserMetadata(dir)
+	require.NoError(t, err)
+	require.NotNil(t, meta)
+	require.NotNil(t, meta.Location)
+	assert.Equal(t, "New York", meta.Location.Name)
+	assert.Equal(t, "New York", meta.Location.City)
+	assert.InDelta(t, 40.7142, meta.Location.Lat, 1e-9)
+	require.Len(t, meta.Photos, 3)
+	require.NotNil(t, meta.Photos[0].Location)
+	assert.Equal(t, "212988663", meta.Photos[0].Location.ID)
+	assert.InDelta(t, -74.0064, meta.Photos[0].Location.Lng, 1e-9)
+}
+
+func TestSaveProfile(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	
+	var pictures []string
+	client := newMockClient(t, func(url string, target interface{}) error {
+		resp := target.(*instagram.InstagramResponse)
+		resp.Status = "ok"
+		if strings.Contains(url, "graphql") {
+			return nil
+		}
+		return json.Unmarshal([]byte(`{"data":{"user":{
+			"id":"42","username":"someone","full_name":"Some One","biography":"Photos of things",
+			"external_url":"https://example.com","is_verified":true,
+			"profile_pic_url":"http://example.com/small.jpg","profile_pic_url_hd":"http://example.com/hd.jpg",
+			"edge_followed_by":{"count":1200},"edge_follow":{"count":300},
+			"edge_owner_to_timeline_media":{"count":0}
+		}},"status":"ok"}`), target)
+	}, func(url string) ([]byte, error) {
+		pictures = append(pictures, url)
+		return []byte("avatar"), nil
+	})
+	
+	cfg := config.DefaultConfig()
+	cfg.Output.BaseDirectory = t.TempDir()
+	cfg.Notifications.Enabled = false
+	s, err := New(cfg)
+	require.NoError(t, err)
+	s.SetClient(client)
+	outputDir := s.getOutputDir("someone")
+	
+	profile, err := s.SaveProfile("someone")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"http://example.com/hd.jpg"}, pictures, "the HD picture is downloaded")
+	assert.Equal(t, "avatar/profile_pic.jpg", profile.ProfilePic)
+	
+	data, err := os.ReadFile(filepath.Join(outputDir, AvatarFolder, "profile_pic.jpg"))
+	require.NoError(t, err)
+	assert.Equal(t, "avatar", string(data))
+	
+	saved, err := metadata.LoadProfile(outputDir)
+	require.NoError(t, err)
+	require.NotNil(t, saved)
+	assert.Equal(t, "42", saved.UserID)
+	assert.Equal(t, "Some One", saved.FullName)
+	assert.Equal(t, "Photos of things", saved.Biography)
+	assert.Equal(t, "https://example.com", saved.ExternalURL)
+	assert.Equal(t, 1200, saved.Followers)
+	assert.Equal(t, 300, saved.Following)
+	assert.True(t, saved.IsVerified)
+	
+	// Scraping takes a snapshot once; verifying never does
+	require.NoError(t, os.RemoveAll(outputDir))
+	pictures = nil
+	require.NoError(t, s.DownloadUserPhotosWithResume("someone", false, true))
+	assert.Len(t, pictures, 1)
+	assert.FileExists(t, filepath.Join(outputDir, metadata.ProfileFile))
+	
+	verifyDir := s.getOutputDir("verified")
+	require.NoError(t, os.MkdirAll(verifyDir, 0755))
+	_, err = s.VerifyRemote("verified", nil)
+	require.NoError(t, err)
+	assert.NoFileExists(t, filepath.Join(verifyDir, metadata.ProfileFile))
+}
+
+func TestPageRetry(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	
+	newScraper := func(t *testing.T, pageErrs ...error) (*Scraper, *int32) {
+		t.Helper()
+		var calls int32
+		cfg := config.DefaultConfig()
+		cfg.Output.BaseDirectory = t.TempDir()
+		cfg.Notifications.Enabled = false
+		cfg.Retry.MaxAttempts = 3
+		cfg.Retry.BaseDelay = time.Millisecond
+		cfg.Retry.MaxDelay = time.Millisecond
+		s, err := New(cfg)
+		require.NoError(t, err)
+		s.SetClient(newMockClient(t, func(url string, target interface{}) error {
+			resp := target.(*instagram.InstagramResponse)
+			resp.Status = "ok"
+			resp.Data.User.ID = "42"
+			if !strings.Contains(url, "graphql") {
+				resp.Data.User.EdgeOwnerToTimelineMedia.Count = 1
+				return nil
+			}
+			call := int(atomic.AddInt32(&calls, 1))
+			if call <= len(pageErrs) {
+				return pageErrs[call-1]
+			}
+			resp.Data.User.EdgeOwnerToTimelineMedia.Edges = []instagram.Edge{
+				{Node: instagram.Node{Shortcode: "RETRIED", DisplayURL: "http://example.com/a.jpg"}},
+			}
+			return nil
+		}, func(url string) ([]byte, error) {
+			return []byte("photo"), nil
+		}))
+		return s, &calls
+	}
+	serverErr := &errors.Error{Type: errors.ErrorTypeServerError, Code: http.StatusBadGateway}
+	
+	t.Run("transient failures are retried", func(t *testing.T) {
+		s, calls := newScraper(t, serverErr, serverErr)
+		require.NoError(t, s.DownloadUserPhotosWithResume("retry_user", false, true))
+		assert.Equal(t, int32(3), atomic.LoadInt32(calls))
+		assert.FileExists(t, filepath.Join(s.getOutputDir("retry_user"), "RETRIED.jpg"))
+	})
+	
+	t.Run("gives up after the configured attempts", func(t *testing.T) {
+		s, calls := newScraper(t, serverErr, serverErr, serverErr)
+		err := s.DownloadUserPhotosWithResume("retry_user", false, true)
+		var pageErr *PageError
+		require.ErrorAs(t, err, &pageErr)
+		assert.Equal(t, 3, pageErr.Attempts)
+		assert.Equal(t, 1, pageErr.Page)
+		assert.Equal(t, "retry_user", pageErr.Feed)
+		assert.ErrorIs(t, err, serverErr)
+		assert.Equal(t, int32(3), atomic.LoadInt32(calls))
+		
+		checkpointMgr, err := checkpoint.NewManager("retry_user")
+		require.NoError(t, err)
+		assert.True(t, checkpointMgr.Exists(), "the checkpoint is kept for --resume")
+	})
+	
+	t.Run("permanent failures are not retried", func(t *testing.T) {
+		s, calls := newScraper(t, &errors.Error{Type: errors.ErrorTypeNotFound, Code: http.StatusNotFound})
+		var pageErr *PageError
+		require.ErrorAs(t, s.DownloadUserPhotosWithResume("retry_user", false, true), &pageErr)
+		assert.Equal(t, 1, pageErr.Attempts)
+		assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+	})
+	
+	t.Run("cancelled context stops retrying", func(t *testing.T) {
+		s, calls := newScraper(t, serverErr, serverErr, serverErr)
+		s.config.Retry.BaseDelay = time.Hour
+		s.config.Retry.MaxDelay = time.Hour
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		s.SetContext(ctx)
+		
+		var pageErr *PageError
+		require.ErrorAs(t, s.DownloadUserPhotosWithResume("retry_user", false, true), &pageErr)
+		assert.ErrorIs(t, pageErr, context.Canceled)
+		assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+	})
+}
+
+func TestSkippedPostsInCheckpoint(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	
+	cfg := config.DefaultConfig()
+	cfg.Output.BaseDirectory = t.TempDir()
+	cfg.Notifications.Enabled = false
+	cfg.Retry.Enabled = false
+	cfg.Download.SkipVideos = true
+	cfg.Download.Until = time.Unix(1700000000, 0)
+	s, err := New(cfg)
+	require.NoError(t, err)
+	
+	photo := func(shortcode string, takenAt int64) instagram.Edge {
+		return instagram.Edge{Node: instagram.Node{
+			Shortcode:        shortcode,
+			DisplayURL:       "http://example.com/" + shortcode + ".jpg",
+			TakenAtTimestamp: takenAt,
+		}}
+	}
+	var queued []string
+	var mu sync.Mutex
+	video := photo("VIDEO", 1600000000)
+	video.Node.IsVideo = true
+	video.Node.VideoURL = "http://example.com/VIDEO.mp4"
+	
+	s.SetClient(newMockClient(t, func(url string, target interface{}) error {
+		resp := target.(*instagram.InstagramResponse)
+		resp.Status = "ok"
+		resp.Data.User.ID = "42"
+		timeline := &resp.Data.User.EdgeOwnerToTimelineMedia
+		switch {
+		case !strings.Contains(url, "graphql"):
+			timeline.Count = 6
+		case strings.Contains(url, "page3"):
+			return &errors.Error{Type: errors.ErrorTypeNotFound, Code: http.StatusNotFound}
+		case strings.Contains(url, "page2"):
+			timeline.Edges = []instagram.Edge{photo("NEWER", 1800000000), photo("OLDER", 1500000000)}
+			timeline.PageInfo = instagram.PageInfo{HasNextPage: true, EndCursor: "page3"}
+		default:
+			timeline.Edges = []instagram.Edge{video, photo("FUTURE", 1800000000), photo("PHOTO", 1600000000)}
+			timeline.PageInfo = instagram.PageInfo{HasNextPage: true, EndCursor: "page2"}
+		}
+		return nil
+	}, func(url string) ([]byte, error) {
+		mu.Lock()
+		queued = append(queued, url)
+		mu.Unlock()
+		return nil, &errors.Error{Type: errors.ErrorTypeNotFound, Code: http.StatusNotFound}
+	}))
+	
+	err = s.DownloadUserPhotosWithResume("skip_user", false, true)
+	var pageErr *PageError
+	require.ErrorAs(t, err, &pageErr)
+	assert.Equal(t, 3, pageErr.Page)
+	
+	// The checkpoint resumes from the second page, so it keeps the skips of the first
+	checkpointMgr, err := checkpoint.NewManager("skip_user")
+	require.NoError(t, err)
+	cp, err := checkpointMgr.Load()
+	require.NoError(t, err)
+	require.NotNil(t, cp)
+	assert.Equal(t, "page2", cp.EndCursor)
+	assert.Equal(t, map[string]int{skipVideos: 1, skipDateRange: 1}, cp.Skipped)
+	assert.Equal(t, 6, cp.TotalPhotos)
+	assert.Equal(t, []checkpoint.PageStats{{Page: 1, Posts: 3, Queued: 1, Skipped: 2}}, cp.Pages)
+	
+	assert.ElementsMatch(t, []string{"http://example.com/PHOTO.jpg", "http://example.com/OLDER.jpg"}, queued)
+}
+
+// controlTUI is a TUI that shows nothing and passes on the controls sent to
+// it, one at a time
+type controlTUI struct {
+	controls chan ui.Control
+}
+
+func (c *controlTUI) StartDownload(id, username, filename string, size int64)           {}
+func (c *controlTUI) UpdateDownloadProgress(id string, downloaded int64, speed float64) {}
+func (c *controlTUI) CompleteDownload(id string)                                        {}
+func (c *controlTUI) FailDownload(id string, err error)                                 {}
+func (c *controlTUI) UpdateRateLimit(used, max int, resetAt time.Time)                  {}
+func (c *controlTUI) UpdateWorkers(workers, max int)                                    {}
+func (c *controlTUI) UpdateProfile(username string, page, total int)                    {}
+func (c *controlTUI) FinishProfile(username string, err error)                          {}
+func (c *controlTUI) LogInfo(format string, args ...interface{})                        {}
+func (c *controlTUI) LogSuccess(format string, args ...interface{})                     {}
+func (c *controlTUI) LogWarning(format string, args ...interface{})                     {}
+func (c *controlTUI) LogError(format string, args ...interface{})                       {}
+func (c *controlTUI) IsPaused() bool                                                    { return false }
+func (c *controlTUI) Controls() <-chan ui.Control                                       { return c.controls }
+
+func TestAbortFromTUI(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	
+	cfg := config.DefaultConfig()
+	cfg.Output.BaseDirectory = t.TempDir()
+	cfg.Notifications.Enabled = false
+	cfg.Retry.Enabled = false
+	s, err := New(cfg)
+	require.NoError(t, err)
+	terminal := &controlTUI{controls: make(chan ui.Control)}
+	s.SetTUI(terminal)
+	
+	var pages int32
+	s.SetClient(newMockClient(t, func(url string, target interface{}) error {
+		resp := target.(*instagram.InstagramResponse)
+		resp.Status = "ok"
+		resp.Data.User.ID = "42"
+		timeline := &resp.Data.User.EdgeOwnerToTimelineMedia
+		if !strings.Contains(url, "graphql") {
+			timeline.Count = 2
+			return nil
+		}
+		atomic.AddInt32(&pages, 1)
+		if strings.Contains(url, "page2") {
+			// The abort has been applied once the next control is taken
+			terminal.controls <- ui.Control{Action: ui.ControlAbort}
+			terminal.controls <- ui.Control{Action: ui.ControlResume}
+			timeline.Edges = []instagram.Edge{{Node: instagram.Node{Shortcode: "SECOND", DisplayURL: "http://example.com/SECOND.jpg"}}}
+			return nil
+		}
+		timeline.Edges = []instagram.Edge{{Node: instagram.Node{Shortcode: "FIRST", DisplayURL: "http://example.com/FIRST.jpg"}}}
+		timeline.PageInfo = instagram.PageInfo{HasNextPage: true, EndCursor: "page2"}
+		return nil
+	}, func(url string) ([]byte, error) {
+		return []byte("photo"), nil
+	}))
+	
+	err = s.DownloadUserPhotosWithResume("abort_user", false, true)
+	require.ErrorIs(t, err, ErrAborted)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&pages))
+	
+	// Posts queued before the abort finish; the aborted page is left for --resume
+	assert.FileExists(t, filepath.Join(s.getOutputDir("abort_user"), "FIRST.jpg"))
+	assert.NoFileExists(t, filepath.Join(s.getOutputDir("abort_user"), "SECOND.jpg"))
+	checkpointMgr, err := checkpoint.NewManager("abort_user")
+	require.NoError(t, err)
+	cp, err := checkpointMgr.Load()
+	require.NoError(t, err)
+	require.NotNil(t, cp)
+	assert.Equal(t, 1, cp.LastProcessedPage)
+}
+
+func TestInterrupt(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	
+	cfg := config.DefaultConfig()
+	cfg.Output.BaseDirectory = t.TempDir()
+	cfg.Notifications.Enabled = false
+	cfg.Retry.Enabled = false
+	cfg.Download.ShutdownTimeout = 20 * time.Millisecond
+	s, err := New(cfg)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.SetContext(ctx)
+	
+	slowStarted := make(chan struct{})
+	s.SetClient(newMockClient(t, func(url string, target interface{}) error {
+		resp := target.(*instagram.InstagramResponse)
+		resp.Status = "ok"
+		resp.Data.User.ID = "42"
+		timeline := &resp.Data.User.EdgeOwnerToTimelineMedia
+		if !strings.Contains(url, "graphql") {
+			timeline.Count = 3
+			return nil
+		}
+		if strings.Contains(url, "page2") {
+			// Interrupted once the first photo is saved and the second is downloading
+			<-slowStarted
+			first := filepath.Join(s.getOutputDir("interrupt_user"), "FIRST.jpg")
+			for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+				if _, err := os.Stat(first); err == nil {
+					break
+				}
+			}
+			cancel()
+			timeline.Edges = []instagram.Edge{{Node: instagram.Node{Shortcode: "THIRD", DisplayURL: "http://example.com/THIRD.jpg"}}}
+			return nil
+		}
+		timeline.Edges = []instagram.Edge{
+			{Node: instagram.Node{Shortcode: "FIRST", DisplayURL: "http://example.com/FIRST.jpg"}},
+			{Node: instagram.Node{Shortcode: "SLOW", DisplayURL: "http://example.com/SLOW.jpg"}},
+		}
+		timeline.PageInfo = instagram.PageInfo{HasNextPage: true, EndCursor: "page2"}
+		return nil
+	}, func(url string) ([]byte, error) {
+		if strings.Contains(url, "SLOW") {
+			close(slowStarted)
+			time.Sleep(300 * time.Millisecond)
+		}
+		return []byte("photo"), nil
+	}))
+	
+	err = s.DownloadUserPhotosWithResume("interrupt_user", false, true)
+	require.ErrorIs(t, err, ErrInterrupted)
+	assert.ErrorIs(t, err, context.Canceled)
+	
+	// The download outlasting the shutdown timeout is stopped and left for --resume
+	outputDir := s.getOutputDir("interrupt_user")
+	assert.FileExists(t, filepath.Join(outputDir, "FIRST.jpg"))
+	assert.NoFileExists(t, filepath.Join(outputDir, "SLOW.jpg"))
+	assert.NoFileExists(t, filepath.Join(outputDir, "THIRD.jpg"))
+	assert.FileExists(t, filepath.Join(outputDir, "metadata.json"))
+	
+	checkpointMgr, err := checkpoint.NewManager("interrupt_user")
+	require.NoError(t, err)
+	cp, err := checkpointMgr.Load()
+	require.NoError(t, err)
+	require.NotNil(t, cp)
+	assert.Equal(t, 1, cp.LastProcessedPage)
+	assert.True(t, cp.IsPhotoDownloaded("FIRST"))
+	assert.False(t, cp.IsPhotoDownloaded("SLOW"))
+	
+	summary := s.Summary()
+	assert.True(t, summary.Interrupted())
+	assert.Equal(t, "interrupt_user", summary.Target)
+	assert.Equal(t, 1, summary.Downloaded)
+	assert.Equal(t, 1, summary.Cancelled)
+	assert.Equal(t, 0, summary.FailedCount())
+	assert.Equal(t, int64(len("photo")), summary.Bytes)
+	
+	// The report says how far it got
+	data, err := os.ReadFile(filepath.Join(outputDir, ReportFile))
+	require.NoError(t, err)
+	var report Report
+	require.NoError(t, json.Unmarshal(data, &report))
+	assert.Equal(t, StatusInterrupted, report.Status)
+	assert.Equal(t, s.ScrapeID(), report.ScrapeID)
+	assert.Equal(t, 2, report.Pages)
+}
+
+// keepAliveClient is a mock client whose session can be checked
+type keepAliveClient struct {
+	*mocks.InstagramClient
+	keepAlive func() error
+}
+
+func (c *keepAliveClient) KeepAlive() error {
+	return c.keepAlive()
+}
+
+func TestSessionKeepAlive(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	
+	cfg := config.DefaultConfig()
+	cfg.Output.BaseDirectory = t.TempDir()
+	cfg.Notifications.Enabled = false
+	cfg.Retry.Enabled = false
+	cfg.Instagram.SessionKeepAlive = 5 * time.Millisecond
+	s, err := New(cfg)
+	require.NoError(t, err)
+	
+	var pings atomic.Int32
+	s.SetClient(&keepAliveClient{
+		InstagramClient: newMockClient(t, func(url string, target interface{}) error {
+			resp := target.(*instagram.InstagramResponse)
+			resp.Status = "ok"
+			resp.Data.User.ID = "42"
+			timeline := &resp.Data.User.EdgeOwnerToTimelineMedia
+			if !strings.Contains(url, "graphql") {
+				timeline.Count = 3
+				return nil
+			}
+			if strings.Contains(url, "page2") {
+				// The session is lost while the second page loads
+				select {
+				case <-s.runContext().Done():
+				case <-time.After(5 * time.Second):
+				}
+				timeline.Edges = []instagram.Edge{{Node: instagram.Node{Shortcode: "SECOND", DisplayURL: "http://example.com/SECOND.jpg"}}}
+				timeline.PageInfo = instagram.PageInfo{HasNextPage: true, EndCursor: "page3"}
+				return nil
+			}
+			timeline.Edges = []instagram.Edge{{Node: instagram.Node{Shortcode: "FIRST", DisplayURL: "http://example.com/FIRST.jpg"}}}
+			timeline.PageInfo = instagram.PageInfo{HasNextPage: true, EndCursor: "page2"}
+			return nil
+		}, func(url string) ([]byte, error) {
+			return []byte("photo"), nil
+		}),
+		keepAlive: func() error {
+			switch pings.Add(1) {
+			case 1:
+				return nil
+			case 2:
+				// Failures that say nothing about the session are ignored
+				return &errors.Error{Type: errors.ErrorTypeServerError, Code: http.StatusServiceUnavailable}
+			default:
+				return &errors.Error{Type: errors.ErrorTypeAuth, Message: "Instagram asks to log in again", Code: http.StatusUnauthorized}
+			}
+		},
+	})
+	
+	err = s.DownloadUserPhotosWithResume("keepalive_user", false, true)
+	require.ErrorIs(t, err, ErrSessionLost)
+	assert.NotErrorIs(t, err, ErrInterrupted)
+	assert.Equal(t, int32(3), pings.Load())
+	
+	// The page being fetched is finished and the checkpoint kept for --resume
+	outputDir := s.getOutputDir("keepalive_user")
+	assert.FileExists(t, filepath.Join(outputDir, "FIRST.jpg"))
+	assert.FileExists(t, filepath.Join(outputDir, "SECOND.jpg"))
+	checkpointMgr, err := checkpoint.NewManager("keepalive_user")
+	require.NoError(t, err)
+	cp, err := checkpointMgr.Load()
+	require.NoError(t, err)
+	require.NotNil(t, cp)
+	assert.Equal(t, 2, cp.LastProcessedPage)
+	assert.True(t, cp.IsPhotoDownloaded("SECOND"))
+	assert.Equal(t, StatusPaused, s.Summary().Status)
+}
+
+func TestScrapeReport(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	
+	cfg := config.DefaultConfig()
+	cfg.Output.BaseDirectory = t.TempDir()
+	cfg.Notifications.Enabled = false
+	cfg.Retry.Enabled = false
+	cfg.Download.RetryAttempts = 0
+	cfg.Download.SkipVideos = true
+	s, err := New(cfg)
+	require.NoError(t, err)
+	
+	video := instagram.Edge{Node: instagram.Node{Shortcode: "VIDEO", IsVideo: true, VideoURL: "http://example.com/VIDEO.mp4"}}
+	s.SetClient(newMockClient(t, func(url string, target interface{}) error {
+		resp := target.(*instagram.InstagramResponse)
+		resp.Status = "ok"
+		resp.Data.User.ID = "42"
+		timeline := &resp.Data.User.EdgeOwnerToTimelineMedia
+		if !strings.Contains(url, "graphql") {
+			timeline.Count = 3
+			return nil
+		}
+		timeline.Edges = []instagram.Edge{
+			{Node: instagram.Node{Shortcode: "PHOTO", DisplayURL: "http://example.com/PHOTO.jpg"}},
+			{Node: instagram.Node{Shortcode: "GONE", DisplayURL: "http://example.com/GONE.jpg"}},
+			video,
+		}
+		return nil
+	}, func(url string) ([]byte, error) {
+		if strings.Contains(url, "GONE") {
+			return nil, &errors.Error{Type: errors.ErrorTypeNotFound, Code: http.StatusNotFound}
+		}
+		return []byte("photo"), nil
+	}))
+	
+	require.NoError(t, s.DownloadUserPhotosWithResume("report_user", false, true))
+	
+	outputDir := s.getOutputDir("report_user")
+	data, err := os.ReadFile(filepath.Join(outputDir, ReportFile))
+	require.NoError(t, err)
+	var report Report
+	require.NoError(t, json.Unmarshal(data, &report))
+	assert.Equal(t, StatusComplete, report.Status)
+	assert.Equal(t, "report_user", report.Target)
+	assert.Empty(t, report.Error)
+	assert.Equal(t, 1, report.Pages)
+	assert.Equal(t, 1, report.Downloaded)
+	assert.Equal(t, int64(len("photo")), report.Bytes)
+	assert.Equal(t, 1, report.Skipped)
+	assert.Equal(t, map[string]int{skipVideos: 1}, report.SkippedReasons)
+	assert.Equal(t, 1, report.Failed)
+	assert.Equal(t, map[string]int{"not_found": 1}, report.Failures)
+	assert.Equal(t, 0, report.RateLimitPauses)
+	assert.False(t, report.FinishedAt.Before(report.StartedAt))
+	
+	text, err := os.ReadFile(filepath.Join(outputDir, ReportTextFile))
+	require.NoError(t, err)
+	assert.Equal(t, s.Summary().Table(), string(text))
+	assert.Contains(t, string(text), "report_user: complete")
+	assert.Contains(t, string(text), "1 (1 not_found)")
+}
+
+func TestSummaryTable(t *testing.T) {
+	summary := Summary{
+		Target:          "johndoe",
+		Status:          StatusInterrupted,
+		Error:           ErrInterrupted.Error(),
+		StartedAt:       time.Date(2026, 10, 15, 14, 2, 11, 0, time.UTC),
+		Elapsed:         6*time.Minute + 32*time.Second,
+		Pages:           12,
+		Downloaded:      214,
+		Bytes:           412 << 20,
+		Skipped:         map[string]int{skipVideos: 10, skipDateRange: 2},
+		Failed:          map[string]int{"not_found": 2, "network": 1},
+		Cancelled:       3,
+		RateLimitPauses: 2,
+		RateLimitWait:   90 * time.Second,
+	}
+	want := `johndoe: interrupted
+  Stopped by   scrape interrupted: context canceled
+  Started      2026-10-15 14:02:11
+  Duration     6m32s
+  Pages        12
+  Downloaded   214 (412.0 MB, 1.1 MB/s)
+  Skipped      12 (10 videos, 2 outside date range)
+  Failed       3 (2 not_found, 1 network)
+  Cancelled    3
+  Rate limits  2 pauses, 1m30s
+`
+	assert.Equal(t, want, summary.Table())
+}
+
+func TestResumeRestoresProgress(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	
+	cfg := config.DefaultConfig()
+	cfg.Output.BaseDirectory = t.TempDir()
+	cfg.Notifications.Enabled = false
+	cfg.Retry.Enabled = false
+	s, err := New(cfg)
+	require.NoError(t, err)
+	
+	checkpointMgr, err := checkpoint.NewManager("resume_user")
+	require.NoError(t, err)
+	require.NoError(t, checkpointMgr.Save(&checkpoint.Checkpoint{
+		Username:          "resume_user",
+		UserID:            "42",
+		EndCursor:         "page2",
+		LastProcessedPage: 1,
+		DownloadedPhotos:  map[string]string{"FIRST": "FIRST.jpg"},
+		TotalQueued:       1,
+		TotalDownloaded:   1,
+		TotalPhotos:       3,
+		BytesDownloaded:   1000,
+		Pages:             []checkpoint.PageStats{{Page: 1, Posts: 1, Queued: 1}},
+	}))
+	
+	s.SetClient(newMockClient(t, func(url string, target interface{}) error {
+		resp := target.(*instagram.InstagramResponse)
+		resp.Status = "ok"
+		resp.Data.User.ID = "42"
+		timeline := &resp.Data.User.EdgeOwnerToTimelineMedia
+		switch {
+		case !strings.Contains(url, "graphql"):
+			timeline.Count = 3
+		case strings.Contains(url, "page3"):
+			return &errors.Error{Type: errors.ErrorTypeNotFound, Code: http.StatusNotFound}
+		case strings.Contains(url, "page2"):
+			timeline.Edges = []instagram.Edge{
+				{Node: instagram.Node{Shortcode: "FIRST", DisplayURL: "http://example.com/FIRST.jpg"}},
+				{Node: instagram.Node{Shortcode: "SECOND", DisplayURL: "http://example.com/SECOND.jpg"}},
+			}
+			timeline.PageInfo = instagram.PageInfo{HasNextPage: true, EndCursor: "page3"}
+		default:
+			t.Errorf("unexpected request for %s", url)
+		}
+		return nil
+	}, func(url string) ([]byte, error) {
+		return make([]byte, 500), nil
+	}))
+	
+	var pageErr *PageError
+	require.ErrorAs(t, s.DownloadUserPhotosWithResume("resume_user", true, false), &pageErr)
+	
+	cp, err := checkpointMgr.Load()
+	require.NoError(t, err)
+	require.NotNil(t, cp)
+	assert.Equal(t, 3, cp.TotalPhotos)
+	assert.Equal(t, 2, cp.TotalDownloaded)
+	assert.Equal(t, int64(1500), cp.BytesDownloaded)
+	assert.Contains(t, cp.DownloadedPhotos, "SECOND")
+	
+	// The checkpoint resumes from the start of the second page again, so
+	// only the first page's stats are kept
+	assert.Equal(t, []checkpoint.PageStats{{Page: 1, Posts: 1, Queued: 1}}, cp.Pages)
+	assert.Equal(t, "page2", cp.EndCursor)
+}
+
+func TestDownloadQuota(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	
+	cfg := config.DefaultConfig()
+	cfg.Output.BaseDirectory = t.TempDir()
+	cfg.Notifications.Enabled = false
+	cfg.Retry.Enabled = false
+	cfg.Download.MaxTotalSize = 1024
+	s, err := New(cfg)
+	require.NoError(t, err)
+	
+	// An earlier archive already fills the quota
+	require.NoError(t, os.MkdirAll(filepath.Join(cfg.Output.BaseDirectory, "other_photos"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(cfg.Output.BaseDirectory, "other_photos", "ABC.jpg"), make([]byte, 2048), 0644))
+	
+	var pages int
+	s.SetClient(newMockClient(t, func(url string, target interface{}) error {
+		resp := target.(*instagram.InstagramResponse)
+		resp.Status = "ok"
+		resp.Data.User.ID = "42"
+		if strings.Contains(url, "graphql") {
+			pages++
+		}
+		return nil
+	}, nil))
+	
+	err = s.DownloadUserPhotosWithResume("quota_user", false, true)
+	var spaceErr *storage.SpaceError
+	require.ErrorAs(t, err, &spaceErr)
+	assert.Contains(t, err.Error(), "download quota reached")
+	assert.Zero(t, pages, "no page is fetched once the quota is reached")
+	
+	// The checkpoint is kept for --resume
+	checkpointMgr, err := checkpoint.NewManager("quota_user")
+	require.NoError(t, err)
+	assert.True(t, checkpointMgr.Exists())
+}
+
+func TestSaveComments(t *testing.T) {
+	comment := func(id int) string {
+		return fmt.Sprintf(`{"pk":%d,"text":"comment %d","created_at":1700000000,"comment_like_count":%d,
+			"child_comment_count":1,"user":{"pk":7,"username":"fan"}}`, id, id, id)
+	}
+	pages := map[string]string{
+		"": fmt.Sprintf(`{"comments":[%s,%s],"comment_count":5,"has_more_comments":true,"next_min_id":"cursor2","status":"ok"}`,
+			comment(1), comment(2)),
+		"cursor2": fmt.Sprintf(`{"comments":[%s,%s],"comment_count":5,"has_more_comments":true,"next_min_id":"cursor3","status":"ok"}`,
+			comment(3), comment(4)),
+		"cursor3": fmt.Sprintf(`{"comments":[%s],"comment_count":5,"has_more_comments":false,"status":"ok"}`,
+			comment(5)),
+	}
+	
+	var requested []string
+	newScraper := func(t *testing.T, maxComments int) *Scraper {
+		requested = nil
+		cfg := config.DefaultConfig()
+		cfg.Download.MaxComments = maxComments
+		s, err := New(cfg)
+		require.NoError(t, err)
+		s.SetClient(newMockClient(t, func(url string, target interface{}) error {
+			requested = append(requested, url)
+			parsed, err := neturl.Parse(url)
+			require.NoError(t, err)
+			mediaID, err := instagram.MediaID("ABC")
+			require.NoError(t, err)
+			require.Equal(t, "/api/v1/media/"+mediaID+"/comments/", parsed.Path)
+			return json.Unmarshal([]byte(pages[parsed.Query().Get("min_id")]), target)
+		}, nil))
+		return s
+	}
+	
+	t.Run("every page", func(t *testing.T) {
+		dir := t.TempDir()
+		s := newScraper(t, 100)
+		s.saveComments(dir, "ABC")
+		assert.Len(t, requested, 3)
+		
+		saved, err := metadata.LoadComments(dir, "ABC")
+		require.NoError(t, err)
+		require.NotNil(t, saved)
+		assert.Equal(t, 5, saved.Total)
+		assert.False(t, saved.Truncated)
+		require.Len(t, saved.Comments, 5)
+		assert.Equal(t, metadata.Comment{
+			ID:        "1",
+			Username:  "fan",
+			UserID:    "7",
+			Text:      "comment 1",
+			CreatedAt: time.Unix(1700000000, 0).UTC(),
+			Likes:     1,
+			Replies:   1,
+		}, saved.Comments[0])
+		
+		// Comments already saved are not fetched again
+		s.saveComments(dir, "ABC")
+		assert.Len(t, requested, 3)
+	})
+	
+	t.Run("stops at max comments", func(t *testing.T) {
+		dir := t.TempDir()
+		s := newScraper(t, 3)
+		s.saveComments(dir, "ABC")
+		assert.Len(t, requested, 2, "the third page is never requested")
+		
+		saved, err := metadata.LoadComments(dir, "ABC")
+		require.NoError(t, err)
+		require.NotNil(t, saved)
+		assert.True(t, saved.Truncated)
+		assert.Len(t, saved.Comments, 3)
+		assert.Equal(t, 5, saved.Total)
+	})
+	
+	t.Run("failures are not saved", func(t *testing.T) {
+		dir := t.TempDir()
+		s := newScraper(t, 100)
+		s.SetClient(newMockClient(t, func(url string, target interface{}) error {
+			return &errors.Error{Type: errors.ErrorTypeRateLimit, Code: http.StatusTooManyRequests}
+		}, nil))
+		s.saveComments(dir, "ABC")
+		
+		saved, err := metadata.LoadComments(dir, "ABC")
+		require.NoError(t, err)
+		assert.Nil(t, saved)
+	})
+}
+
+func TestUsernameChange(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	
+	// Profile 42 was archived as old_name and is now called new_name
+	ids := map[string]string{"old_name": "42"}
+	var downloads int32
+	client := newMockClient(t, func(url string, target interface{}) error {
+		parsed, err := neturl.Parse(url)
+		require.NoError(t, err)
+		resp := target.(*instagram.InstagramResponse)
+		if parsed.Path == instagram.ProfileEndpoint {
+			id, ok := ids[parsed.Query().Get("username")]
+			if !ok {
+				return &errors.Error{Type: errors.ErrorTypeNotFound, Code: http.StatusNotFound}
+			}
+			resp.Status = "ok"
+			resp.Data.User.ID = id
+			resp.Data.User.EdgeOwnerToTimelineMedia.Count = 1
+			return nil
+		}
+		resp.Status = "ok"
+		node := instagram.Node{Shortcode: "POST", DisplayURL: "http://example.com/post.jpg"}
+		resp.Data.User.EdgeOwnerToTimelineMedia.Edges = []instagram.Edge{{Node: node}}
+		return nil
+	}, func(url string) ([]byte, error) {
+		atomic.AddInt32(&downloads, 1)
+		return []byte("photo"), nil
+	})
+	
+	cfg := config.DefaultConfig()
+	cfg.Output.BaseDirectory = t.TempDir()
+	cfg.Notifications.Enabled = false
+	s, err := New(cfg)
+	require.NoError(t, err)
+	s.SetClient(client)
+	
+	oldDir := s.getOutputDir("old_name")
+	require.NoError(t, s.DownloadUserPhotosWithResume("old_name", false, true))
+	require.FileExists(t, filepath.Join(oldDir, "POST.jpg"))
+	
+	oldCheckpoint, err := checkpoint.NewManager("old_name")
+	require.NoError(t, err)
+	require.NoError(t, oldCheckpoint.Save(&checkpoint.Checkpoint{Username: "old_name", UserID: "42", EndCursor: "page2"}))
+	
+	delete(ids, "old_name")
+	ids["new_name"] = "42"
+	
+	// The folder and checkpoint move to the new username
+	f := s.profileFeed("new_name", false)
+	require.NoError(t, f.resolve())
+	newDir := filepath.Join(cfg.Output.BaseDirectory, "new_name_photos")
+	assert.Equal(t, newDir, f.outputDir)
+	assert.NoDirExists(t, oldDir)
+	assert.FileExists(t, filepath.Join(newDir, "POST.jpg"))
+	assert.False(t, oldCheckpoint.Exists())
+	newCheckpoint, err := checkpoint.NewManager("new_name")
+	require.NoError(t, err)
+	cp, err := newCheckpoint.Load()
+	require.NoError(t, err)
+	require.NotNil(t, cp)
+	assert.Equal(t, "new_name", cp.Username)
+	assert.Equal(t, "page2", cp.EndCursor)
+	require.NoError(t, newCheckpoint.Delete())
+	
+	// Nothing is downloaded again under the new username
+	require.NoError(t, s.DownloadUserPhotosWithResume("new_name", false, true))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&downloads))
+	assert.Equal(t, newDir, s.getOutputDir("new_name"))
+	
+	// The old username, no longer in use, leads to the new one
+	f = s.profileFeed("old_name", false)
+	require.NoError(t, f.resolve())
+	assert.Equal(t, "new_name", f.name)
+	assert.Equal(t, newDir, f.outputDir)
+	
+	// A new account with the old username gets a folder of its own
+	ids["old_name"] = "43"
+	f = s.profileFeed("old_name", false)
+	require.NoError(t, f.resolve())
+	assert.Equal(t, "old_name", f.name)
+	assert.Equal(t, oldDir, f.outputDir)
+	
+	// With rename_folders off the old folder is kept
+	cfg.Output.RenameFolders = false
+	delete(ids, "new_name")
+	ids["newest_name"] = "42"
+	f = s.profileFeed("newest_name", false)
+	require.NoError(t, f.resolve())
+	assert.Equal(t, newDir, f.outputDir)
+	assert.Equal(t, newDir, s.getOutputDir("newest_name"))
+}
+
+func TestUsernameChangeLinks(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	ids := map[string]string{"old_name": "42"}
+	client := newMockClient(t, func(url string, target interface{}) error {
+		parsed, err := neturl.Parse(url)
+		require.NoError(t, err)
+		resp := target.(*instagram.InstagramResponse)
+		if parsed.Path == instagram.ProfileEndpoint {
+			id, ok := ids[parsed.Query().Get("username")]
+			if !ok {
+				return &errors.Error{Type: errors.ErrorTypeNotFound, Code: http.StatusNotFound}
+			}
+			resp.Status = "ok"
+			resp.Data.User.ID = id
+			resp.Data.User.EdgeOwnerToTimelineMedia.Count = 1
+			return nil
+		}
+		resp.Status = "ok"
+		node := instagram.Node{Shortcode: "POST", DisplayURL: "http://example.com/post.jpg"}
+		resp.Data.User.EdgeOwnerToTimelineMedia.Edges = []instagram.Edge{{Node: node}}
+		return nil
+	}, func(url string) ([]byte, error) {
+		return []byte("photo"), nil
+	})
+
+	cfg := config.DefaultConfig()
+	cfg.Output.BaseDirectory = t.TempDir()
+	cfg.Output.LinkFolders = true
+	cfg.Notifications.Enabled = false
+	s, err := New(cfg)
+	require.NoError(t, err)
+	s.SetClient(client)
+
+	oldDir := s.getOutputDir("old_name")
+	require.NoError(t, s.DownloadUserPhotosWithResume("old_name", false, true))
+
+	// The renamed folder stays reachable under the old username
+	delete(ids, "old_name")
+	ids["new_name"] = "42"
+	f := s.profileFeed("new_name", false)
+	require.NoError(t, f.resolve())
+	newDir := filepath.Join(cfg.Output.BaseDirectory, "new_name_photos")
+	assert.Equal(t, newDir, f.outputDir)
+	dest, err := os.Readlink(oldDir)
+	require.NoError(t, err)
+	assert.Equal(t, "new_name_photos", dest)
+	assert.FileExists(t, filepath.Join(oldDir, "POST.jpg"))
+
+	// A new account with the old username does not write through the link
+	ids["old_name"] = "43"
+	f = s.profileFeed("old_name", false)
+	require.NoError(t, f.resolve())
+	assert.Equal(t, filepath.Join(cfg.Output.BaseDirectory, "old_name_43_photos"), f.outputDir)
+
+	// With rename_folders off the new username links to the kept folder
+	cfg.Output.RenameFolders = false
+	delete(ids, "new_name")
+	ids["newest_name"] = "42"
+	f = s.profileFeed("newest_name", false)
+	require.NoError(t, f.resolve())
+	assert.Equal(t, newDir, f.outputDir)
+	dest, err = os.Readlink(filepath.Join(cfg.Output.BaseDirectory, "newest_name_photos"))
+	require.NoError(t, err)
+	assert.Equal(t, "new_name_photos", dest)
+}
+
+func TestDownloadUserID(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	
+	var profileRequests int32
+	client := newMockClient(t, func(url string, target interface{}) error {
+		parsed, err := neturl.Parse(url)
+		require.NoError(t, err)
+		resp := target.(*instagram.InstagramResponse)
+		resp.Status = "ok"
+		resp.Data.User.ID = "42"
+		if parsed.Path == instagram.ProfileEndpoint {
+			atomic.AddInt32(&profileRequests, 1)
+			return nil
+		}
+		require.Contains(t, parsed.Query().Get("variables"), `"id":"42"`)
+		node := instagram.Node{Shortcode: "POST", DisplayURL: "http://example.com/post.jpg"}
+		resp.Data.User.EdgeOwnerToTimelineMedia.Edges = []instagram.Edge{{Node: node}}
+		return nil
+	}, func(url string) ([]byte, error) {
+		return []byte("photo"), nil
+	})
+	
+	cfg := config.DefaultConfig()
+	cfg.Output.BaseDirectory = t.TempDir()
+	cfg.Notifications.Enabled = false
+	s, err := New(cfg)
+	require.NoError(t, err)
+	s.SetClient(client)
+	
+	assert.Error(t, s.DownloadUserID("someone", false, true))
+	
+	// An unknown profile is archived under its user ID, without a profile request
+	require.NoError(t, s.DownloadUserID("42", false, true))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&profileRequests))
+	assert.FileExists(t, filepath.Join(cfg.Output.BaseDirectory, "42_photos", "POST.jpg"))
+	
+	// Scraping it by username later takes the archive over
+	require.NoError(t, s.DownloadUserPhotosWithResume("someone", false, true))
+	someoneDir := filepath.Join(cfg.Output.BaseDirectory, "someone_photos")
+	assert.FileExists(t, filepath.Join(someoneDir, "POST.jpg"))
+	assert.NoDirExists(t, filepath.Join(cfg.Output.BaseDirectory, "42_photos"))
+	
+	// From then on the user ID leads to the username's folder
+	f := s.userIDFeed("42")
+	assert.Equal(t, "someone", f.name)
+	assert.Equal(t, someoneDir, f.outputDir)
+}
+
+func TestMetadataAcrossRuns(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	// The second run lists a new post above the one saved by the first
+	listed := []string{"FIRST"}
+	var downloads int32
+	client := newMockClient(t, func(url string, target interface{}) error {
+		parsed, err := neturl.Parse(url)
+		require.NoError(t, err)
+		resp := target.(*instagram.InstagramResponse)
+		resp.Status = "ok"
+		if parsed.Path == instagram.ProfileEndpoint {
+			resp.Data.User.ID = "42"
+			resp.Data.User.EdgeOwnerToTimelineMedia.Count = len(listed)
+			return nil
+		}
+		for _, shortcode := range listed {
+			node := instagram.Node{Shortcode: shortcode, DisplayURL: "http://example.com/" + shortcode + ".jpg"}
+			node.EdgeMediaToCaption.Edges = []instagram.CaptionEdge{{Node: instagram.CaptionNode{Text: "post " + shortcode}}}
+			resp.Data.User.EdgeOwnerToTimelineMedia.Edges = append(resp.Data.User.EdgeOwnerToTimelineMedia.Edges, instagram.Edge{Node: node})
+		}
+		return nil
+	}, func(url string) ([]byte, error) {
+		atomic.AddInt32(&downloads, 1)
+		return []byte(url), nil
+	})
+
+	cfg := config.DefaultConfig()
+	cfg.Output.BaseDirectory = t.TempDir()
+	cfg.Notifications.Enabled = false
+	s, err := New(cfg)
+	require.NoError(t, err)
+	s.SetClient(client)
+	dir := s.getOutputDir("someone")
+
+	require.NoError(t, s.DownloadUserPhotosWithResume("someone", false, true))
+	listed = []string{"SECOND", "FIRST"}
+	require.NoError(t, s.DownloadUserPhotosWithResume("someone", false, true))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&downloads), "the first post is not downloaded again")
+
+	// metadata.json covers both runs, each post once
+	meta, err := metadata.LoadUserMetadata(dir)
+	require.NoError(t, err)
+	require.NotNil(t, meta)
+	var shortcodes []string
+	for _, photo := range meta.Photos {
+		shortcodes = append(shortcodes, photo.Shortcode)
+	}
+	assert.ElementsMatch(t, []string{"FIRST", "SECOND"}, shortcodes)
+	assert.Equal(t, 2, meta.DownloadedPhotos)
+}
+
+func TestExistingArchive(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	var downloads int32
+	client := newMockClient(t, func(url string, target interface{}) error {
+		parsed, err := neturl.Parse(url)
+		require.NoError(t, err)
+		resp := target.(*instagram.InstagramResponse)
+		resp.Status = "ok"
+		if parsed.Path == instagram.ProfileEndpoint {
+			resp.Data.User.ID = "42"
+			resp.Data.User.EdgeOwnerToTimelineMedia.Count = 1
+			return nil
+		}
+		node := instagram.Node{Shortcode: "POST", DisplayURL: "http://example.com/post.jpg"}
+		resp.Data.User.EdgeOwnerToTimelineMedia.Edges = []instagram.Edge{{Node: node}}
+		return nil
+	}, func(url string) ([]byte, error) {
+		atomic.AddInt32(&downloads, 1)
+		return []byte("photo"), nil
+	})
+	newScraper := func(base, existing string) *Scraper {
+		cfg := config.DefaultConfig()
+		cfg.Output.BaseDirectory = base
+		cfg.Output.ExistingArchive = existing
+		cfg.Notifications.Enabled = false
+		s, err := New(cfg)
+		require.NoError(t, err)
+		s.SetClient(client)
+		return s
+	}
+
+	first := t.TempDir()
+	firstDir := filepath.Join(first, "someone_photos")
+	require.NoError(t, newScraper(first, "warn").DownloadUserPhotosWithResume("someone", false, true))
+	require.FileExists(t, filepath.Join(firstDir, "POST.jpg"))
+	require.Equal(t, int32(1), atomic.LoadInt32(&downloads))
+
+	// By default a second output directory is only warned about
+	second := t.TempDir()
+	f := newScraper(second, "warn").profileFeed("someone", false)
+	require.NoError(t, f.resolve())
+	assert.Equal(t, filepath.Join(second, "someone_photos"), f.outputDir)
+
+	// With existing_archive: use the scrape continues the first archive,
+	// the second folder having never been created, and downloads nothing
+	third := t.TempDir()
+	s := newScraper(third, "use")
+	f = s.profileFeed("someone", false)
+	require.NoError(t, f.resolve())
+	assert.Equal(t, firstDir, f.outputDir)
+	require.NoError(t, s.DownloadUserPhotosWithResume("someone", false, true))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&downloads))
+	assert.NoDirExists(t, filepath.Join(third, "someone_photos"))
+
+	// A folder that already holds an archive is kept
+	fourth := t.TempDir()
+	fourthDir := filepath.Join(fourth, "someone_photos")
+	require.NoError(t, os.MkdirAll(fourthDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(fourthDir, "metadata.json"), []byte(`{"username":"someone","user_id":"42"}`), 0644))
+	f = newScraper(fourth, "use").profileFeed("someone", false)
+	require.NoError(t, f.resolve())
+	assert.Equal(t, fourthDir, f.outputDir)
+}
+
+func TestRateLimitStatePath(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	path, err := rateLimitStatePath("12345678%3AabcDEF%3A26")
+	require.NoError(t, err)
+	assert.Equal(t, "12345678.json", filepath.Base(path))
+	assert.Equal(t, "ratelimit", filepath.Base(filepath.Dir(path)))
+
+	// Cookies without the account ID are hashed rather than written out
+	path, err = rateLimitStatePath("opaque-session")
+	require.NoError(t, err)
+	assert.NotContains(t, path, "opaque-session")
+	other, err := rateLimitStatePath("other-session")
+	require.NoError(t, err)
+	assert.NotEqual(t, path, other)
+}
+
+func TestRateLimitPersistsBetweenScrapers(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	cfg := config.DefaultConfig()
+	cfg.Instagram.SessionID = "555%3Asession"
+	cfg.RateLimit.RequestsPerMinute = 2
+
+	first, err := New(cfg)
+	require.NoError(t, err)
+	assert.True(t, first.rateLimiter.Allow())
+	assert.True(t, first.rateLimiter.Allow())
+
+	// A new run for the same account starts with the budget used up
+	second, err := New(cfg)
+	require.NoError(t, err)
+	assert.False(t, second.rateLimiter.Allow())
+
+	cfg.RateLimit.PersistState = false
+	third, err := New(cfg)
+	require.NoError(t, err)
+	assert.True(t, third.rateLimiter.Allow())
+}
+
+func TestPrefetchPages(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	cfg := config.DefaultConfig()
+	cfg.Output.BaseDirectory = t.TempDir()
+	cfg.Notifications.Enabled = false
+	cfg.Retry.Enabled = false
+	cfg.Download.ConcurrentDownloads = 1 // the queue holds two jobs
+	cfg.Download.PrefetchPages = 2
+	s, err := New(cfg)
+	require.NoError(t, err)
+
+	node := func(shortcode string) instagram.Edge {
+		return instagram.Edge{Node: instagram.Node{Shortcode: shortcode, DisplayURL: "http://example.com/" + shortcode + ".jpg"}}
+	}
+	var mu sync.Mutex
+	var fetched []string
+	secondPage := make(chan struct{})
+	var ahead atomic.Bool
+	s.SetClient(newMockClient(t, func(url string, target interface{}) error {
+		resp := target.(*instagram.InstagramResponse)
+		resp.Status = "ok"
+		resp.Data.User.ID = "42"
+		timeline := &resp.Data.User.EdgeOwnerToTimelineMedia
+		if !strings.Contains(url, "graphql") {
+			timeline.Count = 6
+			return nil
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case strings.Contains(url, "page3"):
+			fetched = append(fetched, "page3")
+			timeline.Edges = []instagram.Edge{node("F")}
+		case strings.Contains(url, "page2"):
+			fetched = append(fetched, "page2")
+			timeline.Edges = []instagram.Edge{node("E")}
+			timeline.PageInfo = instagram.PageInfo{HasNextPage: true, EndCursor: "page3"}
+			close(secondPage)
+		default:
+			fetched = append(fetched, "page1")
+			timeline.Edges = []instagram.Edge{node("A"), node("B"), node("C"), node("D")}
+			timeline.PageInfo = instagram.PageInfo{HasNextPage: true, EndCursor: "page2"}
+		}
+		return nil
+	}, func(url string) ([]byte, error) {
+		// The first page fills the queue, so its downloads only let
+		// the scrape move on once they are done
+		if strings.Contains(url, "A.jpg") {
+			select {
+			case <-secondPage:
+				ahead.Store(true)
+			case <-time.After(2 * time.Second):
+			}
+		}
+		return []byte("photo"), nil
+	}))
+
+	require.NoError(t, s.DownloadUserPhotosWithResume("prefetch_user", false, true))
+
+	// The second page was fetched while the first one downloaded, and each
+	// page once and in order
+	assert.True(t, ahead.Load())
+	assert.Equal(t, []string{"page1", "page2", "page3"}, fetched)
+	outputDir := s.getOutputDir("prefetch_user")
+	for _, shortcode := range []string{"A", "B", "C", "D", "E", "F"} {
+		assert.FileExists(t, filepath.Join(outputDir, shortcode+".jpg"))
+	}
+	assert.Equal(t, 3, s.Summary().Pages)
+	assert.Equal(t, 6, s.Summary().Downloaded)
+}
+
+func TestHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the hook commands are written for sh")
+	}
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	out := filepath.Join(t.TempDir(), "hooks.jsonl")
+	cfg := config.DefaultConfig()
+	cfg.Output.BaseDirectory = t.TempDir()
+	cfg.Notifications.Enabled = false
+	cfg.Hooks.PostDownload = `cat >> "` + out + `"`
+	cfg.Hooks.OnComplete = `cat >> "` + out + `"`
+	cfg.Hooks.OnError = `cat >> "` + out + `"`
+	s, err := New(cfg)
+	require.NoError(t, err)
+
+	failProfile := false
+	s.SetClient(newMockClient(t, func(url string, target interface{}) error {
+		if failProfile {
+			return fmt.Errorf("profile unavailable")
+		}
+		resp := target.(*instagram.InstagramResponse)
+		resp.Status = "ok"
+		resp.Data.User.ID = "42"
+		media := &resp.Data.User.EdgeOwnerToTimelineMedia
+		media.Count = 2
+		if strings.Contains(url, "graphql") {
+			for _, shortcode := range []string{"HOOK1", "HOOK2"} {
+				media.Edges = append(media.Edges, instagram.Edge{Node: instagram.Node{
+					Shortcode:  shortcode,
+					DisplayURL: "http://example.com/" + shortcode + ".jpg",
+				}})
+			}
+		}
+		return nil
+	}, func(url string) ([]byte, error) {
+		return []byte("photo " + url), nil
+	}))
+	require.NoError(t, s.DownloadUserPhotosWithResume("hooked", false, true))
+	failProfile = true
+	require.Error(t, s.DownloadUserPhotosWithResume("missing", false, true))
+
+	// Each scrape waits for its hooks, so they are all written by now
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	var contexts []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var context map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &context))
+		contexts = append(contexts, context)
+	}
+	require.Len(t, contexts, 4)
+
+	var saved []string
+	for _, context := range contexts[:2] {
+		assert.Equal(t, "post_download", context["hook"])
+		assert.Equal(t, "hooked", context["username"])
+		assert.Equal(t, "photo", context["media_type"])
+		path, _ := context["path"].(string)
+		assert.FileExists(t, path)
+		saved = append(saved, context["shortcode"].(string))
+	}
+	assert.ElementsMatch(t, []string{"HOOK1", "HOOK2"}, saved)
+
+	complete := contexts[2]
+	assert.Equal(t, "on_complete", complete["hook"])
+	assert.Equal(t, StatusComplete, complete["status"])
+	assert.EqualValues(t, 2, complete["downloaded"])
+	assert.Equal(t, s.OutputDir("hooked"), complete["output_dir"])
+
+	failed := contexts[3]
+	assert.Equal(t, "on_error", failed["hook"])
+	assert.Equal(t, "scrape", failed["scope"])
+	assert.Equal(t, "missing", failed["username"])
+	assert.Contains(t, failed["error"], "profile unavailable")
+	assert.NotContains(t, failed, "status", "no summary of the previous scrape")
+}
+
+// recordingSink records the events of a scrape
+type recordingSink struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recordingSink) record(format string, args ...interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, fmt.Sprintf(format, args...))
+}
+
+func (r *recordingSink) PageFetched(username string, page, total int) {
+	r.record("page %s %d/%d", username, page, total)
+}
+
+func (r *recordingSink) ItemQueued(item ui.Item) {
+	r.record("queued %s %s", item.Shortcode, item.File)
+}
+
+func (r *recordingSink) ItemSkipped(item ui.Item, reason string) {
+	r.record("skipped %s %s", item.Shortcode, reason)
+}
+
+func (r *recordingSink) ItemCompleted(item ui.Item, size int64) {
+	r.record("completed %s %s %d", item.Shortcode, item.File, size)
+}
+
+func (r *recordingSink) ItemFailed(item ui.Item, err error) {
+	r.record("failed %s %v", item.Shortcode, err)
+}
+
+func (r *recordingSink) RateLimited(wait time.Duration, requestsPerMinute int) {
+	r.record("rate limited %s", wait)
+}
+
+func TestProgressSink(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	cfg := config.DefaultConfig()
+	cfg.Output.BaseDirectory = t.TempDir()
+	cfg.Notifications.Enabled = false
+	s, err := New(cfg)
+	require.NoError(t, err)
+
+	sink := &recordingSink{}
+	s.SetProgressSink(sink)
+	s.SetClient(newMockClient(t, func(url string, target interface{}) error {
+		resp := target.(*instagram.InstagramResponse)
+		resp.Status = "ok"
+		resp.Data.User.ID = "42"
+		media := &resp.Data.User.EdgeOwnerToTimelineMedia
+		media.Count = 2
+		if strings.Contains(url, "graphql") {
+			media.Edges = []instagram.Edge{
+				{Node: instagram.Node{Shortcode: "SINK1", DisplayURL: "http://example.com/SINK1.jpg"}},
+				{Node: instagram.Node{Shortcode: "SINK2", DisplayURL: "http://example.com/SINK2.jpg"}},
+			}
+		}
+		return nil
+	}, func(url string) ([]byte, error) {
+		if strings.Contains(url, "SINK2") {
+			return nil, fmt.Errorf("gone")
+		}
+		return []byte("photo"), nil
+	}))
+	_ = s.DownloadUserPhotosWithResume("sinked", false, true)
+	assert.Nil(t, s.progress, "the sink replaces the progress display")
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	require.NotEmpty(t, sink.events)
+	assert.Equal(t, "page sinked 1/2", sink.events[0])
+	assert.Contains(t, sink.events, "queued SINK1 SINK1.jpg")
+	assert.Contains(t, sink.events, "queued SINK2 SINK2.jpg")
+	assert.Contains(t, sink.events, "completed SINK1 SINK1.jpg 5")
+	assert.Contains(t, strings.Join(sink.events, "\n"), "failed SINK2")
+}
+
+func TestSkipImages(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	cfg := config.DefaultConfig()
+	cfg.Output.BaseDirectory = t.TempDir()
+	cfg.Notifications.Enabled = false
+	cfg.Download.SkipImages = true
+	s, err := New(cfg)
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	var fetched []string
+	sink := &recordingSink{}
+	s.SetProgressSink(sink)
+	s.SetClient(newMockClient(t, func(url string, target interface{}) error {
+		resp := target.(*instagram.InstagramResponse)
+		resp.Status = "ok"
+		resp.Data.User.ID = "42"
+		media := &resp.Data.User.EdgeOwnerToTimelineMedia
+		media.Count = 3
+		if strings.Contains(url, "graphql") {
+			media.Edges = []instagram.Edge{
+				{Node: instagram.Node{Shortcode: "PIC", DisplayURL: "http://example.com/PIC.jpg"}},
+				{Node: instagram.Node{Shortcode: "CLIP", DisplayURL: "http://example.com/CLIP.jpg", IsVideo: true, VideoURL: "http://example.com/CLIP.mp4"}},
+				{Node: instagram.Node{Shortcode: "ALBUM", DisplayURL: "http://example.com/ALBUM.jpg", Typename: instagram.TypenameSidecar}},
+			}
+		}
+		return nil
+	}, func(url string) ([]byte, error) {
+		mu.Lock()
+		fetched = append(fetched, url)
+		mu.Unlock()
+		return []byte("video"), nil
+	}))
+	require.NoError(t, s.DownloadUserPhotosWithResume("videos_only", false, true))
+
+	assert.Equal(t, []string{"http://example.com/CLIP.mp4"}, fetched)
+	summary := s.Summary()
+	assert.Equal(t, 1, summary.Downloaded)
+	assert.Equal(t, map[string]int{skipImages: 2}, summary.Skipped)
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	assert.Contains(t, sink.events, "skipped PIC images")
+	assert.Contains(t, sink.events, "skipped ALBUM images")
+	assert.Contains(t, sink.events, "queued CLIP CLIP.mp4")
+}
+
+func TestFileSizeLimits(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	cfg := config.DefaultConfig()
+	cfg.Output.BaseDirectory = t.TempDir()
+	cfg.Notifications.Enabled = false
+	cfg.Download.MinFileSize = 10
+	s, err := New(cfg)
+	require.NoError(t, err)
+
+	sink := &recordingSink{}
+	s.SetProgressSink(sink)
+	s.SetClient(newMockClient(t, func(url string, target interface{}) error {
+		resp := target.(*instagram.InstagramResponse)
+		resp.Status = "ok"
+		resp.Data.User.ID = "42"
+		media := &resp.Data.User.EdgeOwnerToTimelineMedia
+		media.Count = 2
+		if strings.Contains(url, "graphql") {
+			media.Edges = []instagram.Edge{
+				{Node: instagram.Node{Shortcode: "TINY", DisplayURL: "http://example.com/TINY.jpg"}},
+				{Node: instagram.Node{Shortcode: "FULL", DisplayURL: "http://example.com/FULL.jpg"}},
+			}
+		}
+		return nil
+	}, func(url string) ([]byte, error) {
+		if strings.Contains(url, "TINY") {
+			return []byte("tiny"), nil
+		}
+		return []byte("full sized photo"), nil
+	}))
+	require.NoError(t, s.DownloadUserPhotosWithResume("sizes", false, true))
+
+	summary := s.Summary()
+	assert.Equal(t, 1, summary.Downloaded)
+	assert.Empty(t, summary.Failed, "a file outside the limits is not a failure")
+	assert.Equal(t, map[string]int{skipFileSize: 1}, summary.Skipped)
+	assert.NoFileExists(t, filepath.Join(s.OutputDir("sizes"), "TINY.jpg"))
+	assert.FileExists(t, filepath.Join(s.OutputDir("sizes"), "FULL.jpg"))
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	assert.Contains(t, sink.events, "skipped TINY outside size limits")
+}
+
+func TestRefreshAcrossRuns(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	now := time.Now()
+	post := func(shortcode, text string) instagram.Node {
+		node := instagram.Node{Shortcode: shortcode, DisplayURL: "http://example.com/" + shortcode + ".jpg", TakenAtTimestamp: now.Unix()}
+		node.EdgeMediaToCaption.Edges = []instagram.CaptionEdge{{Node: instagram.CaptionNode{Text: text}}}
+		return node
+	}
+
+	var listed []instagram.Node
+	var mu sync.Mutex
+	var fetched []string
+	client := newMockClient(t, func(url string, target interface{}) error {
+		resp := target.(*instagram.InstagramResponse)
+		resp.Status = "ok"
+		resp.Data.User.ID = "42"
+		media := &resp.Data.User.EdgeOwnerToTimelineMedia
+		media.Count = len(listed)
+		if strings.Contains(url, "graphql") {
+			for _, node := range listed {
+				media.Edges = append(media.Edges, instagram.Edge{Node: node})
+			}
+		}
+		return nil
+	}, func(url string) ([]byte, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		fetched = append(fetched, strings.TrimSuffix(path.Base(url), ".jpg"))
+		return []byte(url), nil
+	})
+
+	cfg := config.DefaultConfig()
+	cfg.Output.BaseDirectory = t.TempDir()
+	cfg.Output.RefreshNewerThan = 7 * 24 * time.Hour
+	cfg.Notifications.Enabled = false
+	s, err := New(cfg)
+	require.NoError(t, err)
+	s.SetClient(client)
+
+	// EARLY is saved by the first run and only listed by the second
+	listed = []instagram.Node{post("EARLY", "typo")}
+	require.NoError(t, s.DownloadUserPhotosWithResume("editor", false, true))
+	listed = []instagram.Node{post("LATER", "new post"), post("EARLY", "typo")}
+	require.NoError(t, s.DownloadUserPhotosWithResume("editor", false, true))
+	assert.Equal(t, []string{"EARLY", "LATER"}, fetched)
+
+	// Its caption edited, the third run still knows its record
+	fetched = nil
+	listed = []instagram.Node{post("LATER", "new post"), post("EARLY", "fixed typo")}
+	require.NoError(t, s.DownloadUserPhotosWithResume("editor", false, true))
+	assert.Equal(t, []string{"EARLY"}, fetched)
+
+	meta, err := metadata.LoadUserMetadata(s.OutputDir("editor"))
+	require.NoError(t, err)
+	require.Len(t, meta.Photos, 2)
+	for _, photo := range meta.Photos {
+		if photo.Shortcode == "EARLY" {
+			assert.Equal(t, "fixed typo", photo.Caption, "the refreshed record replaces the old one")
+		}
+	}
+}
+
+func TestOverwriteExisting(t *testing.T) {
+	now := time.Now()
+	caption := func(text string) instagram.EdgeMediaToCaption {
+		return instagram.EdgeMediaToCaption{Edges: []instagram.CaptionEdge{{Node: instagram.CaptionNode{Text: text}}}}
+	}
+	nodes := []instagram.Node{
+		{Shortcode: "EDITED", DisplayURL: "http://example.com/EDITED.jpg", TakenAtTimestamp: now.Unix(), EdgeMediaToCaption: caption("fixed typo")},
+		{Shortcode: "SAME", DisplayURL: "http://example.com/SAME.jpg", TakenAtTimestamp: now.Unix(), EdgeMediaToCaption: caption("unchanged")},
+		{Shortcode: "OLD", DisplayURL: "http://example.com/OLD.jpg", TakenAtTimestamp: now.AddDate(0, -1, 0).Unix(), EdgeMediaToCaption: caption("edited long ago")},
+	}
+
+	tests := []struct {
+		name    string
+		setup   func(cfg *config.Config)
+		fetched []string
+	}{
+		{"saved posts are kept", func(cfg *config.Config) {}, nil},
+		{"overwrite", func(cfg *config.Config) { cfg.Output.OverwriteExisting = true }, []string{"EDITED", "OLD", "SAME"}},
+		{"refresh edited posts", func(cfg *config.Config) { cfg.Output.RefreshNewerThan = 7 * 24 * time.Hour }, []string{"EDITED"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("XDG_DATA_HOME", t.TempDir())
+			cfg := config.DefaultConfig()
+			cfg.Output.BaseDirectory = t.TempDir()
+			cfg.Notifications.Enabled = false
+			tt.setup(cfg)
+			s, err := New(cfg)
+			require.NoError(t, err)
+
+			// The last scrape saved every post, before the caption of EDITED
+			// and OLD were changed
+			dir := s.OutputDir("editor")
+			require.NoError(t, os.MkdirAll(dir, 0755))
+			last := &metadata.UserMetadata{Username: "editor"}
+			for _, node := range nodes {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, node.Shortcode+".jpg"), []byte("old"), 0644))
+				record := metadata.FromInstagramNode(&node, 3)
+				if node.Shortcode != "SAME" {
+					record.Caption = "typo"
+				}
+				last.AddPhoto(*record)
+			}
+			require.NoError(t, last.Save(dir))
+
+			var mu sync.Mutex
+			var fetched []string
+			s.SetClient(newMockClient(t, func(url string, target interface{}) error {
+				resp := target.(*instagram.InstagramResponse)
+				resp.Status = "ok"
+				resp.Data.User.ID = "42"
+				media := &resp.Data.User.EdgeOwnerToTimelineMedia
+				media.Count = len(nodes)
+				if strings.Contains(url, "graphql") {
+					for _, node := range nodes {
+						media.Edges = append(media.Edges, instagram.Edge{Node: node})
+					}
+				}
+				return nil
+			}, func(url string) ([]byte, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				fetched = append(fetched, strings.TrimSuffix(path.Base(url), ".jpg"))
+				return []byte("new"), nil
+			}))
+			require.NoError(t, s.DownloadUserPhotosWithResume("editor", false, true))
+
+			sort.Strings(fetched)
+			assert.Equal(t, tt.fetched, fetched)
+			for _, shortcode := range fetched {
+				data, err := os.ReadFile(filepath.Join(dir, shortcode+".jpg"))
+				require.NoError(t, err)
+				assert.Equal(t, "new", string(data), "%s is replaced", shortcode)
+			}
+		})
+	}
+}
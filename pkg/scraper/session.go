@@ -0,0 +1,135 @@
+package scraper
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"time"
+
+	"igscraper/pkg/auth"
+	"igscraper/pkg/errors"
+	"igscraper/pkg/instagram"
+)
+
+// ErrSessionLost is returned by a scrape stopped because a keep-alive ping
+// found the session logged out or challenged. Downloads already queued
+// finish and the checkpoint is kept, so the scrape can resume once the
+// session is refreshed. The error of the ping is wrapped with it.
+var ErrSessionLost = stderrors.New("session stopped working")
+
+// SessionStore saves the session cookies Instagram renewed for a stored
+// account, such as *auth.Manager
+type SessionStore interface {
+	UpdateSession(username, sessionID, csrfToken string) error
+}
+
+// sessionRefresher is implemented by clients that report renewed session
+// cookies, such as *instagram.Client
+type sessionRefresher interface {
+	OnSessionRefresh(fn func(cookies map[string]string))
+}
+
+// sessionPinger is implemented by clients that can check that their session
+// is still logged in, such as *instagram.Client
+type sessionPinger interface {
+	KeepAlive() error
+}
+
+// SetSessionStore makes the scraper save the cookies Instagram renews during
+// the scrape to store, as those of the stored account username. With account
+// rotation they are saved for the rotator's current account instead.
+func (s *Scraper) SetSessionStore(store SessionStore, username string) {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+	s.sessions = store
+	s.account = username
+}
+
+// sessionRefreshed saves the cookies Instagram renewed for the account in use
+func (s *Scraper) sessionRefreshed(cookies map[string]string) {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+
+	username := s.account
+	if rotating, ok := s.client.(*rotatingClient); ok {
+		username = rotating.rotator.Current().Username
+	}
+	s.logger.DebugWithFields("Instagram renewed the session cookies", map[string]interface{}{
+		"account": username,
+	})
+	if s.sessions == nil || username == "" {
+		return
+	}
+
+	err := s.sessions.UpdateSession(username, cookies[instagram.CookieSessionID], cookies[instagram.CookieCSRFToken])
+	switch {
+	case stderrors.Is(err, auth.ErrStoreUnavailable):
+		// Credentials from the environment or the config file are not saved
+	case err != nil:
+		s.logger.WarnWithFields("Failed to save the renewed session cookies", map[string]interface{}{
+			"account": username,
+			"error":   err.Error(),
+		})
+	}
+}
+
+// pingSession checks the session of client, when it can
+func pingSession(client InstagramClient) error {
+	if pinger, ok := client.(sessionPinger); ok {
+		return pinger.KeepAlive()
+	}
+	return nil
+}
+
+// keepAlive pings Instagram every interval until stop is closed, so the
+// session stays in use while a scrape of username waits on downloads or a
+// cooldown. A ping finding the session logged out or challenged calls lose
+// with ErrSessionLost and ends the pings; other failures are only logged.
+func (s *Scraper) keepAlive(interval time.Duration, username string, lose context.CancelCauseFunc, stop <-chan struct{}) {
+	if _, ok := s.client.(sessionPinger); !ok {
+		s.logger.Debug("Instagram client cannot check its session, keep-alive disabled")
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		err := pingSession(s.client)
+		if err == nil {
+			continue
+		}
+		if !sessionDead(err) {
+			s.logger.WarnWithFields("Keep-alive ping failed", map[string]interface{}{
+				"username": username,
+				"error":    err.Error(),
+			})
+			continue
+		}
+
+		s.logger.ErrorWithFields("Session stopped working, pausing the scrape", map[string]interface{}{
+			"username": username,
+			"error":    err.Error(),
+		})
+		if s.tui != nil {
+			s.tui.LogError("The Instagram session stopped working, pausing %s: %v", username, err)
+		}
+		lose(fmt.Errorf("%w: %w", ErrSessionLost, err))
+		return
+	}
+}
+
+// sessionDead reports whether err says the session can no longer be used:
+// Instagram asks to log in again or the account must pass a security check
+func sessionDead(err error) bool {
+	var apiErr *errors.Error
+	if !stderrors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.Type == errors.ErrorTypeAuth || apiErr.Type == errors.ErrorTypeChallenge
+}
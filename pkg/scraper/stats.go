@@ -0,0 +1,210 @@
+package scraper
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"igscraper/pkg/instagram"
+)
+
+const (
+	// estimatedPhotoBytes is the typical size of a downloaded photo, and
+	// estimatedVideoBitrate the typical bytes per second of a video. Videos
+	// without a duration count as estimatedVideoBytes.
+	estimatedPhotoBytes   = 500 << 10
+	estimatedVideoBitrate = 300 << 10
+	estimatedVideoBytes   = 5 << 20
+)
+
+// ProfileStats describes the posts on a profile's timeline, worked out by
+// ScanProfile from the timeline pages alone
+type ProfileStats struct {
+	Username string `json:"username"`
+
+	// Posts is the post count the profile reports, Scanned the posts on
+	// the pages scanned and Pages the number of those pages
+	Posts   int `json:"posts"`
+	Scanned int `json:"scanned"`
+	Pages   int `json:"pages"`
+
+	// Scanned posts by kind. A carousel counts once, whatever it holds.
+	Photos    int `json:"photos"`
+	Videos    int `json:"videos"`
+	Carousels int `json:"carousels"`
+	Pinned    int `json:"pinned"`
+
+	// Oldest and Newest are when the first and last scanned posts were
+	// taken, zero when no post has a date
+	Oldest time.Time `json:"oldest,omitempty"`
+	Newest time.Time `json:"newest,omitempty"`
+
+	// EstimatedBytes is roughly what downloading the scanned posts would
+	// take, one file per post as a scrape saves them
+	EstimatedBytes int64 `json:"estimated_bytes"`
+
+	Likes LikeStats `json:"likes"`
+}
+
+// LikeStats is the distribution of the like counts of scanned posts
+type LikeStats struct {
+	Min    int     `json:"min"`
+	Max    int     `json:"max"`
+	Mean   float64 `json:"mean"`
+	Median int     `json:"median"`
+	P90    int     `json:"p90"`
+
+	// Buckets counts the posts by order of magnitude of their likes
+	Buckets []LikeBucket `json:"buckets"`
+}
+
+// LikeBucket counts the posts with at least Min likes and fewer than Max,
+// or any number from Min on when Max is 0
+type LikeBucket struct {
+	Min   int `json:"min"`
+	Max   int `json:"max,omitempty"`
+	Posts int `json:"posts"`
+}
+
+// likeBucketBounds are the lower bounds of the like buckets
+var likeBucketBounds = []int{0, 100, 1000, 10000, 100000}
+
+// ScanProfile walks a profile's timeline without downloading anything and
+// counts its posts by kind, date, likes and estimated size. With max_pages
+// set only that many pages are scanned, which samples the newest posts of a
+// large profile. progress, if set, is called after every page with the
+// number of posts scanned so far.
+func (s *Scraper) ScanProfile(username string, progress func(scanned int)) (*ProfileStats, error) {
+	f := s.profileFeed(username, false)
+	stats := &ProfileStats{Username: username}
+
+	if err := s.rateLimiter.WaitContext(s.runContext()); err != nil {
+		return nil, err
+	}
+	userID, total, err := f.info()
+	if err != nil {
+		s.logger.WithError(err).WithField("username", username).Error("Failed to get user info")
+		return nil, fmt.Errorf("failed to get user info: %w", err)
+	}
+	stats.Posts = total
+
+	seen := make(map[string]bool)
+	var likes []int
+	cursor := ""
+	for {
+		if err := s.rateLimiter.WaitContext(s.runContext()); err != nil {
+			return nil, err
+		}
+		media, pageInfo, err := s.fetchPage(s.runContext(), f, userID, cursor, stats.Pages+1)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch timeline: %w", err)
+		}
+		stats.Pages++
+
+		for i := range media {
+			node := &media[i].Node
+			if seen[node.Shortcode] {
+				continue
+			}
+			seen[node.Shortcode] = true
+			stats.add(node)
+			likes = append(likes, max(node.EdgeLikedBy.Count, 0))
+		}
+
+		if progress != nil {
+			progress(stats.Scanned)
+		}
+		if !pageInfo.HasNextPage || pageInfo.EndCursor == "" {
+			break
+		}
+		if maxPages := s.config.Download.MaxPages; maxPages > 0 && stats.Pages >= maxPages {
+			break
+		}
+		cursor = pageInfo.EndCursor
+	}
+	stats.Likes = likeStats(likes)
+
+	s.logger.InfoWithFields("Scanned profile", map[string]interface{}{
+		"username":        username,
+		"pages":           stats.Pages,
+		"scanned":         stats.Scanned,
+		"photos":          stats.Photos,
+		"videos":          stats.Videos,
+		"carousels":       stats.Carousels,
+		"estimated_bytes": stats.EstimatedBytes,
+	})
+	return stats, nil
+}
+
+// add counts a scanned post
+func (p *ProfileStats) add(node *instagram.Node) {
+	p.Scanned++
+	switch {
+	case node.IsCarousel():
+		p.Carousels++
+	case node.IsVideo:
+		p.Videos++
+	default:
+		p.Photos++
+	}
+	if node.Pinned() {
+		p.Pinned++
+	}
+
+	if takenAt := node.TakenAt(); !takenAt.IsZero() {
+		if p.Oldest.IsZero() || takenAt.Before(p.Oldest) {
+			p.Oldest = takenAt
+		}
+		if takenAt.After(p.Newest) {
+			p.Newest = takenAt
+		}
+	}
+
+	switch {
+	case !node.IsVideo:
+		p.EstimatedBytes += estimatedPhotoBytes
+	case node.VideoDuration != nil && *node.VideoDuration > 0:
+		p.EstimatedBytes += int64(*node.VideoDuration * estimatedVideoBitrate)
+	default:
+		p.EstimatedBytes += estimatedVideoBytes
+	}
+}
+
+// likeStats works out the distribution of like counts
+func likeStats(counts []int) LikeStats {
+	stats := LikeStats{Buckets: make([]LikeBucket, len(likeBucketBounds))}
+	for i, bound := range likeBucketBounds {
+		stats.Buckets[i].Min = bound
+		if i+1 < len(likeBucketBounds) {
+			stats.Buckets[i].Max = likeBucketBounds[i+1]
+		}
+	}
+	if len(counts) == 0 {
+		return stats
+	}
+
+	sorted := slices.Clone(counts)
+	slices.Sort(sorted)
+	sum := 0
+	for _, count := range sorted {
+		sum += count
+		bucket := 0
+		for bucket+1 < len(likeBucketBounds) && count >= likeBucketBounds[bucket+1] {
+			bucket++
+		}
+		stats.Buckets[bucket].Posts++
+	}
+	stats.Min = sorted[0]
+	stats.Max = sorted[len(sorted)-1]
+	stats.Mean = float64(sum) / float64(len(sorted))
+	stats.Median = percentile(sorted, 50)
+	stats.P90 = percentile(sorted, 90)
+	return stats
+}
+
+// percentile returns the nearest-rank percentile p of sorted, which must
+// not be empty
+func percentile(sorted []int, p int) int {
+	rank := (p*len(sorted) + 99) / 100
+	return sorted[max(rank, 1)-1]
+}
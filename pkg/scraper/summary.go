@@ -0,0 +1,109 @@
+package scraper
+
+import (
+	stderrors "errors"
+	"time"
+
+	"igscraper/internal/downloader"
+	"igscraper/pkg/errors"
+	"igscraper/pkg/storage"
+)
+
+// Statuses a scrape ends with
+const (
+	StatusComplete    = "complete"
+	StatusInterrupted = "interrupted" // by cancelling the context set with SetContext
+	StatusAborted     = "aborted"     // from the TUI
+	StatusPaused      = "paused"      // to protect disk space or the download quota, or as the session was lost
+	StatusFailed      = "failed"
+)
+
+// Summary counts what the last scrape of a Scraper did, for the report shown
+// and written when it ends
+type Summary struct {
+	Target          string
+	Status          string
+	Error           string // why the scrape stopped early, if it did
+	StartedAt       time.Time
+	Elapsed         time.Duration
+	Pages           int            // fetched in this run
+	Downloaded      int            // saved, or found already on disk
+	Bytes           int64          // downloaded
+	Skipped         map[string]int // posts not downloaded, by reason
+	Failed          map[string]int // failed downloads, by kind of error
+	Cancelled       int            // queued or in progress when the scrape was stopped
+	RateLimitPauses int
+	RateLimitWait   time.Duration // spent in rate limit pauses
+}
+
+// Summary returns the counts of the last scrape
+func (s *Scraper) Summary() Summary {
+	return s.summary
+}
+
+// Interrupted reports whether the scrape was stopped by cancelling the
+// context set with SetContext
+func (s Summary) Interrupted() bool {
+	return s.Status == StatusInterrupted
+}
+
+// SkippedCount returns the number of posts skipped for any reason
+func (s Summary) SkippedCount() int {
+	return total(s.Skipped)
+}
+
+// FailedCount returns the number of failed downloads
+func (s Summary) FailedCount() int {
+	return total(s.Failed)
+}
+
+// Speed returns the average download speed in bytes per second
+func (s Summary) Speed() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Bytes) / s.Elapsed.Seconds()
+}
+
+// total adds up the counts of a map
+func total(counts map[string]int) int {
+	n := 0
+	for _, count := range counts {
+		n += count
+	}
+	return n
+}
+
+// scrapeStatus returns the status of a scrape that ended with err
+func scrapeStatus(err error) string {
+	var spaceErr *storage.SpaceError
+	switch {
+	case err == nil:
+		return StatusComplete
+	case stderrors.Is(err, ErrInterrupted):
+		return StatusInterrupted
+	case stderrors.Is(err, ErrAborted):
+		return StatusAborted
+	case stderrors.As(err, &spaceErr), stderrors.Is(err, ErrSessionLost):
+		return StatusPaused
+	default:
+		return StatusFailed
+	}
+}
+
+// failureKind names the kind of error a download failed with, such as
+// not_found or network, for the failure counts of the summary
+func failureKind(err error) string {
+	var apiErr *errors.Error
+	var spaceErr *storage.SpaceError
+	switch {
+	case stderrors.As(err, &apiErr):
+		return string(apiErr.Type)
+	case stderrors.As(err, &spaceErr):
+		return "disk_space"
+	case stderrors.Is(err, downloader.ErrTimedOut):
+		return "timed_out"
+	default:
+		return string(errors.ErrorTypeUnknown)
+	}
+}
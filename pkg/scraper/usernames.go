@@ -0,0 +1,275 @@
+package scraper
+
+import (
+	stderrors "errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"igscraper/pkg/checkpoint"
+	"igscraper/pkg/errors"
+	"igscraper/pkg/metadata"
+	"igscraper/pkg/storage"
+	"igscraper/pkg/ui"
+)
+
+// loadProfileIndex returns the index of profiles archived under the output
+// directory, loading it on first use. Archives made before the index existed
+// are added from their metadata.
+func (s *Scraper) loadProfileIndex() (*storage.ProfileIndex, error) {
+	if s.profileIndex != nil {
+		return s.profileIndex, nil
+	}
+	idx, err := storage.LoadProfileIndex(filepath.Join(s.config.Output.BaseDirectory, storage.ProfileIndexFile))
+	if err != nil {
+		return nil, err
+	}
+	if idx.Len() == 0 && s.config.Output.CreateUserFolders {
+		s.indexArchivedProfiles(idx)
+	}
+	s.logger.DebugWithFields("Loaded profile index", map[string]interface{}{
+		"profiles": idx.Len(),
+	})
+	s.profileIndex = idx
+	return idx, nil
+}
+
+// indexArchivedProfiles records the profile folders already in the output
+// directory, by the user ID in their metadata
+func (s *Scraper) indexArchivedProfiles(idx *storage.ProfileIndex) {
+	dirs, _ := filepath.Glob(filepath.Join(s.config.Output.BaseDirectory, "*_photos"))
+	for _, dir := range dirs {
+		meta, err := metadata.LoadUserMetadata(dir)
+		if err != nil || meta == nil || meta.UserID == "" || meta.Username == "" {
+			continue
+		}
+		if _, ok := idx.ByID(meta.UserID); !ok {
+			idx.Record(meta.UserID, meta.Username, dir)
+		}
+	}
+}
+
+// resolveProfile looks up the user ID of the profile f archives and points f
+// at the profile's archive. The user ID never changes, so a profile that was
+// renamed since it was last archived keeps its folder, checkpoint and
+// deduplication index. lookup returns the user ID of the profile named
+// f.name. Problems with the profile index are logged and leave f as it is.
+func (s *Scraper) resolveProfile(f *feed, lookup func() (string, error)) error {
+	idx, err := s.loadProfileIndex()
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to load profile index")
+		return nil
+	}
+
+	userID, err := lookup()
+	if err != nil {
+		// The username may have been given up by a profile that renamed
+		entry, ok := idx.ByPreviousUsername(f.name)
+		var igErr *errors.Error
+		if !stderrors.As(err, &igErr) || igErr.Type != errors.ErrorTypeNotFound || !ok || entry.Username == "" {
+			return err
+		}
+		requested := f.name
+		s.announceRename(requested, entry.Username)
+		f.name = entry.Username
+		f.key = entry.Username
+		f.outputDir = idx.Dir(entry)
+
+		if userID, err = lookup(); err != nil {
+			return err
+		}
+		if userID != entry.UserID {
+			return fmt.Errorf("%s now belongs to another account than the one archived as %s", entry.Username, requested)
+		}
+	}
+	if userID == "" {
+		return nil
+	}
+
+	if entry, ok := idx.ByID(userID); ok {
+		if entry.Username != f.name {
+			s.followRename(f, idx, entry)
+		} else {
+			f.outputDir = idx.Dir(entry)
+		}
+	} else if s.config.Output.CreateUserFolders {
+		// The folder may belong to an account that used the username before,
+		// or link to the folder of the account that renamed from it
+		other, ok := idx.ByFolder(f.outputDir)
+		if linked, isLink := linkedProfile(idx, f.outputDir); isLink {
+			other, ok = linked, true
+		}
+		if ok && other.UserID != userID {
+			f.outputDir = filepath.Join(s.config.Output.BaseDirectory, fmt.Sprintf("%s_%s_photos", f.name, userID))
+		}
+	}
+
+	s.trackArchive(f, userID)
+	idx.Record(userID, f.name, f.outputDir)
+	if err := idx.Save(); err != nil {
+		s.logger.WithError(err).Warn("Failed to save profile index")
+	}
+	return nil
+}
+
+// trackArchive records f.outputDir in the archive registry of the data
+// directory as a folder of the profile with userID, and tells the user when
+// the profile is archived in other folders too, such as those of another
+// output directory. With output.existing_archive set to use, a profile with
+// no archive in f.outputDir yet carries on in the one scraped last instead.
+func (s *Scraper) trackArchive(f *feed, userID string) {
+	dataDir, err := checkpoint.DataDirectory()
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to locate archive registry")
+		return
+	}
+	registry, err := storage.LoadArchiveRegistry(filepath.Join(dataDir, storage.ArchiveRegistryFile))
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to load archive registry")
+		return
+	}
+
+	if others := registry.Elsewhere(userID, f.outputDir); len(others) > 0 {
+		existing := others[0]
+		if s.config.Output.ExistingArchive == "use" && !hasArchive(f.outputDir) {
+			s.logger.InfoWithFields("Continuing archive in another folder", map[string]interface{}{
+				"username": f.name,
+				"folder":   existing.Folder,
+				"instead":  f.outputDir,
+			})
+			s.announceArchive("%s is archived in %s, continuing there", f.name, existing.Folder)
+			f.outputDir = existing.Folder
+		} else {
+			s.logger.WarnWithFields("Profile is archived in another folder", map[string]interface{}{
+				"username": f.name,
+				"user_id":  userID,
+				"folder":   f.outputDir,
+				"existing": existing.Folder,
+			})
+			s.announceArchive("%s is also archived in %s (last scraped %s); pass --existing-archive use to continue there",
+				f.name, existing.Folder, existing.LastScraped.Local().Format("2006-01-02"))
+		}
+	}
+
+	registry.Record(userID, f.name, f.outputDir)
+	if err := registry.Save(); err != nil {
+		s.logger.WithError(err).Warn("Failed to save archive registry")
+	}
+}
+
+// announceArchive tells the user about another archive of the profile
+func (s *Scraper) announceArchive(format string, args ...interface{}) {
+	if s.tui != nil {
+		s.tui.LogWarning(format, args...)
+		return
+	}
+	ui.PrintWarning(fmt.Sprintf(format, args...))
+}
+
+// hasArchive reports whether dir holds an archive with its metadata.json
+func hasArchive(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, "metadata.json"))
+	return err == nil
+}
+
+// followRename moves the archive of a profile that is now called f.name, as
+// recorded in entry, to the folder and checkpoint of its new username. With
+// rename_folders off, or when the new folder is taken, the old folder is
+// kept.
+func (s *Scraper) followRename(f *feed, idx *storage.ProfileIndex, entry storage.ProfileEntry) {
+	oldName := entry.Username
+	if oldName == "" && len(entry.Previous) > 0 {
+		oldName = entry.Previous[len(entry.Previous)-1]
+	}
+	oldDir := idx.Dir(entry)
+	newDir := s.userFolder(f.name)
+	f.outputDir = oldDir
+
+	if s.config.Output.CreateUserFolders && oldDir != newDir {
+		if _, err := os.Stat(oldDir); os.IsNotExist(err) {
+			f.outputDir = newDir
+		} else if !s.config.Output.RenameFolders {
+			s.logger.InfoWithFields("Keeping folder of renamed profile", map[string]interface{}{
+				"username": f.name,
+				"folder":   oldDir,
+			})
+			s.linkFolder(newDir, oldDir)
+		} else if _, err := os.Lstat(newDir); err == nil {
+			s.logger.WithField("folder", newDir).Warn("Folder of new username already exists, keeping the old folder")
+		} else if err := os.Rename(oldDir, newDir); err != nil {
+			s.logger.WithError(err).WithField("folder", oldDir).Warn("Failed to rename folder, keeping the old folder")
+		} else {
+			f.outputDir = newDir
+			s.linkFolder(oldDir, newDir)
+			if hashIndex, err := s.loadHashIndex(); err == nil {
+				hashIndex.RenameDir(oldDir, newDir)
+				if err := hashIndex.Save(); err != nil {
+					s.logger.WithError(err).Warn("Failed to save hash index")
+				}
+			}
+		}
+	}
+
+	if oldName != "" {
+		if err := checkpoint.Rename(oldName, f.name); err != nil {
+			s.logger.WithError(err).WithField("username", f.name).Warn("Failed to move checkpoint of renamed profile")
+		}
+		// Profiles scraped by user ID alone were archived under the ID
+		if oldName != entry.UserID {
+			s.announceRename(oldName, f.name)
+		}
+	}
+	s.logger.InfoWithFields("Profile renamed", map[string]interface{}{
+		"user_id":  entry.UserID,
+		"previous": oldName,
+		"username": f.name,
+		"folder":   f.outputDir,
+	})
+}
+
+// linkFolder makes link a symbolic link to the folder target, with
+// output.link_folders on, so the archive of a renamed profile is found under
+// both its old and its new username. An existing file or folder at link is
+// left alone.
+func (s *Scraper) linkFolder(link, target string) {
+	if !s.config.Output.LinkFolders {
+		return
+	}
+	if _, err := os.Lstat(link); err == nil {
+		return
+	}
+	// Relative links keep working when the output directory is moved
+	dest := target
+	if rel, err := filepath.Rel(filepath.Dir(link), target); err == nil {
+		dest = rel
+	}
+	if err := os.Symlink(dest, link); err != nil {
+		s.logger.WithError(err).WithField("folder", link).Warn("Failed to link folder of renamed profile")
+		return
+	}
+	s.logger.InfoWithFields("Linked folder of renamed profile", map[string]interface{}{
+		"link":   link,
+		"folder": target,
+	})
+}
+
+// linkedProfile returns the profile whose folder dir is a symbolic link to
+func linkedProfile(idx *storage.ProfileIndex, dir string) (storage.ProfileEntry, bool) {
+	dest, err := os.Readlink(dir)
+	if err != nil {
+		return storage.ProfileEntry{}, false
+	}
+	if !filepath.IsAbs(dest) {
+		dest = filepath.Join(filepath.Dir(dir), dest)
+	}
+	return idx.ByFolder(dest)
+}
+
+// announceRename tells the user that a profile changed its username
+func (s *Scraper) announceRename(oldName, newName string) {
+	if s.tui != nil {
+		s.tui.LogInfo("%s is now %s, continuing its archive", oldName, newName)
+		return
+	}
+	ui.PrintInfo("Username changed", fmt.Sprintf("%s is now %s, continuing its archive", oldName, newName))
+}
@@ -0,0 +1,121 @@
+package scraper
+
+import (
+	"fmt"
+	"sort"
+
+	"igscraper/pkg/storage"
+)
+
+// VerifyReport compares a profile's remote timeline with its local archive
+type VerifyReport struct {
+	Username  string
+	OutputDir string
+
+	// RemotePosts is the number of posts on the remote timeline, and
+	// RemotePhotos how many of them the scraper would download
+	RemotePosts  int
+	RemotePhotos int
+
+	// LocalPhotos is the number of photos in the output directory
+	LocalPhotos int
+
+	// Missing lists remote photos that have not been downloaded
+	Missing []string
+
+	// Orphaned lists local photos that are no longer on the remote timeline
+	Orphaned []string
+
+	// Files maps the shortcodes of local photos to their file names
+	Files map[string]string
+}
+
+// InSync reports whether the archive matches the remote timeline
+func (r *VerifyReport) InSync() bool {
+	return len(r.Missing) == 0 && len(r.Orphaned) == 0
+}
+
+// VerifyRemote walks a profile's entire remote timeline without downloading
+// anything and compares it with the local archive. A remote photo is
+// missing if the scraper would download it (after the date range and filter)
+// but it is not on disk. A local photo is orphaned if its post is no longer
+// anywhere on the timeline. progress, if set, is called after every page
+// with the number of posts scanned so far.
+func (s *Scraper) VerifyRemote(username string, progress func(scanned int)) (*VerifyReport, error) {
+	f := s.profileFeed(username, false)
+	report := &VerifyReport{Username: username, OutputDir: f.outputDir}
+
+	layout, err := storage.ParseLayout(s.config.Output.FileNamePattern)
+	if err != nil {
+		return nil, err
+	}
+	local, err := storage.ListPhotos(f.outputDir, layout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read output directory: %w", err)
+	}
+	report.LocalPhotos = len(local)
+	report.Files = local
+
+	if err := s.rateLimiter.WaitContext(s.runContext()); err != nil {
+		return nil, err
+	}
+	userID, _, err := f.info()
+	if err != nil {
+		s.logger.WithError(err).WithField("username", username).Error("Failed to get user info")
+		return nil, fmt.Errorf("failed to get user info: %w", err)
+	}
+
+	remote := make(map[string]bool)
+	cursor := ""
+	for {
+		if err := s.rateLimiter.WaitContext(s.runContext()); err != nil {
+			return nil, err
+		}
+		media, pageInfo, err := f.page(userID, cursor)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch timeline: %w", err)
+		}
+
+		for _, edge := range media {
+			node := &edge.Node
+			if remote[node.Shortcode] {
+				continue
+			}
+			remote[node.Shortcode] = true
+			report.RemotePosts++
+
+			if s.skipMedia(node) != "" || !s.config.Download.InDateRange(node.TakenAt()) || !s.filter.Match(node) {
+				continue
+			}
+			report.RemotePhotos++
+			if local[node.Shortcode] == "" {
+				report.Missing = append(report.Missing, node.Shortcode)
+			}
+		}
+
+		if progress != nil {
+			progress(report.RemotePosts)
+		}
+		if !pageInfo.HasNextPage || pageInfo.EndCursor == "" {
+			break
+		}
+		cursor = pageInfo.EndCursor
+	}
+
+	for shortcode := range local {
+		if !remote[shortcode] {
+			report.Orphaned = append(report.Orphaned, shortcode)
+		}
+	}
+	sort.Strings(report.Orphaned)
+
+	s.logger.InfoWithFields("Verified archive against remote timeline", map[string]interface{}{
+		"username":      username,
+		"remote_posts":  report.RemotePosts,
+		"remote_photos": report.RemotePhotos,
+		"local_photos":  report.LocalPhotos,
+		"missing":       len(report.Missing),
+		"orphaned":      len(report.Orphaned),
+	})
+	return report, nil
+}
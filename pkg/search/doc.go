@@ -0,0 +1,31 @@
+// Package search finds posts in a downloaded archive by caption text,
+// hashtag or date.
+//
+// An Index is built from an archive's metadata.json: every post's caption,
+// alt text and location name are split into lowercased words, and each word
+// and hashtag maps to the posts that contain it. Building it takes no request
+// and is quick enough to do for every search.
+//
+// A query is a list of terms that must all match:
+//
+//	beach                 a word of the caption, alt text or location
+//	beach*                a word starting with beach
+//	"golden hour"         the words next to each other
+//	#sunset               the hashtag #sunset
+//	2024-03 / 2024-03-15  taken in that month or on that day
+//	date:2024             taken in that year, month or day
+//	since:2024-01-01      taken on or after the day, month or year
+//	until:2024-06         taken on or before it
+//
+//	meta, err := metadata.LoadUserMetadata("./username_photos")
+//	if err != nil {
+//	    return err
+//	}
+//	q, err := search.ParseQuery(`#sunset "golden hour" since:2024`)
+//	if err != nil {
+//	    return err
+//	}
+//	for _, result := range search.Build(meta).Search(q) {
+//	    fmt.Println(result.Photo.Shortcode, result.Files)
+//	}
+package search
@@ -0,0 +1,209 @@
+package search
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"igscraper/pkg/filter"
+	"igscraper/pkg/metadata"
+)
+
+// Index is an inverted index of an archive's posts
+type Index struct {
+	posts    []Result
+	text     []string         // words of each post joined by spaces, for phrases
+	postings map[string][]int // word or "#hashtag" -> posts, in ascending order
+}
+
+// Result is a post found by a search
+type Result struct {
+	// Photo is the post's metadata; of a carousel, that of its first item
+	// with the shortcode of the post
+	Photo metadata.PhotoMetadata
+	// Files are the names of the post's files in the archive folder, in
+	// carousel order
+	Files []string
+}
+
+// Build indexes the posts recorded in meta. A post recorded more than once
+// keeps its latest metadata, and the items of a carousel are one post, even
+// when earlier runs saved some of them.
+func Build(meta *metadata.UserMetadata) *Index {
+	ix := &Index{postings: make(map[string][]int)}
+	if meta == nil {
+		return ix
+	}
+
+	byPost := make(map[string][]metadata.PhotoMetadata)
+	for _, photo := range meta.Photos {
+		post := postShortcode(photo)
+		items := byPost[post]
+		if i := slices.IndexFunc(items, func(item metadata.PhotoMetadata) bool { return item.Shortcode == photo.Shortcode }); i >= 0 {
+			items[i] = photo
+		} else {
+			byPost[post] = append(items, photo)
+		}
+	}
+
+	for post, items := range byPost {
+		sort.SliceStable(items, func(i, j int) bool { return items[i].Index < items[j].Index })
+		result := Result{Photo: items[0]}
+		result.Photo.Shortcode = post
+		for _, item := range items {
+			name := item.File
+			if name == "" {
+				name = item.Shortcode + ".jpg"
+			}
+			if !slices.Contains(result.Files, name) {
+				result.Files = append(result.Files, name)
+			}
+		}
+		ix.posts = append(ix.posts, result)
+	}
+	sort.Slice(ix.posts, func(i, j int) bool {
+		a, b := ix.posts[i].Photo, ix.posts[j].Photo
+		if !a.TakenAt.Equal(b.TakenAt) {
+			return a.TakenAt.After(b.TakenAt)
+		}
+		return a.Shortcode < b.Shortcode
+	})
+
+	for i, post := range ix.posts {
+		words := Words(searchableText(&post.Photo))
+		ix.text = append(ix.text, strings.Join(words, " "))
+
+		keys := make(map[string]bool)
+		for _, w := range words {
+			keys[w] = true
+		}
+		for _, tag := range filter.Hashtags(post.Photo.Caption) {
+			keys["#"+tag] = true
+		}
+		for key := range keys {
+			ix.postings[key] = append(ix.postings[key], i)
+		}
+	}
+	return ix
+}
+
+// postShortcode returns the shortcode of the post photo belongs to: that of
+// a carousel item without its position
+func postShortcode(photo metadata.PhotoMetadata) string {
+	if photo.Index > 0 {
+		return strings.TrimSuffix(photo.Shortcode, fmt.Sprintf("_%d", photo.Index))
+	}
+	return photo.Shortcode
+}
+
+// Len returns the number of posts indexed
+func (ix *Index) Len() int {
+	return len(ix.posts)
+}
+
+// Search returns the posts matching q, newest first
+func (ix *Index) Search(q *Query) []Result {
+	var lists [][]int
+	for _, w := range q.words {
+		if w.prefix {
+			lists = append(lists, ix.prefixed(w.text))
+		} else {
+			lists = append(lists, ix.postings[w.text])
+		}
+	}
+	for _, phrase := range q.phrases {
+		for _, w := range phrase {
+			lists = append(lists, ix.postings[w])
+		}
+	}
+	for _, tag := range q.tags {
+		lists = append(lists, ix.postings["#"+tag])
+	}
+
+	var candidates []int
+	if len(lists) == 0 {
+		candidates = make([]int, len(ix.posts))
+		for i := range candidates {
+			candidates[i] = i
+		}
+	} else {
+		candidates = lists[0]
+		for _, list := range lists[1:] {
+			candidates = intersect(candidates, list)
+		}
+	}
+
+	var results []Result
+	for _, i := range candidates {
+		if !q.matchDate(ix.posts[i].Photo.TakenAt) || !ix.hasPhrases(i, q.phrases) {
+			continue
+		}
+		results = append(results, ix.posts[i])
+	}
+	return results
+}
+
+// prefixed returns the posts with a word starting with prefix
+func (ix *Index) prefixed(prefix string) []int {
+	seen := make(map[int]bool)
+	for key, list := range ix.postings {
+		if strings.HasPrefix(key, prefix) && !strings.HasPrefix(key, "#") {
+			for _, i := range list {
+				seen[i] = true
+			}
+		}
+	}
+	list := make([]int, 0, len(seen))
+	for i := range seen {
+		list = append(list, i)
+	}
+	sort.Ints(list)
+	return list
+}
+
+// hasPhrases reports whether post i has every phrase, its words in order
+func (ix *Index) hasPhrases(i int, phrases [][]string) bool {
+	text := " " + ix.text[i] + " "
+	for _, phrase := range phrases {
+		if !strings.Contains(text, " "+strings.Join(phrase, " ")+" ") {
+			return false
+		}
+	}
+	return true
+}
+
+// Words splits text into lowercased words of letters and digits
+func Words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// searchableText returns the text of a post that words are searched in
+func searchableText(photo *metadata.PhotoMetadata) string {
+	parts := []string{photo.Caption, photo.AccessibilityCaption}
+	if photo.Location != nil {
+		parts = append(parts, photo.Location.Name)
+	}
+	return strings.Join(parts, "\n")
+}
+
+// intersect returns the posts in both ascending lists
+func intersect(a, b []int) []int {
+	var out []int
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			out = append(out, a[i])
+			i++
+			j++
+		}
+	}
+	return out
+}
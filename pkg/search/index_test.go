@@ -0,0 +1,171 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"igscraper/pkg/instagram"
+	"igscraper/pkg/metadata"
+	"igscraper/pkg/storage"
+)
+
+func testArchive() *metadata.UserMetadata {
+	day := func(year int, month time.Month, d int) time.Time {
+		return time.Date(year, month, d, 12, 0, 0, 0, time.Local)
+	}
+	return &metadata.UserMetadata{
+		Username: "someone",
+		Photos: []metadata.PhotoMetadata{
+			{Shortcode: "BEACH", File: "BEACH.jpg", TakenAt: day(2024, 3, 15), Caption: "Golden hour at the beach #Sunset #travel"},
+			{Shortcode: "CITY", File: "CITY.jpg", TakenAt: day(2024, 6, 1), Caption: "Rooftops after the rain #sunset",
+				Location: &metadata.Location{Name: "Lisbon"}},
+			{Shortcode: "HIKE_1", File: "HIKE_1.jpg", Index: 1, TakenAt: day(2023, 8, 20), Caption: "Summit day, golden light",
+				AccessibilityCaption: "Photo of a mountain ridge"},
+			{Shortcode: "HIKE_2", File: "HIKE_2.jpg", Index: 2, TakenAt: day(2023, 8, 20), Caption: "Summit day, golden light"},
+			{Shortcode: "OLD", TakenAt: day(2022, 1, 2), Caption: "First post!"},
+		},
+	}
+}
+
+func TestSearch(t *testing.T) {
+	ix := Build(testArchive())
+	assert.Equal(t, 4, ix.Len())
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"golden", []string{"BEACH", "HIKE"}},
+		{"GOLDEN hour", []string{"BEACH"}},
+		{`"golden hour"`, []string{"BEACH"}},
+		{`"hour golden"`, nil},
+		{"gold*", []string{"BEACH", "HIKE"}},
+		{"gold", nil},
+		{"#sunset", []string{"CITY", "BEACH"}},
+		{"hashtag:travel", []string{"BEACH"}},
+		{"#golden", nil},
+		{"sunset", []string{"CITY", "BEACH"}},
+		{"lisbon", []string{"CITY"}},
+		{"mountain", []string{"HIKE"}},
+		{"2024-03", []string{"BEACH"}},
+		{"date:2024", []string{"CITY", "BEACH"}},
+		{"date:2023-08-20", []string{"HIKE"}},
+		{"since:2023", []string{"CITY", "BEACH", "HIKE"}},
+		{"until:2023-12", []string{"HIKE", "OLD"}},
+		{"#sunset until:2024-05", []string{"BEACH"}},
+		{"first!", []string{"OLD"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := ParseQuery(tt.query)
+			require.NoError(t, err)
+
+			var got []string
+			for _, result := range ix.Search(q) {
+				got = append(got, result.Photo.Shortcode)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSearchCarousel(t *testing.T) {
+	q, err := ParseQuery("summit")
+	require.NoError(t, err)
+
+	results := Build(testArchive()).Search(q)
+	require.Len(t, results, 1)
+	assert.Equal(t, "HIKE", results[0].Photo.Shortcode)
+	assert.Equal(t, 1, results[0].Photo.Index)
+	assert.Equal(t, []string{"HIKE_1.jpg", "HIKE_2.jpg"}, results[0].Files)
+
+	q, err = ParseQuery("first")
+	require.NoError(t, err)
+	results = Build(testArchive()).Search(q)
+	require.Len(t, results, 1)
+	assert.Equal(t, []string{"OLD.jpg"}, results[0].Files)
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	for _, query := range []string{
+		"",
+		"   ",
+		"!!",
+		`"golden`,
+		"#",
+		"date:March",
+		"since:2024-13",
+		"since:2024 until:2023",
+	} {
+		t.Run(query, func(t *testing.T) {
+			_, err := ParseQuery(query)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestBuildEmpty(t *testing.T) {
+	ix := Build(nil)
+	q, err := ParseQuery("anything")
+	require.NoError(t, err)
+	assert.Zero(t, ix.Len())
+	assert.Empty(t, ix.Search(q))
+}
+
+func TestSearchAcrossRuns(t *testing.T) {
+	dir := t.TempDir()
+
+	// Each run saves one post; the second does not list the first again
+	for i, caption := range []string{"First light #sunrise", "Second wind #sunset"} {
+		manager, err := storage.NewManager(dir)
+		require.NoError(t, err)
+		manager.InitializeUserMetadata("someone", "42", 2)
+		node := &instagram.Node{Shortcode: fmt.Sprintf("POST%d", i+1), TakenAtTimestamp: int64(1700000000 + i*86400)}
+		node.EdgeMediaToCaption.Edges = []instagram.CaptionEdge{{Node: instagram.CaptionNode{Text: caption}}}
+		require.NoError(t, manager.SavePhotoWithMetadata(strings.NewReader(caption), node.Shortcode, node))
+		require.NoError(t, manager.SaveUserMetadata())
+	}
+
+	meta, err := metadata.LoadUserMetadata(dir)
+	require.NoError(t, err)
+	ix := Build(meta)
+	assert.Equal(t, 2, ix.Len())
+
+	q, err := ParseQuery("#sunrise")
+	require.NoError(t, err)
+	results := ix.Search(q)
+	require.Len(t, results, 1, "posts saved by an earlier run are found")
+	assert.Equal(t, "POST1", results[0].Photo.Shortcode)
+}
+
+func TestSearchCarouselAcrossRuns(t *testing.T) {
+	dir := t.TempDir()
+
+	// An interrupted run saves the second item of a carousel, the next the first
+	for _, index := range []int{2, 1} {
+		manager, err := storage.NewManager(dir)
+		require.NoError(t, err)
+		manager.InitializeUserMetadata("someone", "42", 1)
+		node := &instagram.Node{Shortcode: fmt.Sprintf("TRIP_%d", index), CarouselIndex: index, TakenAtTimestamp: 1700000000}
+		node.EdgeMediaToCaption.Edges = []instagram.CaptionEdge{{Node: instagram.CaptionNode{Text: "Road trip #vanlife"}}}
+		require.NoError(t, manager.SavePhotoWithMetadata(strings.NewReader("jpeg"), node.Shortcode, node))
+		require.NoError(t, manager.SaveUserMetadata())
+	}
+
+	meta, err := metadata.LoadUserMetadata(dir)
+	require.NoError(t, err)
+	ix := Build(meta)
+	assert.Equal(t, 1, ix.Len(), "the items of a carousel are one post")
+
+	q, err := ParseQuery("#vanlife")
+	require.NoError(t, err)
+	results := ix.Search(q)
+	require.Len(t, results, 1)
+	assert.Equal(t, "TRIP", results[0].Photo.Shortcode)
+	assert.Equal(t, []string{"TRIP_1.jpg", "TRIP_2.jpg"}, results[0].Files)
+}
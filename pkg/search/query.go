@@ -0,0 +1,194 @@
+package search
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// datePattern matches a month or day given without a field
+var datePattern = regexp.MustCompile(`^\d{4}-\d{2}(-\d{2})?$`)
+
+// Query is a parsed search query. A post matches when it matches every term.
+type Query struct {
+	source  string
+	words   []word
+	phrases [][]string
+	tags    []string
+	since   time.Time // inclusive
+	until   time.Time // exclusive
+}
+
+// word is a word term, matching a whole word or, with prefix, the start of one
+type word struct {
+	text   string
+	prefix bool
+}
+
+// ParseQuery compiles a search query
+func ParseQuery(source string) (*Query, error) {
+	terms, err := splitQuery(source)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &Query{source: source}
+	for _, term := range terms {
+		if term.quoted {
+			if words := Words(term.text); len(words) > 0 {
+				q.phrases = append(q.phrases, words)
+			}
+			continue
+		}
+		if err := q.addTerm(term.text); err != nil {
+			return nil, err
+		}
+	}
+
+	if q.empty() {
+		return nil, fmt.Errorf("query %q has nothing to search for", source)
+	}
+	if !q.since.IsZero() && !q.until.IsZero() && !q.since.Before(q.until) {
+		return nil, fmt.Errorf("query %q matches no dates", source)
+	}
+	return q, nil
+}
+
+// String returns the text the query was parsed from
+func (q *Query) String() string {
+	return q.source
+}
+
+// addTerm adds an unquoted term to the query
+func (q *Query) addTerm(text string) error {
+	if strings.HasPrefix(text, "#") {
+		tag := strings.ToLower(strings.TrimLeft(text, "#"))
+		if tag == "" {
+			return fmt.Errorf("empty hashtag in query")
+		}
+		q.tags = append(q.tags, tag)
+		return nil
+	}
+	if datePattern.MatchString(text) {
+		return q.addDate("date", text)
+	}
+
+	if field, value, ok := strings.Cut(text, ":"); ok {
+		switch strings.ToLower(field) {
+		case "date", "since", "until":
+			return q.addDate(strings.ToLower(field), value)
+		case "hashtag", "tag":
+			return q.addTerm("#" + strings.TrimPrefix(value, "#"))
+		}
+	}
+
+	words := Words(text)
+	for i, w := range words {
+		// A trailing * makes the last word a prefix
+		prefix := i == len(words)-1 && strings.HasSuffix(text, "*")
+		q.words = append(q.words, word{text: w, prefix: prefix})
+	}
+	return nil
+}
+
+// addDate narrows the query to posts taken in, since or until a period
+func (q *Query) addDate(field, value string) error {
+	start, end, err := parsePeriod(value)
+	if err != nil {
+		return fmt.Errorf("%s needs a date such as 2024, 2024-03 or 2024-03-15, got %q", field, value)
+	}
+	if field == "date" || field == "since" {
+		if start.After(q.since) {
+			q.since = start
+		}
+	}
+	if field == "date" || field == "until" {
+		if q.until.IsZero() || end.Before(q.until) {
+			q.until = end
+		}
+	}
+	return nil
+}
+
+// empty reports whether the query has no terms
+func (q *Query) empty() bool {
+	return len(q.words) == 0 && len(q.phrases) == 0 && len(q.tags) == 0 &&
+		q.since.IsZero() && q.until.IsZero()
+}
+
+// matchDate reports whether a post taken at t is within the query's dates
+func (q *Query) matchDate(t time.Time) bool {
+	if !q.since.IsZero() && t.Before(q.since) {
+		return false
+	}
+	if !q.until.IsZero() && !t.Before(q.until) {
+		return false
+	}
+	return true
+}
+
+// parsePeriod returns the local start and end of the year, month or day in
+// value
+func parsePeriod(value string) (time.Time, time.Time, error) {
+	for _, layout := range []struct {
+		format string
+		years  int
+		months int
+		days   int
+	}{
+		{"2006-01-02", 0, 0, 1},
+		{"2006-01", 0, 1, 0},
+		{"2006", 1, 0, 0},
+	} {
+		if len(value) != len(layout.format) {
+			continue
+		}
+		start, err := time.ParseInLocation(layout.format, value, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		return start, start.AddDate(layout.years, layout.months, layout.days), nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("invalid date %q", value)
+}
+
+// queryTerm is a term of the query as typed
+type queryTerm struct {
+	text   string
+	quoted bool
+}
+
+// splitQuery splits a query at spaces, keeping quoted phrases together
+func splitQuery(source string) ([]queryTerm, error) {
+	var terms []queryTerm
+	runes := []rune(source)
+
+	for i := 0; i < len(runes); {
+		switch r := runes[i]; {
+		case r == ' ' || r == '\t' || r == '\n':
+			i++
+
+		case r == '"':
+			start := i
+			i++
+			for i < len(runes) && runes[i] != '"' {
+				i++
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated phrase at position %d", start+1)
+			}
+			terms = append(terms, queryTerm{text: string(runes[start+1 : i]), quoted: true})
+			i++
+
+		default:
+			start := i
+			for i < len(runes) && !strings.ContainsRune(" \t\n\"", runes[i]) {
+				i++
+			}
+			terms = append(terms, queryTerm{text: string(runes[start:i])})
+		}
+	}
+
+	return terms, nil
+}
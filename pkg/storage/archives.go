@@ -0,0 +1,182 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ArchiveRegistryFile is the name of the archive registry kept in the data
+// directory
+const ArchiveRegistryFile = "archives.json"
+
+// archiveRegistryVersion is bumped when the file format changes
+const archiveRegistryVersion = 1
+
+// ArchiveRegistry maps the user IDs of profiles to every folder they were
+// archived in, across output directories and runs. Unlike the profile index,
+// which lives in one output directory, it is kept in the data directory, so
+// a profile scraped into a second output directory is noticed before its
+// posts are downloaded twice. Folders are kept as absolute paths.
+type ArchiveRegistry struct {
+	path     string
+	mu       sync.Mutex
+	profiles map[string][]ArchiveLocation
+	dirty    bool
+}
+
+// ArchiveLocation is one folder a profile was archived in
+type ArchiveLocation struct {
+	Folder      string    `json:"folder"`
+	Username    string    `json:"username"`
+	LastScraped time.Time `json:"last_scraped"`
+}
+
+// archiveRegistryFile is the on-disk format of an ArchiveRegistry
+type archiveRegistryFile struct {
+	Version  int                          `json:"version"`
+	Profiles map[string][]ArchiveLocation `json:"profiles"`
+}
+
+// LoadArchiveRegistry reads the registry at path, or returns an empty
+// registry if the file does not exist yet
+func LoadArchiveRegistry(path string) (*ArchiveRegistry, error) {
+	r := &ArchiveRegistry{
+		path:     path,
+		profiles: make(map[string][]ArchiveLocation),
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive registry: %w", err)
+	}
+
+	var file archiveRegistryFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse archive registry: %w", err)
+	}
+	if file.Version != archiveRegistryVersion {
+		return nil, fmt.Errorf("unsupported archive registry version %d", file.Version)
+	}
+	if file.Profiles != nil {
+		r.profiles = file.Profiles
+	}
+	return r, nil
+}
+
+// Elsewhere returns the folders other than dir that the profile with userID
+// is archived in, most recently scraped first. Folders that no longer exist
+// are forgotten, and links to dir, such as those left by a renamed profile,
+// are not another folder.
+func (r *ArchiveRegistry) Elsewhere(userID, dir string) []ArchiveLocation {
+	folder := resolved(absolute(dir))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var kept, others []ArchiveLocation
+	for _, location := range r.profiles[userID] {
+		if _, err := os.Stat(location.Folder); os.IsNotExist(err) {
+			continue
+		}
+		kept = append(kept, location)
+		if resolved(location.Folder) != folder {
+			others = append(others, location)
+		}
+	}
+	if len(kept) < len(r.profiles[userID]) {
+		r.set(userID, kept)
+		r.dirty = true
+	}
+
+	sort.SliceStable(others, func(i, j int) bool {
+		return others[i].LastScraped.After(others[j].LastScraped)
+	})
+	return others
+}
+
+// Record notes that the profile with userID, currently named username, was
+// scraped into dir now
+func (r *ArchiveRegistry) Record(userID, username, dir string) {
+	folder := absolute(dir)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	locations := r.profiles[userID]
+	location := ArchiveLocation{Folder: folder, Username: username, LastScraped: time.Now().UTC()}
+	for i := range locations {
+		if locations[i].Folder == folder {
+			locations[i] = location
+			r.dirty = true
+			return
+		}
+	}
+	r.profiles[userID] = append(locations, location)
+	r.dirty = true
+}
+
+// set replaces the folders of a profile, dropping the profile once it has
+// none
+func (r *ArchiveRegistry) set(userID string, locations []ArchiveLocation) {
+	if len(locations) == 0 {
+		delete(r.profiles, userID)
+		return
+	}
+	r.profiles[userID] = locations
+}
+
+// Save writes the registry to disk if it changed since it was loaded or
+// saved
+func (r *ArchiveRegistry) Save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.dirty {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(archiveRegistryFile{Version: archiveRegistryVersion, Profiles: r.profiles}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal archive registry: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return fmt.Errorf("failed to write archive registry: %w", err)
+	}
+	tempFile := r.path + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write archive registry: %w", err)
+	}
+	if err := os.Rename(tempFile, r.path); err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("failed to write archive registry: %w", err)
+	}
+
+	r.dirty = false
+	return nil
+}
+
+// resolved returns path with its symbolic links followed, or path itself if
+// they cannot be
+func resolved(path string) string {
+	if real, err := filepath.EvalSymlinks(path); err == nil {
+		return real
+	}
+	return path
+}
+
+// absolute returns dir as an absolute, clean path
+func absolute(dir string) string {
+	if abs, err := filepath.Abs(dir); err == nil {
+		return abs
+	}
+	return filepath.Clean(dir)
+}
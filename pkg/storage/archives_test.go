@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestArchiveRegistry(t *testing.T) {
+	dataDir := t.TempDir()
+	registryPath := filepath.Join(dataDir, "nested", ArchiveRegistryFile)
+
+	r, err := LoadArchiveRegistry(registryPath)
+	if err != nil {
+		t.Fatalf("Failed to load missing registry: %v", err)
+	}
+
+	first := filepath.Join(t.TempDir(), "someone_photos")
+	second := filepath.Join(t.TempDir(), "someone_photos")
+	for _, dir := range []string{first, second} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if others := r.Elsewhere("42", first); len(others) != 0 {
+		t.Errorf("Elsewhere of an unknown profile = %+v, want none", others)
+	}
+	r.Record("42", "someone", first)
+	if others := r.Elsewhere("42", first); len(others) != 0 {
+		t.Errorf("Elsewhere of the only folder = %+v, want none", others)
+	}
+	if err := r.Save(); err != nil {
+		t.Fatalf("Failed to save registry: %v", err)
+	}
+
+	// A second output directory is noticed after a reload
+	r, err = LoadArchiveRegistry(registryPath)
+	if err != nil {
+		t.Fatalf("Failed to reload registry: %v", err)
+	}
+	others := r.Elsewhere("42", second)
+	if len(others) != 1 || others[0].Folder != first || others[0].Username != "someone" {
+		t.Fatalf("Elsewhere = %+v, want %s", others, first)
+	}
+	if others[0].LastScraped.IsZero() {
+		t.Error("Expected the time of the last scrape to be recorded")
+	}
+
+	// Most recently scraped first
+	time.Sleep(10 * time.Millisecond)
+	r.Record("42", "renamed", second)
+	third := t.TempDir()
+	others = r.Elsewhere("42", third)
+	if len(others) != 2 || others[0].Folder != second || others[0].Username != "renamed" || others[1].Folder != first {
+		t.Errorf("Elsewhere = %+v, want %s then %s", others, second, first)
+	}
+
+	// Folders that are gone are forgotten
+	if err := os.RemoveAll(first); err != nil {
+		t.Fatal(err)
+	}
+	if others := r.Elsewhere("42", third); len(others) != 1 || others[0].Folder != second {
+		t.Errorf("Elsewhere after removing a folder = %+v, want %s", others, second)
+	}
+	if err := r.Save(); err != nil {
+		t.Fatalf("Failed to save registry: %v", err)
+	}
+	r, err = LoadArchiveRegistry(registryPath)
+	if err != nil {
+		t.Fatalf("Failed to reload registry: %v", err)
+	}
+	if others := r.Elsewhere("42", third); len(others) != 1 {
+		t.Errorf("Elsewhere after reload = %+v, want one folder", others)
+	}
+}
+
+func TestArchiveRegistryRelativeFolders(t *testing.T) {
+	r, err := LoadArchiveRegistry(filepath.Join(t.TempDir(), ArchiveRegistryFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	r.Record("42", "someone", dir)
+
+	// The same folder given relative to the working directory is not another
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rel, err := filepath.Rel(wd, dir)
+	if err != nil {
+		t.Skip("no relative path to the temporary directory")
+	}
+	if others := r.Elsewhere("42", rel); len(others) != 0 {
+		t.Errorf("Elsewhere(%s) = %+v, want none", rel, others)
+	}
+}
+
+func TestArchiveRegistryLinks(t *testing.T) {
+	r, err := LoadArchiveRegistry(filepath.Join(t.TempDir(), ArchiveRegistryFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	oldDir := filepath.Join(root, "old_name_photos")
+	newDir := filepath.Join(root, "new_name_photos")
+	if err := os.MkdirAll(newDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("new_name_photos", oldDir); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	// A link left by a rename is the same archive, not another one
+	r.Record("42", "old_name", oldDir)
+	if others := r.Elsewhere("42", newDir); len(others) != 0 {
+		t.Errorf("Elsewhere = %+v, want none", others)
+	}
+}
+
+func TestArchiveRegistryVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ArchiveRegistryFile)
+	if err := os.WriteFile(path, []byte(`{"version": 99, "profiles": {}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadArchiveRegistry(path); err == nil {
+		t.Error("Expected an error for an unknown registry version")
+	}
+}
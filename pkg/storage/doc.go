@@ -15,6 +15,12 @@
 //   - Thread-safe operations with read-write mutex
 //   - Automatic scanning of existing files on initialization
 //   - In-memory cache for fast duplicate detection
+//   - Optional content deduplication through a persistent SHA-256 HashIndex
+//   - Optional EXIF/XMP embedding of post details with EmbedMetadata
+//   - Optional resizing, metadata stripping and format conversion of photos
+//     through a postprocess.Pipeline
+//   - Optional small JPEG copies of photos in .thumbnails/ for gallery
+//     browsing, with SetGalleryThumbnails
 //
 // Usage:
 //
@@ -0,0 +1,206 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// HashIndexFile is the name of the hash index kept in the output directory
+const HashIndexFile = ".igscraper-hashes.json"
+
+// hashIndexVersion is bumped when the file format changes
+const hashIndexVersion = 1
+
+// HashIndex maps the SHA-256 of saved files to where they were first saved,
+// so byte-identical photos published under different shortcodes are only
+// stored once. Paths are kept relative to the index's directory, which lets
+// the archive be moved as a whole.
+type HashIndex struct {
+	path  string
+	root  string
+	mu    sync.Mutex
+	files map[string]hashEntry
+	dirty bool
+}
+
+// hashEntry is one file in the index
+type hashEntry struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// hashIndexFile is the on-disk format of a HashIndex
+type hashIndexFile struct {
+	Version int                  `json:"version"`
+	Files   map[string]hashEntry `json:"files"`
+}
+
+// LoadHashIndex reads the index at path, or returns an empty index if the
+// file does not exist yet
+func LoadHashIndex(path string) (*HashIndex, error) {
+	idx := &HashIndex{
+		path:  path,
+		root:  filepath.Dir(path),
+		files: make(map[string]hashEntry),
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hash index: %w", err)
+	}
+
+	var file hashIndexFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse hash index: %w", err)
+	}
+	if file.Version != hashIndexVersion {
+		return nil, fmt.Errorf("unsupported hash index version %d", file.Version)
+	}
+	for sum, entry := range file.Files {
+		idx.files[sum] = entry
+	}
+	return idx, nil
+}
+
+// HashContent returns the hex-encoded SHA-256 of data
+func HashContent(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// HashFile returns the hex-encoded SHA-256 and the size of the file at path,
+// reading it in pieces rather than all at once
+func HashFile(path string) (string, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), size, nil
+}
+
+// Lookup returns the path of a saved file with the given hash and size.
+// Entries whose file has since been deleted or changed size are dropped.
+func (idx *HashIndex) Lookup(sum string, size int64) (string, bool) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	entry, ok := idx.files[sum]
+	if !ok || entry.Size != size {
+		return "", false
+	}
+
+	path := entry.Path
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(idx.root, path)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Size() != size {
+		delete(idx.files, sum)
+		idx.dirty = true
+		return "", false
+	}
+	return path, true
+}
+
+// Add records that the file at path has the given hash and size
+func (idx *HashIndex) Add(sum, path string, size int64) {
+	path = idx.relative(path)
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.files[sum] = hashEntry{Path: path, Size: size}
+	idx.dirty = true
+}
+
+// Rename updates the index after the file at oldPath was moved to newPath
+func (idx *HashIndex) Rename(oldPath, newPath string) {
+	oldPath, newPath = idx.relative(oldPath), idx.relative(newPath)
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for sum, entry := range idx.files {
+		if entry.Path == oldPath {
+			entry.Path = newPath
+			idx.files[sum] = entry
+			idx.dirty = true
+		}
+	}
+}
+
+// RenameDir updates the index after the directory oldDir, and every file in
+// it, was moved to newDir
+func (idx *HashIndex) RenameDir(oldDir, newDir string) {
+	oldDir, newDir = idx.relative(oldDir), idx.relative(newDir)
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for sum, entry := range idx.files {
+		if rest, ok := strings.CutPrefix(entry.Path, oldDir+"/"); ok {
+			entry.Path = newDir + "/" + rest
+			idx.files[sum] = entry
+			idx.dirty = true
+		}
+	}
+}
+
+// relative returns path as it is stored in the index
+func (idx *HashIndex) relative(path string) string {
+	if rel, err := filepath.Rel(idx.root, path); err == nil {
+		path = rel
+	}
+	return filepath.ToSlash(path)
+}
+
+// Len returns the number of files in the index
+func (idx *HashIndex) Len() int {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	return len(idx.files)
+}
+
+// Save writes the index to disk if it changed since it was loaded or saved
+func (idx *HashIndex) Save() error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if !idx.dirty {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(hashIndexFile{Version: hashIndexVersion, Files: idx.files}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal hash index: %w", err)
+	}
+
+	tempFile := idx.path + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write hash index: %w", err)
+	}
+	if err := os.Rename(tempFile, idx.path); err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("failed to write hash index: %w", err)
+	}
+
+	idx.dirty = false
+	return nil
+}
@@ -0,0 +1,170 @@
+package storage
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"igscraper/pkg/instagram"
+)
+
+func TestHashIndex(t *testing.T) {
+	root := t.TempDir()
+	indexPath := filepath.Join(root, HashIndexFile)
+
+	idx, err := LoadHashIndex(indexPath)
+	if err != nil {
+		t.Fatalf("Failed to load missing index: %v", err)
+	}
+	if idx.Len() != 0 {
+		t.Fatalf("Expected empty index, got %d entries", idx.Len())
+	}
+
+	photo := filepath.Join(root, "user_photos", "ABC.jpg")
+	if err := os.MkdirAll(filepath.Dir(photo), 0755); err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("photo data")
+	if err := os.WriteFile(photo, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sum := HashContent(data)
+	idx.Add(sum, photo, int64(len(data)))
+	if err := idx.Save(); err != nil {
+		t.Fatalf("Failed to save index: %v", err)
+	}
+
+	// Reload and look the file up again
+	idx, err = LoadHashIndex(indexPath)
+	if err != nil {
+		t.Fatalf("Failed to reload index: %v", err)
+	}
+	if got, ok := idx.Lookup(sum, int64(len(data))); !ok || got != photo {
+		t.Errorf("Lookup = %q, %v; want %q, true", got, ok, photo)
+	}
+	if _, ok := idx.Lookup(sum, 1); ok {
+		t.Error("Expected lookup with a different size to miss")
+	}
+	if _, ok := idx.Lookup(HashContent([]byte("other")), int64(len(data))); ok {
+		t.Error("Expected lookup of unknown hash to miss")
+	}
+
+	// Paths are stored relative to the index
+	raw, err := os.ReadFile(indexPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(raw, []byte(`"user_photos/ABC.jpg"`)) || bytes.Contains(raw, []byte(root)) {
+		t.Errorf("Expected relative path in index, got %s", raw)
+	}
+
+	// Entries for deleted files are dropped
+	if err := os.Remove(photo); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := idx.Lookup(sum, int64(len(data))); ok {
+		t.Error("Expected lookup of deleted file to miss")
+	}
+	if idx.Len() != 0 {
+		t.Errorf("Expected stale entry to be removed, got %d entries", idx.Len())
+	}
+}
+
+func TestLoadHashIndexInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), HashIndexFile)
+
+	if err := os.WriteFile(path, []byte("not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadHashIndex(path); err == nil {
+		t.Error("Expected error for corrupt index")
+	}
+
+	if err := os.WriteFile(path, []byte(`{"version":99,"files":{}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadHashIndex(path); err == nil {
+		t.Error("Expected error for unsupported version")
+	}
+}
+
+func TestSavePhotoDedup(t *testing.T) {
+	root := t.TempDir()
+	idx, err := LoadHashIndex(filepath.Join(root, HashIndexFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := NewManager(filepath.Join(root, "first_photos"))
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	first.SetHashIndex(idx)
+	first.InitializeUserMetadata("first", "1", 2)
+
+	data := []byte("identical photo data")
+	save := func(m *Manager, shortcode string, content []byte) {
+		t.Helper()
+		node := &instagram.Node{ID: shortcode, Shortcode: shortcode}
+		if err := m.SavePhotoWithMetadata(bytes.NewReader(content), shortcode, node); err != nil {
+			t.Fatalf("Failed to save %s: %v", shortcode, err)
+		}
+	}
+
+	save(first, "ORIGINAL", data)
+	save(first, "REPOST", data)
+	save(first, "DIFFERENT", []byte("other photo data"))
+
+	original, err := os.Stat(filepath.Join(root, "first_photos", "ORIGINAL.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	repost, err := os.Stat(filepath.Join(root, "first_photos", "REPOST.jpg"))
+	if err != nil {
+		t.Fatalf("Expected duplicate to be linked: %v", err)
+	}
+	if !os.SameFile(original, repost) {
+		t.Error("Expected duplicate to be a hardlink of the original")
+	}
+
+	stats := first.DedupStats()
+	if stats.Files != 1 || stats.Linked != 1 || stats.BytesSaved != int64(len(data)) {
+		t.Errorf("Unexpected dedup stats: %+v", stats)
+	}
+
+	photos := first.GetUserMetadata().Photos
+	if len(photos) != 3 {
+		t.Fatalf("Expected 3 photos in metadata, got %d", len(photos))
+	}
+	if photos[0].DuplicateOf != "" || photos[1].DuplicateOf != "ORIGINAL.jpg" || photos[2].DuplicateOf != "" {
+		t.Errorf("Unexpected duplicate_of values: %q, %q, %q", photos[0].DuplicateOf, photos[1].DuplicateOf, photos[2].DuplicateOf)
+	}
+
+	// The index is shared across folders
+	second, err := NewManager(filepath.Join(root, "second_photos"))
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	second.SetHashIndex(idx)
+	second.InitializeUserMetadata("second", "2", 1)
+	save(second, "CROSSPOST", data)
+
+	crosspost, err := os.Stat(filepath.Join(root, "second_photos", "CROSSPOST.jpg"))
+	if err != nil {
+		t.Fatalf("Expected duplicate to be linked: %v", err)
+	}
+	if !os.SameFile(original, crosspost) {
+		t.Error("Expected duplicate in another folder to be a hardlink of the original")
+	}
+	if got := second.GetUserMetadata().Photos[0].DuplicateOf; got != "../first_photos/ORIGINAL.jpg" {
+		t.Errorf("Expected duplicate_of relative to the photo's folder, got %q", got)
+	}
+
+	// Saving the same shortcode again rewrites it rather than linking to itself
+	save(first, "ORIGINAL", data)
+	if stats := first.DedupStats(); stats.Files != 1 {
+		t.Errorf("Expected re-saving a photo not to count as a duplicate, got %+v", stats)
+	}
+}
@@ -0,0 +1,253 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrNotJPEG is returned by EmbedMetadata for data that is not a JPEG image
+var ErrNotJPEG = errors.New("not a JPEG image")
+
+const (
+	// maxSegmentSize is the largest payload a JPEG APP segment can carry
+	maxSegmentSize = 65533
+
+	// maxCaptionBytes keeps the caption well inside one segment; Instagram
+	// captions are limited to 2,200 characters
+	maxCaptionBytes = 16 * 1024
+
+	exifHeader = "Exif\x00\x00"
+	xmpHeader  = "http://ns.adobe.com/xap/1.0/\x00"
+)
+
+// ImageMetadata is the post information embedded into a downloaded photo
+type ImageMetadata struct {
+	Caption string
+	URL     string
+	Author  string
+	TakenAt time.Time
+}
+
+// EmbedMetadata returns a copy of a JPEG image with the metadata written to
+// EXIF and XMP segments. Existing EXIF and XMP segments are replaced; the
+// image data itself is not touched.
+func EmbedMetadata(data []byte, meta ImageMetadata) ([]byte, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, ErrNotJPEG
+	}
+
+	// Walk the application segments at the start of the file. JFIF (APP0)
+	// must stay first; stale EXIF and XMP segments are dropped.
+	var app0, others bytes.Buffer
+	pos := 2
+	for pos+4 <= len(data) && data[pos] == 0xFF && data[pos+1] >= 0xE0 && data[pos+1] <= 0xEF {
+		length := int(binary.BigEndian.Uint16(data[pos+2 : pos+4]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			return nil, fmt.Errorf("%w: truncated segment", ErrNotJPEG)
+		}
+		segment := data[pos:end]
+		payload := segment[4:]
+
+		switch {
+		case data[pos+1] == 0xE0:
+			app0.Write(segment)
+		case data[pos+1] == 0xE1 && (bytes.HasPrefix(payload, []byte(exifHeader)) || bytes.HasPrefix(payload, []byte(xmpHeader))):
+			// replaced below
+		default:
+			others.Write(segment)
+		}
+		pos = end
+	}
+
+	meta.Caption = truncateUTF8(meta.Caption, maxCaptionBytes)
+
+	exif, err := appSegment(0xE1, append([]byte(exifHeader), buildEXIF(meta)...))
+	if err != nil {
+		return nil, err
+	}
+	xmp, err := appSegment(0xE1, append([]byte(xmpHeader), buildXMP(meta)...))
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	out.Grow(len(data) + len(exif) + len(xmp))
+	out.Write(data[:2])
+	out.Write(app0.Bytes())
+	out.Write(exif)
+	out.Write(xmp)
+	out.Write(others.Bytes())
+	out.Write(data[pos:])
+	return out.Bytes(), nil
+}
+
+// appSegment frames a payload as a JPEG application segment
+func appSegment(marker byte, payload []byte) ([]byte, error) {
+	if len(payload) > maxSegmentSize {
+		return nil, fmt.Errorf("metadata segment too large: %d bytes", len(payload))
+	}
+	segment := make([]byte, 4, 4+len(payload))
+	segment[0] = 0xFF
+	segment[1] = marker
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	return append(segment, payload...), nil
+}
+
+// EXIF tags and types used by buildEXIF
+const (
+	tagImageDescription   = 0x010E
+	tagDateTime           = 0x0132
+	tagArtist             = 0x013B
+	tagExifIFD            = 0x8769
+	tagDateTimeOriginal   = 0x9003
+	tagOffsetTimeOriginal = 0x9011
+
+	typeASCII = 2
+	typeLong  = 4
+
+	exifDateFormat = "2006:01:02 15:04:05"
+)
+
+// ifdEntry is one tag of a TIFF image file directory
+type ifdEntry struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	value []byte
+}
+
+func asciiEntry(tag uint16, value string) ifdEntry {
+	b := append([]byte(value), 0)
+	return ifdEntry{tag: tag, typ: typeASCII, count: uint32(len(b)), value: b}
+}
+
+// buildEXIF returns a big-endian TIFF structure with the caption, author and
+// date. Text is written as UTF-8, which photo managers accept in practice.
+func buildEXIF(meta ImageMetadata) []byte {
+	var ifd0, exifIFD []ifdEntry
+	if meta.Caption != "" {
+		ifd0 = append(ifd0, asciiEntry(tagImageDescription, meta.Caption))
+	}
+	if !meta.TakenAt.IsZero() {
+		ifd0 = append(ifd0, asciiEntry(tagDateTime, meta.TakenAt.Format(exifDateFormat)))
+		exifIFD = append(exifIFD,
+			asciiEntry(tagDateTimeOriginal, meta.TakenAt.Format(exifDateFormat)),
+			asciiEntry(tagOffsetTimeOriginal, meta.TakenAt.Format("-07:00")),
+		)
+	}
+	if meta.Author != "" {
+		ifd0 = append(ifd0, asciiEntry(tagArtist, meta.Author))
+	}
+
+	const ifd0Offset = 8
+	if len(exifIFD) > 0 {
+		// The pointer is the last IFD0 tag, so IFD0's size is known before
+		// its value is filled in
+		ifd0 = append(ifd0, ifdEntry{tag: tagExifIFD, typ: typeLong, count: 1, value: make([]byte, 4)})
+		binary.BigEndian.PutUint32(ifd0[len(ifd0)-1].value, uint32(ifd0Offset+ifdSize(ifd0)))
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("MM\x00\x2A")
+	binary.Write(&buf, binary.BigEndian, uint32(ifd0Offset))
+	writeIFD(&buf, ifd0, ifd0Offset)
+	if len(exifIFD) > 0 {
+		writeIFD(&buf, exifIFD, buf.Len())
+	}
+	return buf.Bytes()
+}
+
+// ifdSize is the number of bytes writeIFD produces for entries
+func ifdSize(entries []ifdEntry) int {
+	size := 2 + 12*len(entries) + 4
+	for _, e := range entries {
+		if len(e.value) > 4 {
+			size += len(e.value) + len(e.value)%2
+		}
+	}
+	return size
+}
+
+// writeIFD writes a directory at offset (relative to the TIFF header)
+// followed by the values that do not fit in their entries
+func writeIFD(buf *bytes.Buffer, entries []ifdEntry, offset int) {
+	dataOffset := offset + 2 + 12*len(entries) + 4
+	var data bytes.Buffer
+
+	binary.Write(buf, binary.BigEndian, uint16(len(entries)))
+	for _, e := range entries {
+		binary.Write(buf, binary.BigEndian, e.tag)
+		binary.Write(buf, binary.BigEndian, e.typ)
+		binary.Write(buf, binary.BigEndian, e.count)
+		if len(e.value) <= 4 {
+			value := make([]byte, 4)
+			copy(value, e.value)
+			buf.Write(value)
+			continue
+		}
+		binary.Write(buf, binary.BigEndian, uint32(dataOffset+data.Len()))
+		data.Write(e.value)
+		if len(e.value)%2 == 1 {
+			data.WriteByte(0)
+		}
+	}
+	binary.Write(buf, binary.BigEndian, uint32(0))
+	buf.Write(data.Bytes())
+}
+
+// buildXMP returns an XMP packet with the caption, author, post URL and date
+// in the Dublin Core and XMP basic schemas
+func buildXMP(meta ImageMetadata) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("<?xpacket begin=\"\ufeff\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	buf.WriteString(`<x:xmpmeta xmlns:x="adobe:ns:meta/">` + "\n")
+	buf.WriteString(` <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">` + "\n")
+	buf.WriteString(`  <rdf:Description rdf:about=""` + "\n")
+	buf.WriteString(`    xmlns:dc="http://purl.org/dc/elements/1.1/"` + "\n")
+	buf.WriteString(`    xmlns:xmp="http://ns.adobe.com/xap/1.0/"` + "\n")
+	buf.WriteString(`    xmlns:photoshop="http://ns.adobe.com/photoshop/1.0/">` + "\n")
+
+	if meta.Caption != "" {
+		buf.WriteString(`   <dc:description><rdf:Alt><rdf:li xml:lang="x-default">`)
+		xml.EscapeText(&buf, []byte(meta.Caption))
+		buf.WriteString("</rdf:li></rdf:Alt></dc:description>\n")
+	}
+	if meta.Author != "" {
+		buf.WriteString("   <dc:creator><rdf:Seq><rdf:li>")
+		xml.EscapeText(&buf, []byte(meta.Author))
+		buf.WriteString("</rdf:li></rdf:Seq></dc:creator>\n")
+	}
+	if meta.URL != "" {
+		buf.WriteString("   <dc:source>")
+		xml.EscapeText(&buf, []byte(meta.URL))
+		buf.WriteString("</dc:source>\n")
+	}
+	if !meta.TakenAt.IsZero() {
+		date := meta.TakenAt.Format(time.RFC3339)
+		buf.WriteString("   <xmp:CreateDate>" + date + "</xmp:CreateDate>\n")
+		buf.WriteString("   <photoshop:DateCreated>" + date + "</photoshop:DateCreated>\n")
+	}
+
+	buf.WriteString("  </rdf:Description>\n")
+	buf.WriteString(" </rdf:RDF>\n")
+	buf.WriteString("</x:xmpmeta>\n")
+	buf.WriteString(`<?xpacket end="w"?>`)
+	return buf.Bytes()
+}
+
+// truncateUTF8 shortens s to at most n bytes without splitting a character
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
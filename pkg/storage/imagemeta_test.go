@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"igscraper/pkg/instagram"
+)
+
+func testJPEG(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 8, 8)), nil); err != nil {
+		t.Fatalf("Failed to encode test image: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestEmbedMetadata(t *testing.T) {
+	original := testJPEG(t)
+	meta := ImageMetadata{
+		Caption: "Sunset <over> the bay & more #sunset",
+		URL:     "https://www.instagram.com/p/ABC123/",
+		Author:  "photographer",
+		TakenAt: time.Date(2024, 3, 15, 18, 30, 0, 0, time.FixedZone("", 2*60*60)),
+	}
+
+	embedded, err := EmbedMetadata(original, meta)
+	if err != nil {
+		t.Fatalf("EmbedMetadata failed: %v", err)
+	}
+
+	if _, err := jpeg.Decode(bytes.NewReader(embedded)); err != nil {
+		t.Fatalf("Embedded image no longer decodes: %v", err)
+	}
+
+	content := string(embedded)
+	for _, want := range []string{
+		"Exif\x00\x00MM",
+		"http://ns.adobe.com/xap/1.0/\x00",
+		"photographer\x00",
+		"2024:03:15 18:30:00\x00",
+		"+02:00\x00",
+		"Sunset &lt;over&gt; the bay &amp; more #sunset",
+		"<dc:source>https://www.instagram.com/p/ABC123/</dc:source>",
+		"<xmp:CreateDate>2024-03-15T18:30:00+02:00</xmp:CreateDate>",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("Embedded image is missing %q", want)
+		}
+	}
+
+	// Embedding again replaces the previous segments instead of adding more
+	again, err := EmbedMetadata(embedded, ImageMetadata{Caption: "replaced"})
+	if err != nil {
+		t.Fatalf("EmbedMetadata on embedded image failed: %v", err)
+	}
+	if n := bytes.Count(again, []byte("Exif\x00\x00")); n != 1 {
+		t.Errorf("Expected 1 EXIF segment, got %d", n)
+	}
+	if bytes.Contains(again, []byte("photographer")) {
+		t.Error("Expected previous metadata to be replaced")
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(again)); err != nil {
+		t.Fatalf("Re-embedded image no longer decodes: %v", err)
+	}
+}
+
+func TestEmbedMetadataNotJPEG(t *testing.T) {
+	for _, data := range [][]byte{nil, []byte("test photo data"), []byte("\x89PNG\r\n\x1a\n")} {
+		if _, err := EmbedMetadata(data, ImageMetadata{Caption: "x"}); !errors.Is(err, ErrNotJPEG) {
+			t.Errorf("EmbedMetadata(%q) error = %v, want ErrNotJPEG", data, err)
+		}
+	}
+}
+
+func TestBuildEXIF(t *testing.T) {
+	tiff := buildEXIF(ImageMetadata{
+		Caption: "odd",
+		Author:  "a",
+		TakenAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	})
+
+	// IFD0 holds description, date, artist and the Exif IFD pointer
+	if got := int(tiff[8])<<8 | int(tiff[9]); got != 4 {
+		t.Fatalf("Expected 4 IFD0 entries, got %d", got)
+	}
+	last := 10 + 3*12
+	if tag := int(tiff[last])<<8 | int(tiff[last+1]); tag != tagExifIFD {
+		t.Fatalf("Expected last IFD0 entry to be the Exif IFD pointer, got %#x", tag)
+	}
+	offset := int(tiff[last+8])<<24 | int(tiff[last+9])<<16 | int(tiff[last+10])<<8 | int(tiff[last+11])
+	if offset != 8+ifdSize(nil)+4*12+len("2024:01:02 03:04:05\x00") {
+		t.Fatalf("Unexpected Exif IFD offset %d", offset)
+	}
+	if got := int(tiff[offset])<<8 | int(tiff[offset+1]); got != 2 {
+		t.Errorf("Expected 2 Exif IFD entries, got %d", got)
+	}
+}
+
+func TestSavePhotoWithEmbeddedMetadata(t *testing.T) {
+	tempDir := t.TempDir()
+	manager, err := NewManager(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	manager.InitializeUserMetadata("testuser", "123", 2)
+	manager.SetEmbedMetadata(true)
+
+	node := &instagram.Node{
+		ID:               "1",
+		Shortcode:        "embed1",
+		TakenAtTimestamp: 1710527400,
+		Owner:            instagram.Owner{Username: "testuser"},
+		EdgeMediaToCaption: instagram.EdgeMediaToCaption{
+			Edges: []instagram.CaptionEdge{{Node: instagram.CaptionNode{Text: "Embedded caption"}}},
+		},
+	}
+
+	if err := manager.SavePhotoWithMetadata(bytes.NewReader(testJPEG(t)), "embed1", node); err != nil {
+		t.Fatalf("Failed to save photo: %v", err)
+	}
+	saved, err := os.ReadFile(filepath.Join(tempDir, "embed1.jpg"))
+	if err != nil {
+		t.Fatalf("Failed to read saved file: %v", err)
+	}
+	if !bytes.Contains(saved, []byte("Embedded caption")) || !bytes.Contains(saved, []byte("instagram.com/p/embed1/")) {
+		t.Error("Expected caption and post URL to be embedded")
+	}
+	photos := manager.GetUserMetadata().Photos
+	if len(photos) != 1 || photos[0].FileSize != int64(len(saved)) {
+		t.Errorf("Expected metadata file size %d, got %+v", len(saved), photos)
+	}
+
+	// Files that are not JPEGs are saved unchanged
+	node2 := &instagram.Node{ID: "2", Shortcode: "plain1"}
+	if err := manager.SavePhotoWithMetadata(strings.NewReader("test photo data"), "plain1", node2); err != nil {
+		t.Fatalf("Failed to save non-JPEG photo: %v", err)
+	}
+	plain, err := os.ReadFile(filepath.Join(tempDir, "plain1.jpg"))
+	if err != nil {
+		t.Fatalf("Failed to read saved file: %v", err)
+	}
+	if string(plain) != "test photo data" {
+		t.Errorf("Expected non-JPEG data to be saved unchanged, got %q", plain)
+	}
+}
@@ -0,0 +1,196 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"igscraper/pkg/metadata"
+)
+
+// JournalFile is the write-ahead journal kept in the output directory while a
+// scrape saves files
+const JournalFile = ".igscraper-journal.jsonl"
+
+// Journal operations
+const (
+	journalSave   = "save"
+	journalCommit = "commit"
+)
+
+// Journal is a write-ahead log of the files a scrape saves. A file, its entry
+// in metadata.json and its record in the checkpoint are written at different
+// times, so each file is logged before it is moved into place, and committed
+// once the checkpoint has it. Until metadata.json is saved, the journal lets
+// the next scrape finish what a crash interrupted: files that were saved get
+// their metadata and checkpoint records back, and those that were not are
+// downloaded again.
+type Journal struct {
+	path string
+	mu   sync.Mutex
+	file *os.File
+}
+
+// JournalEntry is a file logged in the journal
+type JournalEntry struct {
+	Shortcode string                  `json:"shortcode"`
+	File      string                  `json:"file"` // holding the content, relative to the output directory
+	Size      int64                   `json:"size"`
+	SHA256    string                  `json:"sha256"`
+	Photo     *metadata.PhotoMetadata `json:"photo,omitempty"`
+
+	// Committed is set once the download is recorded in the checkpoint
+	Committed bool `json:"-"`
+}
+
+// journalRecord is one line of the journal
+type journalRecord struct {
+	Op string `json:"op"`
+	JournalEntry
+}
+
+// OpenJournal opens the journal in dir for appending and returns the entries
+// left in it by an earlier scrape, in the order they were logged. Only the
+// last entry of a shortcode is kept. A line cut short by a crash ends the
+// journal, and is removed so new records start on a line of their own.
+func OpenJournal(dir string) (*Journal, []JournalEntry, error) {
+	path := filepath.Join(dir, JournalFile)
+	entries, valid, err := readJournal(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if info, err := os.Stat(path); err == nil && info.Size() > valid {
+		if err := os.Truncate(path, valid); err != nil {
+			return nil, nil, fmt.Errorf("failed to repair journal: %w", err)
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open journal: %w", err)
+	}
+	return &Journal{path: path, file: file}, entries, nil
+}
+
+// readJournal reads the entries of the journal at path, if there is one, and
+// returns them with the length of the complete lines they were read from
+func readJournal(path string) ([]JournalEntry, int64, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read journal: %w", err)
+	}
+	defer file.Close()
+
+	var entries []JournalEntry
+	var valid int64
+	index := make(map[string]int) // shortcode -> position in entries
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	scanner.Split(scanLines)
+	for scanner.Scan() {
+		var record journalRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			break
+		}
+		valid += int64(len(scanner.Bytes())) + 1
+		i, seen := index[record.Shortcode]
+		switch record.Op {
+		case journalSave:
+			if seen {
+				entries[i] = record.JournalEntry
+			} else {
+				index[record.Shortcode] = len(entries)
+				entries = append(entries, record.JournalEntry)
+			}
+		case journalCommit:
+			if seen {
+				entries[i].Committed = true
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to read journal: %w", err)
+	}
+	return entries, valid, nil
+}
+
+// scanLines splits the journal into lines ended by a newline. Unlike
+// bufio.ScanLines, it drops a last line without one, which a crash cut
+// short.
+func scanLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF {
+		return len(data), nil, bufio.ErrFinalToken
+	}
+	return 0, nil, nil
+}
+
+// Log records that the file of entry is about to be moved into place
+func (j *Journal) Log(entry JournalEntry) error {
+	return j.append(journalRecord{Op: journalSave, JournalEntry: entry})
+}
+
+// Commit records that the download of shortcode is in the checkpoint
+func (j *Journal) Commit(shortcode string) error {
+	return j.append(journalRecord{Op: journalCommit, JournalEntry: JournalEntry{Shortcode: shortcode}})
+}
+
+// append writes a record and syncs it to disk before returning
+func (j *Journal) append(record journalRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal journal record: %w", err)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.file == nil {
+		return fmt.Errorf("journal is closed")
+	}
+	if _, err := j.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	if err := j.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync journal: %w", err)
+	}
+	return nil
+}
+
+// Clear closes the journal and removes it, once what it logged is saved in
+// metadata.json
+func (j *Journal) Clear() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.file != nil {
+		j.file.Close()
+		j.file = nil
+	}
+	if err := os.Remove(j.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove journal: %w", err)
+	}
+	return nil
+}
+
+// Close closes the journal, keeping what it logged for the next scrape
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.file == nil {
+		return nil
+	}
+	err := j.file.Close()
+	j.file = nil
+	return err
+}
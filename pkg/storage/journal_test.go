@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"igscraper/pkg/instagram"
+	"igscraper/pkg/metadata"
+)
+
+func TestJournalRecovery(t *testing.T) {
+	tempDir := t.TempDir()
+
+	save := func(manager *Manager, shortcode, data string) {
+		file, err := os.CreateTemp(tempDir, ".download-*.part")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := file.WriteString(data); err != nil {
+			t.Fatal(err)
+		}
+		file.Close()
+		if err := manager.SaveFile(file.Name(), shortcode, &instagram.Node{Shortcode: shortcode}); err != nil {
+			t.Fatalf("Failed to save %s: %v", shortcode, err)
+		}
+	}
+
+	// A scrape saves two files, records one in the checkpoint and crashes
+	// while a third is being moved into place
+	first, err := NewManager(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	if recovered, err := first.OpenJournal(); err != nil || len(recovered) != 0 {
+		t.Fatalf("Expected an empty journal, got %v, %v", recovered, err)
+	}
+	first.InitializeUserMetadata("testuser", "42", 3)
+	save(first, "ABC123", "first photo")
+	if err := first.CommitDownload("ABC123"); err != nil {
+		t.Fatalf("Failed to commit download: %v", err)
+	}
+	save(first, "DEF456", "second photo")
+	if err := first.journal.Log(JournalEntry{Shortcode: "GHI789", File: "GHI789.jpg", Size: 5, SHA256: HashContent([]byte("third"))}); err != nil {
+		t.Fatalf("Failed to log download: %v", err)
+	}
+	first.CloseJournal()
+
+	// The next scrape gets back the saved files and their metadata
+	second, err := NewManager(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	recovered, err := second.OpenJournal()
+	if err != nil {
+		t.Fatalf("Failed to open journal: %v", err)
+	}
+	if len(recovered) != 2 || recovered[0].Shortcode != "ABC123" || !recovered[0].Committed || recovered[1].Shortcode != "DEF456" || recovered[1].Committed {
+		t.Fatalf("Unexpected recovered entries: %+v", recovered)
+	}
+	if recovered[1].SHA256 != HashContent([]byte("second photo")) || recovered[1].Size != int64(len("second photo")) {
+		t.Errorf("Unexpected digest: %+v", recovered[1])
+	}
+	if !second.IsDownloaded("DEF456") || second.IsDownloaded("GHI789") {
+		t.Error("Expected only the files in place to count as downloaded")
+	}
+	second.InitializeUserMetadata("testuser", "42", 3)
+	if photos := second.GetUserMetadata().Photos; len(photos) != 2 || photos[1].Shortcode != "DEF456" || photos[1].File != "DEF456.jpg" {
+		t.Errorf("Expected the metadata of the recovered files, got %+v", photos)
+	}
+
+	// Saving metadata.json clears the journal
+	if err := second.SaveUserMetadata(); err != nil {
+		t.Fatalf("Failed to save metadata: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, JournalFile)); !os.IsNotExist(err) {
+		t.Error("Expected the journal to be removed")
+	}
+	meta, err := metadata.LoadUserMetadata(tempDir)
+	if err != nil || meta == nil || len(meta.Photos) != 2 {
+		t.Errorf("Expected 2 photos in metadata.json, got %+v, %v", meta, err)
+	}
+}
+
+func TestJournalTruncatedLine(t *testing.T) {
+	tempDir := t.TempDir()
+	data := `{"op":"save","shortcode":"ABC123","file":"ABC123.jpg","size":1,"sha256":"aa"}` + "\n" +
+		`{"op":"commit","shortcode":"ABC123"}` + "\n" +
+		`{"op":"save","shortcode":"DEF4`
+	if err := os.WriteFile(filepath.Join(tempDir, JournalFile), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	journal, entries, err := OpenJournal(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to open journal: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Shortcode != "ABC123" || !entries[0].Committed {
+		t.Errorf("Expected the complete lines only, got %+v", entries)
+	}
+
+	// Records logged afterwards are read back
+	if err := journal.Log(JournalEntry{Shortcode: "GHI789", File: "GHI789.jpg", Size: 1, SHA256: "bb"}); err != nil {
+		t.Fatalf("Failed to log download: %v", err)
+	}
+	journal.Close()
+	journal, entries, err = OpenJournal(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to open journal: %v", err)
+	}
+	defer journal.Close()
+	if len(entries) != 2 || entries[1].Shortcode != "GHI789" || entries[1].Committed {
+		t.Errorf("Expected the new record after the complete lines, got %+v", entries)
+	}
+}
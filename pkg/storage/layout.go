@@ -0,0 +1,247 @@
+package storage
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"igscraper/pkg/metadata"
+)
+
+// DefaultFileNamePattern names photos after their shortcode, which is how
+// archives were laid out before file name patterns were configurable
+const DefaultFileNamePattern = "{shortcode}.{ext}"
+
+// placeholders maps each file name placeholder to the value it renders and
+// the expression that matches it in an existing file name
+var placeholders = map[string]struct {
+	render func(photo *metadata.PhotoMetadata) string
+	match  string
+}{
+	"shortcode": {
+		render: func(p *metadata.PhotoMetadata) string { return p.Shortcode },
+		match:  `(?P<shortcode>[A-Za-z0-9_-]+)`,
+	},
+	"id": {
+		render: func(p *metadata.PhotoMetadata) string { return p.ID },
+		match:  `[0-9]*`,
+	},
+	"username": {
+		render: func(p *metadata.PhotoMetadata) string { return p.Owner.Username },
+		match:  `[A-Za-z0-9._]*`,
+	},
+	"date_taken": {
+		render: func(p *metadata.PhotoMetadata) string { return p.TakenAt.Format("2006-01-02") },
+		match:  `[0-9]{4}-[0-9]{2}-[0-9]{2}`,
+	},
+	"timestamp": {
+		render: func(p *metadata.PhotoMetadata) string { return strconv.FormatInt(p.TakenAt.Unix(), 10) },
+		match:  `-?[0-9]+`,
+	},
+	"taken_at": {
+		render: func(p *metadata.PhotoMetadata) string { return p.TakenAt.Format("2006-01-02_15-04-05") },
+		match:  `[0-9]{4}-[0-9]{2}-[0-9]{2}_[0-9]{2}-[0-9]{2}-[0-9]{2}`,
+	},
+	"year": {
+		render: func(p *metadata.PhotoMetadata) string { return p.TakenAt.Format("2006") },
+		match:  `[0-9]{4}`,
+	},
+	"month": {
+		render: func(p *metadata.PhotoMetadata) string { return p.TakenAt.Format("01") },
+		match:  `[0-9]{2}`,
+	},
+	"likes": {
+		render: func(p *metadata.PhotoMetadata) string { return strconv.Itoa(p.LikesCount) },
+		match:  `[0-9]+`,
+	},
+	"index": {
+		render: func(p *metadata.PhotoMetadata) string { return strconv.Itoa(max(p.Index, 1)) },
+		match:  `[0-9]+`,
+	},
+	"media_type": {
+		render: func(p *metadata.PhotoMetadata) string {
+			if p.IsVideo {
+				return "video"
+			}
+			return "photo"
+		},
+		match: `(?:photo|video)`,
+	},
+	"ext": {
+		render: func(p *metadata.PhotoMetadata) string {
+			if p.IsVideo {
+				return "mp4"
+			}
+			return "jpg"
+		},
+		match: `(?:jpg|mp4|webp|avif)`,
+	},
+}
+
+// placeholderPattern finds the placeholders in a file name pattern
+var placeholderPattern = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+// Layout names photo files from a pattern such as
+// "{date_taken}_{shortcode}.{ext}". Every pattern contains {shortcode}, so
+// the shortcode of a saved photo can be recovered from its file name. A
+// pattern may put photos into folders with "/", as in
+// "{year}/{month}/{shortcode}.{ext}".
+//
+// Supported placeholders are {shortcode}, {id}, {username}, {date_taken}
+// (YYYY-MM-DD), {taken_at} (YYYY-MM-DD_HH-MM-SS), {year}, {month} (01-12),
+// {timestamp} (Unix seconds when the post was taken), {likes}, {index} (the
+// position in a carousel, 1 for single posts), {media_type} (photo or video)
+// and {ext} (jpg, or mp4 for videos).
+type Layout struct {
+	pattern string
+	match   *regexp.Regexp
+	depth   int // folders a file is nested in
+}
+
+// ParseLayout checks a file name pattern and returns its layout. An empty
+// pattern is the default layout.
+func ParseLayout(pattern string) (*Layout, error) {
+	if pattern == "" {
+		pattern = DefaultFileNamePattern
+	}
+	if strings.Contains(pattern, `\`) {
+		return nil, fmt.Errorf("file name pattern %q must separate folders with /", pattern)
+	}
+	for _, segment := range strings.Split(pattern, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return nil, fmt.Errorf("file name pattern %q must be a relative path without empty, . or .. folders", pattern)
+		}
+	}
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	shortcodes := 0
+	last := 0
+	for _, loc := range placeholderPattern.FindAllStringSubmatchIndex(pattern, -1) {
+		name := pattern[loc[2]:loc[3]]
+		placeholder, ok := placeholders[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown placeholder {%s} in file name pattern %q", name, pattern)
+		}
+		if name == "shortcode" {
+			shortcodes++
+			if shortcodes > 1 {
+				return nil, fmt.Errorf("file name pattern %q must contain {shortcode} only once", pattern)
+			}
+		}
+		expr.WriteString(regexp.QuoteMeta(pattern[last:loc[0]]))
+		expr.WriteString(placeholder.match)
+		last = loc[1]
+	}
+	expr.WriteString(regexp.QuoteMeta(pattern[last:]))
+	expr.WriteString("$")
+
+	if shortcodes == 0 {
+		return nil, fmt.Errorf("file name pattern %q must contain {shortcode}", pattern)
+	}
+
+	return &Layout{
+		pattern: pattern,
+		match:   regexp.MustCompile(expr.String()),
+		depth:   strings.Count(pattern, "/"),
+	}, nil
+}
+
+// defaultLayout is the layout of archives without a configured pattern
+var defaultLayout, _ = ParseLayout(DefaultFileNamePattern)
+
+// Pattern returns the pattern the layout was parsed from
+func (l *Layout) Pattern() string {
+	return l.pattern
+}
+
+// FileName returns the name of the photo's file, relative to the archive
+// folder and with / between folders
+func (l *Layout) FileName(photo *metadata.PhotoMetadata) string {
+	return placeholderPattern.ReplaceAllStringFunc(l.pattern, func(m string) string {
+		return placeholders[m[1:len(m)-1]].render(photo)
+	})
+}
+
+// Shortcode returns the shortcode of the photo a file name belongs to, or
+// false if the name does not follow the layout
+func (l *Layout) Shortcode(name string) (string, bool) {
+	m := l.match.FindStringSubmatch(name)
+	if m == nil {
+		return "", false
+	}
+	return m[l.match.SubexpIndex("shortcode")], true
+}
+
+// mediaExtensions are the extensions of saved photos and videos. Photos are
+// only saved in other formats than JPEG when they are converted after
+// downloading.
+var mediaExtensions = map[string]bool{".jpg": true, ".mp4": true, ".webp": true, ".avif": true}
+
+// ListPhotos returns the file names of the photos saved in dir by shortcode.
+// Files are recognised by the names recorded in metadata.json, by the layout
+// and by the default layout they were named after before. Names are relative
+// to dir with / between folders. Folders are searched as deep as files are
+// nested, except hidden ones and the thumbnail folder. A missing
+// directory holds no photos, and an unreadable metadata.json is ignored.
+func ListPhotos(dir string, layout *Layout) (map[string]string, error) {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return map[string]string{}, nil
+	}
+
+	// Files recorded deeper than the layout nests them, under an earlier
+	// pattern, are searched for too
+	depth := layout.depth
+	recorded := make(map[string]string) // file name -> shortcode
+	if meta, err := metadata.LoadUserMetadata(dir); err == nil && meta != nil {
+		for _, photo := range meta.Photos {
+			if photo.File != "" {
+				recorded[photo.File] = photo.Shortcode
+				depth = max(depth, strings.Count(photo.File, "/"))
+			}
+		}
+	}
+
+	photos := make(map[string]string)
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == dir {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if entry.IsDir() {
+			if strings.Count(name, "/") >= depth || strings.HasPrefix(entry.Name(), ".") || name == ThumbnailFolder {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !mediaExtensions[filepath.Ext(name)] {
+			return nil
+		}
+		shortcode, ok := recorded[name]
+		if !ok {
+			shortcode, ok = layout.Shortcode(name)
+		}
+		if !ok {
+			shortcode, ok = defaultLayout.Shortcode(name)
+		}
+		if ok {
+			photos[shortcode] = name
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+	return photos, nil
+}
@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"igscraper/pkg/metadata"
+)
+
+func TestParseLayout(t *testing.T) {
+	for _, pattern := range []string{"{date_taken}.{ext}", "{shortcode}_{shortcode}.jpg", "{shortcode}.{size}", `photos\{shortcode}.jpg`, "/photos/{shortcode}.jpg", "../{shortcode}.jpg", "{year}//{shortcode}.jpg", "{shortcode}/"} {
+		if _, err := ParseLayout(pattern); err == nil {
+			t.Errorf("Expected %q to be rejected", pattern)
+		}
+	}
+
+	layout, err := ParseLayout("")
+	if err != nil {
+		t.Fatalf("Failed to parse default layout: %v", err)
+	}
+	if layout.Pattern() != DefaultFileNamePattern {
+		t.Errorf("Pattern = %q, want %q", layout.Pattern(), DefaultFileNamePattern)
+	}
+}
+
+func TestLayoutFileName(t *testing.T) {
+	layout, err := ParseLayout("{date_taken}_{username}_{shortcode} ({id}).{ext}")
+	if err != nil {
+		t.Fatalf("Failed to parse layout: %v", err)
+	}
+	photo := &metadata.PhotoMetadata{
+		ID:        "123",
+		Shortcode: "AbC-_1",
+		TakenAt:   time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC),
+		Owner:     metadata.Owner{Username: "some.user"},
+	}
+
+	name := layout.FileName(photo)
+	if name != "2024-03-15_some.user_AbC-_1 (123).jpg" {
+		t.Fatalf("FileName = %q", name)
+	}
+	if shortcode, ok := layout.Shortcode(name); !ok || shortcode != "AbC-_1" {
+		t.Errorf("Shortcode(%q) = %q, %v", name, shortcode, ok)
+	}
+	if _, ok := layout.Shortcode("AbC-_1.jpg"); ok {
+		t.Error("Expected a name from another layout not to match")
+	}
+}
+
+func TestNestedLayout(t *testing.T) {
+	layout, err := ParseLayout("{username}/{year}/{month}/{taken_at}_{shortcode}_{index}_{media_type}_{likes}.{ext}")
+	if err != nil {
+		t.Fatalf("Failed to parse layout: %v", err)
+	}
+	photo := &metadata.PhotoMetadata{
+		Shortcode:  "AbC",
+		TakenAt:    time.Date(2024, 3, 15, 10, 4, 5, 0, time.Local),
+		Owner:      metadata.Owner{Username: "some.user"},
+		LikesCount: 42,
+		IsVideo:    true,
+		Index:      2,
+	}
+
+	name := layout.FileName(photo)
+	if name != "some.user/2024/03/2024-03-15_10-04-05_AbC_2_video_42.mp4" {
+		t.Fatalf("FileName = %q", name)
+	}
+	if shortcode, ok := layout.Shortcode(name); !ok || shortcode != "AbC" {
+		t.Errorf("Shortcode(%q) = %q, %v", name, shortcode, ok)
+	}
+
+	// Single posts are index 1
+	photo.Index = 0
+	photo.IsVideo = false
+	if name := layout.FileName(photo); name != "some.user/2024/03/2024-03-15_10-04-05_AbC_1_photo_42.jpg" {
+		t.Errorf("FileName = %q", name)
+	}
+
+	// Photos are found in folders, but not thumbnails or folders nested
+	// deeper than the layout
+	dir := t.TempDir()
+	files := []string{
+		"some.user/2024/03/2024-03-15_10-04-05_AbC_1_photo_42.jpg",
+		"FLAT.jpg",
+		"thumbnails/some.user/2024/03/2024-03-15_10-04-05_VID_1_video_1.jpg",
+		"a/b/c/d/DEEP.jpg",
+	}
+	for _, file := range files {
+		path := filepath.Join(dir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(file), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	photos, err := ListPhotos(dir, layout)
+	if err != nil {
+		t.Fatalf("Failed to list photos: %v", err)
+	}
+	want := map[string]string{"AbC": files[0], "FLAT": "FLAT.jpg"}
+	if len(photos) != len(want) || photos["AbC"] != want["AbC"] || photos["FLAT"] != want["FLAT"] {
+		t.Errorf("ListPhotos = %v, want %v", photos, want)
+	}
+}
+
+func TestListPhotos(t *testing.T) {
+	dir := t.TempDir()
+	layout, _ := ParseLayout("{date_taken}_{shortcode}.{ext}")
+
+	for _, name := range []string{"2024-01-02_NEW.jpg", "OLD.jpg", "renamed by hand.jpg", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	meta := &metadata.UserMetadata{Photos: []metadata.PhotoMetadata{{Shortcode: "HAND", File: "renamed by hand.jpg"}}}
+	if err := meta.Save(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	photos, err := ListPhotos(dir, layout)
+	if err != nil {
+		t.Fatalf("Failed to list photos: %v", err)
+	}
+	want := map[string]string{"NEW": "2024-01-02_NEW.jpg", "OLD": "OLD.jpg", "HAND": "renamed by hand.jpg"}
+	if len(photos) != len(want) {
+		t.Errorf("Expected %d photos, got %v", len(want), photos)
+	}
+	for shortcode, name := range want {
+		if photos[shortcode] != name {
+			t.Errorf("photos[%s] = %q, want %q", shortcode, photos[shortcode], name)
+		}
+	}
+
+	if photos, err := ListPhotos(filepath.Join(dir, "missing"), layout); err != nil || len(photos) != 0 {
+		t.Errorf("Expected missing directory to be empty, got %v, %v", photos, err)
+	}
+}
@@ -1,36 +1,64 @@
 package storage
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
 	"igscraper/pkg/instagram"
 	"igscraper/pkg/logger"
 	"igscraper/pkg/metadata"
+	"igscraper/pkg/postprocess"
 )
 
 // Manager handles file storage operations and duplicate detection
 type Manager struct {
 	outputDir        string
-	downloadedPhotos map[string]bool
+	downloadedPhotos *shortcodeSet // shortcode -> file name
+	layout           *Layout
 	mu               sync.RWMutex
 	logger           logger.Logger
 	userMetadata     *metadata.UserMetadata
+	failed           *metadata.FailedDownloads // loaded from failed.json on first use
+	embedMetadata    bool
+	postProcess      *postprocess.Pipeline
+	galleryThumbs    int // longest side of gallery thumbnails, 0 for none
+	hashIndex        *HashIndex
+	dedupStats       DedupStats
+	scrapeID         string
+	digests          map[string]fileDigest // shortcode -> file saved by SaveFile
+	journal          *Journal
+	recovered        []metadata.PhotoMetadata // from the journal, for the metadata collection
+}
+
+// fileDigest is the size and SHA-256 of a saved file
+type fileDigest struct {
+	size int64
+	sum  string
+}
+
+// DedupStats counts photos that were not written because identical content
+// was already saved
+type DedupStats struct {
+	Files      int   // duplicates found
+	Linked     int   // duplicates stored as hardlinks; the rest were skipped
+	BytesSaved int64 // bytes not written to disk
 }
 
 // NewManager creates a new storage manager with default logger
 func NewManager(outputDir string) (*Manager, error) {
-	return NewManagerWithLogger(outputDir, logger.GetLogger())
+	return NewManagerWithLogger(outputDir, logger.Named(logger.ComponentStorage))
 }
 
 // NewManagerWithLogger creates a new storage manager with a custom logger
 func NewManagerWithLogger(outputDir string, log logger.Logger) (*Manager, error) {
 	if log == nil {
-		log = logger.GetLogger()
+		log = logger.Named(logger.ComponentStorage)
 	}
 	
 	// Create output directory if it doesn't exist
@@ -46,7 +74,9 @@ func NewManagerWithLogger(outputDir string, log logger.Logger) (*Manager, error)
 
 	manager := &Manager{
 		outputDir:        outputDir,
-		downloadedPhotos: make(map[string]bool),
+		downloadedPhotos: newShortcodeSet(),
+		digests:          make(map[string]fileDigest),
+		layout:           defaultLayout,
 		logger:           log,
 		userMetadata:     nil, // Will be initialized when starting download
 	}
@@ -65,51 +95,38 @@ func NewManagerWithLogger(outputDir string, log logger.Logger) (*Manager, error)
 func (m *Manager) scanExistingFiles() error {
 	m.logger.WithField("directory", m.outputDir).Debug("Reading directory contents")
 	
-	entries, err := os.ReadDir(m.outputDir)
+	photos, err := ListPhotos(m.outputDir, m.layout)
 	if err != nil {
 		m.logger.WithError(err).WithField("directory", m.outputDir).Error("Failed to read directory")
-		return fmt.Errorf("failed to read directory: %w", err)
+		return err
 	}
-
-	fileCount := 0
-	for _, entry := range entries {
-		if !entry.IsDir() && filepath.Ext(entry.Name()) == ".jpg" {
-			// Extract shortcode from filename (format: shortcode.jpg)
-			shortcode := filepath.Base(entry.Name())
-			shortcode = shortcode[:len(shortcode)-4] // Remove .jpg extension
-			m.downloadedPhotos[shortcode] = true
-			fileCount++
-		}
+	for shortcode, name := range photos {
+		m.downloadedPhotos.Set(shortcode, name)
 	}
 	
 	m.logger.WithFields(map[string]interface{}{
 		"directory": m.outputDir,
-		"file_count": fileCount,
+		"file_count": len(photos),
 	}).Info("Completed scanning existing files")
 
 	return nil
 }
 
-// IsDownloaded checks if a photo with the given shortcode has already been downloaded
+// IsDownloaded checks if a photo with the given shortcode has already been
+// downloaded. It does not take the manager's lock, so the paginator and the
+// workers can ask at the same time.
 func (m *Manager) IsDownloaded(shortcode string) bool {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-	
 	// Check in-memory map first
-	if m.downloadedPhotos[shortcode] {
+	if m.downloadedPhotos.Get(shortcode) != "" {
 		m.logger.WithField("shortcode", shortcode).Debug("Photo already downloaded (found in cache)")
 		return true
 	}
 	
 	// Double-check file existence
-	filename := filepath.Join(m.outputDir, fmt.Sprintf("%s.jpg", shortcode))
-	if _, err := os.Stat(filename); err == nil {
+	name := m.fileName(shortcode, nil)
+	if _, err := os.Stat(filepath.Join(m.outputDir, name)); err == nil {
 		// Update cache if file exists
-		m.mu.RUnlock()
-		m.mu.Lock()
-		m.downloadedPhotos[shortcode] = true
-		m.mu.Unlock()
-		m.mu.RLock()
+		m.downloadedPhotos.Set(shortcode, name)
 		m.logger.WithField("shortcode", shortcode).Debug("Photo already downloaded (found on disk)")
 		return true
 	}
@@ -119,7 +136,8 @@ func (m *Manager) IsDownloaded(shortcode string) bool {
 
 // SavePhoto saves a photo from the given reader
 func (m *Manager) SavePhoto(r io.Reader, shortcode string) error {
-	filename := filepath.Join(m.outputDir, fmt.Sprintf("%s.jpg", shortcode))
+	name := m.fileName(shortcode, nil)
+	filename := filepath.Join(m.outputDir, name)
 	
 	m.logger.WithFields(map[string]interface{}{
 		"shortcode": shortcode,
@@ -171,9 +189,7 @@ func (m *Manager) SavePhoto(r io.Reader, shortcode string) error {
 	}
 	
 	// Update downloaded map
-	m.mu.Lock()
-	m.downloadedPhotos[shortcode] = true
-	m.mu.Unlock()
+	m.downloadedPhotos.Set(shortcode, name)
 	
 	m.logger.WithFields(map[string]interface{}{
 		"shortcode": shortcode,
@@ -183,19 +199,19 @@ func (m *Manager) SavePhoto(r io.Reader, shortcode string) error {
 	return nil
 }
 
-// SavePhotoWithMetadata saves a photo and its metadata
+// SavePhotoWithMetadata saves a photo and its metadata. The reader is
+// streamed to a temporary file, which is then saved as by SaveFile.
 func (m *Manager) SavePhotoWithMetadata(r io.Reader, shortcode string, node *instagram.Node) error {
-	filename := filepath.Join(m.outputDir, fmt.Sprintf("%s.jpg", shortcode))
-	
-	// Create temporary file first
-	tempFile := filename + ".tmp"
+	tempFile := filepath.Join(m.outputDir, m.fileName(shortcode, node)) + ".tmp"
+	if err := os.MkdirAll(filepath.Dir(tempFile), 0755); err != nil {
+		return fmt.Errorf("failed to create photo folder: %w", err)
+	}
 	out, err := os.Create(tempFile)
 	if err != nil {
 		return fmt.Errorf("failed to create temporary file: %w", err)
 	}
 	
-	// Copy data and get file size
-	size, err := io.Copy(out, r)
+	_, err = io.Copy(out, r)
 	closeErr := out.Close()
 	
 	if err != nil {
@@ -208,28 +224,345 @@ func (m *Manager) SavePhotoWithMetadata(r io.Reader, shortcode string, node *ins
 		return fmt.Errorf("failed to close file: %w", closeErr)
 	}
 	
-	// Atomic rename
-	if err := os.Rename(tempFile, filename); err != nil {
-		os.Remove(tempFile)
-		return fmt.Errorf("failed to rename temporary file: %w", err)
+	return m.SaveFile(tempFile, shortcode, node)
+}
+
+// SaveFile saves a photo or video that was downloaded into the file at path,
+// which must be in the output directory, and records its metadata. The file
+// is renamed into place rather than copied, so the media is never held in
+// memory. Photos go through the post-processing pipeline first, which may
+// change their extension. Only photos whose post details are embedded are
+// read whole; videos are neither processed, embedded into nor
+// deduplicated. The file at path is gone afterwards, whether or not saving
+// succeeded.
+func (m *Manager) SaveFile(path, shortcode string, node *instagram.Node) error {
+	name := m.fileName(shortcode, node)
+	
+	fail := func(format string, err error) error {
+		os.Remove(path)
+		return fmt.Errorf(format, err)
+	}
+	
+	var duplicateOf string
+	video := node != nil && node.IsVideo
+	if !video && !m.postProcess.Empty() {
+		// A photo the pipeline cannot process is saved as far as it got
+		ext, err := m.postProcess.Run(path)
+		if err != nil {
+			m.logger.WithError(err).WithField("shortcode", shortcode).Warn("Failed to post-process photo")
+		}
+		if ext != "" {
+			name = strings.TrimSuffix(name, filepath.Ext(name)) + ext
+		}
+	}
+	filename := filepath.Join(m.outputDir, name)
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return fail("failed to create photo folder: %w", err)
+	}
+	if !video && m.embedMetadata && node != nil {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fail("failed to read photo data: %w", err)
+		}
+		if embedded := m.embedImageMetadata(data, shortcode, node); !bytes.Equal(embedded, data) {
+			if err := os.WriteFile(path, embedded, 0644); err != nil {
+				return fail("failed to embed metadata: %w", err)
+			}
+		}
+	}
+	
+	// Hash what is written, so files with different embedded metadata are
+	// never shared and later changes to the file can be detected
+	sum, size, err := HashFile(path)
+	if err != nil {
+		return fail("failed to hash photo: %w", err)
+	}
+	if !video && m.hashIndex != nil {
+		if existing, ok := m.hashIndex.Lookup(sum, size); ok && existing != filename {
+			duplicateOf = existing
+		}
+	}
+	
+	// The file holding the content, and its metadata if node data is
+	// provided
+	content := name
+	if duplicateOf != "" {
+		if rel, err := filepath.Rel(m.outputDir, duplicateOf); err == nil {
+			content = filepath.ToSlash(rel)
+		} else {
+			content = duplicateOf
+		}
+	}
+	var meta *metadata.PhotoMetadata
+	if node != nil {
+		meta = metadata.FromInstagramNode(node, size)
+		meta.File = name
+		meta.SHA256 = sum
+		if duplicateOf != "" {
+			meta.DuplicateOf = content
+		}
+		m.mu.RLock()
+		meta.ScrapeID = m.scrapeID
+		m.mu.RUnlock()
+	}
+	
+	// Logged before the file is moved into place, so a crash from here on
+	// is recovered by the next scrape
+	if m.journal != nil {
+		if err := m.journal.Log(JournalEntry{Shortcode: shortcode, File: content, Size: size, SHA256: sum, Photo: meta}); err != nil {
+			return fail("failed to save photo data: %w", err)
+		}
 	}
 	
-	// Add metadata to collection if node data is provided
-	if node != nil && m.userMetadata != nil {
-		meta := metadata.FromInstagramNode(node, size)
+	if duplicateOf != "" {
+		os.Remove(path)
+		size = m.saveDuplicate(size, shortcode, filename, duplicateOf)
+	} else {
+		// Temporary files may be private to the process that created them
+		if err := os.Chmod(path, 0644); err != nil {
+			return fail("failed to save photo data: %w", err)
+		}
+		
+		// Atomic rename
+		if err := os.Rename(path, filename); err != nil {
+			return fail("failed to rename temporary file: %w", err)
+		}
+		
+		if !video && m.hashIndex != nil {
+			m.hashIndex.Add(sum, filename, size)
+		}
+	}
+	if !video {
+		// A duplicate that could not be linked is only at duplicateOf
+		src := filename
+		if duplicateOf != "" {
+			src = duplicateOf
+		}
+		m.saveGalleryThumbnail(shortcode, src, name)
+	}
+	
+	// Add metadata to collection
+	if meta != nil {
 		m.mu.Lock()
-		m.userMetadata.AddPhoto(*meta)
+		if m.userMetadata != nil {
+			m.userMetadata.AddPhoto(*meta)
+		}
 		m.mu.Unlock()
 	}
 	
 	// Update downloaded map
+	m.downloadedPhotos.Set(shortcode, name)
 	m.mu.Lock()
-	m.downloadedPhotos[shortcode] = true
+	m.digests[shortcode] = fileDigest{size: size, sum: sum}
 	m.mu.Unlock()
 	
 	return nil
 }
 
+// ThumbnailFolder is the folder inside the output directory that holds the
+// cover images of videos
+const ThumbnailFolder = "thumbnails"
+
+// SaveThumbnail saves the cover image of a video under the thumbnail folder,
+// named like the video, and records it in the video's metadata
+func (m *Manager) SaveThumbnail(r io.Reader, shortcode string, node *instagram.Node) error {
+	name := m.fileName(shortcode, node)
+	name = filepath.Join(ThumbnailFolder, strings.TrimSuffix(name, filepath.Ext(name))+".jpg")
+	filename := filepath.Join(m.outputDir, name)
+	
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return fmt.Errorf("failed to create thumbnail folder: %w", err)
+	}
+	
+	tempFile := filename + ".tmp"
+	out, err := os.Create(tempFile)
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	_, err = io.Copy(out, r)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tempFile, filename)
+	}
+	if err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("failed to save thumbnail: %w", err)
+	}
+	
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.userMetadata != nil {
+		for i := len(m.userMetadata.Photos) - 1; i >= 0; i-- {
+			if m.userMetadata.Photos[i].Shortcode == shortcode {
+				m.userMetadata.Photos[i].Thumbnail = filepath.ToSlash(name)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// GalleryFolder is the folder inside the output directory that holds the
+// small copies of photos made for gallery browsing. Its leading dot keeps it
+// out of the scan for downloaded photos.
+const GalleryFolder = ".thumbnails"
+
+// SetGalleryThumbnails makes a JPEG copy of every photo saved from now on,
+// at most size pixels on its longer side, under the gallery folder and named
+// like the photo. A size of 0 turns it off.
+func (m *Manager) SetGalleryThumbnails(size int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.galleryThumbs = max(size, 0)
+}
+
+// saveGalleryThumbnail makes the gallery thumbnail of the photo saved as
+// name, whose content is in the file at path, if they are on. A photo the thumbnail cannot be made of, such as one
+// converted to WebP, is saved without one.
+func (m *Manager) saveGalleryThumbnail(shortcode, path, name string) {
+	m.mu.RLock()
+	size := m.galleryThumbs
+	m.mu.RUnlock()
+	if size == 0 {
+		return
+	}
+	thumb := filepath.Join(m.outputDir, GalleryFolder, strings.TrimSuffix(name, filepath.Ext(name))+".jpg")
+	if err := postprocess.Thumbnail(path, thumb, size); err != nil {
+		m.logger.WithError(err).WithField("shortcode", shortcode).Warn("Failed to make gallery thumbnail")
+	}
+}
+
+// fileName returns the name the photo is saved under. Without the post's
+// details only the shortcode is known, so the default layout is used.
+func (m *Manager) fileName(shortcode string, node *instagram.Node) string {
+	if node == nil {
+		return defaultLayout.FileName(&metadata.PhotoMetadata{Shortcode: shortcode})
+	}
+	photo := metadata.FromInstagramNode(node, 0)
+	photo.Shortcode = shortcode
+	return m.layout.FileName(photo)
+}
+
+// SetLayout names new photos after the layout's pattern and rescans the
+// output directory for photos already saved under it
+func (m *Manager) SetLayout(layout *Layout) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	m.layout = layout
+	return m.scanExistingFiles()
+}
+
+// FileName returns the name of a downloaded photo's file, or an empty string
+// if the photo has not been downloaded
+func (m *Manager) FileName(shortcode string) string {
+	return m.downloadedPhotos.Get(shortcode)
+}
+
+// FileDigest returns the size and hex SHA-256 of the file SaveFile saved for
+// a shortcode, so it can be recorded elsewhere, such as in the checkpoint.
+// ok is false if no file was saved for it by this manager.
+func (m *Manager) FileDigest(shortcode string) (size int64, sum string, ok bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	digest, ok := m.digests[shortcode]
+	return digest.size, digest.sum, ok
+}
+
+// saveDuplicate stores a photo whose content is already saved at existing by
+// hardlinking to it. If the filesystem cannot link the files, the photo is
+// not written at all. It returns the size of the photo.
+func (m *Manager) saveDuplicate(size int64, shortcode, filename, existing string) int64 {
+	tempFile := filename + ".tmp"
+	os.Remove(tempFile)
+	
+	action := "hardlinked"
+	if err := os.Link(existing, tempFile); err != nil {
+		action = "skipped"
+		m.logger.WithError(err).WithField("shortcode", shortcode).Debug("Failed to hardlink duplicate photo")
+	} else if err := os.Rename(tempFile, filename); err != nil {
+		os.Remove(tempFile)
+		action = "skipped"
+		m.logger.WithError(err).WithField("shortcode", shortcode).Debug("Failed to rename hardlinked photo")
+	}
+	
+	m.mu.Lock()
+	m.dedupStats.Files++
+	m.dedupStats.BytesSaved += size
+	if action == "hardlinked" {
+		m.dedupStats.Linked++
+	}
+	m.mu.Unlock()
+	
+	m.logger.WithFields(map[string]interface{}{
+		"shortcode":    shortcode,
+		"duplicate_of": existing,
+		"action":       action,
+		"bytes_saved":  size,
+	}).Info("Duplicate photo content")
+	
+	return size
+}
+
+// SetHashIndex enables content-addressable deduplication: photos whose bytes
+// match a file already in the index are hardlinked to it instead of being
+// written again. A nil index disables deduplication.
+func (m *Manager) SetHashIndex(idx *HashIndex) {
+	m.hashIndex = idx
+}
+
+// DedupStats returns what deduplication has saved so far
+func (m *Manager) DedupStats() DedupStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.dedupStats
+}
+
+// SetPostProcess runs the pipeline over every photo before it is saved.
+// Post details are embedded and duplicates detected after it has run.
+func (m *Manager) SetPostProcess(p *postprocess.Pipeline) {
+	m.postProcess = p
+}
+
+// SetScrapeID records id in the metadata of the user and of the photos saved
+// from now on, to match them with the log lines of the scrape
+func (m *Manager) SetScrapeID(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.scrapeID = id
+	if m.userMetadata != nil {
+		m.userMetadata.ScrapeID = id
+	}
+}
+
+// SetEmbedMetadata controls whether SavePhotoWithMetadata writes the post's
+// caption, author, URL and date into the photo's EXIF and XMP metadata
+func (m *Manager) SetEmbedMetadata(enabled bool) {
+	m.embedMetadata = enabled
+}
+
+// embedImageMetadata returns the photo with the post details embedded, or the
+// photo unchanged if it cannot carry them
+func (m *Manager) embedImageMetadata(data []byte, shortcode string, node *instagram.Node) []byte {
+	meta := ImageMetadata{
+		URL:     instagram.GetPostURL(shortcode),
+		Author:  node.Owner.Username,
+		TakenAt: node.TakenAt(),
+	}
+	if len(node.EdgeMediaToCaption.Edges) > 0 {
+		meta.Caption = node.EdgeMediaToCaption.Edges[0].Node.Text
+	}
+	
+	embedded, err := EmbedMetadata(data, meta)
+	if err != nil {
+		m.logger.WithError(err).WithField("shortcode", shortcode).Debug("Saving photo without embedded metadata")
+		return data
+	}
+	return embedded
+}
+
 // GetOutputDir returns the output directory path
 func (m *Manager) GetOutputDir() string {
 	return m.outputDir
@@ -237,12 +570,13 @@ func (m *Manager) GetOutputDir() string {
 
 // GetDownloadedCount returns the number of downloaded photos
 func (m *Manager) GetDownloadedCount() int {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-	return len(m.downloadedPhotos)
+	return m.downloadedPhotos.Len()
 }
 
-// InitializeUserMetadata initializes the metadata collection for a user
+// InitializeUserMetadata initializes the metadata collection for a user.
+// metadata.json covers the whole archive, so the photos and failures an
+// earlier run recorded in the output directory are carried over; photos
+// saved again replace their records.
 func (m *Manager) InitializeUserMetadata(username, userID string, totalPhotos int) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -252,11 +586,39 @@ func (m *Manager) InitializeUserMetadata(username, userID string, totalPhotos in
 		UserID:           userID,
 		TotalPhotos:      totalPhotos,
 		DownloadStarted:  time.Now(),
+		FileNamePattern:  m.layout.Pattern(),
+		ScrapeID:         m.scrapeID,
 		Photos:           make([]metadata.PhotoMetadata, 0),
 	}
+	previous, err := metadata.LoadUserMetadata(m.outputDir)
+	if err != nil {
+		m.logger.WithError(err).Warn("Failed to read earlier metadata, recording this run only")
+	} else if previous != nil {
+		m.userMetadata.Photos = append(m.userMetadata.Photos, previous.Photos...)
+		m.userMetadata.Failures = previous.Failures
+		m.userMetadata.DownloadCompleted = previous.DownloadCompleted
+		m.userMetadata.SyncedPosts = previous.SyncedPosts
+	}
+	for _, photo := range m.recovered {
+		m.userMetadata.AddPhoto(photo)
+	}
+}
+
+// MarkSyncComplete records that this run went through every post of the
+// profile, which had posts posts. Saved afterwards, metadata.json tells the
+// next run when the archive was last complete and how many posts it covered.
+func (m *Manager) MarkSyncComplete(posts int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	if m.userMetadata != nil {
+		m.userMetadata.DownloadCompleted = time.Now()
+		m.userMetadata.SyncedPosts = max(posts, 0)
+	}
 }
 
-// SaveUserMetadata saves all collected metadata to a single JSON file
+// SaveUserMetadata saves all collected metadata to a single JSON file. The
+// journal, if one is open, is cleared once the file is saved.
 func (m *Manager) SaveUserMetadata() error {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -265,7 +627,137 @@ func (m *Manager) SaveUserMetadata() error {
 		return nil // Nothing to save
 	}
 	
-	return m.userMetadata.Save(m.outputDir)
+	if err := m.userMetadata.Save(m.outputDir); err != nil {
+		return err
+	}
+	if m.journal != nil {
+		return m.journal.Clear()
+	}
+	return nil
+}
+
+// OpenJournal starts logging the files saved from now on to the output
+// directory's journal, after recovering what an interrupted scrape left in
+// it. Logged files that are in place with the recorded hash count as
+// downloaded, and their metadata is added to the collection started by
+// InitializeUserMetadata; the others were never moved into place and are
+// dropped. It returns the recovered entries, so the caller can record those
+// not committed in the checkpoint and commit them.
+func (m *Manager) OpenJournal() ([]JournalEntry, error) {
+	journal, entries, err := OpenJournal(m.outputDir)
+	if err != nil {
+		return nil, err
+	}
+	
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	var recovered []JournalEntry
+	for _, entry := range entries {
+		sum, size, err := HashFile(filepath.Join(m.outputDir, filepath.FromSlash(entry.File)))
+		if err != nil || sum != entry.SHA256 || size != entry.Size {
+			m.logger.WithField("shortcode", entry.Shortcode).Warn("Dropping interrupted download, its file was not saved")
+			continue
+		}
+		name := entry.File
+		if entry.Photo != nil {
+			name = entry.Photo.File
+			m.recovered = append(m.recovered, *entry.Photo)
+			if m.userMetadata != nil {
+				m.userMetadata.AddPhoto(*entry.Photo)
+			}
+		}
+		m.downloadedPhotos.Set(entry.Shortcode, name)
+		m.digests[entry.Shortcode] = fileDigest{size: size, sum: sum}
+		recovered = append(recovered, entry)
+	}
+	m.journal = journal
+	
+	if len(entries) > 0 {
+		m.logger.WithFields(map[string]interface{}{
+			"recovered": len(recovered),
+			"dropped":   len(entries) - len(recovered),
+		}).Info("Recovered downloads from journal")
+	}
+	return recovered, nil
+}
+
+// CommitDownload records in the journal that a download saved by SaveFile is
+// in the checkpoint. It does nothing if no journal is open.
+func (m *Manager) CommitDownload(shortcode string) error {
+	if m.journal == nil {
+		return nil
+	}
+	return m.journal.Commit(shortcode)
+}
+
+// CloseJournal closes the journal, keeping what it logged if metadata.json
+// was not saved
+func (m *Manager) CloseJournal() error {
+	if m.journal == nil {
+		return nil
+	}
+	return m.journal.Close()
+}
+
+// RecordFailure adds a permanently failed post to the user metadata
+func (m *Manager) RecordFailure(failure metadata.PostFailure) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	if m.userMetadata == nil {
+		return
+	}
+	m.userMetadata.AddFailure(failure)
+}
+
+// RecordFailedDownload lists a download that failed every attempt in
+// failed.json, replacing any earlier record for the same shortcode
+func (m *Manager) RecordFailedDownload(download metadata.FailedDownload) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failedDownloads().Add(download)
+}
+
+// ClearFailedDownload drops shortcode from failed.json, once it is saved or
+// known to be permanently unavailable
+func (m *Manager) ClearFailedDownload(shortcode string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failedDownloads().Remove(shortcode)
+}
+
+// FailedDownloadCount returns the number of downloads listed in failed.json
+func (m *Manager) FailedDownloadCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.failedDownloads().Downloads)
+}
+
+// SaveFailedDownloads writes failed.json, or removes it when no download is
+// left failed. It does nothing if no download was recorded or cleared.
+func (m *Manager) SaveFailedDownloads() error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	
+	if m.failed == nil {
+		return nil
+	}
+	return m.failed.Save(m.outputDir)
+}
+
+// failedDownloads returns the failed downloads of the output directory,
+// reading them on first use. The caller holds m.mu.
+func (m *Manager) failedDownloads() *metadata.FailedDownloads {
+	if m.failed == nil {
+		failed, err := metadata.LoadFailedDownloads(m.outputDir)
+		if err != nil {
+			m.logger.WithError(err).Warn("Failed to read failed downloads, starting a new list")
+			failed = &metadata.FailedDownloads{}
+		}
+		m.failed = failed
+	}
+	return m.failed
 }
 
 // GetUserMetadata returns the collected user metadata
@@ -2,9 +2,16 @@ package storage
 
 import (
 	"bytes"
+	"image"
+	"image/jpeg"
 	"os"
 	"path/filepath"
+	"runtime"
 	"testing"
+
+	"igscraper/pkg/instagram"
+	"igscraper/pkg/metadata"
+	"igscraper/pkg/postprocess"
 )
 
 func TestManager(t *testing.T) {
@@ -82,4 +89,221 @@ func TestManager(t *testing.T) {
 	if !manager2.IsDownloaded("manual456") {
 		t.Error("Expected manually created file to be detected")
 	}
-}
\ No newline at end of file
+}
+func TestManagerRecordFailure(t *testing.T) {
+	tempDir := t.TempDir()
+
+	manager, err := NewManager(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	// Failures before metadata is initialized are ignored
+	manager.RecordFailure(metadata.PostFailure{Shortcode: "early", Reason: metadata.FailureNotFound})
+
+	manager.InitializeUserMetadata("testuser", "42", 3)
+	manager.RecordFailure(metadata.PostFailure{Shortcode: "gone123", Reason: metadata.FailureNotFound, StatusCode: 404})
+	manager.RecordFailure(metadata.PostFailure{Shortcode: "gone123", Reason: metadata.FailureBlocked, StatusCode: 403})
+
+	if err := manager.SaveUserMetadata(); err != nil {
+		t.Fatalf("Failed to save metadata: %v", err)
+	}
+
+	saved, err := metadata.LoadUserMetadata(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to load metadata: %v", err)
+	}
+	if len(saved.Failures) != 1 {
+		t.Fatalf("Expected 1 failure, got %d", len(saved.Failures))
+	}
+	if saved.Failures[0].Reason != metadata.FailureBlocked {
+		t.Errorf("Expected latest failure reason to win, got %s", saved.Failures[0].Reason)
+	}
+	if saved.DownloadedPhotos != 0 {
+		t.Errorf("Failures should not count as downloads, got %d", saved.DownloadedPhotos)
+	}
+}
+
+func TestManagerSaveFile(t *testing.T) {
+	tempDir := t.TempDir()
+
+	manager, err := NewManager(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	manager.InitializeUserMetadata("testuser", "42", 2)
+	manager.SetScrapeID("3f9a1c0e7b24d5a8")
+	idx, err := LoadHashIndex(filepath.Join(tempDir, HashIndexFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager.SetHashIndex(idx)
+
+	download := func(data string) string {
+		file, err := os.CreateTemp(tempDir, ".download-*.part")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		if _, err := file.WriteString(data); err != nil {
+			t.Fatal(err)
+		}
+		return file.Name()
+	}
+
+	// The downloaded file is moved into place
+	path := download("streamed photo")
+	if err := manager.SaveFile(path, "first", &instagram.Node{Shortcode: "first", RequestID: "9c1e04b7a2f3"}); err != nil {
+		t.Fatalf("Failed to save file: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Expected the temporary file to be gone")
+	}
+	info, err := os.Stat(filepath.Join(tempDir, "first.jpg"))
+	if err != nil {
+		t.Fatalf("Expected saved photo: %v", err)
+	}
+	if info.Mode().Perm() != 0644 {
+		t.Errorf("Expected mode 0644, got %v", info.Mode().Perm())
+	}
+
+	// Identical content is still deduplicated
+	path = download("streamed photo")
+	if err := manager.SaveFile(path, "second", &instagram.Node{Shortcode: "second"}); err != nil {
+		t.Fatalf("Failed to save duplicate: %v", err)
+	}
+	if stats := manager.DedupStats(); stats.Files != 1 {
+		t.Errorf("Expected 1 duplicate, got %+v", stats)
+	}
+
+	userMeta := manager.GetUserMetadata()
+	photos := userMeta.Photos
+	if len(photos) != 2 || photos[0].FileSize != int64(len("streamed photo")) || photos[1].DuplicateOf != "first.jpg" {
+		t.Errorf("Unexpected metadata: %+v", photos)
+	}
+	if userMeta.ScrapeID != "3f9a1c0e7b24d5a8" || photos[0].ScrapeID != "3f9a1c0e7b24d5a8" || photos[0].RequestID != "9c1e04b7a2f3" {
+		t.Errorf("Metadata does not carry the scrape and request IDs: %+v", userMeta)
+	}
+	sum, _, err := HashFile(filepath.Join(tempDir, "first.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if photos[0].SHA256 != sum || photos[1].SHA256 != sum {
+		t.Errorf("Expected both photos to record hash %s, got %+v", sum, photos)
+	}
+	if size, digest, ok := manager.FileDigest("first"); !ok || size != int64(len("streamed photo")) || digest != sum {
+		t.Errorf("Unexpected file digest: %d %q %v", size, digest, ok)
+	}
+	if leftover, _ := filepath.Glob(filepath.Join(tempDir, ".download-*")); len(leftover) > 0 {
+		t.Errorf("Temporary files left behind: %v", leftover)
+	}
+}
+
+func TestManagerPostProcess(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake encoder is a shell script")
+	}
+	tempDir := t.TempDir()
+
+	// The fake encoder copies its input to the file after -o
+	encoder := filepath.Join(t.TempDir(), "fake-cwebp")
+	script := "#!/bin/sh\nwhile [ $# -gt 0 ]; do case $1 in -o) shift; out=$1;; -*) ;; *) in=$1;; esac; shift; done\ncp \"$in\" \"$out\"\n"
+	if err := os.WriteFile(encoder, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	pipeline, err := postprocess.New([]postprocess.StepConfig{
+		{Step: postprocess.StepStripMetadata},
+		{Step: postprocess.StepConvert, Format: "webp", Command: encoder},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manager, err := NewManager(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	manager.InitializeUserMetadata("testuser", "42", 1)
+	manager.SetPostProcess(pipeline)
+
+	var photo bytes.Buffer
+	if err := jpeg.Encode(&photo, image.NewGray(image.Rect(0, 0, 4, 4)), nil); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(tempDir, "first.jpg.tmp")
+	if err := os.WriteFile(path, photo.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.SaveFile(path, "first", &instagram.Node{Shortcode: "first"}); err != nil {
+		t.Fatalf("Failed to save file: %v", err)
+	}
+
+	// The converted photo is saved under its new extension
+	if _, err := os.Stat(filepath.Join(tempDir, "first.webp")); err != nil {
+		t.Fatalf("Expected converted photo: %v", err)
+	}
+	if photos := manager.GetUserMetadata().Photos; len(photos) != 1 || photos[0].File != "first.webp" {
+		t.Errorf("Unexpected metadata: %+v", photos)
+	}
+	if err := manager.SaveUserMetadata(); err != nil {
+		t.Fatal(err)
+	}
+
+	// and recognised as downloaded by later runs
+	rescanned, err := NewManager(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rescanned.FileName("first") != "first.webp" {
+		t.Errorf("Expected first.webp to be found, got %q", rescanned.FileName("first"))
+	}
+}
+
+func TestManagerGalleryThumbnails(t *testing.T) {
+	tempDir := t.TempDir()
+	manager, err := NewManager(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	manager.SetGalleryThumbnails(16)
+
+	var photo bytes.Buffer
+	if err := jpeg.Encode(&photo, image.NewGray(image.Rect(0, 0, 64, 32)), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.SavePhotoWithMetadata(bytes.NewReader(photo.Bytes()), "first", &instagram.Node{Shortcode: "first"}); err != nil {
+		t.Fatalf("Failed to save photo: %v", err)
+	}
+	video := filepath.Join(tempDir, "clip.mp4.tmp")
+	if err := os.WriteFile(video, []byte("video"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.SaveFile(video, "clip", &instagram.Node{Shortcode: "clip", IsVideo: true}); err != nil {
+		t.Fatalf("Failed to save video: %v", err)
+	}
+
+	f, err := os.Open(filepath.Join(tempDir, GalleryFolder, "first.jpg"))
+	if err != nil {
+		t.Fatalf("Expected a gallery thumbnail: %v", err)
+	}
+	defer f.Close()
+	cfg, err := jpeg.DecodeConfig(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Width != 16 || cfg.Height != 8 {
+		t.Errorf("Expected a 16x8 thumbnail, got %dx%d", cfg.Width, cfg.Height)
+	}
+	if entries, _ := os.ReadDir(filepath.Join(tempDir, GalleryFolder)); len(entries) != 1 {
+		t.Errorf("Expected only the photo's thumbnail, got %v", entries)
+	}
+
+	// Thumbnails are not mistaken for downloaded photos
+	rescanned, err := NewManager(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := rescanned.GetDownloadedCount(); count != 2 {
+		t.Errorf("Expected 2 downloaded files, got %d", count)
+	}
+}
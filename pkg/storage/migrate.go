@@ -0,0 +1,208 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"igscraper/pkg/metadata"
+)
+
+// MigrationResult describes how MigrateLayout renamed an archive's photos
+type MigrationResult struct {
+	Username  string            // owner of the archive, from metadata.json
+	Renamed   map[string]string // shortcode -> new file name
+	Unchanged int               // photos already named after the layout
+	Missing   []string          // shortcodes in metadata.json without a file
+}
+
+// rename moves a file from one path to another
+type rename struct {
+	from, to string
+}
+
+// MigrateLayout renames the photos of the archive in dir to the layout's
+// pattern, using the post details stored in metadata.json. metadata.json
+// records the new names and pattern, and if index is not nil its paths are
+// updated so deduplication keeps finding the renamed files; the caller
+// saves it. With dryRun nothing is changed and the result shows what would
+// be renamed.
+func MigrateLayout(dir string, to *Layout, index *HashIndex, dryRun bool) (*MigrationResult, error) {
+	meta, err := metadata.LoadUserMetadata(dir)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		return nil, fmt.Errorf("no metadata.json in %s", dir)
+	}
+	from, err := ParseLayout(meta.FileNamePattern)
+	if err != nil {
+		from = defaultLayout
+	}
+
+	// A post recorded more than once is renamed after its latest metadata
+	latest := make(map[string]int)
+	for i, photo := range meta.Photos {
+		latest[photo.Shortcode] = i
+	}
+	shortcodes := make([]string, 0, len(latest))
+	for shortcode := range latest {
+		shortcodes = append(shortcodes, shortcode)
+	}
+	sort.Strings(shortcodes)
+
+	result := &MigrationResult{Username: meta.Username, Renamed: make(map[string]string)}
+	current := make(map[string]string)
+	var moves []rename
+	targets := make(map[string]string)
+	for _, shortcode := range shortcodes {
+		photo := &meta.Photos[latest[shortcode]]
+		name := existingFileName(dir, photo, from)
+		if name == "" {
+			result.Missing = append(result.Missing, shortcode)
+			continue
+		}
+		current[shortcode] = name
+
+		target := to.FileName(photo)
+		if other, ok := targets[target]; ok {
+			return nil, fmt.Errorf("%s and %s would both be named %s", other, shortcode, target)
+		}
+		targets[target] = shortcode
+		if target == name {
+			result.Unchanged++
+			continue
+		}
+		result.Renamed[shortcode] = target
+		moves = append(moves, rename{from: name, to: target})
+	}
+
+	// Renaming into the name of another renamed photo is fine; overwriting
+	// anything else is not
+	sources := make(map[string]bool, len(moves))
+	for _, move := range moves {
+		sources[move.from] = true
+	}
+	for _, move := range moves {
+		if sources[move.to] {
+			continue
+		}
+		if _, err := os.Lstat(filepath.Join(dir, move.to)); err == nil {
+			return nil, fmt.Errorf("cannot rename %s: %s already exists", move.from, move.to)
+		}
+	}
+
+	if dryRun || len(moves) == 0 && meta.FileNamePattern == to.Pattern() {
+		return result, nil
+	}
+
+	if err := renameFiles(dir, moves); err != nil {
+		return nil, err
+	}
+
+	// Record the new names, including those of files that kept theirs
+	renamed := make(map[string]string, len(moves))
+	for _, move := range moves {
+		renamed[move.from] = move.to
+	}
+	for i := range meta.Photos {
+		photo := &meta.Photos[i]
+		if name, ok := current[photo.Shortcode]; ok {
+			photo.File = name
+			if target, ok := renamed[name]; ok {
+				photo.File = target
+			}
+		}
+		if target, ok := renamed[photo.DuplicateOf]; ok {
+			photo.DuplicateOf = target
+		}
+	}
+	meta.FileNamePattern = to.Pattern()
+	if err := meta.Save(dir); err != nil {
+		return nil, err
+	}
+
+	if index != nil {
+		for _, move := range moves {
+			index.Rename(filepath.Join(dir, move.from), filepath.Join(dir, move.to))
+		}
+	}
+	return result, nil
+}
+
+// existingFileName returns the name of the photo's file in dir, looking for
+// the name metadata.json recorded and the names the archive's layout and the
+// default layout give it, or an empty string if there is none
+func existingFileName(dir string, photo *metadata.PhotoMetadata, layout *Layout) string {
+	for _, name := range []string{photo.File, layout.FileName(photo), defaultLayout.FileName(photo)} {
+		if name == "" {
+			continue
+		}
+		if info, err := os.Stat(filepath.Join(dir, name)); err == nil && !info.IsDir() {
+			return name
+		}
+	}
+	return ""
+}
+
+// renameFiles performs the moves in dir. Files are first moved to temporary
+// names so photos can swap names. If a move fails, the moves already made
+// are undone.
+func renameFiles(dir string, moves []rename) error {
+	toTemp := make([]rename, len(moves))
+	toTarget := make([]rename, len(moves))
+	for i, move := range moves {
+		temp := filepath.Join(dir, move.from+".migrating")
+		toTemp[i] = rename{from: filepath.Join(dir, move.from), to: temp}
+		toTarget[i] = rename{from: temp, to: filepath.Join(dir, move.to)}
+	}
+
+	for _, move := range toTarget {
+		if err := os.MkdirAll(filepath.Dir(move.to), 0755); err != nil {
+			return fmt.Errorf("failed to create folder for %s: %w", filepath.Base(move.to), err)
+		}
+	}
+	if err := renameAll(toTemp); err != nil {
+		return err
+	}
+	if err := renameAll(toTarget); err != nil {
+		undoRenames(toTemp)
+		return err
+	}
+	for _, move := range moves {
+		removeEmptyFolders(dir, filepath.Dir(filepath.Join(dir, move.from)))
+	}
+	return nil
+}
+
+// removeEmptyFolders removes folder and its parents up to dir while they
+// are empty, so moving photos out of a nested layout leaves no empty folders
+func removeEmptyFolders(dir, folder string) {
+	dir = filepath.Clean(dir)
+	for folder != dir && strings.HasPrefix(folder, dir+string(filepath.Separator)) {
+		if os.Remove(folder) != nil {
+			return
+		}
+		folder = filepath.Dir(folder)
+	}
+}
+
+// renameAll performs the moves in order, undoing them if one fails
+func renameAll(moves []rename) error {
+	for i, move := range moves {
+		if err := os.Rename(move.from, move.to); err != nil {
+			undoRenames(moves[:i])
+			return fmt.Errorf("failed to rename %s: %w", filepath.Base(move.from), err)
+		}
+	}
+	return nil
+}
+
+// undoRenames moves files back to where they were, last move first
+func undoRenames(moves []rename) {
+	for i := len(moves) - 1; i >= 0; i-- {
+		os.Rename(moves[i].to, moves[i].from)
+	}
+}
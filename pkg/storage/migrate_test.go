@@ -0,0 +1,211 @@
+package storage
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"igscraper/pkg/instagram"
+	"igscraper/pkg/metadata"
+)
+
+// writeArchive creates an archive in the default layout with a photo per
+// shortcode, each taken a day after the previous one
+func writeArchive(t *testing.T, dir string, shortcodes ...string) *metadata.UserMetadata {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	meta := &metadata.UserMetadata{Username: "testuser"}
+	for i, shortcode := range shortcodes {
+		if err := os.WriteFile(filepath.Join(dir, shortcode+".jpg"), []byte(shortcode), 0644); err != nil {
+			t.Fatal(err)
+		}
+		meta.Photos = append(meta.Photos, metadata.PhotoMetadata{
+			Shortcode: shortcode,
+			TakenAt:   time.Date(2024, 1, i+1, 12, 0, 0, 0, time.UTC),
+		})
+	}
+	if err := meta.Save(dir); err != nil {
+		t.Fatal(err)
+	}
+	return meta
+}
+
+func TestMigrateLayout(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "testuser")
+	writeArchive(t, dir, "AAA", "BBB")
+
+	// BBB was deduplicated against AAA
+	meta, _ := metadata.LoadUserMetadata(dir)
+	meta.Photos[1].DuplicateOf = "AAA.jpg"
+	meta.Photos = append(meta.Photos, metadata.PhotoMetadata{Shortcode: "GONE"})
+	if err := meta.Save(dir); err != nil {
+		t.Fatal(err)
+	}
+	index, _ := LoadHashIndex(filepath.Join(root, HashIndexFile))
+	index.Add(HashContent([]byte("AAA")), filepath.Join(dir, "AAA.jpg"), 3)
+
+	layout, _ := ParseLayout("{date_taken}_{shortcode}.{ext}")
+
+	// A dry run changes nothing
+	result, err := MigrateLayout(dir, layout, index, true)
+	if err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	if len(result.Renamed) != 2 || len(result.Missing) != 1 || result.Missing[0] != "GONE" {
+		t.Errorf("Unexpected dry run result: %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "AAA.jpg")); err != nil {
+		t.Error("Dry run renamed a file")
+	}
+
+	result, err = MigrateLayout(dir, layout, index, false)
+	if err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+	if result.Renamed["AAA"] != "2024-01-01_AAA.jpg" || result.Renamed["BBB"] != "2024-01-02_BBB.jpg" {
+		t.Errorf("Unexpected renames: %v", result.Renamed)
+	}
+	for _, name := range []string{"2024-01-01_AAA.jpg", "2024-01-02_BBB.jpg"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("Expected %s to exist", name)
+		}
+	}
+
+	saved, _ := metadata.LoadUserMetadata(dir)
+	if saved.FileNamePattern != layout.Pattern() {
+		t.Errorf("FileNamePattern = %q", saved.FileNamePattern)
+	}
+	if saved.Photos[0].File != "2024-01-01_AAA.jpg" || saved.Photos[1].DuplicateOf != "2024-01-01_AAA.jpg" {
+		t.Errorf("Metadata not updated: %+v", saved.Photos[:2])
+	}
+	if path, ok := index.Lookup(HashContent([]byte("AAA")), 3); !ok || filepath.Base(path) != "2024-01-01_AAA.jpg" {
+		t.Errorf("Index not updated: %q %v", path, ok)
+	}
+
+	// The renamed photos are still known, whatever layout is configured
+	manager, err := NewManager(dir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	if !manager.IsDownloaded("AAA") || !manager.IsDownloaded("BBB") {
+		t.Error("Expected renamed photos to be detected")
+	}
+	if manager.GetDownloadedCount() != 2 {
+		t.Errorf("Expected 2 photos, got %d", manager.GetDownloadedCount())
+	}
+
+	// Migrating back restores the original names
+	result, err = MigrateLayout(dir, defaultLayout, nil, false)
+	if err != nil {
+		t.Fatalf("Migration back failed: %v", err)
+	}
+	if len(result.Renamed) != 2 {
+		t.Errorf("Expected 2 renames back, got %v", result.Renamed)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "AAA.jpg")); err != nil {
+		t.Error("Expected AAA.jpg to be restored")
+	}
+}
+
+func TestMigrateLayoutConflict(t *testing.T) {
+	dir := t.TempDir()
+	writeArchive(t, dir, "AAA")
+	if err := os.WriteFile(filepath.Join(dir, "2024-01-01_AAA.jpg"), []byte("other"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	layout, _ := ParseLayout("{date_taken}_{shortcode}.{ext}")
+	if _, err := MigrateLayout(dir, layout, nil, false); err == nil {
+		t.Fatal("Expected migration onto an existing file to fail")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "AAA.jpg")); err != nil {
+		t.Error("Failed migration should leave files in place")
+	}
+
+	if _, err := MigrateLayout(t.TempDir(), layout, nil, false); err == nil {
+		t.Error("Expected error without metadata.json")
+	}
+}
+
+func TestManagerLayout(t *testing.T) {
+	dir := t.TempDir()
+	writeArchive(t, dir, "OLD")
+
+	manager, err := NewManager(dir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	layout, _ := ParseLayout("{shortcode}_{id}.{ext}")
+	if err := manager.SetLayout(layout); err != nil {
+		t.Fatalf("Failed to set layout: %v", err)
+	}
+	if manager.FileName("OLD") != "OLD.jpg" {
+		t.Errorf("Expected photo in the previous layout to be kept, got %q", manager.FileName("OLD"))
+	}
+
+	manager.InitializeUserMetadata("testuser", "1", 2)
+	node := &instagram.Node{ID: "42", Shortcode: "NEW"}
+	if err := manager.SavePhotoWithMetadata(bytes.NewReader([]byte("new")), "NEW", node); err != nil {
+		t.Fatalf("Failed to save photo: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "NEW_42.jpg")); err != nil {
+		t.Error("Expected photo to be named after the layout")
+	}
+	if manager.FileName("NEW") != "NEW_42.jpg" {
+		t.Errorf("FileName = %q", manager.FileName("NEW"))
+	}
+	meta := manager.GetUserMetadata()
+	if len(meta.Photos) != 2 || meta.Photos[0].Shortcode != "OLD" {
+		t.Fatalf("Expected the earlier photo to be kept in metadata, got %+v", meta.Photos)
+	}
+	if meta.FileNamePattern != layout.Pattern() || meta.Photos[1].File != "NEW_42.jpg" {
+		t.Errorf("Layout not recorded in metadata: %q %q", meta.FileNamePattern, meta.Photos[1].File)
+	}
+}
+
+func TestMigrateToNestedLayout(t *testing.T) {
+	dir := t.TempDir()
+	writeArchive(t, dir, "AAA", "BBB")
+
+	layout, _ := ParseLayout("{year}/{month}/{shortcode}.{ext}")
+	if _, err := MigrateLayout(dir, layout, nil, false); err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "2024", "01", "AAA.jpg")); err != nil {
+		t.Errorf("Expected photo to be moved into its month folder: %v", err)
+	}
+
+	manager, err := NewManager(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.SetLayout(layout); err != nil {
+		t.Fatal(err)
+	}
+	if manager.FileName("BBB") != "2024/01/BBB.jpg" {
+		t.Errorf("FileName = %q", manager.FileName("BBB"))
+	}
+
+	// New photos are saved into their folder
+	manager.InitializeUserMetadata("testuser", "1", 1)
+	node := &instagram.Node{Shortcode: "NEW", TakenAtTimestamp: time.Date(2025, 6, 1, 12, 0, 0, 0, time.Local).Unix()}
+	if err := manager.SavePhotoWithMetadata(bytes.NewReader([]byte("new")), "NEW", node); err != nil {
+		t.Fatalf("Failed to save photo: %v", err)
+	}
+	if manager.FileName("NEW") != "2025/06/NEW.jpg" {
+		t.Errorf("FileName = %q", manager.FileName("NEW"))
+	}
+
+	// Moving back to a flat layout removes the emptied folders
+	if _, err := MigrateLayout(dir, defaultLayout, nil, false); err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "2024")); !os.IsNotExist(err) {
+		t.Errorf("Expected the emptied folder to be removed, got %v", err)
+	}
+}
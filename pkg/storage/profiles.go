@@ -0,0 +1,222 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ProfileIndexFile is the name of the profile index kept in the output
+// directory
+const ProfileIndexFile = ".igscraper-profiles.json"
+
+// profileIndexVersion is bumped when the file format changes
+const profileIndexVersion = 1
+
+// ProfileIndex maps the user IDs of archived profiles, which never change, to
+// their current username and the folder they are archived in, so an archive
+// follows a profile through username changes. Like the hash index, folders
+// are kept relative to the index's directory.
+type ProfileIndex struct {
+	path     string
+	root     string
+	mu       sync.Mutex
+	profiles map[string]ProfileEntry
+	dirty    bool
+}
+
+// ProfileEntry is one archived profile
+type ProfileEntry struct {
+	UserID   string   `json:"-"`
+	Username string   `json:"username"`
+	Folder   string   `json:"folder"`
+	Previous []string `json:"previous_usernames,omitempty"`
+}
+
+// profileIndexFile is the on-disk format of a ProfileIndex
+type profileIndexFile struct {
+	Version  int                     `json:"version"`
+	Profiles map[string]ProfileEntry `json:"profiles"`
+}
+
+// LoadProfileIndex reads the index at path, or returns an empty index if the
+// file does not exist yet
+func LoadProfileIndex(path string) (*ProfileIndex, error) {
+	idx := &ProfileIndex{
+		path:     path,
+		root:     filepath.Dir(path),
+		profiles: make(map[string]ProfileEntry),
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profile index: %w", err)
+	}
+
+	var file profileIndexFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse profile index: %w", err)
+	}
+	if file.Version != profileIndexVersion {
+		return nil, fmt.Errorf("unsupported profile index version %d", file.Version)
+	}
+	for userID, entry := range file.Profiles {
+		entry.UserID = userID
+		idx.profiles[userID] = entry
+	}
+	return idx, nil
+}
+
+// ByID returns the profile with the given user ID
+func (idx *ProfileIndex) ByID(userID string) (ProfileEntry, bool) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	entry, ok := idx.profiles[userID]
+	return entry, ok
+}
+
+// ByUsername returns the profile currently known by username
+func (idx *ProfileIndex) ByUsername(username string) (ProfileEntry, bool) {
+	return idx.find(func(entry ProfileEntry) bool {
+		return strings.EqualFold(entry.Username, username)
+	})
+}
+
+// ByPreviousUsername returns a profile that used to be known by username.
+// Usernames can be taken over by other accounts once released, so this only
+// says who had the name, not who has it now.
+func (idx *ProfileIndex) ByPreviousUsername(username string) (ProfileEntry, bool) {
+	return idx.find(func(entry ProfileEntry) bool {
+		return slices.ContainsFunc(entry.Previous, func(previous string) bool {
+			return strings.EqualFold(previous, username)
+		})
+	})
+}
+
+// ByFolder returns the profile archived in dir
+func (idx *ProfileIndex) ByFolder(dir string) (ProfileEntry, bool) {
+	folder := idx.relative(dir)
+	return idx.find(func(entry ProfileEntry) bool {
+		return entry.Folder == folder
+	})
+}
+
+// find returns the first profile, by user ID, that match accepts
+func (idx *ProfileIndex) find(match func(ProfileEntry) bool) (ProfileEntry, bool) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	userIDs := make([]string, 0, len(idx.profiles))
+	for userID := range idx.profiles {
+		userIDs = append(userIDs, userID)
+	}
+	sort.Strings(userIDs)
+	for _, userID := range userIDs {
+		if entry := idx.profiles[userID]; match(entry) {
+			return entry, true
+		}
+	}
+	return ProfileEntry{}, false
+}
+
+// Record stores the current username and folder of a profile. A username
+// that differs from the recorded one is kept in the profile's previous
+// usernames and returned. Any other profile recorded under the username has
+// since given it up, so its current username becomes unknown.
+func (idx *ProfileIndex) Record(userID, username, dir string) (previous string) {
+	folder := idx.relative(dir)
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	entry, ok := idx.profiles[userID]
+	if ok && entry.Username == username && entry.Folder == folder {
+		return ""
+	}
+	if ok && entry.Username != "" && !strings.EqualFold(entry.Username, username) {
+		previous = entry.Username
+		entry.Previous = addUsername(entry.Previous, previous)
+	}
+	entry.UserID = userID
+	entry.Username = username
+	entry.Folder = folder
+	idx.profiles[userID] = entry
+
+	for otherID, other := range idx.profiles {
+		if otherID != userID && strings.EqualFold(other.Username, username) {
+			other.Previous = addUsername(other.Previous, other.Username)
+			other.Username = ""
+			idx.profiles[otherID] = other
+		}
+	}
+	idx.dirty = true
+	return previous
+}
+
+// addUsername adds username to a list of previous usernames, unless it is
+// in there already
+func addUsername(usernames []string, username string) []string {
+	if slices.Contains(usernames, username) {
+		return usernames
+	}
+	return append(usernames, username)
+}
+
+// Dir returns the folder a profile is archived in
+func (idx *ProfileIndex) Dir(entry ProfileEntry) string {
+	if filepath.IsAbs(entry.Folder) {
+		return entry.Folder
+	}
+	return filepath.Join(idx.root, filepath.FromSlash(entry.Folder))
+}
+
+// relative returns dir as it is stored in the index
+func (idx *ProfileIndex) relative(dir string) string {
+	if rel, err := filepath.Rel(idx.root, dir); err == nil {
+		dir = rel
+	}
+	return filepath.ToSlash(dir)
+}
+
+// Len returns the number of profiles in the index
+func (idx *ProfileIndex) Len() int {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	return len(idx.profiles)
+}
+
+// Save writes the index to disk if it changed since it was loaded or saved
+func (idx *ProfileIndex) Save() error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if !idx.dirty {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(profileIndexFile{Version: profileIndexVersion, Profiles: idx.profiles}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal profile index: %w", err)
+	}
+
+	tempFile := idx.path + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write profile index: %w", err)
+	}
+	if err := os.Rename(tempFile, idx.path); err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("failed to write profile index: %w", err)
+	}
+
+	idx.dirty = false
+	return nil
+}
@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestProfileIndex(t *testing.T) {
+	root := t.TempDir()
+	indexPath := filepath.Join(root, ProfileIndexFile)
+
+	idx, err := LoadProfileIndex(indexPath)
+	if err != nil {
+		t.Fatalf("Failed to load missing index: %v", err)
+	}
+	if idx.Len() != 0 {
+		t.Fatalf("Expected empty index, got %d entries", idx.Len())
+	}
+
+	oldDir := filepath.Join(root, "old_name_photos")
+	if previous := idx.Record("42", "old_name", oldDir); previous != "" {
+		t.Errorf("Record of a new profile returned previous username %q", previous)
+	}
+	if err := idx.Save(); err != nil {
+		t.Fatalf("Failed to save index: %v", err)
+	}
+
+	// Reload and follow the profile through a username change
+	idx, err = LoadProfileIndex(indexPath)
+	if err != nil {
+		t.Fatalf("Failed to reload index: %v", err)
+	}
+	entry, ok := idx.ByUsername("OLD_NAME")
+	if !ok || entry.UserID != "42" || idx.Dir(entry) != oldDir {
+		t.Fatalf("ByUsername = %+v, %v; want user 42 in %s", entry, ok, oldDir)
+	}
+	if got, ok := idx.ByFolder(oldDir); !ok || got.UserID != "42" {
+		t.Errorf("ByFolder = %+v, %v; want user 42", got, ok)
+	}
+
+	newDir := filepath.Join(root, "new_name_photos")
+	if previous := idx.Record("42", "new_name", newDir); previous != "old_name" {
+		t.Errorf("Record after rename returned %q, want old_name", previous)
+	}
+	if _, ok := idx.ByUsername("old_name"); ok {
+		t.Error("Expected the old username to no longer be current")
+	}
+	if got, ok := idx.ByPreviousUsername("old_name"); !ok || got.Username != "new_name" {
+		t.Errorf("ByPreviousUsername = %+v, %v; want new_name", got, ok)
+	}
+
+	// Another account taking over a username leaves the first one nameless
+	idx.Record("43", "taken", filepath.Join(root, "taken_photos"))
+	idx.Record("44", "taken", filepath.Join(root, "taken_44_photos"))
+	if got, _ := idx.ByUsername("taken"); got.UserID != "44" {
+		t.Errorf("ByUsername(taken) = %q, want 44", got.UserID)
+	}
+	got, _ := idx.ByID("43")
+	if got.Username != "" || !reflect.DeepEqual(got.Previous, []string{"taken"}) {
+		t.Errorf("ByID(43) = %+v, want no username and taken as previous", got)
+	}
+
+	// Folders are stored relative to the index
+	if err := idx.Save(); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile(indexPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(raw, []byte(`"new_name_photos"`)) || bytes.Contains(raw, []byte(root)) {
+		t.Errorf("Expected relative folder in index, got %s", raw)
+	}
+}
+
+func TestHashIndexRenameDir(t *testing.T) {
+	root := t.TempDir()
+	idx, err := LoadHashIndex(filepath.Join(root, HashIndexFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		filepath.Join("old_photos", "ABC.jpg"):     "moved",
+		filepath.Join("old_photos_too", "DEF.jpg"): "kept",
+	}
+	for path, data := range files {
+		path = filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	moved := HashContent([]byte("moved"))
+	kept := HashContent([]byte("kept"))
+	idx.Add(moved, filepath.Join(root, "old_photos", "ABC.jpg"), 5)
+	idx.Add(kept, filepath.Join(root, "old_photos_too", "DEF.jpg"), 4)
+
+	if err := os.Rename(filepath.Join(root, "old_photos"), filepath.Join(root, "new_photos")); err != nil {
+		t.Fatal(err)
+	}
+	idx.RenameDir(filepath.Join(root, "old_photos"), filepath.Join(root, "new_photos"))
+	if got, _ := idx.Lookup(moved, 5); got != filepath.Join(root, "new_photos", "ABC.jpg") {
+		t.Errorf("Lookup of moved file = %q", got)
+	}
+	if got, _ := idx.Lookup(kept, 4); got != filepath.Join(root, "old_photos_too", "DEF.jpg") {
+		t.Errorf("Lookup of file in a similarly named folder = %q", got)
+	}
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"hash/maphash"
+	"sync"
+)
+
+// shortcodeShards is the number of independently locked parts of a
+// shortcodeSet. It is a power of two so a shard is picked with a mask.
+const shortcodeShards = 64
+
+// shortcodeSet maps the shortcodes of saved photos to their file names. It
+// is consulted for every post of every page while the workers add to it, so
+// it is split into shards with their own locks: lookups take only a read
+// lock on one shard and rarely wait for a writer.
+type shortcodeSet struct {
+	seed   maphash.Seed
+	shards [shortcodeShards]shortcodeShard
+}
+
+type shortcodeShard struct {
+	mu    sync.RWMutex
+	names map[string]string
+}
+
+func newShortcodeSet() *shortcodeSet {
+	s := &shortcodeSet{seed: maphash.MakeSeed()}
+	for i := range s.shards {
+		s.shards[i].names = make(map[string]string)
+	}
+	return s
+}
+
+func (s *shortcodeSet) shard(shortcode string) *shortcodeShard {
+	return &s.shards[maphash.String(s.seed, shortcode)&(shortcodeShards-1)]
+}
+
+// Get returns the file name saved for shortcode, or an empty string
+func (s *shortcodeSet) Get(shortcode string) string {
+	shard := s.shard(shortcode)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+	return shard.names[shortcode]
+}
+
+// Set records the file name saved for shortcode
+func (s *shortcodeSet) Set(shortcode, name string) {
+	shard := s.shard(shortcode)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	shard.names[shortcode] = name
+}
+
+// Len returns the number of shortcodes in the set
+func (s *shortcodeSet) Len() int {
+	n := 0
+	for i := range s.shards {
+		s.shards[i].mu.RLock()
+		n += len(s.shards[i].names)
+		s.shards[i].mu.RUnlock()
+	}
+	return n
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestShortcodeSet(t *testing.T) {
+	set := newShortcodeSet()
+	if set.Get("ABC") != "" {
+		t.Error("Expected an empty set to have no entries")
+	}
+
+	// Writers and readers at the same time, as the workers and paginator do
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(2)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				code := fmt.Sprintf("W%dP%d", w, i)
+				set.Set(code, code+".jpg")
+			}
+		}(w)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				code := fmt.Sprintf("W%dP%d", w, i)
+				if name := set.Get(code); name != "" && name != code+".jpg" {
+					t.Errorf("Get(%s) = %q", code, name)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if set.Len() != 8*500 {
+		t.Errorf("Expected %d entries, got %d", 8*500, set.Len())
+	}
+	if set.Get("W3P42") != "W3P42.jpg" {
+		t.Errorf("Expected W3P42.jpg, got %q", set.Get("W3P42"))
+	}
+}
+
+func TestIsDownloadedWhileSaving(t *testing.T) {
+	manager, err := NewManager(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				code := fmt.Sprintf("W%dP%d", w, i)
+				if err := manager.SavePhoto(bytes.NewReader([]byte(code)), code); err != nil {
+					t.Errorf("Failed to save %s: %v", code, err)
+				}
+				if !manager.IsDownloaded(code) {
+					t.Errorf("Expected %s to be downloaded after saving", code)
+				}
+			}
+		}(w)
+	}
+	for i := 0; i < 200; i++ {
+		manager.IsDownloaded(fmt.Sprintf("W0P%d", i%20))
+	}
+	wg.Wait()
+
+	if manager.GetDownloadedCount() != 80 {
+		t.Errorf("Expected 80 downloaded photos, got %d", manager.GetDownloadedCount())
+	}
+}
+
+func BenchmarkIsDownloaded(b *testing.B) {
+	manager, err := NewManager(b.TempDir())
+	if err != nil {
+		b.Fatalf("Failed to create manager: %v", err)
+	}
+	for i := 0; i < 10000; i++ {
+		manager.downloadedPhotos.Set(fmt.Sprintf("CODE%d", i), fmt.Sprintf("CODE%d.jpg", i))
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			manager.IsDownloaded(fmt.Sprintf("CODE%d", i%10000))
+			i++
+		}
+	})
+}
@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// SpaceError is returned by Guard.Check when downloads should stop before
+// the disk fills up or the archive outgrows its quota
+type SpaceError struct {
+	Reason string
+}
+
+func (e *SpaceError) Error() string {
+	return e.Reason
+}
+
+// Guard keeps downloads into a directory within the free space that must be
+// left on its disk and a quota on the directory's total size. Both are
+// checked between batches, so a batch already queued may go slightly over.
+// A Guard may be shared by several scrapers writing to the same directory.
+type Guard struct {
+	dir      string
+	minFree  int64
+	maxTotal int64
+	used     atomic.Int64 // bytes in dir when the guard was created plus those added since
+}
+
+// NewGuard creates a guard for dir. minFree is the free space to leave on
+// the disk and maxTotal the most dir may hold; zero disables either. With a
+// quota, the files already in dir are counted.
+func NewGuard(dir string, minFree, maxTotal int64) (*Guard, error) {
+	g := &Guard{dir: dir, minFree: minFree, maxTotal: maxTotal}
+	if maxTotal > 0 {
+		used, err := dirSize(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to measure %s: %w", dir, err)
+		}
+		g.used.Store(used)
+	}
+	return g, nil
+}
+
+// Add counts n more bytes written to the directory
+func (g *Guard) Add(n int64) {
+	g.used.Add(n)
+}
+
+// Used returns the bytes counted against the quota
+func (g *Guard) Used() int64 {
+	return g.used.Load()
+}
+
+// Check returns a *SpaceError when the quota is reached or the disk has less
+// than the minimum free space. Free space that cannot be measured on this
+// platform is not checked.
+func (g *Guard) Check() error {
+	if g.maxTotal > 0 {
+		if used := g.used.Load(); used >= g.maxTotal {
+			return &SpaceError{Reason: fmt.Sprintf("download quota reached: %s of %s used in %s",
+				FormatSize(used), FormatSize(g.maxTotal), g.dir)}
+		}
+	}
+	if g.minFree > 0 {
+		free, err := FreeSpace(g.dir)
+		if err == nil && free < g.minFree {
+			return &SpaceError{Reason: fmt.Sprintf("disk almost full: %s free where %s must stay free",
+				FormatSize(free), FormatSize(g.minFree))}
+		}
+	}
+	return nil
+}
+
+// dirSize returns the total size of the regular files under dir. A missing
+// dir is empty.
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir && os.IsNotExist(err) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+// existingParent returns path, or its closest ancestor that exists, so free
+// space can be measured before the output directory is created
+func existingParent(path string) string {
+	path = filepath.Clean(path)
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
+
+// ParseSize parses a size such as "10GB", "500MB" or "1.5T" into bytes.
+// Units are powers of 1024, and a bare number is bytes. An empty string or
+// zero means no limit.
+func ParseSize(s string) (int64, error) {
+	value := strings.ToUpper(strings.TrimSpace(s))
+	value = strings.TrimSuffix(strings.TrimSuffix(value, "IB"), "B")
+	if value == "" {
+		if s = strings.TrimSpace(s); s != "" {
+			return 0, fmt.Errorf("invalid size %q", s)
+		}
+		return 0, nil
+	}
+
+	multiplier := int64(1)
+	if i := strings.IndexAny(value, "KMGT"); i == len(value)-1 {
+		multiplier = 1 << (10 * (strings.IndexByte("KMGT", value[i]) + 1))
+		value = value[:i]
+	}
+	number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("invalid size %q: use a size such as 10GB or 500MB", s)
+	}
+	return int64(number * float64(multiplier)), nil
+}
+
+// FormatSize formats bytes the way ParseSize reads them
+func FormatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%dB", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit && exp < 3; n /= unit {
+		div *= unit
+		exp++
+	}
+	value := strings.TrimSuffix(strconv.FormatFloat(float64(bytes)/float64(div), 'f', 1, 64), ".0")
+	return value + string("KMGT"[exp]) + "B"
+}
@@ -0,0 +1,10 @@
+//go:build !(linux || darwin || freebsd || openbsd || netbsd || dragonfly || windows)
+
+package storage
+
+import "errors"
+
+// FreeSpace is not available on this platform
+func FreeSpace(path string) (int64, error) {
+	return 0, errors.New("free disk space is not available on this platform")
+}
@@ -0,0 +1,85 @@
+package storage
+
+import (
+	stderrors "errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGuard(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "user_photos"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "user_photos", "ABC.jpg"), make([]byte, 600), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Files already in the directory count against the quota
+	g, err := NewGuard(dir, 0, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Used() != 600 {
+		t.Errorf("Used = %d, want 600", g.Used())
+	}
+	if err := g.Check(); err != nil {
+		t.Errorf("Check below the quota = %v", err)
+	}
+	g.Add(400)
+	var spaceErr *SpaceError
+	if err := g.Check(); !stderrors.As(err, &spaceErr) {
+		t.Errorf("Check at the quota = %v, want a SpaceError", err)
+	}
+
+	// A directory that does not exist yet is empty
+	g, err = NewGuard(filepath.Join(dir, "missing", "downloads"), 0, 1000)
+	if err != nil || g.Used() != 0 {
+		t.Errorf("NewGuard of a missing directory = %d, %v", g.Used(), err)
+	}
+
+	free, err := FreeSpace(filepath.Join(dir, "missing"))
+	if err != nil {
+		t.Skipf("Free space not available: %v", err)
+	}
+	if free <= 0 {
+		t.Errorf("FreeSpace = %d", free)
+	}
+	g, _ = NewGuard(dir, free*2, 0)
+	if err := g.Check(); !stderrors.As(err, &spaceErr) {
+		t.Errorf("Check with too little free space = %v, want a SpaceError", err)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int64
+	}{
+		{"", 0},
+		{"0", 0},
+		{"2048", 2048},
+		{"500MB", 500 << 20},
+		{"10GB", 10 << 30},
+		{"1.5T", 3 << 39},
+		{"64 KiB", 64 << 10},
+	}
+	for _, tt := range tests {
+		got, err := ParseSize(tt.input)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, %v; want %d", tt.input, got, err, tt.want)
+		}
+	}
+	for _, input := range []string{"big", "GB", "-1GB", "5PB"} {
+		if _, err := ParseSize(input); err == nil {
+			t.Errorf("ParseSize(%q) should fail", input)
+		}
+	}
+
+	for bytes, want := range map[int64]string{500: "500B", 512 << 20: "512MB", 3 << 29: "1.5GB", 2 << 40: "2TB"} {
+		if got := FormatSize(bytes); got != want {
+			t.Errorf("FormatSize(%d) = %q, want %q", bytes, got, want)
+		}
+	}
+}
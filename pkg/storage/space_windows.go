@@ -0,0 +1,19 @@
+//go:build windows
+
+package storage
+
+import "golang.org/x/sys/windows"
+
+// FreeSpace returns the bytes available to the current user on the disk
+// holding path
+func FreeSpace(path string) (int64, error) {
+	dir, err := windows.UTF16PtrFromString(existingParent(path))
+	if err != nil {
+		return 0, err
+	}
+	var available, total, free uint64
+	if err := windows.GetDiskFreeSpaceEx(dir, &available, &total, &free); err != nil {
+		return 0, err
+	}
+	return int64(available), nil
+}
@@ -0,0 +1,85 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Output formats accepted by --output-format
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+)
+
+// jsonOutput determines if output is written as JSON lines instead of text
+var jsonOutput bool
+
+var (
+	eventMu  sync.Mutex
+	eventOut io.Writer = os.Stdout
+)
+
+// SetJSONOutput enables or disables JSON lines output. While enabled, the
+// print helpers and the progress display emit one event per line on stdout
+// and ignore quiet and progress-only mode, so scripts see every event.
+func SetJSONOutput(enabled bool) {
+	jsonOutput = enabled
+}
+
+// IsJSONOutput returns true if output is written as JSON lines
+func IsJSONOutput() bool {
+	return jsonOutput
+}
+
+// EmitEvent writes a JSON object with the event name, the current time and
+// fields as a single line. event and time come first and the fields follow
+// in key order. Lines from concurrent callers never interleave.
+func EmitEvent(event string, fields map[string]interface{}) {
+	line := formatEvent(event, fields)
+	eventMu.Lock()
+	defer eventMu.Unlock()
+	io.WriteString(eventOut, line)
+}
+
+// formatEvent returns the line EmitEvent writes for an event
+func formatEvent(event string, fields map[string]interface{}) string {
+	rest := make(map[string]interface{}, len(fields))
+	for key, value := range fields {
+		if err, ok := value.(error); ok {
+			value = err.Error()
+		}
+		if key != "event" && key != "time" {
+			rest[key] = value
+		}
+	}
+	data, err := json.Marshal(rest)
+	if err != nil {
+		event, data = "error", []byte(`{"message":"failed to encode event"}`)
+	}
+
+	head, _ := json.Marshal(event)
+	line := fmt.Sprintf(`{"event":%s,"time":"%s"`, head, time.Now().Format(time.RFC3339))
+	if len(data) > 2 {
+		line += "," + string(data[1:])
+	} else {
+		line += "}"
+	}
+	return line + "\n"
+}
+
+// emitMessage emits a print helper's message as an event, dropping the
+// blank lines and padding the text output uses for spacing
+func emitMessage(event, msg string, args []interface{}) {
+	fields := map[string]interface{}{"message": strings.TrimSpace(msg)}
+	if len(args) > 0 {
+		if detail := strings.TrimSpace(fmt.Sprintf("%v", args[0])); detail != "" {
+			fields["detail"] = detail
+		}
+	}
+	EmitEvent(event, fields)
+}
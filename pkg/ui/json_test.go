@@ -0,0 +1,118 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// captureEvents enables JSON output for the test and returns the events
+// written so far
+func captureEvents(t *testing.T) func() []map[string]interface{} {
+	t.Helper()
+	var buf bytes.Buffer
+	out := eventOut
+	SetJSONOutput(true)
+	eventOut = &buf
+	t.Cleanup(func() {
+		SetJSONOutput(false)
+		eventOut = out
+	})
+
+	return func() []map[string]interface{} {
+		var events []map[string]interface{}
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			if line == "" {
+				continue
+			}
+			var event map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(line), &event), line)
+			events = append(events, event)
+		}
+		return events
+	}
+}
+
+func TestPrintHelpersJSON(t *testing.T) {
+	events := captureEvents(t)
+	SetQuietMode(true)
+	defer SetQuietMode(false)
+
+	PrintLogo()
+	PrintInfo("Target Profile", "johndoe")
+	PrintWarning("\n[DOWNLOADS PAUSED]", errors.New("disk full"))
+	PrintError("EXTRACTION FAILED", "")
+
+	got := events()
+	require.Len(t, got, 3, "quiet mode does not hide events and the logo is left out")
+	assert.Equal(t, "info", got[0]["event"])
+	assert.Equal(t, "Target Profile", got[0]["label"])
+	assert.Equal(t, "johndoe", got[0]["value"])
+	assert.Equal(t, "warning", got[1]["event"])
+	assert.Equal(t, "[DOWNLOADS PAUSED]", got[1]["message"])
+	assert.Equal(t, "disk full", got[1]["detail"])
+	assert.Equal(t, "error", got[2]["event"])
+	assert.NotContains(t, got[2], "detail")
+	assert.NotEmpty(t, got[2]["time"])
+}
+
+func TestProgressDisplayJSON(t *testing.T) {
+	events := captureEvents(t)
+
+	p := NewProgressDisplay("johndoe", 3, false)
+	p.Skip("videos", 1)
+	p.StartDownload("A")
+	p.CompleteDownload("A", 2048, nil)
+	p.StartDownload("B")
+	p.FailDownload("B", errors.New("timeout"))
+	p.QueueComplete()
+	p.Complete()
+
+	got := events()
+	names := make([]string, len(got))
+	for i, event := range got {
+		names[i] = event["event"].(string)
+		assert.Equal(t, "johndoe", event["username"])
+	}
+	assert.Equal(t, []string{
+		"skipped", "download_started", "download_completed", "download_started",
+		"download_failed", "queue_complete", "summary",
+	}, names)
+
+	completed := got[2]
+	assert.Equal(t, "A", completed["shortcode"])
+	assert.EqualValues(t, 2048, completed["bytes"])
+	assert.EqualValues(t, 1, completed["downloaded"])
+	assert.EqualValues(t, 2, completed["total"])
+	assert.Equal(t, "timeout", got[4]["error"])
+
+	summary := got[6]
+	assert.EqualValues(t, 1, summary["downloaded"])
+	assert.EqualValues(t, 1, summary["failed"])
+	assert.EqualValues(t, 1, summary["skipped"])
+	assert.Equal(t, map[string]interface{}{"videos": float64(1)}, summary["skipped_reasons"])
+}
+
+func TestEmitEvent(t *testing.T) {
+	var buf bytes.Buffer
+	out := eventOut
+	eventOut = &buf
+	defer func() { eventOut = out }()
+
+	EmitEvent("download_failed", map[string]interface{}{
+		"shortcode": "A",
+		"error":     errors.New("timeout"),
+		"event":     "ignored",
+	})
+	EmitEvent("queue_complete", nil)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	require.Len(t, lines, 2)
+	assert.Regexp(t, `^\{"event":"download_failed","time":"[^"]+","error":"timeout","shortcode":"A"\}$`, lines[0])
+	assert.Regexp(t, `^\{"event":"queue_complete","time":"[^"]+"\}$`, lines[1])
+}
@@ -2,11 +2,19 @@ package ui
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 )
 
+const (
+	// progressRedraw is the least time between redraws of the progress
+	// line for bytes written, and lineWidth the width it is cleared to
+	progressRedraw = 250 * time.Millisecond
+	lineWidth      = 140
+)
+
 // ProgressDisplay provides a clean, minimal progress display
 type ProgressDisplay struct {
 	mu              sync.Mutex
@@ -19,6 +27,39 @@ type ProgressDisplay struct {
 	bytesDownloaded int64
 	errors          int
 	isDebug         bool
+	duplicates      int
+	bytesSaved      int64
+	
+	// queued counts the posts queued for download and resumed those
+	// downloaded before a resume. skipped counts the posts that will not be
+	// downloaded by reason, and scanned is set once every page is queued.
+	queued  int
+	resumed int
+	skipped map[string]int
+	scanned bool
+	
+	// transfers holds the downloads writing bytes, one per busy worker, by
+	// shortcode. transferred counts the bytes of the downloads finished in
+	// this run, and firstByte is when the first of them started writing.
+	transfers   map[string]*transfer
+	transferred int64
+	firstByte   time.Time
+	lastDraw    time.Time
+}
+
+// transfer is a download writing bytes
+type transfer struct {
+	written int64
+	started time.Time
+}
+
+// speed returns the bytes per second of the download so far
+func (t *transfer) speed(now time.Time) float64 {
+	elapsed := now.Sub(t.started).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(t.written) / elapsed
 }
 
 // NewProgressDisplay creates a new progress display
@@ -29,36 +70,79 @@ func NewProgressDisplay(username string, totalPhotos int, debug bool) *ProgressD
 		startTime:   time.Now(),
 		lastUpdate:  time.Now(),
 		isDebug:     debug,
+		skipped:     make(map[string]int),
+		transfers:   make(map[string]*transfer),
 	}
 }
 
-// StartDownload marks the start of a new download
+// StartDownload marks a post as queued and the start of its download
 func (p *ProgressDisplay) StartDownload(shortcode string) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	
+	p.queued++
 	p.currentPhoto = shortcode
 	p.lastUpdate = time.Now()
 	
-	if !p.isDebug {
+	if IsJSONOutput() {
+		p.emit("download_started", map[string]interface{}{"shortcode": shortcode})
+	} else if !p.isDebug {
 		p.printProgress()
 	}
 }
 
+// DownloadProgress returns a callback for the download of shortcode to
+// report the bytes written so far, from which the speed of each download and
+// of all of them is worked out
+func (p *ProgressDisplay) DownloadProgress(shortcode string) func(written int64) {
+	return func(written int64) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		
+		now := time.Now()
+		t := p.transfers[shortcode]
+		if t == nil {
+			t = &transfer{started: now}
+			p.transfers[shortcode] = t
+			if p.firstByte.IsZero() {
+				p.firstByte = now
+			}
+		}
+		t.written = written
+		
+		if !IsJSONOutput() && !p.isDebug && now.Sub(p.lastDraw) >= progressRedraw {
+			p.printProgress()
+		}
+	}
+}
+
 // CompleteDownload marks a download as complete
 func (p *ProgressDisplay) CompleteDownload(shortcode string, size int64, metadata map[string]interface{}) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	
+	now := time.Now()
+	speed := 0.0
+	if t := p.transfers[shortcode]; t != nil {
+		speed = t.speed(now)
+		delete(p.transfers, shortcode)
+	}
 	p.downloadedCount++
 	p.bytesDownloaded += size
-	p.lastUpdate = time.Now()
+	p.transferred += size
+	p.lastUpdate = now
 	
-	if !p.isDebug {
+	if IsJSONOutput() {
+		fields := map[string]interface{}{"shortcode": shortcode, "bytes": size}
+		if speed > 0 {
+			fields["bytes_per_second"] = speed
+		}
+		p.emitProgress("download_completed", fields)
+	} else if !p.isDebug {
 		p.printProgress()
 	} else {
 		// In debug mode, show more details
-		p.printDebugComplete(shortcode, size, metadata)
+		p.printDebugComplete(shortcode, size, speed, metadata)
 	}
 }
 
@@ -69,14 +153,49 @@ func (p *ProgressDisplay) FailDownload(shortcode string, err error) {
 	
 	p.errors++
 	p.lastUpdate = time.Now()
+	delete(p.transfers, shortcode)
 	
-	if !p.isDebug {
+	if IsJSONOutput() {
+		p.emitProgress("download_failed", map[string]interface{}{"shortcode": shortcode, "error": err})
+	} else if !p.isDebug {
 		p.printProgress()
 	} else {
 		fmt.Printf("\n%s Failed: %s - %v\n", Red("✗"), shortcode, err)
 	}
 }
 
+// ItemQueued marks a post as queued, as StartDownload does
+func (p *ProgressDisplay) ItemQueued(item Item) {
+	p.StartDownload(item.Shortcode)
+}
+
+// ItemSkipped counts a post that will not be downloaded, as Skip does,
+// taking it off the queue if it was queued
+func (p *ProgressDisplay) ItemSkipped(item Item, reason string) {
+	if item.Queued {
+		p.mu.Lock()
+		p.queued--
+		delete(p.transfers, item.Shortcode)
+		p.mu.Unlock()
+	}
+	p.Skip(reason, 1)
+}
+
+// ItemCompleted marks a download as complete, as CompleteDownload does
+func (p *ProgressDisplay) ItemCompleted(item Item, size int64) {
+	p.CompleteDownload(item.Shortcode, size, item.metadata())
+}
+
+// ItemFailed marks a download as failed, as FailDownload does
+func (p *ProgressDisplay) ItemFailed(item Item, err error) {
+	p.FailDownload(item.Shortcode, err)
+}
+
+// RateLimited shows a rate limit warning, as RateLimitWarning does
+func (p *ProgressDisplay) RateLimited(wait time.Duration, requestsPerMinute int) {
+	p.RateLimitWarning(wait)
+}
+
 // printProgress prints the minimal progress line
 func (p *ProgressDisplay) printProgress() {
 	// Don't print if in quiet mode (unless progress-only mode)
@@ -85,13 +204,18 @@ func (p *ProgressDisplay) printProgress() {
 	}
 	
 	// Calculate stats
-	elapsed := time.Since(p.startTime)
-	rate := float64(p.downloadedCount) / elapsed.Minutes()
+	now := time.Now()
+	p.lastDraw = now
+	speed := fmt.Sprintf("%.1f/min", p.rate()*60)
+	if bytesPerSecond := p.speed(now); bytesPerSecond > 0 {
+		speed = p.formatBytes(int64(bytesPerSecond)) + "/s"
+	}
 	eta := p.calculateETA()
 	
 	// Build progress bar
-	progress := float64(p.downloadedCount) / float64(p.totalPhotos)
-	if p.totalPhotos <= 0 {
+	target := p.target()
+	progress := float64(p.downloadedCount) / float64(target)
+	if target <= 0 {
 		progress = 0
 	}
 	barWidth := 20
@@ -108,38 +232,92 @@ func (p *ProgressDisplay) printProgress() {
 	}
 	bar := strings.Repeat("━", filled) + strings.Repeat("─", remaining)
 	
-	// Format line
-	line := fmt.Sprintf("\r%s [%s] %d/%d • %.1f/min • %s • %s",
+	// Format line; feeds without a known size show the count alone
+	count := fmt.Sprintf("%d/%d", p.downloadedCount, target)
+	if target <= 0 {
+		count = fmt.Sprintf("%d", p.downloadedCount)
+	}
+	line := fmt.Sprintf("\r%s [%s] %s • %s • %s • %s",
 		Cyan(p.username),
 		bar,
-		p.downloadedCount,
-		p.totalPhotos,
-		rate,
+		count,
+		speed,
 		p.formatBytes(p.bytesDownloaded),
 		eta,
 	)
 	
-	// Add current photo if downloading
-	if p.currentPhoto != "" {
+	// Add the download under way, with its speed and how many others are
+	if shortcode, t := p.currentTransfer(); t != nil {
+		line += fmt.Sprintf(" • %s %s/s", shortcode, p.formatBytes(int64(t.speed(now))))
+		if others := len(p.transfers) - 1; others > 0 {
+			line += Dim(fmt.Sprintf(" +%d", others))
+		}
+	} else if p.currentPhoto != "" {
 		line += fmt.Sprintf(" • %s", p.currentPhoto)
 	}
 	
-	// Add errors if any
+	// Add skipped posts and errors if any
+	if skipped := p.skippedCount(); skipped > 0 {
+		line += fmt.Sprintf(" • %s", Dim(fmt.Sprintf("%d skipped", skipped)))
+	}
 	if p.errors > 0 {
 		line += fmt.Sprintf(" • %s", Red(fmt.Sprintf("%d errors", p.errors)))
 	}
 	
 	// Clear line and print
-	fmt.Printf("\r%s\r%s", strings.Repeat(" ", 120), line)
+	fmt.Printf("\r%s\r%s", strings.Repeat(" ", lineWidth), line)
+}
+
+// currentTransfer returns the download under way to show: the one queued
+// last if it is writing bytes, otherwise the one started last
+func (p *ProgressDisplay) currentTransfer() (string, *transfer) {
+	if t := p.transfers[p.currentPhoto]; t != nil {
+		return p.currentPhoto, t
+	}
+	var shortcode string
+	var latest *transfer
+	for sc, t := range p.transfers {
+		if latest == nil || t.started.After(latest.started) {
+			shortcode, latest = sc, t
+		}
+	}
+	return shortcode, latest
+}
+
+// emit writes a JSON event for the profile being downloaded
+func (p *ProgressDisplay) emit(event string, fields map[string]interface{}) {
+	if fields == nil {
+		fields = make(map[string]interface{})
+	}
+	fields["username"] = p.username
+	EmitEvent(event, fields)
+}
+
+// emitProgress writes a JSON event with the download counts so far. total is
+// left out while the number of downloads is unknown.
+func (p *ProgressDisplay) emitProgress(event string, fields map[string]interface{}) {
+	if fields == nil {
+		fields = make(map[string]interface{})
+	}
+	fields["downloaded"] = p.downloadedCount
+	fields["failed"] = p.errors
+	fields["skipped"] = p.skippedCount()
+	if target := p.target(); target > 0 {
+		fields["total"] = target
+	}
+	p.emit(event, fields)
 }
 
 // printDebugComplete prints detailed info in debug mode
-func (p *ProgressDisplay) printDebugComplete(shortcode string, size int64, metadata map[string]interface{}) {
+func (p *ProgressDisplay) printDebugComplete(shortcode string, size int64, speed float64, metadata map[string]interface{}) {
 	fmt.Printf("\n%s %s • %s", 
 		Green("✓"),
 		shortcode,
 		p.formatBytes(size),
 	)
+	if speed > 0 {
+		fmt.Printf(" • %s/s", p.formatBytes(int64(speed)))
+	}
 	
 	// Add metadata if available
 	if caption, ok := metadata["caption"].(string); ok && caption != "" {
@@ -162,55 +340,117 @@ func (p *ProgressDisplay) Complete() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	
+	elapsed := time.Since(p.startTime)
+	speed := p.speed(time.Now())
+	if IsJSONOutput() {
+		fields := map[string]interface{}{
+			"downloaded":       p.downloadedCount,
+			"resumed":          p.resumed,
+			"bytes":            p.bytesDownloaded,
+			"duration_seconds": elapsed.Seconds(),
+			"duplicates":       p.duplicates,
+			"bytes_saved":      p.bytesSaved,
+			"skipped":          p.skippedCount(),
+			"skipped_reasons":  p.skipped,
+			"failed":           p.errors,
+		}
+		if speed > 0 {
+			fields["bytes_per_second"] = speed
+		}
+		p.emit("summary", fields)
+		return
+	}
+	
 	// Don't print if in quiet mode (unless progress-only mode)
 	if IsQuietMode() && !IsProgressOnlyMode() {
 		return
 	}
 	
-	elapsed := time.Since(p.startTime)
 	
-	fmt.Printf("\n\n%s Downloaded %d photos from @%s\n",
+	// Sizes, skips and failures follow in the scrape report
+	rate := fmt.Sprintf("%.1f photos/min", p.rate()*60)
+	if speed > 0 {
+		rate += fmt.Sprintf(", %s/s", p.formatBytes(int64(speed)))
+	}
+	fmt.Printf("\n\n%s Downloaded %d photos from @%s (%s)\n",
 		Green("✓"),
 		p.downloadedCount,
 		p.username,
+		rate,
 	)
 	
-	// Summary stats
-	fmt.Printf("  %s %s in %s (%.1f photos/min)\n",
-		Dim("•"),
-		p.formatBytes(p.bytesDownloaded),
-		p.formatDuration(elapsed),
-		float64(p.downloadedCount)/elapsed.Minutes(),
-	)
-	
-	if p.errors > 0 {
-		fmt.Printf("  %s %d downloads failed\n", 
+	if p.duplicates > 0 {
+		fmt.Printf("  %s %d duplicate photos, %s saved\n",
 			Dim("•"),
-			p.errors,
+			p.duplicates,
+			p.formatBytes(p.bytesSaved),
 		)
 	}
 }
 
-// calculateETA estimates time remaining
+// calculateETA estimates time remaining. Once bytes are flowing this is the
+// remaining downloads at the average size so far over the overall speed,
+// otherwise the remaining downloads over the downloads per second.
 func (p *ProgressDisplay) calculateETA() string {
 	if p.downloadedCount == 0 {
 		return "calculating..."
 	}
-	
-	remaining := p.totalPhotos - p.downloadedCount
-	elapsed := time.Since(p.startTime)
-	rate := float64(p.downloadedCount) / elapsed.Seconds()
-	
-	if rate == 0 {
-		return "calculating..."
+	target := p.target()
+	if target <= 0 {
+		return "unknown"
 	}
 	
-	etaSeconds := float64(remaining) / rate
+	remaining := max(target-p.downloadedCount, 0)
+	var etaSeconds float64
+	if speed := p.speed(time.Now()); speed > 0 && p.bytesDownloaded > 0 {
+		averageSize := float64(p.bytesDownloaded) / float64(p.downloadedCount)
+		bytesLeft := float64(remaining)*averageSize - float64(p.transferring())
+		etaSeconds = max(bytesLeft, 0) / speed
+	} else {
+		rate := p.rate()
+		if rate == 0 {
+			return "calculating..."
+		}
+		etaSeconds = float64(remaining) / rate
+	}
 	eta := time.Duration(etaSeconds) * time.Second
 	
 	return p.formatDuration(eta)
 }
 
+// speed returns the bytes per second of all downloads in this run, since
+// the first of them started writing, or 0 before any has
+func (p *ProgressDisplay) speed(now time.Time) float64 {
+	if p.firstByte.IsZero() {
+		return 0
+	}
+	elapsed := now.Sub(p.firstByte).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(p.transferred+p.transferring()) / elapsed
+}
+
+// transferring returns the bytes written so far by the downloads under way
+func (p *ProgressDisplay) transferring() int64 {
+	var written int64
+	for _, t := range p.transfers {
+		written += t.written
+	}
+	return written
+}
+
+// rate returns the downloads per second since the display was created.
+// Downloads from before a resume are left out, since they took no time in
+// this run.
+func (p *ProgressDisplay) rate() float64 {
+	elapsed := time.Since(p.startTime).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(p.downloadedCount-p.resumed) / elapsed
+}
+
 // formatDuration formats a duration in a human-readable way
 func (p *ProgressDisplay) formatDuration(d time.Duration) string {
 	if d < time.Minute {
@@ -243,6 +483,11 @@ func (p *ProgressDisplay) RateLimitWarning(waitTime time.Duration) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	
+	if IsJSONOutput() {
+		p.emit("rate_limited", map[string]interface{}{"wait_seconds": waitTime.Seconds()})
+		return
+	}
+	
 	// Don't print if in quiet mode
 	if IsQuietMode() {
 		return
@@ -254,18 +499,48 @@ func (p *ProgressDisplay) RateLimitWarning(waitTime time.Duration) {
 	)
 }
 
-// ScanningBatch indicates scanning a new batch
-func (p *ProgressDisplay) ScanningBatch(page int) {
+// AccountSwitched shows that requests now use another stored account because
+// the previous one kept receiving the given HTTP status
+func (p *ProgressDisplay) AccountSwitched(from, to string, status int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	
+	if IsJSONOutput() {
+		p.emit("account_switched", map[string]interface{}{"from": from, "to": to, "status": status})
+		return
+	}
+	
+	// Don't print if in quiet mode
+	if IsQuietMode() {
+		return
+	}
+	
+	fmt.Printf("\n%s Account %s keeps receiving HTTP %d. Switched to %s\n",
+		Yellow("⚠"),
+		from,
+		status,
+		to,
+	)
+}
+
+// PageFetched shows that a page of posts was fetched. The display is of one
+// profile, so username is left out.
+func (p *ProgressDisplay) PageFetched(username string, page, total int) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	
+	if IsJSONOutput() {
+		p.emit("page_fetched", map[string]interface{}{"page": page})
+		return
+	}
+	
 	// Don't print if in quiet mode
 	if IsQuietMode() {
 		return
 	}
 	
 	if p.isDebug {
-		fmt.Printf("\n%s Scanning page %d...\n", Magenta("→"), page)
+		fmt.Printf("\n%s Fetched page %d\n", Magenta("→"), page)
 	}
 }
 
@@ -283,4 +558,96 @@ func (p *ProgressDisplay) SetDownloadedCount(count int) {
 	defer p.mu.Unlock()
 	
 	p.downloadedCount = count
-}
\ No newline at end of file
+	p.resumed = count
+}
+
+// SetDownloadedBytes sets the initial size of the downloads (for resume)
+func (p *ProgressDisplay) SetDownloadedBytes(bytes int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	
+	p.bytesDownloaded = bytes
+}
+
+// Skip records count posts that will not be downloaded for the given reason,
+// such as "videos" or "outside date range". They no longer count towards the
+// total the progress bar fills up to.
+func (p *ProgressDisplay) Skip(reason string, count int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	
+	p.skipped[reason] += count
+	if IsJSONOutput() {
+		p.emit("skipped", map[string]interface{}{"reason": reason, "count": count})
+	}
+}
+
+// QueueComplete marks every page as scanned, after which progress is
+// measured against the posts actually queued instead of the profile's total
+func (p *ProgressDisplay) QueueComplete() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	
+	p.scanned = true
+	if IsJSONOutput() {
+		p.emitProgress("queue_complete", nil)
+	} else if !p.isDebug {
+		p.printProgress()
+	}
+}
+
+// target returns the number of downloads the progress bar fills up to, or
+// zero or less when it is unknown. Until every page is scanned this is the
+// profile's total less the posts skipped so far.
+func (p *ProgressDisplay) target() int {
+	queued := p.resumed + p.queued
+	if p.scanned {
+		return queued
+	}
+	if p.totalPhotos <= 0 {
+		return p.totalPhotos
+	}
+	return max(p.totalPhotos-p.skippedCount(), queued)
+}
+
+// skippedCount returns the number of skipped posts over all reasons
+func (p *ProgressDisplay) skippedCount() int {
+	total := 0
+	for _, count := range p.skipped {
+		total += count
+	}
+	return total
+}
+
+// FormatSkipped formats skipped posts by reason, most common first, as in
+// "8 videos, 4 outside date range"
+func FormatSkipped(skipped map[string]int) string {
+	reasons := make([]string, 0, len(skipped))
+	for reason, count := range skipped {
+		if count > 0 {
+			reasons = append(reasons, reason)
+		}
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		if skipped[reasons[i]] != skipped[reasons[j]] {
+			return skipped[reasons[i]] > skipped[reasons[j]]
+		}
+		return reasons[i] < reasons[j]
+	})
+	
+	parts := make([]string, len(reasons))
+	for i, reason := range reasons {
+		parts[i] = fmt.Sprintf("%d %s", skipped[reason], reason)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// SetDeduplicated records how many photos deduplication stored only once, and
+// the bytes that saved, for the completion summary
+func (p *ProgressDisplay) SetDeduplicated(files int, bytesSaved int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	
+	p.duplicates = files
+	p.bytesSaved = bytesSaved
+}
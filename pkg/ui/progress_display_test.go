@@ -0,0 +1,118 @@
+package ui
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgressDisplayTarget(t *testing.T) {
+	SetQuietMode(true)
+	defer SetQuietMode(false)
+	
+	t.Run("skipped posts shrink the total", func(t *testing.T) {
+		p := NewProgressDisplay("user", 100, false)
+		assert.Equal(t, 100, p.target())
+		
+		p.Skip("videos", 8)
+		p.Skip("outside date range", 4)
+		assert.Equal(t, 88, p.target())
+		assert.Equal(t, 12, p.skippedCount())
+	})
+	
+	t.Run("queued posts once every page is scanned", func(t *testing.T) {
+		p := NewProgressDisplay("user", 100, false)
+		p.SetDownloadedCount(10)
+		for _, shortcode := range []string{"A", "B", "C"} {
+			p.StartDownload(shortcode)
+		}
+		p.Skip("filtered out", 5)
+		assert.Equal(t, 95, p.target())
+		
+		p.QueueComplete()
+		assert.Equal(t, 13, p.target(), "resumed and queued posts")
+	})
+	
+	t.Run("queued posts skipped for their size", func(t *testing.T) {
+		p := NewProgressDisplay("user", 10, false)
+		p.StartDownload("A")
+		p.StartDownload("B")
+		p.ItemSkipped(Item{Shortcode: "B", Queued: true}, "outside size limits")
+		assert.Equal(t, 9, p.target())
+		
+		p.QueueComplete()
+		assert.Equal(t, 1, p.target(), "the skipped post is no longer queued")
+		assert.NotContains(t, p.transfers, "B")
+	})
+	
+	t.Run("unknown total", func(t *testing.T) {
+		p := NewProgressDisplay("user", -1, false)
+		p.Skip("videos", 2)
+		p.CompleteDownload("A", 1024, nil)
+		assert.Equal(t, -1, p.target())
+		assert.Equal(t, "unknown", p.calculateETA())
+	})
+}
+
+func TestProgressDisplayResume(t *testing.T) {
+	SetQuietMode(true)
+	defer SetQuietMode(false)
+	
+	p := NewProgressDisplay("user", 100, false)
+	p.SetDownloadedCount(40)
+	p.SetDownloadedBytes(4096)
+	assert.Zero(t, p.rate(), "downloads before the resume took no time in this run")
+	
+	p.startTime = time.Now().Add(-10 * time.Second)
+	p.CompleteDownload("A", 1024, nil)
+	assert.InDelta(t, 0.1, p.rate(), 0.01)
+	assert.Equal(t, int64(5120), p.bytesDownloaded)
+	assert.Equal(t, "9m50s", p.calculateETA())
+}
+
+func TestFormatSkipped(t *testing.T) {
+	assert.Equal(t, "", FormatSkipped(nil))
+	assert.Equal(t, "8 videos, 4 filtered out, 4 outside date range", FormatSkipped(map[string]int{
+		"outside date range": 4,
+		"videos":             8,
+		"filtered out":       4,
+		"excluded":           0,
+	}))
+}
+
+func TestProgressDisplaySpeed(t *testing.T) {
+	SetQuietMode(true)
+	defer SetQuietMode(false)
+	
+	p := NewProgressDisplay("user", 10, false)
+	for _, shortcode := range []string{"A", "B", "C"} {
+		p.StartDownload(shortcode)
+	}
+	assert.Zero(t, p.speed(time.Now()), "no bytes written yet")
+	
+	// A finished 1 MB in 2 seconds while B is halfway through its own
+	p.DownloadProgress("A")(1 << 20)
+	p.DownloadProgress("B")(512 << 10)
+	started := time.Now().Add(-2 * time.Second)
+	p.firstByte = started
+	p.transfers["A"].started = started
+	p.transfers["B"].started = started.Add(time.Second)
+	
+	now := time.Now()
+	assert.InDelta(t, 1<<20/2, p.transfers["A"].speed(now), 1<<14)
+	shortcode, current := p.currentTransfer()
+	assert.Equal(t, "B", shortcode, "C is not writing yet, so the download started last")
+	assert.Equal(t, int64(512<<10), current.written)
+	
+	p.CompleteDownload("A", 1<<20, nil)
+	assert.NotContains(t, p.transfers, "A")
+	assert.Equal(t, int64(1<<20), p.transferred)
+	assert.InDelta(t, (1<<20+512<<10)/2, p.speed(time.Now()), 1<<14)
+	
+	// 9 more downloads of 1 MB at 768 KB/s, less the half written of B
+	assert.Equal(t, "11s", p.calculateETA())
+	
+	p.FailDownload("B", assert.AnError)
+	assert.Empty(t, p.transfers)
+}
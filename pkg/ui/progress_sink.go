@@ -0,0 +1,223 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// ProgressSink receives the progress of a scrape. The scraper reports to one
+// sink, whatever shows the progress: the progress display, the TUI, JSON
+// lines or nothing at all.
+type ProgressSink interface {
+	// PageFetched reports that page, counted from 1, of username's posts was
+	// fetched. total is the number of posts of the profile, or 0 or less
+	// while it is not known.
+	PageFetched(username string, page, total int)
+
+	// ItemQueued reports that a post was queued for download
+	ItemQueued(item Item)
+
+	// ItemSkipped reports that a post will not be downloaded for reason,
+	// such as the date range or the kind of media being skipped. A post is
+	// skipped after ItemQueued, with item.Queued set, when its file turns
+	// out to be outside the size limits.
+	ItemSkipped(item Item, reason string)
+
+	// ItemCompleted reports that a post was saved, size bytes
+	ItemCompleted(item Item, size int64)
+
+	// ItemFailed reports that a post could not be saved, or was dropped from
+	// the queue
+	ItemFailed(item Item, err error)
+
+	// RateLimited reports that the scrape waits for the rate limit of
+	// requestsPerMinute to let it carry on
+	RateLimited(wait time.Duration, requestsPerMinute int)
+}
+
+// Item is a download reported to a ProgressSink
+type Item struct {
+	Username  string
+	Shortcode string
+	File      string // name of its file, such as <shortcode>.jpg
+	Caption   string
+	Likes     int
+	Queued    bool // reported by ItemQueued before it was skipped
+}
+
+// metadata returns the details of the item the debug display shows
+func (i Item) metadata() map[string]interface{} {
+	return map[string]interface{}{
+		"caption": i.Caption,
+		"likes":   i.Likes,
+	}
+}
+
+// NopSink returns a ProgressSink that ignores every event
+func NopSink() ProgressSink {
+	return nopSink{}
+}
+
+type nopSink struct{}
+
+func (nopSink) PageFetched(username string, page, total int)          {}
+func (nopSink) ItemQueued(item Item)                                  {}
+func (nopSink) ItemSkipped(item Item, reason string)                  {}
+func (nopSink) ItemCompleted(item Item, size int64)                   {}
+func (nopSink) ItemFailed(item Item, err error)                       {}
+func (nopSink) RateLimited(wait time.Duration, requestsPerMinute int) {}
+
+// estimatedSize is the size the TUI shows for a download until it starts
+const estimatedSize = 500000
+
+// NewTUISink returns a ProgressSink passing the events on to tui. The total
+// shown for a profile leaves out the posts skipped so far.
+func NewTUISink(tui TUI) ProgressSink {
+	return &tuiSink{tui: tui, profiles: make(map[string]*tuiProfile)}
+}
+
+type tuiSink struct {
+	tui TUI
+
+	mu       sync.Mutex
+	profiles map[string]*tuiProfile
+}
+
+// tuiProfile is what the sink last heard of a profile
+type tuiProfile struct {
+	page    int
+	total   int
+	skipped int
+}
+
+// expected returns the number of the profile's posts still expected to be
+// downloaded, or 0 while it is not known
+func (p *tuiProfile) expected() int {
+	if p.total <= 0 {
+		return 0
+	}
+	return max(p.total-p.skipped, 0)
+}
+
+// profile returns the state of username's profile, adding it if it is new.
+// The caller holds s.mu.
+func (s *tuiSink) profile(username string) *tuiProfile {
+	p, ok := s.profiles[username]
+	if !ok {
+		p = &tuiProfile{}
+		s.profiles[username] = p
+	}
+	return p
+}
+
+func (s *tuiSink) PageFetched(username string, page, total int) {
+	s.mu.Lock()
+	p := s.profile(username)
+	p.page = page
+	if total > 0 {
+		p.total = total
+	}
+	expected := p.expected()
+	s.mu.Unlock()
+	s.tui.UpdateProfile(username, page, expected)
+}
+
+func (s *tuiSink) ItemQueued(item Item) {
+	s.tui.StartDownload(item.Shortcode, item.Username, item.File, estimatedSize)
+}
+
+func (s *tuiSink) ItemSkipped(item Item, reason string) {
+	if item.Queued {
+		// The TUI has no skipped state for a download it shows
+		s.tui.FailDownload(item.Shortcode, fmt.Errorf("skipped: %s", reason))
+	}
+	s.mu.Lock()
+	p := s.profile(item.Username)
+	p.skipped++
+	page, expected := p.page, p.expected()
+	s.mu.Unlock()
+	if expected > 0 {
+		s.tui.UpdateProfile(item.Username, page, expected)
+	}
+}
+
+func (s *tuiSink) ItemCompleted(item Item, size int64) {
+	s.tui.CompleteDownload(item.Shortcode)
+}
+
+func (s *tuiSink) ItemFailed(item Item, err error) {
+	s.tui.FailDownload(item.Shortcode, err)
+}
+
+func (s *tuiSink) RateLimited(wait time.Duration, requestsPerMinute int) {
+	s.tui.UpdateRateLimit(requestsPerMinute, requestsPerMinute, time.Now().Add(wait))
+	s.tui.LogWarning("Rate limit reached, cooling down for %s", wait.Round(time.Second))
+}
+
+// NewJSONSink returns a ProgressSink writing each event to w as a line of
+// JSON, in the format of the JSON output mode, whether or not that mode is
+// on
+func NewJSONSink(w io.Writer) ProgressSink {
+	return &jsonSink{w: w}
+}
+
+type jsonSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *jsonSink) emit(event string, fields map[string]interface{}) {
+	line := formatEvent(event, fields)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	io.WriteString(s.w, line)
+}
+
+// itemFields returns the fields of an event about item
+func itemFields(item Item) map[string]interface{} {
+	return map[string]interface{}{
+		"username":  item.Username,
+		"shortcode": item.Shortcode,
+		"file":      item.File,
+	}
+}
+
+func (s *jsonSink) PageFetched(username string, page, total int) {
+	fields := map[string]interface{}{"username": username, "page": page}
+	if total > 0 {
+		fields["total"] = total
+	}
+	s.emit("page_fetched", fields)
+}
+
+func (s *jsonSink) ItemQueued(item Item) {
+	s.emit("download_started", itemFields(item))
+}
+
+func (s *jsonSink) ItemSkipped(item Item, reason string) {
+	fields := itemFields(item)
+	fields["reason"] = reason
+	fields["count"] = 1
+	s.emit("skipped", fields)
+}
+
+func (s *jsonSink) ItemCompleted(item Item, size int64) {
+	fields := itemFields(item)
+	fields["bytes"] = size
+	s.emit("download_completed", fields)
+}
+
+func (s *jsonSink) ItemFailed(item Item, err error) {
+	fields := itemFields(item)
+	fields["error"] = err
+	s.emit("download_failed", fields)
+}
+
+func (s *jsonSink) RateLimited(wait time.Duration, requestsPerMinute int) {
+	s.emit("rate_limited", map[string]interface{}{
+		"wait_seconds":        wait.Seconds(),
+		"requests_per_minute": requestsPerMinute,
+	})
+}
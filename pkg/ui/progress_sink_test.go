@@ -0,0 +1,131 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewJSONSink(&buf)
+	item := Item{Username: "johndoe", Shortcode: "A", File: "A.jpg"}
+	sink.PageFetched("johndoe", 1, 0)
+	sink.ItemQueued(item)
+	sink.ItemCompleted(item, 2048)
+	sink.ItemFailed(item, errors.New("timeout"))
+	sink.RateLimited(time.Minute, 60)
+	sink.ItemSkipped(Item{Username: "johndoe", Shortcode: "B"}, "images")
+
+	var got []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var event map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &event), line)
+		got = append(got, event)
+	}
+	require.Len(t, got, 6)
+	assert.Equal(t, "page_fetched", got[0]["event"])
+	assert.NotContains(t, got[0], "total", "an unknown total is left out")
+	assert.Equal(t, "download_started", got[1]["event"])
+	assert.Equal(t, "A.jpg", got[1]["file"])
+	assert.Equal(t, "download_completed", got[2]["event"])
+	assert.EqualValues(t, 2048, got[2]["bytes"])
+	assert.Equal(t, "timeout", got[3]["error"])
+	assert.EqualValues(t, 60, got[4]["wait_seconds"])
+	assert.Equal(t, "skipped", got[5]["event"])
+	assert.Equal(t, "images", got[5]["reason"])
+	assert.Equal(t, "B", got[5]["shortcode"])
+	assert.False(t, IsJSONOutput(), "the sink does not need JSON output mode")
+}
+
+// recordingTUI records the calls of a TUI the sink makes
+type recordingTUI struct {
+	TUI
+	calls []string
+}
+
+func (r *recordingTUI) UpdateProfile(username string, page, total int) {
+	r.calls = append(r.calls, fmt.Sprintf("profile %s %d/%d", username, page, total))
+}
+
+func (r *recordingTUI) StartDownload(id, username, filename string, size int64) {
+	r.calls = append(r.calls, fmt.Sprintf("start %s %s %s", id, username, filename))
+}
+
+func (r *recordingTUI) CompleteDownload(id string) {
+	r.calls = append(r.calls, "complete "+id)
+}
+
+func (r *recordingTUI) FailDownload(id string, err error) {
+	r.calls = append(r.calls, fmt.Sprintf("fail %s %v", id, err))
+}
+
+func (r *recordingTUI) UpdateRateLimit(used, max int, resetAt time.Time) {
+	r.calls = append(r.calls, fmt.Sprintf("rate limit %d/%d", used, max))
+}
+
+func (r *recordingTUI) LogWarning(format string, args ...interface{}) {
+	r.calls = append(r.calls, "warning "+fmt.Sprintf(format, args...))
+}
+
+func TestTUISink(t *testing.T) {
+	tui := &recordingTUI{}
+	sink := NewTUISink(tui)
+	item := Item{Username: "johndoe", Shortcode: "A", File: "A.mp4"}
+	sink.PageFetched("johndoe", 2, -1)
+	sink.ItemQueued(item)
+	sink.ItemCompleted(item, 2048)
+	sink.ItemFailed(item, errors.New("timeout"))
+	sink.RateLimited(time.Hour, 60)
+
+	// Skipped posts come off the total once it is known
+	sink.ItemSkipped(Item{Username: "johndoe", Shortcode: "B"}, "images")
+	sink.PageFetched("johndoe", 3, 10)
+	sink.ItemSkipped(Item{Username: "johndoe", Shortcode: "C"}, "images")
+
+	// A queued post skipped for its size leaves the downloads shown
+	sink.ItemSkipped(Item{Username: "johndoe", Shortcode: "A", Queued: true}, "outside size limits")
+
+	assert.Equal(t, []string{
+		"profile johndoe 2/0",
+		"start A johndoe A.mp4",
+		"complete A",
+		"fail A timeout",
+		"rate limit 60/60",
+		"warning Rate limit reached, cooling down for 1h0m0s",
+		"profile johndoe 3/9",
+		"profile johndoe 3/8",
+		"fail A skipped: outside size limits",
+		"profile johndoe 3/7",
+	}, tui.calls)
+}
+
+func TestProgressDisplaySink(t *testing.T) {
+	events := captureEvents(t)
+
+	var sink ProgressSink = NewProgressDisplay("johndoe", 2, false)
+	item := Item{Username: "johndoe", Shortcode: "A"}
+	sink.PageFetched("johndoe", 1, 2)
+	sink.ItemQueued(item)
+	sink.ItemCompleted(item, 2048)
+	sink.RateLimited(time.Minute, 60)
+	sink.ItemSkipped(Item{Username: "johndoe", Shortcode: "B"}, "videos")
+
+	got := events()
+	names := make([]string, len(got))
+	for i, event := range got {
+		names[i] = event["event"].(string)
+	}
+	assert.Equal(t, []string{"page_fetched", "download_started", "download_completed", "rate_limited", "skipped"}, names)
+	assert.EqualValues(t, 1, got[2]["downloaded"])
+
+	NopSink().ItemCompleted(item, 2048)
+	assert.Len(t, events(), 5, "the no-op sink writes nothing")
+}
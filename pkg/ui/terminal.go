@@ -67,7 +67,7 @@ func IsProgressOnlyMode() bool {
 
 // PrintLogo prints the ASCII logo with color
 func PrintLogo() {
-	if IsQuietMode() || IsProgressOnlyMode() {
+	if IsJSONOutput() || IsQuietMode() || IsProgressOnlyMode() {
 		return
 	}
 	fmt.Print(Cyan(ASCIILogo))
@@ -76,6 +76,10 @@ func PrintLogo() {
 // PrintError prints an error message in red
 func PrintError(msg string, args ...interface{}) {
 	// Always print errors, even in quiet mode
+	if IsJSONOutput() {
+		emitMessage("error", msg, args)
+		return
+	}
 	if len(args) > 0 {
 		fmt.Println(Red(msg + ": " + fmt.Sprintf("%v", args[0])))
 	} else {
@@ -85,6 +89,10 @@ func PrintError(msg string, args ...interface{}) {
 
 // PrintSuccess prints a success message in green
 func PrintSuccess(msg string) {
+	if IsJSONOutput() {
+		emitMessage("success", msg, nil)
+		return
+	}
 	if IsQuietMode() || IsProgressOnlyMode() {
 		return
 	}
@@ -93,6 +101,10 @@ func PrintSuccess(msg string) {
 
 // PrintInfo prints an info message in cyan
 func PrintInfo(label string, value string) {
+	if IsJSONOutput() {
+		EmitEvent("info", map[string]interface{}{"label": label, "value": value})
+		return
+	}
 	if IsQuietMode() || IsProgressOnlyMode() {
 		return
 	}
@@ -101,6 +113,10 @@ func PrintInfo(label string, value string) {
 
 // PrintWarning prints a warning message in yellow
 func PrintWarning(msg string, args ...interface{}) {
+	if IsJSONOutput() {
+		emitMessage("warning", msg, args)
+		return
+	}
 	if IsQuietMode() || IsProgressOnlyMode() {
 		return
 	}
@@ -113,6 +129,10 @@ func PrintWarning(msg string, args ...interface{}) {
 
 // PrintHighlight prints a highlighted message in magenta
 func PrintHighlight(msg string) {
+	if IsJSONOutput() {
+		emitMessage("status", msg, nil)
+		return
+	}
 	if IsQuietMode() || IsProgressOnlyMode() {
 		return
 	}
@@ -2,6 +2,7 @@ package tui
 
 import (
 	"fmt"
+	"slices"
 	"sync"
 	"time"
 
@@ -9,6 +10,9 @@ import (
 	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+
+	"igscraper/pkg/ratelimit"
+	"igscraper/pkg/ui"
 )
 
 // DownloadState represents the state of a download
@@ -34,6 +38,27 @@ type DownloadItem struct {
 	Error       error
 }
 
+// ProfileState represents the state of a profile in a batch
+type ProfileState int
+
+const (
+	ProfilePending ProfileState = iota
+	ProfileActive
+	ProfileCompleted
+	ProfileFailed
+)
+
+// ProfileItem represents one profile being scraped
+type ProfileItem struct {
+	Username   string
+	State      ProfileState
+	Page       int // page of posts being fetched
+	Total      int // posts on the profile, 0 while unknown
+	Downloaded int
+	Errors     int
+	LastError  error
+}
+
 // Model represents the TUI model
 type Model struct {
 	// UI components
@@ -45,6 +70,7 @@ type Model struct {
 	downloadOrder  []string
 	activeDownloads int
 	maxConcurrent  int
+	workers        int // concurrent downloads, below maxConcurrent while autoscaling
 	
 	// Stats
 	totalDownloaded   int
@@ -56,14 +82,33 @@ type Model struct {
 	rateLimitUsed     int
 	rateLimitResetAt  time.Time
 	
+	// Download bandwidth, adjustable with + and -
+	bandwidth *ratelimit.Bandwidth
+	
+	// Request limiter, adjustable with [ and ]. requestsPerMinute is the
+	// rate chosen with the keys, 0 until one is.
+	rateLimiter       ratelimit.Adjustable
+	requestsPerMinute int
+	
+	// Profiles of a batch. The focused profile's downloads are the ones
+	// shown; focus follows the profile being scraped until the user moves it.
+	profiles     map[string]*ProfileItem
+	profileOrder []string
+	focused      string
+	focusPinned  bool
+	
 	// UI state
 	width         int
 	height        int
 	showHelp      bool
 	isPaused      bool
+	aborting      bool
 	logMessages   []LogMessage
 	maxLogMessages int
 	
+	// Pause, skip and abort requests for the scraper
+	controls chan ui.Control
+	
 	// Mutex for thread safety
 	mu sync.RWMutex
 }
@@ -87,11 +132,14 @@ func NewModel(maxConcurrent int) Model {
 		progressBars:     make(map[string]progress.Model),
 		downloads:        make(map[string]*DownloadItem),
 		downloadOrder:    []string{},
+		profiles:         make(map[string]*ProfileItem),
 		maxConcurrent:    maxConcurrent,
+		workers:          maxConcurrent,
 		sessionStartTime: time.Now(),
 		logMessages:      []LogMessage{},
 		maxLogMessages:   50,
 		rateLimitMax:     100, // Default rate limit
+		controls:         make(chan ui.Control, 16),
 	}
 }
 
@@ -153,6 +201,9 @@ func (m *Model) CompleteDownload(id string) {
 		m.activeDownloads--
 		m.totalDownloaded++
 		m.totalSize += download.Size
+		if profile, ok := m.profiles[download.Username]; ok {
+			profile.Downloaded++
+		}
 	}
 }
 
@@ -165,6 +216,10 @@ func (m *Model) FailDownload(id string, err error) {
 		download.State = DownloadFailed
 		download.Error = err
 		m.activeDownloads--
+		if profile, ok := m.profiles[download.Username]; ok {
+			profile.Errors++
+			profile.LastError = err
+		}
 	}
 }
 
@@ -178,6 +233,109 @@ func (m *Model) UpdateRateLimit(used, max int, resetAt time.Time) {
 	m.rateLimitResetAt = resetAt
 }
 
+// UpdateWorkers updates the number of concurrent downloads
+func (m *Model) UpdateWorkers(workers, max int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	m.workers = workers
+	m.maxConcurrent = max
+}
+
+// AddProfile adds a profile waiting to be scraped
+func (m *Model) AddProfile(username string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.profile(username)
+}
+
+// UpdateProfile marks a profile as being scraped and records the page being
+// fetched and the profile's post count
+func (m *Model) UpdateProfile(username string, page, total int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	profile := m.profile(username)
+	profile.State = ProfileActive
+	profile.Page = page
+	if total > 0 {
+		profile.Total = total
+	}
+	if !m.focusPinned {
+		m.focused = username
+	}
+}
+
+// FinishProfile marks a profile as done, or as failed with err
+func (m *Model) FinishProfile(username string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	profile := m.profile(username)
+	if err != nil {
+		profile.State = ProfileFailed
+		profile.Errors++
+		profile.LastError = err
+	} else {
+		profile.State = ProfileCompleted
+	}
+}
+
+// profile returns the profile for username, adding it if it is new. The
+// caller must hold the lock.
+func (m *Model) profile(username string) *ProfileItem {
+	if profile, ok := m.profiles[username]; ok {
+		return profile
+	}
+	profile := &ProfileItem{Username: username}
+	m.profiles[username] = profile
+	m.profileOrder = append(m.profileOrder, username)
+	if m.focused == "" {
+		m.focused = username
+	}
+	return profile
+}
+
+// MoveFocus moves the focus by delta profiles, wrapping around, and keeps it
+// there instead of following the profile being scraped
+func (m *Model) MoveFocus(delta int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	n := len(m.profileOrder)
+	if n == 0 {
+		return
+	}
+	i := slices.Index(m.profileOrder, m.focused)
+	i = ((i+delta)%n + n) % n
+	m.focused = m.profileOrder[i]
+	m.focusPinned = true
+}
+
+// GetProfiles returns copies of the profiles in the order they were added
+func (m *Model) GetProfiles() []ProfileItem {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	
+	profiles := make([]ProfileItem, 0, len(m.profileOrder))
+	for _, username := range m.profileOrder {
+		profiles = append(profiles, *m.profiles[username])
+	}
+	return profiles
+}
+
+// isDashboard returns true when there are several profiles to switch
+// between. The caller must hold the lock.
+func (m *Model) isDashboard() bool {
+	return len(m.profileOrder) > 1
+}
+
+// visible returns true if the download belongs to the focused profile, or
+// there is only one profile. The caller must hold the lock.
+func (m *Model) visible(download *DownloadItem) bool {
+	return !m.isDashboard() || download.Username == m.focused
+}
+
 // AddLogMessage adds a log message
 func (m *Model) AddLogMessage(level, message string) {
 	m.mu.Lock()
@@ -215,13 +373,48 @@ func (m *Model) GetActiveDownloads() []*DownloadItem {
 	
 	var active []*DownloadItem
 	for _, id := range m.downloadOrder {
-		if download := m.downloads[id]; download != nil && download.State == DownloadActive {
+		if download := m.downloads[id]; download != nil && download.State == DownloadActive && m.visible(download) {
 			active = append(active, download)
 		}
 	}
 	return active
 }
 
+// skipCandidate returns the shortcode of the download s skips: the first
+// active download shown, or else the first pending one. It returns "" if
+// there is none.
+func (m *Model) skipCandidate() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	
+	var pending string
+	for _, id := range m.downloadOrder {
+		download := m.downloads[id]
+		if download == nil || !m.visible(download) {
+			continue
+		}
+		if download.State == DownloadActive {
+			return id
+		}
+		if download.State == DownloadPending && pending == "" {
+			pending = id
+		}
+	}
+	return pending
+}
+
+// sendControl passes a request on to the scraper. Requests are dropped while
+// the scraper is not keeping up with them.
+func (m *Model) sendControl(control ui.Control) bool {
+	select {
+	case m.controls <- control:
+		return true
+	default:
+		m.AddLogMessage("WARN", "Busy, try again in a moment")
+		return false
+	}
+}
+
 // GetPendingDownloads returns a slice of pending downloads
 func (m *Model) GetPendingDownloads() []*DownloadItem {
 	m.mu.RLock()
@@ -229,7 +422,7 @@ func (m *Model) GetPendingDownloads() []*DownloadItem {
 	
 	var pending []*DownloadItem
 	for _, id := range m.downloadOrder {
-		if download := m.downloads[id]; download != nil && download.State == DownloadPending {
+		if download := m.downloads[id]; download != nil && download.State == DownloadPending && m.visible(download) {
 			pending = append(pending, download)
 		}
 	}
@@ -243,7 +436,7 @@ func (m *Model) GetCompletedDownloads() []*DownloadItem {
 	
 	var completed []*DownloadItem
 	for _, id := range m.downloadOrder {
-		if download := m.downloads[id]; download != nil && download.State == DownloadCompleted {
+		if download := m.downloads[id]; download != nil && download.State == DownloadCompleted && m.visible(download) {
 			completed = append(completed, download)
 		}
 	}
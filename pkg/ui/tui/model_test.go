@@ -1,8 +1,16 @@
 package tui
 
 import (
+	"errors"
+	"strings"
 	"testing"
 	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"igscraper/pkg/logger"
+	"igscraper/pkg/ratelimit"
+	"igscraper/pkg/ui"
 )
 
 func TestModel(t *testing.T) {
@@ -59,6 +67,221 @@ func TestModel(t *testing.T) {
 	}
 }
 
+func TestBandwidthKeys(t *testing.T) {
+	model := NewModel(3)
+	press := func(key string) {
+		model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+	}
+
+	// Without a limiter the keys do nothing
+	press("-")
+
+	b := ratelimit.NewBandwidth(5 << 20)
+	model.bandwidth = b
+	press("-")
+	if b.Limit() != 2<<20 {
+		t.Errorf("Expected - to lower 5MB/s to 2MB/s, got %s", ratelimit.FormatBandwidth(b.Limit()))
+	}
+	press("+")
+	press("+")
+	if b.Limit() != 10<<20 {
+		t.Errorf("Expected + twice to raise 2MB/s to 10MB/s, got %s", ratelimit.FormatBandwidth(b.Limit()))
+	}
+
+	// Past the highest step the limit is lifted, and lowering starts again
+	// from the top
+	b.SetLimit(50 << 20)
+	press("+")
+	if b.Limit() != 0 {
+		t.Errorf("Expected + above 50MB/s to remove the limit, got %s", ratelimit.FormatBandwidth(b.Limit()))
+	}
+	press("-")
+	if b.Limit() != 50<<20 {
+		t.Errorf("Expected - without a limit to set 50MB/s, got %s", ratelimit.FormatBandwidth(b.Limit()))
+	}
+
+	last := model.logMessages[len(model.logMessages)-1]
+	if last.Message != "Bandwidth limit: 50MB/s" {
+		t.Errorf("Unexpected log message %q", last.Message)
+	}
+
+	// A configured limit below the lowest step is not raised by -
+	b.SetLimit(100 << 10)
+	press("-")
+	if b.Limit() != 100<<10 {
+		t.Errorf("Expected - to keep 100KB/s, got %s", ratelimit.FormatBandwidth(b.Limit()))
+	}
+}
+
+func TestControlKeys(t *testing.T) {
+	model := NewModel(3)
+	press := func(key string) {
+		model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+	}
+	next := func() ui.Control {
+		select {
+		case control := <-model.controls:
+			return control
+		default:
+			t.Fatal("Expected a control to be sent")
+			return ui.Control{}
+		}
+	}
+
+	press("p")
+	if control := next(); control.Action != ui.ControlPause || !model.isPaused {
+		t.Errorf("Expected p to pause, got %+v", control)
+	}
+	press("p")
+	if control := next(); control.Action != ui.ControlResume || model.isPaused {
+		t.Errorf("Expected p again to resume, got %+v", control)
+	}
+
+	// s skips the first active download, or else the first pending one
+	press("s")
+	if len(model.controls) != 0 {
+		t.Error("Expected nothing to skip without downloads")
+	}
+	model.AddDownload("queued", "alice", "queued.jpg", 1024)
+	model.AddDownload("running", "alice", "running.jpg", 1024)
+	model.StartDownload("running")
+	press("s")
+	if control := next(); control.Action != ui.ControlSkip || control.Shortcode != "running" {
+		t.Errorf("Expected the active download to be skipped, got %+v", control)
+	}
+	model.CompleteDownload("running")
+	press("s")
+	if control := next(); control.Shortcode != "queued" {
+		t.Errorf("Expected the pending download to be skipped, got %+v", control)
+	}
+
+	// a aborts once, and lifts a pause so queued downloads can finish
+	press("p")
+	next()
+	press("a")
+	press("a")
+	if control := next(); control.Action != ui.ControlAbort || model.isPaused {
+		t.Errorf("Expected a to abort, got %+v", control)
+	}
+	if len(model.controls) != 0 {
+		t.Error("Expected a second a to do nothing")
+	}
+}
+
+func TestLiveSettingsKeys(t *testing.T) {
+	terminal := NewTUI(3)
+	model := terminal.model
+	press := func(key string) {
+		model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+	}
+
+	// Without a limiter [ and ] do nothing
+	press("]")
+
+	limiter := ratelimit.NewTokenBucket(60, time.Minute)
+	terminal.SetRateLimiter(limiter)
+	press("[")
+	if limiter.Capacity() != 45 {
+		t.Errorf("Expected [ to lower 60/min to 45/min, got %d", limiter.Capacity())
+	}
+	press("]")
+	press("]")
+	if limiter.Capacity() != 90 || model.rateLimitMax != 90 {
+		t.Errorf("Expected ] twice to raise 45/min to 90/min, got %d", limiter.Capacity())
+	}
+
+	// The chosen limit carries over to the next limiter
+	next := ratelimit.NewTokenBucket(60, time.Minute)
+	terminal.SetRateLimiter(next)
+	if next.Capacity() != 90 {
+		t.Errorf("Expected the next limiter to get 90/min, got %d", next.Capacity())
+	}
+
+	// l cycles through the log levels
+	defer logger.SetLevel(logger.Level())
+	logger.SetLevel("info")
+	press("l")
+	if logger.Level() != "warn" {
+		t.Errorf("Expected l to switch info to warn, got %s", logger.Level())
+	}
+	press("l")
+	press("l")
+	if logger.Level() != "debug" {
+		t.Errorf("Expected l to wrap around to debug, got %s", logger.Level())
+	}
+	last := model.logMessages[len(model.logMessages)-1]
+	if last.Message != "Log level: DEBUG" {
+		t.Errorf("Unexpected log message %q", last.Message)
+	}
+}
+
+func TestProfileDashboard(t *testing.T) {
+	model := NewModel(3)
+	model.AddProfile("alice")
+	model.AddProfile("bob")
+	model.AddProfile("carol")
+
+	model.UpdateProfile("alice", 1, 40)
+	model.AddDownload("a1", "alice", "a1.jpg", 1024)
+	model.StartDownload("a1")
+	model.CompleteDownload("a1")
+	model.AddDownload("a2", "alice", "a2.jpg", 1024)
+	model.StartDownload("a2")
+	model.FailDownload("a2", errors.New("timeout"))
+	model.FinishProfile("alice", nil)
+
+	model.UpdateProfile("bob", 2, 0)
+	model.AddDownload("b1", "bob", "b1.jpg", 1024)
+	model.StartDownload("b1")
+
+	profiles := model.GetProfiles()
+	if len(profiles) != 3 {
+		t.Fatalf("Expected 3 profiles, got %d", len(profiles))
+	}
+	alice, bob, carol := profiles[0], profiles[1], profiles[2]
+	if alice.State != ProfileCompleted || alice.Downloaded != 1 || alice.Errors != 1 || alice.Total != 40 {
+		t.Errorf("Unexpected state for alice: %+v", alice)
+	}
+	if bob.State != ProfileActive || bob.Page != 2 {
+		t.Errorf("Unexpected state for bob: %+v", bob)
+	}
+	if carol.State != ProfilePending {
+		t.Errorf("Expected carol to be pending, got %v", carol.State)
+	}
+
+	// Focus follows the profile being scraped, and only its downloads show
+	if model.focused != "bob" {
+		t.Errorf("Expected focus on bob, got %q", model.focused)
+	}
+	if active := model.GetActiveDownloads(); len(active) != 1 || active[0].ID != "b1" {
+		t.Errorf("Expected only bob's download to be active, got %v", active)
+	}
+
+	// Once moved by the user, focus stays put
+	model.Update(tea.KeyMsg{Type: tea.KeyShiftTab})
+	if model.focused != "alice" {
+		t.Errorf("Expected shift+tab to focus alice, got %q", model.focused)
+	}
+	if completed := model.GetCompletedDownloads(); len(completed) != 1 || completed[0].ID != "a1" {
+		t.Errorf("Expected alice's completed download, got %v", completed)
+	}
+	model.UpdateProfile("bob", 3, 0)
+	model.Update(tea.KeyMsg{Type: tea.KeyShiftTab})
+	if model.focused != "carol" {
+		t.Errorf("Expected focus to wrap around to carol, got %q", model.focused)
+	}
+
+	model.FinishProfile("carol", errors.New("profile not found"))
+	if profiles := model.GetProfiles(); profiles[2].State != ProfileFailed || profiles[2].LastError == nil {
+		t.Errorf("Expected carol to have failed, got %+v", profiles[2])
+	}
+
+	model.width, model.height = 160, 60
+	if view := model.View(); !strings.Contains(view, "PROFILES") || !strings.Contains(view, "profile not found") {
+		t.Errorf("Expected the profiles panel with carol's error in the view")
+	}
+}
+
 func TestFormatBytes(t *testing.T) {
 	tests := []struct {
 		bytes    int64
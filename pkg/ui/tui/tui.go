@@ -5,6 +5,9 @@ import (
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+
+	"igscraper/pkg/ratelimit"
+	"igscraper/pkg/ui"
 )
 
 // TUI represents the terminal user interface
@@ -73,6 +76,31 @@ func (t *TUI) UpdateRateLimit(used, max int, resetAt time.Time) {
 	t.Send(SendRateLimitUpdate(used, max, resetAt))
 }
 
+// UpdateWorkers reports the number of concurrent downloads and the most
+// there may be, which differ while the worker pool autoscales
+func (t *TUI) UpdateWorkers(workers, max int) {
+	t.Send(SendWorkersUpdate(workers, max))
+}
+
+// AddProfiles lists the profiles of a batch as waiting, so the dashboard
+// shows them before their turn comes
+func (t *TUI) AddProfiles(usernames ...string) {
+	for _, username := range usernames {
+		t.model.AddProfile(username)
+	}
+}
+
+// UpdateProfile reports the page being fetched for a profile and the
+// profile's post count, 0 if unknown
+func (t *TUI) UpdateProfile(username string, page, total int) {
+	t.Send(SendProfileUpdate(username, page, total))
+}
+
+// FinishProfile reports that a profile is done, or failed with err
+func (t *TUI) FinishProfile(username string, err error) {
+	t.Send(SendProfileDone(username, err))
+}
+
 // Log sends a log message to the TUI
 func (t *TUI) Log(level, format string, args ...interface{}) {
 	message := fmt.Sprintf(format, args...)
@@ -99,9 +127,41 @@ func (t *TUI) LogError(format string, args ...interface{}) {
 	t.Log("ERROR", format, args...)
 }
 
+// SetBandwidth lets the user raise and lower the download bandwidth limit
+// with the + and - keys
+func (t *TUI) SetBandwidth(b *ratelimit.Bandwidth) {
+	t.model.mu.Lock()
+	defer t.model.mu.Unlock()
+	t.model.bandwidth = b
+}
+
+// SetRateLimiter lets the user raise and lower the request limit with the
+// [ and ] keys, if limiter can be adjusted. A limit chosen with the keys
+// carries over to a limiter set later, such as the next profile's in a
+// batch.
+func (t *TUI) SetRateLimiter(limiter ratelimit.Limiter) {
+	adjustable, ok := limiter.(ratelimit.Adjustable)
+	if !ok {
+		return
+	}
+	t.model.mu.Lock()
+	defer t.model.mu.Unlock()
+	if t.model.requestsPerMinute > 0 {
+		adjustable.SetCapacity(t.model.requestsPerMinute)
+	}
+	t.model.rateLimiter = adjustable
+	t.model.rateLimitMax = adjustable.Capacity()
+}
+
 // IsPaused returns whether downloads are paused
 func (t *TUI) IsPaused() bool {
 	t.model.mu.RLock()
 	defer t.model.mu.RUnlock()
 	return t.model.isPaused
-}
\ No newline at end of file
+}
+
+// Controls returns the pause, resume, skip and abort requests the user makes
+// with the p, s and a keys. The scraper applies them to its downloads.
+func (t *TUI) Controls() <-chan ui.Control {
+	return t.model.controls
+}
@@ -1,10 +1,16 @@
 package tui
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
+
+	"igscraper/pkg/logger"
+	"igscraper/pkg/ratelimit"
+	"igscraper/pkg/ui"
 )
 
 // Message types for the TUI
@@ -42,6 +48,26 @@ type RateLimitUpdateMsg struct {
 	ResetAt time.Time
 }
 
+// WorkersUpdateMsg is sent when the number of concurrent downloads changes
+type WorkersUpdateMsg struct {
+	Workers int
+	Max     int
+}
+
+// ProfileUpdateMsg is sent when a profile's next page of posts is fetched
+type ProfileUpdateMsg struct {
+	Username string
+	Page     int
+	Total    int
+}
+
+// ProfileDoneMsg is sent when a profile is finished, with the error it
+// failed with if any
+type ProfileDoneMsg struct {
+	Username string
+	Error    error
+}
+
 // LogMsg is sent to add a log message
 type LogMsg struct {
 	Level   string
@@ -108,6 +134,18 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.UpdateRateLimit(msg.Used, msg.Max, msg.ResetAt)
 		return m, nil
 
+	case WorkersUpdateMsg:
+		m.UpdateWorkers(msg.Workers, msg.Max)
+		return m, nil
+
+	case ProfileUpdateMsg:
+		m.UpdateProfile(msg.Username, msg.Page, msg.Total)
+		return m, nil
+
+	case ProfileDoneMsg:
+		m.FinishProfile(msg.Username, msg.Error)
+		return m, nil
+
 	case LogMsg:
 		m.AddLogMessage(msg.Level, msg.Message)
 		return m, nil
@@ -128,18 +166,75 @@ func (m *Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, tea.Quit
 
 	case "p", "P":
+		control := ui.Control{Action: ui.ControlPause}
+		if m.isPaused {
+			control.Action = ui.ControlResume
+		}
+		if !m.sendControl(control) {
+			return m, nil
+		}
 		m.isPaused = !m.isPaused
 		if m.isPaused {
-			m.AddLogMessage("WARN", "Downloads paused by user")
+			m.AddLogMessage("WARN", "Downloads paused by user, downloads in progress finish")
 		} else {
 			m.AddLogMessage("INFO", "Downloads resumed by user")
 		}
 		return m, nil
 
+	case "s", "S":
+		shortcode := m.skipCandidate()
+		if shortcode == "" {
+			m.AddLogMessage("INFO", "Nothing to skip")
+			return m, nil
+		}
+		if m.sendControl(ui.Control{Action: ui.ControlSkip, Shortcode: shortcode}) {
+			m.AddLogMessage("WARN", fmt.Sprintf("Skipping %s", shortcode))
+		}
+		return m, nil
+
+	case "a", "A":
+		if m.aborting {
+			return m, nil
+		}
+		if m.sendControl(ui.Control{Action: ui.ControlAbort}) {
+			m.aborting = true
+			m.isPaused = false
+			m.AddLogMessage("WARN", "Stopping after the downloads in progress, run again with --resume to continue")
+		}
+		return m, nil
+
 	case "?":
 		m.showHelp = !m.showHelp
 		return m, nil
 
+	case "tab", "right":
+		m.MoveFocus(1)
+		return m, nil
+
+	case "shift+tab", "left":
+		m.MoveFocus(-1)
+		return m, nil
+
+	case "+", "=":
+		m.adjustBandwidth(true)
+		return m, nil
+
+	case "-", "_":
+		m.adjustBandwidth(false)
+		return m, nil
+
+	case "]":
+		m.adjustRequestRate(true)
+		return m, nil
+
+	case "[":
+		m.adjustRequestRate(false)
+		return m, nil
+
+	case "l", "L":
+		m.cycleLogLevel()
+		return m, nil
+
 	case "ctrl+l":
 		// Clear logs
 		m.mu.Lock()
@@ -151,6 +246,112 @@ func (m *Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// bandwidthSteps are the limits + and - step through, in bytes per second.
+// Above the last step there is no limit.
+var bandwidthSteps = []int64{
+	256 << 10, 512 << 10,
+	1 << 20, 2 << 20, 5 << 20, 10 << 20, 20 << 20, 50 << 20,
+}
+
+// adjustBandwidth moves the bandwidth limit to the next step up or down
+func (m *Model) adjustBandwidth(up bool) {
+	m.mu.RLock()
+	b := m.bandwidth
+	m.mu.RUnlock()
+	if b == nil {
+		return
+	}
+
+	current := b.Limit()
+	var next int64
+	if up {
+		if current == 0 {
+			return
+		}
+		for _, step := range bandwidthSteps {
+			if step > current {
+				next = step
+				break
+			}
+		}
+	} else {
+		next = current
+		for _, step := range bandwidthSteps {
+			if step < current || current == 0 {
+				next = step
+			}
+		}
+		if next == current {
+			return
+		}
+	}
+
+	b.SetLimit(next)
+	m.AddLogMessage("INFO", "Bandwidth limit: "+ratelimit.FormatBandwidth(next))
+}
+
+// requestRateSteps are the request limits [ and ] step through, in requests
+// per minute
+var requestRateSteps = []int{5, 10, 20, 30, 45, 60, 90, 120}
+
+// adjustRequestRate moves the request limit one step up or down. Limits
+// between steps move to the nearest step in that direction.
+func (m *Model) adjustRequestRate(up bool) {
+	m.mu.RLock()
+	limiter := m.rateLimiter
+	m.mu.RUnlock()
+	if limiter == nil {
+		return
+	}
+
+	current := limiter.Capacity()
+	next := current
+	if up {
+		for _, step := range requestRateSteps {
+			if step > current {
+				next = step
+				break
+			}
+		}
+	} else {
+		for _, step := range requestRateSteps {
+			if step < current {
+				next = step
+			}
+		}
+	}
+	if next == current {
+		return
+	}
+
+	limiter.SetCapacity(next)
+	m.mu.Lock()
+	m.requestsPerMinute = next
+	m.rateLimitUsed = min(m.rateLimitUsed, next)
+	m.rateLimitMax = next
+	m.mu.Unlock()
+	m.AddLogMessage("INFO", fmt.Sprintf("Request limit: %d/min", next))
+}
+
+// logLevels are the log levels l cycles through
+var logLevels = []string{"debug", "info", "warn", "error"}
+
+// cycleLogLevel switches the log level to the next of logLevels
+func (m *Model) cycleLogLevel() {
+	next := logLevels[0]
+	for i, level := range logLevels {
+		if level == logger.Level() {
+			next = logLevels[(i+1)%len(logLevels)]
+			break
+		}
+	}
+	if err := logger.SetLevel(next); err != nil {
+		m.AddLogMessage("ERROR", err.Error())
+		return
+	}
+	m.AddLogMessage("INFO", "Log level: "+strings.ToUpper(next))
+}
+
 // Commands
 
 // tickCmd returns a command that sends a tick message
@@ -200,6 +401,29 @@ func SendRateLimitUpdate(used, max int, resetAt time.Time) tea.Msg {
 	}
 }
 
+// SendWorkersUpdate creates a message to update the number of concurrent
+// downloads
+func SendWorkersUpdate(workers, max int) tea.Msg {
+	return WorkersUpdateMsg{
+		Workers: workers,
+		Max:     max,
+	}
+}
+
+// SendProfileUpdate creates a message when a profile's next page is fetched
+func SendProfileUpdate(username string, page, total int) tea.Msg {
+	return ProfileUpdateMsg{
+		Username: username,
+		Page:     page,
+		Total:    total,
+	}
+}
+
+// SendProfileDone creates a message when a profile is finished
+func SendProfileDone(username string, err error) tea.Msg {
+	return ProfileDoneMsg{Username: username, Error: err}
+}
+
 // SendLog creates a log message
 func SendLog(level, message string) tea.Msg {
 	return LogMsg{Level: level, Message: message}
@@ -6,6 +6,9 @@ import (
 	"time"
 
 	"github.com/charmbracelet/lipgloss"
+
+	"igscraper/pkg/logger"
+	"igscraper/pkg/ratelimit"
 )
 
 // View renders the entire TUI
@@ -70,6 +73,11 @@ func (m *Model) renderLeftColumn() string {
 	// Stats panel
 	sections = append(sections, m.renderStatsPanel(width))
 
+	// Profiles panel, in batch mode
+	if profiles := m.renderProfilesPanel(width); profiles != "" {
+		sections = append(sections, profiles)
+	}
+
 	// Active downloads panel
 	sections = append(sections, m.renderActiveDownloadsPanel(width))
 
@@ -113,6 +121,23 @@ func (m *Model) renderStatsPanel(width int) string {
 		fmt.Sprintf("%s %s", statsLabelStyle.Render("ETA:"), statsValueStyle.Render(formatDuration(eta))),
 	}
 
+	if m.workers > 0 {
+		workers := fmt.Sprintf("%d", m.workers)
+		if m.workers != m.maxConcurrent {
+			workers = fmt.Sprintf("%d/%d", m.workers, m.maxConcurrent)
+		}
+		stats = append(stats, fmt.Sprintf("%s %s", statsLabelStyle.Render("Workers:"), statsValueStyle.Render(workers)))
+	}
+
+	if m.bandwidth != nil {
+		stats = append(stats, fmt.Sprintf("%s %s", statsLabelStyle.Render("Bandwidth Limit:"), statsValueStyle.Render(ratelimit.FormatBandwidth(m.bandwidth.Limit()))))
+	}
+
+	if m.rateLimiter != nil {
+		stats = append(stats, fmt.Sprintf("%s %s", statsLabelStyle.Render("Request Limit:"), statsValueStyle.Render(fmt.Sprintf("%d/min", m.rateLimiter.Capacity()))))
+	}
+	stats = append(stats, fmt.Sprintf("%s %s", statsLabelStyle.Render("Log Level:"), statsValueStyle.Render(strings.ToUpper(logger.Level()))))
+
 	if m.isPaused {
 		stats = append(stats, warningStyle.Render("⏸  PAUSED"))
 	}
@@ -124,9 +149,70 @@ func (m *Model) renderStatsPanel(width int) string {
 	)
 }
 
-// renderActiveDownloadsPanel renders the active downloads
+// renderProfilesPanel renders a line for each profile of a batch with its
+// page, downloads and errors, and the focused profile's last error. It
+// renders nothing for a single profile.
+func (m *Model) renderProfilesPanel(width int) string {
+	m.mu.RLock()
+	dashboard, focused := m.isDashboard(), m.focused
+	m.mu.RUnlock()
+	if !dashboard {
+		return ""
+	}
+
+	title := titleStyle.Render(" PROFILES ")
+	line := lipgloss.NewStyle().MaxWidth(width - 4)
+
+	var rows []string
+	for _, profile := range m.GetProfiles() {
+		icon, style := "⏳", queueItemStyle
+		switch profile.State {
+		case ProfileActive:
+			icon, style = "▶", queueItemActiveStyle
+		case ProfileCompleted:
+			icon, style = "✓", queueItemCompletedStyle
+		case ProfileFailed:
+			icon, style = "✗", errorStyle
+		}
+
+		marker := "  "
+		if profile.Username == focused {
+			marker = "» "
+		}
+		row := marker + style.Render(icon+" "+profile.Username)
+		if profile.State != ProfilePending {
+			downloaded := fmt.Sprintf("%d", profile.Downloaded)
+			if profile.Total > 0 {
+				downloaded += fmt.Sprintf("/%d", profile.Total)
+			}
+			row += fmt.Sprintf(" %s %s",
+				statsLabelStyle.Render(fmt.Sprintf("page %d", profile.Page)),
+				statsValueStyle.Render(downloaded))
+		}
+		if profile.Errors > 0 {
+			row += " " + errorStyle.Render(fmt.Sprintf("%d errors", profile.Errors))
+		}
+		rows = append(rows, line.Render(row))
+
+		if profile.Username == focused && profile.LastError != nil {
+			rows = append(rows, line.Render(errorStyle.Render("    "+profile.LastError.Error())))
+		}
+	}
+
+	return panelStyle.Width(width).Render(
+		lipgloss.JoinVertical(lipgloss.Left, title, lipgloss.JoinVertical(lipgloss.Left, rows...)),
+	)
+}
+
+// renderActiveDownloadsPanel renders the active downloads of the focused
+// profile
 func (m *Model) renderActiveDownloadsPanel(width int) string {
 	title := titleStyle.Render(" ACTIVE DOWNLOADS ")
+	m.mu.RLock()
+	if m.isDashboard() {
+		title = titleStyle.Render(" ACTIVE DOWNLOADS: " + m.focused + " ")
+	}
+	m.mu.RUnlock()
 	
 	active := m.GetActiveDownloads()
 	
@@ -306,6 +392,13 @@ func (m *Model) renderHelp() string {
   Navigation:
     q/Q      - Quit the application
     p/P      - Pause/Resume downloads
+    s/S      - Skip the current download
+    a/A      - Stop after the downloads in progress
+    +/-      - Raise/Lower the bandwidth limit
+    ]/[      - Raise/Lower the request limit
+    l/L      - Cycle the log level
+    tab/→    - Focus the next profile of a batch
+    shift+tab/← - Focus the previous profile
     ?        - Toggle this help
 
   Status Indicators:
@@ -316,6 +409,7 @@ func (m *Model) renderHelp() string {
   Icons:
     ⏳       - Pending download
     ✓        - Completed download
+    ▶/✗      - Profile being scraped/failed
     ⏸        - Paused
     █        - Progress indicator
 `
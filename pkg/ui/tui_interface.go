@@ -9,9 +9,130 @@ type TUI interface {
 	CompleteDownload(id string)
 	FailDownload(id string, err error)
 	UpdateRateLimit(used, max int, resetAt time.Time)
+	UpdateWorkers(workers, max int)
+	UpdateProfile(username string, page, total int)
+	FinishProfile(username string, err error)
 	LogInfo(format string, args ...interface{})
 	LogSuccess(format string, args ...interface{})
 	LogWarning(format string, args ...interface{})
 	LogError(format string, args ...interface{})
 	IsPaused() bool
-}
\ No newline at end of file
+	Controls() <-chan Control
+}
+
+// ControlAction is something the user asks a running scrape to do
+type ControlAction int
+
+const (
+	// ControlPause stops new downloads from starting
+	ControlPause ControlAction = iota
+	// ControlResume lets downloads start again
+	ControlResume
+	// ControlSkip drops or stops the download of Control.Shortcode
+	ControlSkip
+	// ControlAbort stops the scrape once the downloads in progress finish,
+	// keeping the checkpoint
+	ControlAbort
+)
+
+// Control is a request from the user to the running scrape
+type Control struct {
+	Action    ControlAction
+	Shortcode string
+}
+
+// Multi returns a TUI that reports to every one of tuis, such as the terminal
+// UI and the web monitor. Pausing and controls come from the first, which is
+// the one the user interacts with.
+func Multi(tuis ...TUI) TUI {
+	if len(tuis) == 1 {
+		return tuis[0]
+	}
+	return multiTUI(tuis)
+}
+
+// multiTUI forwards every update to each of its TUIs
+type multiTUI []TUI
+
+func (m multiTUI) StartDownload(id, username, filename string, size int64) {
+	for _, t := range m {
+		t.StartDownload(id, username, filename, size)
+	}
+}
+
+func (m multiTUI) UpdateDownloadProgress(id string, downloaded int64, speed float64) {
+	for _, t := range m {
+		t.UpdateDownloadProgress(id, downloaded, speed)
+	}
+}
+
+func (m multiTUI) CompleteDownload(id string) {
+	for _, t := range m {
+		t.CompleteDownload(id)
+	}
+}
+
+func (m multiTUI) FailDownload(id string, err error) {
+	for _, t := range m {
+		t.FailDownload(id, err)
+	}
+}
+
+func (m multiTUI) UpdateWorkers(workers, max int) {
+	for _, t := range m {
+		t.UpdateWorkers(workers, max)
+	}
+}
+
+func (m multiTUI) UpdateRateLimit(used, max int, resetAt time.Time) {
+	for _, t := range m {
+		t.UpdateRateLimit(used, max, resetAt)
+	}
+}
+
+func (m multiTUI) UpdateProfile(username string, page, total int) {
+	for _, t := range m {
+		t.UpdateProfile(username, page, total)
+	}
+}
+
+func (m multiTUI) FinishProfile(username string, err error) {
+	for _, t := range m {
+		t.FinishProfile(username, err)
+	}
+}
+
+func (m multiTUI) LogInfo(format string, args ...interface{}) {
+	for _, t := range m {
+		t.LogInfo(format, args...)
+	}
+}
+
+func (m multiTUI) LogSuccess(format string, args ...interface{}) {
+	for _, t := range m {
+		t.LogSuccess(format, args...)
+	}
+}
+
+func (m multiTUI) LogWarning(format string, args ...interface{}) {
+	for _, t := range m {
+		t.LogWarning(format, args...)
+	}
+}
+
+func (m multiTUI) LogError(format string, args ...interface{}) {
+	for _, t := range m {
+		t.LogError(format, args...)
+	}
+}
+
+func (m multiTUI) IsPaused() bool {
+	return len(m) > 0 && m[0].IsPaused()
+}
+
+func (m multiTUI) Controls() <-chan Control {
+	if len(m) == 0 {
+		return nil
+	}
+	return m[0].Controls()
+}
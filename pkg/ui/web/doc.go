@@ -0,0 +1,19 @@
+// Package web serves the progress of a scrape over HTTP, for watching a
+// headless run on a server or in a container from a browser.
+//
+// A Monitor implements ui.TUI and keeps what the terminal UI would show:
+// profiles, active and queued downloads, recent results, the request budget
+// and the latest log messages. A Server serves it as a page on / and as JSON
+// on /api/status:
+//
+//	monitor := web.NewMonitor()
+//	server, err := web.NewServer("127.0.0.1:8080", monitor)
+//	if err != nil {
+//	    return err
+//	}
+//	defer server.Close()
+//	s.SetTUI(monitor)
+//
+// The server has no authentication. Listen on a loopback address and reach
+// it through an SSH tunnel, or put it behind a proxy that adds some.
+package web
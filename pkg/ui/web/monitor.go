@@ -0,0 +1,369 @@
+package web
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"igscraper/pkg/ui"
+)
+
+const (
+	// maxRecent is the number of finished downloads kept for the page
+	maxRecent = 50
+
+	// maxLogs is the number of log messages kept for the page
+	maxLogs = 100
+)
+
+// Download is a download the monitor knows of
+type Download struct {
+	ID         string    `json:"id"`
+	Username   string    `json:"username"`
+	Filename   string    `json:"filename"`
+	Size       int64     `json:"size"`
+	Downloaded int64     `json:"downloaded"`
+	Speed      float64   `json:"speed"`
+	State      string    `json:"state"`
+	Error      string    `json:"error,omitempty"`
+	StartedAt  time.Time `json:"started_at,omitempty"`
+	FinishedAt time.Time `json:"finished_at,omitempty"`
+}
+
+// Download states
+const (
+	StateQueued    = "queued"
+	StateActive    = "active"
+	StateCompleted = "completed"
+	StateFailed    = "failed"
+)
+
+// Profile is a profile being scraped, or waiting its turn in a batch
+type Profile struct {
+	Username   string `json:"username"`
+	State      string `json:"state"`
+	Page       int    `json:"page"`
+	Total      int    `json:"total"`
+	Downloaded int    `json:"downloaded"`
+	Errors     int    `json:"errors"`
+	LastError  string `json:"last_error,omitempty"`
+}
+
+// Profile states
+const (
+	ProfilePending   = "pending"
+	ProfileActive    = "active"
+	ProfileCompleted = "completed"
+	ProfileFailed    = "failed"
+)
+
+// RateLimit is the state of the request budget
+type RateLimit struct {
+	Used    int       `json:"used"`
+	Max     int       `json:"max"`
+	ResetAt time.Time `json:"reset_at,omitempty"`
+}
+
+// Workers is the number of concurrent downloads and the most there may be,
+// which differ while the worker pool autoscales
+type Workers struct {
+	Current int `json:"current"`
+	Max     int `json:"max"`
+}
+
+// LogMessage is a message the scraper logged for the user
+type LogMessage struct {
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level"`
+	Message string    `json:"message"`
+}
+
+// Status is a snapshot of everything the monitor shows, as served by
+// /api/status
+type Status struct {
+	StartedAt  time.Time    `json:"started_at"`
+	Uptime     float64      `json:"uptime_seconds"`
+	Downloaded int          `json:"downloaded"`
+	Failed     int          `json:"failed"`
+	Bytes      int64        `json:"bytes"`
+	Speed      float64      `json:"speed"`
+	RateLimit  RateLimit    `json:"rate_limit"`
+	Workers    Workers      `json:"workers"`
+	Profiles   []Profile    `json:"profiles"`
+	Active     []Download   `json:"active"`
+	Queued     []Download   `json:"queued"`
+	Recent     []Download   `json:"recent"`
+	Logs       []LogMessage `json:"logs"`
+}
+
+// Monitor collects the progress of a scrape for the web page. It implements
+// ui.TUI, so the scraper reports to it as it does to the terminal UI. Only
+// the most recent finished downloads and log messages are kept, so a daemon
+// can run for weeks without it growing.
+type Monitor struct {
+	mu           sync.Mutex
+	started      time.Time
+	now          func() time.Time
+	downloads    map[string]*Download // queued and active
+	order        []string
+	recent       []Download // newest last
+	profiles     map[string]*Profile
+	profileOrder []string
+	downloaded   int
+	failed       int
+	bytes        int64
+	rateLimit    RateLimit
+	workers      Workers
+	logs         []LogMessage
+}
+
+// NewMonitor creates an empty monitor
+func NewMonitor() *Monitor {
+	return &Monitor{
+		started:   time.Now(),
+		now:       time.Now,
+		downloads: make(map[string]*Download),
+		profiles:  make(map[string]*Profile),
+	}
+}
+
+// AddProfiles lists the profiles of a batch or daemon as waiting
+func (m *Monitor) AddProfiles(usernames ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, username := range usernames {
+		m.profile(username)
+	}
+}
+
+// StartDownload records a download that has been queued
+func (m *Monitor) StartDownload(id, username, filename string, size int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.downloads[id]; !ok {
+		m.order = append(m.order, id)
+	}
+	m.downloads[id] = &Download{
+		ID:       id,
+		Username: username,
+		Filename: filename,
+		Size:     size,
+		State:    StateQueued,
+	}
+}
+
+// UpdateDownloadProgress records how much of a download has been written
+func (m *Monitor) UpdateDownloadProgress(id string, downloaded int64, speed float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	download, ok := m.downloads[id]
+	if !ok {
+		return
+	}
+	if download.State == StateQueued {
+		download.State = StateActive
+		download.StartedAt = m.now()
+	}
+	download.Downloaded = downloaded
+	download.Speed = speed
+	download.Size = max(download.Size, downloaded)
+}
+
+// CompleteDownload records a finished download
+func (m *Monitor) CompleteDownload(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	download := m.finish(id, StateCompleted)
+	if download == nil {
+		return
+	}
+	download.Size = max(download.Downloaded, 0)
+	m.downloaded++
+	m.bytes += download.Downloaded
+	if p, ok := m.profiles[download.Username]; ok {
+		p.Downloaded++
+	}
+	m.addRecent(*download)
+}
+
+// FailDownload records a failed download
+func (m *Monitor) FailDownload(id string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	download := m.finish(id, StateFailed)
+	if download == nil {
+		return
+	}
+	if err != nil {
+		download.Error = err.Error()
+	}
+	m.failed++
+	if p, ok := m.profiles[download.Username]; ok {
+		p.Errors++
+	}
+	m.addRecent(*download)
+}
+
+// UpdateRateLimit records the state of the request budget
+func (m *Monitor) UpdateRateLimit(used, max int, resetAt time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rateLimit = RateLimit{Used: used, Max: max, ResetAt: resetAt}
+}
+
+// UpdateWorkers records the number of concurrent downloads
+func (m *Monitor) UpdateWorkers(workers, max int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.workers = Workers{Current: workers, Max: max}
+}
+
+// UpdateProfile records the page being fetched for a profile and its post
+// count, 0 if unknown
+func (m *Monitor) UpdateProfile(username string, page, total int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p := m.profile(username)
+	p.State = ProfileActive
+	p.Page = page
+	if total > 0 {
+		p.Total = total
+	}
+}
+
+// FinishProfile records that a profile is done, or failed with err
+func (m *Monitor) FinishProfile(username string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p := m.profile(username)
+	p.State = ProfileCompleted
+	p.LastError = ""
+	if err != nil {
+		p.State = ProfileFailed
+		p.LastError = err.Error()
+	}
+}
+
+// LogInfo logs an info message
+func (m *Monitor) LogInfo(format string, args ...interface{}) {
+	m.log("INFO", format, args...)
+}
+
+// LogSuccess logs a success message
+func (m *Monitor) LogSuccess(format string, args ...interface{}) {
+	m.log("SUCCESS", format, args...)
+}
+
+// LogWarning logs a warning message
+func (m *Monitor) LogWarning(format string, args ...interface{}) {
+	m.log("WARN", format, args...)
+}
+
+// LogError logs an error message
+func (m *Monitor) LogError(format string, args ...interface{}) {
+	m.log("ERROR", format, args...)
+}
+
+// IsPaused returns false: the page only shows progress
+func (m *Monitor) IsPaused() bool {
+	return false
+}
+
+// Controls returns nil, as the page sends no controls
+func (m *Monitor) Controls() <-chan ui.Control {
+	return nil
+}
+
+// Status returns a snapshot of the scrape's progress
+func (m *Monitor) Status() Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	status := Status{
+		StartedAt:  m.started,
+		Uptime:     now.Sub(m.started).Seconds(),
+		Downloaded: m.downloaded,
+		Failed:     m.failed,
+		Bytes:      m.bytes,
+		RateLimit:  m.rateLimit,
+		Workers:    m.workers,
+		Profiles:   []Profile{},
+		Active:     []Download{},
+		Queued:     []Download{},
+		Recent:     append([]Download{}, m.recent...),
+		Logs:       append([]LogMessage{}, m.logs...),
+	}
+	for _, username := range m.profileOrder {
+		status.Profiles = append(status.Profiles, *m.profiles[username])
+	}
+	for _, id := range m.order {
+		download := *m.downloads[id]
+		if download.State == StateActive {
+			status.Active = append(status.Active, download)
+			status.Speed += download.Speed
+		} else {
+			status.Queued = append(status.Queued, download)
+		}
+	}
+	return status
+}
+
+// profile returns the entry for username, adding a waiting one if there is
+// none. The caller holds m.mu.
+func (m *Monitor) profile(username string) *Profile {
+	p, ok := m.profiles[username]
+	if !ok {
+		p = &Profile{Username: username, State: ProfilePending}
+		m.profiles[username] = p
+		m.profileOrder = append(m.profileOrder, username)
+	}
+	return p
+}
+
+// finish removes a download from the queued and active ones and marks it
+// with state. It returns nil if the download is unknown. The caller holds
+// m.mu.
+func (m *Monitor) finish(id, state string) *Download {
+	download, ok := m.downloads[id]
+	if !ok {
+		return nil
+	}
+	delete(m.downloads, id)
+	for i, queued := range m.order {
+		if queued == id {
+			m.order = append(m.order[:i], m.order[i+1:]...)
+			break
+		}
+	}
+	download.State = state
+	download.Speed = 0
+	download.FinishedAt = m.now()
+	return download
+}
+
+// addRecent keeps a finished download, dropping the oldest beyond maxRecent.
+// The caller holds m.mu.
+func (m *Monitor) addRecent(download Download) {
+	m.recent = append(m.recent, download)
+	if extra := len(m.recent) - maxRecent; extra > 0 {
+		m.recent = append(m.recent[:0], m.recent[extra:]...)
+	}
+}
+
+// log keeps a message, dropping the oldest beyond maxLogs
+func (m *Monitor) log(level, format string, args ...interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.logs = append(m.logs, LogMessage{Time: m.now(), Level: level, Message: fmt.Sprintf(format, args...)})
+	if extra := len(m.logs) - maxLogs; extra > 0 {
+		m.logs = append(m.logs[:0], m.logs[extra:]...)
+	}
+}
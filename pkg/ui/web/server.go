@@ -0,0 +1,76 @@
+package web
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+//go:embed index.html
+var indexPage []byte
+
+// Server serves the progress page and its JSON API. It has no
+// authentication, so it should listen on a loopback address or behind a
+// proxy that adds some.
+type Server struct {
+	listener   net.Listener
+	httpServer *http.Server
+	monitor    *Monitor
+}
+
+// NewServer starts serving the monitor's progress on addr, such as ":8080"
+// or "127.0.0.1:8080"
+func NewServer(addr string, monitor *Monitor) (*Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start web server: %w", err)
+	}
+
+	s := &Server{listener: listener, monitor: monitor}
+	s.httpServer = &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go s.httpServer.Serve(listener)
+
+	return s, nil
+}
+
+// Handler returns an HTTP handler serving the page on / and the status on
+// /api/status
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/status", s.handleStatus)
+	mux.HandleFunc("/", s.handleIndex)
+	return mux
+}
+
+// URL returns the address of the page
+func (s *Server) URL() string {
+	return "http://" + s.listener.Addr().String()
+}
+
+// Close shuts the server down
+func (s *Server) Close() error {
+	return s.httpServer.Close()
+}
+
+// handleIndex serves the page, which polls /api/status
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(indexPage)
+}
+
+// handleStatus serves the monitor's snapshot as JSON
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(s.monitor.Status())
+}
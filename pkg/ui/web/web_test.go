@@ -0,0 +1,168 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"igscraper/pkg/ui"
+)
+
+func TestMonitor(t *testing.T) {
+	m := NewMonitor()
+	m.AddProfiles("alice", "bob")
+
+	m.UpdateProfile("alice", 2, 10)
+	m.StartDownload("A1", "alice", "A1.jpg", 500)
+	m.StartDownload("A2", "alice", "A2.jpg", 500)
+	m.StartDownload("A3", "alice", "A3.mp4", 500)
+	m.UpdateDownloadProgress("A1", 800, 400)
+	m.UpdateRateLimit(12, 60, time.Now().Add(time.Minute))
+	m.LogWarning("Rate limited, cooling down for %s", "30s")
+
+	status := m.Status()
+	if len(status.Profiles) != 2 || status.Profiles[0].State != ProfileActive || status.Profiles[1].State != ProfilePending {
+		t.Fatalf("profiles = %+v, want alice active and bob pending", status.Profiles)
+	}
+	if status.Profiles[0].Page != 2 || status.Profiles[0].Total != 10 {
+		t.Errorf("alice = %+v, want page 2 of 10 posts", status.Profiles[0])
+	}
+	if len(status.Active) != 1 || status.Active[0].ID != "A1" || status.Active[0].Size != 800 {
+		t.Errorf("active = %+v, want A1 grown to 800 bytes", status.Active)
+	}
+	if len(status.Queued) != 2 || status.Queued[0].ID != "A2" || status.Queued[1].ID != "A3" {
+		t.Errorf("queued = %+v, want A2 and A3 in order", status.Queued)
+	}
+	if status.Speed != 400 {
+		t.Errorf("speed = %v, want 400", status.Speed)
+	}
+	if status.RateLimit.Used != 12 || status.RateLimit.Max != 60 {
+		t.Errorf("rate limit = %+v, want 12/60", status.RateLimit)
+	}
+	if len(status.Logs) != 1 || status.Logs[0].Level != "WARN" || status.Logs[0].Message != "Rate limited, cooling down for 30s" {
+		t.Errorf("logs = %+v", status.Logs)
+	}
+
+	m.CompleteDownload("A1")
+	m.FailDownload("A2", errors.New("HTTP 404"))
+	m.CompleteDownload("unknown")
+	m.FinishProfile("alice", nil)
+
+	status = m.Status()
+	if status.Downloaded != 1 || status.Failed != 1 || status.Bytes != 800 {
+		t.Errorf("totals = %d downloaded, %d failed, %d bytes, want 1, 1 and 800", status.Downloaded, status.Failed, status.Bytes)
+	}
+	if len(status.Active) != 0 || len(status.Queued) != 1 {
+		t.Errorf("active = %+v, queued = %+v, want only A3 queued", status.Active, status.Queued)
+	}
+	if len(status.Recent) != 2 || status.Recent[1].State != StateFailed || status.Recent[1].Error != "HTTP 404" {
+		t.Errorf("recent = %+v, want A1 then the failed A2", status.Recent)
+	}
+	if p := status.Profiles[0]; p.State != ProfileCompleted || p.Downloaded != 1 || p.Errors != 1 {
+		t.Errorf("alice = %+v, want completed with 1 download and 1 error", p)
+	}
+}
+
+func TestMonitorKeepsRecent(t *testing.T) {
+	m := NewMonitor()
+	for i := 0; i < maxRecent+10; i++ {
+		id := fmt.Sprintf("P%d", i)
+		m.StartDownload(id, "alice", id+".jpg", 100)
+		m.CompleteDownload(id)
+	}
+	for i := 0; i < maxLogs+10; i++ {
+		m.LogInfo("message %d", i)
+	}
+
+	status := m.Status()
+	if len(status.Recent) != maxRecent || status.Recent[0].ID != "P10" {
+		t.Errorf("recent has %d downloads starting at %s, want %d starting at P10", len(status.Recent), status.Recent[0].ID, maxRecent)
+	}
+	if len(status.Logs) != maxLogs || status.Logs[0].Message != "message 10" {
+		t.Errorf("logs has %d messages starting at %q, want %d starting at message 10", len(status.Logs), status.Logs[0].Message, maxLogs)
+	}
+	if status.Downloaded != maxRecent+10 {
+		t.Errorf("downloaded = %d, want %d", status.Downloaded, maxRecent+10)
+	}
+}
+
+func TestServer(t *testing.T) {
+	m := NewMonitor()
+	server, err := NewServer("127.0.0.1:0", m)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	defer server.Close()
+
+	m.UpdateProfile("alice", 1, 0)
+	m.StartDownload("A1", "alice", "A1.jpg", 500)
+
+	resp, err := http.Get(server.URL() + "/api/status")
+	if err != nil {
+		t.Fatalf("GET /api/status: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	var status Status
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		t.Fatalf("decoding status: %v", err)
+	}
+	if len(status.Profiles) != 1 || len(status.Queued) != 1 || status.Queued[0].Filename != "A1.jpg" {
+		t.Errorf("status = %+v, want alice with A1.jpg queued", status)
+	}
+
+	resp, err = http.Get(server.URL() + "/")
+	if err != nil {
+		t.Fatalf("GET /: %v", err)
+	}
+	page, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(page), "api/status") {
+		t.Error("page does not poll api/status")
+	}
+
+	resp, err = http.Get(server.URL() + "/missing")
+	if err != nil {
+		t.Fatalf("GET /missing: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET /missing = %d, want 404", resp.StatusCode)
+	}
+}
+
+// recorder is a TUI that counts the updates it is sent
+type recorder struct {
+	ui.TUI
+	started  int
+	paused   bool
+	controls chan ui.Control
+}
+
+func (r *recorder) StartDownload(id, username, filename string, size int64) { r.started++ }
+func (r *recorder) IsPaused() bool                                          { return r.paused }
+func (r *recorder) Controls() <-chan ui.Control                             { return r.controls }
+
+func TestMulti(t *testing.T) {
+	first := &recorder{paused: true, controls: make(chan ui.Control)}
+	monitor := NewMonitor()
+	multi := ui.Multi(first, monitor)
+
+	multi.StartDownload("A1", "alice", "A1.jpg", 500)
+	if first.started != 1 || len(monitor.Status().Queued) != 1 {
+		t.Error("the download was not reported to both TUIs")
+	}
+	if !multi.IsPaused() || multi.Controls() != first.controls {
+		t.Error("pausing and controls do not come from the first TUI")
+	}
+	if ui.Multi(monitor) != monitor {
+		t.Error("Multi of one TUI should return it")
+	}
+}
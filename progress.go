@@ -0,0 +1,216 @@
+package igscraper
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"igscraper/pkg/logger"
+	"igscraper/pkg/ui"
+)
+
+// EventKind tells what an Event reports
+type EventKind int
+
+const (
+	// EventPage reports that a page of a profile's posts was fetched
+	EventPage EventKind = iota + 1
+	// EventDownloadStarted reports that a file was queued for download
+	EventDownloadStarted
+	// EventDownloadProgress reports how much of a file has been written
+	EventDownloadProgress
+	// EventDownloadCompleted reports that a file was saved, or was already
+	// on disk
+	EventDownloadCompleted
+	// EventDownloadFailed reports that a file could not be saved, or was
+	// dropped as the scrape stopped
+	EventDownloadFailed
+	// EventRateLimited reports that the scrape waits for Instagram's rate
+	// limit until Event.Until
+	EventRateLimited
+)
+
+// String returns the name of the kind, such as "download_completed"
+func (k EventKind) String() string {
+	switch k {
+	case EventPage:
+		return "page"
+	case EventDownloadStarted:
+		return "download_started"
+	case EventDownloadProgress:
+		return "download_progress"
+	case EventDownloadCompleted:
+		return "download_completed"
+	case EventDownloadFailed:
+		return "download_failed"
+	case EventRateLimited:
+		return "rate_limited"
+	default:
+		return fmt.Sprintf("EventKind(%d)", int(k))
+	}
+}
+
+// Event is a step of a scrape. Only the fields that make sense for its Kind
+// are set.
+type Event struct {
+	Kind EventKind
+
+	// Username is the profile being scraped
+	Username string
+
+	// Page is the number of the page fetched, from 1, and Posts the number
+	// of posts of the profile less those skipped so far, such as by a date
+	// range, or 0 if it is not known yet
+	Page  int
+	Posts int
+
+	// Shortcode is the post a download is of, and File the name of its file
+	// in the profile's folder
+	Shortcode string
+	File      string
+
+	// Size is the expected size of a download, or 0 if it is not known.
+	// Bytes is how much of it has been written, and Speed how fast in bytes
+	// a second.
+	Size  int64
+	Bytes int64
+	Speed float64
+
+	// Err is why a download failed
+	Err error
+
+	// Until is when the scrape carries on after a rate limit
+	Until time.Time
+}
+
+// Progress receives the events of a scrape. Report is called from the
+// download workers as well as from ScrapeUser's goroutine, so it must be
+// safe to call concurrently, and it should return quickly as the scrape
+// waits for it.
+type Progress interface {
+	Report(Event)
+}
+
+// ProgressFunc is a function that is a Progress
+type ProgressFunc func(Event)
+
+// Report calls f(e)
+func (f ProgressFunc) Report(e Event) {
+	f(e)
+}
+
+// download is what progressTUI knows of a download in progress
+type download struct {
+	username string
+	file     string
+	size     int64
+	written  int64
+}
+
+// progressTUI passes on what the scraper shows in its terminal UI to a
+// Progress and to the log. It never pauses and has no controls.
+type progressTUI struct {
+	progress Progress
+	logger   logger.Logger
+
+	mu        sync.Mutex
+	downloads map[string]*download
+	pages     map[string]int // the last page reported of each profile
+}
+
+func newProgressTUI(progress Progress, log logger.Logger) *progressTUI {
+	return &progressTUI{
+		progress:  progress,
+		logger:    log,
+		downloads: make(map[string]*download),
+		pages:     make(map[string]int),
+	}
+}
+
+// report passes e on to the Progress, if there is one
+func (t *progressTUI) report(e Event) {
+	if t.progress != nil {
+		t.progress.Report(e)
+	}
+}
+
+func (t *progressTUI) StartDownload(id, username, filename string, size int64) {
+	t.mu.Lock()
+	t.downloads[id] = &download{username: username, file: filename, size: size}
+	t.mu.Unlock()
+	t.report(Event{Kind: EventDownloadStarted, Username: username, Shortcode: id, File: filename, Size: size})
+}
+
+func (t *progressTUI) UpdateDownloadProgress(id string, downloaded int64, speed float64) {
+	var d download
+	t.mu.Lock()
+	if known, ok := t.downloads[id]; ok {
+		known.written = downloaded
+		d = *known
+	}
+	t.mu.Unlock()
+	t.report(Event{Kind: EventDownloadProgress, Username: d.username, Shortcode: id, File: d.file, Size: d.size, Bytes: downloaded, Speed: speed})
+}
+
+func (t *progressTUI) CompleteDownload(id string) {
+	d := t.finish(id)
+	t.report(Event{Kind: EventDownloadCompleted, Username: d.username, Shortcode: id, File: d.file, Size: d.size, Bytes: d.written})
+}
+
+func (t *progressTUI) FailDownload(id string, err error) {
+	d := t.finish(id)
+	t.report(Event{Kind: EventDownloadFailed, Username: d.username, Shortcode: id, File: d.file, Size: d.size, Bytes: d.written, Err: err})
+}
+
+// finish forgets a download, returning what was known of it
+func (t *progressTUI) finish(id string) download {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	d, ok := t.downloads[id]
+	if !ok {
+		return download{}
+	}
+	delete(t.downloads, id)
+	return *d
+}
+
+func (t *progressTUI) UpdateRateLimit(used, max int, resetAt time.Time) {
+	// The scraper reports an unused budget when a pause ends
+	if used > 0 {
+		t.report(Event{Kind: EventRateLimited, Until: resetAt})
+	}
+}
+
+// UpdateProfile reports a page once: the scraper updates the total of the
+// page as it skips posts
+func (t *progressTUI) UpdateProfile(username string, page, total int) {
+	t.mu.Lock()
+	reported := t.pages[username] == page
+	t.pages[username] = page
+	t.mu.Unlock()
+	if reported {
+		return
+	}
+	t.report(Event{Kind: EventPage, Username: username, Page: page, Posts: total})
+}
+
+func (t *progressTUI) UpdateWorkers(workers, max int)           {}
+func (t *progressTUI) FinishProfile(username string, err error) {}
+func (t *progressTUI) IsPaused() bool                           { return false }
+func (t *progressTUI) Controls() <-chan ui.Control              { return nil }
+
+func (t *progressTUI) LogInfo(format string, args ...interface{}) {
+	t.logger.Info(fmt.Sprintf(format, args...))
+}
+
+func (t *progressTUI) LogSuccess(format string, args ...interface{}) {
+	t.logger.Info(fmt.Sprintf(format, args...))
+}
+
+func (t *progressTUI) LogWarning(format string, args ...interface{}) {
+	t.logger.Warn(fmt.Sprintf(format, args...))
+}
+
+func (t *progressTUI) LogError(format string, args ...interface{}) {
+	t.logger.Error(fmt.Sprintf(format, args...))
+}